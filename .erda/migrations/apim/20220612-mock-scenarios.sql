@@ -0,0 +1,18 @@
+CREATE TABLE `erda_apim_mock_scenario`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织 id',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名',
+    `creator_id`      varchar(255) NOT NULL DEFAULT '' COMMENT '创建人 user id',
+    `updater_id`      varchar(255) NOT NULL DEFAULT '' COMMENT '更新人 user id',
+    `asset_id`        varchar(191) NOT NULL DEFAULT '' COMMENT 'api 集市 id',
+    `version_id`      bigint(20)   NOT NULL DEFAULT 0 COMMENT '对应的 dice_api_asset_versions 的主键',
+    `name`            varchar(191) NOT NULL DEFAULT '' COMMENT '场景名称',
+    `is_enabled`      tinyint(1)   NOT NULL DEFAULT 0 COMMENT '是否按条件自动命中',
+    `config`          text         NOT NULL COMMENT '场景的命中条件和响应',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除时间, 0 表示未删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_version_name` (`version_id`, `name`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='API 资料版本的 mock 场景';
@@ -0,0 +1,2 @@
+ALTER TABLE dice_branch_rules ADD required_approvals int(11) NOT NULL DEFAULT 0 COMMENT '合并前需要的 approve 数量';
+ALTER TABLE dice_branch_rules ADD is_code_owner_approval_required tinyint(1) NOT NULL DEFAULT 0 COMMENT '合并前是否需要 CODEOWNERS 中的 owner approve';
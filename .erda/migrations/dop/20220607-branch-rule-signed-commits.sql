@@ -0,0 +1 @@
+ALTER TABLE dice_branch_rules ADD is_signed_commits_required tinyint(1) NOT NULL DEFAULT 0 COMMENT '推送的提交是否必须有验证通过的签名';
@@ -0,0 +1,2 @@
+ALTER TABLE dice_repos ADD merge_methods varchar(64) NOT NULL DEFAULT '' COMMENT '允许的合并方式, 逗号分隔, 为空时允许所有方式';
+ALTER TABLE dice_repo_merge_requests ADD merge_method varchar(20) NOT NULL DEFAULT '' COMMENT '合并方式: merge, squash, rebase, ff';
@@ -0,0 +1,19 @@
+CREATE TABLE `erda_repo_merge_request_reviewer`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `repo_id`         bigint(20)   NOT NULL DEFAULT 0 COMMENT '仓库 ID',
+    `mr_id`           bigint(20)   NOT NULL DEFAULT 0 COMMENT '合并请求 ID',
+    `reviewer_id`     varchar(150) NOT NULL DEFAULT '' COMMENT '评审人 ID',
+    `state`           varchar(32)  NOT NULL DEFAULT '' COMMENT '评审状态: pending, approved, changes_requested',
+    `is_code_owner`   tinyint(1)   NOT NULL DEFAULT 0 COMMENT '是否根据 CODEOWNERS 自动添加',
+    `commit`          varchar(64)  NOT NULL DEFAULT '' COMMENT '评审时源分支的 commit',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_mr_reviewer` (`mr_id`, `reviewer_id`, `soft_deleted_at`),
+    KEY `idx_repo_id` (`repo_id`),
+    KEY `idx_reviewer_id` (`reviewer_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='合并请求评审人';
@@ -0,0 +1,17 @@
+CREATE TABLE `erda_repo_user_key`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '添加公钥时所在的组织 ID',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '添加公钥时所在的组织名称',
+    `user_id`         varchar(150) NOT NULL DEFAULT '' COMMENT '用户 ID',
+    `title`           varchar(255) NOT NULL DEFAULT '' COMMENT '公钥名称',
+    `content`         text         NOT NULL COMMENT '公钥内容, authorized_keys 格式',
+    `fingerprint`     varchar(128) NOT NULL DEFAULT '' COMMENT '公钥 SHA256 指纹',
+    `last_used_at`    datetime     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '最近使用时间',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_fingerprint` (`fingerprint`, `soft_deleted_at`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户 SSH 公钥';
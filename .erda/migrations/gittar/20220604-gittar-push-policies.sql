@@ -0,0 +1,20 @@
+CREATE TABLE `erda_repo_push_policy`
+(
+    `id`                        varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`                    bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`                  varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `repo_id`                   bigint(20)   NOT NULL DEFAULT 0 COMMENT '仓库 ID',
+    `is_enabled`                tinyint(1)   NOT NULL DEFAULT 0 COMMENT '是否开启推送规则',
+    `commit_message_pattern`    varchar(512) NOT NULL DEFAULT '' COMMENT '提交信息需要匹配的正则',
+    `is_issue_ref_required`     tinyint(1)   NOT NULL DEFAULT 0 COMMENT '提交信息是否需要关联项目中的事项, 如 #123',
+    `is_signed_off_by_required` tinyint(1)   NOT NULL DEFAULT 0 COMMENT '是否需要作者的 Signed-off-by',
+    `max_file_size`             bigint(20)   NOT NULL DEFAULT 0 COMMENT '单个文件大小上限, 单位字节, 0 表示不限制',
+    `forbidden_extensions`      varchar(512) NOT NULL DEFAULT '' COMMENT '禁止提交的文件后缀, 逗号分隔',
+    `is_secret_scan_enabled`    tinyint(1)   NOT NULL DEFAULT 0 COMMENT '是否检测密钥和 token',
+    `updated_by`                varchar(150) NOT NULL DEFAULT '' COMMENT '更新人',
+    `created_at`                datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`                datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at`           bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_repo_id` (`repo_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='仓库推送规则';
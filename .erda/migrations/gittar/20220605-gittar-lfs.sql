@@ -0,0 +1,33 @@
+ALTER TABLE dice_repos ADD lfs_quota bigint(20) NOT NULL DEFAULT 0 COMMENT 'lfs 存储配额, 单位字节, 0 表示使用默认配额';
+
+CREATE TABLE `erda_repo_lfs_object`
+(
+    `id`              varchar(36) NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)  NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`        varchar(50) NOT NULL DEFAULT '' COMMENT '组织名称',
+    `repo_id`         bigint(20)  NOT NULL DEFAULT 0 COMMENT '仓库 ID',
+    `oid`             varchar(64) NOT NULL DEFAULT '' COMMENT '对象 sha256',
+    `size`            bigint(20)  NOT NULL DEFAULT 0 COMMENT '对象大小, 单位字节',
+    `created_at`      datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)  NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_repo_oid` (`repo_id`, `oid`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='仓库 lfs 对象';
+
+CREATE TABLE `erda_repo_lfs_lock`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `repo_id`         bigint(20)   NOT NULL DEFAULT 0 COMMENT '仓库 ID',
+    `path`            varchar(512) NOT NULL DEFAULT '' COMMENT '锁定的文件路径',
+    `ref`             varchar(255) NOT NULL DEFAULT '' COMMENT '锁定时的分支',
+    `owner_id`        varchar(150) NOT NULL DEFAULT '' COMMENT '锁定人 ID',
+    `owner_name`      varchar(255) NOT NULL DEFAULT '' COMMENT '锁定人名称',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_repo_path` (`repo_id`, `path`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='仓库 lfs 文件锁';
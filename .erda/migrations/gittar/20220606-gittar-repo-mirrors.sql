@@ -0,0 +1,23 @@
+CREATE TABLE `erda_repo_mirror`
+(
+    `id`              varchar(36)   NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)    NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`        varchar(50)   NOT NULL DEFAULT '' COMMENT '组织名称',
+    `repo_id`         bigint(20)    NOT NULL DEFAULT 0 COMMENT '仓库 ID',
+    `direction`       varchar(16)   NOT NULL DEFAULT '' COMMENT '镜像方向: pull, push',
+    `remote_url`      varchar(1024) NOT NULL DEFAULT '' COMMENT '远程仓库地址',
+    `username`        varchar(255)  NOT NULL DEFAULT '' COMMENT '远程仓库用户名',
+    `password`        varchar(1024) NOT NULL DEFAULT '' COMMENT '远程仓库密码或 token, 加密存储',
+    `sync_interval`   bigint(20)    NOT NULL DEFAULT 0 COMMENT '拉取间隔, 单位分钟',
+    `is_enabled`      tinyint(1)    NOT NULL DEFAULT 1 COMMENT '是否开启',
+    `status`          varchar(16)   NOT NULL DEFAULT '' COMMENT '同步状态: pending, syncing, success, failed',
+    `last_error`      text          NOT NULL COMMENT '最近一次同步的错误',
+    `last_sync_at`    datetime      NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '最近一次同步时间',
+    `last_success_at` datetime      NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '最近一次同步成功时间',
+    `created_by`      varchar(150)  NOT NULL DEFAULT '' COMMENT '创建人',
+    `created_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)    NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_repo_id` (`repo_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='仓库镜像';
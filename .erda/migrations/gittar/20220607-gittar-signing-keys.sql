@@ -0,0 +1,19 @@
+CREATE TABLE `erda_repo_signing_key`
+(
+    `id`              varchar(36)   NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)    NOT NULL DEFAULT 0 COMMENT '添加密钥时所在的组织 ID',
+    `org_name`        varchar(50)   NOT NULL DEFAULT '' COMMENT '添加密钥时所在的组织名称',
+    `user_id`         varchar(150)  NOT NULL DEFAULT '' COMMENT '用户 ID',
+    `type`            varchar(16)   NOT NULL DEFAULT '' COMMENT '签名密钥类型, gpg 或 ssh',
+    `title`           varchar(255)  NOT NULL DEFAULT '' COMMENT '密钥名称',
+    `content`         text          NOT NULL COMMENT '公钥内容, armored gpg 公钥或 authorized_keys 格式',
+    `fingerprint`     varchar(128)  NOT NULL DEFAULT '' COMMENT '公钥指纹',
+    `key_ids`         varchar(1024) NOT NULL DEFAULT '' COMMENT 'gpg 主密钥和子密钥 ID, 逗号分隔',
+    `emails`          varchar(1024) NOT NULL DEFAULT '' COMMENT '可签名的邮箱, 逗号分隔',
+    `created_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)    NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_fingerprint` (`fingerprint`, `soft_deleted_at`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户提交签名公钥';
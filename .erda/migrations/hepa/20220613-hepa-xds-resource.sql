@@ -0,0 +1,17 @@
+CREATE TABLE `erda_gateway_xds_resource`
+(
+    `id`              varchar(36)   NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)    NOT NULL DEFAULT 0 COMMENT '企业id',
+    `org_name`        varchar(50)   NOT NULL DEFAULT '' COMMENT '企业名',
+    `cluster`         varchar(32)   NOT NULL DEFAULT '' COMMENT '集群名',
+    `kind`            varchar(32)   NOT NULL DEFAULT '' COMMENT '资源类型',
+    `name`            varchar(256)  NOT NULL DEFAULT '' COMMENT '资源名称',
+    `parent_id`       varchar(36)   NOT NULL DEFAULT '' COMMENT '所属资源id',
+    `tags`            varchar(1024) NOT NULL DEFAULT '' COMMENT '标签，逗号分隔',
+    `content`         mediumtext    NOT NULL COMMENT '资源内容',
+    `created_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)    NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_cluster_kind` (`cluster`, `kind`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='xDS 网关资源';
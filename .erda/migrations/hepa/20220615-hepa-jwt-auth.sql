@@ -0,0 +1,23 @@
+alter table `tb_gateway_package`
+      add column `auth_config` text COMMENT '鉴权配置';
+
+alter table `tb_gateway_package_api`
+      add column `scopes` varchar(1024) NOT NULL DEFAULT '' COMMENT '要求的jwt scope，逗号分隔';
+
+CREATE TABLE `erda_gateway_jwt_credential`
+(
+    `id`              varchar(36)   NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)    NOT NULL DEFAULT 0 COMMENT '企业id',
+    `org_name`        varchar(50)   NOT NULL DEFAULT '' COMMENT '企业名',
+    `consumer_id`     varchar(128)  NOT NULL DEFAULT '' COMMENT 'kong的消费者id',
+    `credential_id`   varchar(128)  NOT NULL DEFAULT '' COMMENT 'kong的凭证id',
+    `issuer`          varchar(1024) NOT NULL DEFAULT '' COMMENT '签发者',
+    `jwks_uri`        varchar(1024) NOT NULL DEFAULT '' COMMENT 'JWKS地址',
+    `key_id`          varchar(256)  NOT NULL DEFAULT '' COMMENT 'JWKS中的密钥id',
+    `is_pinned`       tinyint(1)    NOT NULL DEFAULT 0 COMMENT '密钥id是否由用户指定',
+    `created_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)    NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_consumer` (`consumer_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='消费者的 JWT 凭证';
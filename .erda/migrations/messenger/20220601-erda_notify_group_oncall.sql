@@ -0,0 +1,16 @@
+CREATE TABLE `erda_notify_group_oncall`
+(
+    `id`              varchar(36)   NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)    NOT NULL COMMENT '组织id',
+    `org_name`        varchar(50)   NOT NULL DEFAULT '' COMMENT '组织名称',
+    `notify_group_id` bigint(20)    NOT NULL COMMENT '通知组id',
+    `user_ids`        varchar(2048) NOT NULL COMMENT '轮值用户id，逗号分隔',
+    `shift_minutes`   bigint(20)    NOT NULL COMMENT '每班时长（分钟）',
+    `start_at`        datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '轮值开始时间',
+    `creator`         varchar(36)   NOT NULL DEFAULT '' COMMENT '创建者',
+    `created_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)    NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_notify_group_id` (`notify_group_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='通知组值班轮换';
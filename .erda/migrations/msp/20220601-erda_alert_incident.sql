@@ -0,0 +1,63 @@
+CREATE TABLE `erda_alert_incident_policy`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL COMMENT '组织id',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `scope`           varchar(50)  NOT NULL COMMENT 'Scope',
+    `scope_id`        varchar(100) NOT NULL COMMENT 'ScopeId',
+    `group_keys`      varchar(255) NOT NULL DEFAULT 'alert_id' COMMENT '告警事件聚合的字段，逗号分隔',
+    `group_window`    bigint(20)   NOT NULL DEFAULT 0 COMMENT '聚合窗口（分钟），0 表示不限制',
+    `notify_group_id` bigint(20)   NOT NULL DEFAULT 0 COMMENT '值班通知组id',
+    `escalation`      text         NOT NULL COMMENT '升级策略',
+    `creator`         varchar(36)  NOT NULL DEFAULT '' COMMENT '创建者',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_scope` (`scope`, `scope_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警事件聚合策略';
+
+CREATE TABLE `erda_alert_incident`
+(
+    `id`                varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`            bigint(20)   NOT NULL COMMENT '组织id',
+    `org_name`          varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `scope`             varchar(50)  NOT NULL COMMENT 'Scope',
+    `scope_id`          varchar(100) NOT NULL COMMENT 'ScopeId',
+    `policy_id`         varchar(36)  NOT NULL DEFAULT '' COMMENT '聚合策略id',
+    `group_key`         varchar(64)  NOT NULL COMMENT '聚合键',
+    `title`             varchar(512) NOT NULL DEFAULT '' COMMENT '标题',
+    `alert_level`       varchar(50)  NOT NULL DEFAULT '' COMMENT '最高告警级别',
+    `state`             varchar(50)  NOT NULL COMMENT '状态 triggered,acknowledged,resolved',
+    `assignee`          varchar(36)  NOT NULL DEFAULT '' COMMENT '处理人',
+    `escalation_step`   int(11)      NOT NULL DEFAULT 0 COMMENT '已执行的升级步骤数',
+    `last_escalated_at` datetime     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '最后升级时间',
+    `acknowledged_by`   varchar(36)  NOT NULL DEFAULT '' COMMENT '认领人',
+    `acknowledged_at`   datetime     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '认领时间',
+    `resolved_by`       varchar(36)  NOT NULL DEFAULT '' COMMENT '解决人',
+    `resolved_at`       datetime     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '解决时间',
+    `first_event_time`  datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '首次事件时间',
+    `last_event_time`   datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最近事件时间',
+    `event_count`       bigint(20)   NOT NULL DEFAULT 0 COMMENT '事件数',
+    `created_at`        datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`        datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at`   bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_group_key` (`group_key`, `state`),
+    KEY `idx_scope` (`scope`, `scope_id`, `state`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警事件聚合';
+
+CREATE TABLE `erda_alert_incident_event`
+(
+    `id`              varchar(36) NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)  NOT NULL COMMENT '组织id',
+    `org_name`        varchar(50) NOT NULL DEFAULT '' COMMENT '组织名称',
+    `incident_id`     varchar(36) NOT NULL COMMENT '聚合id',
+    `alert_event_id`  varchar(64) NOT NULL COMMENT '告警事件id',
+    `created_at`      datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)  NOT NULL DEFAULT 0 COMMENT '软删除',
+    PRIMARY KEY (`id`),
+    KEY `idx_incident_id` (`incident_id`),
+    KEY `idx_alert_event_id` (`alert_event_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警事件聚合关联';
@@ -0,0 +1,20 @@
+CREATE TABLE `erda_autotest_scene_dataset`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织id',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名',
+    `name`            varchar(255) NOT NULL DEFAULT '' COMMENT '数据集名称',
+    `scope_type`      varchar(32)  NOT NULL DEFAULT '' COMMENT '关联对象类型: scene, sceneset',
+    `scope_id`        bigint(20)   NOT NULL DEFAULT 0 COMMENT '关联对象id',
+    `space_id`        bigint(20)   NOT NULL DEFAULT 0 COMMENT '空间id',
+    `columns`         text         NOT NULL COMMENT '列名, json 数组',
+    `data_rows`       longtext     NOT NULL COMMENT '数据行, json 二维数组',
+    `is_parallel`     tinyint(1)   NOT NULL DEFAULT 0 COMMENT '数据行是否并行执行',
+    `creator_id`      varchar(255) NOT NULL DEFAULT '' COMMENT '创建人',
+    `updater_id`      varchar(255) NOT NULL DEFAULT '' COMMENT '更新人',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除时间, 0 表示未删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_scope` (`scope_type`, `scope_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='自动化测试场景参数化数据集表';
@@ -0,0 +1,20 @@
+ALTER TABLE `dice_test_cases`
+    ADD COLUMN `review_status` varchar(16) NOT NULL DEFAULT '' COMMENT '评审状态: 空表示未开启评审, DRAFT, IN_REVIEW, APPROVED',
+    ADD COLUMN `reviewers` varchar(1024) NOT NULL DEFAULT '' COMMENT '评审人, json 数组';
+
+CREATE TABLE `erda_test_case_version`
+(
+    `id`              varchar(36)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织id',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名',
+    `test_case_id`    bigint(20)   NOT NULL DEFAULT 0 COMMENT '测试用例id',
+    `version`         bigint(20)   NOT NULL DEFAULT 0 COMMENT '版本号, 从 1 开始递增',
+    `snapshot`        longtext     NOT NULL COMMENT '用例内容快照, json',
+    `diffs`           text         NOT NULL COMMENT '相对上一版本的字段变更, json 数组',
+    `creator_id`      varchar(255) NOT NULL DEFAULT '' COMMENT '创建人',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '软删除时间, 0 表示未删除',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_test_case_version` (`test_case_id`, `version`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='测试用例历史版本表';
@@ -241,6 +241,27 @@ func (m APIAssetVersionSpecsModel) TableName() string {
 	return "dice_api_asset_version_specs"
 }
 
+// API 资料版本的 mock 场景
+type APIMockScenarioModel struct {
+	ID            string    `json:"id" gorm:"primary_key"`
+	OrgID         uint64    `json:"orgID"`
+	OrgName       string    `json:"orgName"`
+	CreatorID     string    `json:"creatorID"`
+	UpdaterID     string    `json:"updaterID"`
+	AssetID       string    `json:"assetID"`
+	VersionID     uint64    `json:"versionID"`
+	Name          string    `json:"name"`
+	IsEnabled     bool      `json:"isEnabled"`
+	Config        string    `json:"config"` // 场景的命中条件和响应, mock.Scenario 的 JSON
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	SoftDeletedAt uint64    `json:"softDeletedAt"`
+}
+
+func (m APIMockScenarioModel) TableName() string {
+	return "erda_apim_mock_scenario"
+}
+
 type APIAccessesModel struct {
 	BaseModel
 
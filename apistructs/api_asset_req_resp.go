@@ -21,6 +21,9 @@ import (
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/erda-project/erda/pkg/swagger/mock"
+	"github.com/erda-project/erda/pkg/swagger/oas3"
 )
 
 const (
@@ -65,6 +68,8 @@ type APIAssetVersionCreateRequest struct {
 	AppID       uint64 `json:"appID"`       // 如果 source == design_center, appID 为设计中心文档所在应用
 	Branch      string `json:"branch"`      // 如果 source == design_center, branch 为设计中心文档所在分支
 	ServiceName string `json:"serviceName"` // 如果 source = design_center, serviceName 为文档表述的服务的名称
+
+	BlockBreaking bool `json:"blockBreaking"` // 为 true 时, 发布 minor/patch 版本若与同 major 的上一版本存在不兼容变更则拒绝发布
 }
 
 type GetAPIAssetReq struct {
@@ -125,6 +130,7 @@ type CreateAPIAssetVersionBody struct {
 	Patch            uint64 `json:"patch"`
 	SpecProtocol     string `json:"specProtocol"`
 	SpecDiceFileUUID string `json:"specDiceFileUUID"`
+	BlockBreaking    bool   `json:"blockBreaking"`
 }
 
 type PagingAPIAssetVersionsReq struct {
@@ -187,6 +193,59 @@ type GetAssetVersionRsp struct {
 	Access           *APIAccessesModel          `json:"access,omitempty"`
 }
 
+// CompareAPIAssetVersionsReq 比较两个 API 资料版本的请求
+type CompareAPIAssetVersionsReq struct {
+	OrgID       uint64
+	Identity    *IdentityInfo
+	URIParams   *AssetVersionDetailURI
+	QueryParams *CompareAPIAssetVersionsQueryParams
+}
+
+type CompareAPIAssetVersionsQueryParams struct {
+	// 作为比较基准的版本, 不指定时取比当前版本小的最新版本
+	BaseVersionID uint64 `json:"baseVersionID" schema:"baseVersionID"`
+}
+
+// CompareAPIAssetVersionsRsp 比较结果, Changes 为 Target 相对于 Base 的语义变更
+type CompareAPIAssetVersionsRsp struct {
+	Base     *APIAssetVersionsModel `json:"base"`
+	Target   *APIAssetVersionsModel `json:"target"`
+	Breaking bool                   `json:"breaking"`
+	Changes  []*oas3.Change         `json:"changes"`
+}
+
+// APIMockReq mock 请求, Request 为去掉 mock 地址前缀后的请求
+type APIMockReq struct {
+	OrgID     uint64
+	Identity  *IdentityInfo
+	AssetID   string
+	VersionID uint64
+	Request   *mock.Request
+}
+
+// APIMockScenarioReq mock 场景的增删改查请求
+type APIMockScenarioReq struct {
+	OrgID      uint64
+	Identity   *IdentityInfo
+	AssetID    string
+	VersionID  uint64
+	ScenarioID string
+	Scenario   *mock.Scenario
+}
+
+// APIMockScenario mock 场景
+type APIMockScenario struct {
+	mock.Scenario
+
+	ID        string    `json:"id"`
+	AssetID   string    `json:"assetID"`
+	VersionID uint64    `json:"versionID"`
+	CreatorID string    `json:"creatorID"`
+	UpdaterID string    `json:"updaterID"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
 type APIAssetVersionInstanceCreateRequest struct {
 	Name string `json:"name"`
 
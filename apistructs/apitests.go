@@ -30,6 +30,10 @@ type APIInfo struct {
 	Body      APIBody       `json:"body"`
 	OutParams []APIOutParam `json:"outParams"`
 	Asserts   [][]APIAssert `json:"asserts"`
+
+	Protocol  APIProtocol         `json:"protocol,omitempty"`
+	GRPC      *APIGRPCConfig      `json:"grpc,omitempty"`
+	WebSocket *APIWebSocketConfig `json:"websocket,omitempty"`
 }
 
 type APIInfoV2 struct {
@@ -42,6 +46,50 @@ type APIInfoV2 struct {
 	Body      APIBody       `json:"body"`
 	OutParams []APIOutParam `json:"out_params"`
 	Asserts   []APIAssert   `json:"asserts"`
+
+	Protocol  APIProtocol         `json:"protocol,omitempty"`
+	GRPC      *APIGRPCConfig      `json:"grpc,omitempty"`
+	WebSocket *APIWebSocketConfig `json:"websocket,omitempty"`
+}
+
+// APIProtocol API 测试使用的协议
+type APIProtocol string
+
+var (
+	APIProtocolHTTP      APIProtocol = "" // default
+	APIProtocolGRPC      APIProtocol = "grpc"
+	APIProtocolWebSocket APIProtocol = "websocket"
+)
+
+func (p APIProtocol) String() string {
+	return string(p)
+}
+
+// APIGRPCConfig gRPC API 测试的配置
+//
+// url is the target address, e.g. grpc://host:port or grpcs://host:port for tls;
+// method is the full method name, e.g. package.Service/Method;
+// headers are sent as metadata and the json body is converted to the request message.
+type APIGRPCConfig struct {
+	// Descriptor is the base64 encoded FileDescriptorSet of the uploaded proto,
+	// generated by `protoc --include_imports --descriptor_set_out`
+	Descriptor string `json:"descriptor,omitempty"`
+	// Reflection resolves the method by server reflection when no descriptor is uploaded
+	Reflection bool `json:"reflection,omitempty"`
+	// Timeout in seconds, including the whole server stream
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// APIWebSocketConfig WebSocket API 测试的配置
+//
+// url is the ws:// or wss:// address and headers are sent with the handshake.
+type APIWebSocketConfig struct {
+	// Messages are sent in order after connected
+	Messages []string `json:"messages,omitempty"`
+	// Expect is the number of messages must be received within the timeout
+	Expect int `json:"expect,omitempty"`
+	// Timeout in seconds
+	Timeout int64 `json:"timeout,omitempty"`
 }
 
 // APIHeader API测试请求头
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AutoTestLoadTestMaxVirtualUsers = 500
+	AutoTestLoadTestMaxDurationSec  = 3600
+)
+
+// AutoTestLoadTestConfig run the api steps of a scene as a performance test
+type AutoTestLoadTestConfig struct {
+	VirtualUsers int   `json:"virtualUsers"`
+	RampUpSec    int64 `json:"rampUpSec"`   // virtual users are started evenly during ramp-up
+	DurationSec  int64 `json:"durationSec"` // including ramp-up
+	ThinkTimeMs  int64 `json:"thinkTimeMs"` // pause of virtual user between steps
+
+	Thresholds AutoTestLoadTestThresholds `json:"thresholds"`
+}
+
+// AutoTestLoadTestThresholds the load test fails if any threshold is exceeded, zero means not checked.
+// Latency and error rate are checked for every step, throughput is checked for the whole scene.
+type AutoTestLoadTestThresholds struct {
+	MaxErrorRate  float64 `json:"maxErrorRate"` // percent
+	MaxAvgMs      float64 `json:"maxAvgMs"`
+	MaxP95Ms      float64 `json:"maxP95Ms"`
+	MaxP99Ms      float64 `json:"maxP99Ms"`
+	MinThroughput float64 `json:"minThroughput"` // requests per second
+}
+
+func (c *AutoTestLoadTestConfig) Validate() error {
+	if c.VirtualUsers <= 0 || c.VirtualUsers > AutoTestLoadTestMaxVirtualUsers {
+		return fmt.Errorf("virtualUsers should be in range [1, %d]", AutoTestLoadTestMaxVirtualUsers)
+	}
+	if c.DurationSec <= 0 || c.DurationSec > AutoTestLoadTestMaxDurationSec {
+		return fmt.Errorf("durationSec should be in range [1, %d]", AutoTestLoadTestMaxDurationSec)
+	}
+	if c.RampUpSec < 0 || c.RampUpSec >= c.DurationSec {
+		return fmt.Errorf("rampUpSec should be in range [0, durationSec)")
+	}
+	if c.ThinkTimeMs < 0 {
+		return fmt.Errorf("thinkTimeMs should not be negative")
+	}
+	t := c.Thresholds
+	if t.MaxErrorRate < 0 || t.MaxErrorRate > 100 || t.MaxAvgMs < 0 || t.MaxP95Ms < 0 || t.MaxP99Ms < 0 || t.MinThroughput < 0 {
+		return fmt.Errorf("invalid thresholds")
+	}
+	return nil
+}
+
+// AutoTestLoadTestStep api step of the scene, invoked in order by every virtual user
+type AutoTestLoadTestStep struct {
+	ID      uint64    `json:"id"`
+	Name    string    `json:"name"`
+	APISpec APIInfoV2 `json:"apiSpec"`
+}
+
+// AutoTestLoadTestStat statistics of a step or the whole scene
+type AutoTestLoadTestStat struct {
+	StepID     uint64  `json:"stepID,omitempty"`
+	Name       string  `json:"name"`
+	Requests   int64   `json:"requests"`
+	Errors     int64   `json:"errors"`
+	ErrorRate  float64 `json:"errorRate"`  // percent
+	Throughput float64 `json:"throughput"` // requests per second
+	AvgMs      float64 `json:"avgMs"`
+	MinMs      float64 `json:"minMs"`
+	MaxMs      float64 `json:"maxMs"`
+	P50Ms      float64 `json:"p50Ms"`
+	P90Ms      float64 `json:"p90Ms"`
+	P95Ms      float64 `json:"p95Ms"`
+	P99Ms      float64 `json:"p99Ms"`
+}
+
+// AutoTestLoadTestReport result of the load test, saved as pipeline report with type load-test
+type AutoTestLoadTestReport struct {
+	PipelineID uint64                 `json:"pipelineID"`
+	SceneID    uint64                 `json:"sceneID"`
+	Config     AutoTestLoadTestConfig `json:"config"`
+	StartedAt  time.Time              `json:"startedAt"`
+	EndedAt    time.Time              `json:"endedAt"`
+	Total      AutoTestLoadTestStat   `json:"total"`
+	Steps      []AutoTestLoadTestStat `json:"steps"`
+	Passed     bool                   `json:"passed"`
+	Violations []string               `json:"violations,omitempty"` // thresholds not met
+}
+
+// AutotestLoadTestSceneRequest run scene as load test
+type AutotestLoadTestSceneRequest struct {
+	SceneID                uint64                 `json:"-"`
+	Config                 AutoTestLoadTestConfig `json:"config"`
+	ClusterName            string                 `json:"clusterName"`
+	Labels                 map[string]string      `json:"labels"`
+	ConfigManageNamespaces string                 `json:"configManageNamespaces"`
+
+	IdentityInfo
+}
+
+// AutoTestLoadTestReportResponse .
+type AutoTestLoadTestReportResponse struct {
+	Header
+	Data *AutoTestLoadTestReport `json:"data"`
+}
@@ -260,6 +260,8 @@ const (
 	StepTypeScene        StepAPIType = "SCENE"
 	StepTypeCustomScript StepAPIType = "CUSTOM"
 	StepTypeConfigSheet  StepAPIType = "CONFIGSHEET"
+	StepTypeGRPC         StepAPIType = "GRPC"
+	StepTypeWebSocket    StepAPIType = "WEBSOCKET"
 	AutotestType                     = "AUTOTESTTYPE"
 	AutotestSceneStep                = "STEP"
 	AutotestSceneSet                 = "SCENESET"
@@ -267,7 +269,7 @@ const (
 	AutoTestPlan                     = "TESTPLAN"
 )
 
-var EffectiveStepType = []StepAPIType{StepTypeAPI, StepTypeCustomScript, StepTypeConfigSheet}
+var EffectiveStepType = []StepAPIType{StepTypeAPI, StepTypeCustomScript, StepTypeConfigSheet, StepTypeGRPC, StepTypeWebSocket}
 
 // IsEffectiveStepType Check is effective stepType or not
 func (s StepAPIType) IsEffectiveStepType() bool {
@@ -279,6 +281,23 @@ func (s StepAPIType) IsEffectiveStepType() bool {
 	return false
 }
 
+// IsAPIStepType Check the step invokes an api by http, grpc or websocket
+func (s StepAPIType) IsAPIStepType() bool {
+	return s == StepTypeAPI || s == StepTypeGRPC || s == StepTypeWebSocket
+}
+
+// APIProtocol return the protocol used by the api step
+func (s StepAPIType) APIProtocol() APIProtocol {
+	switch s {
+	case StepTypeGRPC:
+		return APIProtocolGRPC
+	case StepTypeWebSocket:
+		return APIProtocolWebSocket
+	default:
+		return APIProtocolHTTP
+	}
+}
+
 func (v StepAPIType) String() string {
 	return string(v)
 }
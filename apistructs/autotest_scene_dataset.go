@@ -0,0 +1,100 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"time"
+)
+
+// AutoTestSceneDatasetScope the object which dataset is attached to
+type AutoTestSceneDatasetScope string
+
+const (
+	AutoTestSceneDatasetScopeScene    AutoTestSceneDatasetScope = "scene"
+	AutoTestSceneDatasetScopeSceneSet AutoTestSceneDatasetScope = "sceneset"
+)
+
+func (s AutoTestSceneDatasetScope) Valid() bool {
+	switch s {
+	case AutoTestSceneDatasetScopeScene, AutoTestSceneDatasetScopeSceneSet:
+		return true
+	default:
+		return false
+	}
+}
+
+// AutoTestSceneDataset parameter dataset of data-driven scene,
+// the scene runs once per row and the row values are bound to the scene inputs with the same names as columns
+type AutoTestSceneDataset struct {
+	ID        string                    `json:"id"`
+	Name      string                    `json:"name"`
+	ScopeType AutoTestSceneDatasetScope `json:"scopeType"`
+	ScopeID   uint64                    `json:"scopeID"`
+	SpaceID   uint64                    `json:"spaceID"`
+	Columns   []string                  `json:"columns"`
+	Rows      [][]string                `json:"rows"`
+	Parallel  bool                      `json:"parallel"` // run rows in parallel inside the pipeline
+	CreatorID string                    `json:"creatorID"`
+	UpdaterID string                    `json:"updaterID"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+}
+
+// RowParams returns the values of row index by column name
+func (d *AutoTestSceneDataset) RowParams(index int) map[string]string {
+	params := make(map[string]string, len(d.Columns))
+	if index < 0 || index >= len(d.Rows) {
+		return params
+	}
+	for i, column := range d.Columns {
+		if i < len(d.Rows[index]) {
+			params[column] = d.Rows[index][i]
+		}
+	}
+	return params
+}
+
+// RowName returns the display name of row index which starts from 1
+func (d *AutoTestSceneDataset) RowName(index int) string {
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// AutoTestSceneDatasetSaveRequest create or replace the dataset of scene or scene set
+type AutoTestSceneDatasetSaveRequest struct {
+	ScopeType AutoTestSceneDatasetScope `json:"-"`
+	ScopeID   uint64                    `json:"-"`
+	Name      string                    `json:"name"`
+	Columns   []string                  `json:"columns"`
+	Rows      [][]string                `json:"rows"`
+	Parallel  bool                      `json:"parallel"`
+
+	IdentityInfo
+}
+
+// AutoTestSceneDatasetImportRequest import dataset from csv or excel file, the first row is the header
+type AutoTestSceneDatasetImportRequest struct {
+	ScopeType AutoTestSceneDatasetScope `schema:"-"`
+	ScopeID   uint64                    `schema:"-"`
+	Name      string                    `schema:"name"`
+	Parallel  bool                      `schema:"parallel"`
+
+	IdentityInfo
+}
+
+type AutoTestSceneDatasetResponse struct {
+	Header
+	Data *AutoTestSceneDataset `json:"data"`
+}
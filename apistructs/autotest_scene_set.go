@@ -17,12 +17,15 @@ package apistructs
 type TestSceneSetFileType string
 
 var (
-	TestSceneSetFileTypeExcel TestSceneSetFileType = "excel"
+	TestSceneSetFileTypeExcel   TestSceneSetFileType = "excel"
+	TestSceneSetFileTypeOpenAPI TestSceneSetFileType = "openapi"
+	TestSceneSetFileTypePostman TestSceneSetFileType = "postman"
+	TestSceneSetFileTypeHAR     TestSceneSetFileType = "har"
 )
 
 func (t TestSceneSetFileType) Valid() bool {
 	switch t {
-	case TestSceneSetFileTypeExcel:
+	case TestSceneSetFileTypeExcel, TestSceneSetFileTypeOpenAPI, TestSceneSetFileTypePostman, TestSceneSetFileTypeHAR:
 		return true
 	default:
 		return false
@@ -68,6 +68,8 @@ func TestIsEffectiveStepType(t *testing.T) {
 		{StepTypeAPI, true},
 		{StepTypeCustomScript, true},
 		{StepTypeConfigSheet, true},
+		{StepTypeGRPC, true},
+		{StepTypeWebSocket, true},
 		{StepTypeWait, false},
 		{"", false},
 	}
@@ -75,3 +77,21 @@ func TestIsEffectiveStepType(t *testing.T) {
 		assert.Equal(t, v.want, v.t.IsEffectiveStepType())
 	}
 }
+
+func TestIsAPIStepType(t *testing.T) {
+	tt := []struct {
+		t        StepAPIType
+		want     bool
+		protocol APIProtocol
+	}{
+		{StepTypeAPI, true, APIProtocolHTTP},
+		{StepTypeGRPC, true, APIProtocolGRPC},
+		{StepTypeWebSocket, true, APIProtocolWebSocket},
+		{StepTypeCustomScript, false, APIProtocolHTTP},
+		{StepTypeWait, false, APIProtocolHTTP},
+	}
+	for _, v := range tt {
+		assert.Equal(t, v.want, v.t.IsAPIStepType())
+		assert.Equal(t, v.protocol, v.t.APIProtocol())
+	}
+}
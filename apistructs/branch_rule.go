@@ -29,6 +29,12 @@ type BranchRule struct {
 	Workspace string `json:"workspace"`
 	// 制品可部署的环境
 	ArtifactWorkspace string `json:"artifactWorkspace"`
+	// 合并前需要的 approve 数量
+	RequiredApprovals int `json:"requiredApprovals"`
+	// 合并前需要 CODEOWNERS 中的 owner approve
+	RequireCodeOwnerApproval bool `json:"requireCodeOwnerApproval"`
+	// 推送的提交必须有验证通过的签名
+	RequireSignedCommits bool `json:"requireSignedCommits"`
 }
 type QueryBranchRuleRequest struct {
 	ProjectID int64 `query:"projectId"`
@@ -41,15 +47,18 @@ type QueryBranchRuleResponse struct {
 }
 
 type CreateBranchRuleRequest struct {
-	ScopeType         ScopeType `json:"scopeType"`
-	ScopeID           int64     `json:"scopeId"`
-	Rule              string    `json:"rule"`
-	IsProtect         bool      `json:"isProtect"`
-	NeedApproval      bool      `json:"needApproval"`
-	IsTriggerPipeline bool      `json:"isTriggerPipeline"`
-	Workspace         string    `json:"workspace"`
-	ArtifactWorkspace string    `json:"artifactWorkspace"`
-	Desc              string    `json:"desc"`
+	ScopeType                ScopeType `json:"scopeType"`
+	ScopeID                  int64     `json:"scopeId"`
+	Rule                     string    `json:"rule"`
+	IsProtect                bool      `json:"isProtect"`
+	NeedApproval             bool      `json:"needApproval"`
+	IsTriggerPipeline        bool      `json:"isTriggerPipeline"`
+	Workspace                string    `json:"workspace"`
+	ArtifactWorkspace        string    `json:"artifactWorkspace"`
+	Desc                     string    `json:"desc"`
+	RequiredApprovals        int       `json:"requiredApprovals"`
+	RequireCodeOwnerApproval bool      `json:"requireCodeOwnerApproval"`
+	RequireSignedCommits     bool      `json:"requireSignedCommits"`
 }
 
 type CreateBranchRuleResponse struct {
@@ -58,14 +67,17 @@ type CreateBranchRuleResponse struct {
 }
 
 type UpdateBranchRuleRequest struct {
-	ID                int64  `json:"-"`
-	Rule              string `json:"rule"`
-	IsProtect         bool   `json:"isProtect"`
-	NeedApproval      bool   `json:"needApproval"`
-	IsTriggerPipeline bool   `json:"isTriggerPipeline"`
-	Desc              string `json:"desc"`
-	Workspace         string `json:"workspace"`
-	ArtifactWorkspace string `json:"artifactWorkspace"`
+	ID                       int64  `json:"-"`
+	Rule                     string `json:"rule"`
+	IsProtect                bool   `json:"isProtect"`
+	NeedApproval             bool   `json:"needApproval"`
+	IsTriggerPipeline        bool   `json:"isTriggerPipeline"`
+	Desc                     string `json:"desc"`
+	Workspace                string `json:"workspace"`
+	ArtifactWorkspace        string `json:"artifactWorkspace"`
+	RequiredApprovals        int    `json:"requiredApprovals"`
+	RequireCodeOwnerApproval bool   `json:"requireCodeOwnerApproval"`
+	RequireSignedCommits     bool   `json:"requireSignedCommits"`
 }
 
 type UpdateBranchRuleResponse struct {
@@ -309,6 +309,41 @@ type MergeRequestInfo struct {
 	EventName            string       `json:"eventName"`
 	CheckRuns            CheckRuns    `json:"checkRuns,omitempty"`
 	JoinTempBranchStatus string       `json:"joinTempBranchStatus"`
+	MergeMethod          string       `json:"mergeMethod"`
+	// ReviewerIds are the reviewers requested on creation, the code owners are requested automatically
+	ReviewerIds []string        `json:"reviewerIds"`
+	Reviewers   []*MrReviewer   `json:"reviewers,omitempty"`
+	Approval    *MrApprovalInfo `json:"approval,omitempty"`
+}
+
+// MrReviewState is the review state of a reviewer
+type MrReviewState string
+
+const (
+	MrReviewStatePending          MrReviewState = "pending"
+	MrReviewStateApproved         MrReviewState = "approved"
+	MrReviewStateChangesRequested MrReviewState = "changes_requested"
+)
+
+// MrReviewer is a reviewer of the merge request
+type MrReviewer struct {
+	ReviewerId   string        `json:"reviewerId"`
+	ReviewerUser *UserInfoDto  `json:"reviewerUser"`
+	State        MrReviewState `json:"state"`
+	IsCodeOwner  bool          `json:"isCodeOwner"`
+	Commit       string        `json:"commit"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
+
+// MrApprovalInfo is the approval status checked by the target branch rule
+type MrApprovalInfo struct {
+	RequiredApprovals        int      `json:"requiredApprovals"`
+	RequireCodeOwnerApproval bool     `json:"requireCodeOwnerApproval"`
+	Approvals                int      `json:"approvals"`
+	StaleApprovals           []string `json:"staleApprovals"` // approved an outdated commit of the source branch
+	ChangesRequested         []string `json:"changesRequested"`
+	MissingCodeOwners        []string `json:"missingCodeOwners"`
+	Approved                 bool     `json:"approved"`
 }
 
 func (that MergeRequestInfo) IsJoinTempBranch() bool {
@@ -319,10 +354,11 @@ func (that MergeRequestInfo) IsJoinTempBranch() bool {
 }
 
 type MergeStatusInfo struct {
-	HasConflict bool   `json:"hasConflict"`
-	IsMerged    bool   `json:"isMerged"`
-	HasError    bool   `json:"hasError"`
-	ErrorMsg    string `json:"errorMsg"`
+	HasConflict bool            `json:"hasConflict"`
+	IsMerged    bool            `json:"isMerged"`
+	HasError    bool            `json:"hasError"`
+	ErrorMsg    string          `json:"errorMsg"`
+	Methods     map[string]bool `json:"methods"`
 }
 
 // GittarCreateMergeResponse 创建mr响应
@@ -70,6 +70,7 @@ const (
 	LabelSpaceID          = "spaceID"          // 空间 id
 	LabelIterationID      = "iterationID"
 	LabelIsRefSet         = "isRefSet"
+	LabelSceneDataRow     = "sceneDataRow" // 数据驱动场景执行的数据行
 	// FDP
 	LabelFdpWorkflowID          = "CDP_WF_ID"
 	LabelFdpWorkflowName        = "CDP_WF_NAME"
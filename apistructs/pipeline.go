@@ -665,6 +665,12 @@ type ValidBranch struct {
 	Workspace string `json:"workspace"`
 	// 制品可部署的环境
 	ArtifactWorkspace string `json:"artifactWorkspace"`
+	// 合并前需要的 approve 数量
+	RequiredApprovals int `json:"requiredApprovals"`
+	// 合并前需要 CODEOWNERS 中的 owner approve
+	RequireCodeOwnerApproval bool `json:"requireCodeOwnerApproval"`
+	// 推送的提交必须有验证通过的签名
+	RequireSignedCommits bool `json:"requireSignedCommits"`
 }
 
 func (branch *ValidBranch) GetPermissionResource() string {
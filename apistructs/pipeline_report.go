@@ -53,6 +53,7 @@ var (
 	PipelineReportTypeEvent        PipelineReportType = "event"
 	PipelineReportTypeInspect      PipelineReportType = "task-inspect"
 	PipelineReportTypeAutotestPlan PipelineReportType = "auto-test-execute-config"
+	PipelineReportTypeLoadTest     PipelineReportType = "load-test"
 )
 
 // PipelineReportMeta 流水线报告元数据，前端根据该数据拼装报告详情界面
@@ -27,6 +27,7 @@ const (
 	ActionTypeSnippet      = "snippet"
 	ActionTypeCustomScript = "custom-script"
 	ActionTypeWait         = "wait"
+	ActionTypeLoadTest     = "load-test"
 
 	SnippetSourceLocal = "local"
 )
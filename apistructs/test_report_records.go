@@ -33,11 +33,15 @@ type TestReportRecord struct {
 	Summary      string         `json:"summary"`
 	QualityScore float64        `json:"qualityScore"`
 	ReportData   TestReportData `json:"reportData"`
+
+	// LoadTestPipelineIDs load test reports of the pipelines are attached when creating
+	LoadTestPipelineIDs []uint64 `json:"loadTestPipelineIDs,omitempty"`
 }
 
 type TestReportData struct {
 	IssueDashboard *ComponentProtocolRequest `json:"issue-dashboard,omitempty"`
 	TestDashboard  *ComponentProtocolRequest `json:"test-dashboard,omitempty"`
+	LoadTests      []AutoTestLoadTestReport  `json:"load-tests,omitempty"`
 }
 
 type reportQualityScore struct {
@@ -42,6 +42,8 @@ type TestCase struct {
 	Labels         []ProjectLabel          `json:"labels"`         // 标签
 	APIs           []*ApiTestInfo          `json:"apis"`           // 接口测试集合
 	APICount       TestCaseAPICount        `json:"apiCount"`
+	ReviewStatus   TestCaseReviewStatus    `json:"reviewStatus"` // 评审状态
+	Reviewers      []string                `json:"reviewers"`    // 评审人
 	CreatedAt      time.Time               `json:"createdAt"`
 	UpdatedAt      time.Time               `json:"updatedAt"`
 }
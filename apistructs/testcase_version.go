@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TestCaseReviewStatus 测试用例评审状态，为空表示未开启评审
+type TestCaseReviewStatus string
+
+var (
+	TestCaseReviewStatusNone     TestCaseReviewStatus = ""
+	TestCaseReviewStatusDraft    TestCaseReviewStatus = "DRAFT"
+	TestCaseReviewStatusInReview TestCaseReviewStatus = "IN_REVIEW"
+	TestCaseReviewStatusApproved TestCaseReviewStatus = "APPROVED"
+)
+
+// CanAddToTestPlan only approved test cases can be added to test plan if review is enabled
+func (s TestCaseReviewStatus) CanAddToTestPlan() bool {
+	return s == TestCaseReviewStatusNone || s == TestCaseReviewStatusApproved
+}
+
+// TestCaseReviewAction 测试用例评审操作
+type TestCaseReviewAction string
+
+var (
+	TestCaseReviewActionSubmit  TestCaseReviewAction = "submit"  // draft/approved -> in review
+	TestCaseReviewActionApprove TestCaseReviewAction = "approve" // in review -> approved
+	TestCaseReviewActionReject  TestCaseReviewAction = "reject"  // in review -> draft
+)
+
+// Transit returns the status after the action, false if the action is not allowed in current status
+func (a TestCaseReviewAction) Transit(from TestCaseReviewStatus) (TestCaseReviewStatus, bool) {
+	switch a {
+	case TestCaseReviewActionSubmit:
+		if from != TestCaseReviewStatusInReview {
+			return TestCaseReviewStatusInReview, true
+		}
+	case TestCaseReviewActionApprove:
+		if from == TestCaseReviewStatusInReview {
+			return TestCaseReviewStatusApproved, true
+		}
+	case TestCaseReviewActionReject:
+		if from == TestCaseReviewStatusInReview {
+			return TestCaseReviewStatusDraft, true
+		}
+	}
+	return from, false
+}
+
+// TestCaseSnapshot 测试用例版本快照，只包含用例内容
+type TestCaseSnapshot struct {
+	Name           string                  `json:"name"`
+	Priority       TestCasePriority        `json:"priority"`
+	PreCondition   string                  `json:"preCondition"`
+	Desc           string                  `json:"desc"`
+	StepAndResults []TestCaseStepAndResult `json:"stepAndResults"`
+}
+
+// Diff returns the changed fields from s to after
+func (s TestCaseSnapshot) Diff(after TestCaseSnapshot) []TestCaseFieldDiff {
+	var diffs []TestCaseFieldDiff
+	add := func(field, before, after string) {
+		if before != after {
+			diffs = append(diffs, TestCaseFieldDiff{Field: field, Before: before, After: after})
+		}
+	}
+	add("name", s.Name, after.Name)
+	add("priority", string(s.Priority), string(after.Priority))
+	add("preCondition", s.PreCondition, after.PreCondition)
+	add("desc", s.Desc, after.Desc)
+	add("stepAndResults", stepAndResultsString(s.StepAndResults), stepAndResultsString(after.StepAndResults))
+	return diffs
+}
+
+func stepAndResultsString(srs []TestCaseStepAndResult) string {
+	if len(srs) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(srs)
+	return string(b)
+}
+
+// TestCaseFieldDiff 字段变更
+type TestCaseFieldDiff struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// TestCaseVersion 测试用例历史版本
+type TestCaseVersion struct {
+	ID         string              `json:"id"`
+	TestCaseID uint64              `json:"testCaseID"`
+	Version    uint64              `json:"version"`
+	Snapshot   TestCaseSnapshot    `json:"snapshot"`
+	Diffs      []TestCaseFieldDiff `json:"diffs"` // 相对上一版本的变更
+	CreatorID  string              `json:"creatorID"`
+	CreatedAt  time.Time           `json:"createdAt"`
+}
+
+// TestCaseVersionListResponse 测试用例历史版本列表响应
+type TestCaseVersionListResponse struct {
+	Header
+	Data []TestCaseVersion `json:"data"`
+}
+
+// TestCaseVersionGetResponse 测试用例历史版本详情响应
+type TestCaseVersionGetResponse struct {
+	Header
+	Data *TestCaseVersion `json:"data"`
+}
+
+// TestCaseVersionRestoreRequest 恢复测试用例至指定版本，恢复后生成新的版本
+type TestCaseVersionRestoreRequest struct {
+	TestCaseID uint64 `json:"-"`
+	Version    uint64 `json:"-"`
+
+	IdentityInfo
+}
+
+// TestCaseReviewRequest 测试用例评审请求
+type TestCaseReviewRequest struct {
+	TestCaseID uint64               `json:"-"`
+	Action     TestCaseReviewAction `json:"action"`
+	Reviewers  []string             `json:"reviewers"` // 提交评审时指定
+
+	IdentityInfo
+}
+
+// TestCaseReviewResponse 测试用例评审响应
+type TestCaseReviewResponse struct {
+	Header
+	Data TestCaseReviewStatus `json:"data"`
+}
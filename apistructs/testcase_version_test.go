@@ -0,0 +1,74 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestCaseReviewAction_Transit(t *testing.T) {
+	tests := []struct {
+		action TestCaseReviewAction
+		from   TestCaseReviewStatus
+		want   TestCaseReviewStatus
+		ok     bool
+	}{
+		{TestCaseReviewActionSubmit, TestCaseReviewStatusNone, TestCaseReviewStatusInReview, true},
+		{TestCaseReviewActionSubmit, TestCaseReviewStatusDraft, TestCaseReviewStatusInReview, true},
+		{TestCaseReviewActionSubmit, TestCaseReviewStatusApproved, TestCaseReviewStatusInReview, true},
+		{TestCaseReviewActionSubmit, TestCaseReviewStatusInReview, TestCaseReviewStatusInReview, false},
+		{TestCaseReviewActionApprove, TestCaseReviewStatusInReview, TestCaseReviewStatusApproved, true},
+		{TestCaseReviewActionApprove, TestCaseReviewStatusDraft, TestCaseReviewStatusDraft, false},
+		{TestCaseReviewActionReject, TestCaseReviewStatusInReview, TestCaseReviewStatusDraft, true},
+		{TestCaseReviewActionReject, TestCaseReviewStatusApproved, TestCaseReviewStatusApproved, false},
+		{"unknown", TestCaseReviewStatusInReview, TestCaseReviewStatusInReview, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.action.Transit(tt.from)
+		assert.Equal(t, tt.want, got, "%s from %q", tt.action, tt.from)
+		assert.Equal(t, tt.ok, ok, "%s from %q", tt.action, tt.from)
+	}
+}
+
+func TestTestCaseReviewStatus_CanAddToTestPlan(t *testing.T) {
+	assert.True(t, TestCaseReviewStatusNone.CanAddToTestPlan())
+	assert.True(t, TestCaseReviewStatusApproved.CanAddToTestPlan())
+	assert.False(t, TestCaseReviewStatusDraft.CanAddToTestPlan())
+	assert.False(t, TestCaseReviewStatusInReview.CanAddToTestPlan())
+}
+
+func TestTestCaseSnapshot_Diff(t *testing.T) {
+	before := TestCaseSnapshot{
+		Name:           "login",
+		Priority:       TestCasePriorityP1,
+		StepAndResults: []TestCaseStepAndResult{{Step: "open", Result: "ok"}},
+	}
+	assert.Empty(t, before.Diff(before))
+
+	after := before
+	after.Priority = TestCasePriorityP0
+	after.StepAndResults = []TestCaseStepAndResult{{Step: "open", Result: "ok"}, {Step: "login", Result: "ok"}}
+	diffs := before.Diff(after)
+	assert.Equal(t, 2, len(diffs))
+	assert.Equal(t, TestCaseFieldDiff{Field: "priority", Before: "P1", After: "P0"}, diffs[0])
+	assert.Equal(t, "stepAndResults", diffs[1].Field)
+	assert.Equal(t, `[{"step":"open","result":"ok"}]`, diffs[1].Before)
+
+	// nil and empty steps are the same
+	empty := TestCaseSnapshot{StepAndResults: []TestCaseStepAndResult{}}
+	assert.Empty(t, TestCaseSnapshot{}.Diff(empty))
+}
@@ -72,7 +72,8 @@ type TestPlanCaseRelCreateResponse struct {
 	Data *TestPlanCaseRelCreateResult `json:"data,omitempty"`
 }
 type TestPlanCaseRelCreateResult struct {
-	TotalCount uint64 `json:"totalCount"`
+	TotalCount   uint64 `json:"totalCount"`
+	SkippedCount uint64 `json:"skippedCount"` // 未通过评审而跳过的用例数
 }
 
 type TestPlanCaseRelGetRequest struct {
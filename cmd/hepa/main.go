@@ -28,6 +28,7 @@ import (
 	_ "github.com/erda-project/erda/modules/hepa/providers/api_policy"
 	_ "github.com/erda-project/erda/modules/hepa/providers/domain"
 	_ "github.com/erda-project/erda/modules/hepa/providers/endpoint_api"
+	_ "github.com/erda-project/erda/modules/hepa/providers/gateway_config"
 	_ "github.com/erda-project/erda/modules/hepa/providers/global"
 	_ "github.com/erda-project/erda/modules/hepa/providers/legacy_consumer"
 	_ "github.com/erda-project/erda/modules/hepa/providers/legacy_upstream"
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// load-test is the entrypoint of the load-test action, which runs in its own job container,
+// the report is written into metafile and saved as pipeline report by the load-test-report aop plugin.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	_ "github.com/erda-project/erda-infra/base/version"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/apitestsv2/cookiejar"
+	"github.com/erda-project/erda/pkg/apitestsv2/loadtest"
+	"github.com/erda-project/erda/pkg/envconf"
+)
+
+const (
+	cookieJar = "cookieJar"
+
+	metaKeyLoadTestPassed = "load_test_passed" // true; false
+	metaKeyLoadTestReport = "load_test_report"
+)
+
+type envConfig struct {
+	MetaFile     string                            `env:"METAFILE"`
+	PipelineID   uint64                            `env:"PIPELINE_ID"`
+	SceneID      uint64                            `env:"ACTION_SCENE_ID"`
+	Steps        []apistructs.AutoTestLoadTestStep `env:"ACTION_STEPS" required:"true"`
+	Config       apistructs.AutoTestLoadTestConfig `env:"ACTION_CONFIG" required:"true"`
+	GlobalConfig *apistructs.AutoTestAPIConfig     `env:"AUTOTEST_API_GLOBAL_CONFIG"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
+
+	if err := run(); err != nil {
+		log.Printf("Load Test Failed: %v", err)
+		os.Exit(1)
+	}
+	log.Println("Load Test Success")
+}
+
+func run() error {
+	var cfg envConfig
+	if err := envconf.Load(&cfg); err != nil {
+		return fmt.Errorf("failed to parse action envs, err: %v", err)
+	}
+	if err := cfg.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid load test config, err: %v", err)
+	}
+	printConfig(cfg)
+
+	testEnv, caseParams := parseGlobalConfig(cfg.GlobalConfig)
+	cookies, err := parseCookieJar(testEnv)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal cookieJar from header, err: %v", err)
+	}
+
+	// stop virtual users when the job is canceled, the report is still written
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	runner := loadtest.New(cfg.Steps, cfg.Config,
+		loadtest.WithTestEnv(testEnv, caseParams),
+		loadtest.WithCookies(cookies),
+	)
+	report := runner.Run(ctx)
+	report.PipelineID = cfg.PipelineID
+	report.SceneID = cfg.SceneID
+	printReport(report)
+
+	if err := writeMetaFile(cfg.MetaFile, report); err != nil {
+		return fmt.Errorf("failed to write metafile, err: %v", err)
+	}
+	if !report.Passed {
+		return fmt.Errorf("thresholds not met")
+	}
+	return nil
+}
+
+// parseGlobalConfig convert global config of autotest to env data and case params.
+func parseGlobalConfig(globalConfig *apistructs.AutoTestAPIConfig) (*apistructs.APITestEnvData, map[string]*apistructs.CaseParams) {
+	caseParams := make(map[string]*apistructs.CaseParams)
+	if globalConfig == nil {
+		return nil, caseParams
+	}
+	testEnv := &apistructs.APITestEnvData{
+		Domain: globalConfig.Domain,
+		Header: globalConfig.Header,
+		Global: make(map[string]*apistructs.APITestEnvVariable),
+	}
+	for name, item := range globalConfig.Global {
+		testEnv.Global[name] = &apistructs.APITestEnvVariable{Value: item.Value, Type: item.Type}
+		caseParams[name] = &apistructs.CaseParams{Key: name, Type: item.Type, Value: item.Value}
+	}
+	return testEnv, caseParams
+}
+
+// parseCookieJar parse cookies kept by former steps from header.
+func parseCookieJar(testEnv *apistructs.APITestEnvData) (cookiejar.Cookies, error) {
+	var cookies cookiejar.Cookies
+	if testEnv != nil && len(testEnv.Header[cookieJar]) > 0 {
+		if err := json.Unmarshal([]byte(testEnv.Header[cookieJar]), &cookies); err != nil {
+			return nil, err
+		}
+	}
+	return cookies, nil
+}
+
+// writeMetaFile write report as metadata in key=value lines, value is one-line json.
+func writeMetaFile(metaFile string, report *apistructs.AutoTestLoadTestReport) error {
+	if metaFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	lines := []string{
+		metaKeyLoadTestPassed + "=" + strconv.FormatBool(report.Passed),
+		metaKeyLoadTestReport + "=" + string(b),
+	}
+	return ioutil.WriteFile(metaFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func printConfig(cfg envConfig) {
+	log.Printf("Load Test Config:")
+	log.Printf("virtual users: %d", cfg.Config.VirtualUsers)
+	log.Printf("ramp-up: %ds", cfg.Config.RampUpSec)
+	log.Printf("duration: %ds", cfg.Config.DurationSec)
+	log.Printf("think time: %dms", cfg.Config.ThinkTimeMs)
+	thresholds, _ := json.Marshal(cfg.Config.Thresholds)
+	log.Printf("thresholds: %s", thresholds)
+	log.Printf("steps:")
+	for _, step := range cfg.Steps {
+		log.Printf("  %d: %s", step.ID, step.Name)
+	}
+}
+
+func printReport(report *apistructs.AutoTestLoadTestReport) {
+	log.Printf("Load Test Report:")
+	printStat := func(s apistructs.AutoTestLoadTestStat) {
+		log.Printf("%-30s %10d %10d %9.2f%% %10.2f %10.2f %10.2f %10.2f %10.2f %10.2f",
+			s.Name, s.Requests, s.Errors, s.ErrorRate, s.Throughput, s.AvgMs, s.P50Ms, s.P90Ms, s.P95Ms, s.P99Ms)
+	}
+	log.Printf("%-30s %10s %10s %10s %10s %10s %10s %10s %10s %10s",
+		"step", "requests", "errors", "error%", "req/s", "avg(ms)", "p50(ms)", "p90(ms)", "p95(ms)", "p99(ms)")
+	for _, s := range report.Steps {
+		printStat(s)
+	}
+	printStat(report.Total)
+
+	if len(report.Violations) > 0 {
+		log.Printf("threshold violations:")
+		for _, v := range report.Violations {
+			log.Printf("  %s", v)
+		}
+	}
+}
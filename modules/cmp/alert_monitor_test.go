@@ -35,6 +35,21 @@ func (m *MockAlertServiceServer) EXPECT() *MockAlertServiceServerMockRecorder {
 	return m.recorder
 }
 
+// AcknowledgeAlertIncident mocks base method.
+func (m *MockAlertServiceServer) AcknowledgeAlertIncident(arg0 context.Context, arg1 *pb.AcknowledgeAlertIncidentRequest) (*pb.AcknowledgeAlertIncidentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcknowledgeAlertIncident", arg0, arg1)
+	ret0, _ := ret[0].(*pb.AcknowledgeAlertIncidentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcknowledgeAlertIncident indicates an expected call of AcknowledgeAlertIncident.
+func (mr *MockAlertServiceServerMockRecorder) AcknowledgeAlertIncident(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcknowledgeAlertIncident", reflect.TypeOf((*MockAlertServiceServer)(nil).AcknowledgeAlertIncident), arg0, arg1)
+}
+
 // CancelSuppressAlertEvent mocks base method.
 func (m *MockAlertServiceServer) CancelSuppressAlertEvent(arg0 context.Context, arg1 *pb.CancelSuppressAlertEventRequest) (*pb.CancelSuppressAlertEventResponse, error) {
 	m.ctrl.T.Helper()
@@ -215,6 +230,21 @@ func (mr *MockAlertServiceServerMockRecorder) DeleteOrgCustomizeAlert(arg0, arg1
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrgCustomizeAlert", reflect.TypeOf((*MockAlertServiceServer)(nil).DeleteOrgCustomizeAlert), arg0, arg1)
 }
 
+// ExportPrometheusRules mocks base method.
+func (m *MockAlertServiceServer) ExportPrometheusRules(arg0 context.Context, arg1 *pb.ExportPrometheusRulesRequest) (*pb.ExportPrometheusRulesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportPrometheusRules", arg0, arg1)
+	ret0, _ := ret[0].(*pb.ExportPrometheusRulesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportPrometheusRules indicates an expected call of ExportPrometheusRules.
+func (mr *MockAlertServiceServerMockRecorder) ExportPrometheusRules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportPrometheusRules", reflect.TypeOf((*MockAlertServiceServer)(nil).ExportPrometheusRules), arg0, arg1)
+}
+
 // GetAlert mocks base method.
 func (m *MockAlertServiceServer) GetAlert(arg0 context.Context, arg1 *pb.GetAlertRequest) (*pb.GetAlertResponse, error) {
 	m.ctrl.T.Helper()
@@ -290,6 +320,36 @@ func (mr *MockAlertServiceServerMockRecorder) GetAlertEvents(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertEvents", reflect.TypeOf((*MockAlertServiceServer)(nil).GetAlertEvents), arg0, arg1)
 }
 
+// GetAlertIncident mocks base method.
+func (m *MockAlertServiceServer) GetAlertIncident(arg0 context.Context, arg1 *pb.GetAlertIncidentRequest) (*pb.GetAlertIncidentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertIncident", arg0, arg1)
+	ret0, _ := ret[0].(*pb.GetAlertIncidentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertIncident indicates an expected call of GetAlertIncident.
+func (mr *MockAlertServiceServerMockRecorder) GetAlertIncident(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertIncident", reflect.TypeOf((*MockAlertServiceServer)(nil).GetAlertIncident), arg0, arg1)
+}
+
+// GetAlertIncidentPolicy mocks base method.
+func (m *MockAlertServiceServer) GetAlertIncidentPolicy(arg0 context.Context, arg1 *pb.GetAlertIncidentPolicyRequest) (*pb.GetAlertIncidentPolicyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertIncidentPolicy", arg0, arg1)
+	ret0, _ := ret[0].(*pb.GetAlertIncidentPolicyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertIncidentPolicy indicates an expected call of GetAlertIncidentPolicy.
+func (mr *MockAlertServiceServerMockRecorder) GetAlertIncidentPolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertIncidentPolicy", reflect.TypeOf((*MockAlertServiceServer)(nil).GetAlertIncidentPolicy), arg0, arg1)
+}
+
 // GetAlertRecord mocks base method.
 func (m *MockAlertServiceServer) GetAlertRecord(arg0 context.Context, arg1 *pb.GetAlertRecordRequest) (*pb.GetAlertRecordResponse, error) {
 	m.ctrl.T.Helper()
@@ -425,6 +485,21 @@ func (mr *MockAlertServiceServerMockRecorder) GetRawAlertExpression(arg0, arg1 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRawAlertExpression", reflect.TypeOf((*MockAlertServiceServer)(nil).GetRawAlertExpression), arg0, arg1)
 }
 
+// ImportPrometheusRules mocks base method.
+func (m *MockAlertServiceServer) ImportPrometheusRules(arg0 context.Context, arg1 *pb.ImportPrometheusRulesRequest) (*pb.ImportPrometheusRulesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportPrometheusRules", arg0, arg1)
+	ret0, _ := ret[0].(*pb.ImportPrometheusRulesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportPrometheusRules indicates an expected call of ImportPrometheusRules.
+func (mr *MockAlertServiceServerMockRecorder) ImportPrometheusRules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportPrometheusRules", reflect.TypeOf((*MockAlertServiceServer)(nil).ImportPrometheusRules), arg0, arg1)
+}
+
 // QueryAlert mocks base method.
 func (m *MockAlertServiceServer) QueryAlert(arg0 context.Context, arg1 *pb.QueryAlertRequest) (*pb.QueryAlertsResponse, error) {
 	m.ctrl.T.Helper()
@@ -455,6 +530,21 @@ func (mr *MockAlertServiceServerMockRecorder) QueryAlertHistory(arg0, arg1 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAlertHistory", reflect.TypeOf((*MockAlertServiceServer)(nil).QueryAlertHistory), arg0, arg1)
 }
 
+// QueryAlertIncidents mocks base method.
+func (m *MockAlertServiceServer) QueryAlertIncidents(arg0 context.Context, arg1 *pb.QueryAlertIncidentsRequest) (*pb.QueryAlertIncidentsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAlertIncidents", arg0, arg1)
+	ret0, _ := ret[0].(*pb.QueryAlertIncidentsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAlertIncidents indicates an expected call of QueryAlertIncidents.
+func (mr *MockAlertServiceServerMockRecorder) QueryAlertIncidents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAlertIncidents", reflect.TypeOf((*MockAlertServiceServer)(nil).QueryAlertIncidents), arg0, arg1)
+}
+
 // QueryAlertRecord mocks base method.
 func (m *MockAlertServiceServer) QueryAlertRecord(arg0 context.Context, arg1 *pb.QueryAlertRecordRequest) (*pb.QueryAlertRecordResponse, error) {
 	m.ctrl.T.Helper()
@@ -680,6 +770,21 @@ func (mr *MockAlertServiceServerMockRecorder) QueryOrgHostsAlertRecord(arg0, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOrgHostsAlertRecord", reflect.TypeOf((*MockAlertServiceServer)(nil).QueryOrgHostsAlertRecord), arg0, arg1)
 }
 
+// ResolveAlertIncident mocks base method.
+func (m *MockAlertServiceServer) ResolveAlertIncident(arg0 context.Context, arg1 *pb.ResolveAlertIncidentRequest) (*pb.ResolveAlertIncidentResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveAlertIncident", arg0, arg1)
+	ret0, _ := ret[0].(*pb.ResolveAlertIncidentResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveAlertIncident indicates an expected call of ResolveAlertIncident.
+func (mr *MockAlertServiceServerMockRecorder) ResolveAlertIncident(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveAlertIncident", reflect.TypeOf((*MockAlertServiceServer)(nil).ResolveAlertIncident), arg0, arg1)
+}
+
 // SuppressAlertEvent mocks base method.
 func (m *MockAlertServiceServer) SuppressAlertEvent(arg0 context.Context, arg1 *pb.SuppressAlertEventRequest) (*pb.SuppressAlertEventResponse, error) {
 	m.ctrl.T.Helper()
@@ -725,6 +830,21 @@ func (mr *MockAlertServiceServerMockRecorder) UpdateAlertEnable(arg0, arg1 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAlertEnable", reflect.TypeOf((*MockAlertServiceServer)(nil).UpdateAlertEnable), arg0, arg1)
 }
 
+// UpdateAlertIncidentPolicy mocks base method.
+func (m *MockAlertServiceServer) UpdateAlertIncidentPolicy(arg0 context.Context, arg1 *pb.UpdateAlertIncidentPolicyRequest) (*pb.UpdateAlertIncidentPolicyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAlertIncidentPolicy", arg0, arg1)
+	ret0, _ := ret[0].(*pb.UpdateAlertIncidentPolicyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAlertIncidentPolicy indicates an expected call of UpdateAlertIncidentPolicy.
+func (mr *MockAlertServiceServerMockRecorder) UpdateAlertIncidentPolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAlertIncidentPolicy", reflect.TypeOf((*MockAlertServiceServer)(nil).UpdateAlertIncidentPolicy), arg0, arg1)
+}
+
 // UpdateAlertIssue mocks base method.
 func (m *MockAlertServiceServer) UpdateAlertIssue(arg0 context.Context, arg1 *pb.UpdateAlertIssueRequest) (*pb.UpdateAlertIssueResponse, error) {
 	m.ctrl.T.Helper()
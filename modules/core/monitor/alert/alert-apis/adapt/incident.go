@@ -0,0 +1,391 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/alert/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/db"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/incident"
+)
+
+const incidentEscalationBatchSize = 500
+
+var incidentEscalationNotifyItem = &apistructs.NotifyItem{
+	Name:             "alert_incident_escalation",
+	DisplayName:      "Alert Incident Escalation",
+	Category:         "alert",
+	MarkdownTemplate: "## 【{{alertLevel}}】{{title}}\n\n- state: {{state}}\n- events: {{eventCount}}\n- first triggered: {{firstEventTime}}\n- assignee: {{assignee}}\n\nThe incident has not been acknowledged for {{afterMinutes}} minutes.",
+	MobileTemplate:   "【{{alertLevel}}】{{title}} has not been acknowledged for {{afterMinutes}} minutes.",
+	VMSTemplate:      "{{title}} has not been acknowledged for {{afterMinutes}} minutes.",
+}
+
+// OnCallFunc returns the user on call of the notify group.
+type OnCallFunc func(notifyGroupID int64) (string, error)
+
+// GetIncidentPolicy returns the incident policy of the scope, or the default one if it's not set.
+func (a *Adapt) GetIncidentPolicy(scope, scopeID string) (*pb.AlertIncidentPolicy, error) {
+	policy, err := a.db.AlertIncidentPolicy.GetByScope(scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return &pb.AlertIncidentPolicy{
+			Scope:     scope,
+			ScopeId:   scopeID,
+			GroupKeys: incident.DefaultGroupKeys,
+		}, nil
+	}
+	return toPBIncidentPolicy(policy)
+}
+
+// UpdateIncidentPolicy .
+func (a *Adapt) UpdateIncidentPolicy(req *pb.UpdateAlertIncidentPolicyRequest, orgID int64, userID string) (*pb.AlertIncidentPolicy, error) {
+	if len(req.Scope) == 0 || len(req.ScopeId) == 0 {
+		return nil, invalidParameter("scope and scopeId must not be empty")
+	}
+	if len(req.GroupKeys) == 0 {
+		req.GroupKeys = incident.DefaultGroupKeys
+	}
+	if err := incident.ValidateGroupKeys(req.GroupKeys); err != nil {
+		return nil, invalidParameter(err.Error())
+	}
+	if req.GroupWindow < 0 {
+		return nil, invalidParameter("groupWindow must not be negative")
+	}
+	steps := make([]*incident.EscalationStep, 0, len(req.Escalation))
+	for _, item := range req.Escalation {
+		steps = append(steps, &incident.EscalationStep{
+			AfterMinutes:  item.AfterMinutes,
+			NotifyGroupID: item.NotifyGroupId,
+			Channels:      item.Channels,
+		})
+	}
+	if err := incident.ValidateEscalation(steps); err != nil {
+		return nil, invalidParameter(err.Error())
+	}
+	escalation, err := json.Marshal(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := a.db.AlertIncidentPolicy.GetByScope(req.Scope, req.ScopeId)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = &db.AlertIncidentPolicy{
+			OrgID:   orgID,
+			Scope:   req.Scope,
+			ScopeID: req.ScopeId,
+			Creator: userID,
+		}
+	}
+	policy.GroupKeys = strings.Join(req.GroupKeys, ",")
+	policy.GroupWindow = req.GroupWindow
+	policy.NotifyGroupID = req.NotifyGroupId
+	policy.Escalation = string(escalation)
+	if err := a.db.AlertIncidentPolicy.Save(policy); err != nil {
+		return nil, err
+	}
+	return toPBIncidentPolicy(policy)
+}
+
+// QueryIncidents .
+func (a *Adapt) QueryIncidents(req *pb.QueryAlertIncidentsRequest) ([]*pb.AlertIncident, int64, error) {
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	list, total, err := a.db.AlertIncident.QueryByCondition(req.Scope, req.ScopeId, &db.AlertIncidentQueryCondition{
+		States:      req.States,
+		AlertLevels: req.AlertLevels,
+		Assignee:    req.Assignee,
+	}, req.PageNo, req.PageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]*pb.AlertIncident, 0, len(list))
+	for _, item := range list {
+		result = append(result, toPBIncident(item))
+	}
+	return result, total, nil
+}
+
+// GetIncident returns the incident and its events, it returns nil if the incident does not exist.
+func (a *Adapt) GetIncident(id string) (*pb.AlertIncident, []*pb.AlertEventItem, error) {
+	data, err := a.db.AlertIncident.GetByID(id)
+	if err != nil || data == nil {
+		return nil, nil, err
+	}
+	ids, err := a.db.AlertIncidentEvent.QueryEventIDs(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var events []*pb.AlertEventItem
+	if len(ids) > 0 {
+		list, err := a.db.AlertEventDB.QueryByCondition(data.Scope, data.ScopeID, &db.AlertEventQueryCondition{Ids: ids},
+			[]*db.AlertEventSort{{SortField: "LastTriggerTime", Descending: true}}, 1, int64(len(ids)))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, event := range list {
+			events = append(events, toPBIncidentEvent(event))
+		}
+	}
+	return toPBIncident(data), events, nil
+}
+
+// AcknowledgeIncident marks the incident and its alerting events as acknowledged,
+// the escalation of the incident stops after it.
+func (a *Adapt) AcknowledgeIncident(id, userID string) (*pb.AlertIncident, error) {
+	return a.transitIncident(id, incident.StateAcknowledged, []string{incident.EventStateAlert}, incident.EventStateAcknowledged,
+		func(data *db.AlertIncident, now time.Time) map[string]interface{} {
+			fields := map[string]interface{}{
+				"acknowledged_by": userID,
+				"acknowledged_at": now,
+			}
+			if len(data.Assignee) == 0 {
+				fields["assignee"] = userID
+			}
+			return fields
+		})
+}
+
+// ResolveIncident marks the incident and its unrecovered events as resolved.
+func (a *Adapt) ResolveIncident(id, userID string) (*pb.AlertIncident, error) {
+	return a.transitIncident(id, incident.StateResolved, []string{incident.EventStateAlert, incident.EventStateAcknowledged}, incident.EventStateResolved,
+		func(data *db.AlertIncident, now time.Time) map[string]interface{} {
+			return map[string]interface{}{
+				"resolved_by": userID,
+				"resolved_at": now,
+			}
+		})
+}
+
+// transitIncident moves the incident to state only if it is still in the state read, so concurrent transitions
+// can not override each other, then moves its events in eventStates to eventState.
+func (a *Adapt) transitIncident(id, state string, eventStates []string, eventState string,
+	update func(data *db.AlertIncident, now time.Time) map[string]interface{}) (*pb.AlertIncident, error) {
+	data, err := a.db.AlertIncident.GetByID(id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	if !incident.CanTransit(data.State, state) {
+		return nil, invalidParameter("incident is %s, can not be %s", data.State, state)
+	}
+	fields := update(data, time.Now())
+	fields["state"] = state
+	ok, err := a.db.AlertIncident.Transit(id, data.State, fields)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, invalidParameter("incident is changed by others, please retry")
+	}
+	ids, err := a.db.AlertIncidentEvent.QueryEventIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.db.AlertEventDB.UpdateStateByIds(ids, eventStates, eventState); err != nil {
+		return nil, err
+	}
+	data, err = a.db.AlertIncident.GetByID(id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return toPBIncident(data), nil
+}
+
+// EscalateIncidents notifies the escalation groups of the incidents which are not acknowledged in time,
+// and assigns the incidents without assignee to the user on call.
+func (a *Adapt) EscalateIncidents(now time.Time, onCall OnCallFunc) error {
+	policies := make(map[string]*db.AlertIncidentPolicy)
+	var afterID string
+	for {
+		list, err := a.db.AlertIncident.QueryTriggered(afterID, incidentEscalationBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range list {
+			afterID = item.Id
+			if len(item.PolicyID) == 0 {
+				continue
+			}
+			policy, ok := policies[item.PolicyID]
+			if !ok {
+				policy, err = a.db.AlertIncidentPolicy.GetByID(item.PolicyID)
+				if err != nil {
+					return err
+				}
+				policies[item.PolicyID] = policy
+			}
+			if policy == nil {
+				continue
+			}
+			if err := a.escalateIncident(item, policy, now, onCall); err != nil {
+				a.l.Errorf("failed to escalate incident %s: %s", item.Id, err)
+			}
+		}
+		if len(list) < incidentEscalationBatchSize {
+			return nil
+		}
+	}
+}
+
+func (a *Adapt) escalateIncident(data *db.AlertIncident, policy *db.AlertIncidentPolicy, now time.Time, onCall OnCallFunc) error {
+	fields := make(map[string]interface{})
+	if len(data.Assignee) == 0 && policy.NotifyGroupID > 0 && onCall != nil {
+		user, err := onCall(policy.NotifyGroupID)
+		if err != nil {
+			return err
+		}
+		if len(user) > 0 {
+			data.Assignee = user
+			fields["assignee"] = user
+		}
+	}
+	var steps []*incident.EscalationStep
+	if len(policy.Escalation) > 0 {
+		if err := json.Unmarshal([]byte(policy.Escalation), &steps); err != nil {
+			return err
+		}
+	}
+	if len(fields) > 0 {
+		if err := a.db.AlertIncident.Update(data.Id, fields); err != nil {
+			return err
+		}
+	}
+	idx, ok := incident.NextEscalation(steps, data.EscalationStep, data.FirstEventTime, now)
+	if !ok {
+		return nil
+	}
+	// every replica runs the escalation, only the one claimed the step sends the notification
+	claimed, err := a.db.AlertIncident.ClaimEscalation(data.Id, data.EscalationStep, idx+1, now)
+	if err != nil || !claimed {
+		return err
+	}
+	return a.notifyIncidentEscalation(data, steps[idx])
+}
+
+func (a *Adapt) notifyIncidentEscalation(data *db.AlertIncident, step *incident.EscalationStep) error {
+	return a.bdl.CreateGroupNotifyEvent(apistructs.EventBoxGroupNotifyRequest{
+		Sender:     "monitor-alert",
+		GroupID:    step.NotifyGroupID,
+		NotifyItem: incidentEscalationNotifyItem,
+		Channels:   strings.Join(step.Channels, ","),
+		NotifyContent: &apistructs.GroupNotifyContent{
+			SourceName:            data.Title,
+			SourceType:            data.Scope,
+			SourceID:              data.ScopeID,
+			NotifyName:            incidentEscalationNotifyItem.Name,
+			NotifyItemDisplayName: incidentEscalationNotifyItem.DisplayName,
+			OrgID:                 data.OrgID,
+			Label:                 incidentEscalationNotifyItem.Category,
+		},
+		Params: map[string]string{
+			"title":          data.Title,
+			"alertLevel":     data.AlertLevel,
+			"state":          data.State,
+			"assignee":       data.Assignee,
+			"eventCount":     strconv.FormatInt(data.EventCount, 10),
+			"firstEventTime": data.FirstEventTime.Format("2006-01-02 15:04:05"),
+			"afterMinutes":   strconv.FormatInt(step.AfterMinutes, 10),
+		},
+	})
+}
+
+func toPBIncidentPolicy(policy *db.AlertIncidentPolicy) (*pb.AlertIncidentPolicy, error) {
+	var steps []*incident.EscalationStep
+	if len(policy.Escalation) > 0 {
+		if err := json.Unmarshal([]byte(policy.Escalation), &steps); err != nil {
+			return nil, fmt.Errorf("invalid escalation of incident policy %s: %s", policy.Id, err)
+		}
+	}
+	result := &pb.AlertIncidentPolicy{
+		Id:            policy.Id,
+		Scope:         policy.Scope,
+		ScopeId:       policy.ScopeID,
+		GroupKeys:     strings.Split(policy.GroupKeys, ","),
+		GroupWindow:   policy.GroupWindow,
+		NotifyGroupId: policy.NotifyGroupID,
+	}
+	for _, step := range steps {
+		result.Escalation = append(result.Escalation, &pb.AlertIncidentEscalation{
+			AfterMinutes:  step.AfterMinutes,
+			NotifyGroupId: step.NotifyGroupID,
+			Channels:      step.Channels,
+		})
+	}
+	return result, nil
+}
+
+func toPBIncident(data *db.AlertIncident) *pb.AlertIncident {
+	result := &pb.AlertIncident{
+		Id:             data.Id,
+		Scope:          data.Scope,
+		ScopeId:        data.ScopeID,
+		Title:          data.Title,
+		AlertLevel:     data.AlertLevel,
+		State:          data.State,
+		Assignee:       data.Assignee,
+		EscalationStep: int64(data.EscalationStep),
+		AcknowledgedBy: data.AcknowledgedBy,
+		ResolvedBy:     data.ResolvedBy,
+		FirstEventTime: data.FirstEventTime.UnixNano() / 1e6,
+		LastEventTime:  data.LastEventTime.UnixNano() / 1e6,
+		EventCount:     data.EventCount,
+	}
+	if len(data.AcknowledgedBy) > 0 {
+		result.AcknowledgedAt = data.AcknowledgedAt.UnixNano() / 1e6
+	}
+	if data.State == incident.StateResolved {
+		result.ResolvedAt = data.ResolvedAt.UnixNano() / 1e6
+	}
+	return result
+}
+
+func toPBIncidentEvent(event *db.AlertEvent) *pb.AlertEventItem {
+	return &pb.AlertEventItem{
+		Id:               event.Id,
+		Name:             event.Name,
+		OrgID:            event.OrgID,
+		AlertGroupID:     event.AlertGroupID,
+		AlertGroup:       event.AlertGroup,
+		Scope:            event.Scope,
+		ScopeId:          event.ScopeID,
+		AlertID:          event.AlertID,
+		AlertName:        event.AlertName,
+		AlertType:        event.AlertType,
+		AlertIndex:       event.AlertIndex,
+		AlertLevel:       event.AlertLevel,
+		AlertSource:      event.AlertSource,
+		AlertSubject:     event.AlertSubject,
+		AlertState:       event.AlertState,
+		RuleID:           event.RuleID,
+		RuleName:         event.RuleName,
+		ExpressionID:     event.ExpressionID,
+		LastTriggerTime:  event.LastTriggerTime.UnixNano() / 1e6,
+		FirstTriggerTime: event.FirstTriggerTime.UnixNano() / 1e6,
+	}
+}
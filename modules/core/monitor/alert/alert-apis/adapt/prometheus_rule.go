@@ -0,0 +1,404 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/alert/pb"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/promrule"
+)
+
+// prometheus rule import status
+const (
+	PrometheusRuleCreated     = "created"
+	PrometheusRuleReady       = "ready"
+	PrometheusRuleExists      = "exists"
+	PrometheusRuleUnsupported = "unsupported"
+	PrometheusRuleFailed      = "failed"
+)
+
+const (
+	// the job label of prometheus is the metric name of erda, the prometheus metric name is the field
+	prometheusJobLabel = "job"
+
+	prometheusGroupAttr  = "prometheus_group"
+	prometheusForAttr    = "prometheus_for"
+	prometheusLabelsAttr = "prometheus_labels"
+
+	prometheusDefaultGroup = "erda-customize-alerts"
+)
+
+// ImportPrometheusRules creates customize alerts from a prometheus rule file, one alert per alerting rule.
+// With dryRun, nothing is created and the report tells which rules can be imported.
+func (a *Adapt) ImportPrometheusRules(req *pb.ImportPrometheusRulesRequest, userID string) (*pb.PrometheusRulesImportReport, error) {
+	file, err := promrule.Parse([]byte(req.Content))
+	if err != nil {
+		return nil, invalidParameter("%s", err)
+	}
+	report := &pb.PrometheusRulesImportReport{DryRun: req.DryRun}
+	names := make(map[string]bool)
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			report.Total++
+			result := &pb.PrometheusRuleImportResult{
+				Group: group.Name,
+				Alert: rule.Alert,
+				Expr:  rule.Expr,
+			}
+			report.Results = append(report.Results, result)
+			if rule.Record != "" {
+				result.Alert = rule.Record
+				result.Status = PrometheusRuleUnsupported
+				result.Reason = "recording rules are not supported"
+				report.Unsupported++
+				continue
+			}
+			alert, warnings, err := a.prometheusRuleToCustomizeAlert(req, group.Name, rule)
+			result.Warnings = warnings
+			if err != nil {
+				result.Status = PrometheusRuleUnsupported
+				result.Reason = err.Error()
+				report.Unsupported++
+				continue
+			}
+			if names[rule.Alert] {
+				result.Status = PrometheusRuleExists
+				result.Reason = "duplicate alert name in rule file"
+				continue
+			}
+			names[rule.Alert] = true
+			exist, err := a.db.CustomizeAlert.GetByScopeAndScopeIDAndName(req.AlertScope, req.AlertScopeId, rule.Alert)
+			if err != nil {
+				result.Status = PrometheusRuleFailed
+				result.Reason = err.Error()
+				continue
+			}
+			if exist != nil {
+				result.Status = PrometheusRuleExists
+				result.AlertId = exist.ID
+				continue
+			}
+			if req.DryRun {
+				result.Status = PrometheusRuleReady
+				continue
+			}
+			id, err := a.CreateCustomizeAlert(alert, userID)
+			if err != nil {
+				result.Status = PrometheusRuleFailed
+				result.Reason = err.Error()
+				continue
+			}
+			result.Status = PrometheusRuleCreated
+			result.AlertId = id
+			report.Imported++
+		}
+	}
+	return report, nil
+}
+
+func (a *Adapt) prometheusRuleToCustomizeAlert(req *pb.ImportPrometheusRulesRequest, group string, rule *promrule.Rule) (*pb.CustomizeAlertDetail, []string, error) {
+	var warnings []string
+	cond, err := promrule.ParseExpr(rule.Expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metric := req.DefaultMetric
+	var filters []*pb.CustomizeAlertRuleFilter
+	for _, m := range cond.Matchers {
+		if m.Label == prometheusJobLabel && m.Op == "eq" {
+			metric = m.Value
+			continue
+		}
+		filters = append(filters, &pb.CustomizeAlertRuleFilter{
+			Tag:      m.Label,
+			Operator: m.Op,
+			Value:    structpb.NewStringValue(m.Value),
+		})
+	}
+	if metric == "" {
+		return nil, nil, fmt.Errorf("metric is unknown, add a %s matcher to the expression or set the default metric", prometheusJobLabel)
+	}
+
+	window := cond.Window
+	if rule.For != "" {
+		pending, err := promrule.ParseDuration(rule.For)
+		if err != nil {
+			return nil, nil, err
+		}
+		if window <= 0 {
+			window = pending
+			warnings = append(warnings, fmt.Sprintf("for %s is used as the aggregation window", rule.For))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("for %s has no equivalent and is only kept for export", rule.For))
+		}
+	}
+	minutes := uint64((window + time.Minute - 1) / time.Minute)
+	if minutes == 0 {
+		minutes = 1
+	}
+	if time.Duration(minutes)*time.Minute != window {
+		warnings = append(warnings, fmt.Sprintf("window %s is rounded up to %d minutes", promrule.FormatDuration(window), minutes))
+	}
+
+	targets := append([]string{}, req.NotifyTargets...)
+	hasTicket := false
+	for _, target := range targets {
+		if !notifyTargetSet[target] {
+			return nil, nil, invalidParameter("not support notify target %s", target)
+		}
+		hasTicket = hasTicket || target == "ticket"
+	}
+	if !hasTicket {
+		targets = append(targets, "ticket")
+	}
+
+	valueKey := cond.Metric
+	title, content := rule.Annotations["summary"], rule.Annotations["description"]
+	if title == "" {
+		title = rule.Alert
+	}
+	if content == "" {
+		content = title
+	}
+	title, unsupported := promrule.ToNotifyTemplate(title, valueKey)
+	content, unsupportedContent := promrule.ToNotifyTemplate(content, valueKey)
+	for _, action := range append(unsupported, unsupportedContent...) {
+		warnings = append(warnings, fmt.Sprintf("template action %s is not translated", action))
+	}
+	for key := range rule.Annotations {
+		if key != "summary" && key != "description" {
+			warnings = append(warnings, fmt.Sprintf("annotation %s is dropped", key))
+		}
+	}
+	sort.Strings(warnings)
+
+	attributes := map[string]*structpb.Value{
+		prometheusGroupAttr: structpb.NewStringValue(group),
+	}
+	if rule.For != "" {
+		attributes[prometheusForAttr] = structpb.NewStringValue(rule.For)
+	}
+	if len(rule.Labels) > 0 {
+		labels := make(map[string]interface{})
+		for k, v := range rule.Labels {
+			labels[k] = v
+		}
+		value, err := structpb.NewValue(labels)
+		if err != nil {
+			return nil, nil, err
+		}
+		attributes[prometheusLabelsAttr] = value
+	}
+
+	alertType := req.AlertType
+	if alertType == "" {
+		alertType = customizeAlertTypeMicroService
+		if req.AlertScope == orgScope {
+			alertType = customizeAlertTypeOrg
+		}
+	}
+	return &pb.CustomizeAlertDetail{
+		Name:         rule.Alert,
+		AlertType:    alertType,
+		AlertScope:   req.AlertScope,
+		AlertScopeId: req.AlertScopeId,
+		Enable:       true,
+		Attributes:   attributes,
+		Rules: []*pb.CustomizeAlertRule{{
+			Name:   rule.Alert,
+			Metric: metric,
+			Window: minutes,
+			Functions: []*pb.CustomizeAlertRuleFunction{{
+				Field:      cond.Metric,
+				Alias:      valueKey,
+				Aggregator: cond.Aggregator,
+				Operator:   cond.Operator,
+				Value:      structpb.NewNumberValue(cond.Threshold),
+			}},
+			Filters: filters,
+			Group:   cond.Group,
+			Outputs: []string{"alert"},
+		}},
+		Notifies: []*pb.CustomizeAlertNotifyTemplates{{
+			Name:    rule.Alert,
+			Targets: targets,
+			Title:   title,
+			Content: content,
+		}},
+	}, warnings, nil
+}
+
+// ExportPrometheusRules exports the customize alerts of the scope as a prometheus rule file.
+// The alerts which can not be expressed in PromQL are returned as skipped.
+func (a *Adapt) ExportPrometheusRules(scope, scopeID string) (*pb.PrometheusRulesExport, error) {
+	alerts, err := a.db.CustomizeAlert.QueryAllByScopeAndScopeID(scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	result := &pb.PrometheusRulesExport{}
+	file := &promrule.RuleFile{}
+	groups := make(map[string]*promrule.RuleGroup)
+	for _, item := range alerts {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, &pb.PrometheusRuleImportResult{
+				Alert:   item.Name,
+				AlertId: item.ID,
+				Status:  PrometheusRuleUnsupported,
+				Reason:  reason,
+			})
+		}
+		if !item.Enable {
+			skip("alert is disabled")
+			continue
+		}
+		alert, err := a.CustomizeAlertDetail(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if alert == nil {
+			skip("alert has no rule or notify")
+			continue
+		}
+		for _, rule := range alert.Rules {
+			expr, valueKey, err := customizeAlertRuleToPromExpr(rule)
+			if err != nil {
+				skip(err.Error())
+				continue
+			}
+			promRule := &promrule.Rule{
+				Alert: alert.Name,
+				Expr:  expr,
+			}
+			if len(alert.Rules) > 1 {
+				promRule.Alert = rule.Name
+			}
+			if v, ok := item.Attributes[prometheusForAttr].(string); ok {
+				promRule.For = v
+			}
+			if labels, ok := item.Attributes[prometheusLabelsAttr].(map[string]interface{}); ok {
+				promRule.Labels = make(map[string]string)
+				for k, v := range labels {
+					promRule.Labels[k] = fmt.Sprint(v)
+				}
+			}
+			if len(alert.Notifies) > 0 {
+				promRule.Annotations = map[string]string{
+					"summary":     promrule.FromNotifyTemplate(alert.Notifies[0].Title, valueKey),
+					"description": promrule.FromNotifyTemplate(alert.Notifies[0].Content, valueKey),
+				}
+			}
+			groupName := prometheusDefaultGroup
+			if v, ok := item.Attributes[prometheusGroupAttr].(string); ok && v != "" {
+				groupName = v
+			}
+			group, ok := groups[groupName]
+			if !ok {
+				group = &promrule.RuleGroup{Name: groupName}
+				groups[groupName] = group
+				file.Groups = append(file.Groups, group)
+			}
+			group.Rules = append(group.Rules, promRule)
+		}
+	}
+	content, err := file.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	result.Content = string(content)
+	return result, nil
+}
+
+// customizeAlertRuleToPromExpr returns the PromQL of the rule and the template key of its value.
+func customizeAlertRuleToPromExpr(rule *pb.CustomizeAlertRule) (string, string, error) {
+	if len(rule.Functions) != 1 {
+		return "", "", fmt.Errorf("rule with %d functions is not supported", len(rule.Functions))
+	}
+	function := rule.Functions[0]
+	var threshold float64
+	switch v := function.Value.AsInterface().(type) {
+	case float64:
+		threshold = v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", "", fmt.Errorf("threshold %q is not a number", v)
+		}
+		threshold = f
+	default:
+		return "", "", fmt.Errorf("threshold %v is not a number", v)
+	}
+
+	matchers := []*promrule.Matcher{{Label: prometheusJobLabel, Op: "eq", Value: rule.Metric}}
+	for _, filter := range rule.Filters {
+		matcher := &promrule.Matcher{Label: filter.Tag, Op: filter.Operator}
+		switch filter.Operator {
+		case "eq", "neq", "match", "notMatch":
+			matcher.Value = fmt.Sprint(filter.Value.AsInterface())
+		case "in", "notIn":
+			var values []string
+			if list := filter.Value.GetListValue(); list != nil {
+				for _, v := range list.Values {
+					values = append(values, regexp.QuoteMeta(fmt.Sprint(v.AsInterface())))
+				}
+			} else {
+				for _, v := range strings.Split(filter.Value.GetStringValue(), ",") {
+					values = append(values, regexp.QuoteMeta(v))
+				}
+			}
+			matcher.Value = strings.Join(values, "|")
+			matcher.Op = "match"
+			if filter.Operator == "notIn" {
+				matcher.Op = "notMatch"
+			}
+		case "like":
+			matcher.Value = ".*" + regexp.QuoteMeta(filter.Value.GetStringValue()) + ".*"
+			matcher.Op = "match"
+		case any:
+			continue
+		default:
+			return "", "", fmt.Errorf("filter operator %s is not supported", filter.Operator)
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	field := strings.TrimPrefix(function.Field, "fields.")
+	cond := &promrule.Condition{
+		Metric:     field,
+		Matchers:   matchers,
+		Window:     time.Duration(rule.Window) * time.Minute,
+		Aggregator: function.Aggregator,
+		Group:      rule.Group,
+		Operator:   function.Operator,
+		Threshold:  threshold,
+	}
+	expr, err := cond.String()
+	if err != nil {
+		return "", "", err
+	}
+	valueKey := function.Alias
+	if valueKey == "" {
+		valueKey = field
+	}
+	return expr, valueKey, nil
+}
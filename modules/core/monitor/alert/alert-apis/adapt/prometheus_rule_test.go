@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapt
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/alert/pb"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/promrule"
+)
+
+func Test_prometheusRuleToCustomizeAlert(t *testing.T) {
+	a := &Adapt{}
+	req := &pb.ImportPrometheusRulesRequest{
+		AlertScope:    "micro_service",
+		AlertScopeId:  "tk",
+		NotifyTargets: []string{"dingding"},
+	}
+	rule := &promrule.Rule{
+		Alert: "HighLatency",
+		Expr:  `avg by (service_name) (avg_over_time(elapsed_mean{job="application_http", http_path=~"/api/.*"}[5m])) > 500`,
+		For:   "10m",
+		Labels: map[string]string{
+			"severity": "page",
+		},
+		Annotations: map[string]string{
+			"summary": "{{ $labels.service_name }} latency is {{ $value }}",
+		},
+	}
+	alert, warnings, err := a.prometheusRuleToCustomizeAlert(req, "api", rule)
+	if err != nil {
+		t.Fatalf("prometheusRuleToCustomizeAlert() error: %s", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("prometheusRuleToCustomizeAlert() warnings = %v", warnings)
+	}
+	if alert.AlertType != customizeAlertTypeMicroService || alert.Attributes[prometheusForAttr].GetStringValue() != "10m" {
+		t.Errorf("prometheusRuleToCustomizeAlert() alert = %+v", alert)
+	}
+	r := alert.Rules[0]
+	if r.Metric != "application_http" || r.Window != 5 || len(r.Filters) != 1 || r.Filters[0].Operator != "match" {
+		t.Errorf("prometheusRuleToCustomizeAlert() rule = %+v", r)
+	}
+	f := r.Functions[0]
+	if f.Field != "elapsed_mean" || f.Aggregator != "avg" || f.Operator != "gt" || f.Value.GetNumberValue() != 500 {
+		t.Errorf("prometheusRuleToCustomizeAlert() function = %+v", f)
+	}
+	notify := alert.Notifies[0]
+	if notify.Title != "{{service_name}} latency is {{elapsed_mean}}" || len(notify.Targets) != 2 {
+		t.Errorf("prometheusRuleToCustomizeAlert() notify = %+v", notify)
+	}
+
+	rule.Expr = `rate(elapsed_count{job="application_http"}[5m]) > 1`
+	if _, _, err := a.prometheusRuleToCustomizeAlert(req, "api", rule); err == nil {
+		t.Errorf("prometheusRuleToCustomizeAlert() with rate should fail")
+	}
+	rule.Expr = `elapsed_count > 1`
+	if _, _, err := a.prometheusRuleToCustomizeAlert(req, "api", rule); err == nil {
+		t.Errorf("prometheusRuleToCustomizeAlert() without metric should fail")
+	}
+}
+
+func Test_customizeAlertRuleToPromExpr(t *testing.T) {
+	values, _ := structpb.NewList([]interface{}{"a.b", "c"})
+	rule := &pb.CustomizeAlertRule{
+		Metric: "application_http",
+		Window: 5,
+		Functions: []*pb.CustomizeAlertRuleFunction{{
+			Field:      "fields.elapsed_mean",
+			Alias:      "latency",
+			Aggregator: "p95",
+			Operator:   "gte",
+			Value:      structpb.NewNumberValue(200),
+		}},
+		Filters: []*pb.CustomizeAlertRuleFilter{
+			{Tag: "service_name", Operator: "in", Value: structpb.NewListValue(values)},
+			{Tag: "host", Operator: "any"},
+		},
+		Group: []string{"service_name"},
+	}
+	expr, valueKey, err := customizeAlertRuleToPromExpr(rule)
+	if err != nil {
+		t.Fatalf("customizeAlertRuleToPromExpr() error: %s", err)
+	}
+	want := `max by (service_name) (quantile_over_time(0.95, elapsed_mean{job="application_http", service_name=~"a\\.b|c"}[5m])) >= 200`
+	if expr != want || valueKey != "latency" {
+		t.Errorf("customizeAlertRuleToPromExpr() = %q, %q, want %q", expr, valueKey, want)
+	}
+
+	rule.Functions[0].Aggregator = "distinct"
+	if _, _, err := customizeAlertRuleToPromExpr(rule); err == nil {
+		t.Errorf("customizeAlertRuleToPromExpr() with distinct should fail")
+	}
+}
@@ -1450,6 +1450,34 @@ func (m *alertService) CountUnRecoverAlertEvents(ctx context.Context, req *pb.Co
 	}, nil
 }
 
+func (m *alertService) ImportPrometheusRules(ctx context.Context, req *pb.ImportPrometheusRulesRequest) (*pb.ImportPrometheusRulesResponse, error) {
+	if req.AlertScope == "" || req.AlertScopeId == "" {
+		return nil, errors.NewMissingParameterError("alertScope or alertScopeId")
+	}
+	if req.Content == "" {
+		return nil, errors.NewMissingParameterError("content")
+	}
+	report, err := m.p.a.ImportPrometheusRules(req, apis.GetUserID(ctx))
+	if err != nil {
+		if adapt.IsInvalidParameterError(err) {
+			return nil, errors.NewInvalidParameterError("content", err.Error())
+		}
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.ImportPrometheusRulesResponse{Data: report}, nil
+}
+
+func (m *alertService) ExportPrometheusRules(ctx context.Context, req *pb.ExportPrometheusRulesRequest) (*pb.ExportPrometheusRulesResponse, error) {
+	if req.AlertScope == "" || req.AlertScopeId == "" {
+		return nil, errors.NewMissingParameterError("alertScope or alertScopeId")
+	}
+	data, err := m.p.a.ExportPrometheusRules(req.AlertScope, req.AlertScopeId)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.ExportPrometheusRulesResponse{Data: data}, nil
+}
+
 func (m *alertService) GetAlertEvents(ctx context.Context, req *pb.GetAlertEventRequest) (*pb.GetAlertEventResponse, error) {
 	var eventQuery = &db.AlertEventQueryCondition{
 		Name:                 req.Condition.Name,
@@ -1607,6 +1635,82 @@ func (m *alertService) CancelSuppressAlertEvent(ctx context.Context, req *pb.Can
 	}, nil
 }
 
+func (m *alertService) GetAlertIncidentPolicy(ctx context.Context, req *pb.GetAlertIncidentPolicyRequest) (*pb.GetAlertIncidentPolicyResponse, error) {
+	if req.Scope == "" || req.ScopeId == "" {
+		return nil, errors.NewMissingParameterError("scope or scopeId")
+	}
+	data, err := m.p.a.GetIncidentPolicy(req.Scope, req.ScopeId)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.GetAlertIncidentPolicyResponse{Data: data}, nil
+}
+
+func (m *alertService) UpdateAlertIncidentPolicy(ctx context.Context, req *pb.UpdateAlertIncidentPolicyRequest) (*pb.UpdateAlertIncidentPolicyResponse, error) {
+	orgID, err := strconv.ParseInt(apis.GetOrgID(ctx), 10, 64)
+	if err != nil {
+		return nil, errors.NewInvalidParameterError("orgId", "invalid orgId")
+	}
+	data, err := m.p.a.UpdateIncidentPolicy(req, orgID, apis.GetUserID(ctx))
+	if err != nil {
+		if adapt.IsInvalidParameterError(err) {
+			return nil, errors.NewInvalidParameterError("policy", err.Error())
+		}
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.UpdateAlertIncidentPolicyResponse{Data: data}, nil
+}
+
+func (m *alertService) QueryAlertIncidents(ctx context.Context, req *pb.QueryAlertIncidentsRequest) (*pb.QueryAlertIncidentsResponse, error) {
+	if req.Scope == "" || req.ScopeId == "" {
+		return nil, errors.NewMissingParameterError("scope or scopeId")
+	}
+	list, total, err := m.p.a.QueryIncidents(req)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.QueryAlertIncidentsResponse{Total: total, List: list}, nil
+}
+
+func (m *alertService) GetAlertIncident(ctx context.Context, req *pb.GetAlertIncidentRequest) (*pb.GetAlertIncidentResponse, error) {
+	data, events, err := m.p.a.GetIncident(req.Id)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert incident")
+	}
+	return &pb.GetAlertIncidentResponse{Data: data, Events: events}, nil
+}
+
+func (m *alertService) AcknowledgeAlertIncident(ctx context.Context, req *pb.AcknowledgeAlertIncidentRequest) (*pb.AcknowledgeAlertIncidentResponse, error) {
+	data, err := m.p.a.AcknowledgeIncident(req.Id, apis.GetUserID(ctx))
+	if err != nil {
+		if adapt.IsInvalidParameterError(err) {
+			return nil, errors.NewInvalidParameterError("state", err.Error())
+		}
+		return nil, errors.NewInternalServerError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert incident")
+	}
+	return &pb.AcknowledgeAlertIncidentResponse{Data: data}, nil
+}
+
+func (m *alertService) ResolveAlertIncident(ctx context.Context, req *pb.ResolveAlertIncidentRequest) (*pb.ResolveAlertIncidentResponse, error) {
+	data, err := m.p.a.ResolveIncident(req.Id, apis.GetUserID(ctx))
+	if err != nil {
+		if adapt.IsInvalidParameterError(err) {
+			return nil, errors.NewInvalidParameterError("state", err.Error())
+		}
+		return nil, errors.NewInternalServerError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert incident")
+	}
+	return &pb.ResolveAlertIncidentResponse{Data: data}, nil
+}
+
 func (m *alertService) GetRawAlertExpression(ctx context.Context, req *pb.GetRawAlertExpressionRequest) (*pb.GetRawAlertExpressionResponse, error) {
 	list, err := m.p.db.AlertExpression.QueryByIDs([]uint64{req.Id})
 	if err != nil {
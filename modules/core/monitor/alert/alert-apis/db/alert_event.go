@@ -64,6 +64,15 @@ func (db *AlertEventDB) UpdateAlertEvent(id string, fields map[string]interface{
 	return db.Table(TableAlertEvent).Where("id=?", id).Updates(fields).Error
 }
 
+// UpdateStateByIds changes the alert state of the events which are in one of the from states.
+func (db *AlertEventDB) UpdateStateByIds(ids []string, fromStates []string, state string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return db.Table(TableAlertEvent).Where("id in (?)", ids).Where("alert_state in (?)", fromStates).
+		Update("alert_state", state).Error
+}
+
 func (db *AlertEventDB) GetById(id string) (*AlertEvent, error) {
 	var record AlertEvent
 	err := db.Where("id=?", id).Find(&record).Error
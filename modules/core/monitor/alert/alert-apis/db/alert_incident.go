@@ -0,0 +1,238 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/incident"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// AlertIncidentPolicyDB .
+type AlertIncidentPolicyDB struct {
+	*gorm.DB
+}
+
+// GetByScope .
+func (db *AlertIncidentPolicyDB) GetByScope(scope, scopeID string) (*AlertIncidentPolicy, error) {
+	var policy AlertIncidentPolicy
+	err := db.Where("scope=? AND scope_id=? AND soft_deleted_at=0", scope, scopeID).First(&policy).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetByID .
+func (db *AlertIncidentPolicyDB) GetByID(id string) (*AlertIncidentPolicy, error) {
+	var policy AlertIncidentPolicy
+	err := db.Where("id=? AND soft_deleted_at=0", id).First(&policy).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Save creates or updates the policy of the scope.
+func (db *AlertIncidentPolicyDB) Save(policy *AlertIncidentPolicy) error {
+	if len(policy.Id) == 0 {
+		policy.Id = uuid.UUID()
+	}
+	return db.DB.Save(policy).Error
+}
+
+// AlertIncidentDB .
+type AlertIncidentDB struct {
+	*gorm.DB
+}
+
+// AlertIncidentQueryCondition .
+type AlertIncidentQueryCondition struct {
+	States      []string
+	AlertLevels []string
+	Assignee    string
+}
+
+// Create .
+func (db *AlertIncidentDB) Create(data *AlertIncident) error {
+	if len(data.Id) == 0 {
+		data.Id = uuid.UUID()
+	}
+	return db.DB.Create(data).Error
+}
+
+// Update .
+func (db *AlertIncidentDB) Update(id string, fields map[string]interface{}) error {
+	return db.Table(TableAlertIncident).Where("id=?", id).Updates(fields).Error
+}
+
+// Transit moves the incident from state to another state with fields,
+// it returns false if the incident is not in the from state any more, e.g. changed by another request.
+func (db *AlertIncidentDB) Transit(id, from string, fields map[string]interface{}) (bool, error) {
+	result := db.Table(TableAlertIncident).
+		Where("id=? AND state=? AND soft_deleted_at=0", id, from).
+		Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClaimEscalation moves the escalation step of the triggered incident from done to next,
+// it returns false if the step is already claimed by another replica.
+func (db *AlertIncidentDB) ClaimEscalation(id string, done, next int, now time.Time) (bool, error) {
+	result := db.Table(TableAlertIncident).
+		Where("id=? AND escalation_step=? AND state=? AND soft_deleted_at=0", id, done, incident.StateTriggered).
+		Updates(map[string]interface{}{"escalation_step": next, "last_escalated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetByID .
+func (db *AlertIncidentDB) GetByID(id string) (*AlertIncident, error) {
+	var record AlertIncident
+	err := db.Where("id=? AND soft_deleted_at=0", id).First(&record).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetOpenByGroupKey returns the incident which is not resolved yet with the group key.
+func (db *AlertIncidentDB) GetOpenByGroupKey(groupKey string) (*AlertIncident, error) {
+	var record AlertIncident
+	err := db.Where("group_key=? AND soft_deleted_at=0", groupKey).
+		Where("state in (?)", []string{incident.StateTriggered, incident.StateAcknowledged}).
+		Order("created_at DESC").First(&record).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// QueryByCondition .
+func (db *AlertIncidentDB) QueryByCondition(scope, scopeID string, condition *AlertIncidentQueryCondition, pageNo, pageSize int64) ([]*AlertIncident, int64, error) {
+	query := db.Table(TableAlertIncident).
+		Where("scope=? AND scope_id=? AND soft_deleted_at=0", scope, scopeID)
+	if condition != nil {
+		if len(condition.States) > 0 {
+			query = query.Where("state in (?)", condition.States)
+		}
+		if len(condition.AlertLevels) > 0 {
+			query = query.Where("alert_level in (?)", condition.AlertLevels)
+		}
+		if len(condition.Assignee) > 0 {
+			query = query.Where("assignee=?", condition.Assignee)
+		}
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*AlertIncident
+	err := query.Order("last_event_time DESC").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// QueryTriggered returns a page of the incidents which are neither acknowledged nor resolved,
+// ordered by id, the next page starts after the id of the last incident in this page.
+func (db *AlertIncidentDB) QueryTriggered(afterID string, limit int) ([]*AlertIncident, error) {
+	var list []*AlertIncident
+	err := db.Where("state=? AND soft_deleted_at=0 AND id>?", incident.StateTriggered, afterID).
+		Order("id ASC").Limit(limit).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AlertIncidentEventDB .
+type AlertIncidentEventDB struct {
+	*gorm.DB
+}
+
+// Link attaches the alert event to the incident, it returns false if they have been linked.
+func (db *AlertIncidentEventDB) Link(orgID int64, incidentID, eventID string) (bool, error) {
+	var count int64
+	err := db.Table(TableAlertIncidentEvent).
+		Where("incident_id=? AND alert_event_id=? AND soft_deleted_at=0", incidentID, eventID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return false, err
+	}
+	err = db.Create(&AlertIncidentEvent{
+		Id:           uuid.UUID(),
+		OrgID:        orgID,
+		IncidentID:   incidentID,
+		AlertEventID: eventID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}).Error
+	return err == nil, err
+}
+
+// QueryEventIDs .
+func (db *AlertIncidentEventDB) QueryEventIDs(incidentID string) ([]string, error) {
+	var ids []string
+	err := db.Table(TableAlertIncidentEvent).
+		Where("incident_id=? AND soft_deleted_at=0", incidentID).
+		Pluck("alert_event_id", &ids).Error
+	return ids, err
+}
+
+// GetOpenIncidentID returns the unresolved incident which the alert event is linked to.
+func (db *AlertIncidentEventDB) GetOpenIncidentID(eventID string) (string, error) {
+	var ids []string
+	err := db.Table(TableAlertIncidentEvent+" AS e").
+		Joins("JOIN "+TableAlertIncident+" AS i ON i.id=e.incident_id").
+		Where("e.alert_event_id=? AND e.soft_deleted_at=0 AND i.soft_deleted_at=0", eventID).
+		Where("i.state in (?)", []string{incident.StateTriggered, incident.StateAcknowledged}).
+		Limit(1).Pluck("e.incident_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// CountByEventState counts the events of the incident in the alert states.
+func (db *AlertIncidentEventDB) CountByEventState(incidentID string, states []string) (int64, error) {
+	var count int64
+	err := db.Table(TableAlertIncidentEvent+" AS e").
+		Joins("JOIN "+TableAlertEvent+" AS a ON a.id=e.alert_event_id").
+		Where("e.incident_id=? AND e.soft_deleted_at=0", incidentID).
+		Where("a.alert_state in (?)", states).
+		Count(&count).Error
+	return count, err
+}
@@ -66,6 +66,17 @@ func (db *CustomizeAlertDB) QueryByScopeAndScopeID(scope, scopeID string, pageNo
 	return alerts, nil
 }
 
+// QueryAllByScopeAndScopeID .
+func (db *CustomizeAlertDB) QueryAllByScopeAndScopeID(scope, scopeID string) ([]*CustomizeAlert, error) {
+	var alerts []*CustomizeAlert
+	if err := db.Where("alert_scope=? AND alert_scope_id=?", scope, scopeID).
+		Order("id ASC").
+		Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
 // CountByScopeAndScopeID .
 func (db *CustomizeAlertDB) CountByScopeAndScopeID(scope, scopeID, name string) (int, error) {
 	var count int
@@ -32,6 +32,9 @@ type DB struct {
 	AlertRecord                  AlertRecordDB
 	AlertEventDB                 AlertEventDB
 	AlertEventSuppressDB         AlertEventSuppressDB
+	AlertIncidentPolicy          AlertIncidentPolicyDB
+	AlertIncident                AlertIncidentDB
+	AlertIncidentEvent           AlertIncidentEventDB
 }
 
 // New .
@@ -49,6 +52,9 @@ func New(db *gorm.DB) *DB {
 		AlertRecord:                  AlertRecordDB{db},
 		AlertEventDB:                 AlertEventDB{db},
 		AlertEventSuppressDB:         AlertEventSuppressDB{db},
+		AlertIncidentPolicy:          AlertIncidentPolicyDB{db},
+		AlertIncident:                AlertIncidentDB{db},
+		AlertIncidentEvent:           AlertIncidentEventDB{db},
 	}
 }
 
@@ -34,6 +34,9 @@ const (
 	TableAlert                        = "sp_alert"
 	TableAlertEvent                   = "sp_alert_event"
 	TableAlertEventSuppress           = "sp_alert_event_suppress"
+	TableAlertIncidentPolicy          = "erda_alert_incident_policy"
+	TableAlertIncident                = "erda_alert_incident"
+	TableAlertIncidentEvent           = "erda_alert_incident_event"
 )
 
 type AlertEvent struct {
@@ -254,3 +257,68 @@ type Alert struct {
 
 // TableName 。
 func (Alert) TableName() string { return TableAlert }
+
+// AlertIncidentPolicy .
+type AlertIncidentPolicy struct {
+	Id            string    `gorm:"column:id;primary_key"`
+	OrgID         int64     `gorm:"column:org_id"`
+	OrgName       string    `gorm:"column:org_name"`
+	Scope         string    `gorm:"column:scope"`
+	ScopeID       string    `gorm:"column:scope_id"`
+	GroupKeys     string    `gorm:"column:group_keys"`
+	GroupWindow   int64     `gorm:"column:group_window"`
+	NotifyGroupID int64     `gorm:"column:notify_group_id"`
+	Escalation    string    `gorm:"column:escalation"`
+	Creator       string    `gorm:"column:creator"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+	SoftDeletedAt int64     `gorm:"column:soft_deleted_at"`
+}
+
+// TableName .
+func (AlertIncidentPolicy) TableName() string { return TableAlertIncidentPolicy }
+
+// AlertIncident .
+type AlertIncident struct {
+	Id              string    `gorm:"column:id;primary_key"`
+	OrgID           int64     `gorm:"column:org_id"`
+	OrgName         string    `gorm:"column:org_name"`
+	Scope           string    `gorm:"column:scope"`
+	ScopeID         string    `gorm:"column:scope_id"`
+	PolicyID        string    `gorm:"column:policy_id"`
+	GroupKey        string    `gorm:"column:group_key"`
+	Title           string    `gorm:"column:title"`
+	AlertLevel      string    `gorm:"column:alert_level"`
+	State           string    `gorm:"column:state"`
+	Assignee        string    `gorm:"column:assignee"`
+	EscalationStep  int       `gorm:"column:escalation_step"`
+	LastEscalatedAt time.Time `gorm:"column:last_escalated_at"`
+	AcknowledgedBy  string    `gorm:"column:acknowledged_by"`
+	AcknowledgedAt  time.Time `gorm:"column:acknowledged_at"`
+	ResolvedBy      string    `gorm:"column:resolved_by"`
+	ResolvedAt      time.Time `gorm:"column:resolved_at"`
+	FirstEventTime  time.Time `gorm:"column:first_event_time"`
+	LastEventTime   time.Time `gorm:"column:last_event_time"`
+	EventCount      int64     `gorm:"column:event_count"`
+	CreatedAt       time.Time `gorm:"column:created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at"`
+	SoftDeletedAt   int64     `gorm:"column:soft_deleted_at"`
+}
+
+// TableName .
+func (AlertIncident) TableName() string { return TableAlertIncident }
+
+// AlertIncidentEvent links the alert events to the incident.
+type AlertIncidentEvent struct {
+	Id            string    `gorm:"column:id;primary_key"`
+	OrgID         int64     `gorm:"column:org_id"`
+	OrgName       string    `gorm:"column:org_name"`
+	IncidentID    string    `gorm:"column:incident_id"`
+	AlertEventID  string    `gorm:"column:alert_event_id"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+	SoftDeletedAt int64     `gorm:"column:soft_deleted_at"`
+}
+
+// TableName .
+func (AlertIncidentEvent) TableName() string { return TableAlertIncidentEvent }
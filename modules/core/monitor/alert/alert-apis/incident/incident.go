@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package incident
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// incident states
+const (
+	StateTriggered    = "triggered"
+	StateAcknowledged = "acknowledged"
+	StateResolved     = "resolved"
+)
+
+// alert states of the events, alert and recover are reported by the alert engine,
+// acknowledged and resolved are set by the incident operations.
+const (
+	EventStateAlert        = "alert"
+	EventStateRecover      = "recover"
+	EventStateAcknowledged = "acknowledged"
+	EventStateResolved     = "resolved"
+)
+
+// DefaultGroupKeys groups the events of the same alert into one incident.
+var DefaultGroupKeys = []string{"alert_id"}
+
+// Event is the part of an alert event used to group it into an incident.
+type Event struct {
+	Scope        string
+	ScopeID      string
+	AlertID      uint64
+	AlertName    string
+	AlertGroup   string
+	AlertSubject string
+	AlertLevel   string
+	AlertType    string
+	RuleID       uint64
+}
+
+var groupKeyFields = map[string]func(e *Event) string{
+	"alert_id":      func(e *Event) string { return strconv.FormatUint(e.AlertID, 10) },
+	"alert_name":    func(e *Event) string { return e.AlertName },
+	"alert_group":   func(e *Event) string { return e.AlertGroup },
+	"alert_subject": func(e *Event) string { return e.AlertSubject },
+	"alert_level":   func(e *Event) string { return e.AlertLevel },
+	"alert_type":    func(e *Event) string { return e.AlertType },
+	"rule_id":       func(e *Event) string { return strconv.FormatUint(e.RuleID, 10) },
+}
+
+// ValidateGroupKeys checks the keys used to group events.
+func ValidateGroupKeys(keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("group keys must not be empty")
+	}
+	for _, key := range keys {
+		if _, ok := groupKeyFields[key]; !ok {
+			return fmt.Errorf("not support group key %q", key)
+		}
+	}
+	return nil
+}
+
+// GroupKey returns the key of the incident which the event belongs to.
+// Events of different scopes never share an incident.
+func GroupKey(keys []string, e *Event) (string, error) {
+	if len(keys) == 0 {
+		keys = DefaultGroupKeys
+	}
+	if err := ValidateGroupKeys(keys); err != nil {
+		return "", err
+	}
+	keys = append([]string{}, keys...)
+	sort.Strings(keys)
+	parts := []string{e.Scope, e.ScopeID}
+	for _, key := range keys {
+		parts = append(parts, key+"="+groupKeyFields[key](e))
+	}
+	sum := md5.Sum([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// InGroupWindow reports whether an event triggered at t can join an incident
+// whose last event was at last. A zero window means no limit.
+func InGroupWindow(last, t time.Time, windowMinutes int64) bool {
+	if windowMinutes <= 0 {
+		return true
+	}
+	return t.Sub(last) <= time.Duration(windowMinutes)*time.Minute
+}
+
+// CanTransit reports whether an incident can change from one state to another.
+func CanTransit(from, to string) bool {
+	switch from {
+	case StateTriggered:
+		return to == StateAcknowledged || to == StateResolved
+	case StateAcknowledged:
+		return to == StateResolved
+	}
+	return false
+}
+
+var levelRanks = map[string]int{
+	"FATAL":    4,
+	"CRITICAL": 3,
+	"WARNING":  2,
+	"NOTICE":   1,
+}
+
+// HigherLevel returns the more severe one of the two alert levels.
+func HigherLevel(a, b string) string {
+	if levelRanks[strings.ToUpper(b)] > levelRanks[strings.ToUpper(a)] {
+		return b
+	}
+	return a
+}
+
+// EscalationStep notifies another group when the incident is not acknowledged in time.
+type EscalationStep struct {
+	AfterMinutes  int64    `json:"afterMinutes"`
+	NotifyGroupID int64    `json:"notifyGroupId"`
+	Channels      []string `json:"channels"`
+}
+
+// ValidateEscalation checks the escalation chain, the steps must be in ascending order of time.
+func ValidateEscalation(steps []*EscalationStep) error {
+	var last int64
+	for i, step := range steps {
+		if step == nil {
+			return fmt.Errorf("escalation step %d is empty", i+1)
+		}
+		if step.AfterMinutes <= last {
+			return fmt.Errorf("afterMinutes of escalation step %d must be greater than %d", i+1, last)
+		}
+		if step.NotifyGroupID <= 0 {
+			return fmt.Errorf("notifyGroupId of escalation step %d is required", i+1)
+		}
+		if len(step.Channels) == 0 {
+			return fmt.Errorf("channels of escalation step %d is required", i+1)
+		}
+		last = step.AfterMinutes
+	}
+	return nil
+}
+
+// NextEscalation returns the index of the escalation step which should be executed at now,
+// done is the number of steps already executed.
+func NextEscalation(steps []*EscalationStep, done int, triggeredAt, now time.Time) (int, bool) {
+	if done < 0 || done >= len(steps) {
+		return 0, false
+	}
+	if now.Before(triggeredAt.Add(time.Duration(steps[done].AfterMinutes) * time.Minute)) {
+		return 0, false
+	}
+	return done, true
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package incident
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupKey(t *testing.T) {
+	a := &Event{Scope: "micro_service", ScopeID: "tk", AlertID: 1, AlertSubject: "host-1", AlertLevel: "WARNING"}
+	b := &Event{Scope: "micro_service", ScopeID: "tk", AlertID: 1, AlertSubject: "host-2", AlertLevel: "WARNING"}
+	c := &Event{Scope: "org", ScopeID: "tk", AlertID: 1, AlertSubject: "host-1", AlertLevel: "WARNING"}
+
+	key := func(keys []string, e *Event) string {
+		k, err := GroupKey(keys, e)
+		if err != nil {
+			t.Fatalf("GroupKey() error: %s", err)
+		}
+		return k
+	}
+	if key(nil, a) != key(nil, b) {
+		t.Errorf("events of the same alert should be grouped by default")
+	}
+	if key(nil, a) == key(nil, c) {
+		t.Errorf("events of different scopes should not be grouped")
+	}
+	if key([]string{"alert_id", "alert_subject"}, a) == key([]string{"alert_id", "alert_subject"}, b) {
+		t.Errorf("events of different subjects should not be grouped")
+	}
+	if key([]string{"alert_subject", "alert_id"}, a) != key([]string{"alert_id", "alert_subject"}, a) {
+		t.Errorf("the order of group keys should not matter")
+	}
+	if _, err := GroupKey([]string{"cluster"}, a); err == nil {
+		t.Errorf("GroupKey() with unknown key should fail")
+	}
+}
+
+func TestNextEscalation(t *testing.T) {
+	steps := []*EscalationStep{
+		{AfterMinutes: 5, NotifyGroupID: 1, Channels: []string{"dingding"}},
+		{AfterMinutes: 15, NotifyGroupID: 2, Channels: []string{"sms"}},
+	}
+	triggered := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		done   int
+		now    time.Time
+		want   int
+		wantOk bool
+	}{
+		{done: 0, now: triggered.Add(4 * time.Minute)},
+		{done: 0, now: triggered.Add(5 * time.Minute), want: 0, wantOk: true},
+		{done: 0, now: triggered.Add(20 * time.Minute), want: 0, wantOk: true},
+		{done: 1, now: triggered.Add(10 * time.Minute)},
+		{done: 1, now: triggered.Add(15 * time.Minute), want: 1, wantOk: true},
+		{done: 2, now: triggered.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		got, ok := NextEscalation(steps, tt.done, triggered, tt.now)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("NextEscalation(%d, %s) = %d, %v, want %d, %v", tt.done, tt.now, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestValidateEscalation(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []*EscalationStep
+		wantErr bool
+	}{
+		{name: "empty"},
+		{
+			name: "valid",
+			steps: []*EscalationStep{
+				{AfterMinutes: 5, NotifyGroupID: 1, Channels: []string{"email"}},
+				{AfterMinutes: 10, NotifyGroupID: 2, Channels: []string{"sms"}},
+			},
+		},
+		{
+			name: "not ascending",
+			steps: []*EscalationStep{
+				{AfterMinutes: 10, NotifyGroupID: 1, Channels: []string{"email"}},
+				{AfterMinutes: 10, NotifyGroupID: 2, Channels: []string{"sms"}},
+			},
+			wantErr: true,
+		},
+		{name: "no group", steps: []*EscalationStep{{AfterMinutes: 5, Channels: []string{"email"}}}, wantErr: true},
+		{name: "no channel", steps: []*EscalationStep{{AfterMinutes: 5, NotifyGroupID: 1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidateEscalation(tt.steps); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateEscalation() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStates(t *testing.T) {
+	if !CanTransit(StateTriggered, StateAcknowledged) || !CanTransit(StateAcknowledged, StateResolved) {
+		t.Errorf("CanTransit() should allow triggered -> acknowledged -> resolved")
+	}
+	if CanTransit(StateResolved, StateAcknowledged) || CanTransit(StateAcknowledged, StateTriggered) {
+		t.Errorf("CanTransit() should not allow going back")
+	}
+	if HigherLevel("WARNING", "FATAL") != "FATAL" || HigherLevel("CRITICAL", "NOTICE") != "CRITICAL" {
+		t.Errorf("HigherLevel() returns the wrong level")
+	}
+	last := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	if !InGroupWindow(last, last.Add(time.Hour), 0) || InGroupWindow(last, last.Add(time.Hour), 30) {
+		t.Errorf("InGroupWindow() returns the wrong result")
+	}
+}
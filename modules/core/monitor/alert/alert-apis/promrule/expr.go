@@ -0,0 +1,754 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promrule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Matcher is a label matcher of a vector selector, Op is one of the filter operators of customize alert.
+type Matcher struct {
+	Label string
+	Op    string
+	Value string
+}
+
+// Condition is the subset of PromQL which can be expressed by a customize alert rule:
+//
+//	[outer [by (group)]] ( [<aggregator>_over_time] ( metric{matchers}[window] ) ) <operator> threshold
+type Condition struct {
+	Metric     string
+	Matchers   []*Matcher
+	Window     time.Duration
+	Aggregator string
+	Group      []string
+	Operator   string
+	Threshold  float64
+}
+
+var (
+	comparisonOperators = map[string]string{
+		">":  "gt",
+		">=": "gte",
+		"<":  "lt",
+		"<=": "lte",
+		"==": "eq",
+		"!=": "neq",
+	}
+	flippedOperators = map[string]string{
+		"gt":  "lt",
+		"gte": "lte",
+		"lt":  "gt",
+		"lte": "gte",
+		"eq":  "eq",
+		"neq": "neq",
+	}
+	matchOperators = map[string]string{
+		"=":  "eq",
+		"!=": "neq",
+		"=~": "match",
+		"!~": "notMatch",
+	}
+	aggregationOperators = map[string]bool{
+		"sum":   true,
+		"avg":   true,
+		"max":   true,
+		"min":   true,
+		"count": true,
+	}
+	overTimeFunctions = map[string]string{
+		"avg_over_time":   "avg",
+		"sum_over_time":   "sum",
+		"max_over_time":   "max",
+		"min_over_time":   "min",
+		"count_over_time": "count",
+		"last_over_time":  "value",
+	}
+	quantiles = map[string]string{
+		"0.99": "p99",
+		"0.95": "p95",
+		"0.9":  "p90",
+		"0.75": "p75",
+		"0.5":  "p50",
+	}
+	// outerAggregators is the aggregation used across series when a rule is grouped.
+	outerAggregators = map[string]string{
+		"avg":   "avg",
+		"sum":   "sum",
+		"max":   "max",
+		"min":   "min",
+		"count": "sum",
+		"value": "max",
+		"p99":   "max",
+		"p95":   "max",
+		"p90":   "max",
+		"p75":   "max",
+		"p50":   "max",
+	}
+)
+
+// ParseExpr parses a PromQL alerting expression into a Condition.
+// An error is returned when the expression is invalid or can not be expressed as a customize alert rule.
+func ParseExpr(expr string) (*Condition, error) {
+	p := &parser{lex: newLexer(expr)}
+	cond, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+// String renders the condition as PromQL.
+func (c *Condition) String() (string, error) {
+	var sb strings.Builder
+	sb.WriteString(c.Metric)
+	if len(c.Matchers) > 0 {
+		sb.WriteString("{")
+		for i, m := range c.Matchers {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			op, ok := reverseMatchOperator(m.Op)
+			if !ok {
+				return "", fmt.Errorf("filter operator %q is not supported", m.Op)
+			}
+			sb.WriteString(m.Label + op + strconv.Quote(m.Value))
+		}
+		sb.WriteString("}")
+	}
+	vector := sb.String()
+	if c.Window > 0 || c.Aggregator != "value" {
+		if c.Window <= 0 {
+			return "", fmt.Errorf("window of aggregator %q must be positive", c.Aggregator)
+		}
+		rng := "[" + FormatDuration(c.Window) + "]"
+		switch c.Aggregator {
+		case "avg", "sum", "max", "min", "count":
+			vector = c.Aggregator + "_over_time(" + vector + rng + ")"
+		case "value":
+			vector = "last_over_time(" + vector + rng + ")"
+		default:
+			q, ok := reverseQuantile(c.Aggregator)
+			if !ok {
+				return "", fmt.Errorf("aggregator %q is not supported", c.Aggregator)
+			}
+			vector = "quantile_over_time(" + q + ", " + vector + rng + ")"
+		}
+	}
+	if len(c.Group) > 0 {
+		vector = fmt.Sprintf("%s by (%s) (%s)", outerAggregators[c.Aggregator], strings.Join(c.Group, ", "), vector)
+	}
+	var op string
+	for k, v := range comparisonOperators {
+		if v == c.Operator {
+			op = k
+			break
+		}
+	}
+	if op == "" {
+		return "", fmt.Errorf("function operator %q is not supported", c.Operator)
+	}
+	return vector + " " + op + " " + strconv.FormatFloat(c.Threshold, 'f', -1, 64), nil
+}
+
+func reverseMatchOperator(op string) (string, bool) {
+	for k, v := range matchOperators {
+		if v == op {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func reverseQuantile(aggregator string) (string, bool) {
+	for k, v := range quantiles {
+		if v == aggregator {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// ParseDuration parses a prometheus duration such as 5m or 1h30m.
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var d time.Duration
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		s = s[i:]
+		j := 0
+		for j < len(s) && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		unit, ok := durationUnits[s[:j]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		d += time.Duration(n) * unit
+		s = s[j:]
+	}
+	return d, nil
+}
+
+// FormatDuration formats d as a prometheus duration.
+func FormatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "0s"
+	}
+	units := []string{"d", "h", "m", "s"}
+	var sb strings.Builder
+	for _, unit := range units {
+		if n := d / durationUnits[unit]; n > 0 {
+			sb.WriteString(strconv.FormatInt(int64(n), 10) + unit)
+			d -= n * durationUnits[unit]
+		}
+	}
+	if d > 0 {
+		sb.WriteString(strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms")
+	}
+	return sb.String()
+}
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenDuration
+	tokenPunct
+)
+
+type token struct {
+	typ tokenType
+	val string
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.val)
+}
+
+type lexer struct {
+	input   string
+	pos     int
+	inRange bool
+}
+
+func newLexer(input string) *lexer {
+	return &lexer{input: input}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(rune(l.input[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return token{typ: tokenEOF}, nil
+	}
+	start := l.pos
+	c := l.input[l.pos]
+	switch {
+	case l.inRange && c >= '0' && c <= '9':
+		for l.pos < len(l.input) && isAlphaNumeric(l.input[l.pos]) {
+			l.pos++
+		}
+		return token{typ: tokenDuration, val: l.input[start:l.pos]}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		for l.pos < len(l.input) && (isAlphaNumeric(l.input[l.pos]) || l.input[l.pos] == '.' ||
+			((l.input[l.pos] == '+' || l.input[l.pos] == '-') && (l.input[l.pos-1] == 'e' || l.input[l.pos-1] == 'E'))) {
+			l.pos++
+		}
+		return token{typ: tokenNumber, val: l.input[start:l.pos]}, nil
+	case c == '_' || c == ':' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.input) && (isAlphaNumeric(l.input[l.pos]) || l.input[l.pos] == ':') {
+			l.pos++
+		}
+		return token{typ: tokenIdent, val: l.input[start:l.pos]}, nil
+	case c == '"' || c == '\'' || c == '`':
+		l.pos++
+		for l.pos < len(l.input) && l.input[l.pos] != c {
+			if l.input[l.pos] == '\\' && c != '`' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.input) {
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		}
+		l.pos++
+		raw := l.input[start:l.pos]
+		if c == '`' {
+			return token{typ: tokenString, val: raw[1 : len(raw)-1]}, nil
+		}
+		if c == '\'' {
+			raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`), `"`, `\"`) + `"`
+		}
+		val, err := strconv.Unquote(raw)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid string %s: %s", raw, err)
+		}
+		return token{typ: tokenString, val: val}, nil
+	}
+	for _, punct := range []string{"=~", "!~", "!=", ">=", "<=", "==", "(", ")", "{", "}", "[", "]", ",", "=", ">", "<", "-", "+", "*", "/", "%", "^"} {
+		if strings.HasPrefix(l.input[l.pos:], punct) {
+			l.pos += len(punct)
+			switch punct {
+			case "[":
+				l.inRange = true
+			case "]":
+				l.inRange = false
+			}
+			return token{typ: tokenPunct, val: punct}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character %q at position %d", c, start)
+}
+
+func isAlphaNumeric(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+type parser struct {
+	lex    *lexer
+	peeked *token
+}
+
+func (p *parser) peek() (token, error) {
+	if p.peeked == nil {
+		t, err := p.lex.next()
+		if err != nil {
+			return token{}, err
+		}
+		p.peeked = &t
+	}
+	return *p.peeked, nil
+}
+
+func (p *parser) next() (token, error) {
+	t, err := p.peek()
+	p.peeked = nil
+	return t, err
+}
+
+func (p *parser) expect(val string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.typ != tokenPunct || t.val != val {
+		return fmt.Errorf("expected %q but got %s", val, t)
+	}
+	return nil
+}
+
+// operand is either a number or a vector
+type operand struct {
+	isNumber bool
+	number   float64
+	vector   *vector
+}
+
+type vector struct {
+	metric   string
+	matchers []*Matcher
+	window   time.Duration
+	inner    string
+	outer    string
+	group    []string
+}
+
+func (p *parser) parse() (*Condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.typ == tokenEOF {
+		return nil, fmt.Errorf("expression must compare a vector with a threshold")
+	}
+	op, ok := comparisonOperators[t.val]
+	if t.typ != tokenPunct || !ok {
+		return nil, fmt.Errorf("operator %s is not supported, only comparisons with a threshold are supported", t)
+	}
+	if t, err := p.peek(); err != nil {
+		return nil, err
+	} else if t.typ == tokenIdent && t.val == "bool" {
+		return nil, fmt.Errorf("bool modifier is not supported")
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if t, err := p.next(); err != nil {
+		return nil, err
+	} else if t.typ != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s, only a single comparison is supported", t)
+	}
+
+	var vec *vector
+	var threshold float64
+	switch {
+	case !left.isNumber && right.isNumber:
+		vec, threshold = left.vector, right.number
+	case left.isNumber && !right.isNumber:
+		vec, threshold, op = right.vector, left.number, flippedOperators[op]
+	default:
+		return nil, fmt.Errorf("expression must compare a vector with a threshold")
+	}
+
+	cond := &Condition{
+		Metric:    vec.metric,
+		Matchers:  vec.matchers,
+		Window:    vec.window,
+		Group:     vec.group,
+		Operator:  op,
+		Threshold: threshold,
+	}
+	switch {
+	case vec.inner != "" && vec.outer != "":
+		if outerAggregators[vec.inner] != vec.outer {
+			return nil, fmt.Errorf("nested aggregation %s over %s is not supported", vec.outer, vec.inner)
+		}
+		cond.Aggregator = vec.inner
+	case vec.inner != "":
+		cond.Aggregator = vec.inner
+	case vec.outer != "":
+		cond.Aggregator = vec.outer
+	default:
+		cond.Aggregator = "value"
+	}
+	return cond, nil
+}
+
+func (p *parser) parseOperand() (*operand, error) {
+	t, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.typ == tokenNumber || (t.typ == tokenPunct && t.val == "-"):
+		return p.parseNumber()
+	case t.typ == tokenPunct && t.val == "(":
+		p.next()
+		op, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return op, nil
+	}
+	vec, err := p.parseVector(true)
+	if err != nil {
+		return nil, err
+	}
+	return &operand{vector: vec}, nil
+}
+
+func (p *parser) parseNumber() (*operand, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	sign := 1.0
+	if t.typ == tokenPunct && t.val == "-" {
+		sign = -1
+		if t, err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if t.typ != tokenNumber {
+		return nil, fmt.Errorf("expected number but got %s", t)
+	}
+	n, err := strconv.ParseFloat(t.val, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", t.val)
+	}
+	return &operand{isNumber: true, number: sign * n}, nil
+}
+
+func (p *parser) parseVector(allowAggregation bool) (*vector, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.typ == tokenPunct && t.val == "{" {
+		return p.parseSelector("")
+	}
+	if t.typ == tokenPunct && t.val == "(" {
+		vec, err := p.parseVector(allowAggregation)
+		if err != nil {
+			return nil, err
+		}
+		return vec, p.expect(")")
+	}
+	if t.typ != tokenIdent {
+		return nil, fmt.Errorf("unexpected %s", t)
+	}
+	name := t.val
+	next, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	isCall := next.typ == tokenPunct && next.val == "("
+	isGrouping := next.typ == tokenIdent && (next.val == "by" || next.val == "without")
+	switch {
+	case aggregationOperators[name] && (isCall || isGrouping):
+		if !allowAggregation {
+			return nil, fmt.Errorf("nested aggregation %s is not supported", name)
+		}
+		return p.parseAggregation(name)
+	case isCall:
+		return p.parseFunction(name)
+	case isGrouping:
+		return nil, fmt.Errorf("aggregation %s is not supported", name)
+	}
+	return p.parseSelector(name)
+}
+
+func (p *parser) parseAggregation(name string) (*vector, error) {
+	group, err := p.parseGrouping()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	vec, err := p.parseVector(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if group == nil {
+		if group, err = p.parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	vec.outer = name
+	vec.group = group
+	return vec, nil
+}
+
+func (p *parser) parseGrouping() ([]string, error) {
+	t, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if t.typ != tokenIdent || (t.val != "by" && t.val != "without") {
+		return nil, nil
+	}
+	p.next()
+	if t.val == "without" {
+		return nil, fmt.Errorf("aggregation without labels is not supported")
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	group := make([]string, 0)
+	for {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.typ == tokenPunct && t.val == ")" {
+			return group, nil
+		}
+		if t.typ == tokenPunct && t.val == "," && len(group) > 0 {
+			continue
+		}
+		if t.typ != tokenIdent {
+			return nil, fmt.Errorf("expected label name but got %s", t)
+		}
+		group = append(group, t.val)
+	}
+}
+
+func (p *parser) parseFunction(name string) (*vector, error) {
+	aggregator, ok := overTimeFunctions[name]
+	if !ok && name != "quantile_over_time" {
+		return nil, fmt.Errorf("function %s is not supported", name)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if name == "quantile_over_time" {
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseFloat(t.val, 64)
+		if t.typ != tokenNumber || err != nil {
+			return nil, fmt.Errorf("expected quantile but got %s", t)
+		}
+		aggregator, ok = quantiles[strconv.FormatFloat(n, 'f', -1, 64)]
+		if !ok {
+			return nil, fmt.Errorf("quantile %s is not supported", t.val)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var vec *vector
+	switch {
+	case t.typ == tokenIdent:
+		vec, err = p.parseSelector(t.val)
+	case t.typ == tokenPunct && t.val == "{":
+		vec, err = p.parseSelector("")
+	default:
+		return nil, fmt.Errorf("function %s expects a range vector but got %s", name, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if vec.window <= 0 {
+		return nil, fmt.Errorf("function %s expects a range vector", name)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	vec.inner = aggregator
+	return vec, nil
+}
+
+func (p *parser) parseSelector(name string) (*vector, error) {
+	vec := &vector{metric: name}
+	if name == "" {
+		if err := p.parseMatchers(vec); err != nil {
+			return nil, err
+		}
+	} else if t, err := p.peek(); err != nil {
+		return nil, err
+	} else if t.typ == tokenPunct && t.val == "{" {
+		p.next()
+		if err := p.parseMatchers(vec); err != nil {
+			return nil, err
+		}
+	}
+	if vec.metric == "" {
+		return nil, fmt.Errorf("vector selector must have a metric name")
+	}
+	t, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if t.typ == tokenPunct && t.val == "[" {
+		p.next()
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if t.typ != tokenDuration {
+			return nil, fmt.Errorf("expected duration but got %s", t)
+		}
+		if vec.window, err = ParseDuration(t.val); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, fmt.Errorf("%s, subqueries are not supported", err)
+		}
+		t, err = p.peek()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.typ == tokenIdent && (t.val == "offset" || t.val == "@") {
+		return nil, fmt.Errorf("%s modifier is not supported", t.val)
+	}
+	return vec, nil
+}
+
+// parseMatchers parses matchers after the opening brace.
+func (p *parser) parseMatchers(vec *vector) error {
+	for count := 0; ; count++ {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		if t.typ == tokenPunct && t.val == "}" {
+			return nil
+		}
+		if t.typ == tokenPunct && t.val == "," && count > 0 {
+			continue
+		}
+		if t.typ != tokenIdent {
+			return fmt.Errorf("expected label name but got %s", t)
+		}
+		label := t.val
+		opToken, err := p.next()
+		if err != nil {
+			return err
+		}
+		op, ok := matchOperators[opToken.val]
+		if opToken.typ != tokenPunct || !ok {
+			return fmt.Errorf("expected label matcher operator but got %s", opToken)
+		}
+		value, err := p.next()
+		if err != nil {
+			return err
+		}
+		if value.typ != tokenString {
+			return fmt.Errorf("expected label value but got %s", value)
+		}
+		if label == "__name__" {
+			if op != "eq" {
+				return fmt.Errorf("metric name matcher must use =")
+			}
+			vec.metric = value.val
+			continue
+		}
+		vec.matchers = append(vec.matchers, &Matcher{Label: label, Op: op, Value: value.val})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promrule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    *Condition
+		wantErr bool
+	}{
+		{
+			name: "instant vector",
+			expr: `up{job="node"} == 0`,
+			want: &Condition{
+				Metric:     "up",
+				Matchers:   []*Matcher{{Label: "job", Op: "eq", Value: "node"}},
+				Aggregator: "value",
+				Operator:   "eq",
+				Threshold:  0,
+			},
+		},
+		{
+			name: "over time with grouping",
+			expr: `avg by (instance, path) (avg_over_time(http_latency_seconds{job="api", path=~"/v1/.*"}[5m])) > 0.5`,
+			want: &Condition{
+				Metric: "http_latency_seconds",
+				Matchers: []*Matcher{
+					{Label: "job", Op: "eq", Value: "api"},
+					{Label: "path", Op: "match", Value: "/v1/.*"},
+				},
+				Window:     5 * time.Minute,
+				Aggregator: "avg",
+				Group:      []string{"instance", "path"},
+				Operator:   "gt",
+				Threshold:  0.5,
+			},
+		},
+		{
+			name: "grouping after the call and threshold on the left",
+			expr: `10 < sum(count_over_time({__name__="errors_total", env!='dev'}[1h30m])) by (service)`,
+			want: &Condition{
+				Metric:     "errors_total",
+				Matchers:   []*Matcher{{Label: "env", Op: "neq", Value: "dev"}},
+				Window:     90 * time.Minute,
+				Aggregator: "count",
+				Group:      []string{"service"},
+				Operator:   "gt",
+				Threshold:  10,
+			},
+		},
+		{
+			name: "quantile",
+			expr: `quantile_over_time(0.99, rt{job="x"}[10m]) >= 1e3`,
+			want: &Condition{
+				Metric:     "rt",
+				Matchers:   []*Matcher{{Label: "job", Op: "eq", Value: "x"}},
+				Window:     10 * time.Minute,
+				Aggregator: "p99",
+				Operator:   "gte",
+				Threshold:  1000,
+			},
+		},
+		{name: "rate", expr: `rate(http_requests_total[5m]) > 1`, wantErr: true},
+		{name: "vector comparison", expr: `a > b`, wantErr: true},
+		{name: "logical operator", expr: `up == 0 and on() vector(1)`, wantErr: true},
+		{name: "without", expr: `sum without (pod) (up) > 1`, wantErr: true},
+		{name: "mismatched aggregations", expr: `max(avg_over_time(up[5m])) > 1`, wantErr: true},
+		{name: "offset", expr: `avg_over_time(up[5m] offset 1h) > 1`, wantErr: true},
+		{name: "no comparison", expr: `up`, wantErr: true},
+		{name: "unquoted matcher", expr: `up{job=node} > 1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpr(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseExpr() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	exprs := []string{
+		`up{job="node"} == 0`,
+		`avg by (instance, path) (avg_over_time(http_latency_seconds{job="api", path=~"/v1/.*"}[5m])) > 0.5`,
+		`sum by (service) (count_over_time(errors_total{env!="dev"}[1h30m])) > 10`,
+		`max by (host) (quantile_over_time(0.95, rt[10m])) <= 200`,
+		`last_over_time(load5{cluster="c1"}[3m]) != 1.5`,
+	}
+	for _, expr := range exprs {
+		cond, err := ParseExpr(expr)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q) error: %s", expr, err)
+		}
+		got, err := cond.String()
+		if err != nil {
+			t.Fatalf("String() error: %s", err)
+		}
+		if got != expr {
+			t.Errorf("String() = %q, want %q", got, expr)
+		}
+	}
+
+	_, err := (&Condition{Metric: "m", Window: time.Minute, Aggregator: "distinct_count", Operator: "gt"}).String()
+	if err == nil {
+		t.Errorf("String() with unsupported aggregator should fail")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "5m", want: 5 * time.Minute},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "2d", want: 48 * time.Hour},
+		{in: "500ms", want: 500 * time.Millisecond},
+		{in: "", wantErr: true},
+		{in: "5", wantErr: true},
+		{in: "m", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+		if !tt.wantErr && FormatDuration(got) != tt.in {
+			t.Errorf("FormatDuration(%v) = %q, want %q", got, FormatDuration(got), tt.in)
+		}
+	}
+}
+
+func TestNotifyTemplate(t *testing.T) {
+	text := "{{ $labels.instance }} latency is {{ $value | humanize }} {{ if gt $value 1.0 }}!{{ end }}"
+	got, unsupported := ToNotifyTemplate(text, "latency")
+	if want := "{{instance}} latency is {{latency}} {{ if gt $value 1.0 }}!{{ end }}"; got != want {
+		t.Errorf("ToNotifyTemplate() = %q, want %q", got, want)
+	}
+	if len(unsupported) != 2 {
+		t.Errorf("ToNotifyTemplate() unsupported = %v, want 2 actions", unsupported)
+	}
+	if got, want := FromNotifyTemplate("{{instance}} latency is {{latency}}", "latency"), "{{ $labels.instance }} latency is {{ $value }}"; got != want {
+		t.Errorf("FromNotifyTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := []byte(`
+groups:
+- name: example
+  rules:
+  - alert: HighLatency
+    expr: avg_over_time(latency{job="api"}[5m]) > 0.5
+    for: 10m
+    labels:
+      severity: page
+    annotations:
+      summary: High latency on {{ $labels.instance }}
+  - record: job:latency:avg
+    expr: avg(latency) by (job)
+`)
+	file, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error: %s", err)
+	}
+	if len(file.Groups) != 1 || len(file.Groups[0].Rules) != 2 {
+		t.Fatalf("Parse() = %+v", file)
+	}
+	rule := file.Groups[0].Rules[0]
+	if rule.Alert != "HighLatency" || rule.For != "10m" || rule.Labels["severity"] != "page" {
+		t.Errorf("Parse() rule = %+v", rule)
+	}
+	if _, err := Parse([]byte("groups:\n- name: g\n  rules:\n  - alert: A\n")); err == nil {
+		t.Errorf("Parse() without expr should fail")
+	}
+}
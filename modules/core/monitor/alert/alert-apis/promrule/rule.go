@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promrule
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+)
+
+// RuleFile is a prometheus rule file, see
+// https://prometheus.io/docs/prometheus/latest/configuration/alerting_rules/
+type RuleFile struct {
+	Groups []*RuleGroup `json:"groups"`
+}
+
+// RuleGroup .
+type RuleGroup struct {
+	Name     string  `json:"name"`
+	Interval string  `json:"interval,omitempty"`
+	Rules    []*Rule `json:"rules"`
+}
+
+// Rule is an alerting rule or a recording rule.
+type Rule struct {
+	Record      string            `json:"record,omitempty"`
+	Alert       string            `json:"alert,omitempty"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Parse parses the content of a prometheus rule file.
+func Parse(data []byte) (*RuleFile, error) {
+	file := &RuleFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid prometheus rule file: %s", err)
+	}
+	for i, group := range file.Groups {
+		if group == nil || group.Name == "" {
+			return nil, fmt.Errorf("invalid prometheus rule file: groups[%d] name is empty", i)
+		}
+		for j, rule := range group.Rules {
+			if rule == nil || (rule.Alert == "" && rule.Record == "") {
+				return nil, fmt.Errorf("invalid prometheus rule file: group %q rules[%d] has neither alert nor record", group.Name, j)
+			}
+			if rule.Expr == "" {
+				return nil, fmt.Errorf("invalid prometheus rule file: group %q rules[%d] expr is empty", group.Name, j)
+			}
+		}
+	}
+	return file, nil
+}
+
+// Marshal encodes the rule file as yaml.
+func (f *RuleFile) Marshal() ([]byte, error) {
+	return yaml.Marshal(f)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promrule
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	promTemplateAction = regexp.MustCompile(`\{\{-?\s*(.*?)\s*-?\}\}`)
+	promLabelRef       = regexp.MustCompile(`^\$labels\.([a-zA-Z_][a-zA-Z0-9_]*)(\s*\|.*)?$`)
+	promValueRef       = regexp.MustCompile(`^\$value(\s*\|.*)?$`)
+	erdaTemplateAction = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_.]*)\s*\}\}`)
+)
+
+// ToNotifyTemplate converts a prometheus annotation template into a notify template of customize alert.
+// {{ $labels.x }} becomes {{x}} and {{ $value }} becomes {{valueKey}}; the actions which can not be
+// converted are kept and returned so that they can be reported.
+func ToNotifyTemplate(text, valueKey string) (string, []string) {
+	var unsupported []string
+	result := promTemplateAction.ReplaceAllStringFunc(text, func(action string) string {
+		inner := promTemplateAction.FindStringSubmatch(action)[1]
+		if m := promLabelRef.FindStringSubmatch(inner); m != nil {
+			return "{{" + m[1] + "}}"
+		}
+		if promValueRef.MatchString(inner) {
+			return "{{" + valueKey + "}}"
+		}
+		unsupported = append(unsupported, action)
+		return action
+	})
+	return result, unsupported
+}
+
+// FromNotifyTemplate is the reverse of ToNotifyTemplate.
+func FromNotifyTemplate(text, valueKey string) string {
+	return erdaTemplateAction.ReplaceAllStringFunc(text, func(action string) string {
+		key := erdaTemplateAction.FindStringSubmatch(action)[1]
+		if key == valueKey {
+			return "{{ $value }}"
+		}
+		if strings.Contains(key, ".") {
+			return action
+		}
+		return "{{ $labels." + key + " }}"
+	})
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/erda-project/erda-infra/providers/i18n"
 	"github.com/erda-project/erda-infra/providers/mysql"
 	channelpb "github.com/erda-project/erda-proto-go/core/messenger/notifychannel/pb"
+	notifygrouppb "github.com/erda-project/erda-proto-go/core/messenger/notifygroup/pb"
 	"github.com/erda-project/erda-proto-go/core/monitor/alert/pb"
 	metricpb "github.com/erda-project/erda-proto-go/core/monitor/metric/pb"
 	"github.com/erda-project/erda/apistructs"
@@ -48,11 +49,12 @@ import (
 )
 
 type config struct {
-	OrgFilterTags               string `file:"org_filter_tags"`
-	MicroServiceFilterTags      string `file:"micro_service_filter_tags"`
-	MicroServiceOtherFilterTags string `file:"micro_service_other_filter_tags"`
-	SilencePolicy               string `file:"silence_policy"`
-	AlertConditions             string `file:"alert_conditions"`
+	OrgFilterTags               string        `file:"org_filter_tags"`
+	MicroServiceFilterTags      string        `file:"micro_service_filter_tags"`
+	MicroServiceOtherFilterTags string        `file:"micro_service_other_filter_tags"`
+	SilencePolicy               string        `file:"silence_policy"`
+	AlertConditions             string        `file:"alert_conditions"`
+	IncidentEscalationInterval  time.Duration `file:"incident_escalation_interval" default:"1m"`
 	Cassandra                   struct {
 		Enabled                 bool `file:"enabled"`
 		cassandra.SessionConfig `file:"session"`
@@ -82,7 +84,8 @@ type provider struct {
 	Metric        metricpb.MetricServiceServer `autowired:"erda.core.monitor.metric.MetricService"`
 	Perm          perm.Interface               `autowired:"permission"`
 	alertService  *alertService
-	NotifyChannel channelpb.NotifyChannelServiceServer `autowired:"erda.core.messenger.notifychannel.NotifyChannelService"`
+	NotifyChannel channelpb.NotifyChannelServiceServer   `autowired:"erda.core.messenger.notifychannel.NotifyChannelService"`
+	NotifyGroup   notifygrouppb.NotifyGroupServiceServer `autowired:"erda.core.messenger.notifygroup.NotifyGroupService" optional:"true"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -209,6 +212,14 @@ func (p *provider) Init(ctx servicehub.Context) error {
 			perm.NoPermMethod(MonitorService.GetAlertEvents),
 			perm.NoPermMethod(MonitorService.SuppressAlertEvent),
 			perm.NoPermMethod(MonitorService.CancelSuppressAlertEvent),
+			perm.NoPermMethod(MonitorService.ImportPrometheusRules),
+			perm.NoPermMethod(MonitorService.ExportPrometheusRules),
+			perm.NoPermMethod(MonitorService.GetAlertIncidentPolicy),
+			perm.NoPermMethod(MonitorService.UpdateAlertIncidentPolicy),
+			perm.NoPermMethod(MonitorService.QueryAlertIncidents),
+			perm.NoPermMethod(MonitorService.GetAlertIncident),
+			perm.NoPermMethod(MonitorService.AcknowledgeAlertIncident),
+			perm.NoPermMethod(MonitorService.ResolveAlertIncident),
 		),
 			p.audit.Audit(
 				audit.Method(MonitorService.UpdateOrgCustomizeAlert, audit.OrgScope, string(apistructs.UpdateOrgCustomAlert),
@@ -247,6 +258,37 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	return nil
 }
 
+// Run escalates the incidents which are not acknowledged in time.
+func (p *provider) Run(ctx context.Context) error {
+	if p.C.IncidentEscalationInterval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(p.C.IncidentEscalationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.a.EscalateIncidents(time.Now(), p.getOnCallUser); err != nil {
+				p.L.Errorf("failed to escalate alert incidents: %s", err)
+			}
+		}
+	}
+}
+
+func (p *provider) getOnCallUser(notifyGroupID int64) (string, error) {
+	if p.NotifyGroup == nil {
+		return "", nil
+	}
+	ctx := apis.WithInternalClientContext(context.Background(), "monitor")
+	resp, err := p.NotifyGroup.GetNotifyGroupOnCall(ctx, &notifygrouppb.GetNotifyGroupOnCallRequest{GroupID: notifyGroupID})
+	if err != nil || resp.Data == nil {
+		return "", err
+	}
+	return resp.Data.CurrentUser, nil
+}
+
 func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
 	switch {
 	case ctx.Service() == "erda.core.monitor.alert" || ctx.Type() == pb.AlertServiceServerType() || ctx.Type() == pb.AlertServiceHandlerType():
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/db"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/incident"
 	"github.com/erda-project/erda/pkg/database/gormutil"
 )
 
@@ -87,12 +88,21 @@ func (p *provider) invoke(key []byte, value []byte, topic *string, timestamp tim
 	if existEvent == nil {
 		//create
 		err = p.alertEventDB.CreateAlertEvent(alertEvent)
-		return err
 	} else {
 		//update
+		if existEvent.AlertState == incident.EventStateAcknowledged && alertEvent.AlertState == incident.EventStateAlert {
+			// keep the acknowledgement until the event recovers
+			alertEvent.AlertState = existEvent.AlertState
+		}
 		err = p.alertEventDB.UpdateAlertEvent(existEvent.Id, p.calcNeedUpdateFields(existEvent, alertEvent))
+	}
+	if err != nil {
 		return err
 	}
+	if err := p.processIncident(alertEvent); err != nil {
+		p.L.Errorf("failed to process incident of alert event %s: %s", alertEvent.Id, err)
+	}
+	return nil
 }
 
 var alertEventFieldColumnsMap = gormutil.GetFieldToColumnMap(reflect.TypeOf(db.AlertEvent{}))
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert_event
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/db"
+	"github.com/erda-project/erda/modules/core/monitor/alert/alert-apis/incident"
+)
+
+// processIncident groups the alerting event into an incident,
+// and resolves the incident when all of its events are recovered.
+func (p *provider) processIncident(event *db.AlertEvent) error {
+	switch event.AlertState {
+	case incident.EventStateAlert:
+		return p.attachIncident(event)
+	case incident.EventStateRecover:
+		return p.recoverIncident(event)
+	}
+	return nil
+}
+
+func (p *provider) attachIncident(event *db.AlertEvent) error {
+	policy, err := p.incidentPolicyDB.GetByScope(event.Scope, event.ScopeID)
+	if err != nil {
+		return err
+	}
+	var (
+		keys     []string
+		window   int64
+		policyID string
+	)
+	if policy != nil {
+		keys = strings.Split(policy.GroupKeys, ",")
+		window = policy.GroupWindow
+		policyID = policy.Id
+	}
+	groupKey, err := incident.GroupKey(keys, toIncidentEvent(event))
+	if err != nil {
+		return err
+	}
+
+	open, err := p.incidentDB.GetOpenByGroupKey(groupKey)
+	if err != nil {
+		return err
+	}
+	if open == nil || !incident.InGroupWindow(open.LastEventTime, event.LastTriggerTime, window) {
+		data := &db.AlertIncident{
+			OrgID:          event.OrgID,
+			Scope:          event.Scope,
+			ScopeID:        event.ScopeID,
+			PolicyID:       policyID,
+			GroupKey:       groupKey,
+			Title:          event.Name,
+			AlertLevel:     event.AlertLevel,
+			State:          incident.StateTriggered,
+			FirstEventTime: event.LastTriggerTime,
+			LastEventTime:  event.LastTriggerTime,
+			EventCount:     1,
+		}
+		if err := p.incidentDB.Create(data); err != nil {
+			return err
+		}
+		_, err = p.incidentEventDB.Link(event.OrgID, data.Id, event.Id)
+		return err
+	}
+
+	linked, err := p.incidentEventDB.Link(event.OrgID, open.Id, event.Id)
+	if err != nil {
+		return err
+	}
+	fields := map[string]interface{}{
+		"alert_level": incident.HigherLevel(open.AlertLevel, event.AlertLevel),
+	}
+	if event.LastTriggerTime.After(open.LastEventTime) {
+		fields["last_event_time"] = event.LastTriggerTime
+	}
+	if linked {
+		fields["event_count"] = gorm.Expr("event_count + 1")
+	}
+	return p.incidentDB.Update(open.Id, fields)
+}
+
+func (p *provider) recoverIncident(event *db.AlertEvent) error {
+	incidentID, err := p.incidentEventDB.GetOpenIncidentID(event.Id)
+	if err != nil || len(incidentID) == 0 {
+		return err
+	}
+	count, err := p.incidentEventDB.CountByEventState(incidentID, []string{incident.EventStateAlert, incident.EventStateAcknowledged})
+	if err != nil || count > 0 {
+		return err
+	}
+	return p.incidentDB.Update(incidentID, map[string]interface{}{
+		"state":       incident.StateResolved,
+		"resolved_at": time.Now(),
+	})
+}
+
+func toIncidentEvent(e *db.AlertEvent) *incident.Event {
+	return &incident.Event{
+		Scope:        e.Scope,
+		ScopeID:      e.ScopeID,
+		AlertID:      e.AlertID,
+		AlertName:    e.AlertName,
+		AlertGroup:   e.AlertGroup,
+		AlertSubject: e.AlertSubject,
+		AlertLevel:   e.AlertLevel,
+		AlertType:    e.AlertType,
+		RuleID:       e.RuleID,
+	}
+}
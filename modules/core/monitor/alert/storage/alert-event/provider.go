@@ -62,11 +62,18 @@ type provider struct {
 	L     logs.Logger
 	kafka kafka.Interface
 
-	alertEventDB *db.AlertEventDB
+	alertEventDB     *db.AlertEventDB
+	incidentPolicyDB *db.AlertIncidentPolicyDB
+	incidentDB       *db.AlertIncidentDB
+	incidentEventDB  *db.AlertIncidentEventDB
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
-	p.alertEventDB = &db.AlertEventDB{DB: ctx.Service("mysql").(mysql.Interface).DB()}
+	mysqlDB := ctx.Service("mysql").(mysql.Interface).DB()
+	p.alertEventDB = &db.AlertEventDB{DB: mysqlDB}
+	p.incidentPolicyDB = &db.AlertIncidentPolicyDB{DB: mysqlDB}
+	p.incidentDB = &db.AlertIncidentDB{DB: mysqlDB}
+	p.incidentEventDB = &db.AlertIncidentEventDB{DB: mysqlDB}
 	p.kafka = ctx.Service("kafka").(kafka.Interface)
 	return nil
 }
@@ -21,7 +21,9 @@ import (
 
 	"github.com/erda-project/erda-infra/base/logs"
 	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/httpserver"
 	"github.com/erda-project/erda-infra/providers/kafka"
+	"github.com/erda-project/erda/modules/core/monitor/log/persist/tail"
 	"github.com/erda-project/erda/modules/core/monitor/log/storage"
 	"github.com/erda-project/erda/modules/core/monitor/storekit"
 )
@@ -35,16 +37,19 @@ type (
 		IDKeys               []string                `file:"id_keys"`
 		PrintInvalidLog      bool                    `file:"print_invalid_log" default:"false"`
 		StorageWriterService string                  `file:"storage_writer_service" default:"log-storage-elasticsearch-writer"`
+		Tail                 tailConfig              `file:"tail"`
 	}
 	provider struct {
-		Cfg   *config
-		Log   logs.Logger
-		Kafka kafka.Interface `autowired:"kafka"`
+		Cfg    *config
+		Log    logs.Logger
+		Kafka  kafka.Interface   `autowired:"kafka"`
+		Router httpserver.Router `autowired:"http-router" optional:"true"`
 
 		storage   storage.Storage
 		stats     Statistics
 		validator Validator
 		metadata  MetadataProcessor
+		tail      *tail.Hub
 	}
 )
 
@@ -64,6 +69,17 @@ func (p *provider) Init(ctx servicehub.Context) (err error) {
 
 	p.stats = sharedStatistics
 
+	if p.Cfg.Tail.Enable {
+		p.tail = tail.NewHub(p.Cfg.Tail.MaxSubscribers, p.Cfg.Tail.BufferSize)
+		if err := p.initTailInput(); err != nil {
+			return err
+		}
+		ctx.AddTask(p.consumeTailLogs, servicehub.WithTaskName("log tail consumer"))
+		if p.Router != nil {
+			p.initRoutes(p.Router)
+		}
+	}
+
 	// add consumer task
 	for i := 0; i < p.Cfg.Parallelism; i++ {
 		ctx.AddTask(func(ctx context.Context) error {
@@ -0,0 +1,202 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	"github.com/erda-project/erda-infra/providers/kafka"
+	log "github.com/erda-project/erda/modules/core/monitor/log"
+	"github.com/erda-project/erda/modules/core/monitor/log/persist/tail"
+	"github.com/erda-project/erda/modules/core/monitor/storekit"
+	"github.com/erda-project/erda/modules/monitor/common"
+	"github.com/erda-project/erda/modules/monitor/common/permission"
+	api "github.com/erda-project/erda/pkg/common/httpapi"
+)
+
+type tailConfig struct {
+	Enable bool `file:"enable" default:"false"`
+	// Input is the topics and the group to tail, the default is the topics of persist consumers.
+	// The group must be unique to the instance to consume all partitions, because a subscriber
+	// may connect to any of the instances. The default is the group of persist consumers with the suffix of hostname.
+	Input          kafka.BatchReaderConfig `file:"input"`
+	MaxSubscribers int                     `file:"max_subscribers" default:"100"`
+	BufferSize     int                     `file:"buffer_size" default:"256"`
+	PingInterval   time.Duration           `file:"ping_interval" default:"30s"`
+	WriteTimeout   time.Duration           `file:"write_timeout" default:"10s"`
+	// AllowedOrigins are the domains allowed to connect besides DICE_ROOT_DOMAIN.
+	AllowedOrigins []string `file:"allowed_origins"`
+}
+
+func (p *provider) initTailInput() error {
+	input := &p.Cfg.Tail.Input
+	if len(input.Topics) <= 0 {
+		input.Topics = p.Cfg.Input.Topics
+	}
+	if len(input.Group) <= 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		input.Group = p.Cfg.Input.Group + "-tail-" + hostname
+	}
+	return nil
+}
+
+func (p *provider) consumeTailLogs(ctx context.Context) error {
+	r, err := p.Kafka.NewBatchReader(&p.Cfg.Tail.Input, kafka.WithReaderDecoder(p.decodeTailLog))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return storekit.BatchConsume(ctx, r, storekit.DefaultNopWriter, &storekit.BatchConsumeOptions{
+		BufferSize:          p.Cfg.BufferSize,
+		ReadTimeout:         p.Cfg.ReadTimeout,
+		ReadErrorHandler:    p.handleReadError,
+		WriteErrorHandler:   p.handleWriteError,
+		ConfirmErrorHandler: p.confirmErrorHandler,
+	})
+}
+
+func (p *provider) decodeTailLog(key, value []byte, topic *string, timestamp time.Time) (interface{}, error) {
+	if !p.tail.Active() {
+		return nil, nil
+	}
+	data := &log.LabeledLog{}
+	if err := json.Unmarshal(value, data); err != nil {
+		return nil, err
+	}
+	p.normalize(&data.Log)
+	p.tail.Publish(&data.Log)
+	return nil, nil
+}
+
+func (p *provider) initRoutes(routes httpserver.Router) {
+	routes.GET("/api/runtime/logs/actions/tail", p.tailRuntimeLog, permission.Intercepter(
+		permission.ScopeApp, permission.QueryValue("applicationId"),
+		common.ResourceRuntime, permission.ActionGet,
+	))
+}
+
+type tailMessage struct {
+	Type    string   `json:"type"`
+	Log     *log.Log `json:"log,omitempty"`
+	Dropped int64    `json:"dropped,omitempty"`
+}
+
+// checkTailOrigin allows the requests without Origin, and the origins of the same host, DICE_ROOT_DOMAIN or AllowedOrigins.
+func (p *provider) checkTailOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) <= 0 {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	domains := append(strings.Split(os.Getenv("DICE_ROOT_DOMAIN"), ","), p.Cfg.Tail.AllowedOrigins...)
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.TrimSpace(domain), ".")
+		if len(domain) <= 0 {
+			continue
+		}
+		if strings.EqualFold(host, domain) || strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailRuntimeLog follows the logs of the application like `kubectl logs -f`, the logs are filtered by tail.ParseFilter.
+// The logs are dropped if the client can't keep up, and the number of the dropped logs is sent every second.
+func (p *provider) tailRuntimeLog(w http.ResponseWriter, r *http.Request, params *struct {
+	ApplicationID string `query:"applicationId" validate:"required"`
+}) interface{} {
+	values := r.URL.Query()
+	values.Set(tail.TagsPrefix+"dice_application_id", params.ApplicationID)
+	filter, err := tail.ParseFilter(values)
+	if err != nil {
+		return api.Errors.InvalidParameter(err)
+	}
+	sub, err := p.tail.Subscribe(filter)
+	if err != nil {
+		return api.Errors.Internal(err)
+	}
+	defer sub.Close()
+
+	upgrader := websocket.Upgrader{CheckOrigin: p.checkTailOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		p.Log.Warnf("failed to upgrade log tail connection: %v", err)
+		return nil
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(p.Cfg.Tail.PingInterval)
+	defer ping.Stop()
+	report := time.NewTicker(time.Second)
+	defer report.Stop()
+	write := func(msgType int, data interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(p.Cfg.Tail.WriteTimeout))
+		if msgType == websocket.PingMessage {
+			return conn.WriteMessage(msgType, nil)
+		}
+		return conn.WriteJSON(data)
+	}
+	for {
+		var err error
+		select {
+		case <-closed:
+			return nil
+		case <-r.Context().Done():
+			return nil
+		case data := <-sub.C:
+			err = write(websocket.TextMessage, &tailMessage{Type: "log", Log: data})
+		case <-report.C:
+			if dropped := sub.Dropped(); dropped > 0 {
+				err = write(websocket.TextMessage, &tailMessage{Type: "dropped", Dropped: dropped})
+			}
+		case <-ping.C:
+			err = write(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			p.Log.Debugf("failed to write log tail message: %v", err)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tail
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/erda-project/erda/modules/core/monitor/log"
+)
+
+// TagsPrefix is the prefix of the query parameters which filter the logs by tags.
+const TagsPrefix = "tags."
+
+// Filter selects the logs to tail.
+type Filter struct {
+	ID       string
+	Source   string
+	Stream   string
+	Tags     map[string]string
+	Keywords []string
+	Pattern  *regexp.Regexp
+	Levels   map[string]bool
+}
+
+// ParseFilter parses the filter from the query parameters:
+//
+//	id, source, stream: match the fields of the log
+//	tags.<key>: match the tag exactly
+//	keyword: the content contains all the keywords
+//	pattern: the content matches the regexp
+//	level: the comma separated levels
+func ParseFilter(values url.Values) (*Filter, error) {
+	f := &Filter{
+		ID:       values.Get("id"),
+		Source:   values.Get("source"),
+		Stream:   values.Get("stream"),
+		Tags:     make(map[string]string),
+		Keywords: values["keyword"],
+	}
+	for key, vals := range values {
+		if strings.HasPrefix(key, TagsPrefix) && len(vals) > 0 {
+			f.Tags[key[len(TagsPrefix):]] = vals[0]
+		}
+	}
+	if pattern := values.Get("pattern"); len(pattern) > 0 {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", err)
+		}
+		f.Pattern = reg
+	}
+	if level := values.Get("level"); len(level) > 0 {
+		f.Levels = make(map[string]bool)
+		for _, item := range strings.Split(level, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				f.Levels[strings.ToUpper(item)] = true
+			}
+		}
+	}
+	if len(f.ID) <= 0 && len(f.Tags) <= 0 {
+		return nil, fmt.Errorf("id or tags is required")
+	}
+	return f, nil
+}
+
+// Match reports whether the log is selected by the filter, the cheap conditions are checked first.
+func (f *Filter) Match(l *log.Log) bool {
+	if len(f.ID) > 0 && l.ID != f.ID {
+		return false
+	}
+	if len(f.Source) > 0 && l.Source != f.Source {
+		return false
+	}
+	if len(f.Stream) > 0 && l.Stream != f.Stream {
+		return false
+	}
+	for k, v := range f.Tags {
+		if l.Tags[k] != v {
+			return false
+		}
+	}
+	if f.Levels != nil && !f.Levels[l.Tags["level"]] {
+		return false
+	}
+	for _, keyword := range f.Keywords {
+		if !strings.Contains(l.Content, keyword) {
+			return false
+		}
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(l.Content) {
+		return false
+	}
+	return true
+}
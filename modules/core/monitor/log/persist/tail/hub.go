@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tail
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/erda-project/erda/modules/core/monitor/log"
+)
+
+// ErrTooManySubscribers .
+var ErrTooManySubscribers = errors.New("too many log tail subscribers")
+
+// Hub dispatches the consumed logs to the subscribers.
+// Publish never blocks the consumer, the logs are dropped if the buffer of a subscriber is full.
+type Hub struct {
+	maxSubscribers int
+	bufferSize     int
+	lock           sync.RWMutex
+	subscribers    map[*Subscriber]struct{}
+	count          int32
+}
+
+// Subscriber receives the matched logs from C.
+type Subscriber struct {
+	C       chan *log.Log
+	filter  *Filter
+	dropped int64
+	hub     *Hub
+	once    sync.Once
+}
+
+// NewHub .
+func NewHub(maxSubscribers, bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 256
+	}
+	return &Hub{
+		maxSubscribers: maxSubscribers,
+		bufferSize:     bufferSize,
+		subscribers:    make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe .
+func (h *Hub) Subscribe(filter *Filter) (*Subscriber, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.maxSubscribers > 0 && len(h.subscribers) >= h.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	s := &Subscriber{
+		C:      make(chan *log.Log, h.bufferSize),
+		filter: filter,
+		hub:    h,
+	}
+	h.subscribers[s] = struct{}{}
+	atomic.StoreInt32(&h.count, int32(len(h.subscribers)))
+	return s, nil
+}
+
+// Active reports whether there are subscribers, it's cheap enough to be checked for every log.
+func (h *Hub) Active() bool {
+	return atomic.LoadInt32(&h.count) > 0
+}
+
+// Publish dispatches a copy of the log, because the log may be modified by the storage writer later.
+func (h *Hub) Publish(l *log.Log) {
+	if !h.Active() {
+		return
+	}
+	var data *log.Log
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for s := range h.subscribers {
+		if !s.filter.Match(l) {
+			continue
+		}
+		if data == nil {
+			data = copyLog(l)
+		}
+		select {
+		case s.C <- data:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	}
+}
+
+// Dropped returns the number of the logs dropped since last call.
+func (s *Subscriber) Dropped() int64 {
+	return atomic.SwapInt64(&s.dropped, 0)
+}
+
+// Close unsubscribes from the hub.
+func (s *Subscriber) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		delete(h.subscribers, s)
+		atomic.StoreInt32(&h.count, int32(len(h.subscribers)))
+	})
+}
+
+func copyLog(l *log.Log) *log.Log {
+	data := *l
+	data.Tags = make(map[string]string, len(l.Tags))
+	for k, v := range l.Tags {
+		data.Tags[k] = v
+	}
+	return &data
+}
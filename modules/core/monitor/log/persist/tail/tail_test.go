@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tail
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/erda-project/erda/modules/core/monitor/log"
+)
+
+func TestFilter_Match(t *testing.T) {
+	l := &log.Log{
+		ID:      "c1",
+		Source:  "container",
+		Stream:  "stdout",
+		Content: "GET /api/users failed: read timeout",
+		Tags:    map[string]string{"level": "ERROR", "dice_application_id": "1"},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "id=c1", want: true},
+		{query: "id=c2", want: false},
+		{query: "tags.dice_application_id=1&stream=stdout", want: true},
+		{query: "tags.dice_application_id=2", want: false},
+		{query: "id=c1&keyword=timeout&keyword=users", want: true},
+		{query: "id=c1&keyword=timeout&keyword=orders", want: false},
+		{query: "id=c1&pattern=" + url.QueryEscape(`failed: \w+ timeout`), want: true},
+		{query: "id=c1&level=warn,error", want: true},
+		{query: "id=c1&level=INFO", want: false},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		f, err := ParseFilter(values)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) error: %s", tt.query, err)
+		}
+		if got := f.Match(l); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	for _, query := range []string{"keyword=timeout", "id=c1&pattern=" + url.QueryEscape("(")} {
+		values, _ := url.ParseQuery(query)
+		if _, err := ParseFilter(values); err == nil {
+			t.Errorf("ParseFilter(%q) should fail", query)
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	h := NewHub(2, 2)
+	if h.Active() {
+		t.Fatalf("Active() without subscribers should be false")
+	}
+	s1, _ := h.Subscribe(&Filter{ID: "c1"})
+	s2, _ := h.Subscribe(&Filter{ID: "c2"})
+	if _, err := h.Subscribe(&Filter{ID: "c3"}); err != ErrTooManySubscribers {
+		t.Errorf("Subscribe() over the max subscribers error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		h.Publish(&log.Log{ID: "c1"})
+	}
+	if len(s1.C) != 2 || len(s2.C) != 0 {
+		t.Errorf("Publish() buffered %d and %d logs", len(s1.C), len(s2.C))
+	}
+	if dropped := s1.Dropped(); dropped != 1 {
+		t.Errorf("Dropped() = %d, want 1", dropped)
+	}
+	if dropped := s1.Dropped(); dropped != 0 {
+		t.Errorf("Dropped() should be reset, got %d", dropped)
+	}
+	s1.Close()
+	s1.Close()
+	s2.Close()
+	if h.Active() {
+		t.Errorf("Active() after all subscribers closed should be false")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda/modules/core/monitor/metric"
+	"github.com/erda-project/erda/modules/core/monitor/metric/persist/cardinality"
+	"github.com/erda-project/erda/modules/core/monitor/metric/storage"
+)
+
+const (
+	// MetricCardinality is the metric of the cardinality snapshots.
+	MetricCardinality = "_metric_cardinality"
+	// MetricCardinalityExceeded is the event metric written when a metric exceeds the limit in a window.
+	MetricCardinalityExceeded = "_metric_cardinality_exceeded"
+)
+
+// ErrCardinalityExceeded .
+var ErrCardinalityExceeded = errors.New("metric cardinality exceeds the limit")
+
+// CardinalityLimiter .
+type CardinalityLimiter interface {
+	Limit(m *metric.Metric) error
+	Top(tenant string, n int) ([]*cardinality.Entry, error)
+	History(tenant string, n int) ([]*cardinality.Snapshot, error)
+}
+
+type nopCardinalityLimiter struct{}
+
+func (*nopCardinalityLimiter) Limit(m *metric.Metric) error { return nil }
+func (*nopCardinalityLimiter) Top(tenant string, n int) ([]*cardinality.Entry, error) {
+	return []*cardinality.Entry{}, nil
+}
+func (*nopCardinalityLimiter) History(tenant string, n int) ([]*cardinality.Snapshot, error) {
+	return []*cardinality.Snapshot{}, nil
+}
+
+// NopCardinalityLimiter .
+var NopCardinalityLimiter CardinalityLimiter = &nopCardinalityLimiter{}
+
+func newCardinalityLimiter(cfg *config, p *provider) (CardinalityLimiter, error) {
+	if !cfg.Cardinality.Enable {
+		return NopCardinalityLimiter, nil
+	}
+	if p.Redis == nil {
+		return nil, errors.New("redis is required by the metric cardinality limiter to share the series of the instances")
+	}
+	return &cardinalityLimiter{
+		tracker: cardinality.NewTracker(cardinality.Options{
+			Window:       cfg.Cardinality.Window,
+			History:      cfg.Cardinality.History,
+			TopN:         cfg.Cardinality.TopN,
+			Limit:        cfg.Cardinality.Limit,
+			TenantLimits: cfg.Cardinality.TenantLimits,
+			Store:        cardinality.NewRedisStore(p.Redis, 2*cfg.Cardinality.Window),
+		}),
+		events:   make(chan *metric.Metric, 128),
+		interval: time.Minute,
+		stats:    p.stats,
+		log:      p.Log,
+		storage:  p.StorageWriter,
+	}, nil
+}
+
+type cardinalityLimiter struct {
+	tracker  *cardinality.Tracker
+	events   chan *metric.Metric
+	interval time.Duration
+	stats    Statistics
+	log      logs.Logger
+	storage  storage.Storage
+	failures int64
+	lastErr  atomic.Value
+}
+
+// Limit counts the series of the metric per org, and returns ErrCardinalityExceeded if the metric should be dropped.
+// The metric is accepted if the series can not be counted, and the errors are logged in Run.
+func (l *cardinalityLimiter) Limit(m *metric.Metric) error {
+	if strings.HasPrefix(m.Name, "_") {
+		return nil
+	}
+	tenant := m.Tags["org_name"]
+	result, err := l.tracker.Observe(tenant, m.Name, m.Tags, time.Now())
+	if err != nil {
+		atomic.AddInt64(&l.failures, 1)
+		l.lastErr.Store(err.Error())
+		return nil
+	}
+	if result.Decision == cardinality.Accept {
+		return nil
+	}
+	action := cardinality.ActionDrop
+	if result.Decision == cardinality.Truncate {
+		action = cardinality.ActionTruncate
+	}
+	l.stats.CardinalityLimited(m, action)
+	if result.Exceeded {
+		l.log.Warnf("metric %q of org %q exceeds the cardinality limit, new series will be %s", m.Name, tenant, action)
+		select {
+		case l.events <- exceededEvent(tenant, m, action, l.tracker.LimitOf(tenant)):
+		default:
+		}
+	}
+	if result.Decision == cardinality.Drop {
+		return ErrCardinalityExceeded
+	}
+	return nil
+}
+
+func (l *cardinalityLimiter) Top(tenant string, n int) ([]*cardinality.Entry, error) {
+	return l.tracker.Top(tenant, n)
+}
+
+func (l *cardinalityLimiter) History(tenant string, n int) ([]*cardinality.Snapshot, error) {
+	return l.tracker.History(tenant, n)
+}
+
+// Run writes the exceeded events and the snapshots of the windows into the storage,
+// the snapshot of a window is written by only one of the instances.
+func (l *cardinalityLimiter) Run(ctx context.Context) error {
+	w, err := l.storage.NewWriter(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-l.events:
+			if _, err := w.WriteN(e); err != nil {
+				l.log.Errorf("failed to write metric cardinality event: %v", err)
+			}
+		case now := <-ticker.C:
+			if n := atomic.SwapInt64(&l.failures, 0); n > 0 {
+				l.log.Errorf("failed to count the series of %d metrics, accepted them: %v", n, l.lastErr.Load())
+			}
+			s, err := l.tracker.Roll(now)
+			if err != nil {
+				l.log.Errorf("failed to roll metric cardinality window: %v", err)
+				continue
+			}
+			if s == nil || len(s.Entries) <= 0 {
+				continue
+			}
+			buf := make([]interface{}, 0, len(s.Entries))
+			for _, e := range s.Entries {
+				buf = append(buf, snapshotMetric(s, e))
+			}
+			if _, err := w.WriteN(buf...); err != nil {
+				l.log.Errorf("failed to write metric cardinality snapshot: %v", err)
+			}
+		}
+	}
+}
+
+func exceededEvent(tenant string, m *metric.Metric, action string, limit cardinality.Limit) *metric.Metric {
+	return &metric.Metric{
+		Name:      MetricCardinalityExceeded,
+		Timestamp: time.Now().UnixNano(),
+		Tags: map[string]string{
+			"org_name":     tenant,
+			"cluster_name": m.Tags["cluster_name"],
+			"metric_name":  m.Name,
+			"action":       action,
+		},
+		Fields: map[string]interface{}{
+			"max_series":     int64(limit.MaxSeries),
+			"max_tag_values": int64(limit.MaxTagValues),
+		},
+	}
+}
+
+func snapshotMetric(s *cardinality.Snapshot, e *cardinality.Entry) *metric.Metric {
+	fields := map[string]interface{}{
+		"series":    int64(e.Series),
+		"dropped":   e.Dropped,
+		"truncated": e.Truncated,
+	}
+	tags := make([]string, 0, len(e.Tags))
+	for _, t := range e.Tags {
+		tags = append(tags, t.Tag)
+		fields["tag_values."+t.Tag] = int64(t.Values)
+	}
+	fields["tags"] = tags
+	return &metric.Metric{
+		Name:      MetricCardinality,
+		Timestamp: s.Start.UnixNano(),
+		Tags: map[string]string{
+			"org_name":    e.Tenant,
+			"metric_name": e.Metric,
+		},
+		Fields: fields,
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const redisKeyPrefix = "metric-cardinality:"
+
+// addSeriesScript adds the series if the number of series has not reached the max, and adds the values of its tags.
+// KEYS: series, metrics of the window, tags, values of each tag.
+// ARGV: series id, max, ttl, metric of the window, names of the tags, values of the tags.
+var addSeriesScript = redis.NewScript(`
+local ttl = tonumber(ARGV[3])
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+local max = tonumber(ARGV[2])
+if max > 0 and redis.call('SCARD', KEYS[1]) >= max then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[1])
+redis.call('SADD', KEYS[2], ARGV[4])
+local n = #KEYS - 3
+for i = 1, n do
+	redis.call('SADD', KEYS[3], ARGV[4 + i])
+	redis.call('SADD', KEYS[3 + i], ARGV[4 + n + i])
+	redis.call('EXPIRE', KEYS[3 + i], ttl)
+end
+for i = 1, 3 do
+	redis.call('EXPIRE', KEYS[i], ttl)
+end
+return 1
+`)
+
+type redisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisStore returns a Store shared by the instances, the keys of a window expire after ttl.
+func NewRedisStore(client *redis.Client, ttl time.Duration) Store {
+	return &redisStore{client: client, ttl: ttl}
+}
+
+func windowKey(start time.Time) string {
+	return redisKeyPrefix + strconv.FormatInt(start.Unix(), 10)
+}
+
+func metricMember(key Key) string { return key.Tenant + "\n" + key.Metric }
+
+func metricKey(key Key) string {
+	return windowKey(key.Start) + ":" + key.Tenant + ":" + key.Metric
+}
+
+func (s *redisStore) HasSeries(key Key, id uint64) (bool, error) {
+	return s.client.SIsMember(metricKey(key)+":series", strconv.FormatUint(id, 36)).Result()
+}
+
+func (s *redisStore) OverflowTags(key Key, tags map[string]string, max int) ([]string, error) {
+	names := make([]string, 0, len(tags))
+	members := make([]*redis.BoolCmd, 0, len(tags))
+	counts := make([]*redis.IntCmd, 0, len(tags))
+	prefix := metricKey(key) + ":tag:"
+	_, err := s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for k, v := range tags {
+			names = append(names, k)
+			members = append(members, pipe.SIsMember(prefix+k, v))
+			counts = append(counts, pipe.SCard(prefix+k))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for i, k := range names {
+		if !members[i].Val() && counts[i].Val() >= int64(max) {
+			list = append(list, k)
+		}
+	}
+	return list, nil
+}
+
+func (s *redisStore) AddSeries(key Key, id uint64, tags map[string]string, max int) (bool, error) {
+	mkey := metricKey(key)
+	keys := []string{mkey + ":series", windowKey(key.Start) + ":metrics", mkey + ":tags"}
+	args := []interface{}{strconv.FormatUint(id, 36), max, int64(s.ttl.Seconds()), metricMember(key)}
+	values := make([]interface{}, 0, len(tags))
+	for k, v := range tags {
+		keys = append(keys, mkey+":tag:"+k)
+		args = append(args, k)
+		values = append(values, v)
+	}
+	added, err := addSeriesScript.Run(s.client, keys, append(args, values...)...).Int64()
+	if err != nil {
+		return false, err
+	}
+	return added == 1, nil
+}
+
+func (s *redisStore) Incr(key Key, counter string) error {
+	stat := metricKey(key) + ":stat"
+	_, err := s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(stat, counter, 1)
+		pipe.Expire(stat, s.ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) Exceed(key Key) (bool, error) {
+	stat := metricKey(key) + ":stat"
+	var first *redis.BoolCmd
+	_, err := s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		first = pipe.HSetNX(stat, "exceeded", 1)
+		pipe.Expire(stat, s.ttl)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return first.Val(), nil
+}
+
+func (s *redisStore) Entries(start time.Time) ([]*Entry, error) {
+	members, err := s.client.SMembers(windowKey(start) + ":metrics").Result()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list   []*Entry
+		series []*redis.IntCmd
+		stats  []*redis.StringStringMapCmd
+		tags   []*redis.StringSliceCmd
+	)
+	_, err = s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, m := range members {
+			parts := strings.SplitN(m, "\n", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key := Key{Start: start, Tenant: parts[0], Metric: parts[1]}
+			list = append(list, &Entry{Tenant: key.Tenant, Metric: key.Metric})
+			mkey := metricKey(key)
+			series = append(series, pipe.SCard(mkey+":series"))
+			stats = append(stats, pipe.HGetAll(mkey+":stat"))
+			tags = append(tags, pipe.SMembers(mkey+":tags"))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	values := make([][]*redis.IntCmd, len(list))
+	_, err = s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, e := range list {
+			e.Series = int(series[i].Val())
+			e.Dropped, _ = strconv.ParseInt(stats[i].Val()[CounterDropped], 10, 64)
+			e.Truncated, _ = strconv.ParseInt(stats[i].Val()[CounterTruncated], 10, 64)
+			mkey := metricKey(Key{Start: start, Tenant: e.Tenant, Metric: e.Metric})
+			for _, tag := range tags[i].Val() {
+				values[i] = append(values[i], pipe.SCard(mkey+":tag:"+tag))
+			}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	for i, e := range list {
+		for j, tag := range tags[i].Val() {
+			e.Tags = append(e.Tags, &TagCardinality{Tag: tag, Values: int(values[i][j].Val())})
+		}
+	}
+	return list, nil
+}
+
+func (s *redisStore) ClaimSnapshot(start time.Time) (bool, error) {
+	return s.client.SetNX(windowKey(start)+":snapshot", 1, s.ttl).Result()
+}
+
+func (s *redisStore) SaveSnapshot(snap *Snapshot, n int) error {
+	byts, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	key := redisKeyPrefix + "snapshots"
+	_, err = s.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.LPush(key, byts)
+		pipe.LTrim(key, 0, int64(n-1))
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) Snapshots() ([]*Snapshot, error) {
+	items, err := s.client.LRange(redisKeyPrefix+"snapshots", 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Snapshot, 0, len(items))
+	for _, item := range items {
+		snap := &Snapshot{}
+		if err := json.Unmarshal([]byte(item), snap); err != nil {
+			continue
+		}
+		list = append(list, snap)
+	}
+	return list, nil
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// counters of a metric
+const (
+	CounterDropped   = "dropped"
+	CounterTruncated = "truncated"
+)
+
+// Key is a metric of a tenant in a window.
+type Key struct {
+	Start  time.Time
+	Tenant string
+	Metric string
+}
+
+// Store keeps the series of the windows and the snapshots. It is shared by the instances,
+// so that the limits are enforced on the series of all the instances.
+type Store interface {
+	// HasSeries returns whether the series has been added into the window.
+	HasSeries(key Key, id uint64) (bool, error)
+	// OverflowTags returns the tags whose values are new, and whose number of values has reached max.
+	OverflowTags(key Key, tags map[string]string, max int) ([]string, error)
+	// AddSeries adds the series and the values of its tags, it returns false if the number of series has reached max.
+	AddSeries(key Key, id uint64, tags map[string]string, max int) (bool, error)
+	// Incr increases the counter of the metric.
+	Incr(key Key, counter string) error
+	// Exceed marks the metric exceeded, it returns true only at the first time in the window.
+	Exceed(key Key) (bool, error)
+	// Entries returns the cardinality of the metrics in the window.
+	Entries(start time.Time) ([]*Entry, error)
+	// ClaimSnapshot returns true if the snapshot of the window has not been claimed by another instance.
+	ClaimSnapshot(start time.Time) (bool, error)
+	// SaveSnapshot saves the snapshot, and keeps the latest n snapshots.
+	SaveSnapshot(s *Snapshot, n int) error
+	// Snapshots returns the snapshots, the latest first.
+	Snapshots() ([]*Snapshot, error)
+}
+
+type memoryStore struct {
+	lock      sync.Mutex
+	metrics   map[Key]*metricSet
+	snapshots []*Snapshot
+}
+
+type metricSet struct {
+	series   map[uint64]struct{}
+	tags     map[string]map[string]struct{}
+	counters map[string]int64
+	exceeded bool
+}
+
+// NewMemoryStore returns a Store which is not shared by the instances.
+func NewMemoryStore() Store {
+	return &memoryStore{metrics: make(map[Key]*metricSet)}
+}
+
+func (s *memoryStore) get(key Key) *metricSet {
+	ms, ok := s.metrics[key]
+	if !ok {
+		ms = &metricSet{
+			series:   make(map[uint64]struct{}),
+			tags:     make(map[string]map[string]struct{}),
+			counters: make(map[string]int64),
+		}
+		s.metrics[key] = ms
+	}
+	return ms
+}
+
+func (s *memoryStore) HasSeries(key Key, id uint64) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.get(key).series[id]
+	return ok, nil
+}
+
+func (s *memoryStore) OverflowTags(key Key, tags map[string]string, max int) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ms := s.get(key)
+	var list []string
+	for k, v := range tags {
+		values := ms.tags[k]
+		if _, ok := values[v]; !ok && len(values) >= max {
+			list = append(list, k)
+		}
+	}
+	return list, nil
+}
+
+func (s *memoryStore) AddSeries(key Key, id uint64, tags map[string]string, max int) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ms := s.get(key)
+	if _, ok := ms.series[id]; ok {
+		return true, nil
+	}
+	if max > 0 && len(ms.series) >= max {
+		return false, nil
+	}
+	ms.series[id] = struct{}{}
+	for k, v := range tags {
+		values, ok := ms.tags[k]
+		if !ok {
+			values = make(map[string]struct{})
+			ms.tags[k] = values
+		}
+		values[v] = struct{}{}
+	}
+	return true, nil
+}
+
+func (s *memoryStore) Incr(key Key, counter string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.get(key).counters[counter]++
+	return nil
+}
+
+func (s *memoryStore) Exceed(key Key) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ms := s.get(key)
+	if ms.exceeded {
+		return false, nil
+	}
+	ms.exceeded = true
+	return true, nil
+}
+
+func (s *memoryStore) Entries(start time.Time) ([]*Entry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var list []*Entry
+	for key, ms := range s.metrics {
+		if !key.Start.Equal(start) {
+			continue
+		}
+		e := &Entry{
+			Tenant:    key.Tenant,
+			Metric:    key.Metric,
+			Series:    len(ms.series),
+			Dropped:   ms.counters[CounterDropped],
+			Truncated: ms.counters[CounterTruncated],
+		}
+		for tag, values := range ms.tags {
+			e.Tags = append(e.Tags, &TagCardinality{Tag: tag, Values: len(values)})
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func (s *memoryStore) ClaimSnapshot(start time.Time) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, snap := range s.snapshots {
+		if snap.Start.Equal(start) {
+			return false, nil
+		}
+	}
+	// the windows before the claimed one are not used any more.
+	for key := range s.metrics {
+		if key.Start.Before(start) {
+			delete(s.metrics, key)
+		}
+	}
+	return true, nil
+}
+
+func (s *memoryStore) SaveSnapshot(snap *Snapshot, n int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.snapshots = append(s.snapshots, snap)
+	sort.Slice(s.snapshots, func(i, j int) bool { return s.snapshots[i].Start.After(s.snapshots[j].Start) })
+	if len(s.snapshots) > n {
+		s.snapshots = s.snapshots[:n]
+	}
+	return nil
+}
+
+func (s *memoryStore) Snapshots() ([]*Snapshot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*Snapshot(nil), s.snapshots...), nil
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TruncatedValue replaces the values of the tags which exceed the limit.
+const TruncatedValue = "__truncated__"
+
+// limit actions
+const (
+	ActionDrop     = "drop"
+	ActionTruncate = "truncate"
+)
+
+// Limit of the series of a tenant, zero means no limit.
+type Limit struct {
+	MaxSeries    int    `file:"max_series" json:"maxSeries"`
+	MaxTagValues int    `file:"max_tag_values" json:"maxTagValues"`
+	Action       string `file:"action" json:"action"`
+}
+
+// Decision of a series.
+type Decision int
+
+// decisions
+const (
+	Accept Decision = iota
+	Truncate
+	Drop
+)
+
+// Result of the series observed by the Tracker.
+type Result struct {
+	Decision Decision
+	// TruncatedTags are the tags whose values have been replaced by TruncatedValue.
+	TruncatedTags []string
+	// Exceeded is true only at the first time the metric exceeds the limit in a window.
+	Exceeded bool
+}
+
+// TagCardinality is the number of distinct values of a tag.
+type TagCardinality struct {
+	Tag    string `json:"tag"`
+	Values int    `json:"values"`
+}
+
+// Entry is the cardinality of a metric of a tenant.
+type Entry struct {
+	Tenant    string            `json:"tenant"`
+	Metric    string            `json:"metric"`
+	Series    int               `json:"series"`
+	Dropped   int64             `json:"dropped"`
+	Truncated int64             `json:"truncated"`
+	Tags      []*TagCardinality `json:"tags"`
+}
+
+// Snapshot is the top entries of a window.
+type Snapshot struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Entries []*Entry  `json:"entries"`
+}
+
+// Options of Tracker.
+type Options struct {
+	// Window is the duration in which the series are counted.
+	Window time.Duration
+	// History is the number of the snapshots of the past windows to keep.
+	History int
+	// TopN is the number of the entries kept in a snapshot.
+	TopN int
+	// MaxTopTags is the number of the tags kept in an entry.
+	MaxTopTags int
+	Limit      Limit
+	// TenantLimits overwrite Limit for the tenants.
+	TenantLimits map[string]Limit
+	// Store keeps the series shared by the instances, the default is an in-memory store of the instance.
+	Store Store
+}
+
+// Tracker counts the series of the metrics per tenant in tumbling windows, and limits the new series.
+// The series are counted in the Store, so the limits apply to the series of all the instances.
+type Tracker struct {
+	opts  Options
+	store Store
+	lock  sync.Mutex
+	start time.Time
+	// seen are the series accepted by the instance in the current window, to avoid querying the Store for them.
+	seen map[Key]map[uint64]struct{}
+}
+
+// NewTracker .
+func NewTracker(opts Options) *Tracker {
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = 100
+	}
+	if opts.MaxTopTags <= 0 {
+		opts.MaxTopTags = 5
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	return &Tracker{
+		opts:  opts,
+		store: opts.Store,
+		seen:  make(map[Key]map[uint64]struct{}),
+	}
+}
+
+// LimitOf returns the limit of the tenant.
+func (t *Tracker) LimitOf(tenant string) Limit {
+	if limit, ok := t.opts.TenantLimits[tenant]; ok {
+		return limit
+	}
+	return t.opts.Limit
+}
+
+// Observe counts the series of the metric, the values of the tags which exceed MaxTagValues
+// are replaced by TruncatedValue in place if the action of the limit is truncate.
+// The tags with the built-in _ prefixes are ignored.
+func (t *Tracker) Observe(tenant, metric string, tags map[string]string, now time.Time) (*Result, error) {
+	limit := t.LimitOf(tenant)
+
+	t.lock.Lock()
+	t.roll(now)
+	key := Key{Start: t.start, Tenant: tenant, Metric: metric}
+	seen, ok := t.seen[key]
+	if !ok {
+		seen = make(map[uint64]struct{})
+		t.seen[key] = seen
+	}
+	t.lock.Unlock()
+
+	id := seriesID(tags)
+	if t.isSeen(seen, id) {
+		return &Result{Decision: Accept}, nil
+	}
+	if ok, err := t.store.HasSeries(key, id); err != nil {
+		return nil, err
+	} else if ok {
+		t.markSeen(seen, id)
+		return &Result{Decision: Accept}, nil
+	}
+
+	// the series is new, check the values of tags before counting them.
+	var overflow []string
+	if limit.MaxTagValues > 0 {
+		list, err := t.store.OverflowTags(key, userTags(tags), limit.MaxTagValues)
+		if err != nil {
+			return nil, err
+		}
+		overflow = list
+	}
+	result := &Result{Decision: Accept}
+	if len(overflow) > 0 {
+		if limit.Action != ActionTruncate {
+			return t.exceed(key, result, Drop, CounterDropped)
+		}
+		sort.Strings(overflow)
+		for _, k := range overflow {
+			tags[k] = TruncatedValue
+		}
+		result.TruncatedTags = overflow
+		if _, err := t.exceed(key, result, Truncate, CounterTruncated); err != nil {
+			return nil, err
+		}
+		id = seriesID(tags)
+		if t.isSeen(seen, id) {
+			return result, nil
+		}
+		if ok, err := t.store.HasSeries(key, id); err != nil {
+			return nil, err
+		} else if ok {
+			t.markSeen(seen, id)
+			return result, nil
+		}
+	}
+	return t.addSeries(key, seen, id, tags, limit, result)
+}
+
+func (t *Tracker) addSeries(key Key, seen map[uint64]struct{}, id uint64, tags map[string]string, limit Limit, r *Result) (*Result, error) {
+	added, err := t.store.AddSeries(key, id, userTags(tags), limit.MaxSeries)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return t.exceed(key, r, Drop, CounterDropped)
+	}
+	t.markSeen(seen, id)
+	return r, nil
+}
+
+func (t *Tracker) exceed(key Key, r *Result, d Decision, counter string) (*Result, error) {
+	if err := t.store.Incr(key, counter); err != nil {
+		return nil, err
+	}
+	first, err := t.store.Exceed(key)
+	if err != nil {
+		return nil, err
+	}
+	r.Decision = d
+	r.Exceeded = r.Exceeded || first
+	return r, nil
+}
+
+func (t *Tracker) isSeen(seen map[uint64]struct{}, id uint64) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	_, ok := seen[id]
+	return ok
+}
+
+func (t *Tracker) markSeen(seen map[uint64]struct{}, id uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	seen[id] = struct{}{}
+}
+
+// Top returns the entries of the current window which have the most series, the tenant is optional.
+func (t *Tracker) Top(tenant string, n int) ([]*Entry, error) {
+	t.lock.Lock()
+	start := t.start
+	t.lock.Unlock()
+	if start.IsZero() {
+		start = time.Now().Truncate(t.opts.Window)
+	}
+	entries, err := t.top(start)
+	if err != nil {
+		return nil, err
+	}
+	return filterTop(entries, tenant, n), nil
+}
+
+// History returns the snapshots of the past windows, the latest first.
+func (t *Tracker) History(tenant string, n int) ([]*Snapshot, error) {
+	snapshots, err := t.store.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Snapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		list = append(list, &Snapshot{
+			Start:   s.Start,
+			End:     s.End,
+			Entries: filterTop(s.Entries, tenant, n),
+		})
+	}
+	return list, nil
+}
+
+// Roll ends the current window if it has expired, and returns its snapshot.
+// Only one of the instances sharing the Store gets the snapshot of a window, the others get nil.
+func (t *Tracker) Roll(now time.Time) (*Snapshot, error) {
+	t.lock.Lock()
+	start, ended := t.roll(now)
+	t.lock.Unlock()
+	if !ended {
+		return nil, nil
+	}
+	if ok, err := t.store.ClaimSnapshot(start); err != nil || !ok {
+		return nil, err
+	}
+	entries, err := t.top(start)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{
+		Start:   start,
+		End:     start.Add(t.opts.Window),
+		Entries: entries,
+	}
+	if t.opts.History > 0 {
+		if err := t.store.SaveSnapshot(s, t.opts.History); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// roll moves to the window of now, and returns the start of the window ended.
+func (t *Tracker) roll(now time.Time) (time.Time, bool) {
+	if t.start.IsZero() {
+		t.start = now.Truncate(t.opts.Window)
+		return time.Time{}, false
+	}
+	if now.Before(t.start.Add(t.opts.Window)) {
+		return time.Time{}, false
+	}
+	start := t.start
+	t.start = now.Truncate(t.opts.Window)
+	t.seen = make(map[Key]map[uint64]struct{})
+	return start, true
+}
+
+func (t *Tracker) top(start time.Time) ([]*Entry, error) {
+	list, err := t.store.Entries(start)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		sort.Slice(e.Tags, func(i, j int) bool {
+			if e.Tags[i].Values != e.Tags[j].Values {
+				return e.Tags[i].Values > e.Tags[j].Values
+			}
+			return e.Tags[i].Tag < e.Tags[j].Tag
+		})
+		if len(e.Tags) > t.opts.MaxTopTags {
+			e.Tags = e.Tags[:t.opts.MaxTopTags]
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Series != list[j].Series {
+			return list[i].Series > list[j].Series
+		}
+		if list[i].Tenant != list[j].Tenant {
+			return list[i].Tenant < list[j].Tenant
+		}
+		return list[i].Metric < list[j].Metric
+	})
+	if len(list) > t.opts.TopN {
+		list = list[:t.opts.TopN]
+	}
+	return list, nil
+}
+
+func filterTop(entries []*Entry, tenant string, n int) []*Entry {
+	list := make([]*Entry, 0)
+	for _, e := range entries {
+		if n > 0 && len(list) >= n {
+			break
+		}
+		if len(tenant) <= 0 || e.Tenant == tenant {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// userTags returns the tags without the built-in _ prefixes.
+func userTags(tags map[string]string) map[string]string {
+	m := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if !strings.HasPrefix(k, "_") {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func seriesID(tags map[string]string) uint64 {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		if !strings.HasPrefix(k, "_") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := fnv.New64a()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(tags[k]))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
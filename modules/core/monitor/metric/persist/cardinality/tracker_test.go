@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cardinality
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTracker_Observe(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker(Options{
+		Window:  time.Hour,
+		History: 2,
+		Limit:   Limit{MaxSeries: 3, Action: ActionDrop},
+		TenantLimits: map[string]Limit{
+			"erda": {MaxTagValues: 2, Action: ActionTruncate},
+		},
+	})
+	observe := func(tenant, path string) *Result {
+		r, err := tr.Observe(tenant, "http", map[string]string{"service": "api", "path": path, "_id": path}, now)
+		if err != nil {
+			t.Fatalf("Observe() got error: %s", err)
+		}
+		return r
+	}
+	top := func(tenant string, n int) []*Entry {
+		list, err := tr.Top(tenant, n)
+		if err != nil {
+			t.Fatalf("Top() got error: %s", err)
+		}
+		return list
+	}
+	roll := func(now time.Time) *Snapshot {
+		s, err := tr.Roll(now)
+		if err != nil {
+			t.Fatalf("Roll() got error: %s", err)
+		}
+		return s
+	}
+
+	for i := 0; i < 3; i++ {
+		if r := observe("terminus", fmt.Sprint(i)); r.Decision != Accept {
+			t.Fatalf("Observe() series %d = %+v, want accept", i, r)
+		}
+	}
+	r := observe("terminus", "3")
+	if r.Decision != Drop || !r.Exceeded {
+		t.Errorf("Observe() over max series = %+v, want drop and exceeded", r)
+	}
+	if r := observe("terminus", "4"); r.Decision != Drop || r.Exceeded {
+		t.Errorf("Observe() over max series again = %+v, want drop only", r)
+	}
+	if r := observe("terminus", "1"); r.Decision != Accept {
+		t.Errorf("Observe() existing series = %+v, want accept", r)
+	}
+
+	observe("erda", "a")
+	observe("erda", "b")
+	tags := map[string]string{"service": "api", "path": "c"}
+	r, _ = tr.Observe("erda", "http", tags, now)
+	if r.Decision != Truncate || len(r.TruncatedTags) != 1 || tags["path"] != TruncatedValue {
+		t.Errorf("Observe() over max tag values = %+v, tags = %v", r, tags)
+	}
+
+	if top := top("", 0); len(top) != 2 || top[0].Tenant != "erda" {
+		t.Errorf("Top() = %+v, want the entries sorted by series and tenant", top)
+	}
+	terminus := top("terminus", 0)
+	if len(terminus) != 1 || terminus[0].Series != 3 || terminus[0].Dropped != 2 {
+		t.Fatalf("Top(terminus) = %+v", terminus)
+	}
+	if terminus[0].Tags[0].Tag != "path" || terminus[0].Tags[0].Values != 3 {
+		t.Errorf("Top() tags = %+v, the tags with _ prefix should be ignored", terminus[0].Tags)
+	}
+	if erda := top("erda", 1); len(erda) != 1 || erda[0].Series != 3 || erda[0].Truncated != 1 {
+		t.Errorf("Top(erda) = %+v", erda)
+	}
+
+	if s := roll(now.Add(30 * time.Minute)); s != nil {
+		t.Errorf("Roll() before the window ends = %+v", s)
+	}
+	s := roll(now.Add(time.Hour))
+	if s == nil || !s.Start.Equal(now) || len(s.Entries) != 2 {
+		t.Fatalf("Roll() = %+v", s)
+	}
+	if top := top("", 0); len(top) != 0 {
+		t.Errorf("Top() after roll = %+v, want empty", top)
+	}
+	if r := observe("terminus", "3"); r.Decision != Accept {
+		t.Errorf("Observe() in the next window = %+v, want accept", r)
+	}
+	roll(now.Add(2 * time.Hour))
+	roll(now.Add(3 * time.Hour))
+	history, _ := tr.History("terminus", 0)
+	if len(history) != 2 || !history[0].Start.Equal(now.Add(2*time.Hour)) || len(history[1].Entries) != 1 {
+		t.Errorf("History() = %+v", history)
+	}
+}
+
+func TestTracker_SharedStore(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	opts := Options{Window: time.Hour, History: 1, Limit: Limit{MaxSeries: 2}, Store: store}
+	a, b := NewTracker(opts), NewTracker(opts)
+	var decisions []Decision
+	for i, tr := range []*Tracker{a, b, a} {
+		r, err := tr.Observe("terminus", "http", map[string]string{"path": fmt.Sprint(i)}, now)
+		if err != nil {
+			t.Fatalf("Observe() got error: %s", err)
+		}
+		decisions = append(decisions, r.Decision)
+	}
+	if decisions[0] != Accept || decisions[1] != Accept || decisions[2] != Drop {
+		t.Errorf("Observe() = %v, want the limit applied to the series of both trackers", decisions)
+	}
+	if top, _ := b.Top("", 0); len(top) != 1 || top[0].Series != 2 || top[0].Dropped != 1 {
+		t.Errorf("Top() = %+v", top)
+	}
+	sa, _ := a.Roll(now.Add(time.Hour))
+	sb, _ := b.Roll(now.Add(time.Hour))
+	if (sa == nil) == (sb == nil) {
+		t.Errorf("Roll() = %+v, %+v, want the snapshot returned by only one tracker", sa, sb)
+	}
+	if history, _ := b.History("", 0); len(history) != 1 {
+		t.Errorf("History() = %+v", history)
+	}
+}
@@ -63,6 +63,9 @@ func (p *provider) decodeData(key, value []byte, topic *string, timestamp time.T
 		}
 		return nil, err
 	}
+	if err := p.cardinality.Limit(data); err != nil {
+		return nil, Skip
+	}
 	if p.Cfg.Features.GenerateMeta {
 		if err := p.metadata.Process(data); err != nil {
 			p.stats.MetadataError(data, err)
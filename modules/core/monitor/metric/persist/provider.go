@@ -19,9 +19,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-redis/redis"
+
 	"github.com/erda-project/erda-infra/base/logs"
 	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/httpserver"
 	"github.com/erda-project/erda-infra/providers/kafka"
+	"github.com/erda-project/erda/modules/core/monitor/metric/persist/cardinality"
 	"github.com/erda-project/erda/modules/core/monitor/metric/storage"
 	"github.com/erda-project/erda/modules/core/monitor/storekit"
 )
@@ -38,17 +42,29 @@ type config struct {
 		MachineSummary bool   `file:"machine_summary" default:"false"` // this code will be removed later.
 		FilterPrefix   string `file:"filter_prefix" default:"go_" env:"METRIC_FILTER_PREFIX"`
 	} `file:"features"`
+
+	Cardinality struct {
+		Enable       bool                         `file:"enable" default:"false"`
+		Window       time.Duration                `file:"window" default:"1h"`
+		History      int                          `file:"history" default:"24"`
+		TopN         int                          `file:"top_n" default:"100"`
+		Limit        cardinality.Limit            `file:"limit"`
+		TenantLimits map[string]cardinality.Limit `file:"tenant_limits"`
+	} `file:"cardinality"`
 }
 
 type provider struct {
 	Cfg           *config
 	Log           logs.Logger
-	Kafka         kafka.Interface `autowired:"kafka"`
-	StorageWriter storage.Storage `autowired:"metric-storage-writer"`
+	Kafka         kafka.Interface   `autowired:"kafka"`
+	StorageWriter storage.Storage   `autowired:"metric-storage-writer"`
+	Router        httpserver.Router `autowired:"http-router" optional:"true"`
+	Redis         *redis.Client     `autowired:"redis-client" optional:"true"`
 
-	stats     Statistics
-	validator Validator
-	metadata  MetadataProcessor
+	stats       Statistics
+	validator   Validator
+	metadata    MetadataProcessor
+	cardinality CardinalityLimiter
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -65,6 +81,18 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		ctx.AddTask(runner.Run, servicehub.WithTaskName("metric metadata processor"))
 	}
 
+	limiter, err := newCardinalityLimiter(p.Cfg, p)
+	if err != nil {
+		return err
+	}
+	p.cardinality = limiter
+	if runner, ok := p.cardinality.(servicehub.ProviderRunnerWithContext); ok {
+		ctx.AddTask(runner.Run, servicehub.WithTaskName("metric cardinality limiter"))
+	}
+	if p.Router != nil {
+		p.initRoutes(p.Router)
+	}
+
 	// add consumer task
 	for i := 0; i < p.Cfg.Parallelism; i++ {
 		ctx.AddTask(func(ctx context.Context) error {
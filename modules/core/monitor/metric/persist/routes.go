@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persist
+
+import (
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	"github.com/erda-project/erda/modules/monitor/common"
+	"github.com/erda-project/erda/modules/monitor/common/permission"
+	api "github.com/erda-project/erda/pkg/common/httpapi"
+)
+
+func (p *provider) initRoutes(routes httpserver.Router) {
+	orgPermission := permission.Intercepter(
+		permission.ScopeOrg, permission.OrgIDByOrgName("orgName"),
+		common.ResourceOrgCenter, permission.ActionGet,
+	)
+	routes.GET("/api/metric-cardinality/top", p.topCardinality, orgPermission)
+	routes.GET("/api/metric-cardinality/history", p.cardinalityHistory, orgPermission)
+}
+
+type cardinalityParams struct {
+	OrgName string `query:"orgName" validate:"required"`
+	Limit   int    `query:"limit"`
+}
+
+// topCardinality lists the metrics with the most series of all the instances in the current window.
+func (p *provider) topCardinality(params cardinalityParams) interface{} {
+	entries, err := p.cardinality.Top(params.OrgName, params.Limit)
+	if err != nil {
+		return api.Errors.Internal(err)
+	}
+	limit, ok := p.Cfg.Cardinality.TenantLimits[params.OrgName]
+	if !ok {
+		limit = p.Cfg.Cardinality.Limit
+	}
+	return api.Success(map[string]interface{}{
+		"enable":  p.Cfg.Cardinality.Enable,
+		"limit":   limit,
+		"entries": entries,
+	})
+}
+
+// cardinalityHistory lists the top metrics of the past windows, the latest first.
+func (p *provider) cardinalityHistory(params cardinalityParams) interface{} {
+	history, err := p.cardinality.History(params.OrgName, params.Limit)
+	if err != nil {
+		return api.Errors.Internal(err)
+	}
+	return api.Success(history)
+}
@@ -31,6 +31,7 @@ type Statistics interface {
 	ValidateError(data *metric.Metric)
 	MetadataError(data *metric.Metric, err error)
 	MetadataUpdates(v int)
+	CardinalityLimited(data *metric.Metric, action string)
 }
 
 type statistics struct {
@@ -46,6 +47,8 @@ type statistics struct {
 
 	metadataUpdates prometheus.Counter
 
+	cardinalityLimited *prometheus.CounterVec
+
 	// performance
 	readLatency  prometheus.Histogram
 	writeLatency prometheus.Histogram
@@ -124,6 +127,12 @@ func newStatistics() Statistics {
 				Subsystem: subSystem,
 			},
 		),
+		cardinalityLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:      "cardinality_limited",
+				Subsystem: subSystem,
+			}, append(distinguishingKeys, "action"),
+		),
 	}
 
 	// only register once
@@ -139,6 +148,7 @@ func newStatistics() Statistics {
 		s.validateErrors,
 		s.metadataError,
 		s.metadataUpdates,
+		s.cardinalityLimited,
 	)
 	return s
 }
@@ -181,6 +191,10 @@ func (s *statistics) MetadataUpdates(v int) {
 	s.metadataUpdates.Add(float64(v))
 }
 
+func (s *statistics) CardinalityLimited(data *metric.Metric, action string) {
+	s.cardinalityLimited.WithLabelValues(append(getStatisticsLabels(data), action)...).Inc()
+}
+
 func (s *statistics) ObserveReadLatency(start time.Time) {
 	s.readLatency.Observe(float64(time.Since(start).Milliseconds()))
 }
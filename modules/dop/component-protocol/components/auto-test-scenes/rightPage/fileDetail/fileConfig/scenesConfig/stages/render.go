@@ -229,7 +229,7 @@ func (i *ComponentStageForm) Render(ctx context.Context, c *cptype.Component, sc
 		if err != nil {
 			return err
 		}
-		if step.Type == apistructs.StepTypeAPI {
+		if step.Type.IsAPIStepType() {
 			i.State.ShowApiEditorDrawer = true
 		} else if step.Type == apistructs.StepTypeWait {
 			i.State.ShowWaitEditorDrawer = true
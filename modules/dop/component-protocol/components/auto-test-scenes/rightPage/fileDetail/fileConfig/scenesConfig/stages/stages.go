@@ -46,7 +46,7 @@ func (i *ComponentStageForm) RenderStage(groupID uint64, step apistructs.AutoTes
 			}
 			title = title + i.sdk.I18n("wait") + " " + strconv.Itoa(value.WaitTimeSec) + " " + i.sdk.I18n("second")
 		}
-	} else if step.Type == apistructs.StepTypeAPI {
+	} else if step.Type.IsAPIStepType() {
 		if step.Value == "" {
 			title = title + i.sdk.I18n("emptyApi")
 		} else {
@@ -251,7 +251,7 @@ func (a *ExecuteTaskTable) transformStepType(str apistructs.StepAPIType) string
 	switch str {
 	case apistructs.StepTypeWait:
 		return a.sdk.I18n("wait")
-	case apistructs.StepTypeAPI:
+	case apistructs.StepTypeAPI, apistructs.StepTypeGRPC, apistructs.StepTypeWebSocket:
 		return a.sdk.I18n("API")
 	case apistructs.StepTypeScene:
 		return a.sdk.I18n("scene")
@@ -361,7 +361,7 @@ func (a *ExecuteTaskTable) setData(pipeline *apistructs.PipelineDetailDTO) error
 							return err
 						}
 
-						if res.Type == apistructs.StepTypeAPI || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
+						if res.Type.IsAPIStepType() || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
 							err := json.Unmarshal([]byte(res.Value), &value)
 							if err != nil {
 								return err
@@ -379,7 +379,7 @@ func (a *ExecuteTaskTable) setData(pipeline *apistructs.PipelineDetailDTO) error
 						res.Type = apistructs.StepAPIType(task.Type)
 					}
 					operations := map[string]interface{}{}
-					if res.Type == apistructs.StepTypeAPI || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
+					if res.Type.IsAPIStepType() || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
 						operations = map[string]interface{}{
 							"checkDetail": dataOperation{
 								Key:         "checkDetail",
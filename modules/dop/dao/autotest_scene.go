@@ -269,6 +269,9 @@ func (db *DBClient) DeleteAutoTestScene(id uint64) (err error) {
 		if err = tx.Where(AutoTestSceneStep{}).Where("scene_id = ?", scene.ID).Delete(AutoTestSceneStep{}).Error; err != nil {
 			return err
 		}
+		if err = deleteAutoTestSceneDatasets(tx, string(apistructs.AutoTestSceneDatasetScopeScene), scene.ID); err != nil {
+			return err
+		}
 		return nil
 	})
 }
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// AutoTestSceneDataset parameter dataset of scene or scene set
+type AutoTestSceneDataset struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         uint64
+	OrgName       string
+	Name          string
+	ScopeType     string // scene or sceneset
+	ScopeID       uint64
+	SpaceID       uint64 // 所属测试空间ID
+	Columns       string // json array of column names
+	DataRows      string // json array of rows
+	IsParallel    bool
+	CreatorID     string
+	UpdaterID     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (AutoTestSceneDataset) TableName() string {
+	return "erda_autotest_scene_dataset"
+}
+
+func (d *AutoTestSceneDataset) Convert2DTO() *apistructs.AutoTestSceneDataset {
+	dto := &apistructs.AutoTestSceneDataset{
+		ID:        d.ID,
+		Name:      d.Name,
+		ScopeType: apistructs.AutoTestSceneDatasetScope(d.ScopeType),
+		ScopeID:   d.ScopeID,
+		SpaceID:   d.SpaceID,
+		Columns:   []string{},
+		Rows:      [][]string{},
+		Parallel:  d.IsParallel,
+		CreatorID: d.CreatorID,
+		UpdaterID: d.UpdaterID,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+	_ = json.Unmarshal([]byte(d.Columns), &dto.Columns)
+	_ = json.Unmarshal([]byte(d.DataRows), &dto.Rows)
+	return dto
+}
+
+// GetAutoTestSceneDataset returns nil if the scope has no dataset
+func (db *DBClient) GetAutoTestSceneDataset(scopeType string, scopeID uint64) (*AutoTestSceneDataset, error) {
+	var dataset AutoTestSceneDataset
+	err := db.Scopes(NotDeleted).Where("scope_type = ? AND scope_id = ?", scopeType, scopeID).First(&dataset).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+func (db *DBClient) ListAutoTestSceneDatasets(scopeType string, scopeIDs []uint64) ([]AutoTestSceneDataset, error) {
+	var datasets []AutoTestSceneDataset
+	if len(scopeIDs) == 0 {
+		return datasets, nil
+	}
+	err := db.Scopes(NotDeleted).Where("scope_type = ? AND scope_id in (?)", scopeType, scopeIDs).Find(&datasets).Error
+	if err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+func (db *DBClient) CreateAutoTestSceneDataset(dataset *AutoTestSceneDataset) error {
+	if dataset.ID == "" {
+		dataset.ID = uuid.New().String()
+	}
+	return db.Create(dataset).Error
+}
+
+func (db *DBClient) UpdateAutoTestSceneDataset(dataset *AutoTestSceneDataset) error {
+	return db.Save(dataset).Error
+}
+
+func (db *DBClient) DeleteAutoTestSceneDataset(scopeType string, scopeID uint64) error {
+	return deleteAutoTestSceneDatasets(db.DB, scopeType, scopeID)
+}
+
+// deleteAutoTestSceneDatasets soft deletes the datasets of the scopes, scopeIDs is an id or a slice of ids
+func deleteAutoTestSceneDatasets(tx *gorm.DB, scopeType string, scopeIDs interface{}) error {
+	return tx.Model(AutoTestSceneDataset{}).Scopes(NotDeleted).
+		Where("scope_type = ? AND scope_id IN (?)", scopeType, scopeIDs).
+		Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+}
@@ -97,6 +97,12 @@ func (client *DBClient) DeleteSceneSet(sceneSet *SceneSet, scenes []uint64) erro
 		if err := tx.Where(AutoTestSceneStep{}).Where("scene_id IN (?)", scenes).Delete(AutoTestSceneStep{}).Error; err != nil {
 			return err
 		}
+		if err := deleteAutoTestSceneDatasets(tx, string(apistructs.AutoTestSceneDatasetScopeScene), scenes); err != nil {
+			return err
+		}
+		if err := deleteAutoTestSceneDatasets(tx, string(apistructs.AutoTestSceneDatasetScopeSceneSet), sceneSet.ID); err != nil {
+			return err
+		}
 
 		var next SceneSet
 		if err := tx.Where("pre_id = ?", sceneSet.ID).Find(&next).Error; err != nil {
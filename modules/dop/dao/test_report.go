@@ -82,6 +82,7 @@ func (t *TestReportRecord) Convert() apistructs.TestReportRecord {
 		ReportData: apistructs.TestReportData{
 			IssueDashboard: t.ReportData.IssueDashboard,
 			TestDashboard:  t.ReportData.TestDashboard,
+			LoadTests:      t.ReportData.LoadTests,
 		},
 	}
 }
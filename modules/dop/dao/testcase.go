@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 
 	"github.com/erda-project/erda/apistructs"
@@ -44,10 +45,25 @@ type TestCase struct {
 	From           apistructs.TestCaseFrom
 	CreatorID      string
 	UpdaterID      string
+	ReviewStatus   apistructs.TestCaseReviewStatus
+	Reviewers      TestCaseReviewers
 }
 
 type TestCaseStepAndResults []apistructs.TestCaseStepAndResult
 
+type TestCaseReviewers []string
+
+// Snapshot returns the content of test case which is kept by version
+func (tc *TestCase) Snapshot() apistructs.TestCaseSnapshot {
+	return apistructs.TestCaseSnapshot{
+		Name:           tc.Name,
+		Priority:       tc.Priority,
+		PreCondition:   tc.PreCondition,
+		Desc:           tc.Desc,
+		StepAndResults: tc.StepAndResults,
+	}
+}
+
 // TableName 设置模型对应数据库表名称
 func (TestCase) TableName() string {
 	return "dice_test_cases"
@@ -77,6 +93,30 @@ func (sr *TestCaseStepAndResults) Scan(value interface{}) error {
 	return nil
 }
 
+func (r TestCaseReviewers) Value() (driver.Value, error) {
+	if b, err := json.Marshal(r); err != nil {
+		return nil, errors.Errorf("failed to marshal reviewers, err: %v", err)
+	} else {
+		return string(b), nil
+	}
+}
+func (r *TestCaseReviewers) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v, ok := value.([]byte)
+	if !ok {
+		return errors.New("invalid scan source for reviewers")
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(v, r); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal reviewers")
+	}
+	return nil
+}
+
 // CreateTestCase 创建测试用例
 func (client *DBClient) CreateTestCase(uc *TestCase) error {
 	return client.Create(uc).Error
@@ -138,11 +178,15 @@ func (client *DBClient) ListTestCasesByTestSetIDs(req apistructs.TestCaseListReq
 }
 
 func (client *DBClient) BatchUpdateTestCases(req apistructs.TestCaseBatchUpdateRequest) error {
+	return batchUpdateTestCases(client.DB, req)
+}
+
+func batchUpdateTestCases(tx *gorm.DB, req apistructs.TestCaseBatchUpdateRequest) error {
 	if len(req.TestCaseIDs) == 0 {
 		return fmt.Errorf("no testcase selected")
 	}
 
-	sql := client.Model(TestCase{}).Where("`id` IN (?)", req.TestCaseIDs)
+	sql := tx.Model(TestCase{}).Where("`id` IN (?)", req.TestCaseIDs)
 
 	kvs := make(map[string]interface{})
 
@@ -159,6 +203,12 @@ func (client *DBClient) BatchUpdateTestCases(req apistructs.TestCaseBatchUpdateR
 	return sql.Updates(kvs).Error
 }
 
+// UpdateTestCaseReview 更新测试用例评审状态及评审人
+func (client *DBClient) UpdateTestCaseReview(id uint64, status apistructs.TestCaseReviewStatus, reviewers TestCaseReviewers) error {
+	return client.Model(TestCase{}).Where("`id` = ?", id).
+		Updates(map[string]interface{}{"review_status": status, "reviewers": reviewers}).Error
+}
+
 func (client *DBClient) BatchCopyTestCases(req apistructs.TestCaseBatchCopyRequest) error {
 	if len(req.TestCaseIDs) == 0 {
 		return fmt.Errorf("no testcase selected")
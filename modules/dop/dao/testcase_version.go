@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// TestCaseVersion 测试用例历史版本
+type TestCaseVersion struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         uint64
+	OrgName       string
+	TestCaseID    uint64
+	Version       uint64
+	Snapshot      string // json of apistructs.TestCaseSnapshot
+	Diffs         string // json array of apistructs.TestCaseFieldDiff
+	CreatorID     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (TestCaseVersion) TableName() string {
+	return "erda_test_case_version"
+}
+
+func (v *TestCaseVersion) Convert() apistructs.TestCaseVersion {
+	version := apistructs.TestCaseVersion{
+		ID:         v.ID,
+		TestCaseID: v.TestCaseID,
+		Version:    v.Version,
+		Diffs:      []apistructs.TestCaseFieldDiff{},
+		CreatorID:  v.CreatorID,
+		CreatedAt:  v.CreatedAt,
+	}
+	_ = json.Unmarshal([]byte(v.Snapshot), &version.Snapshot)
+	_ = json.Unmarshal([]byte(v.Diffs), &version.Diffs)
+	return version
+}
+
+// CreateNextTestCaseVersion keep the content as the next version of test case, see createNextTestCaseVersion.
+func (client *DBClient) CreateNextTestCaseVersion(baseline, version *TestCaseVersion) error {
+	return client.Transaction(func(tx *gorm.DB) error {
+		if err := lockTestCase(tx, version.TestCaseID); err != nil {
+			return err
+		}
+		return createNextTestCaseVersion(tx, baseline, version)
+	})
+}
+
+// UpdateTestCaseWithVersion save test case and keep its content as the next version in one transaction.
+func (client *DBClient) UpdateTestCaseWithVersion(tc *TestCase, baseline, version *TestCaseVersion) error {
+	return client.Transaction(func(tx *gorm.DB) error {
+		return updateTestCaseWithVersion(tx, tc, baseline, version)
+	})
+}
+
+// TestCaseVersionUpdate the test case to save and the versions to keep, see UpdateTestCaseWithVersion.
+type TestCaseVersionUpdate struct {
+	TestCase *TestCase
+	Baseline *TestCaseVersion
+	Version  *TestCaseVersion
+}
+
+// BatchUpdateTestCasesWithVersions apply the batch update, then save the changed test cases and keep their
+// contents as the next versions, all in one transaction.
+func (client *DBClient) BatchUpdateTestCasesWithVersions(req apistructs.TestCaseBatchUpdateRequest, updates []TestCaseVersionUpdate) error {
+	return client.Transaction(func(tx *gorm.DB) error {
+		if err := batchUpdateTestCases(tx, req); err != nil {
+			return err
+		}
+		for _, update := range updates {
+			if err := updateTestCaseWithVersion(tx, update.TestCase, update.Baseline, update.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func updateTestCaseWithVersion(tx *gorm.DB, tc *TestCase, baseline, version *TestCaseVersion) error {
+	if err := lockTestCase(tx, tc.ID); err != nil {
+		return err
+	}
+	if err := tx.Save(tc).Error; err != nil {
+		return err
+	}
+	return createNextTestCaseVersion(tx, baseline, version)
+}
+
+// createNextTestCaseVersion numbers the version as latest+1, the test case row must be locked by the transaction,
+// so concurrent edits of the same test case get sequential versions instead of colliding on uk_test_case_version.
+// Test cases created before versioning have no version, so baseline is kept as the first version.
+func createNextTestCaseVersion(tx *gorm.DB, baseline, version *TestCaseVersion) error {
+	var latest TestCaseVersion
+	err := tx.Scopes(NotDeleted).Where("test_case_id = ?", version.TestCaseID).Order("version DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		version.Version = latest.Version + 1
+	case !gorm.IsRecordNotFoundError(err):
+		return err
+	case baseline != nil:
+		baseline.ID = uuid.New().String()
+		baseline.Version = 1
+		if err := tx.Create(baseline).Error; err != nil {
+			return err
+		}
+		version.Version = 2
+	default:
+		version.Version = 1
+	}
+	version.ID = uuid.New().String()
+	return tx.Create(version).Error
+}
+
+// lockTestCase lock the test case row until the transaction is end
+func lockTestCase(tx *gorm.DB, testCaseID uint64) error {
+	var tc TestCase
+	return tx.Set("gorm:query_option", "FOR UPDATE").Select("`id`").Where("`id` = ?", testCaseID).First(&tc).Error
+}
+
+// GetTestCaseVersion returns nil if the version not exist
+func (client *DBClient) GetTestCaseVersion(testCaseID, version uint64) (*TestCaseVersion, error) {
+	var v TestCaseVersion
+	err := client.Scopes(NotDeleted).Where("test_case_id = ? AND version = ?", testCaseID, version).First(&v).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (client *DBClient) ListTestCaseVersions(testCaseID uint64) ([]TestCaseVersion, error) {
+	var versions []TestCaseVersion
+	if err := client.Scopes(NotDeleted).Where("test_case_id = ?", testCaseID).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (client *DBClient) DeleteTestCaseVersionsByTestCaseIDs(testCaseIDs []uint64) error {
+	if len(testCaseIDs) == 0 {
+		return nil
+	}
+	return client.Model(TestCaseVersion{}).Scopes(NotDeleted).Where("test_case_id IN (?)", testCaseIDs).
+		Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+}
@@ -135,6 +135,25 @@ func DeleteAPIAssetVersion(orgID uint64, assetID string, versionID uint64, casca
 	return nil
 }
 
+// QueryAPIPreviousVersion queries the latest version lower than the giving semantic version.
+// If sameMajor is true, only versions under the same major are considered.
+func QueryAPIPreviousVersion(orgID uint64, assetID string, major, minor, patch uint64, sameMajor bool) (*apistructs.APIAssetVersionsModel, error) {
+	var model apistructs.APIAssetVersionsModel
+	q := Sq().Where(map[string]interface{}{
+		"org_id":   orgID,
+		"asset_id": assetID,
+	})
+	if sameMajor {
+		q = q.Where("major = ? AND (minor < ? OR (minor = ? AND patch < ?))", major, minor, minor, patch)
+	} else {
+		q = q.Where("major < ? OR (major = ? AND (minor < ? OR (minor = ? AND patch < ?)))", major, major, minor, minor, patch)
+	}
+	if err := q.Order("major DESC, minor DESC, patch DESC").First(&model).Error; err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
 // GenSemVer generate the semantics version
 func GenSemVer(orgID uint64, assetID, swaggerVersion string, major, minor, patch *uint64) error {
 	if major == nil || minor == nil || patch == nil {
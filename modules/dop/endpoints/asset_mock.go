@@ -0,0 +1,201 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/modules/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/swagger/mock"
+)
+
+const (
+	urlPathMockPath   = "mockPath"
+	urlPathScenarioID = "scenarioID"
+)
+
+// MockAPIAsset 按 API 资料版本的文档模拟请求, 地址为 /api/api-assets/{assetID}/versions/{versionID}/mock-server/{接口路径},
+// 使用独立的前缀, 避免与 mock-scenarios 等管理接口冲突
+func (e *Endpoints) MockAPIAsset(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.MockAPIAsset.NotLogin().Write(w)
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.MockAPIAsset.MissingParameter(apierrors.MissingOrgID).Write(w)
+	}
+	versionID, err := strconv.ParseUint(vars[urlPathVersionID], 10, 64)
+	if err != nil {
+		return apierrors.MockAPIAsset.InvalidParameter(err).Write(w)
+	}
+	mockReq, err := mock.NewRequest(r, vars[urlPathMockPath])
+	if err != nil {
+		return apierrors.MockAPIAsset.InvalidParameter(err).Write(w)
+	}
+	resp, err := e.assetSvc.ServeMock(ctx, &apistructs.APIMockReq{
+		OrgID:     orgID,
+		Identity:  &identity,
+		AssetID:   vars[urlPathAssetID],
+		VersionID: versionID,
+		Request:   mockReq,
+	})
+	if err != nil {
+		return errorresp.ErrWrite(err, w)
+	}
+	return resp.Write(w)
+}
+
+// ListMockScenarios 查询 API 资料版本的 mock 场景
+func (e *Endpoints) ListMockScenarios(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ListMockScenarios.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.ListMockScenarios.MissingParameter(apierrors.MissingOrgID).ToResp(), nil
+	}
+	versionID, err := strconv.ParseUint(vars[urlPathVersionID], 10, 64)
+	if err != nil {
+		return apierrors.ListMockScenarios.InvalidParameter(err).ToResp(), nil
+	}
+
+	scenarios, err := e.assetSvc.ListMockScenarios(&apistructs.APIMockScenarioReq{
+		OrgID:     orgID,
+		Identity:  &identity,
+		AssetID:   vars[urlPathAssetID],
+		VersionID: versionID,
+	})
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	var userIDs []string
+	for _, scenario := range scenarios {
+		userIDs = append(userIDs, scenario.CreatorID, scenario.UpdaterID)
+	}
+	return httpserver.OkResp(map[string]interface{}{"total": len(scenarios), "list": scenarios}, userIDs)
+}
+
+// CreateMockScenario 创建 mock 场景
+func (e *Endpoints) CreateMockScenario(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.CreateMockScenario.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.CreateMockScenario.MissingParameter(apierrors.MissingOrgID).ToResp(), nil
+	}
+	versionID, err := strconv.ParseUint(vars[urlPathVersionID], 10, 64)
+	if err != nil {
+		return apierrors.CreateMockScenario.InvalidParameter(err).ToResp(), nil
+	}
+	var scenario mock.Scenario
+	if err := json.NewDecoder(r.Body).Decode(&scenario); err != nil {
+		return apierrors.CreateMockScenario.InvalidParameter(err).ToResp(), nil
+	}
+
+	result, err := e.assetSvc.CreateMockScenario(&apistructs.APIMockScenarioReq{
+		OrgID:     orgID,
+		Identity:  &identity,
+		AssetID:   vars[urlPathAssetID],
+		VersionID: versionID,
+		Scenario:  &scenario,
+	})
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	return httpserver.OkResp(result, []string{result.CreatorID})
+}
+
+// UpdateMockScenario 修改 mock 场景
+func (e *Endpoints) UpdateMockScenario(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.UpdateMockScenario.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.UpdateMockScenario.MissingParameter(apierrors.MissingOrgID).ToResp(), nil
+	}
+	versionID, err := strconv.ParseUint(vars[urlPathVersionID], 10, 64)
+	if err != nil {
+		return apierrors.UpdateMockScenario.InvalidParameter(err).ToResp(), nil
+	}
+	scenarioID := vars[urlPathScenarioID]
+	if scenarioID == "" {
+		return apierrors.UpdateMockScenario.MissingParameter(urlPathScenarioID).ToResp(), nil
+	}
+	var scenario mock.Scenario
+	if err := json.NewDecoder(r.Body).Decode(&scenario); err != nil {
+		return apierrors.UpdateMockScenario.InvalidParameter(err).ToResp(), nil
+	}
+
+	result, err := e.assetSvc.UpdateMockScenario(&apistructs.APIMockScenarioReq{
+		OrgID:      orgID,
+		Identity:   &identity,
+		AssetID:    vars[urlPathAssetID],
+		VersionID:  versionID,
+		ScenarioID: scenarioID,
+		Scenario:   &scenario,
+	})
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	return httpserver.OkResp(result, []string{result.CreatorID, result.UpdaterID})
+}
+
+// DeleteMockScenario 删除 mock 场景
+func (e *Endpoints) DeleteMockScenario(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.DeleteMockScenario.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.DeleteMockScenario.MissingParameter(apierrors.MissingOrgID).ToResp(), nil
+	}
+	versionID, err := strconv.ParseUint(vars[urlPathVersionID], 10, 64)
+	if err != nil {
+		return apierrors.DeleteMockScenario.InvalidParameter(err).ToResp(), nil
+	}
+	scenarioID := vars[urlPathScenarioID]
+	if scenarioID == "" {
+		return apierrors.DeleteMockScenario.MissingParameter(urlPathScenarioID).ToResp(), nil
+	}
+
+	if err := e.assetSvc.DeleteMockScenario(&apistructs.APIMockScenarioReq{
+		OrgID:      orgID,
+		Identity:   &identity,
+		AssetID:    vars[urlPathAssetID],
+		VersionID:  versionID,
+		ScenarioID: scenarioID,
+	}); err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	return httpserver.OkResp(nil)
+}
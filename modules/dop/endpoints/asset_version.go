@@ -60,6 +60,7 @@ func (e *Endpoints) CreateAPIVersion(ctx context.Context, r *http.Request, vars
 		Spec:             "",
 		Instances:        nil,
 		IdentityInfo:     identity,
+		BlockBreaking:    rb.BlockBreaking,
 	})
 	if err != nil {
 		return errorresp.ErrResp(err)
@@ -153,6 +154,38 @@ func (e *Endpoints) GetAPIAssetVersion(ctx context.Context, r *http.Request, var
 	return httpserver.OkResp(response, strutil.DedupSlice(userIDs))
 }
 
+// CompareAPIAssetVersions 比较 API 资料版本, 返回相对于基准版本的兼容与不兼容变更
+func (e *Endpoints) CompareAPIAssetVersions(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.CompareAPIAssetVersions.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.CompareAPIAssetVersions.MissingParameter(apierrors.MissingOrgID).ToResp(), nil
+	}
+
+	var queryParams apistructs.CompareAPIAssetVersionsQueryParams
+	if err := e.queryStringDecoder.Decode(&queryParams, r.URL.Query()); err != nil {
+		return apierrors.CompareAPIAssetVersions.InvalidParameter(err).ToResp(), nil
+	}
+
+	response, err := e.assetSvc.CompareAssetVersions(ctx, &apistructs.CompareAPIAssetVersionsReq{
+		OrgID:    orgID,
+		Identity: &identityInfo,
+		URIParams: &apistructs.AssetVersionDetailURI{
+			AssetID:   vars[urlPathAssetID],
+			VersionID: vars[urlPathVersionID],
+		},
+		QueryParams: &queryParams,
+	})
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	return httpserver.OkResp(response, strutil.DedupSlice([]string{response.Base.CreatorID, response.Target.CreatorID}))
+}
+
 // DeleteAPIAssetVersion 删除 API Version
 func (e *Endpoints) DeleteAPIAssetVersion(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
 	identity, err := user.GetIdentityInfo(r)
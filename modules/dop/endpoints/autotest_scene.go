@@ -293,6 +293,36 @@ func (e *Endpoints) ExecuteDiceAutotestScene(ctx context.Context, r *http.Reques
 	return httpserver.OkResp(result)
 }
 
+// LoadTestDiceAutotestScene run scene as load test
+func (e *Endpoints) LoadTestDiceAutotestScene(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	var req apistructs.AutotestLoadTestSceneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrLoadTestAutoTestScene.InvalidParameter(err).ToResp(), nil
+	}
+
+	sceneID, err := strconv.ParseUint(vars["sceneID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrLoadTestAutoTestScene.InvalidParameter("sceneID").ToResp(), nil
+	}
+	req.SceneID = sceneID
+	if err := req.Config.Validate(); err != nil {
+		return apierrors.ErrLoadTestAutoTestScene.InvalidParameter(err).ToResp(), nil
+	}
+
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrLoadTestAutoTestScene.NotLogin().ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+
+	result, err := e.autotestV2.ExecuteDiceAutotestSceneLoadTest(req)
+	if err != nil {
+		return apierrors.ErrLoadTestAutoTestScene.InternalError(err).ToResp(), nil
+	}
+
+	return httpserver.OkResp(result)
+}
+
 // ExecuteDiceAutotestScene 执行步骤
 func (e *Endpoints) ExecuteDiceAutotestSceneStep(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
 	var req apistructs.AutotestExecuteSceneStepRequest
@@ -0,0 +1,166 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/modules/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+)
+
+// GetAutoTestSceneDataset 获取场景或场景集的数据集
+func (e *Endpoints) GetAutoTestSceneDataset(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	scopeType, scopeID, err := sceneDatasetScope(vars)
+	if err != nil {
+		return apierrors.ErrGetAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetAutoTestSceneDataset.NotLogin().ToResp(), nil
+	}
+	if err := e.checkSceneDatasetPermission(identityInfo, scopeType, scopeID, apistructs.GetAction, false); err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	dataset, err := e.autotestV2.GetSceneDataset(scopeType, scopeID)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(dataset)
+}
+
+// SaveAutoTestSceneDataset 保存场景或场景集的数据集
+func (e *Endpoints) SaveAutoTestSceneDataset(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	scopeType, scopeID, err := sceneDatasetScope(vars)
+	if err != nil {
+		return apierrors.ErrSaveAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	var req apistructs.AutoTestSceneDatasetSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrSaveAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrSaveAutoTestSceneDataset.NotLogin().ToResp(), nil
+	}
+	req.ScopeType = scopeType
+	req.ScopeID = scopeID
+	req.IdentityInfo = identityInfo
+	if err := e.checkSceneDatasetPermission(identityInfo, scopeType, scopeID, apistructs.UpdateAction, true); err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	dataset, err := e.autotestV2.SaveSceneDataset(req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(dataset)
+}
+
+// ImportAutoTestSceneDataset 从 csv 或 excel 文件导入数据集
+func (e *Endpoints) ImportAutoTestSceneDataset(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	scopeType, scopeID, err := sceneDatasetScope(vars)
+	if err != nil {
+		return apierrors.ErrImportAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	var req apistructs.AutoTestSceneDatasetImportRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrImportAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrImportAutoTestSceneDataset.NotLogin().ToResp(), nil
+	}
+	req.ScopeType = scopeType
+	req.ScopeID = scopeID
+	req.IdentityInfo = identityInfo
+	if err := e.checkSceneDatasetPermission(identityInfo, scopeType, scopeID, apistructs.UpdateAction, true); err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	dataset, err := e.autotestV2.ImportSceneDataset(req, r)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(dataset)
+}
+
+// DeleteAutoTestSceneDataset 删除场景或场景集的数据集
+func (e *Endpoints) DeleteAutoTestSceneDataset(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	scopeType, scopeID, err := sceneDatasetScope(vars)
+	if err != nil {
+		return apierrors.ErrDeleteAutoTestSceneDataset.InvalidParameter(err).ToResp(), nil
+	}
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrDeleteAutoTestSceneDataset.NotLogin().ToResp(), nil
+	}
+	if err := e.checkSceneDatasetPermission(identityInfo, scopeType, scopeID, apistructs.UpdateAction, true); err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	if err := e.autotestV2.DeleteSceneDataset(scopeType, scopeID); err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(scopeID)
+}
+
+// sceneDatasetScope the dataset belongs to scene if sceneID in path, otherwise scene set
+func sceneDatasetScope(vars map[string]string) (apistructs.AutoTestSceneDatasetScope, uint64, error) {
+	scopeType, idStr := apistructs.AutoTestSceneDatasetScopeScene, vars["sceneID"]
+	if idStr == "" {
+		scopeType, idStr = apistructs.AutoTestSceneDatasetScopeSceneSet, vars["setID"]
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return scopeType, id, nil
+}
+
+func (e *Endpoints) checkSceneDatasetPermission(identityInfo apistructs.IdentityInfo, scopeType apistructs.AutoTestSceneDatasetScope,
+	scopeID uint64, action string, checkOpen bool) error {
+	sp, err := e.autotestV2.SceneDatasetSpace(scopeType, scopeID)
+	if err != nil {
+		return err
+	}
+	if checkOpen && !sp.IsOpen() {
+		return apierrors.ErrSaveAutoTestSceneDataset.InvalidState("所属测试空间已锁定")
+	}
+	if identityInfo.IsInternalClient() {
+		return nil
+	}
+	access, err := e.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   identityInfo.UserID,
+		Scope:    apistructs.ProjectScope,
+		ScopeID:  uint64(sp.ProjectID),
+		Resource: apistructs.AutotestSceneResource,
+		Action:   action,
+	})
+	if err != nil {
+		return err
+	}
+	if !access.Access {
+		return apierrors.ErrSaveAutoTestSceneDataset.AccessDenied()
+	}
+	return nil
+}
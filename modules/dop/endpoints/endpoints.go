@@ -137,6 +137,18 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/api-assets/{assetID}/versions/{versionID}", Method: http.MethodDelete,
 			Handler: httpserver.Wrap(e.DeleteAPIAssetVersion, httpserver.WithI18nCodes)},
 		{Path: "/api/api-assets/{assetID}/versions/{versionID}/export", Method: http.MethodGet, WriterHandler: e.DownloadSpecText},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/compare", Method: http.MethodGet, Handler: httpserver.Wrap(e.CompareAPIAssetVersions, httpserver.WithI18nCodes)},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-scenarios", Method: http.MethodGet, Handler: httpserver.Wrap(e.ListMockScenarios, httpserver.WithI18nCodes)},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-scenarios", Method: http.MethodPost, Handler: httpserver.Wrap(e.CreateMockScenario, httpserver.WithI18nCodes)},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-scenarios/{scenarioID}", Method: http.MethodPut, Handler: httpserver.Wrap(e.UpdateMockScenario, httpserver.WithI18nCodes)},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-scenarios/{scenarioID}", Method: http.MethodDelete, Handler: httpserver.Wrap(e.DeleteMockScenario, httpserver.WithI18nCodes)},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodGet, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodPost, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodPut, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodPatch, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodDelete, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodHead, WriterHandler: e.MockAPIAsset},
+		{Path: "/api/api-assets/{assetID}/versions/{versionID}/mock-server{mockPath:/.*}", Method: http.MethodOptions, WriterHandler: e.MockAPIAsset},
 
 		{Path: "/api/api-assets/{assetID}/swagger-versions", Method: http.MethodGet, Handler: httpserver.Wrap(e.ListSwaggerVersions, httpserver.WithI18nCodes)},
 
@@ -316,6 +328,10 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/testcases/actions/batch-clean-from-recycle-bin", Method: http.MethodDelete, Handler: e.BatchCleanTestCasesFromRecycleBin},
 		{Path: "/api/testcases/actions/export", Method: http.MethodGet, Handler: e.ExportTestCases},
 		{Path: "/api/testcases/actions/import", Method: http.MethodPost, Handler: e.ImportTestCases},
+		{Path: "/api/testcases/{testCaseID}/versions", Method: http.MethodGet, Handler: e.ListTestCaseVersions},
+		{Path: "/api/testcases/{testCaseID}/versions/{version}", Method: http.MethodGet, Handler: e.GetTestCaseVersion},
+		{Path: "/api/testcases/{testCaseID}/versions/{version}/actions/restore", Method: http.MethodPost, Handler: e.RestoreTestCaseVersion},
+		{Path: "/api/testcases/{testCaseID}/actions/review", Method: http.MethodPost, Handler: e.ReviewTestCase},
 
 		// 测试集 管理
 		{Path: "/api/testsets", Method: http.MethodPost, Handler: e.CreateTestSet},
@@ -406,6 +422,16 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/autotests/scenes/{sceneID}/actions/update-output", Method: http.MethodPut, Handler: e.UpdateAutoTestSceneOutput},
 		{Path: "/api/autotests/scenes/{sceneID}/actions/list-output", Method: http.MethodGet, Handler: e.ListAutoTestSceneOutput},
 
+		// 自动化测试 - 数据驱动
+		{Path: "/api/autotests/scenes/{sceneID}/dataset", Method: http.MethodGet, Handler: e.GetAutoTestSceneDataset},
+		{Path: "/api/autotests/scenes/{sceneID}/dataset", Method: http.MethodPut, Handler: e.SaveAutoTestSceneDataset},
+		{Path: "/api/autotests/scenes/{sceneID}/dataset", Method: http.MethodDelete, Handler: e.DeleteAutoTestSceneDataset},
+		{Path: "/api/autotests/scenes/{sceneID}/dataset/actions/import", Method: http.MethodPost, Handler: e.ImportAutoTestSceneDataset},
+		{Path: "/api/autotests/scenesets/{setID}/dataset", Method: http.MethodGet, Handler: e.GetAutoTestSceneDataset},
+		{Path: "/api/autotests/scenesets/{setID}/dataset", Method: http.MethodPut, Handler: e.SaveAutoTestSceneDataset},
+		{Path: "/api/autotests/scenesets/{setID}/dataset", Method: http.MethodDelete, Handler: e.DeleteAutoTestSceneDataset},
+		{Path: "/api/autotests/scenesets/{setID}/dataset/actions/import", Method: http.MethodPost, Handler: e.ImportAutoTestSceneDataset},
+
 		// 自动化测试 - 步骤
 		{Path: "/api/autotests/scenes/{sceneID}/actions/add-step", Method: http.MethodPost, Handler: e.CreateAutoTestSceneStep},
 		{Path: "/api/autotests/scenes-step/{stepID}", Method: http.MethodDelete, Handler: e.DeleteAutoTestSceneStep},
@@ -434,6 +460,7 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		// 场景 执行取消
 		{Path: "/api/autotests/scenes-step/{stepID}/actions/execute", Method: http.MethodPost, Handler: e.ExecuteDiceAutotestSceneStep},
 		{Path: "/api/autotests/scenes/{sceneID}/actions/execute", Method: http.MethodPost, Handler: e.ExecuteDiceAutotestScene},
+		{Path: "/api/autotests/scenes/{sceneID}/actions/load-test", Method: http.MethodPost, Handler: e.LoadTestDiceAutotestScene},
 		{Path: "/api/autotests/scenes/{sceneID}/actions/cancel", Method: http.MethodPost, Handler: e.CancelDiceAutotestScene},
 
 		// 计划 执行取消
@@ -612,6 +639,7 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/projects/{projectID}/test-reports", Method: http.MethodPost, Handler: e.CreateTestReportRecord},
 		{Path: "/api/projects/{projectID}/test-reports/actions/list", Method: http.MethodGet, Handler: e.ListTestReportRecord},
 		{Path: "/api/projects/{projectID}/test-reports/{id}", Method: http.MethodGet, Handler: e.GetTestReportRecord},
+		{Path: "/api/projects/{projectID}/test-reports/load-tests/{pipelineID}", Method: http.MethodGet, Handler: e.GetLoadTestReport},
 
 		// project template
 		{Path: "/api/orgs/{orgID}/projects/{projectID}/template/actions/export", Method: http.MethodGet, Handler: e.ExportProjectTemplate},
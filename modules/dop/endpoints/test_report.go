@@ -98,3 +98,21 @@ func (e *Endpoints) GetTestReportRecord(ctx context.Context, r *http.Request, va
 	}
 	return httpserver.OkResp(record)
 }
+
+func (e *Endpoints) GetLoadTestReport(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	_, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetLoadTestReport.NotLogin().ToResp(), nil
+	}
+
+	pipelineID, err := strconv.ParseUint(vars["pipelineID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetLoadTestReport.InvalidParameter("pipelineID").ToResp(), nil
+	}
+
+	report, err := e.testReportSvc.GetLoadTestReport(pipelineID)
+	if err != nil {
+		return apierrors.ErrGetLoadTestReport.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(report)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/modules/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// ListTestCaseVersions 查询测试用例历史版本
+func (e *Endpoints) ListTestCaseVersions(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, err := user.GetIdentityInfo(r); err != nil {
+		return apierrors.ErrListTestCaseVersions.NotLogin().ToResp(), nil
+	}
+
+	tcID, err := strconv.ParseUint(vars["testCaseID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrListTestCaseVersions.InvalidParameter("testCaseID").ToResp(), nil
+	}
+
+	versions, err := e.testcase.ListTestCaseVersions(tcID)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	var userIDs []string
+	for _, v := range versions {
+		userIDs = append(userIDs, v.CreatorID)
+	}
+	return httpserver.OkResp(versions, strutil.DedupSlice(userIDs, true))
+}
+
+// GetTestCaseVersion 获取测试用例指定版本
+func (e *Endpoints) GetTestCaseVersion(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, err := user.GetIdentityInfo(r); err != nil {
+		return apierrors.ErrGetTestCaseVersion.NotLogin().ToResp(), nil
+	}
+
+	tcID, err := strconv.ParseUint(vars["testCaseID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetTestCaseVersion.InvalidParameter("testCaseID").ToResp(), nil
+	}
+	version, err := strconv.ParseUint(vars["version"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetTestCaseVersion.InvalidParameter("version").ToResp(), nil
+	}
+
+	v, err := e.testcase.GetTestCaseVersion(tcID, version)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(v, []string{v.CreatorID})
+}
+
+// RestoreTestCaseVersion 恢复测试用例至指定版本
+func (e *Endpoints) RestoreTestCaseVersion(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrRestoreTestCaseVersion.NotLogin().ToResp(), nil
+	}
+
+	var req apistructs.TestCaseVersionRestoreRequest
+	req.TestCaseID, err = strconv.ParseUint(vars["testCaseID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrRestoreTestCaseVersion.InvalidParameter("testCaseID").ToResp(), nil
+	}
+	req.Version, err = strconv.ParseUint(vars["version"], 10, 64)
+	if err != nil {
+		return apierrors.ErrRestoreTestCaseVersion.InvalidParameter("version").ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+
+	if err := e.testcase.RestoreTestCaseVersion(req); err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(nil)
+}
+
+// ReviewTestCase 测试用例评审
+func (e *Endpoints) ReviewTestCase(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrReviewTestCase.NotLogin().ToResp(), nil
+	}
+
+	tcID, err := strconv.ParseUint(vars["testCaseID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrReviewTestCase.InvalidParameter("testCaseID").ToResp(), nil
+	}
+
+	var req apistructs.TestCaseReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrReviewTestCase.InvalidParameter(err).ToResp(), nil
+	}
+	req.TestCaseID = tcID
+	req.IdentityInfo = identityInfo
+
+	status, err := e.testcase.ReviewTestCase(req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(status)
+}
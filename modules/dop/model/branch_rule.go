@@ -31,6 +31,12 @@ type BranchRule struct {
 	Desc              string //规则说明
 	Workspace         string `json:"workspace"`
 	ArtifactWorkspace string `json:"artifactWorkspace"`
+	// 合并前需要的 approve 数量
+	RequiredApprovals int
+	// 合并前需要 CODEOWNERS 中的 owner approve
+	RequireCodeOwnerApproval bool `gorm:"column:is_code_owner_approval_required"`
+	// 推送的提交必须有验证通过的签名
+	RequireSignedCommits bool `gorm:"column:is_signed_commits_required"`
 }
 
 // TableName 设置模型对应数据库表名称
@@ -50,5 +56,9 @@ func (rule *BranchRule) ToApiData() *apistructs.BranchRule {
 		Desc:              rule.Desc,
 		Workspace:         rule.Workspace,
 		ArtifactWorkspace: rule.ArtifactWorkspace,
+
+		RequiredApprovals:        rule.RequiredApprovals,
+		RequireCodeOwnerApproval: rule.RequireCodeOwnerApproval,
+		RequireSignedCommits:     rule.RequireSignedCommits,
 	}
 }
@@ -343,7 +343,8 @@ func (s *TestPlanService) calcTotalApiNum(req *pb.TestPlanUpdateByHookRequest) (
 	case apistructs.StepTypeScene.String():
 		return s.countApiBySceneIDRepeat(req.Content.SceneID)
 	case apistructs.StepTypeAPI.String(), apistructs.StepTypeWait.String(),
-		apistructs.StepTypeCustomScript.String(), apistructs.StepTypeConfigSheet.String():
+		apistructs.StepTypeCustomScript.String(), apistructs.StepTypeConfigSheet.String(),
+		apistructs.StepTypeGRPC.String(), apistructs.StepTypeWebSocket.String():
 		return 1, nil
 	}
 	return 0, nil
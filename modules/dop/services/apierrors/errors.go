@@ -32,11 +32,18 @@ var (
 	PagingAPIAssets = err("ErrPagingAPIAssets", "分页查询 API 资料失败")
 	DeleteAPIAsset  = err("ErrDeleteAPIAsset", "删除 API 资料失败")
 
-	CreateAPIAssetVersion  = err("ErrCreateAPIAssetVersion", "创建 API 资料版本失败")
-	PagingAPIAssetVersions = err("ErrPagingAPIAssetVersions", "获取 API 资料版本列表失败")
-	GetAPIAssetVersion     = err("ErrGetAPIAssetVersion", "查询 API 资料版本详情失败")
-	UpdateAssetVersion     = err("ErrUpdateAssetVersion", "修改 API 资料版本失败")
-	DeleteAPIAssetVersion  = err("ErrDeleteAPIAssetVersion", "删除 API 资料详情失败")
+	CreateAPIAssetVersion   = err("ErrCreateAPIAssetVersion", "创建 API 资料版本失败")
+	PagingAPIAssetVersions  = err("ErrPagingAPIAssetVersions", "获取 API 资料版本列表失败")
+	GetAPIAssetVersion      = err("ErrGetAPIAssetVersion", "查询 API 资料版本详情失败")
+	UpdateAssetVersion      = err("ErrUpdateAssetVersion", "修改 API 资料版本失败")
+	DeleteAPIAssetVersion   = err("ErrDeleteAPIAssetVersion", "删除 API 资料详情失败")
+	CompareAPIAssetVersions = err("ErrCompareAPIAssetVersions", "比较 API 资料版本失败")
+
+	MockAPIAsset       = err("ErrMockAPIAsset", "模拟 API 请求失败")
+	ListMockScenarios  = err("ErrListMockScenarios", "查询 Mock 场景列表失败")
+	CreateMockScenario = err("ErrCreateMockScenario", "创建 Mock 场景失败")
+	UpdateMockScenario = err("ErrUpdateMockScenario", "修改 Mock 场景失败")
+	DeleteMockScenario = err("ErrDeleteMockScenario", "删除 Mock 场景失败")
 
 	ValidateAPISpec        = err("ErrValidateAPISpec", "校验 API Spec 失败")
 	GetAPIAssetVersionSpec = err("GetAPIAssetVersionSpec", "查询 API 资料版本 Spec 失败")
@@ -221,6 +228,10 @@ var (
 	ErrInvalidTestCaseExcelFormat        = err("ErrInvalidTestCaseExcelFormat", "文件格式不正确，请对比 Excel 导入模板")
 	ErrGetApiTestInfo                    = err("ErrErrGetApiTestInfo", "查询接口测试信息失败")
 	ErrBatchCleanTestCasesFromRecycleBin = err("ErrBatchCleanTestCasesFromRecycleBin", "从回收站批量删除测试用例失败")
+	ErrListTestCaseVersions              = err("ErrListTestCaseVersions", "查询测试用例历史版本失败")
+	ErrGetTestCaseVersion                = err("ErrGetTestCaseVersion", "获取测试用例历史版本失败")
+	ErrRestoreTestCaseVersion            = err("ErrRestoreTestCaseVersion", "恢复测试用例历史版本失败")
+	ErrReviewTestCase                    = err("ErrReviewTestCase", "评审测试用例失败")
 	ErrExportTestPlanCaseRels            = err("ErrExportTestPlanCaseRels", "导出测试计划下的测试用例失败")
 	ErrGenerateTestPlanReport            = err("ErrGenerateTestPlanReport", "生成测试计划报告失败")
 	ErrExecuteTestPlanReport             = err("ErrExecuteTestPlanReport", "执行测试计划失败")
@@ -302,6 +313,7 @@ var (
 	ErrListAutoTestScene        = err("ErrListAutoTestScene", "获取自动化测试场景列表失败")
 	ErrExecuteAutoTestScene     = err("ErrExecuteAutoTestScene", "执行自动化测试场景失败")
 	ErrExecuteAutoTestSceneStep = err("ErrExecuteAutoTestSceneStep", "执行自动化测试场景步骤失败")
+	ErrLoadTestAutoTestScene    = err("ErrLoadTestAutoTestScene", "压测自动化测试场景失败")
 	ErrCancelAutoTestScene      = err("ErrCancelAutoTestScene", "取消执行自动化测试场景失败")
 	ErrMoveAutoTestScene        = err("ErrMoveAutoTestScene", "拖动自动化测试场景失败")
 	ErrCopyAutoTestScene        = err("ErrCopyAutoTestScene", "复制自动化测试场景失败")
@@ -322,6 +334,11 @@ var (
 	ErrListAutoTestSceneStep       = err("ErrListAutoTestSceneStep", "获取自动化测试场景步骤失败")
 	ErrListAutoTestSceneStepOutPut = err("ErrListAutoTestSceneStepOutPut", "获取自动化测试场景步骤出参失败")
 
+	ErrGetAutoTestSceneDataset    = err("ErrGetAutoTestSceneDataset", "获取自动化测试场景数据集失败")
+	ErrSaveAutoTestSceneDataset   = err("ErrSaveAutoTestSceneDataset", "保存自动化测试场景数据集失败")
+	ErrImportAutoTestSceneDataset = err("ErrImportAutoTestSceneDataset", "导入自动化测试场景数据集失败")
+	ErrDeleteAutoTestSceneDataset = err("ErrDeleteAutoTestSceneDataset", "删除自动化测试场景数据集失败")
+
 	ErrPagingSonarMetricRules          = err("ErrPagingSonarMetricRules", "分页查询指标规则失败")
 	ErrQuerySonarMetricRules           = err("ErrQuerySonarMetricRules", "查询指标规则失败")
 	ErrBatchCreateSonarMetricRules     = err("ErrBatchCreateSonarMetricRules", "批量创建指标规则失败")
@@ -514,6 +531,7 @@ var (
 	ErrCreateTestReportRecord = err("ErrCreateTestReportRecord", "创建测试报告记录失败")
 	ErrListTestReportRecord   = err("ErrListTestReportRecord", "查询测试报告记录失败")
 	ErrGetTestReportRecord    = err("ErrGetTestReportRecord", "获取测试报告记录失败")
+	ErrGetLoadTestReport      = err("ErrGetLoadTestReport", "获取压测报告失败")
 
 	ErrApplicationsResources = err("ErrApplicationsResources", "查询应用资源列表失败")
 
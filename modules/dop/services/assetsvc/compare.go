@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assetsvc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dbclient"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/pkg/swagger/oas3"
+)
+
+// CompareAssetVersions 比较 API 资料的两个版本, 返回目标版本相对于基准版本的语义变更
+func (svc *Service) CompareAssetVersions(ctx context.Context, req *apistructs.CompareAPIAssetVersionsReq) (*apistructs.CompareAPIAssetVersionsRsp, error) {
+	// 参数校验
+	if req.OrgID == 0 {
+		return nil, apierrors.CompareAPIAssetVersions.MissingParameter(apierrors.MissingOrgID)
+	}
+	if err := apistructs.ValidateAPIAssetID(req.URIParams.AssetID); err != nil {
+		return nil, apierrors.CompareAPIAssetVersions.InvalidParameter(fmt.Errorf("assetID: %v", err))
+	}
+
+	// 查询目标版本
+	target, err := dbclient.GetAPIAssetVersion(&apistructs.GetAPIAssetVersionReq{
+		OrgID:     req.OrgID,
+		Identity:  req.Identity,
+		URIParams: req.URIParams,
+	})
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, apierrors.CompareAPIAssetVersions.NotFound()
+		}
+		return nil, apierrors.CompareAPIAssetVersions.InternalError(err)
+	}
+
+	// 查询基准版本, 未指定时取上一个版本
+	var base *apistructs.APIAssetVersionsModel
+	if req.QueryParams.BaseVersionID != 0 {
+		base, err = dbclient.GetAPIAssetVersion(&apistructs.GetAPIAssetVersionReq{
+			OrgID:     req.OrgID,
+			Identity:  req.Identity,
+			URIParams: &apistructs.AssetVersionDetailURI{AssetID: req.URIParams.AssetID, VersionID: req.QueryParams.BaseVersionID},
+		})
+	} else {
+		base, err = dbclient.QueryAPIPreviousVersion(req.OrgID, req.URIParams.AssetID, target.Major, target.Minor, target.Patch, false)
+	}
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, apierrors.CompareAPIAssetVersions.InvalidParameter("no base version to compare with")
+		}
+		return nil, apierrors.CompareAPIAssetVersions.InternalError(err)
+	}
+
+	targetSwagger, err := svc.loadVersionSwagger(ctx, req.OrgID, target.ID)
+	if err != nil {
+		return nil, apierrors.CompareAPIAssetVersions.InternalError(err)
+	}
+	result, err := svc.diffWithVersion(ctx, req.OrgID, base, targetSwagger)
+	if err != nil {
+		return nil, apierrors.CompareAPIAssetVersions.InternalError(err)
+	}
+
+	return &apistructs.CompareAPIAssetVersionsRsp{
+		Base:     base,
+		Target:   target,
+		Breaking: result.HasBreaking(),
+		Changes:  result.Changes,
+	}, nil
+}
+
+// checkBreakingChanges 发布 minor/patch 版本时, 与同一 major 下的上一版本比较, 存在不兼容变更则返回错误
+func (svc *Service) checkBreakingChanges(ctx context.Context, version *apistructs.APIAssetVersionsModel, swagger *openapi3.Swagger) error {
+	if version.Minor == 0 && version.Patch == 0 {
+		return nil
+	}
+	previous, err := dbclient.QueryAPIPreviousVersion(version.OrgID, version.AssetID, version.Major, version.Minor, version.Patch, true)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return apierrors.CreateAPIAssetVersion.InternalError(err)
+	}
+	result, err := svc.diffWithVersion(ctx, version.OrgID, previous, swagger)
+	if err != nil {
+		return apierrors.CreateAPIAssetVersion.InternalError(err)
+	}
+	breaking := result.BreakingChanges()
+	if len(breaking) == 0 {
+		return nil
+	}
+
+	var messages []string
+	for _, change := range breaking {
+		messages = append(messages, change.String())
+	}
+	return apierrors.CreateAPIAssetVersion.InvalidParameter(fmt.Errorf("%d breaking changes against version %d.%d.%d, publish a new major version instead: %s",
+		len(breaking), previous.Major, previous.Minor, previous.Patch, strings.Join(messages, "; ")))
+}
+
+// diffWithVersion 以 base 版本的文档为基准与 target 比较
+func (svc *Service) diffWithVersion(ctx context.Context, orgID uint64, base *apistructs.APIAssetVersionsModel, target *openapi3.Swagger) (*oas3.DiffResult, error) {
+	baseSwagger, err := svc.loadVersionSwagger(ctx, orgID, base.ID)
+	if err != nil {
+		return nil, err
+	}
+	return oas3.Diff(baseSwagger, target), nil
+}
+
+// loadVersionSwagger 查询并解析版本对应的文档
+func (svc *Service) loadVersionSwagger(ctx context.Context, orgID, versionID uint64) (*openapi3.Swagger, error) {
+	spec, err := dbclient.QueryVersionLatestSpec(orgID, versionID)
+	if err != nil {
+		logrus.Errorf("failed to QueryVersionLatestSpec, versionID: %d, err: %v", versionID, err)
+		return nil, err
+	}
+	protocol := apistructs.APISpecProtocol(spec.SpecProtocol)
+	return svc.parseSpec(ctx, &protocol, spec.Spec)
+}
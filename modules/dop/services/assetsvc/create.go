@@ -278,6 +278,12 @@ func (svc *Service) CreateAPIAssetVersion(ctx context.Context, req apistructs.AP
 		return nil, nil, nil, apierrors.CreateAPIAssetVersion.InternalError(err)
 	}
 
+	if req.BlockBreaking {
+		if err := svc.checkBreakingChanges(ctx, version, swagger); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	if err := dbclient.Sq().Create(version).Error; err != nil {
 		return nil, nil, nil, apierrors.CreateAPIAssetVersion.InternalError(err)
 	}
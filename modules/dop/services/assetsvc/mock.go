@@ -0,0 +1,299 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assetsvc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dbclient"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/pkg/swagger/mock"
+)
+
+// mockCache 缓存每个版本构建好的 mock 服务, key 为版本 id, 文档的 id 或更新时间变化后重新构建
+var mockCache sync.Map
+
+// mockCacheKey 标识文档的一个版本, 文档记录的 id 和更新时间
+type mockCacheKey struct {
+	specID        uint64
+	specUpdatedAt int64
+}
+
+type cachedMock struct {
+	key  mockCacheKey
+	mock *mock.Mock
+}
+
+// ServeMock 按 API 资料版本的文档模拟一次请求
+func (svc *Service) ServeMock(ctx context.Context, req *apistructs.APIMockReq) (*mock.Response, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.MockAPIAsset.MissingParameter(apierrors.MissingOrgID)
+	}
+	if _, err := svc.getMockVersion(req.OrgID, req.AssetID, req.VersionID); err != nil {
+		return nil, apierrors.MockAPIAsset.NotFound()
+	}
+
+	m, err := svc.loadMock(ctx, req.OrgID, req.VersionID)
+	if err != nil {
+		return nil, apierrors.MockAPIAsset.InternalError(err)
+	}
+	scenarios, err := svc.listMockScenarios(req.OrgID, req.VersionID)
+	if err != nil {
+		return nil, apierrors.MockAPIAsset.InternalError(err)
+	}
+	var list []*mock.Scenario
+	for i := range scenarios {
+		list = append(list, &scenarios[i].Scenario)
+	}
+	return m.Serve(req.Request, list), nil
+}
+
+// ListMockScenarios 查询版本的 mock 场景
+func (svc *Service) ListMockScenarios(req *apistructs.APIMockScenarioReq) ([]*apistructs.APIMockScenario, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.ListMockScenarios.MissingParameter(apierrors.MissingOrgID)
+	}
+	if _, err := svc.getMockVersion(req.OrgID, req.AssetID, req.VersionID); err != nil {
+		return nil, apierrors.ListMockScenarios.NotFound()
+	}
+	scenarios, err := svc.listMockScenarios(req.OrgID, req.VersionID)
+	if err != nil {
+		return nil, apierrors.ListMockScenarios.InternalError(err)
+	}
+	return scenarios, nil
+}
+
+// CreateMockScenario 创建 mock 场景, 场景名称在版本内唯一
+func (svc *Service) CreateMockScenario(req *apistructs.APIMockScenarioReq) (*apistructs.APIMockScenario, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.CreateMockScenario.MissingParameter(apierrors.MissingOrgID)
+	}
+	if req.Scenario == nil {
+		return nil, apierrors.CreateMockScenario.MissingParameter("scenario")
+	}
+	if err := req.Scenario.Validate(); err != nil {
+		return nil, apierrors.CreateMockScenario.InvalidParameter(err)
+	}
+	if _, err := svc.getMockVersion(req.OrgID, req.AssetID, req.VersionID); err != nil {
+		return nil, apierrors.CreateMockScenario.NotFound()
+	}
+	if !svc.writeAssetPermission(req.OrgID, req.Identity.UserID, req.AssetID) {
+		return nil, apierrors.CreateMockScenario.AccessDenied()
+	}
+	if svc.FirstRecord(new(apistructs.APIMockScenarioModel), map[string]interface{}{
+		"version_id":      req.VersionID,
+		"name":            req.Scenario.Name,
+		"soft_deleted_at": 0,
+	}) == nil {
+		return nil, apierrors.CreateMockScenario.AlreadyExists()
+	}
+	org, err := svc.bdl.GetOrg(req.OrgID)
+	if err != nil {
+		return nil, apierrors.CreateMockScenario.InternalError(err)
+	}
+
+	config, err := json.Marshal(req.Scenario)
+	if err != nil {
+		return nil, apierrors.CreateMockScenario.InvalidParameter(err)
+	}
+	model := apistructs.APIMockScenarioModel{
+		ID:        uuid.New().String(),
+		OrgID:     req.OrgID,
+		OrgName:   org.Name,
+		CreatorID: req.Identity.UserID,
+		UpdaterID: req.Identity.UserID,
+		AssetID:   req.AssetID,
+		VersionID: req.VersionID,
+		Name:      req.Scenario.Name,
+		IsEnabled: req.Scenario.Enabled,
+		Config:    string(config),
+	}
+	if err := dbclient.Sq().Create(&model).Error; err != nil {
+		return nil, apierrors.CreateMockScenario.InternalError(err)
+	}
+	return toAPIMockScenario(&model)
+}
+
+// UpdateMockScenario 修改 mock 场景
+func (svc *Service) UpdateMockScenario(req *apistructs.APIMockScenarioReq) (*apistructs.APIMockScenario, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.UpdateMockScenario.MissingParameter(apierrors.MissingOrgID)
+	}
+	if req.Scenario == nil {
+		return nil, apierrors.UpdateMockScenario.MissingParameter("scenario")
+	}
+	if err := req.Scenario.Validate(); err != nil {
+		return nil, apierrors.UpdateMockScenario.InvalidParameter(err)
+	}
+	model, err := svc.getMockScenario(req)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, apierrors.UpdateMockScenario.NotFound()
+		}
+		return nil, apierrors.UpdateMockScenario.InternalError(err)
+	}
+	if !svc.writeAssetPermission(req.OrgID, req.Identity.UserID, req.AssetID) {
+		return nil, apierrors.UpdateMockScenario.AccessDenied()
+	}
+	if req.Scenario.Name != model.Name && svc.FirstRecord(new(apistructs.APIMockScenarioModel), map[string]interface{}{
+		"version_id":      req.VersionID,
+		"name":            req.Scenario.Name,
+		"soft_deleted_at": 0,
+	}) == nil {
+		return nil, apierrors.UpdateMockScenario.AlreadyExists()
+	}
+
+	config, err := json.Marshal(req.Scenario)
+	if err != nil {
+		return nil, apierrors.UpdateMockScenario.InvalidParameter(err)
+	}
+	model.Name = req.Scenario.Name
+	model.IsEnabled = req.Scenario.Enabled
+	model.Config = string(config)
+	model.UpdaterID = req.Identity.UserID
+	if err := dbclient.Sq().Save(model).Error; err != nil {
+		return nil, apierrors.UpdateMockScenario.InternalError(err)
+	}
+	return toAPIMockScenario(model)
+}
+
+// DeleteMockScenario 删除 mock 场景
+func (svc *Service) DeleteMockScenario(req *apistructs.APIMockScenarioReq) error {
+	if req.OrgID == 0 {
+		return apierrors.DeleteMockScenario.MissingParameter(apierrors.MissingOrgID)
+	}
+	model, err := svc.getMockScenario(req)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return apierrors.DeleteMockScenario.NotFound()
+		}
+		return apierrors.DeleteMockScenario.InternalError(err)
+	}
+	if !svc.writeAssetPermission(req.OrgID, req.Identity.UserID, req.AssetID) {
+		return apierrors.DeleteMockScenario.AccessDenied()
+	}
+	if err := dbclient.Sq().Model(model).Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error; err != nil {
+		return apierrors.DeleteMockScenario.InternalError(err)
+	}
+	return nil
+}
+
+func (svc *Service) getMockVersion(orgID uint64, assetID string, versionID uint64) (*apistructs.APIAssetVersionsModel, error) {
+	var version apistructs.APIAssetVersionsModel
+	if err := svc.FirstRecord(&version, map[string]interface{}{
+		"org_id":   orgID,
+		"asset_id": assetID,
+		"id":       versionID,
+	}); err != nil {
+		logrus.Errorf("failed to FirstRecord version, versionID: %d, err: %v", versionID, err)
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (svc *Service) getMockScenario(req *apistructs.APIMockScenarioReq) (*apistructs.APIMockScenarioModel, error) {
+	var model apistructs.APIMockScenarioModel
+	if err := svc.FirstRecord(&model, map[string]interface{}{
+		"org_id":          req.OrgID,
+		"asset_id":        req.AssetID,
+		"version_id":      req.VersionID,
+		"id":              req.ScenarioID,
+		"soft_deleted_at": 0,
+	}); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (svc *Service) listMockScenarios(orgID, versionID uint64) ([]*apistructs.APIMockScenario, error) {
+	var models []apistructs.APIMockScenarioModel
+	if err := dbclient.Sq().Where(map[string]interface{}{
+		"org_id":          orgID,
+		"version_id":      versionID,
+		"soft_deleted_at": 0,
+	}).Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	scenarios := make([]*apistructs.APIMockScenario, 0, len(models))
+	for i := range models {
+		scenario, err := toAPIMockScenario(&models[i])
+		if err != nil {
+			logrus.Errorf("failed to parse mock scenario, id: %s, err: %v", models[i].ID, err)
+			continue
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// loadMock 构建版本的 mock 服务, 只查询文档的 id 和更新时间, 文档未变更时使用缓存,
+// 不再重复加载和解析文档
+func (svc *Service) loadMock(ctx context.Context, orgID, versionID uint64) (*mock.Mock, error) {
+	var spec apistructs.APIAssetVersionSpecsModel
+	if err := dbclient.Sq().Select("id, updated_at").Where(map[string]interface{}{
+		"org_id":     orgID,
+		"version_id": versionID,
+	}).First(&spec).Error; err != nil {
+		return nil, err
+	}
+	key := mockCacheKey{specID: spec.ID, specUpdatedAt: spec.UpdatedAt.UnixNano()}
+	if v, ok := mockCache.Load(versionID); ok {
+		if cached := v.(*cachedMock); cached.key == key {
+			return cached.mock, nil
+		}
+	}
+
+	var full apistructs.APIAssetVersionSpecsModel
+	if err := dbclient.Sq().First(&full, spec.ID).Error; err != nil {
+		return nil, err
+	}
+	protocol := apistructs.APISpecProtocol(full.SpecProtocol)
+	swagger, err := svc.parseSpec(ctx, &protocol, full.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec of version %d: %v", versionID, err)
+	}
+	m := mock.New(swagger)
+	// 每个版本只保留最新文档的 mock 服务
+	key = mockCacheKey{specID: full.ID, specUpdatedAt: full.UpdatedAt.UnixNano()}
+	mockCache.Store(versionID, &cachedMock{key: key, mock: m})
+	return m, nil
+}
+
+func toAPIMockScenario(model *apistructs.APIMockScenarioModel) (*apistructs.APIMockScenario, error) {
+	scenario := apistructs.APIMockScenario{
+		ID:        model.ID,
+		AssetID:   model.AssetID,
+		VersionID: model.VersionID,
+		CreatorID: model.CreatorID,
+		UpdaterID: model.UpdaterID,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(model.Config), &scenario.Scenario); err != nil {
+		return nil, err
+	}
+	scenario.Name = model.Name
+	scenario.Enabled = model.IsEnabled
+	return &scenario, nil
+}
@@ -17,6 +17,7 @@ package autotestv2
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
@@ -617,6 +618,37 @@ func (svc *Service) ImportSceneSetFile(record *dao.TestFileRecord) {
 			}
 			return
 		}
+	case apistructs.TestSceneSetFileTypeOpenAPI, apistructs.TestSceneSetFileTypePostman, apistructs.TestSceneSetFileTypeHAR:
+		content, err := ioutil.ReadAll(f)
+		if err != nil {
+			logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: record.ID, State: apistructs.FileRecordStateFail, Description: fmt.Sprintf("%s, err: %v", record.Description, err)}); err != nil {
+				logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			}
+			return
+		}
+		external, err := newSceneSetExternal(req.FileType, content, &AutoTestSpaceData{
+			ProjectID:    req.ProjectID,
+			SpaceID:      req.SpaceID,
+			IdentityInfo: req.IdentityInfo,
+			svc:          svc,
+			Space:        space,
+			NewSpace:     space,
+		})
+		if err == nil {
+			creator := AutoTestSpaceDirector{}
+			creator.New(external)
+			if err = creator.ConstructSceneSet(); err == nil {
+				err = creator.Creator.GetSpaceData().CopyFromSceneSets()
+			}
+		}
+		if err != nil {
+			logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: record.ID, State: apistructs.FileRecordStateFail, Description: fmt.Sprintf("%s, import sceneset data err: %v", record.Description, err)}); err != nil {
+				logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			}
+			return
+		}
 	default:
 
 	}
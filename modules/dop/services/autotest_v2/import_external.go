@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/expression"
+)
+
+// importedSceneSet scene set parsed from external formats (OpenAPI, Postman, HAR)
+type importedSceneSet struct {
+	Name        string
+	Description string
+	Scenes      []importedScene
+}
+
+type importedScene struct {
+	Name        string
+	Description string
+	Inputs      []apistructs.AutoTestSceneInput
+	Steps       []importedStep
+}
+
+type importedStep struct {
+	Name string
+	API  apistructs.APIInfoV2
+}
+
+// sceneSetParser parses file content to scene sets
+type sceneSetParser func(data []byte) ([]importedSceneSet, error)
+
+// AutoTestSceneSetExternal convert external api definitions or recordings to scene set datas
+// will achieve AutoTestSpaceDataCreator implement
+type AutoTestSceneSetExternal struct {
+	content []byte
+	parse   sceneSetParser
+	sets    []importedSceneSet
+	Data    *AutoTestSpaceData
+}
+
+func newSceneSetExternal(fileType apistructs.TestSceneSetFileType, content []byte, data *AutoTestSpaceData) (*AutoTestSceneSetExternal, error) {
+	var parse sceneSetParser
+	switch fileType {
+	case apistructs.TestSceneSetFileTypeOpenAPI:
+		parse = parseOpenAPISceneSets
+	case apistructs.TestSceneSetFileTypePostman:
+		parse = parsePostmanSceneSets
+	case apistructs.TestSceneSetFileTypeHAR:
+		parse = parseHARSceneSets
+	default:
+		return nil, fmt.Errorf("unsupported file type: %s", fileType)
+	}
+	return &AutoTestSceneSetExternal{content: content, parse: parse, Data: data}, nil
+}
+
+func (a *AutoTestSceneSetExternal) SetSpace() error {
+	return nil
+}
+
+// SetSceneSets parse the content and convert scene sets, scenes and steps at once,
+// ids are only used to associate the datas before they are created
+func (a *AutoTestSceneSetExternal) SetSceneSets() error {
+	if a.sets != nil {
+		return nil
+	}
+	sets, err := a.parse(a.content)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("no scene found in file")
+	}
+	a.sets = sets
+
+	var id uint64
+	nextID := func() uint64 {
+		id++
+		return id
+	}
+	spaceID := a.Data.Space.ID
+	a.Data.SceneSets = map[uint64][]apistructs.SceneSet{}
+	a.Data.Scenes = map[uint64][]apistructs.AutoTestScene{}
+	a.Data.Steps = map[uint64][]apistructs.AutoTestSceneStep{}
+	for _, set := range sets {
+		sceneSet := apistructs.SceneSet{ID: nextID(), Name: set.Name, Description: set.Description, SpaceID: spaceID}
+		a.Data.SceneSets[spaceID] = append(a.Data.SceneSets[spaceID], sceneSet)
+		for _, s := range set.Scenes {
+			scene := apistructs.AutoTestScene{
+				Name:        s.Name,
+				Description: s.Description,
+				SetID:       sceneSet.ID,
+				Inputs:      s.Inputs,
+			}
+			scene.ID = nextID()
+			scene.SpaceID = spaceID
+			for i := range scene.Inputs {
+				scene.Inputs[i].SceneID = scene.ID
+				scene.Inputs[i].SpaceID = spaceID
+			}
+			a.Data.Scenes[sceneSet.ID] = append(a.Data.Scenes[sceneSet.ID], scene)
+
+			var steps []apistructs.AutoTestSceneStep
+			for _, st := range s.Steps {
+				value, err := json.Marshal(apistructs.AutoTestRunStep{ApiSpec: apiSpecMap(st.API)})
+				if err != nil {
+					return err
+				}
+				step := apistructs.AutoTestSceneStep{
+					Type:    apistructs.StepTypeAPI,
+					Method:  apistructs.StepAPIMethod(st.API.Method),
+					Value:   string(value),
+					Name:    st.Name,
+					PreType: apistructs.PreTypeSerial,
+					SceneID: scene.ID,
+				}
+				step.ID = nextID()
+				step.SpaceID = spaceID
+				steps = append(steps, step)
+			}
+			a.Data.Steps[scene.ID] = steps
+		}
+	}
+	return nil
+}
+
+func (a *AutoTestSceneSetExternal) SetSingleSceneSet(setID uint64) error {
+	return nil
+}
+
+// SetScenes scenes are set together with scene sets
+func (a *AutoTestSceneSetExternal) SetScenes() error {
+	return nil
+}
+
+// SetSceneSteps steps are set together with scene sets
+func (a *AutoTestSceneSetExternal) SetSceneSteps() error {
+	return nil
+}
+
+func (a *AutoTestSceneSetExternal) SetConfigs() error {
+	return nil
+}
+
+func (a *AutoTestSceneSetExternal) GetSpaceData() *AutoTestSpaceData {
+	return a.Data
+}
+
+// apiSpecMap convert api info to the map stored in step value
+func apiSpecMap(api apistructs.APIInfoV2) map[string]interface{} {
+	if api.Headers == nil {
+		api.Headers = []apistructs.APIHeader{}
+	}
+	if api.Params == nil {
+		api.Params = []apistructs.APIParam{}
+	}
+	if api.OutParams == nil {
+		api.OutParams = []apistructs.APIOutParam{}
+	}
+	if api.Asserts == nil {
+		api.Asserts = []apistructs.APIAssert{}
+	}
+	if api.Body.Type == "" {
+		api.Body.Type = apistructs.APIBodyTypeNone
+	}
+	b, _ := json.Marshal(api)
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// statusAssert make the out param and assert which check response status
+func statusAssert(api *apistructs.APIInfoV2, status int) {
+	const key = "status"
+	if !hasOutParam(api, key) {
+		api.OutParams = append(api.OutParams, apistructs.APIOutParam{Key: key, Source: apistructs.APIOutParamSourceStatus})
+	}
+	api.Asserts = append(api.Asserts, apistructs.APIAssert{Arg: key, Operator: "=", Value: fmt.Sprint(status)})
+}
+
+func hasOutParam(api *apistructs.APIInfoV2, key string) bool {
+	for _, p := range api.OutParams {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// paramsRef returns the expression which refers to scene input
+func paramsRef(name string) string {
+	return fmt.Sprintf("%s %s.%s %s", expression.LeftPlaceholder, expression.Params, name, expression.RightPlaceholder)
+}
+
+// stepName use the given name, or method and path if empty
+func stepName(name, method, path string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return strings.ToUpper(method) + " " + path
+}
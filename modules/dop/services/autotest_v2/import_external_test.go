@@ -0,0 +1,264 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const testOpenAPIDoc = `
+openapi: 3.0.0
+info:
+  title: pet store
+servers:
+  - url: https://petstore.example.com/api/v1
+tags:
+  - name: pet
+    description: pet operations
+paths:
+  /pets/{petID}:
+    parameters:
+      - name: petID
+        in: path
+        required: true
+        schema:
+          type: integer
+          example: 10
+    get:
+      tags: [pet]
+      summary: get pet
+      parameters:
+        - name: verbose
+          in: query
+          required: true
+          schema:
+            type: boolean
+      responses:
+        "404":
+          description: not found
+        "200":
+          description: ok
+  /pets:
+    post:
+      tags: [pet]
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+                  example: kitty
+      responses:
+        "201":
+          description: created
+  /health:
+    get:
+      responses:
+        default:
+          description: ok
+`
+
+func TestParseOpenAPISceneSets(t *testing.T) {
+	sets, err := parseOpenAPISceneSets([]byte(testOpenAPIDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sets))
+	assert.Equal(t, "pet store", sets[0].Name)
+	assert.Equal(t, 2, len(sets[0].Scenes))
+
+	health := sets[0].Scenes[0]
+	assert.Equal(t, "default", health.Name)
+	assert.Equal(t, "GET /health", health.Steps[0].Name)
+	assert.Equal(t, "/api/v1/health", health.Steps[0].API.URL)
+	assert.Equal(t, 0, len(health.Steps[0].API.Asserts))
+
+	pet := sets[0].Scenes[1]
+	assert.Equal(t, "pet", pet.Name)
+	assert.Equal(t, "pet operations", pet.Description)
+	assert.Equal(t, []apistructs.AutoTestSceneInput{{Name: "petID", Value: "10"}}, pet.Inputs)
+	assert.Equal(t, 2, len(pet.Steps))
+
+	get := pet.Steps[1].API
+	assert.Equal(t, "get pet", get.Name)
+	assert.Equal(t, "/api/v1/pets/${{ params.petID }}", get.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "verbose", Value: "true"}}, get.Params)
+	assert.Equal(t, []apistructs.APIAssert{{Arg: "status", Operator: "=", Value: "200"}}, get.Asserts)
+
+	create := pet.Steps[0].API
+	assert.Equal(t, "createPet", create.Name)
+	assert.Equal(t, apistructs.APIBodyTypeApplicationJSON, create.Body.Type)
+	assert.JSONEq(t, `{"name":"kitty"}`, create.Body.Content.(string))
+	assert.Equal(t, "201", create.Asserts[0].Value)
+}
+
+const testPostmanCollection = `{
+  "info": {"name": "users", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "baseUrl", "value": "https://example.com"}],
+  "item": [
+    {
+      "name": "ping",
+      "request": {"method": "GET", "url": "{{baseUrl}}/ping"}
+    },
+    {
+      "name": "user",
+      "item": [
+        {
+          "name": "create user",
+          "request": {
+            "method": "POST",
+            "header": [{"key": "Authorization", "value": "Bearer {{token}}"}, {"key": "X-Disabled", "value": "1", "disabled": true}],
+            "url": {"raw": "{{baseUrl}}/users?dry=true", "query": [{"key": "dry", "value": "true"}]},
+            "body": {"mode": "raw", "raw": "{\"name\": \"erda\"}", "options": {"raw": {"language": "json"}}}
+          },
+          "event": [{
+            "listen": "test",
+            "script": {"exec": [
+              "pm.test(\"ok\", function () {",
+              "    pm.response.to.have.status(201);",
+              "    var jsonData = pm.response.json();",
+              "    pm.expect(jsonData.data.name).to.eql(\"erda\");",
+              "    pm.response.to.have.header(\"X-Request-Id\");",
+              "    pm.expect(pm.response.responseTime).to.be.below(200);",
+              "});"
+            ]}
+          }]
+        },
+        {
+          "name": "login",
+          "item": [{
+            "name": "login",
+            "request": {
+              "method": "POST",
+              "url": "{{baseUrl}}/login",
+              "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "admin"}]}
+            }
+          }]
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParsePostmanSceneSets(t *testing.T) {
+	sets, err := parsePostmanSceneSets([]byte(testPostmanCollection))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sets))
+	scenes := sets[0].Scenes
+	assert.Equal(t, 3, len(scenes))
+	assert.Equal(t, "users", scenes[0].Name)
+	assert.Equal(t, "user", scenes[1].Name)
+	assert.Equal(t, "user / login", scenes[2].Name)
+
+	assert.Equal(t, "${{ params.baseUrl }}/ping", scenes[0].Steps[0].API.URL)
+	assert.Equal(t, []apistructs.AutoTestSceneInput{{Name: "baseUrl", Value: "https://example.com"}}, scenes[0].Inputs)
+
+	create := scenes[1].Steps[0].API
+	assert.Equal(t, "${{ params.baseUrl }}/users", create.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "dry", Value: "true"}}, create.Params)
+	assert.Equal(t, []apistructs.APIHeader{{Key: "Authorization", Value: "Bearer ${{ params.token }}"}}, create.Headers)
+	assert.Equal(t, apistructs.APIBodyTypeApplicationJSON, create.Body.Type)
+	assert.Equal(t, []apistructs.APIOutParam{
+		{Key: "status", Source: apistructs.APIOutParamSourceStatus},
+		{Key: "body_data_name", Source: apistructs.APIOutParamSourceBodyJson, Expression: ".data.name"},
+		{Key: "header_X-Request-Id", Source: apistructs.APIOutParamSourceHeader, Expression: "X-Request-Id"},
+	}, create.OutParams)
+	assert.Equal(t, []apistructs.APIAssert{
+		{Arg: "status", Operator: "=", Value: "201"},
+		{Arg: "body_data_name", Operator: "=", Value: "erda"},
+		{Arg: "header_X-Request-Id", Operator: "not_empty"},
+	}, create.Asserts)
+	assert.Equal(t, 2, len(scenes[1].Inputs))
+	assert.Equal(t, "1 test assertion(s) could not be translated", scenes[1].Description)
+
+	login := scenes[2].Steps[0].API
+	assert.Equal(t, apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, login.Body.Type)
+	assert.Equal(t, []apistructs.APIParam{{Key: "user", Value: "admin"}}, login.Body.Content)
+}
+
+const testHAR = `{
+  "log": {
+    "pages": [{"id": "page_1", "title": "https://example.com/"}],
+    "entries": [
+      {
+        "pageref": "page_1",
+        "_resourceType": "script",
+        "request": {"method": "GET", "url": "https://example.com/app.js", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "application/javascript"}}
+      },
+      {
+        "pageref": "page_1",
+        "_resourceType": "xhr",
+        "request": {
+          "method": "POST",
+          "url": "https://example.com/api/orders?debug=1",
+          "headers": [{"name": ":authority", "value": "example.com"}, {"name": "Cookie", "value": "a=b"}, {"name": "X-Token", "value": "t"}],
+          "queryString": [{"name": "debug", "value": "1"}],
+          "postData": {"mimeType": "application/json", "text": "{\"id\":1}"}
+        },
+        "response": {"status": 201, "content": {"mimeType": "application/json"}}
+      }
+    ]
+  }
+}`
+
+func TestParseHARSceneSets(t *testing.T) {
+	sets, err := parseHARSceneSets([]byte(testHAR))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sets[0].Scenes))
+	scene := sets[0].Scenes[0]
+	assert.Equal(t, "https://example.com/", scene.Name)
+	assert.Equal(t, 1, len(scene.Steps))
+
+	api := scene.Steps[0].API
+	assert.Equal(t, "POST /api/orders", api.Name)
+	assert.Equal(t, "https://example.com/api/orders", api.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "debug", Value: "1"}}, api.Params)
+	assert.Equal(t, []apistructs.APIHeader{{Key: "X-Token", Value: "t"}}, api.Headers)
+	assert.Equal(t, apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: `{"id":1}`}, api.Body)
+	assert.Equal(t, "201", api.Asserts[0].Value)
+}
+
+func TestAutoTestSceneSetExternal_SetSceneSets(t *testing.T) {
+	data := &AutoTestSpaceData{Space: &apistructs.AutoTestSpace{ID: 5}}
+	external, err := newSceneSetExternal(apistructs.TestSceneSetFileTypeHAR, []byte(testHAR), data)
+	assert.NoError(t, err)
+
+	creator := AutoTestSpaceDirector{}
+	creator.New(external)
+	assert.NoError(t, creator.ConstructSceneSet())
+
+	sets := data.SceneSets[5]
+	assert.Equal(t, 1, len(sets))
+	scenes := data.Scenes[sets[0].ID]
+	assert.Equal(t, 1, len(scenes))
+	steps := data.Steps[scenes[0].ID]
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, apistructs.PreTypeSerial, steps[0].PreType)
+	assert.Equal(t, apistructs.StepTypeAPI, steps[0].Type)
+
+	var value apistructs.AutoTestRunStep
+	assert.NoError(t, json.Unmarshal([]byte(steps[0].Value), &value))
+	assert.Equal(t, "https://example.com/api/orders", value.ApiSpec["url"])
+
+	_, err = newSceneSetExternal(apistructs.TestSceneSetFileTypeExcel, nil, data)
+	assert.Error(t, err)
+}
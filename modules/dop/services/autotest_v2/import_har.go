@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// harFile http archive format, see http://www.softwareishard.com/blog/har-12-spec/
+type harFile struct {
+	Log struct {
+		Pages []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	PageRef      string `json:"pageref"`
+	ResourceType string `json:"_resourceType"`
+	Request      struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkippedHeaders headers which are set by client automatically
+var harSkippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"content-type":      true,
+	"cookie":            true,
+	"connection":        true,
+	"accept-encoding":   true,
+	"transfer-encoding": true,
+}
+
+// parseHARSceneSets convert http archive to one scene set,
+// entries of every page are a scene, static resources are skipped
+func parseHARSceneSets(data []byte) ([]importedSceneSet, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid har file: %v", err)
+	}
+
+	var (
+		refs   []string
+		scenes = map[string]*importedScene{}
+		titles = map[string]string{}
+	)
+	for _, page := range har.Log.Pages {
+		titles[page.ID] = page.Title
+	}
+	for _, entry := range har.Log.Entries {
+		if !isHARAPIEntry(entry) {
+			continue
+		}
+		step, err := harStep(entry)
+		if err != nil {
+			return nil, err
+		}
+		scene, ok := scenes[entry.PageRef]
+		if !ok {
+			name := titles[entry.PageRef]
+			if name == "" {
+				name = entry.PageRef
+			}
+			if name == "" {
+				name = "HAR"
+			}
+			scene = &importedScene{Name: name}
+			scenes[entry.PageRef] = scene
+			refs = append(refs, entry.PageRef)
+		}
+		scene.Steps = append(scene.Steps, step)
+	}
+
+	set := importedSceneSet{Name: "HAR"}
+	for _, ref := range refs {
+		set.Scenes = append(set.Scenes, *scenes[ref])
+	}
+	return []importedSceneSet{set}, nil
+}
+
+// isHARAPIEntry check whether the entry is an api request rather than static resource
+func isHARAPIEntry(entry harEntry) bool {
+	switch strings.ToLower(entry.ResourceType) {
+	case "xhr", "fetch":
+		return true
+	case "":
+	default:
+		return false
+	}
+	mimeType := strings.ToLower(entry.Response.Content.MimeType)
+	return mimeType == "" || strings.Contains(mimeType, "json") || strings.Contains(mimeType, "xml") ||
+		strings.HasPrefix(mimeType, "text/plain")
+}
+
+func harStep(entry harEntry) (importedStep, error) {
+	req := entry.Request
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return importedStep{}, fmt.Errorf("invalid request url: %s, err: %v", req.URL, err)
+	}
+	query := req.QueryString
+	if len(query) == 0 {
+		query = harNameValues(u.Query())
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	api := apistructs.APIInfoV2{
+		Method: strings.ToUpper(req.Method),
+		URL:    u.String(),
+	}
+	api.Name = stepName("", api.Method, u.Path)
+	for _, q := range query {
+		api.Params = append(api.Params, apistructs.APIParam{Key: q.Name, Value: q.Value})
+	}
+	for _, h := range req.Headers {
+		// skip http2 pseudo headers
+		if strings.HasPrefix(h.Name, ":") || harSkippedHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: h.Name, Value: h.Value})
+	}
+
+	api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+	if post := req.PostData; post != nil {
+		mimeType := strings.ToLower(post.MimeType)
+		switch {
+		case strings.HasPrefix(mimeType, string(apistructs.APIBodyTypeApplicationXWWWFormUrlencoded)):
+			var form []apistructs.APIParam
+			params := post.Params
+			if len(params) == 0 {
+				values, _ := url.ParseQuery(post.Text)
+				params = harNameValues(values)
+			}
+			for _, p := range params {
+				form = append(form, apistructs.APIParam{Key: p.Name, Value: p.Value})
+			}
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: form}
+		case post.Text == "":
+		case isJSONMediaType(mimeType):
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: post.Text}
+		default:
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeTextPlain, Content: post.Text}
+		}
+	}
+	if entry.Response.Status > 0 {
+		statusAssert(&api, entry.Response.Status)
+	}
+	return importedStep{Name: api.Name, API: api}, nil
+}
+
+func harNameValues(values url.Values) []harNameValue {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []harNameValue
+	for _, key := range keys {
+		for _, value := range values[key] {
+			result = append(result, harNameValue{Name: key, Value: value})
+		}
+	}
+	return result
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/swagger"
+	"github.com/erda-project/erda/pkg/swagger/mock"
+)
+
+const defaultOpenAPITag = "default"
+
+var openAPIMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
+// parseOpenAPISceneSets convert OpenAPI 3 (or swagger 2) document to one scene set,
+// every tag is a scene and every operation is a step
+func parseOpenAPISceneSets(data []byte) ([]importedSceneSet, error) {
+	doc, err := swagger.LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi document: %v", err)
+	}
+
+	set := importedSceneSet{Name: "OpenAPI"}
+	if doc.Info != nil {
+		if title := strings.TrimSpace(doc.Info.Title); title != "" {
+			set.Name = title
+		}
+		set.Description = doc.Info.Description
+	}
+	basePath := openAPIBasePath(doc)
+
+	var (
+		tags   []string
+		scenes = map[string]*importedScene{}
+	)
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op := item.GetOperation(method)
+			if op == nil {
+				continue
+			}
+			tag := defaultOpenAPITag
+			if len(op.Tags) > 0 && op.Tags[0] != "" {
+				tag = op.Tags[0]
+			}
+			scene, ok := scenes[tag]
+			if !ok {
+				scene = &importedScene{Name: tag, Description: openAPITagDescription(doc, tag)}
+				scenes[tag] = scene
+				tags = append(tags, tag)
+			}
+			scene.Steps = append(scene.Steps, openAPIStep(scene, basePath, path, method, item, op))
+		}
+	}
+	for _, tag := range tags {
+		set.Scenes = append(set.Scenes, *scenes[tag])
+	}
+	return []importedSceneSet{set}, nil
+}
+
+func openAPIStep(scene *importedScene, basePath, path, method string, item *openapi3.PathItem, op *openapi3.Operation) importedStep {
+	name := op.Summary
+	if strings.TrimSpace(name) == "" {
+		name = op.OperationID
+	}
+	api := apistructs.APIInfoV2{
+		Name:   stepName(name, method, path),
+		URL:    basePath + path,
+		Method: method,
+	}
+
+	for _, param := range openAPIParameters(item.Parameters, op.Parameters) {
+		value := openAPIParamValue(param)
+		switch param.In {
+		case openapi3.ParameterInPath:
+			addSceneInput(scene, param.Name, value, param.Description)
+			api.URL = strings.Replace(api.URL, "{"+param.Name+"}", paramsRef(param.Name), -1)
+		case openapi3.ParameterInQuery:
+			if param.Required {
+				api.Params = append(api.Params, apistructs.APIParam{Key: param.Name, Value: value, Desc: param.Description})
+			}
+		case openapi3.ParameterInHeader:
+			if param.Required {
+				api.Headers = append(api.Headers, apistructs.APIHeader{Key: param.Name, Value: value, Desc: param.Description})
+			}
+		}
+	}
+
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		api.Body = openAPIRequestBody(op.RequestBody.Value.Content)
+	}
+	if status, ok := openAPISuccessStatus(op.Responses); ok {
+		statusAssert(&api, status)
+	}
+	return importedStep{Name: api.Name, API: api}
+}
+
+// openAPIParameters merge path item parameters and operation parameters,
+// operation parameters override the path item ones with the same name and location
+func openAPIParameters(lists ...openapi3.Parameters) []*openapi3.Parameter {
+	var (
+		params []*openapi3.Parameter
+		index  = map[string]int{}
+	)
+	for _, list := range lists {
+		for _, ref := range list {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			key := ref.Value.In + ":" + ref.Value.Name
+			if i, ok := index[key]; ok {
+				params[i] = ref.Value
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, ref.Value)
+		}
+	}
+	return params
+}
+
+func openAPIParamValue(param *openapi3.Parameter) string {
+	if param.Example != nil {
+		return fmt.Sprint(param.Example)
+	}
+	if param.Schema == nil || param.Schema.Value == nil {
+		return ""
+	}
+	value := mock.Generate(param.Schema.Value)
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(value)
+		return string(b)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func openAPIRequestBody(content openapi3.Content) apistructs.APIBody {
+	for mediaType, media := range content {
+		if media == nil || !isJSONMediaType(mediaType) {
+			continue
+		}
+		var value interface{}
+		switch {
+		case media.Example != nil:
+			value = media.Example
+		case media.Schema != nil && media.Schema.Value != nil:
+			value = mock.Generate(media.Schema.Value)
+		}
+		b, _ := json.MarshalIndent(value, "", "  ")
+		return apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: string(b)}
+	}
+	if media := content.Get(string(apistructs.APIBodyTypeApplicationXWWWFormUrlencoded)); media != nil {
+		var form []apistructs.APIParam
+		if media.Schema != nil && media.Schema.Value != nil {
+			props := media.Schema.Value.Properties
+			names := make([]string, 0, len(props))
+			for name := range props {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				var value string
+				if props[name] != nil && props[name].Value != nil {
+					value = fmt.Sprint(mock.Generate(props[name].Value))
+				}
+				form = append(form, apistructs.APIParam{Key: name, Value: value})
+			}
+		}
+		return apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: form}
+	}
+	return apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+}
+
+// openAPISuccessStatus returns the lowest 2xx status declared in responses
+func openAPISuccessStatus(responses openapi3.Responses) (int, bool) {
+	status := 0
+	for code := range responses {
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 200 || n > 299 {
+			continue
+		}
+		if status == 0 || n < status {
+			status = n
+		}
+	}
+	if status == 0 {
+		if _, ok := responses["2XX"]; ok {
+			return http.StatusOK, true
+		}
+		return 0, false
+	}
+	return status, true
+}
+
+// openAPIBasePath returns the path of the first server, relative urls will be
+// prefixed with the domain of the runtime config
+func openAPIBasePath(doc *openapi3.Swagger) string {
+	if len(doc.Servers) == 0 || doc.Servers[0] == nil {
+		return ""
+	}
+	u, err := url.Parse(doc.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+func openAPITagDescription(doc *openapi3.Swagger, name string) string {
+	for _, tag := range doc.Tags {
+		if tag != nil && tag.Name == name {
+			return tag.Description
+		}
+	}
+	return ""
+}
+
+func addSceneInput(scene *importedScene, name, value, desc string) {
+	for _, input := range scene.Inputs {
+		if input.Name == name {
+			return
+		}
+	}
+	scene.Inputs = append(scene.Inputs, apistructs.AutoTestSceneInput{Name: name, Value: value, Description: desc})
+}
+
+func isJSONMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// postmanCollection postman collection v2.1 format,
+// see https://schema.getpostman.com/json/collection/v2.1.0/collection.json
+type postmanCollection struct {
+	Info struct {
+		Name        string          `json:"name"`
+		Description json.RawMessage `json:"description"`
+		Schema      string          `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description json.RawMessage `json:"description"`
+	Item        []postmanItem   `json:"item"`
+	Request     *postmanRequest `json:"request"`
+	Event       []postmanEvent  `json:"event"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	URL    postmanURL        `json:"url"`
+	Body   *postmanBody      `json:"body"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     postmanStrings    `json:"host"`
+	Path     postmanStrings    `json:"path"`
+	Query    []postmanKeyValue `json:"query"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// UnmarshalJSON url may be a raw string or an object
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+	type alias postmanURL
+	return json.Unmarshal(data, (*alias)(u))
+}
+
+// postmanStrings host and path may be a string or a list of segments
+type postmanStrings []string
+
+func (s *postmanStrings) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = postmanStrings{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanKeyValue struct {
+	Key         string          `json:"key"`
+	Value       interface{}     `json:"value"`
+	Type        string          `json:"type"`
+	Disabled    bool            `json:"disabled"`
+	Description json.RawMessage `json:"description"`
+}
+
+func (kv postmanKeyValue) value() string {
+	if kv.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(kv.Value)
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec postmanStrings `json:"exec"`
+	} `json:"script"`
+}
+
+// postmanDescription description may be a string or an object with content
+func postmanDescription(data json.RawMessage) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s
+	}
+	var d struct {
+		Content string `json:"content"`
+	}
+	_ = json.Unmarshal(data, &d)
+	return d.Content
+}
+
+// parsePostmanSceneSets convert postman collection to one scene set,
+// folders are scenes, requests are steps and variables are scene inputs
+func parsePostmanSceneSets(data []byte) ([]importedSceneSet, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("invalid postman collection: %v", err)
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.1") {
+		return nil, fmt.Errorf("unsupported postman collection schema: %s, only v2.1 is supported", collection.Info.Schema)
+	}
+
+	name := strings.TrimSpace(collection.Info.Name)
+	if name == "" {
+		name = "Postman"
+	}
+	vars := map[string]string{}
+	for _, v := range collection.Variable {
+		if !v.Disabled {
+			vars[v.Key] = v.value()
+		}
+	}
+	set := importedSceneSet{Name: name, Description: postmanDescription(collection.Info.Description)}
+
+	// requests at the top level are collected into the scene named by collection
+	root := &postmanScene{scene: importedScene{Name: name}, vars: vars}
+	var folders []*postmanScene
+	var walk func(prefix string, items []postmanItem, current *postmanScene)
+	walk = func(prefix string, items []postmanItem, current *postmanScene) {
+		for _, item := range items {
+			if item.Request != nil {
+				current.addRequest(item)
+				continue
+			}
+			folderName := item.Name
+			if prefix != "" {
+				folderName = prefix + " / " + item.Name
+			}
+			folder := &postmanScene{
+				scene: importedScene{Name: folderName, Description: postmanDescription(item.Description)},
+				vars:  vars,
+			}
+			folders = append(folders, folder)
+			walk(folderName, item.Item, folder)
+		}
+	}
+	walk("", collection.Item, root)
+
+	for _, s := range append([]*postmanScene{root}, folders...) {
+		if len(s.scene.Steps) == 0 {
+			continue
+		}
+		if s.untranslated > 0 {
+			note := fmt.Sprintf("%d test assertion(s) could not be translated", s.untranslated)
+			s.scene.Description = strings.TrimSpace(s.scene.Description + "\n" + note)
+		}
+		set.Scenes = append(set.Scenes, s.scene)
+	}
+	return []importedSceneSet{set}, nil
+}
+
+type postmanScene struct {
+	scene        importedScene
+	vars         map[string]string
+	untranslated int
+}
+
+var postmanVarRegexp = regexp.MustCompile(`{{\s*([\w.\-]+)\s*}}`)
+
+// render replace {{name}} with scene input reference and add the input to scene
+func (p *postmanScene) render(s string) string {
+	return postmanVarRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVarRegexp.FindStringSubmatch(m)[1]
+		addSceneInput(&p.scene, name, p.vars[name], "")
+		return paramsRef(name)
+	})
+}
+
+func (p *postmanScene) addRequest(item postmanItem) {
+	req := item.Request
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	api := apistructs.APIInfoV2{Method: method}
+
+	// url without query, query params are taken from the query list if provided
+	rawURL := req.URL.Raw
+	if rawURL == "" {
+		rawURL = strings.Join(req.URL.Host, ".")
+		if len(req.URL.Path) > 0 {
+			rawURL += "/" + strings.Join(req.URL.Path, "/")
+		}
+	}
+	query := req.URL.Query
+	if i := strings.Index(rawURL, "?"); i >= 0 {
+		if len(query) == 0 {
+			for _, kv := range strings.Split(rawURL[i+1:], "&") {
+				if kv == "" {
+					continue
+				}
+				pair := strings.SplitN(kv, "=", 2)
+				q := postmanKeyValue{Key: pair[0]}
+				if len(pair) == 2 {
+					q.Value = pair[1]
+				}
+				query = append(query, q)
+			}
+		}
+		rawURL = rawURL[:i]
+	}
+	for _, v := range req.URL.Variable {
+		rawURL = strings.Replace(rawURL, "/:"+v.Key, "/"+v.value(), -1)
+	}
+	api.URL = p.render(rawURL)
+	api.Name = stepName(item.Name, method, api.URL)
+	for _, q := range query {
+		if !q.Disabled {
+			api.Params = append(api.Params, apistructs.APIParam{Key: q.Key, Value: p.render(q.value()), Desc: postmanDescription(q.Description)})
+		}
+	}
+	for _, h := range req.Header {
+		if h.Disabled || strings.EqualFold(h.Key, "Content-Type") {
+			continue
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: h.Key, Value: p.render(h.value()), Desc: postmanDescription(h.Description)})
+	}
+	api.Body = p.body(req)
+
+	for _, event := range item.Event {
+		if event.Listen == "test" {
+			p.translateTests(&api, event.Script.Exec)
+		}
+	}
+	p.scene.Steps = append(p.scene.Steps, importedStep{Name: api.Name, API: api})
+}
+
+func (p *postmanScene) body(req *postmanRequest) apistructs.APIBody {
+	if req.Body == nil {
+		return apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+	}
+	switch req.Body.Mode {
+	case "raw":
+		if strings.TrimSpace(req.Body.Raw) == "" {
+			break
+		}
+		bodyType := apistructs.APIBodyTypeTextPlain
+		if req.Body.Options.Raw.Language == "json" || json.Valid([]byte(req.Body.Raw)) {
+			bodyType = apistructs.APIBodyTypeApplicationJSON
+		} else {
+			for _, h := range req.Header {
+				if strings.EqualFold(h.Key, "Content-Type") && isJSONMediaType(h.value()) {
+					bodyType = apistructs.APIBodyTypeApplicationJSON
+				}
+			}
+		}
+		return apistructs.APIBody{Type: bodyType, Content: p.render(req.Body.Raw)}
+	case "urlencoded", "formdata":
+		fields := req.Body.URLEncoded
+		if req.Body.Mode == "formdata" {
+			fields = req.Body.FormData
+		}
+		var form []apistructs.APIParam
+		for _, f := range fields {
+			// files can not be uploaded by form params
+			if f.Disabled || f.Type == "file" {
+				continue
+			}
+			form = append(form, apistructs.APIParam{Key: f.Key, Value: p.render(f.value()), Desc: postmanDescription(f.Description)})
+		}
+		return apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: form}
+	}
+	return apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+}
+
+var (
+	postmanJSONVarRegexp     = regexp.MustCompile(`(?:var|let|const)\s+(\w+)\s*=\s*pm\.response\.json\(\)`)
+	postmanStatusRegexp      = regexp.MustCompile(`pm\.response\.to\.(?:have|be)\.status\((\d{3})\)`)
+	postmanCodeRegexp        = regexp.MustCompile(`pm\.expect\(pm\.response\.code\)\.to\.(?:be\.)?(?:eql|equal|eq)\((\d{3})\)`)
+	postmanJSONExpectRegexp  = regexp.MustCompile(`pm\.expect\((\w+)((?:\.\w+|\[\d+\])*)\)\.to\.(?:be\.)?(?:eql|equal|eq)\((.+?)\)\s*;?\s*$`)
+	postmanTextIncludeRegexp = regexp.MustCompile(`pm\.expect\(pm\.response\.text\(\)\)\.to\.(?:include|contain)\((.+?)\)\s*;?\s*$`)
+	postmanHeaderRegexp      = regexp.MustCompile(`pm\.response\.to\.have\.header\(\s*["']([^"']+)["']\s*\)`)
+	postmanAssertRegexp      = regexp.MustCompile(`pm\.expect\(|pm\.response\.to\.`)
+)
+
+// translateTests translate postman test scripts to out params and asserts,
+// only the commonly used statements are supported, others are counted as untranslated
+func (p *postmanScene) translateTests(api *apistructs.APIInfoV2, exec []string) {
+	jsonVars := map[string]bool{}
+	for _, line := range exec {
+		line = strings.TrimSpace(line)
+		if m := postmanJSONVarRegexp.FindStringSubmatch(line); m != nil {
+			jsonVars[m[1]] = true
+			continue
+		}
+		if !postmanAssertRegexp.MatchString(line) {
+			continue
+		}
+		if m := postmanStatusRegexp.FindStringSubmatch(line); m != nil {
+			status, _ := strconv.Atoi(m[1])
+			statusAssert(api, status)
+			continue
+		}
+		if m := postmanCodeRegexp.FindStringSubmatch(line); m != nil {
+			status, _ := strconv.Atoi(m[1])
+			statusAssert(api, status)
+			continue
+		}
+		if m := postmanHeaderRegexp.FindStringSubmatch(line); m != nil {
+			key := "header_" + m[1]
+			if !hasOutParam(api, key) {
+				api.OutParams = append(api.OutParams, apistructs.APIOutParam{Key: key, Source: apistructs.APIOutParamSourceHeader, Expression: m[1]})
+			}
+			api.Asserts = append(api.Asserts, apistructs.APIAssert{Arg: key, Operator: "not_empty"})
+			continue
+		}
+		if m := postmanTextIncludeRegexp.FindStringSubmatch(line); m != nil {
+			const key = "body"
+			if !hasOutParam(api, key) {
+				api.OutParams = append(api.OutParams, apistructs.APIOutParam{Key: key, Source: apistructs.APIOutParamSourceBodyJson, Expression: "."})
+			}
+			api.Asserts = append(api.Asserts, apistructs.APIAssert{Arg: key, Operator: "contains", Value: postmanLiteral(m[1])})
+			continue
+		}
+		if m := postmanJSONExpectRegexp.FindStringSubmatch(line); m != nil && jsonVars[m[1]] {
+			expr := m[2]
+			if expr == "" {
+				expr = "."
+			}
+			key := "body" + strings.NewReplacer(".", "_", "[", "_", "]", "").Replace(m[2])
+			if !hasOutParam(api, key) {
+				api.OutParams = append(api.OutParams, apistructs.APIOutParam{Key: key, Source: apistructs.APIOutParamSourceBodyJson, Expression: expr})
+			}
+			api.Asserts = append(api.Asserts, apistructs.APIAssert{Arg: key, Operator: "=", Value: postmanLiteral(m[3])})
+			continue
+		}
+		p.untranslated++
+	}
+}
+
+// postmanLiteral unquote javascript string literal, other literals are kept as they are
+func postmanLiteral(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		if (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
@@ -600,20 +600,35 @@ func (svc *Service) ExecuteDiceAutotestScene(req apistructs.AutotestExecuteScene
 		return nil, err
 	}
 
-	yml, err := svc.SceneToYml(scene.ID, apistructs.SnippetConfig{})
+	datasets, err := svc.sceneDatasets([]apistructs.AutoTestScene{*scene})
 	if err != nil {
 		return nil, err
 	}
 
-	var params []apistructs.PipelineRunParam
-	for _, input := range sceneInputs {
-		// replace mock temp before create pipeline
-		// and so steps can use the same mock temp
-		replacedTemp := expression.ReplaceRandomParams(input.Temp)
-		params = append(params, apistructs.PipelineRunParam{
-			Name:  input.Name,
-			Value: replacedTemp,
-		})
+	var (
+		yml    string
+		params []apistructs.PipelineRunParam
+	)
+	if dataset := datasets[scene.ID]; dataset != nil && len(dataset.Rows) > 0 {
+		// data-driven scene runs as snippets, one per dataset row
+		yml, err = svc.dataDrivenSceneYml(scene, sceneInputs, dataset)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		yml, err = svc.SceneToYml(scene.ID, apistructs.SnippetConfig{})
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range sceneInputs {
+			// replace mock temp before create pipeline
+			// and so steps can use the same mock temp
+			replacedTemp := expression.ReplaceRandomParams(input.Temp)
+			params = append(params, apistructs.PipelineRunParam{
+				Name:  input.Name,
+				Value: replacedTemp,
+			})
+		}
 	}
 
 	space, err := svc.GetSpace(scene.SpaceID)
@@ -667,7 +682,7 @@ func (svc *Service) ExecuteDiceAutotestSceneStep(req apistructs.AutotestExecuteS
 	if err != nil {
 		return nil, err
 	}
-	if step.Type != apistructs.StepTypeAPI {
+	if !step.Type.IsAPIStepType() {
 		return nil, fmt.Errorf("only supports api type execution")
 	}
 	if step.Value == "" {
@@ -768,6 +783,9 @@ func (svc *Service) ExecuteDiceAutotestSceneStep(req apistructs.AutotestExecuteS
 		Body:      apiInfoV2.Body,
 		OutParams: apiInfoV2.OutParams,
 		Asserts:   [][]apistructs.APIAssert{apiInfoV2.Asserts},
+		Protocol:  step.Type.APIProtocol(),
+		GRPC:      apiInfoV2.GRPC,
+		WebSocket: apiInfoV2.WebSocket,
 	}, apitestsv2.WithNetportalConfigs(customhttp.GetNetPortalUrl(clusterName)))
 
 	var respData apistructs.AutotestExecuteSceneStepRespData
@@ -999,7 +1017,7 @@ func StepToAction(step apistructs.AutoTestSceneStep, req apistructs.SnippetConfi
 				apistructs.LabelTestPlanID:       req.Labels[apistructs.LabelTestPlanID],
 			},
 		}
-	case apistructs.StepTypeAPI:
+	case apistructs.StepTypeAPI, apistructs.StepTypeGRPC, apistructs.StepTypeWebSocket:
 		var value apistructs.AutoTestRunStep
 		err := json.Unmarshal([]byte(step.Value), &value)
 		if err != nil {
@@ -1009,6 +1027,16 @@ func StepToAction(step apistructs.AutoTestSceneStep, req apistructs.SnippetConfi
 		action.Type = "api-test"
 		action.Version = "2.0"
 		action.Params = value.ApiSpec
+		// grpc and websocket steps are executed by api-test action as well, distinguished by protocol
+		if protocol := step.Type.APIProtocol(); protocol != apistructs.APIProtocolHTTP {
+			if action.Params == nil {
+				action.Params = make(map[string]interface{})
+			}
+			action.Params["protocol"] = protocol.String()
+			if step.Type == apistructs.StepTypeWebSocket {
+				action.Params["method"] = http.MethodGet
+			}
+		}
 		if value.Loop != nil && value.Loop.Strategy != nil && value.Loop.Strategy.MaxTimes > 0 {
 			action.Loop = value.Loop
 		}
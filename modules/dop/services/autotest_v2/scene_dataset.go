@@ -0,0 +1,431 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dao"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/pkg/excel"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+// maxSceneDatasetRows limit the rows of dataset, every row is a snippet pipeline
+const maxSceneDatasetRows = 500
+
+var sceneDatasetColumnRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// GetSceneDataset returns nil if no dataset attached
+func (svc *Service) GetSceneDataset(scopeType apistructs.AutoTestSceneDatasetScope, scopeID uint64) (*apistructs.AutoTestSceneDataset, error) {
+	if !scopeType.Valid() {
+		return nil, apierrors.ErrGetAutoTestSceneDataset.InvalidParameter("scopeType")
+	}
+	dataset, err := svc.db.GetAutoTestSceneDataset(string(scopeType), scopeID)
+	if err != nil {
+		return nil, apierrors.ErrGetAutoTestSceneDataset.InternalError(err)
+	}
+	if dataset == nil {
+		return nil, nil
+	}
+	return dataset.Convert2DTO(), nil
+}
+
+// SaveSceneDataset create or replace the dataset of scene or scene set
+func (svc *Service) SaveSceneDataset(req apistructs.AutoTestSceneDatasetSaveRequest) (*apistructs.AutoTestSceneDataset, error) {
+	if !req.ScopeType.Valid() {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InvalidParameter("scopeType")
+	}
+	if err := validateSceneDataset(req.Columns, req.Rows); err != nil {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InvalidParameter(err)
+	}
+	spaceID, err := svc.sceneDatasetSpaceID(req.ScopeType, req.ScopeID)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := json.Marshal(req.Columns)
+	if err != nil {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InternalError(err)
+	}
+	rows, err := json.Marshal(req.Rows)
+	if err != nil {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InternalError(err)
+	}
+
+	dataset, err := svc.db.GetAutoTestSceneDataset(string(req.ScopeType), req.ScopeID)
+	if err != nil {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InternalError(err)
+	}
+	if dataset == nil {
+		org, err := svc.sceneDatasetOrg(spaceID)
+		if err != nil {
+			return nil, apierrors.ErrSaveAutoTestSceneDataset.InternalError(err)
+		}
+		dataset = &dao.AutoTestSceneDataset{
+			OrgID:     org.ID,
+			OrgName:   org.Name,
+			ScopeType: string(req.ScopeType),
+			ScopeID:   req.ScopeID,
+			CreatorID: req.UserID,
+		}
+	}
+	dataset.Name = req.Name
+	dataset.SpaceID = spaceID
+	dataset.Columns = string(columns)
+	dataset.DataRows = string(rows)
+	dataset.IsParallel = req.Parallel
+	dataset.UpdaterID = req.UserID
+	if dataset.ID == "" {
+		err = svc.db.CreateAutoTestSceneDataset(dataset)
+	} else {
+		err = svc.db.UpdateAutoTestSceneDataset(dataset)
+	}
+	if err != nil {
+		return nil, apierrors.ErrSaveAutoTestSceneDataset.InternalError(err)
+	}
+	return dataset.Convert2DTO(), nil
+}
+
+// ImportSceneDataset import dataset from uploaded csv or excel file
+func (svc *Service) ImportSceneDataset(req apistructs.AutoTestSceneDatasetImportRequest, r *http.Request) (*apistructs.AutoTestSceneDataset, error) {
+	f, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		return nil, apierrors.ErrImportAutoTestSceneDataset.InvalidParameter(err)
+	}
+	defer f.Close()
+
+	columns, rows, err := ParseSceneDataset(fileHeader.Filename, f)
+	if err != nil {
+		return nil, apierrors.ErrImportAutoTestSceneDataset.InvalidParameter(err)
+	}
+	name := req.Name
+	if name == "" {
+		name = strings.TrimSuffix(fileHeader.Filename, filepath.Ext(fileHeader.Filename))
+	}
+	return svc.SaveSceneDataset(apistructs.AutoTestSceneDatasetSaveRequest{
+		ScopeType:    req.ScopeType,
+		ScopeID:      req.ScopeID,
+		Name:         name,
+		Columns:      columns,
+		Rows:         rows,
+		Parallel:     req.Parallel,
+		IdentityInfo: req.IdentityInfo,
+	})
+}
+
+// DeleteSceneDataset detach the dataset, the scene runs once with its inputs again
+func (svc *Service) DeleteSceneDataset(scopeType apistructs.AutoTestSceneDatasetScope, scopeID uint64) error {
+	if !scopeType.Valid() {
+		return apierrors.ErrDeleteAutoTestSceneDataset.InvalidParameter("scopeType")
+	}
+	if err := svc.db.DeleteAutoTestSceneDataset(string(scopeType), scopeID); err != nil {
+		return apierrors.ErrDeleteAutoTestSceneDataset.InternalError(err)
+	}
+	return nil
+}
+
+// SceneDatasetSpace returns the space of scene or scene set which dataset attached to
+func (svc *Service) SceneDatasetSpace(scopeType apistructs.AutoTestSceneDatasetScope, scopeID uint64) (*apistructs.AutoTestSpace, error) {
+	spaceID, err := svc.sceneDatasetSpaceID(scopeType, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetSpace(spaceID)
+}
+
+func (svc *Service) sceneDatasetSpaceID(scopeType apistructs.AutoTestSceneDatasetScope, scopeID uint64) (uint64, error) {
+	switch scopeType {
+	case apistructs.AutoTestSceneDatasetScopeScene:
+		scene, err := svc.GetAutotestScene(apistructs.AutotestSceneRequest{SceneID: scopeID})
+		if err != nil {
+			return 0, err
+		}
+		return scene.SpaceID, nil
+	case apistructs.AutoTestSceneDatasetScopeSceneSet:
+		set, err := svc.GetSceneSet(scopeID)
+		if err != nil {
+			return 0, err
+		}
+		return set.SpaceID, nil
+	default:
+		return 0, fmt.Errorf("invalid dataset scope type: %s", scopeType)
+	}
+}
+
+// sceneDatasetOrg returns the org of the space, the dataset is recorded with it
+func (svc *Service) sceneDatasetOrg(spaceID uint64) (*apistructs.OrgDTO, error) {
+	space, err := svc.GetSpace(spaceID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := svc.bdl.GetProject(uint64(space.ProjectID))
+	if err != nil {
+		return nil, err
+	}
+	return svc.bdl.GetOrg(project.OrgID)
+}
+
+// ParseSceneDataset parse csv or excel file to dataset, the first row is the header and
+// the first sheet is used for excel
+func ParseSceneDataset(fileName string, r io.Reader) ([]string, [][]string, error) {
+	var table [][]string
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, err
+		}
+		table = records
+	case ".xlsx", ".xls":
+		sheets, err := excel.Decode(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(sheets) == 0 {
+			return nil, nil, fmt.Errorf("no sheet found")
+		}
+		table = sheets[0]
+	default:
+		return nil, nil, fmt.Errorf("unsupported file type: %s, only csv and excel are supported", fileName)
+	}
+
+	// skip blank rows
+	var nonBlank [][]string
+	for _, row := range table {
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				nonBlank = append(nonBlank, row)
+				break
+			}
+		}
+	}
+	if len(nonBlank) == 0 {
+		return nil, nil, fmt.Errorf("empty dataset")
+	}
+
+	var columns []string
+	for _, cell := range nonBlank[0] {
+		columns = append(columns, strings.TrimSpace(cell))
+	}
+	// trim trailing empty header cells of excel
+	for len(columns) > 0 && columns[len(columns)-1] == "" {
+		columns = columns[:len(columns)-1]
+	}
+	rows := make([][]string, 0, len(nonBlank)-1)
+	for _, record := range nonBlank[1:] {
+		row := make([]string, len(columns))
+		copy(row, record)
+		rows = append(rows, row)
+	}
+	return columns, rows, validateSceneDataset(columns, rows)
+}
+
+func validateSceneDataset(columns []string, rows [][]string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("dataset columns is empty")
+	}
+	exists := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if !sceneDatasetColumnRegexp.MatchString(column) {
+			return fmt.Errorf("invalid column name: %q, only letters, digits, - and _ are allowed", column)
+		}
+		if exists[column] {
+			return fmt.Errorf("duplicate column name: %s", column)
+		}
+		exists[column] = true
+	}
+	if len(rows) > maxSceneDatasetRows {
+		return fmt.Errorf("too many rows: %d, at most %d rows are allowed", len(rows), maxSceneDatasetRows)
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values, but %d columns defined", i+1, len(row), len(columns))
+		}
+	}
+	return nil
+}
+
+// sceneDatasets returns the dataset which drives every scene,
+// the dataset of scene takes precedence over the one of its scene set
+func (svc *Service) sceneDatasets(scenes []apistructs.AutoTestScene) (map[uint64]*apistructs.AutoTestSceneDataset, error) {
+	var sceneIDs, setIDs []uint64
+	for _, scene := range scenes {
+		sceneIDs = append(sceneIDs, scene.ID)
+		setIDs = append(setIDs, scene.SetID)
+	}
+	sceneDatasets, err := svc.db.ListAutoTestSceneDatasets(string(apistructs.AutoTestSceneDatasetScopeScene), sceneIDs)
+	if err != nil {
+		return nil, err
+	}
+	setDatasets, err := svc.db.ListAutoTestSceneDatasets(string(apistructs.AutoTestSceneDatasetScopeSceneSet), setIDs)
+	if err != nil {
+		return nil, err
+	}
+	bySet := make(map[uint64]*apistructs.AutoTestSceneDataset, len(setDatasets))
+	for i := range setDatasets {
+		bySet[setDatasets[i].ScopeID] = setDatasets[i].Convert2DTO()
+	}
+	byScene := make(map[uint64]*apistructs.AutoTestSceneDataset, len(sceneDatasets))
+	for i := range sceneDatasets {
+		byScene[sceneDatasets[i].ScopeID] = sceneDatasets[i].Convert2DTO()
+	}
+
+	result := make(map[uint64]*apistructs.AutoTestSceneDataset)
+	for _, scene := range scenes {
+		if dataset, ok := byScene[scene.ID]; ok {
+			result[scene.ID] = dataset
+		} else if dataset, ok := bySet[scene.SetID]; ok {
+			result[scene.ID] = dataset
+		}
+	}
+	return result, nil
+}
+
+// sceneStages collects the snippet actions of a scene group, every element is a stage.
+// Actions of data-driven scenes are expanded to one action per dataset row, rows run in the
+// same stage when the dataset is parallel, otherwise row n runs in the nth stage.
+type sceneStages [][]map[pipelineyml.ActionType]*pipelineyml.Action
+
+func (s *sceneStages) put(index int, action map[pipelineyml.ActionType]*pipelineyml.Action) {
+	for len(*s) <= index {
+		*s = append(*s, nil)
+	}
+	(*s)[index] = append((*s)[index], action)
+}
+
+// add put the snippet action of scene, the last row keeps the alias of scene
+// so that the outputs of scene refer to it
+func (s *sceneStages) add(action *pipelineyml.Action, scene *apistructs.AutoTestScene, dataset *apistructs.AutoTestSceneDataset) error {
+	if dataset == nil || len(dataset.Rows) == 0 {
+		s.put(0, map[pipelineyml.ActionType]*pipelineyml.Action{pipelineyml.Snippet: action})
+		return nil
+	}
+	for i := range dataset.Rows {
+		rowAction := *action
+		if i < len(dataset.Rows)-1 {
+			rowAction.Alias = pipelineyml.ActionAlias(fmt.Sprintf("%s-%d", action.Alias, i+1))
+		}
+
+		// only the columns declared as scene inputs are bound
+		rowAction.Params = make(map[string]interface{}, len(action.Params))
+		for k, v := range action.Params {
+			rowAction.Params[k] = v
+		}
+		for column, value := range dataset.RowParams(i) {
+			if _, ok := rowAction.Params[column]; ok {
+				rowAction.Params[column] = expression.ReplaceRandomParams(value)
+			}
+		}
+
+		rowScene := *scene
+		rowScene.Name = fmt.Sprintf("%s %s", scene.Name, dataset.RowName(i))
+		sceneJson, err := json.Marshal(rowScene)
+		if err != nil {
+			return err
+		}
+		rowAction.Labels = make(map[string]string, len(action.Labels)+1)
+		for k, v := range action.Labels {
+			rowAction.Labels[k] = v
+		}
+		rowAction.Labels[apistructs.AutotestScene] = base64.StdEncoding.EncodeToString(sceneJson)
+		rowAction.Labels[apistructs.LabelSceneDataRow] = strconv.Itoa(i + 1)
+
+		if action.SnippetConfig != nil {
+			snippetConfig := *action.SnippetConfig
+			snippetConfig.Labels = make(map[string]string, len(action.SnippetConfig.Labels)+1)
+			for k, v := range action.SnippetConfig.Labels {
+				snippetConfig.Labels[k] = v
+			}
+			snippetConfig.Labels[apistructs.LabelSceneDataRow] = strconv.Itoa(i + 1)
+			rowAction.SnippetConfig = &snippetConfig
+		}
+
+		index := i
+		if dataset.Parallel {
+			index = 0
+		}
+		s.put(index, map[pipelineyml.ActionType]*pipelineyml.Action{pipelineyml.Snippet: &rowAction})
+	}
+	return nil
+}
+
+func (s sceneStages) stages() []*pipelineyml.Stage {
+	stages := make([]*pipelineyml.Stage, 0, len(s))
+	for _, actions := range s {
+		if len(actions) > 0 {
+			stages = append(stages, &pipelineyml.Stage{Actions: actions})
+		}
+	}
+	return stages
+}
+
+// dataDrivenSceneYml generate the pipeline which runs the scene once per dataset row,
+// the current values of inputs are used for the columns not in dataset
+func (svc *Service) dataDrivenSceneYml(scene *apistructs.AutoTestScene, inputs []apistructs.AutoTestSceneInput, dataset *apistructs.AutoTestSceneDataset) (string, error) {
+	params := make(map[string]interface{}, len(inputs))
+	for _, input := range inputs {
+		params[input.Name] = expression.ReplaceRandomParams(input.Temp)
+	}
+	sceneJson, err := json.Marshal(scene)
+	if err != nil {
+		return "", err
+	}
+
+	var stages sceneStages
+	err = stages.add(&pipelineyml.Action{
+		Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(scene.ID))),
+		Type:   pipelineyml.Snippet,
+		Params: params,
+		Labels: map[string]string{
+			apistructs.AutotestType:  apistructs.AutotestScene,
+			apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
+		},
+		If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
+		SnippetConfig: &pipelineyml.SnippetConfig{
+			Name:   strconv.Itoa(int(scene.ID)),
+			Source: apistructs.PipelineSourceAutoTest.String(),
+			Labels: map[string]string{
+				apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
+				apistructs.LabelSceneID:          strconv.Itoa(int(scene.ID)),
+				apistructs.LabelSpaceID:          strconv.Itoa(int(scene.SpaceID)),
+			},
+		},
+	}, scene, dataset)
+	if err != nil {
+		return "", err
+	}
+
+	var spec pipelineyml.Spec
+	spec.Version = "1.1"
+	spec.Stages = stages.stages()
+	yml, err := pipelineyml.GenerateYml(&spec)
+	if err != nil {
+		return "", err
+	}
+	return string(yml), nil
+}
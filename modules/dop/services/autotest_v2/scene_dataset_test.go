@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+func TestParseSceneDataset(t *testing.T) {
+	columns, rows, err := ParseSceneDataset("users.csv", strings.NewReader("name,age\nerda,3\n,\ndice,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "age"}, columns)
+	assert.Equal(t, [][]string{{"erda", "3"}, {"dice", ""}}, rows)
+
+	_, _, err = ParseSceneDataset("users.csv", strings.NewReader("user name,age\nerda,3\n"))
+	assert.Error(t, err)
+
+	_, _, err = ParseSceneDataset("users.csv", strings.NewReader("name,name\nerda,3\n"))
+	assert.Error(t, err)
+
+	_, _, err = ParseSceneDataset("users.json", strings.NewReader("[]"))
+	assert.Error(t, err)
+}
+
+func TestValidateSceneDataset(t *testing.T) {
+	assert.NoError(t, validateSceneDataset([]string{"a", "b"}, [][]string{{"1", "2"}}))
+	assert.Error(t, validateSceneDataset(nil, nil))
+	assert.Error(t, validateSceneDataset([]string{"a", "b"}, [][]string{{"1"}}))
+	assert.Error(t, validateSceneDataset([]string{"a"}, make([][]string, maxSceneDatasetRows+1)))
+}
+
+func newTestSceneAction() *pipelineyml.Action {
+	return &pipelineyml.Action{
+		Alias:  "1",
+		Type:   pipelineyml.Snippet,
+		Params: map[string]interface{}{"user": "default", "token": "t"},
+		Labels: map[string]string{apistructs.AutotestType: apistructs.AutotestScene},
+		SnippetConfig: &pipelineyml.SnippetConfig{
+			Name:   "1",
+			Labels: map[string]string{apistructs.LabelSceneID: "1"},
+		},
+	}
+}
+
+func TestSceneStages(t *testing.T) {
+	scene := &apistructs.AutoTestScene{Name: "login"}
+	scene.ID = 1
+	dataset := &apistructs.AutoTestSceneDataset{
+		Columns: []string{"user", "unused"},
+		Rows:    [][]string{{"erda", "x"}, {"dice", "y"}},
+	}
+
+	// serial rows run in separate stages
+	var serial sceneStages
+	serial.put(0, map[pipelineyml.ActionType]*pipelineyml.Action{pipelineyml.Snippet: {Alias: "2"}})
+	assert.NoError(t, serial.add(newTestSceneAction(), scene, dataset))
+	stages := serial.stages()
+	assert.Equal(t, 2, len(stages))
+	assert.Equal(t, 2, len(stages[0].Actions))
+	assert.Equal(t, 1, len(stages[1].Actions))
+
+	first := stages[0].Actions[1][pipelineyml.Snippet]
+	assert.Equal(t, pipelineyml.ActionAlias("1-1"), first.Alias)
+	assert.Equal(t, map[string]interface{}{"user": "erda", "token": "t"}, first.Params)
+	assert.Equal(t, "1", first.Labels[apistructs.LabelSceneDataRow])
+	assert.Equal(t, "1", first.SnippetConfig.Labels[apistructs.LabelSceneDataRow])
+	b, err := base64.StdEncoding.DecodeString(first.Labels[apistructs.AutotestScene])
+	assert.NoError(t, err)
+	var rowScene apistructs.AutoTestScene
+	assert.NoError(t, json.Unmarshal(b, &rowScene))
+	assert.Equal(t, "login #1", rowScene.Name)
+
+	// the last row keeps the scene alias
+	last := stages[1].Actions[0][pipelineyml.Snippet]
+	assert.Equal(t, pipelineyml.ActionAlias("1"), last.Alias)
+	assert.Equal(t, "dice", last.Params["user"])
+
+	// parallel rows run in the same stage
+	dataset.Parallel = true
+	var parallel sceneStages
+	assert.NoError(t, parallel.add(newTestSceneAction(), scene, dataset))
+	stages = parallel.stages()
+	assert.Equal(t, 1, len(stages))
+	assert.Equal(t, 2, len(stages[0].Actions))
+
+	// scene without dataset runs once
+	var single sceneStages
+	action := newTestSceneAction()
+	assert.NoError(t, single.add(action, scene, nil))
+	assert.Equal(t, action, single.stages()[0].Actions[0][pipelineyml.Snippet])
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+// loadTestTimeoutBufferSec the extra time given to load test action beyond its duration
+const loadTestTimeoutBufferSec = 300
+
+// step outputs are kept in case params by virtual user, so ${{ outputs.<stepID>.<key> }} is rendered as {{key}}
+var loadTestOutputRefRegex = regexp.MustCompile(`\$\{\{\s*` + expression.Outputs + `\.[^.\s}]+\.([^\s}]+)\s*\}\}`)
+
+// ExecuteDiceAutotestSceneLoadTest run api steps of the scene as load test
+func (svc *Service) ExecuteDiceAutotestSceneLoadTest(req apistructs.AutotestLoadTestSceneRequest) (*apistructs.PipelineDTO, error) {
+	if err := req.Config.Validate(); err != nil {
+		return nil, err
+	}
+	scene, err := svc.GetAutotestScene(apistructs.AutotestSceneRequest{SceneID: req.SceneID})
+	if err != nil {
+		return nil, err
+	}
+	sceneInputs, err := svc.ListAutoTestSceneInput(scene.ID)
+	if err != nil {
+		return nil, err
+	}
+	sceneSteps, err := svc.ListAutoTestSceneStep(scene.ID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := sceneLoadTestSteps(sceneSteps, sceneInputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no api step in scene")
+	}
+
+	yml, err := sceneLoadTestYml(scene.ID, steps, req.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	space, err := svc.GetSpace(scene.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := svc.bdl.GetProject(uint64(space.ProjectID))
+	if err != nil {
+		return nil, err
+	}
+	org, err := svc.bdl.GetOrg(project.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqPipeline = apistructs.PipelineCreateRequestV2{
+		PipelineYmlName: fmt.Sprintf("%d-%s", scene.ID, apistructs.ActionTypeLoadTest),
+		PipelineSource:  apistructs.PipelineSourceAutoTest,
+		AutoRun:         true,
+		ForceRun:        true,
+		ClusterName:     req.ClusterName,
+		PipelineYml:     yml,
+		Labels:          req.Labels,
+		IdentityInfo:    req.IdentityInfo,
+		NormalLabels:    map[string]string{apistructs.LabelOrgName: org.Name, apistructs.LabelOrgID: strconv.FormatUint(org.ID, 10)},
+	}
+	if req.ConfigManageNamespaces != "" {
+		reqPipeline.ConfigManageNamespaces = append(reqPipeline.ConfigManageNamespaces, req.ConfigManageNamespaces)
+	}
+	if reqPipeline.ClusterName == "" {
+		testClusterName, err := svc.GetTestClusterNameBySpaceID(scene.SpaceID)
+		if err != nil {
+			return nil, err
+		}
+		reqPipeline.ClusterName = testClusterName
+	}
+
+	return svc.bdl.CreatePipeline(&reqPipeline)
+}
+
+// sceneLoadTestSteps flatten the enabled api steps of the scene in execution order,
+// scene inputs are rendered at once and outputs of former steps are rendered by virtual user.
+func sceneLoadTestSteps(sceneSteps []apistructs.AutoTestSceneStep, sceneInputs []apistructs.AutoTestSceneInput) ([]apistructs.AutoTestLoadTestStep, error) {
+	var steps []apistructs.AutoTestLoadTestStep
+	for _, stage := range StepToStages(sceneSteps) {
+		for _, step := range stage {
+			if !step.Type.IsAPIStepType() || step.IsDisabled || step.Value == "" {
+				continue
+			}
+			var value apistructs.AutoTestRunStep
+			if err := json.Unmarshal([]byte(step.Value), &value); err != nil {
+				return nil, err
+			}
+			specJson, err := json.Marshal(value.ApiSpec)
+			if err != nil {
+				return nil, err
+			}
+
+			apiSpecStr := string(specJson)
+			for _, param := range sceneInputs {
+				temp := expression.ReplaceRandomParams(convertJsonParam(param.Temp))
+				apiSpecStr = strings.ReplaceAll(apiSpecStr, expression.LeftPlaceholder+" "+expression.Params+"."+param.Name+" "+expression.RightPlaceholder, temp)
+				apiSpecStr = strings.ReplaceAll(apiSpecStr, expression.OldLeftPlaceholder+expression.Params+"."+param.Name+expression.OldRightPlaceholder, temp)
+			}
+			apiSpecStr = loadTestOutputRefRegex.ReplaceAllString(apiSpecStr, "{{$1}}")
+
+			var apiSpec apistructs.APIInfoV2
+			if err := json.Unmarshal([]byte(apiSpecStr), &apiSpec); err != nil {
+				return nil, err
+			}
+			apiSpec.Protocol = step.Type.APIProtocol()
+			steps = append(steps, apistructs.AutoTestLoadTestStep{
+				ID:      step.ID,
+				Name:    step.Name,
+				APISpec: apiSpec,
+			})
+		}
+	}
+	return steps, nil
+}
+
+// sceneLoadTestYml generate pipeline yml with a single load-test action
+func sceneLoadTestYml(sceneID uint64, steps []apistructs.AutoTestLoadTestStep, config apistructs.AutoTestLoadTestConfig) (string, error) {
+	stepsParam, err := toActionParam(steps)
+	if err != nil {
+		return "", err
+	}
+	configParam, err := toActionParam(config)
+	if err != nil {
+		return "", err
+	}
+
+	action := &pipelineyml.Action{
+		Type:    apistructs.ActionTypeLoadTest,
+		Version: "1.0",
+		Alias:   pipelineyml.ActionAlias(apistructs.ActionTypeLoadTest),
+		Timeout: config.DurationSec + loadTestTimeoutBufferSec,
+		Params: map[string]interface{}{
+			"scene_id": sceneID,
+			"steps":    stepsParam,
+			"config":   configParam,
+		},
+	}
+	spec := pipelineyml.Spec{
+		Version: "1.1",
+		Stages: []*pipelineyml.Stage{
+			{Actions: []map[pipelineyml.ActionType]*pipelineyml.Action{{action.Type: action}}},
+		},
+	}
+	yml, err := pipelineyml.GenerateYml(&spec)
+	if err != nil {
+		return "", err
+	}
+	return string(yml), nil
+}
+
+// toActionParam convert struct to generic value, so it is kept as json field names in yml
+func toActionParam(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var param interface{}
+	if err := json.Unmarshal(b, &param); err != nil {
+		return nil, err
+	}
+	return param, nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestSceneLoadTestSteps(t *testing.T) {
+	newStep := func(id uint64, typ apistructs.StepAPIType, value string) apistructs.AutoTestSceneStep {
+		step := apistructs.AutoTestSceneStep{Type: typ, Value: value, Name: "step"}
+		step.ID = id
+		return step
+	}
+	login := newStep(1, apistructs.StepTypeAPI, `{"apiSpec":{"name":"login","url":"/login?user=${{ params.user }}","method":"POST","out_params":[{"key":"token","source":"status"}]}}`)
+	login.Children = []apistructs.AutoTestSceneStep{
+		newStep(2, apistructs.StepTypeWait, `{"waitTimeSec":1}`),
+		newStep(3, apistructs.StepTypeWebSocket, `{"apiSpec":{"name":"ws","url":"/ws","headers":[{"key":"token","value":"${{ outputs.1.token }}"}]}}`),
+	}
+	disabled := newStep(4, apistructs.StepTypeAPI, `{"apiSpec":{"name":"disabled","url":"/"}}`)
+	disabled.IsDisabled = true
+	empty := newStep(5, apistructs.StepTypeGRPC, "")
+
+	steps, err := sceneLoadTestSteps([]apistructs.AutoTestSceneStep{login, disabled, empty},
+		[]apistructs.AutoTestSceneInput{{Name: "user", Temp: "erda"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, uint64(1), steps[0].ID)
+	assert.Equal(t, "/login?user=erda", steps[0].APISpec.URL)
+	assert.Equal(t, apistructs.APIProtocolHTTP, steps[0].APISpec.Protocol)
+	assert.Equal(t, uint64(3), steps[1].ID)
+	assert.Equal(t, "{{token}}", steps[1].APISpec.Headers[0].Value)
+	assert.Equal(t, apistructs.APIProtocolWebSocket, steps[1].APISpec.Protocol)
+
+	_, err = sceneLoadTestSteps([]apistructs.AutoTestSceneStep{newStep(6, apistructs.StepTypeAPI, "{")}, nil)
+	assert.Error(t, err)
+}
+
+func TestSceneLoadTestYml(t *testing.T) {
+	steps := []apistructs.AutoTestLoadTestStep{{ID: 1, Name: "login", APISpec: apistructs.APIInfoV2{URL: "/login", Method: "POST"}}}
+	config := apistructs.AutoTestLoadTestConfig{VirtualUsers: 10, DurationSec: 60}
+	yml, err := sceneLoadTestYml(1, steps, config)
+	assert.NoError(t, err)
+
+	assert.Contains(t, yml, "load-test:")
+	assert.Contains(t, yml, "timeout: 360")
+	assert.Contains(t, yml, "virtualUsers: 10")
+	assert.Contains(t, yml, "url: /login")
+}
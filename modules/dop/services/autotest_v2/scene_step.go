@@ -350,7 +350,7 @@ func appendStepOutput(step apistructs.AutoTestSceneStep, outputs map[string]stri
 		outputs = map[string]string{}
 	}
 
-	if step.Value == "" || step.Type != apistructs.StepTypeAPI {
+	if step.Value == "" || !step.Type.IsAPIStepType() {
 		return nil
 	}
 
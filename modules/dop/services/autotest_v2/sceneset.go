@@ -144,9 +144,13 @@ func (svc *Service) ExecuteAutotestSceneSet(req apistructs.AutotestExecuteSceneS
 	}
 	sceneList := svc.sortAutoTestSceneList(scenes[req.AutoTestSceneSet.ID], 1, 10000)
 	sceneGroupMap, groupIDs := getSceneMapByGroupID(sceneList)
+	datasets, err := svc.sceneDatasets(sceneList)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, groupID := range groupIDs {
-		var specStage pipelineyml.Stage
+		var groupStages sceneStages
 		for _, v := range sceneGroupMap[groupID] {
 			inputs := v.Inputs
 
@@ -162,7 +166,7 @@ func (svc *Service) ExecuteAutotestSceneSet(req apistructs.AutotestExecuteSceneS
 
 			if v.RefSetID > 0 {
 				// scene reference scene set
-				specStage.Actions = append(specStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
+				groupStages.put(0, map[pipelineyml.ActionType]*pipelineyml.Action{
 					pipelineyml.Snippet: {
 						Alias: pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
 						Type:  pipelineyml.Snippet,
@@ -186,31 +190,32 @@ func (svc *Service) ExecuteAutotestSceneSet(req apistructs.AutotestExecuteSceneS
 					},
 				})
 			} else {
-				specStage.Actions = append(specStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
-					pipelineyml.Snippet: {
-						Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
-						Type:   pipelineyml.Snippet,
-						Params: params,
+				err = groupStages.add(&pipelineyml.Action{
+					Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
+					Type:   pipelineyml.Snippet,
+					Params: params,
+					Labels: map[string]string{
+						apistructs.AutotestType:  apistructs.AutotestScene,
+						apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
+					},
+					If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
+					SnippetConfig: &pipelineyml.SnippetConfig{
+						Name:   strconv.Itoa(int(v.ID)),
+						Source: apistructs.PipelineSourceAutoTest.String(),
 						Labels: map[string]string{
-							apistructs.AutotestType:  apistructs.AutotestScene,
-							apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
-						},
-						If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
-						SnippetConfig: &pipelineyml.SnippetConfig{
-							Name:   strconv.Itoa(int(v.ID)),
-							Source: apistructs.PipelineSourceAutoTest.String(),
-							Labels: map[string]string{
-								apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
-								apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
-								apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
-								// apistructs.LabelIsRefSet:         isRefSetMap[key],
-							},
+							apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
+							apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
+							apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
+							// apistructs.LabelIsRefSet:         isRefSetMap[key],
 						},
 					},
-				})
+				}, v, datasets[v.ID])
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
-		stagesValue = append(stagesValue, &specStage)
+		stagesValue = append(stagesValue, groupStages.stages()...)
 
 		for _, v := range sceneGroupMap[groupID] {
 			for _, output := range v.Output {
@@ -620,9 +620,13 @@ func (svc *Service) BatchQuerySceneSetPipelineSnippetYaml(configs []apistructs.S
 
 		scenes := svc.sortAutoTestSceneList(resultsScenes, 1, 10000)
 		sceneGroupMap, groupIDs := getSceneMapByGroupID(scenes)
+		datasets, err := svc.sceneDatasets(scenes)
+		if err != nil {
+			return nil, err
+		}
 		var stagesValue []*pipelineyml.Stage
 		for _, groupID := range groupIDs {
-			var specStage pipelineyml.Stage
+			var groupStages sceneStages
 			for _, v := range sceneGroupMap[groupID] {
 				inputs := v.Inputs
 
@@ -641,7 +645,7 @@ func (svc *Service) BatchQuerySceneSetPipelineSnippetYaml(configs []apistructs.S
 
 				if v.RefSetID > 0 {
 					// scene reference scene set
-					specStage.Actions = append(specStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
+					groupStages.put(0, map[pipelineyml.ActionType]*pipelineyml.Action{
 						pipelineyml.Snippet: {
 							Alias: pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
 							Type:  pipelineyml.Snippet,
@@ -665,31 +669,32 @@ func (svc *Service) BatchQuerySceneSetPipelineSnippetYaml(configs []apistructs.S
 						},
 					})
 				} else {
-					specStage.Actions = append(specStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
-						pipelineyml.Snippet: {
-							Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
-							Type:   pipelineyml.Snippet,
-							Params: params,
+					err = groupStages.add(&pipelineyml.Action{
+						Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
+						Type:   pipelineyml.Snippet,
+						Params: params,
+						Labels: map[string]string{
+							apistructs.AutotestType:  apistructs.AutotestScene,
+							apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
+						},
+						If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
+						SnippetConfig: &pipelineyml.SnippetConfig{
+							Name:   strconv.Itoa(int(v.ID)),
+							Source: apistructs.PipelineSourceAutoTest.String(),
 							Labels: map[string]string{
-								apistructs.AutotestType:  apistructs.AutotestScene,
-								apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
-							},
-							If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
-							SnippetConfig: &pipelineyml.SnippetConfig{
-								Name:   strconv.Itoa(int(v.ID)),
-								Source: apistructs.PipelineSourceAutoTest.String(),
-								Labels: map[string]string{
-									apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
-									apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
-									apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
-									apistructs.LabelIsRefSet:         isRefSetMap[key],
-								},
+								apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
+								apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
+								apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
+								apistructs.LabelIsRefSet:         isRefSetMap[key],
 							},
 						},
-					})
+					}, v, datasets[v.ID])
+					if err != nil {
+						return nil, err
+					}
 				}
 			}
-			stagesValue = append(stagesValue, &specStage)
+			stagesValue = append(stagesValue, groupStages.stages()...)
 			for _, v := range sceneGroupMap[groupID] {
 				for _, output := range v.Output {
 					spec.Outputs = append(spec.Outputs, &pipelineyml.PipelineOutput{
@@ -730,11 +735,16 @@ func (svc *Service) QuerySceneSetPipelineSnippetYaml(req apistructs.SnippetConfi
 		return "", err
 	}
 
+	datasets, err := svc.sceneDatasets(scenes)
+	if err != nil {
+		return "", err
+	}
+
 	var spec pipelineyml.Spec
 	spec.Version = "1.1"
-	spec.Stages = make([]*pipelineyml.Stage, len(scenes))
-	for index, v := range scenes {
-		var specStage pipelineyml.Stage
+	for i := range scenes {
+		v := &scenes[i]
+		var stages sceneStages
 
 		var sceneReq apistructs.AutotestSceneRequest
 		sceneReq.SceneID = v.ID
@@ -753,30 +763,31 @@ func (svc *Service) QuerySceneSetPipelineSnippetYaml(req apistructs.SnippetConfi
 			return "", nil
 		}
 
-		specStage.Actions = append(specStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
-			pipelineyml.Snippet: {
-				Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
-				Type:   pipelineyml.Snippet,
-				Params: params,
+		err = stages.add(&pipelineyml.Action{
+			Alias:  pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
+			Type:   pipelineyml.Snippet,
+			Params: params,
+			Labels: map[string]string{
+				apistructs.AutotestType:  apistructs.AutotestScene,
+				apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
+			},
+			If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
+			SnippetConfig: &pipelineyml.SnippetConfig{
+				Name:   strconv.Itoa(int(v.ID)),
+				Source: apistructs.PipelineSourceAutoTest.String(),
 				Labels: map[string]string{
-					apistructs.AutotestType:  apistructs.AutotestScene,
-					apistructs.AutotestScene: base64.StdEncoding.EncodeToString(sceneJson),
-				},
-				If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
-				SnippetConfig: &pipelineyml.SnippetConfig{
-					Name:   strconv.Itoa(int(v.ID)),
-					Source: apistructs.PipelineSourceAutoTest.String(),
-					Labels: map[string]string{
-						apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
-						apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
-						apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
-						apistructs.LabelTestPlanID:       req.Labels[apistructs.LabelTestPlanID],
-						apistructs.LabelIterationID:      req.Labels[apistructs.LabelIterationID],
-					},
+					apistructs.LabelAutotestExecType: apistructs.SceneAutotestExecType,
+					apistructs.LabelSceneID:          strconv.Itoa(int(v.ID)),
+					apistructs.LabelSpaceID:          strconv.Itoa(int(v.SpaceID)),
+					apistructs.LabelTestPlanID:       req.Labels[apistructs.LabelTestPlanID],
+					apistructs.LabelIterationID:      req.Labels[apistructs.LabelIterationID],
 				},
 			},
-		})
-		spec.Stages[index] = &specStage
+		}, v, datasets[v.ID])
+		if err != nil {
+			return "", err
+		}
+		spec.Stages = append(spec.Stages, stages.stages()...)
 	}
 
 	yml, err := pipelineyml.GenerateYml(&spec)
@@ -162,6 +162,9 @@ func (branchRule *BranchRule) Update(request apistructs.UpdateBranchRuleRequest)
 	rule.Workspace = request.Workspace
 	rule.ArtifactWorkspace = request.ArtifactWorkspace
 	rule.NeedApproval = request.NeedApproval
+	rule.RequiredApprovals = request.RequiredApprovals
+	rule.RequireCodeOwnerApproval = request.RequireCodeOwnerApproval
+	rule.RequireSignedCommits = request.RequireSignedCommits
 	err = branchRule.CheckRuleValid(&rule)
 	if err != nil {
 		return nil, err
@@ -184,6 +187,10 @@ func (branchRule *BranchRule) Create(request apistructs.CreateBranchRuleRequest)
 		ArtifactWorkspace: request.ArtifactWorkspace,
 		NeedApproval:      request.NeedApproval,
 		Desc:              request.Desc,
+
+		RequiredApprovals:        request.RequiredApprovals,
+		RequireCodeOwnerApproval: request.RequireCodeOwnerApproval,
+		RequireSignedCommits:     request.RequireSignedCommits,
 	}
 	err := branchRule.CheckRuleValid(&rule)
 	if err != nil {
@@ -226,6 +233,9 @@ func isRuleMatch(sourceRule, targetRule string) bool {
 }
 
 func (branchRule *BranchRule) CheckRuleValid(newBranchRule *model.BranchRule) error {
+	if newBranchRule.RequiredApprovals < 0 {
+		return fmt.Errorf("invalid required approvals %d", newBranchRule.RequiredApprovals)
+	}
 	// check duplicate
 	currentRules, err := branchRule.Query(newBranchRule.ScopeType, newBranchRule.ScopeID)
 	if err != nil {
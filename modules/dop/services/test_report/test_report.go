@@ -15,6 +15,7 @@
 package test_report
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/erda-project/erda/apistructs"
@@ -95,8 +96,16 @@ func (svc *TestReport) CreateTestReport(req apistructs.TestReportRecord) (uint64
 		ReportData: dao.TestReportData{
 			IssueDashboard: req.ReportData.IssueDashboard,
 			TestDashboard:  req.ReportData.TestDashboard,
+			LoadTests:      req.ReportData.LoadTests,
 		},
 	}
+	for _, pipelineID := range req.LoadTestPipelineIDs {
+		loadTest, err := svc.GetLoadTestReport(pipelineID)
+		if err != nil {
+			return 0, err
+		}
+		record.ReportData.LoadTests = append(record.ReportData.LoadTests, *loadTest)
+	}
 	if err := svc.db.CreateTestReportRecord(record); err != nil {
 		return 0, err
 	}
@@ -139,3 +148,26 @@ func (svc *TestReport) GetTestReportByID(id uint64) (apistructs.TestReportRecord
 	}
 	return record.Convert(), nil
 }
+
+// GetLoadTestReport get load test report saved by load-test action of the pipeline
+func (svc *TestReport) GetLoadTestReport(pipelineID uint64) (*apistructs.AutoTestLoadTestReport, error) {
+	reportSet, err := svc.bdl.GetPipelineReportSet(pipelineID, []string{string(apistructs.PipelineReportTypeLoadTest)})
+	if err != nil {
+		return nil, err
+	}
+	for _, report := range reportSet.Reports {
+		if report.Type != apistructs.PipelineReportTypeLoadTest {
+			continue
+		}
+		b, err := json.Marshal(report.Meta["data"])
+		if err != nil {
+			return nil, err
+		}
+		var loadTest apistructs.AutoTestLoadTestReport
+		if err := json.Unmarshal(b, &loadTest); err != nil {
+			return nil, err
+		}
+		return &loadTest, nil
+	}
+	return nil, fmt.Errorf("load test report of pipeline %d not found", pipelineID)
+}
@@ -72,6 +72,8 @@ func (svc *Service) batchConvertTestCases(projectID uint64, models []dao.TestCas
 			Labels:         nil,
 			APIs:           apis[model.ID],
 			APICount:       apiCount,
+			ReviewStatus:   model.ReviewStatus,
+			Reviewers:      model.Reviewers,
 			CreatedAt:      model.CreatedAt,
 			UpdatedAt:      model.UpdatedAt,
 		}
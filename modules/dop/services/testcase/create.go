@@ -55,6 +55,9 @@ func (svc *Service) CreateTestCase(req apistructs.TestCaseCreateRequest) (uint64
 	if err := svc.db.CreateTestCase(&tc); err != nil {
 		return 0, apierrors.ErrCreateTestCase.InternalError(fmt.Errorf("failed to insert testcase into database, err: %v", err))
 	}
+	if err := svc.createTestCaseVersion(nil, &tc, nil); err != nil {
+		return 0, apierrors.ErrCreateTestCase.InternalError(fmt.Errorf("failed to create testcase version, err: %v", err))
+	}
 
 	// 创建 API 信息
 	if len(req.APIs) > 0 {
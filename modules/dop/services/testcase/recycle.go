@@ -45,6 +45,11 @@ func (svc *Service) BatchCleanFromRecycleBin(req apistructs.TestCaseBatchCleanFr
 		return apierrors.ErrBatchCleanTestCasesFromRecycleBin.InternalError(err)
 	}
 
+	// 批量删除测试用例历史版本
+	if err := svc.db.DeleteTestCaseVersionsByTestCaseIDs(req.TestCaseIDs); err != nil {
+		return apierrors.ErrBatchCleanTestCasesFromRecycleBin.InternalError(err)
+	}
+
 	// 批量删除测试用例
 	if err := svc.db.BatchDeleteTestCases(req.TestCaseIDs); err != nil {
 		return apierrors.ErrBatchCleanTestCasesFromRecycleBin.InternalError(err)
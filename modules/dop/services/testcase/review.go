@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dao"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// ReviewTestCase 测试用例评审：提交评审、通过、驳回
+func (svc *Service) ReviewTestCase(req apistructs.TestCaseReviewRequest) (apistructs.TestCaseReviewStatus, error) {
+	tc, err := svc.db.GetTestCaseByID(req.TestCaseID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return "", apierrors.ErrReviewTestCase.NotFound()
+		}
+		return "", apierrors.ErrReviewTestCase.InternalError(err)
+	}
+
+	status, ok := req.Action.Transit(tc.ReviewStatus)
+	if !ok {
+		return "", apierrors.ErrReviewTestCase.InvalidState(fmt.Sprintf("cannot %s test case in status %q", req.Action, tc.ReviewStatus))
+	}
+
+	reviewers := tc.Reviewers
+	switch req.Action {
+	case apistructs.TestCaseReviewActionSubmit:
+		reviewers = strutil.DedupSlice(req.Reviewers, true)
+		if len(reviewers) == 0 {
+			return "", apierrors.ErrReviewTestCase.MissingParameter("reviewers")
+		}
+		// 提交人和最后修改人不能评审自己的用例
+		if strutil.Exist(reviewers, req.UserID) || strutil.Exist(reviewers, tc.UpdaterID) {
+			return "", apierrors.ErrReviewTestCase.InvalidParameter("reviewers: submitter or updater cannot be reviewer")
+		}
+	default:
+		if !strutil.Exist(reviewers, req.UserID) || req.UserID == tc.UpdaterID {
+			return "", apierrors.ErrReviewTestCase.AccessDenied()
+		}
+	}
+
+	if err := svc.db.UpdateTestCaseReview(tc.ID, status, reviewers); err != nil {
+		return "", apierrors.ErrReviewTestCase.InternalError(err)
+	}
+	return status, nil
+}
+
+// resetReviewStatus approved or in review test case goes back to draft once the content is changed
+func resetReviewStatus(tc *dao.TestCase) {
+	if tc.ReviewStatus != apistructs.TestCaseReviewStatusNone {
+		tc.ReviewStatus = apistructs.TestCaseReviewStatusDraft
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dao"
 	"github.com/erda-project/erda/modules/dop/services/apierrors"
 	"github.com/erda-project/erda/pkg/strutil"
 )
@@ -62,6 +63,7 @@ func (svc *Service) UpdateTestCase(req apistructs.TestCaseUpdateRequest) error {
 	}
 
 	// 更新至数据库
+	before := *tc
 	if req.Name != "" {
 		tc.Name = req.Name
 	}
@@ -79,7 +81,13 @@ func (svc *Service) UpdateTestCase(req apistructs.TestCaseUpdateRequest) error {
 	}
 	tc.UpdaterID = req.IdentityInfo.UserID
 
-	if err := svc.db.UpdateTestCase(tc); err != nil {
+	diffs := before.Snapshot().Diff(tc.Snapshot())
+	if len(diffs) > 0 {
+		resetReviewStatus(tc)
+		if err := svc.updateTestCaseWithVersion(&before, tc, diffs); err != nil {
+			return apierrors.ErrUpdateTestCase.InternalError(fmt.Errorf("failed to update testcase with version, err: %v", err))
+		}
+	} else if err := svc.db.UpdateTestCase(tc); err != nil {
 		return apierrors.ErrUpdateTestCase.InternalError(err)
 	}
 
@@ -134,13 +142,37 @@ func (svc *Service) BatchUpdateTestCases(req apistructs.TestCaseBatchUpdateReque
 	}
 
 	// 校验 ids 是否都存在
-	_, err := svc.db.ListTestCasesByIDs(req.TestCaseIDs)
+	tcs, err := svc.db.ListTestCasesByIDs(req.TestCaseIDs)
 	if err != nil {
 		return apierrors.ErrBatchUpdateTestCases.InvalidParameter(err)
 	}
 
+	// 优先级变更保存为新的版本, 与批量更新在同一事务中
+	var updates []dao.TestCaseVersionUpdate
+	for i := range tcs {
+		tc := tcs[i]
+		if req.Priority == "" || tc.Priority == req.Priority {
+			continue
+		}
+		before := tc
+		tc.Priority = req.Priority
+		if req.Recycled != nil {
+			tc.Recycled = req.Recycled
+		}
+		if req.MoveToTestSetID != nil {
+			tc.TestSetID = *req.MoveToTestSetID
+		}
+		tc.UpdaterID = req.UserID
+		resetReviewStatus(&tc)
+		baseline, v, err := svc.newTestCaseVersions(&before, &tc, before.Snapshot().Diff(tc.Snapshot()))
+		if err != nil {
+			return apierrors.ErrBatchUpdateTestCases.InternalError(err)
+		}
+		updates = append(updates, dao.TestCaseVersionUpdate{TestCase: &tc, Baseline: baseline, Version: v})
+	}
+
 	// 批量更新字段
-	if err := svc.db.BatchUpdateTestCases(req); err != nil {
+	if err := svc.db.BatchUpdateTestCasesWithVersions(req, updates); err != nil {
 		return apierrors.ErrBatchUpdateTestCases.InternalError(err)
 	}
 
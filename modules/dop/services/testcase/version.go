@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcase
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/dop/dao"
+	"github.com/erda-project/erda/modules/dop/services/apierrors"
+)
+
+// ListTestCaseVersions 查询测试用例历史版本，按版本号倒序
+func (svc *Service) ListTestCaseVersions(tcID uint64) ([]apistructs.TestCaseVersion, error) {
+	versions, err := svc.db.ListTestCaseVersions(tcID)
+	if err != nil {
+		return nil, apierrors.ErrListTestCaseVersions.InternalError(err)
+	}
+	results := make([]apistructs.TestCaseVersion, 0, len(versions))
+	for _, v := range versions {
+		results = append(results, v.Convert())
+	}
+	return results, nil
+}
+
+// GetTestCaseVersion 查询测试用例指定版本
+func (svc *Service) GetTestCaseVersion(tcID, version uint64) (*apistructs.TestCaseVersion, error) {
+	v, err := svc.db.GetTestCaseVersion(tcID, version)
+	if err != nil {
+		return nil, apierrors.ErrGetTestCaseVersion.InternalError(err)
+	}
+	if v == nil {
+		return nil, apierrors.ErrGetTestCaseVersion.NotFound()
+	}
+	result := v.Convert()
+	return &result, nil
+}
+
+// RestoreTestCaseVersion 恢复测试用例至指定版本，恢复后的内容保存为新的版本
+func (svc *Service) RestoreTestCaseVersion(req apistructs.TestCaseVersionRestoreRequest) error {
+	tc, err := svc.db.GetTestCaseByID(req.TestCaseID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return apierrors.ErrRestoreTestCaseVersion.NotFound()
+		}
+		return apierrors.ErrRestoreTestCaseVersion.InternalError(err)
+	}
+	v, err := svc.db.GetTestCaseVersion(req.TestCaseID, req.Version)
+	if err != nil {
+		return apierrors.ErrRestoreTestCaseVersion.InternalError(err)
+	}
+	if v == nil {
+		return apierrors.ErrRestoreTestCaseVersion.NotFound()
+	}
+	snapshot := v.Convert().Snapshot
+
+	before := *tc
+	tc.Name = snapshot.Name
+	tc.Priority = snapshot.Priority
+	tc.PreCondition = snapshot.PreCondition
+	tc.Desc = snapshot.Desc
+	tc.StepAndResults = snapshot.StepAndResults
+	tc.UpdaterID = req.UserID
+
+	diffs := before.Snapshot().Diff(tc.Snapshot())
+	if len(diffs) == 0 {
+		return nil
+	}
+	resetReviewStatus(tc)
+	if err := svc.updateTestCaseWithVersion(&before, tc, diffs); err != nil {
+		return apierrors.ErrRestoreTestCaseVersion.InternalError(err)
+	}
+	return nil
+}
+
+// createTestCaseVersion keep the content of test case as a new version, before is nil when test case is created.
+func (svc *Service) createTestCaseVersion(before, after *dao.TestCase, diffs []apistructs.TestCaseFieldDiff) error {
+	baseline, v, err := svc.newTestCaseVersions(before, after, diffs)
+	if err != nil {
+		return err
+	}
+	return svc.db.CreateNextTestCaseVersion(baseline, v)
+}
+
+// updateTestCaseWithVersion save test case and keep its content as a new version in one transaction.
+func (svc *Service) updateTestCaseWithVersion(before, after *dao.TestCase, diffs []apistructs.TestCaseFieldDiff) error {
+	baseline, v, err := svc.newTestCaseVersions(before, after, diffs)
+	if err != nil {
+		return err
+	}
+	return svc.db.UpdateTestCaseWithVersion(after, baseline, v)
+}
+
+// newTestCaseVersions returns the new version of test case, and the content before change as baseline,
+// which is kept as the first version if test case is created before versioning.
+// Version numbers are assigned by dao in transaction.
+func (svc *Service) newTestCaseVersions(before, after *dao.TestCase, diffs []apistructs.TestCaseFieldDiff) (baseline, version *dao.TestCaseVersion, err error) {
+	org, err := svc.testCaseOrg(after.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if before != nil {
+		if baseline, err = newTestCaseVersion(org, before, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	if version, err = newTestCaseVersion(org, after, diffs); err != nil {
+		return nil, nil, err
+	}
+	return baseline, version, nil
+}
+
+// testCaseOrg returns the org of the project which test case belongs to
+func (svc *Service) testCaseOrg(projectID uint64) (*apistructs.OrgDTO, error) {
+	project, err := svc.bdl.GetProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.bdl.GetOrg(project.OrgID)
+}
+
+func newTestCaseVersion(org *apistructs.OrgDTO, tc *dao.TestCase, diffs []apistructs.TestCaseFieldDiff) (*dao.TestCaseVersion, error) {
+	snapshot, err := json.Marshal(tc.Snapshot())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot, err: %v", err)
+	}
+	if diffs == nil {
+		diffs = []apistructs.TestCaseFieldDiff{}
+	}
+	diffsJson, err := json.Marshal(diffs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal diffs, err: %v", err)
+	}
+	return &dao.TestCaseVersion{
+		OrgID:      org.ID,
+		OrgName:    org.Name,
+		TestCaseID: tc.ID,
+		Snapshot:   string(snapshot),
+		Diffs:      string(diffsJson),
+		CreatorID:  tc.UpdaterID,
+	}, nil
+}
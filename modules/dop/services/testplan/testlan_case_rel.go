@@ -78,12 +78,17 @@ func (t *TestPlan) CreateCaseRelations(req apistructs.TestPlanCaseRelCreateReque
 		existTcIDMap[existTcID] = struct{}{}
 	}
 
-	// 批量插入
+	// 批量插入，开启评审但未通过的用例不允许引用
 	var rels []dao.TestPlanCaseRel
+	var skippedCount uint64
 	for _, tc := range tcs {
 		if _, ok := existTcIDMap[uint64(tc.ID)]; ok {
 			continue
 		}
+		if !tc.ReviewStatus.CanAddToTestPlan() {
+			skippedCount++
+			continue
+		}
 		rel := dao.TestPlanCaseRel{
 			TestPlanID: tp.ID,
 			TestSetID:  tc.TestSetID,
@@ -98,7 +103,7 @@ func (t *TestPlan) CreateCaseRelations(req apistructs.TestPlanCaseRelCreateReque
 	}
 
 	// result
-	result := apistructs.TestPlanCaseRelCreateResult{TotalCount: uint64(len(rels))}
+	result := apistructs.TestPlanCaseRelCreateResult{TotalCount: uint64(len(rels)), SkippedCount: skippedCount}
 
 	return &result, nil
 }
@@ -49,6 +49,10 @@ type MergeTemplatesResponseData struct {
 	Names  []string `json:"names"`
 }
 
+type MergeMethodsRequest struct {
+	Methods []string `json:"methods"`
+}
+
 // 分页查询
 type PagingRequest struct {
 	// +optional default 1
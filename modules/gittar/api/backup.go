@@ -50,7 +50,11 @@ func Backup(ctx *webcontext.Context) {
 		ctx.Abort(errors.New("备份数已达上限3"))
 		return
 	}
-	path := helper.OutPutArchive(ctx, branch, format)
+	path, err := helper.OutPutArchive(ctx, branch, format)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		ctx.Abort(err)
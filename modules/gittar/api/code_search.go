@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/erda-project/erda/modules/gittar/auth"
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+func getCodeSearchRequest(ctx *webcontext.Context) (*models.CodeSearchRequest, error) {
+	request := &models.CodeSearchRequest{
+		Query:         ctx.Query("q"),
+		Regexp:        ctx.GetQueryBool("regexp", false),
+		CaseSensitive: ctx.GetQueryBool("caseSensitive", false),
+		Language:      ctx.Query("language"),
+		Path:          ctx.Query("path"),
+		PageSize:      ctx.GetQueryInt32("pageSize", 0),
+	}
+	if request.Query == "" {
+		return nil, errors.New("q is required")
+	}
+	var err error
+	for key, value := range map[string]*int64{
+		"orgId":     &request.OrgID,
+		"projectId": &request.ProjectID,
+		"appId":     &request.AppID,
+	} {
+		if s := ctx.Query(key); s != "" {
+			if *value, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, errors.New("invalid " + key)
+			}
+		}
+	}
+	return request, nil
+}
+
+// SearchCode searches the contents of the default branches of the repos in the org the user can access
+func SearchCode(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	request, err := getCodeSearchRequest(ctx)
+	if err != nil {
+		ctx.AbortWithStatus(400, err)
+		return
+	}
+	if request.OrgID == 0 {
+		request.OrgID, _ = strconv.ParseInt(ctx.GetHeader(httputil.OrgHeader), 10, 64)
+	}
+	if request.OrgID == 0 {
+		ctx.AbortWithStatus(400, errors.New("orgId is required"))
+		return
+	}
+
+	repos, err := ctx.Service.ListReposByScope(request.OrgID, request.ProjectID, request.AppID)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	accessible := make([]models.Repo, 0, len(repos))
+	for i := range repos {
+		if _, err := auth.ValidaUserRepoWithCache(ctx, user.Id, &repos[i]); err == nil {
+			accessible = append(accessible, repos[i])
+		}
+	}
+	result, err := ctx.Service.SearchCode(accessible, request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(result)
+}
+
+// SearchRepoCode searches the contents of the default branch of the repo
+func SearchRepoCode(ctx *webcontext.Context) {
+	request, err := getCodeSearchRequest(ctx)
+	if err != nil {
+		ctx.AbortWithStatus(400, err)
+		return
+	}
+	repo, err := ctx.Service.GetRepoById(ctx.Repository.ID)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	result, err := ctx.Service.SearchCode([]models.Repo{*repo}, request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(result)
+}
@@ -32,4 +32,5 @@ var (
 	ERROR_HOOK_NOT_FOUND = errors.New("hook not found")
 	ERROR_LOCKED_DENIED  = errors.New("locked denied")
 	ERROR_REPO_LOCKED    = errors.New("repo locked")
+	ERROR_NOT_LOGIN      = errors.New("not login")
 )
@@ -72,6 +72,34 @@ func ServiceRepoRPC(c *webcontext.Context) {
 			c.Abort(ERROR_REPO_LOCKED)
 			return
 		}
+		// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+		if err = c.Service.CheckPullMirror(c.Repository); err != nil {
+			c.Abort(err)
+			return
+		}
 	}
 	helper.RunProcess(service, c)
 }
+
+// ServiceRepoSSH serves git-upload-pack and git-receive-pack over ssh
+func ServiceRepoSSH(c *webcontext.Context) {
+	service := c.Param("service")
+	if service == "receive-pack" {
+		// 检查仓库是否锁定
+		isLocked, err := c.Service.GetRepoLocked(c.Repository.ProjectId, c.Repository.ApplicationId)
+		if err != nil {
+			c.Abort(err)
+			return
+		}
+		if isLocked {
+			c.Abort(ERROR_REPO_LOCKED)
+			return
+		}
+		// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+		if err = c.Service.CheckPullMirror(c.Repository); err != nil {
+			c.Abort(err)
+			return
+		}
+	}
+	helper.RunSSHProcess(service, c)
+}
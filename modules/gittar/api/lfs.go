@@ -0,0 +1,390 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/pkg/lfs"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+func lfsResponse(ctx *webcontext.Context, code int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Data(code, lfs.MediaType, body)
+}
+
+func lfsAbort(ctx *webcontext.Context, code int, err error) {
+	if code >= http.StatusInternalServerError {
+		logrus.Errorf("lfs request %s failed: %v", ctx.HttpRequest().URL.Path, err)
+	}
+	lfsResponse(ctx, code, &lfs.ErrorResponse{Message: err.Error()})
+}
+
+func lfsBind(ctx *webcontext.Context, obj interface{}) bool {
+	if err := json.NewDecoder(ctx.GetRequestBody()).Decode(obj); err != nil && err != io.EOF {
+		lfsAbort(ctx, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	return true
+}
+
+// lfsCheck checks lfs is enabled and the user has the permission, the repo must not be locked if it's writing
+func lfsCheck(ctx *webcontext.Context, write bool) bool {
+	if !models.LFSEnabled() {
+		lfsAbort(ctx, http.StatusNotImplemented, models.ErrLFSDisabled)
+		return false
+	}
+	if !write {
+		return true
+	}
+	if err := ctx.CheckPermission(models.PermissionPush); err != nil {
+		lfsAbort(ctx, http.StatusForbidden, err)
+		return false
+	}
+	isLocked, err := ctx.Service.GetRepoLocked(ctx.Repository.ProjectId, ctx.Repository.ApplicationId)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+	if isLocked {
+		lfsAbort(ctx, http.StatusForbidden, ERROR_REPO_LOCKED)
+		return false
+	}
+	if err = ctx.Service.CheckPullMirror(ctx.Repository); err != nil {
+		lfsAbort(ctx, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+// lfsBaseURL returns the lfs endpoint of the repo as the client requested, like http://host/org/dop/project/app.git/info/lfs
+func lfsBaseURL(ctx *webcontext.Context) string {
+	request := ctx.HttpRequest()
+	scheme := "http"
+	if proto := request.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	} else if request.TLS != nil {
+		scheme = "https"
+	}
+	path := request.URL.Path
+	if idx := strings.Index(path, "/info/lfs/"); idx >= 0 {
+		path = path[:idx]
+	}
+	return scheme + "://" + ctx.Host() + path + "/info/lfs"
+}
+
+// lfsActionHeader passes the credentials of the batch request to the actions
+func lfsActionHeader(ctx *webcontext.Context, extra map[string]string) map[string]string {
+	header := make(map[string]string)
+	if authorization := ctx.GetHeader("Authorization"); authorization != "" {
+		header["Authorization"] = authorization
+	}
+	for k, v := range extra {
+		header[k] = v
+	}
+	return header
+}
+
+// LFSBatch returns the actions to upload or download the lfs objects
+func LFSBatch(ctx *webcontext.Context) {
+	var request lfs.BatchRequest
+	if !lfsBind(ctx, &request) {
+		return
+	}
+	if request.HashAlgo != "" && request.HashAlgo != lfs.HashAlgo {
+		lfsAbort(ctx, http.StatusConflict, errors.New("unsupported hash algorithm "+request.HashAlgo))
+		return
+	}
+	upload := request.Operation == lfs.OperationUpload
+	if !upload && request.Operation != lfs.OperationDownload {
+		lfsAbort(ctx, http.StatusUnprocessableEntity, errors.New("invalid operation "+request.Operation))
+		return
+	}
+	if !lfsCheck(ctx, upload) {
+		return
+	}
+
+	var oids []string
+	for _, object := range request.Objects {
+		if object.Valid() {
+			oids = append(oids, object.Oid)
+		}
+	}
+	objects, err := ctx.Service.GetLFSObjects(ctx.Repository.ID, oids)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	baseURL := lfsBaseURL(ctx)
+	response := &lfs.BatchResponse{
+		Transfer: lfs.TransferBasic,
+		Objects:  make([]*lfs.ObjectResponse, 0, len(request.Objects)),
+		HashAlgo: lfs.HashAlgo,
+	}
+	var uploadSize int64
+	for _, pointer := range request.Objects {
+		result := &lfs.ObjectResponse{Pointer: pointer, Authenticated: true}
+		response.Objects = append(response.Objects, result)
+		if !pointer.Valid() {
+			result.Error = &lfs.ObjectError{Code: http.StatusUnprocessableEntity, Message: "invalid object"}
+			continue
+		}
+		object, exists := objects[pointer.Oid]
+		if upload {
+			if exists {
+				continue
+			}
+			uploadSize += pointer.Size
+			result.Actions = map[string]*lfs.Link{
+				"upload": {
+					Href:   baseURL + "/objects/" + pointer.Oid,
+					Header: lfsActionHeader(ctx, map[string]string{"Content-Type": "application/octet-stream"}),
+				},
+				"verify": {
+					Href:   baseURL + "/verify",
+					Header: lfsActionHeader(ctx, nil),
+				},
+			}
+			continue
+		}
+		if !exists || object.Size != pointer.Size {
+			result.Error = &lfs.ObjectError{Code: http.StatusNotFound, Message: "object does not exist"}
+			continue
+		}
+		result.Actions = map[string]*lfs.Link{
+			"download": {
+				Href:   baseURL + "/objects/" + pointer.Oid,
+				Header: lfsActionHeader(ctx, nil),
+			},
+		}
+	}
+
+	if uploadSize > 0 {
+		err = ctx.Service.CheckLFSQuota(ctx.Repository, uploadSize)
+		if err == models.ErrLFSQuotaExceeded {
+			lfsAbort(ctx, http.StatusInsufficientStorage, err)
+			return
+		}
+		if err != nil {
+			lfsAbort(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	lfsResponse(ctx, http.StatusOK, response)
+}
+
+// LFSUpload stores the lfs object
+func LFSUpload(ctx *webcontext.Context) {
+	if !lfsCheck(ctx, true) {
+		return
+	}
+	pointer := lfs.Pointer{Oid: ctx.Param("oid"), Size: ctx.HttpRequest().ContentLength}
+	if pointer.Size < 0 {
+		lfsAbort(ctx, http.StatusLengthRequired, errors.New("content length required"))
+		return
+	}
+	if !pointer.Valid() {
+		lfsAbort(ctx, http.StatusUnprocessableEntity, errors.New("invalid object"))
+		return
+	}
+	err := ctx.Service.UploadLFSObject(ctx.Repository, pointer, ctx.GetRequestBody())
+	switch err {
+	case nil:
+		ctx.Status(http.StatusOK)
+	case lfs.ErrVerify:
+		lfsAbort(ctx, http.StatusUnprocessableEntity, err)
+	case models.ErrLFSQuotaExceeded:
+		lfsAbort(ctx, http.StatusInsufficientStorage, err)
+	default:
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// LFSDownload streams the lfs object
+func LFSDownload(ctx *webcontext.Context) {
+	if !lfsCheck(ctx, false) {
+		return
+	}
+	oid := ctx.Param("oid")
+	if !lfs.ValidOid(oid) {
+		lfsAbort(ctx, http.StatusUnprocessableEntity, errors.New("invalid object"))
+		return
+	}
+	object, rc, err := ctx.Service.OpenLFSObject(ctx.Repository.ID, oid)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if object == nil {
+		lfsAbort(ctx, http.StatusNotFound, errors.New("object does not exist"))
+		return
+	}
+	defer rc.Close()
+	ctx.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	ctx.EchoContext.Stream(http.StatusOK, "application/octet-stream", rc)
+}
+
+// LFSVerify checks the lfs object is uploaded
+func LFSVerify(ctx *webcontext.Context) {
+	var pointer lfs.Pointer
+	if !lfsBind(ctx, &pointer) {
+		return
+	}
+	if !lfsCheck(ctx, true) {
+		return
+	}
+	object, err := ctx.Service.GetLFSObject(ctx.Repository.ID, pointer.Oid)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if object == nil || object.Size != pointer.Size {
+		lfsAbort(ctx, http.StatusNotFound, errors.New("object does not exist"))
+		return
+	}
+	lfsResponse(ctx, http.StatusOK, map[string]string{})
+}
+
+// LFSCreateLock locks the file
+func LFSCreateLock(ctx *webcontext.Context) {
+	var request lfs.LockRequest
+	if !lfsBind(ctx, &request) {
+		return
+	}
+	if !lfsCheck(ctx, true) {
+		return
+	}
+	lock, err := ctx.Service.CreateLFSLock(ctx.Repository, ctx.User, &request)
+	if err == models.ErrLFSLockExists {
+		lfsResponse(ctx, http.StatusConflict, &lfs.LockResponse{Lock: lock.ToLock(), Message: err.Error()})
+		return
+	}
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	lfsResponse(ctx, http.StatusCreated, &lfs.LockResponse{Lock: lock.ToLock()})
+}
+
+// LFSListLocks lists the locks of the repo
+func LFSListLocks(ctx *webcontext.Context) {
+	if !lfsCheck(ctx, false) {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+	locks, nextCursor, err := ctx.Service.ListLFSLocks(ctx.Repository.ID, ctx.Query("path"), ctx.Query("id"), ctx.Query("cursor"), limit)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	result := &lfs.LockList{Locks: make([]*lfs.Lock, 0, len(locks)), NextCursor: nextCursor}
+	for i := range locks {
+		result.Locks = append(result.Locks, locks[i].ToLock())
+	}
+	lfsResponse(ctx, http.StatusOK, result)
+}
+
+// LFSVerifyLocks lists the locks of the current user and the others before pushing
+func LFSVerifyLocks(ctx *webcontext.Context) {
+	var request lfs.LockVerifyRequest
+	if !lfsBind(ctx, &request) {
+		return
+	}
+	if !lfsCheck(ctx, true) {
+		return
+	}
+	locks, nextCursor, err := ctx.Service.ListLFSLocks(ctx.Repository.ID, "", "", request.Cursor, request.Limit)
+	if err != nil {
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	result := &lfs.LockVerifyResponse{Ours: []*lfs.Lock{}, Theirs: []*lfs.Lock{}, NextCursor: nextCursor}
+	for i := range locks {
+		if locks[i].OwnerID == ctx.User.Id {
+			result.Ours = append(result.Ours, locks[i].ToLock())
+		} else {
+			result.Theirs = append(result.Theirs, locks[i].ToLock())
+		}
+	}
+	lfsResponse(ctx, http.StatusOK, result)
+}
+
+// LFSDeleteLock unlocks the file
+func LFSDeleteLock(ctx *webcontext.Context) {
+	var request lfs.UnlockRequest
+	if !lfsBind(ctx, &request) {
+		return
+	}
+	if !lfsCheck(ctx, true) {
+		return
+	}
+	id := ctx.Param("id")
+	if id == "" {
+		lfsAbort(ctx, http.StatusUnprocessableEntity, ERROR_ARG_ID)
+		return
+	}
+	lock, err := ctx.Service.DeleteLFSLock(ctx.Repository, ctx.User, id, request.Force)
+	switch err {
+	case nil:
+		lfsResponse(ctx, http.StatusOK, &lfs.LockResponse{Lock: lock.ToLock()})
+	case gorm.ErrRecordNotFound:
+		lfsAbort(ctx, http.StatusNotFound, errors.New("lock not found"))
+	case models.ErrLFSLockOwner:
+		lfsAbort(ctx, http.StatusForbidden, err)
+	default:
+		lfsAbort(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// GetLFSStats returns the lfs quota and usage of the repo
+func GetLFSStats(ctx *webcontext.Context) {
+	stats, err := ctx.Service.GetLFSStats(ctx.Repository)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(stats)
+}
+
+// SetLFSQuota updates the lfs quota of the repo
+func SetLFSQuota(ctx *webcontext.Context) {
+	var request models.LFSQuotaRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	stats, err := ctx.Service.SetLFSQuota(ctx.Repository, ctx.User, request.Quota)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(stats)
+}
@@ -45,10 +45,50 @@ func CheckMergeStatus(ctx *webcontext.Context) {
 		return
 	}
 
+	// the methods not allowed by the repo are not possible
+	if conflictInfo.Methods != nil {
+		methods, err := ctx.Service.GetMergeMethods(ctx.Repository)
+		if err != nil {
+			ctx.Abort(err)
+			return
+		}
+		for method := range conflictInfo.Methods {
+			if !gitmodule.IsMergeMethodAllowed(methods, method) {
+				conflictInfo.Methods[method] = false
+			}
+		}
+	}
+
 	ctx.Success(conflictInfo)
 
 }
 
+// GetMergeMethods returns the merge methods allowed by the repo
+func GetMergeMethods(ctx *webcontext.Context) {
+	methods, err := ctx.Service.GetMergeMethods(ctx.Repository)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(methods)
+}
+
+// SetMergeMethods sets the merge methods allowed by the repo
+func SetMergeMethods(ctx *webcontext.Context) {
+	var request MergeMethodsRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	methods, err := ctx.Service.SetMergeMethods(ctx.Repository, ctx.User, request.Methods)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(methods)
+}
+
 func GetMergeTemplates(ctx *webcontext.Context) {
 	branch, err := ctx.Repository.GetDefaultBranch()
 	if err != nil {
@@ -96,6 +136,11 @@ func CreateMergeRequest(ctx *webcontext.Context) {
 		ctx.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = ctx.Service.CheckPullMirror(ctx.Repository); err != nil {
+		ctx.Abort(err)
+		return
+	}
 
 	var createMergeInfo apistructs.MergeRequestInfo
 	err = ctx.BindJSON(&createMergeInfo)
@@ -159,12 +204,16 @@ func GetMergeRequestDetail(ctx *webcontext.Context) {
 		ctx.Abort(err)
 		return
 	}
-	ctx.Success(mergeRequestInfo, []string{
+	userIDs := []string{
 		mergeRequestInfo.AssigneeId,
 		mergeRequestInfo.CloseUserId,
 		mergeRequestInfo.MergeUserId,
 		mergeRequestInfo.AuthorId,
-	})
+	}
+	for _, reviewer := range mergeRequestInfo.Reviewers {
+		userIDs = append(userIDs, reviewer.ReviewerId)
+	}
+	ctx.Success(mergeRequestInfo, userIDs)
 }
 
 func GetMergeRequests(ctx *webcontext.Context) {
@@ -205,6 +254,11 @@ func UpdateMergeRequest(ctx *webcontext.Context) {
 		ctx.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = ctx.Service.CheckPullMirror(ctx.Repository); err != nil {
+		ctx.Abort(err)
+		return
+	}
 
 	id := ctx.ParamInt32("id", 0)
 	if id == 0 {
@@ -256,6 +310,11 @@ func Merge(ctx *webcontext.Context) {
 		ctx.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = ctx.Service.CheckPullMirror(ctx.Repository); err != nil {
+		ctx.Abort(err)
+		return
+	}
 
 	id := ctx.ParamInt32("id", 0)
 	if id == 0 {
@@ -304,6 +363,7 @@ func Merge(ctx *webcontext.Context) {
 		request.AssigneeId = mergeRequestInfo.AuthorId
 		ctx.Service.TriggerEvent(ctx.Repository, apistructs.GitMergeMREvent, request)
 	}()
+	ctx.Service.VerifyCommits(ctx.Repository, commit)
 	ctx.Success(commit)
 }
 
@@ -505,3 +565,82 @@ func GetMergeRequestsStats(ctx *webcontext.Context) {
 	}
 	ctx.Success(response)
 }
+
+// GetMrReviewers lists the reviewers and the approval status of the merge request
+func GetMrReviewers(ctx *webcontext.Context) {
+	id := ctx.ParamInt32("id", 0)
+	if id == 0 {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	mergeRequestInfo, err := ctx.Service.GetMergeRequestDetail(ctx.Repository, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	userIDs := make([]string, 0, len(mergeRequestInfo.Reviewers))
+	for _, reviewer := range mergeRequestInfo.Reviewers {
+		userIDs = append(userIDs, reviewer.ReviewerId)
+	}
+	ctx.Success(map[string]interface{}{
+		"reviewers": mergeRequestInfo.Reviewers,
+		"approval":  mergeRequestInfo.Approval,
+	}, userIDs)
+}
+
+// GetMrCommits lists the commits of the merge request with the signature verification
+func GetMrCommits(ctx *webcontext.Context) {
+	id := ctx.ParamInt32("id", 0)
+	if id == 0 {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	commits, err := ctx.Service.GetMrCommits(ctx.Repository, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(commits)
+}
+
+// AddMrReviewers requests more reviewers on the merge request
+func AddMrReviewers(ctx *webcontext.Context) {
+	id := ctx.ParamInt32("id", 0)
+	if id == 0 {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	var request models.MrReviewersRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	reviewers, err := ctx.Service.AddReviewers(ctx.Repository, ctx.User, id, request.ReviewerIds)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(reviewers)
+}
+
+// ReviewMR approves or requests changes on the merge request
+func ReviewMR(ctx *webcontext.Context) {
+	id := ctx.ParamInt32("id", 0)
+	if id == 0 {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	var request models.MrReviewRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	reviewer, err := ctx.Service.Review(ctx.Repository, ctx.User, id, &request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(reviewer)
+}
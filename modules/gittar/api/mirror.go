@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+// ListRepoMirrors lists the pull and push mirrors of the repo
+func ListRepoMirrors(ctx *webcontext.Context) {
+	mirrors, err := ctx.Service.ListRepoMirrors(ctx.Repository)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	userIDs := make([]string, 0, len(mirrors))
+	for _, m := range mirrors {
+		userIDs = append(userIDs, m.CreatedBy)
+	}
+	ctx.Success(mirrors, userIDs)
+}
+
+// CreateRepoMirror adds a pull or push mirror to the repo
+func CreateRepoMirror(ctx *webcontext.Context) {
+	var request models.RepoMirrorRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	mirror, err := ctx.Service.CreateRepoMirror(ctx.Repository, ctx.User, &request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(mirror)
+}
+
+// UpdateRepoMirror updates the mirror
+func UpdateRepoMirror(ctx *webcontext.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	var request models.RepoMirrorRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	mirror, err := ctx.Service.UpdateRepoMirror(ctx.Repository, ctx.User, id, &request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(mirror)
+}
+
+// DeleteRepoMirror removes the mirror
+func DeleteRepoMirror(ctx *webcontext.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	err := ctx.Service.DeleteRepoMirror(ctx.Repository, ctx.User, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(nil)
+}
+
+// SyncRepoMirror syncs the mirror now, the result is updated to the status of the mirror
+func SyncRepoMirror(ctx *webcontext.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	mirror, err := ctx.Service.SyncRepoMirror(ctx.Repository, ctx.User, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(mirror)
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/erda-project/erda/modules/gittar/pkg/pushpolicy"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+// GetPushPolicy returns the push rules of the repo
+func GetPushPolicy(ctx *webcontext.Context) {
+	policy, err := ctx.Service.GetPushPolicy(ctx.Repository)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(policy)
+}
+
+// SetPushPolicy updates the push rules of the repo
+func SetPushPolicy(ctx *webcontext.Context) {
+	var request pushpolicy.Policy
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	policy, err := ctx.Service.SetPushPolicy(ctx.Repository, ctx.User, &request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(policy)
+}
@@ -104,6 +104,7 @@ func SetRepoDefaultBranch(context *webcontext.Context) {
 	if err != nil {
 		context.Abort(err)
 	} else {
+		go context.Service.UpdateSearchIndex(repository.ID, repository.DiskPath())
 		context.Success("")
 	}
 }
@@ -121,6 +122,11 @@ func CreateRepoBranch(context *webcontext.Context) {
 		context.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = context.Service.CheckPullMirror(context.Repository); err != nil {
+		context.Abort(err)
+		return
+	}
 
 	request := CreateBranchRequest{}
 	err = context.BindJSON(&request)
@@ -186,6 +192,11 @@ func DeleteRepoBranch(context *webcontext.Context) {
 		context.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = context.Service.CheckPullMirror(context.Repository); err != nil {
+		context.Abort(err)
+		return
+	}
 
 	branch := context.Param("*")
 	err = context.CheckBranchOperatePermission(context.User, branch)
@@ -248,6 +259,7 @@ func GetRepoTags(context *webcontext.Context) {
 		logrus.Errorf("repo:%v branch error %v", repository.DiskPath(), err)
 		context.Abort(errors.New("tags error"))
 	} else {
+		context.Service.VerifyTags(repository, tags...)
 		context.Success(tags)
 	}
 }
@@ -348,6 +360,7 @@ func GetRepoCommits(context *webcontext.Context) {
 	if err != nil {
 		context.Abort(err)
 	} else {
+		context.Service.VerifyCommits(context.Repository, commits...)
 		context.Success(commits)
 	}
 }
@@ -376,6 +389,7 @@ func Commit(ctx *webcontext.Context) {
 		ctx.AbortWithStatus(404, err)
 		return
 	}
+	ctx.Service.VerifyCommits(ctx.Repository, newCommit)
 	ctx.Success(Map{
 		"diff":   diff,
 		"commit": newCommit,
@@ -466,6 +480,7 @@ func Compare(ctx *webcontext.Context) {
 		ctx.AbortWithStatus(500, err)
 		return
 	}
+	ctx.Service.VerifyCommits(ctx.Repository, betweenCommits...)
 
 	ctx.Success(Map{
 		"commits":      betweenCommits,
@@ -695,6 +710,11 @@ func CreateCommit(context *webcontext.Context) {
 		context.Abort(ERROR_REPO_LOCKED)
 		return
 	}
+	// 从镜像拉取的仓库只读, 否则写入会被下一次拉取覆盖
+	if err = context.Service.CheckPullMirror(context.Repository); err != nil {
+		context.Abort(err)
+		return
+	}
 
 	var createCommitRequest gitmodule.CreateCommit
 	if err = context.BindJSON(&createCommitRequest); err != nil {
@@ -711,6 +731,11 @@ func CreateCommit(context *webcontext.Context) {
 		context.Abort(err)
 		return
 	}
+	// 网页上创建的提交没有签名, 不能写入要求签名的分支
+	if err := context.Service.CheckUnsignedCommit(repository, createCommitRequest.Branch); err != nil {
+		context.Abort(err)
+		return
+	}
 
 	beforeCommitID := gitmodule.INIT_COMMIT_ID
 	beforeCommit, err := context.Repository.GetBranchCommit(createCommitRequest.Branch)
@@ -1104,5 +1129,7 @@ func GetArchive(ctx *webcontext.Context) {
 		ctx.AbortWithString(404, "ref not found "+ref)
 		return
 	}
-	helper.RunArchive(ctx, ref, format)
+	if err := helper.RunArchive(ctx, ref, format); err != nil {
+		ctx.Abort(err)
+	}
 }
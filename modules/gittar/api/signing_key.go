@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+// ListSigningKeys lists the gpg and ssh signing keys of the current user
+func ListSigningKeys(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	keys, err := ctx.Service.ListSigningKeys(user)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(keys)
+}
+
+// AddSigningKey adds a gpg or ssh signing key for the current user
+func AddSigningKey(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	var request models.SigningKeyRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	request.OrgID, request.OrgName = currentOrg(ctx)
+	key, err := ctx.Service.AddSigningKey(user, &request)
+	if err != nil {
+		ctx.AbortWithStatus(400, err)
+		return
+	}
+	ctx.Success(key)
+}
+
+// DeleteSigningKey deletes a signing key of the current user
+func DeleteSigningKey(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	err := ctx.Service.DeleteSigningKey(user, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(nil)
+}
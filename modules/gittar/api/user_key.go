@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+func currentUser(ctx *webcontext.Context) *models.User {
+	if ctx.User != nil {
+		return ctx.User
+	}
+	userID := ctx.GetHeader(httputil.UserHeader)
+	if userID == "" {
+		return nil
+	}
+	return &models.User{Id: userID}
+}
+
+// currentOrg returns the org of the request, the keys belong to the user but record the org they are added in
+func currentOrg(ctx *webcontext.Context) (int64, string) {
+	orgID, _ := strconv.ParseInt(ctx.GetHeader(httputil.OrgHeader), 10, 64)
+	if orgID <= 0 {
+		return 0, ""
+	}
+	org, err := ctx.Bundle.GetOrg(orgID)
+	if err != nil {
+		logrus.Errorf("failed to get org %d: %v", orgID, err)
+		return orgID, ""
+	}
+	return orgID, org.Name
+}
+
+// ListUserKeys lists the ssh public keys of the current user
+func ListUserKeys(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	keys, err := ctx.Service.ListUserKeys(user)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(keys)
+}
+
+// AddUserKey adds a ssh public key for the current user
+func AddUserKey(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	var request models.UserKeyRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	request.OrgID, request.OrgName = currentOrg(ctx)
+	key, err := ctx.Service.AddUserKey(user, &request)
+	if err != nil {
+		ctx.AbortWithStatus(400, err)
+		return
+	}
+	ctx.Success(key)
+}
+
+// DeleteUserKey deletes a ssh public key of the current user
+func DeleteUserKey(ctx *webcontext.Context) {
+	user := currentUser(ctx)
+	if user == nil {
+		ctx.AbortWithStatus(401, ERROR_NOT_LOGIN)
+		return
+	}
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Abort(ERROR_ARG_ID)
+		return
+	}
+	err := ctx.Service.DeleteUserKey(user, id)
+	if err != nil {
+		ctx.Abort(err)
+		return
+	}
+	ctx.Success(nil)
+}
@@ -15,6 +15,7 @@
 package conf
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/erda-project/erda/pkg/discover"
@@ -31,6 +32,30 @@ type Conf struct {
 	Debug           bool   `env:"DEBUG" default:"false"`
 	DiceProtocol    string `env:"DICE_PROTOCOL"`
 
+	// ssh config
+	SSHEnable      bool   `env:"GITTAR_SSH_ENABLE" default:"false"`
+	SSHPort        string `env:"GITTAR_SSH_PORT" default:"2222"`
+	SSHHostKeyPath string `env:"GITTAR_SSH_HOST_KEY_PATH"`
+
+	// lfs config
+	LFSEnable       bool   `env:"GITTAR_LFS_ENABLE" default:"true"`
+	LFSStoragePath  string `env:"GITTAR_LFS_STORAGE_PATH"`
+	LFSDefaultQuota int64  `env:"GITTAR_LFS_DEFAULT_QUOTA" default:"0"`
+	OSSEndpoint     string `env:"OSS_ENDPOINT"`
+	OSSAccessID     string `env:"OSS_ACCESS_ID"`
+	OSSAccessSecret string `env:"OSS_ACCESS_SECRET"`
+	OSSBucket       string `env:"OSS_BUCKET"`
+
+	// mirror config
+	MirrorInterval   int64  `env:"GITTAR_MIRROR_INTERVAL" default:"30"`
+	MirrorAllowLocal bool   `env:"GITTAR_MIRROR_ALLOW_LOCAL" default:"false"`
+	MirrorSecretKey  string `env:"GITTAR_MIRROR_SECRET_KEY"`
+
+	// code search config
+	SearchEnable           bool   `env:"GITTAR_SEARCH_ENABLE" default:"true"`
+	SearchIndexPath        string `env:"GITTAR_SEARCH_INDEX_PATH"`
+	SearchMaxLoadedIndexes int    `env:"GITTAR_SEARCH_MAX_LOADED_INDEXES" default:"32"`
+
 	UCAddr            string `env:"UC_ADDR"`
 	UCClientID        string `env:"UC_CLIENT_ID"`
 	UCClientSecret    string `env:"UC_CLIENT_SECRET"`
@@ -200,3 +225,95 @@ func GitRepoTreeSearchDepth() int64 {
 func DiceProtocol() string {
 	return cfg.DiceProtocol
 }
+
+// SSHEnable 是否开启 ssh 协议
+func SSHEnable() bool {
+	return cfg.SSHEnable
+}
+
+// SSHPort ssh 监听端口
+func SSHPort() string {
+	return cfg.SSHPort
+}
+
+// SSHHostKeyPath ssh host key 路径, 不存在时自动生成
+func SSHHostKeyPath() string {
+	if cfg.SSHHostKeyPath == "" {
+		return filepath.Join(cfg.RepoRoot, ".ssh", "ssh_host_ed25519_key")
+	}
+	return cfg.SSHHostKeyPath
+}
+
+// LFSEnable 是否开启 git lfs
+func LFSEnable() bool {
+	return cfg.LFSEnable
+}
+
+// LFSStoragePath lfs 对象存储路径, 配置了 oss 时为 oss 中的路径前缀
+func LFSStoragePath() string {
+	if cfg.LFSStoragePath != "" {
+		return cfg.LFSStoragePath
+	}
+	if cfg.OSSEndpoint != "" {
+		return "/gittar/lfs"
+	}
+	return filepath.Join(cfg.RepoRoot, ".lfs")
+}
+
+// LFSDefaultQuota 仓库默认 lfs 存储配额, 单位字节, 0 表示不限制
+func LFSDefaultQuota() int64 {
+	return cfg.LFSDefaultQuota
+}
+
+// OSSEndpoint 返回 oss endpoint, 为空时 lfs 对象存储在本地文件系统
+func OSSEndpoint() string {
+	return cfg.OSSEndpoint
+}
+
+// OSSAccessID 返回 oss access id.
+func OSSAccessID() string {
+	return cfg.OSSAccessID
+}
+
+// OSSAccessSecret 返回 oss access secret.
+func OSSAccessSecret() string {
+	return cfg.OSSAccessSecret
+}
+
+// OSSBucket 返回 oss bucket.
+func OSSBucket() string {
+	return cfg.OSSBucket
+}
+
+// MirrorInterval 拉取镜像的默认间隔, 单位分钟
+func MirrorInterval() int64 {
+	return cfg.MirrorInterval
+}
+
+// MirrorAllowLocal 是否允许镜像本地路径的仓库, 仅用于测试
+func MirrorAllowLocal() bool {
+	return cfg.MirrorAllowLocal
+}
+
+// MirrorSecretKey 加密镜像密码的密钥
+func MirrorSecretKey() string {
+	return cfg.MirrorSecretKey
+}
+
+// SearchEnable 是否开启代码搜索
+func SearchEnable() bool {
+	return cfg.SearchEnable
+}
+
+// SearchIndexPath 代码搜索索引存储目录
+func SearchIndexPath() string {
+	if cfg.SearchIndexPath == "" {
+		return filepath.Join(cfg.RepoRoot, ".search")
+	}
+	return cfg.SearchIndexPath
+}
+
+// SearchMaxLoadedIndexes 内存中保留的索引数量
+func SearchMaxLoadedIndexes() int {
+	return cfg.SearchMaxLoadedIndexes
+}
@@ -20,6 +20,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
@@ -183,42 +184,32 @@ func RunProcess(service string, c *webcontext.Context) {
 			return
 		}
 		logrus.Infof("push header:" + string(header))
-		re := regexp.MustCompile(
-			`(?mi)(?P<before>[0-9a-fA-F]{40}) (?P<after>[0-9a-fA-F]{40}) (?P<ref>refs\/(heads|tags)\/\S*)`,
-		)
-
-		matchHeader := removeEndMarkerFromHeader(header)
-		for _, matches := range re.FindAllSubmatch(matchHeader, -1) {
-			pushEvent := &models.PayloadPushEvent{
-				Before:            string(matches[1]),
-				After:             string(matches[2]),
-				Ref:               string(bytes.Trim(matches[3], "\x00")),
-				IsTag:             string(matches[4]) == "tags",
-				Pusher:            c.MustGet("user").(*models.User),
-				TotalCommitsCount: 0,
-			}
-			pushEvent.IsDelete = pushEvent.After == gitmodule.INIT_COMMIT_ID
-			pushEvents = append(pushEvents, pushEvent)
-		}
+		pushEvents = parsePushEvents(header, c.MustGet("user").(*models.User))
 
 		repository := c.MustGet("repository").(*gitmodule.Repository)
-		if preReceiveHook(pushEvents, c) {
-			// Only when one branch is created will it be written to the writer
-			// Refer to github
-			if len(pushEvents) == 1 && pushEvents[0].IsCreateNewBranch() {
-				c.GetWriter().Write(NewReportStatus(
-					"unpack ok",
-					"ok "+pushEvents[0].Ref,
-					makeCreatePipelineLink(pushEvents[0].Ref[len(gitmodule.BRANCH_PREFIX):], c.Repository.OrgName, c.Repository.ProjectId)))
-			}
-			runCommand2(c.GetWriter(), gitCommand(
-				version,
-				service,
-				"--stateless-rpc",
-				repository.DiskPath(),
-			), bytes.NewReader(header), reqBody)
-			go PostReceiveHook(pushEvents, c)
+		if !preReceiveHook(pushEvents, c) {
+			return
 		}
+		pack, cleanup, ok := checkPushPolicy(pushEvents, c, reqBody)
+		if !ok {
+			return
+		}
+		defer cleanup()
+		// Only when one branch is created will it be written to the writer
+		// Refer to github
+		if len(pushEvents) == 1 && pushEvents[0].IsCreateNewBranch() {
+			c.GetWriter().Write(NewReportStatus(
+				"unpack ok",
+				"ok "+pushEvents[0].Ref,
+				makeCreatePipelineLink(pushEvents[0].Ref[len(gitmodule.BRANCH_PREFIX):], c.Repository.OrgName, c.Repository.ProjectId)))
+		}
+		runCommand2(c.GetWriter(), gitCommand(
+			version,
+			service,
+			"--stateless-rpc",
+			repository.DiskPath(),
+		), bytes.NewReader(header), pack)
+		go PostReceiveHook(pushEvents, c)
 	} else {
 		runCommand2(c.GetWriter(), gitCommand(
 			version,
@@ -229,6 +220,29 @@ func RunProcess(service string, c *webcontext.Context) {
 	}
 }
 
+var pushCommandRegexp = regexp.MustCompile(
+	`(?mi)(?P<before>[0-9a-fA-F]{40}) (?P<after>[0-9a-fA-F]{40}) (?P<ref>refs\/(heads|tags)\/\S*)`,
+)
+
+// parsePushEvents parses the ref update commands sent by git-send-pack
+func parsePushEvents(header []byte, pusher *models.User) []*models.PayloadPushEvent {
+	var pushEvents []*models.PayloadPushEvent
+	matchHeader := removeEndMarkerFromHeader(header)
+	for _, matches := range pushCommandRegexp.FindAllSubmatch(matchHeader, -1) {
+		pushEvent := &models.PayloadPushEvent{
+			Before:            string(matches[1]),
+			After:             string(matches[2]),
+			Ref:               string(bytes.Trim(matches[3], "\x00")),
+			IsTag:             string(matches[4]) == "tags",
+			Pusher:            pusher,
+			TotalCommitsCount: 0,
+		}
+		pushEvent.IsDelete = pushEvent.After == gitmodule.INIT_COMMIT_ID
+		pushEvents = append(pushEvents, pushEvent)
+	}
+	return pushEvents
+}
+
 // removeEndMarkerFromHeader remove end marker '0000' from header
 // see https://github.com/git/git/blob/master/Documentation/technical/http-protocol.txt line:342
 func removeEndMarkerFromHeader(header []byte) []byte {
@@ -239,12 +253,36 @@ func removeEndMarkerFromHeader(header []byte) []byte {
 	return matchHeader
 }
 
-func RunArchive(c *webcontext.Context, ref string, format string) {
-	c.EchoContext.Response().Header().Add("Content-Disposition", "attachment; filename="+
-		c.Repository.ProjectName+"-"+
-		c.Repository.ApplicationName+"-"+
-		strings.Replace(ref, "/", "-", -1)+"."+format)
+func RunArchive(c *webcontext.Context, ref string, format string) error {
+	setDisposition := func() {
+		c.EchoContext.Response().Header().Add("Content-Disposition", "attachment; filename="+
+			c.Repository.ProjectName+"-"+
+			c.Repository.ApplicationName+"-"+
+			strings.Replace(ref, "/", "-", -1)+"."+format)
+	}
 
+	// 包含 lfs 对象时将指针文件替换为实际内容, 先打包到临时文件, 失败时才能返回错误
+	if c.Service.HasLFSObjects(c.Repository.ID) {
+		f, err := ioutil.TempFile("", "gittar-lfs-archive-")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			f.Close()
+			os.Remove(f.Name())
+		}()
+		if err = runLFSArchive(c, f, ref, format); err != nil {
+			return fmt.Errorf("failed to archive %s with lfs objects: %v", ref, err)
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		setDisposition()
+		_, err = io.Copy(c.GetWriter(), f)
+		return err
+	}
+
+	setDisposition()
 	fullPath, _ := filepath.Abs(c.MustGet("repository").(*gitmodule.Repository).DiskPath())
 	runCommand2(c.GetWriter(), gitCommand(
 		"",
@@ -252,14 +290,28 @@ func RunArchive(c *webcontext.Context, ref string, format string) {
 		"--format="+format,
 		ref,
 		"--remote=file:///"+fullPath))
+	return nil
 }
 
 // OutPutArchive 创建打包文件
-func OutPutArchive(c *webcontext.Context, ref string, format string) string {
+func OutPutArchive(c *webcontext.Context, ref string, format string) (string, error) {
 
 	fullPath, _ := filepath.Abs(c.MustGet("repository").(*gitmodule.Repository).DiskPath())
 	filename := fullPath + "/" + strings.Replace(ref, "/", "-", -1) + "." + format
 
+	if c.Service.HasLFSObjects(c.Repository.ID) {
+		f, err := os.Create(filename)
+		if err != nil {
+			return "", fmt.Errorf("failed to create archive %s: %v", filename, err)
+		}
+		defer f.Close()
+		if err = runLFSArchive(c, f, ref, format); err != nil {
+			os.Remove(filename)
+			return "", fmt.Errorf("failed to archive %s with lfs objects: %v", ref, err)
+		}
+		return filename, nil
+	}
+
 	runCommand2(c.GetWriter(), gitCommand(
 		"",
 		"archive",
@@ -267,7 +319,7 @@ func OutPutArchive(c *webcontext.Context, ref string, format string) string {
 		ref,
 		"--remote=file:///"+fullPath,
 		"--output="+filename))
-	return filename
+	return filename, nil
 }
 
 // OutPutArchiveDelete 删除打包文件  （文件打包上传完必须删除）
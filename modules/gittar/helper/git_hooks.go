@@ -124,6 +124,9 @@ func PostReceiveHook(pushEvents []*models.PayloadPushEvent, c *webcontext.Contex
 		c.Service.UpdateRepoSizeCache(repository.ID, size)
 	}
 
+	go c.Service.SyncPushMirrors(repository)
+	go c.Service.UpdateSearchIndex(repository.ID, repository.DiskPath())
+
 	repo, err := git.OpenRepository(repository.DiskPath())
 	if err != nil {
 		logrus.Errorf("repo error %s %s", repository.DiskPath(), err)
@@ -60,11 +60,16 @@ func NewPtkLine(line string) []byte {
 }
 
 func NewReportStatus(unpackStatus string, refState string, remoteMsg string) []byte {
+	return NewReportStatuses(unpackStatus, []string{refState}, remoteMsg)
+}
+
+// NewReportStatuses reports the status of every ref in the push
+func NewReportStatuses(unpackStatus string, refStates []string, remoteMsg string) []byte {
 	var statusBuffer bytes.Buffer
 	var totalBuffer bytes.Buffer
 	var headLines []string
 	headLines = append(headLines, unpackStatus)
-	headLines = append(headLines, refState)
+	headLines = append(headLines, refStates...)
 	for _, line := range headLines {
 		statusBuffer.Write(NewPtkLine(line))
 	}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+// runCommandDuplex copies the input and the output at the same time,
+// because the git commands over ssh are stateful and the client waits for the response before sending more.
+func runCommandDuplex(w io.Writer, cmd *exec.Cmd, readers ...io.Reader) error {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	logrus.Infof("command %v processId:%s", cmd.Args, strconv.Itoa(cmd.Process.Pid))
+
+	go func() {
+		defer stdin.Close()
+		for _, r := range readers {
+			if _, err := io.Copy(stdin, r); err != nil {
+				return
+			}
+		}
+	}()
+	if _, err := io.Copy(w, stdout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		logrus.Errorf("command %v exec error %v, stderr: %s", cmd.Args, err, stderr.String())
+		return err
+	}
+	return nil
+}
+
+// RunSSHProcess runs git-upload-pack or git-receive-pack for the ssh session, the input is the request body.
+// The pushes go through preReceiveHook, checkPushPolicy and PostReceiveHook like RunProcess.
+func RunSSHProcess(service string, c *webcontext.Context) {
+	version := c.MustGet("gitProtocol").(string)
+	repository := c.MustGet("repository").(*gitmodule.Repository)
+	body := c.GetRequestBody()
+
+	if service != "receive-pack" {
+		if err := runCommandDuplex(c.GetWriter(), gitCommand(version, service, repository.DiskPath()), body); err != nil {
+			c.AbortWithStatus(500, err)
+		}
+		return
+	}
+
+	// receive-pack 拆成 advertise 和 stateless-rpc 两步, 以便像 http 一样在写入前检查推送的分支
+	runCommand2(c.GetWriter(), gitCommand(
+		version,
+		service,
+		"--stateless-rpc",
+		"--advertise-refs",
+		repository.DiskPath(),
+	))
+	header, err := ReadGitSendPackHeader(body)
+	if err != nil {
+		logrus.Errorf("receive-pack error %v", err)
+		c.AbortWithStatus(500, err)
+		return
+	}
+	if len(header) <= 4 {
+		// 没有需要更新的 ref
+		return
+	}
+	pushEvents := parsePushEvents(header, c.MustGet("user").(*models.User))
+	if !preReceiveHook(pushEvents, c) {
+		return
+	}
+	pack, cleanup, ok := checkPushPolicy(pushEvents, c, body)
+	if !ok {
+		return
+	}
+	defer cleanup()
+	if len(pushEvents) == 1 && pushEvents[0].IsCreateNewBranch() {
+		c.GetWriter().Write(NewReportStatus(
+			"unpack ok",
+			"ok "+pushEvents[0].Ref,
+			makeCreatePipelineLink(pushEvents[0].Ref[len(gitmodule.BRANCH_PREFIX):], c.Repository.OrgName, c.Repository.ProjectId)))
+	}
+	err = runCommandDuplex(c.GetWriter(), gitCommand(
+		version,
+		service,
+		"--stateless-rpc",
+		repository.DiskPath(),
+	), bytes.NewReader(header), pack)
+	if err != nil {
+		c.AbortWithStatus(500, err)
+		return
+	}
+	go PostReceiveHook(pushEvents, c)
+}
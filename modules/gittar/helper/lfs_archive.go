@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/lfs"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+)
+
+// runLFSArchive archives the ref with the contents of the lfs objects instead of the pointer files
+func runLFSArchive(c *webcontext.Context, w io.Writer, ref string, format string) error {
+	repo := c.Repository
+	cmd := gitCommand("", "archive", "--format=tar", ref)
+	cmd.Dir = repo.DiskPath()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	resolve := func(pointer *lfs.Pointer) (io.ReadCloser, error) {
+		object, rc, err := c.Service.OpenLFSObject(repo.ID, pointer.Oid)
+		if err != nil || object == nil {
+			return nil, err
+		}
+		if object.Size != pointer.Size {
+			rc.Close()
+			return nil, nil
+		}
+		return rc, nil
+	}
+	err = lfs.RewriteArchive(stdout, w, format, resolve)
+	if err != nil {
+		io.Copy(ioutil.Discard, stdout)
+		cmd.Wait()
+		return err
+	}
+	if err = cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive failed: %v, %s", err, stderr.String())
+	}
+	return nil
+}
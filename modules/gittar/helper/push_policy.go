@@ -0,0 +1,309 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/pkg/pushpolicy"
+	"github.com/erda-project/erda/modules/gittar/pkg/signature"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+)
+
+// maxScanFiles limits the files scanned for secrets in a push, the push with more files is rejected
+const maxScanFiles = 1000
+
+// pushQuarantine holds the incoming pack in a temporary object directory,
+// the objects are visible to the git commands with envs but not to the repository, like the quarantine of git-receive-pack.
+type pushQuarantine struct {
+	repoPath string
+	dir      string
+	packFile string
+	envs     []string
+}
+
+func newPushQuarantine(repoPath string, body io.Reader) (*pushQuarantine, error) {
+	dir, err := ioutil.TempDir(filepath.Join(repoPath, "objects"), "tmp_push_policy-")
+	if err != nil {
+		return nil, err
+	}
+	q := &pushQuarantine{
+		repoPath: repoPath,
+		dir:      dir,
+		packFile: filepath.Join(dir, "incoming.pack"),
+		envs: []string{
+			"GIT_OBJECT_DIRECTORY=" + dir,
+			"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + filepath.Join(repoPath, "objects"),
+		},
+	}
+	if err := q.index(body); err != nil {
+		q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// index saves the pack for git-receive-pack and indexes the objects
+func (q *pushQuarantine) index(body io.Reader) error {
+	f, err := os.Create(q.packFile)
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(f, body)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(q.dir, "pack"), 0755); err != nil {
+		return err
+	}
+	pack, err := os.Open(q.packFile)
+	if err != nil {
+		return err
+	}
+	defer pack.Close()
+	_, err = q.git(pack, "index-pack", "--stdin", "--fix-thin")
+	return err
+}
+
+func (q *pushQuarantine) git(stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = q.repoPath
+	cmd.Env = append(os.Environ(), q.envs...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v, %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Pack returns the saved pack
+func (q *pushQuarantine) Pack() (io.ReadCloser, error) {
+	return os.Open(q.packFile)
+}
+
+func (q *pushQuarantine) Close() {
+	if err := os.RemoveAll(q.dir); err != nil {
+		logrus.Errorf("failed to remove push quarantine %s: %v", q.dir, err)
+	}
+}
+
+// check returns the violations of the commits and files which are not in the repository yet
+func (q *pushQuarantine) check(checker *pushpolicy.Checker, heads []string) ([]error, error) {
+	revs := append(append([]string{}, heads...), "--not", "--all")
+	output, err := q.git(nil, append([]string{"log", pushpolicy.LogFormat}, revs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var violations []error
+	for _, commit := range pushpolicy.ParseLog(string(output)) {
+		if err := checker.CheckCommit(commit); err != nil {
+			if _, ok := err.(*pushpolicy.Violation); !ok {
+				return nil, err
+			}
+			violations = append(violations, err)
+		}
+	}
+	if !checker.NeedFiles() {
+		return violations, nil
+	}
+
+	output, err = q.git(nil, append([]string{"rev-list", "--objects"}, revs...)...)
+	if err != nil {
+		return nil, err
+	}
+	paths := pushpolicy.ParseObjects(string(output))
+	if len(paths) <= 0 {
+		return violations, nil
+	}
+	var ids bytes.Buffer
+	for id := range paths {
+		ids.WriteString(id + "\n")
+	}
+	output, err = q.git(&ids, "cat-file", "--batch-check=%(objectname) %(objecttype) %(objectsize)")
+	if err != nil {
+		return nil, err
+	}
+	scanned := 0
+	for id, size := range pushpolicy.ParseBatchCheck(string(output)) {
+		file := &pushpolicy.File{Path: paths[id], BlobID: id, Size: size}
+		if err := checker.CheckFile(file); err != nil {
+			violations = append(violations, err)
+			continue
+		}
+		if !checker.NeedContent(file) {
+			continue
+		}
+		if scanned >= maxScanFiles {
+			// 未扫描的文件可能包含密钥, 不能放行
+			violations = append(violations, &pushpolicy.Violation{Rule: pushpolicy.RuleSecret,
+				Message: fmt.Sprintf("too many files to scan for secrets, push at most %d files at a time", maxScanFiles)})
+			break
+		}
+		scanned++
+		content, err := q.git(nil, "cat-file", "blob", id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checker.CheckContent(file, content); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	return violations, nil
+}
+
+// checkSignatures returns the violations of the new commits which are not signed or not verified
+func (q *pushQuarantine) checkSignatures(svc *models.Service, heads []string) ([]error, error) {
+	output, err := q.git(nil, append(append([]string{"rev-list"}, heads...), "--not", "--all")...)
+	if err != nil {
+		return nil, err
+	}
+	var violations []error
+	for _, id := range strings.Fields(string(output)) {
+		raw, err := q.git(nil, "cat-file", "commit", id)
+		if err != nil {
+			return nil, err
+		}
+		v, err := svc.VerifySignature(signature.ParseCommit(raw))
+		if err != nil {
+			return nil, err
+		}
+		if !v.Verified {
+			violations = append(violations, &pushpolicy.Violation{Rule: pushpolicy.RuleSignedCommit, Commit: id,
+				Message: fmt.Sprintf("commit must be signed with a verified key, got %s", v.Reason)})
+		}
+	}
+	return violations, nil
+}
+
+// checkPushPolicy checks the incoming commits against the push rules of the repo after preReceiveHook.
+// It returns the pack to feed into git-receive-pack, and false if the push is rejected.
+func checkPushPolicy(pushEvents []*models.PayloadPushEvent, c *webcontext.Context, body io.Reader) (io.Reader, func(), bool) {
+	nop := func() {}
+	// 推送被拒绝时所有 ref 都需要返回 ng
+	reject := func(reason, msg string) (io.Reader, func(), bool) {
+		refStates := make([]string, 0, len(pushEvents))
+		for _, pushEvent := range pushEvents {
+			refStates = append(refStates, "ng "+pushEvent.Ref+" "+reason)
+		}
+		c.Status(200)
+		c.GetWriter().Write(NewReportStatuses("unpack ok", refStates, msg))
+		return nil, nop, false
+	}
+	policy, err := c.Service.GetPushPolicy(c.Repository)
+	if err != nil {
+		logrus.Errorf("failed to get push policy of repo %d: %v", c.Repository.ID, err)
+		return reject("failed to get push policy", err.Error())
+	}
+	var heads, signedHeads []string
+	for _, pushEvent := range pushEvents {
+		if pushEvent.IsDelete {
+			continue
+		}
+		heads = append(heads, pushEvent.After)
+		if pushEvent.IsTag {
+			continue
+		}
+		// 保护分支可要求推送的提交必须签名并验证通过
+		required, err := c.Repository.RequireSignedCommits(strings.TrimPrefix(pushEvent.Ref, gitmodule.BRANCH_PREFIX))
+		if err != nil {
+			logrus.Errorf("failed to get branch rules of repo %d: %v", c.Repository.ID, err)
+			return reject("failed to get branch rules", err.Error())
+		}
+		if required {
+			signedHeads = append(signedHeads, pushEvent.After)
+		}
+	}
+	if (!policy.Enable && len(signedHeads) <= 0) || len(heads) <= 0 {
+		return body, nop, true
+	}
+
+	var checker *pushpolicy.Checker
+	if policy.Enable {
+		checker, err = pushpolicy.NewChecker(policy, func(id uint64) (bool, error) {
+			issue, err := c.Bundle.GetIssue(id)
+			if err != nil {
+				if apiErr, ok := err.(*errorresp.APIError); ok && apiErr.HttpCode() == http.StatusNotFound {
+					return false, nil
+				}
+				return false, err
+			}
+			return issue.ProjectID == uint64(c.Repository.ProjectId), nil
+		})
+		if err != nil {
+			return reject("invalid push policy", err.Error())
+		}
+	}
+	q, err := newPushQuarantine(c.Repository.DiskPath(), body)
+	if err != nil {
+		logrus.Errorf("failed to receive pack of repo %d: %v", c.Repository.ID, err)
+		return reject("failed to check push policy", err.Error())
+	}
+	var violations []error
+	if checker != nil {
+		violations, err = q.check(checker, heads)
+		if err != nil {
+			q.Close()
+			logrus.Errorf("failed to check push policy of repo %d: %v", c.Repository.ID, err)
+			return reject("failed to check push policy", err.Error())
+		}
+	}
+	if len(signedHeads) > 0 {
+		signatureViolations, err := q.checkSignatures(c.Service, signedHeads)
+		if err != nil {
+			q.Close()
+			logrus.Errorf("failed to check commit signatures of repo %d: %v", c.Repository.ID, err)
+			return reject("failed to check commit signatures", err.Error())
+		}
+		violations = append(violations, signatureViolations...)
+	}
+	if len(violations) > 0 {
+		q.Close()
+		var msg strings.Builder
+		msg.WriteString("push rejected by the push rules of the repository:")
+		for _, v := range violations {
+			msg.WriteString("\n  " + v.Error())
+		}
+		return reject("push policy: "+violations[0].Error(), msg.String())
+	}
+	pack, err := q.Pack()
+	if err != nil {
+		q.Close()
+		return reject("failed to check push policy", err.Error())
+	}
+	return pack, func() {
+		pack.Close()
+		q.Close()
+	}, true
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/erda-project/erda/modules/gittar/cache"
 	"github.com/erda-project/erda/modules/gittar/conf"
 	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/pkg/codesearch"
 	"github.com/erda-project/erda/modules/gittar/pkg/gc"
 	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
 	"github.com/erda-project/erda/modules/gittar/profiling"
@@ -118,6 +119,13 @@ func (p *provider) Initialize() error {
 	functionalGroup := e.Group("/api")
 	{
 		functionalGroup.GET("/merge-requests-count", webcontext.WrapHandler(api.MergeRequestCount))
+		functionalGroup.GET("/user-keys", webcontext.WrapHandler(api.ListUserKeys))
+		functionalGroup.POST("/user-keys", webcontext.WrapHandler(api.AddUserKey))
+		functionalGroup.DELETE("/user-keys/:id", webcontext.WrapHandler(api.DeleteUserKey))
+		functionalGroup.GET("/signing-keys", webcontext.WrapHandler(api.ListSigningKeys))
+		functionalGroup.POST("/signing-keys", webcontext.WrapHandler(api.AddSigningKey))
+		functionalGroup.DELETE("/signing-keys/:id", webcontext.WrapHandler(api.DeleteSigningKey))
+		functionalGroup.GET("/code-search", webcontext.WrapHandler(api.SearchCode))
 	}
 
 	logger := middleware.Logger()
@@ -150,6 +158,30 @@ func (p *provider) Initialize() error {
 	// start hook task consumer
 	models.Init(dbClient)
 
+	if conf.LFSEnable() {
+		models.WithLFSStore(newLFSStore())
+	}
+
+	if conf.SearchEnable() {
+		models.WithCodeSearchIndexer(codesearch.NewIndexer(conf.SearchIndexPath(), conf.SearchMaxLoadedIndexes()))
+	}
+
+	// pull the mirrors periodically
+	go models.StartMirrorScheduler(dbClient)
+
+	if conf.SSHEnable() {
+		sshServer, err := newSSHServer(dbClient)
+		if err != nil {
+			panic(err)
+		}
+		go func() {
+			logrus.Infof("ssh server listening on :%s", conf.SSHPort())
+			if err := sshServer.ListenAndServe(); err != nil {
+				logrus.Errorf("ssh server exit: %v", err)
+			}
+		}()
+	}
+
 	return e.Start(":" + conf.ListenPort())
 }
 
@@ -167,6 +199,18 @@ func addApiRoutes(g *echo.Group) {
 	// implements the service_rpc function
 	g.POST("/git-:service", webcontext.WrapHandler(api.ServiceRepoRPC))
 
+	// implements the git lfs batch, transfer and locking api
+	g.POST("/info/lfs/objects/batch", webcontext.WrapHandler(api.LFSBatch))
+	g.PUT("/info/lfs/objects/:oid", webcontext.WrapHandler(api.LFSUpload))
+	g.GET("/info/lfs/objects/:oid", webcontext.WrapHandler(api.LFSDownload))
+	g.POST("/info/lfs/verify", webcontext.WrapHandler(api.LFSVerify))
+	g.GET("/info/lfs/locks", webcontext.WrapHandler(api.LFSListLocks))
+	g.POST("/info/lfs/locks", webcontext.WrapHandler(api.LFSCreateLock))
+	g.POST("/info/lfs/locks/verify", webcontext.WrapHandler(api.LFSVerifyLocks))
+	g.POST("/info/lfs/locks/:id/unlock", webcontext.WrapHandler(api.LFSDeleteLock))
+	g.GET("/lfs", webcontext.WrapHandler(api.GetLFSStats))
+	g.PUT("/lfs/quota", webcontext.WrapHandler(api.SetLFSQuota))
+
 	g.GET("/commits/*", webcontext.WrapHandlerWithRepoCheck(api.GetRepoCommits))
 	g.POST("/commits", webcontext.WrapHandler(api.CreateCommit))
 
@@ -184,6 +228,7 @@ func addApiRoutes(g *echo.Group) {
 	g.DELETE("/tags/*", webcontext.WrapHandler(api.DeleteRepoTag))
 	g.GET("/tree/*", webcontext.WrapHandlerWithRepoCheck(api.GetRepoTree))
 	g.GET("/tree-search", webcontext.WrapHandlerWithRepoCheck(api.SearchRepoTree))
+	g.GET("/code-search", webcontext.WrapHandler(api.SearchRepoCode))
 	g.GET("/blob/*", webcontext.WrapHandlerWithRepoCheck(api.GetRepoBlob))
 	g.GET("/blob-range/*", webcontext.WrapHandlerWithRepoCheck(api.GetRepoBlobRange))
 	g.GET("/raw/*", webcontext.WrapHandlerWithRepoCheck(api.GetRepoRaw))
@@ -196,6 +241,15 @@ func addApiRoutes(g *echo.Group) {
 	//merge request
 	g.GET("/merge-stats", webcontext.WrapHandler(api.CheckMergeStatus))
 	g.GET("/merge-templates", webcontext.WrapHandler(api.GetMergeTemplates))
+	g.GET("/merge-methods", webcontext.WrapHandler(api.GetMergeMethods))
+	g.PUT("/merge-methods", webcontext.WrapHandler(api.SetMergeMethods))
+	g.GET("/push-policy", webcontext.WrapHandler(api.GetPushPolicy))
+	g.PUT("/push-policy", webcontext.WrapHandler(api.SetPushPolicy))
+	g.GET("/mirrors", webcontext.WrapHandler(api.ListRepoMirrors))
+	g.POST("/mirrors", webcontext.WrapHandler(api.CreateRepoMirror))
+	g.PUT("/mirrors/:id", webcontext.WrapHandler(api.UpdateRepoMirror))
+	g.DELETE("/mirrors/:id", webcontext.WrapHandler(api.DeleteRepoMirror))
+	g.POST("/mirrors/:id/sync", webcontext.WrapHandler(api.SyncRepoMirror))
 	g.GET("/merge-requests/:id", webcontext.WrapHandler(api.GetMergeRequestDetail))
 	g.GET("/merge-requests", webcontext.WrapHandler(api.GetMergeRequests))
 	g.GET("/merge-request-stats", webcontext.WrapHandler(api.GetMergeRequestsStats))
@@ -204,6 +258,10 @@ func addApiRoutes(g *echo.Group) {
 	g.POST("/merge-requests/:id/merge", webcontext.WrapHandler(api.Merge))
 	g.POST("/merge-requests/:id/close", webcontext.WrapHandler(api.CloseMR))
 	g.POST("/merge-requests/:id/reopen", webcontext.WrapHandler(api.ReopenMR))
+	g.GET("/merge-requests/:id/commits", webcontext.WrapHandler(api.GetMrCommits))
+	g.GET("/merge-requests/:id/reviewers", webcontext.WrapHandler(api.GetMrReviewers))
+	g.POST("/merge-requests/:id/reviewers", webcontext.WrapHandler(api.AddMrReviewers))
+	g.POST("/merge-requests/:id/review", webcontext.WrapHandler(api.ReviewMR))
 	g.GET("/merge-requests/:id/notes", webcontext.WrapHandler(api.QueryNotes))
 	g.POST("/merge-requests/:id/notes", webcontext.WrapHandler(api.CreateNotes))
 	g.POST("/check-runs", webcontext.WrapHandler(api.CreateCheckRun))
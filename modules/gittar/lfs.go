@@ -0,0 +1,30 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gittar
+
+import (
+	"github.com/erda-project/erda/modules/gittar/conf"
+	"github.com/erda-project/erda/modules/gittar/pkg/lfs"
+	"github.com/erda-project/erda/pkg/storage"
+)
+
+// newLFSStore stores the lfs objects in oss if configured, otherwise in the local file system
+func newLFSStore() *lfs.Store {
+	var storager storage.Storager = storage.NewFS()
+	if conf.OSSEndpoint() != "" {
+		storager = storage.NewOSS(conf.OSSEndpoint(), conf.OSSAccessID(), conf.OSSAccessSecret(), conf.OSSBucket(), nil, nil)
+	}
+	return lfs.NewStore(storager, conf.LFSStoragePath())
+}
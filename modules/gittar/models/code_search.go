@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/codesearch"
+)
+
+var ErrCodeSearchDisabled = errors.New("code search is disabled")
+
+var codeSearchIndexer *codesearch.Indexer
+
+// WithCodeSearchIndexer sets the indexer of the code search
+func WithCodeSearchIndexer(indexer *codesearch.Indexer) {
+	codeSearchIndexer = indexer
+}
+
+// CodeSearchRequest searches the contents of the default branches
+type CodeSearchRequest struct {
+	OrgID         int64
+	ProjectID     int64
+	AppID         int64
+	Query         string
+	Regexp        bool
+	CaseSensitive bool
+	Language      string
+	Path          string
+	PageSize      int
+}
+
+// CodeSearchFile is the matched file of the repo
+type CodeSearchFile struct {
+	RepoID      int64  `json:"repoId"`
+	ProjectID   int64  `json:"projectId"`
+	ProjectName string `json:"projectName"`
+	AppID       int64  `json:"appId"`
+	AppName     string `json:"appName"`
+	Commit      string `json:"commit"`
+	*codesearch.Result
+}
+
+// CodeSearchResult is the matched files of the repos
+type CodeSearchResult struct {
+	Total int               `json:"total"`
+	Files []*CodeSearchFile `json:"files"`
+	// Indexing are the repos whose indexes are being built, the results do not contain them
+	Indexing []string `json:"indexing"`
+}
+
+// UpdateSearchIndex indexes the changes of the default branch of the repo
+func (svc *Service) UpdateSearchIndex(repoID int64, repoPath string) {
+	if codeSearchIndexer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), codesearch.DefaultTimeout)
+	defer cancel()
+	if _, err := codeSearchIndexer.Update(ctx, repoID, repoPath); err != nil {
+		logrus.Errorf("failed to update search index of repo %d: %v", repoID, err)
+	}
+}
+
+// RemoveSearchIndex removes the index of the repo
+func (svc *Service) RemoveSearchIndex(repoID int64) {
+	if codeSearchIndexer == nil {
+		return
+	}
+	if err := codeSearchIndexer.Remove(repoID); err != nil {
+		logrus.Errorf("failed to remove search index of repo %d: %v", repoID, err)
+	}
+}
+
+// ListReposByScope lists the repos of the org, the project or the app
+func (svc *Service) ListReposByScope(orgID, projectID, appID int64) ([]Repo, error) {
+	query := svc.db.Where("org_id = ?", orgID)
+	if projectID > 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if appID > 0 {
+		query = query.Where("app_id = ?", appID)
+	}
+	var repos []Repo
+	err := query.Order("id").Find(&repos).Error
+	return repos, err
+}
+
+// SearchCode searches the default branches of the repos, the repos must be checked by the caller
+func (svc *Service) SearchCode(repos []Repo, request *CodeSearchRequest) (*CodeSearchResult, error) {
+	if codeSearchIndexer == nil {
+		return nil, ErrCodeSearchDisabled
+	}
+	query := &codesearch.Query{
+		Pattern:       request.Query,
+		Regexp:        request.Regexp,
+		CaseSensitive: request.CaseSensitive,
+		Language:      request.Language,
+		Path:          request.Path,
+		MaxResults:    request.PageSize,
+	}
+	if query.MaxResults <= 0 {
+		query.MaxResults = codesearch.DefaultMaxResults
+	}
+	if _, err := query.Compile(); err != nil {
+		return nil, err
+	}
+
+	result := &CodeSearchResult{Files: []*CodeSearchFile{}, Indexing: []string{}}
+	for _, repo := range repos {
+		ix := codeSearchIndexer.Get(repo.ID, repo.DiskPath())
+		if ix == nil {
+			result.Indexing = append(result.Indexing, repo.Path)
+			continue
+		}
+		files, total, err := ix.Search(query)
+		if err != nil {
+			return nil, err
+		}
+		result.Total += total
+		for _, file := range files {
+			result.Files = append(result.Files, &CodeSearchFile{
+				RepoID:      repo.ID,
+				ProjectID:   repo.ProjectID,
+				ProjectName: repo.ProjectName,
+				AppID:       repo.AppID,
+				AppName:     repo.AppName,
+				Commit:      ix.Commit,
+				Result:      file,
+			})
+		}
+	}
+	sort.SliceStable(result.Files, func(i, j int) bool {
+		return len(result.Files[i].Matches) > len(result.Files[j].Matches)
+	})
+	if len(result.Files) > query.MaxResults {
+		result.Files = result.Files[:query.MaxResults]
+	}
+	return result, nil
+}
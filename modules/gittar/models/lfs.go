@@ -0,0 +1,356 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/conf"
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/pkg/lfs"
+)
+
+var (
+	ErrLFSDisabled      = errors.New("lfs is disabled")
+	ErrLFSQuotaExceeded = errors.New("lfs quota exceeded")
+	ErrLFSLockExists    = errors.New("lock already exists")
+	ErrLFSLockOwner     = errors.New("lock is owned by others")
+)
+
+var lfsStore *lfs.Store
+
+// WithLFSStore sets the store of the lfs objects
+func WithLFSStore(store *lfs.Store) {
+	lfsStore = store
+}
+
+// LFSObject model, the lfs objects uploaded to the repo
+type LFSObject struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         int64
+	OrgName       string
+	RepoID        int64
+	Oid           string
+	Size          int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (LFSObject) TableName() string {
+	return "erda_repo_lfs_object"
+}
+
+// LFSLock model, the file locked by the user
+type LFSLock struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         int64
+	OrgName       string
+	RepoID        int64
+	Path          string
+	Ref           string
+	OwnerID       string
+	OwnerName     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (LFSLock) TableName() string {
+	return "erda_repo_lfs_lock"
+}
+
+func (l *LFSLock) ToLock() *lfs.Lock {
+	return &lfs.Lock{
+		ID:       l.ID,
+		Path:     l.Path,
+		LockedAt: l.CreatedAt,
+		Owner:    &lfs.Owner{Name: l.OwnerName},
+	}
+}
+
+// LFSStats is the storage usage of the lfs objects
+type LFSStats struct {
+	Enable  bool  `json:"enable"`
+	Quota   int64 `json:"quota"`
+	Usage   int64 `json:"usage"`
+	Objects int64 `json:"objects"`
+}
+
+// LFSQuotaRequest sets the lfs quota of the repo
+type LFSQuotaRequest struct {
+	Quota int64 `json:"quota"`
+}
+
+// LFSEnabled returns true if lfs is enabled and the store is ready
+func LFSEnabled() bool {
+	return conf.LFSEnable() && lfsStore != nil
+}
+
+func checkLFSEnable() error {
+	if !LFSEnabled() {
+		return ErrLFSDisabled
+	}
+	return nil
+}
+
+// GetLFSStats returns the quota and the usage of the repo
+func (svc *Service) GetLFSStats(repo *gitmodule.Repository) (*LFSStats, error) {
+	var currentRepo Repo
+	err := svc.db.Table("dice_repos").Where("id = ?", repo.ID).First(&currentRepo).Error
+	if err != nil {
+		return nil, err
+	}
+	stats := &LFSStats{
+		Enable: LFSEnabled(),
+		Quota:  currentRepo.LFSQuota,
+	}
+	if stats.Quota <= 0 {
+		stats.Quota = conf.LFSDefaultQuota()
+	}
+	var result struct {
+		TotalSize  int64
+		TotalCount int64
+	}
+	err = svc.db.Model(&LFSObject{}).Select("COALESCE(SUM(size), 0) AS total_size, COUNT(*) AS total_count").
+		Where("repo_id = ? and soft_deleted_at = 0", repo.ID).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.Usage = result.TotalSize
+	stats.Objects = result.TotalCount
+	return stats, nil
+}
+
+// SetLFSQuota sets the lfs quota of the repo, 0 means the default quota
+func (svc *Service) SetLFSQuota(repo *gitmodule.Repository, user *User, quota int64) (*LFSStats, error) {
+	// 配额占用组织的存储, 只有企业管理员或系统管理员可以修改
+	if err := svc.CheckOrgPermission(repo, user); err != nil {
+		return nil, err
+	}
+	if quota < 0 {
+		return nil, errors.New("invalid lfs quota")
+	}
+	err := svc.db.Table("dice_repos").Where("id = ?", repo.ID).Update("lfs_quota", quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetLFSStats(repo)
+}
+
+// CheckLFSQuota returns ErrLFSQuotaExceeded if the objects to upload exceed the quota
+func (svc *Service) CheckLFSQuota(repo *gitmodule.Repository, size int64) error {
+	stats, err := svc.GetLFSStats(repo)
+	if err != nil {
+		return err
+	}
+	if stats.Quota > 0 && stats.Usage+size > stats.Quota {
+		return ErrLFSQuotaExceeded
+	}
+	return nil
+}
+
+// GetLFSObjects returns the objects of the repo by oid
+func (svc *Service) GetLFSObjects(repoID int64, oids []string) (map[string]*LFSObject, error) {
+	result := make(map[string]*LFSObject)
+	if len(oids) <= 0 {
+		return result, nil
+	}
+	var objects []LFSObject
+	err := svc.db.Where("repo_id = ? and oid in (?) and soft_deleted_at = 0", repoID, oids).Find(&objects).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		result[objects[i].Oid] = &objects[i]
+	}
+	return result, nil
+}
+
+// GetLFSObject returns the object of the repo, nil if not uploaded
+func (svc *Service) GetLFSObject(repoID int64, oid string) (*LFSObject, error) {
+	var object LFSObject
+	err := svc.db.Where("repo_id = ? and oid = ? and soft_deleted_at = 0", repoID, oid).First(&object).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// HasLFSObjects returns true if any lfs object is uploaded to the repo
+func (svc *Service) HasLFSObjects(repoID int64) bool {
+	if checkLFSEnable() != nil {
+		return false
+	}
+	var count int64
+	svc.db.Model(&LFSObject{}).Where("repo_id = ? and soft_deleted_at = 0", repoID).Count(&count)
+	return count > 0
+}
+
+// UploadLFSObject stores the object after verifying the content
+func (svc *Service) UploadLFSObject(repo *gitmodule.Repository, pointer lfs.Pointer, r io.Reader) error {
+	if err := checkLFSEnable(); err != nil {
+		return err
+	}
+	object, err := svc.GetLFSObject(repo.ID, pointer.Oid)
+	if err != nil {
+		return err
+	}
+	if object != nil {
+		return nil
+	}
+	if err = svc.CheckLFSQuota(repo, pointer.Size); err != nil {
+		return err
+	}
+	if err = lfsStore.Put(repo.ID, pointer, r); err != nil {
+		return err
+	}
+	return svc.db.Create(&LFSObject{
+		ID:      uuid.New().String(),
+		OrgID:   repo.OrgId,
+		OrgName: repo.OrgName,
+		RepoID:  repo.ID,
+		Oid:     pointer.Oid,
+		Size:    pointer.Size,
+	}).Error
+}
+
+// OpenLFSObject opens the object of the repo, returns nil if not uploaded
+func (svc *Service) OpenLFSObject(repoID int64, oid string) (*LFSObject, io.ReadCloser, error) {
+	if err := checkLFSEnable(); err != nil {
+		return nil, nil, err
+	}
+	object, err := svc.GetLFSObject(repoID, oid)
+	if err != nil || object == nil {
+		return nil, nil, err
+	}
+	rc, err := lfsStore.Get(repoID, oid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return object, rc, nil
+}
+
+// DeleteLFSObjects removes all the lfs objects of the repo
+func (svc *Service) DeleteLFSObjects(repoID int64) error {
+	if lfsStore == nil {
+		return nil
+	}
+	var objects []LFSObject
+	err := svc.db.Where("repo_id = ? and soft_deleted_at = 0", repoID).Find(&objects).Error
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := lfsStore.Delete(repoID, object.Oid); err != nil {
+			logrus.Errorf("failed to delete lfs object %s of repo %d: %v", object.Oid, repoID, err)
+		}
+	}
+	now := time.Now().UnixNano() / 1e6
+	err = svc.db.Model(&LFSObject{}).Where("repo_id = ? and soft_deleted_at = 0", repoID).Update("soft_deleted_at", now).Error
+	if err != nil {
+		return err
+	}
+	return svc.db.Model(&LFSLock{}).Where("repo_id = ? and soft_deleted_at = 0", repoID).Update("soft_deleted_at", now).Error
+}
+
+// CreateLFSLock locks the file, returns the existing lock with ErrLFSLockExists if it's locked
+func (svc *Service) CreateLFSLock(repo *gitmodule.Repository, user *User, request *lfs.LockRequest) (*LFSLock, error) {
+	if request.Path == "" {
+		return nil, errors.New("path is required")
+	}
+	var lock LFSLock
+	err := svc.db.Where("repo_id = ? and path = ? and soft_deleted_at = 0", repo.ID, request.Path).First(&lock).Error
+	if err == nil {
+		return &lock, ErrLFSLockExists
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	lock = LFSLock{
+		ID:        uuid.New().String(),
+		OrgID:     repo.OrgId,
+		OrgName:   repo.OrgName,
+		RepoID:    repo.ID,
+		Path:      request.Path,
+		OwnerID:   user.Id,
+		OwnerName: user.NickName,
+	}
+	if lock.OwnerName == "" {
+		lock.OwnerName = user.Name
+	}
+	if request.Ref != nil {
+		lock.Ref = request.Ref.Name
+	}
+	if err = svc.db.Create(&lock).Error; err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// ListLFSLocks lists the locks ordered by id, the cursor is the id to start from
+func (svc *Service) ListLFSLocks(repoID int64, path string, id string, cursor string, limit int) ([]LFSLock, string, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	query := svc.db.Where("repo_id = ? and soft_deleted_at = 0", repoID)
+	if path != "" {
+		query = query.Where("path = ?", path)
+	}
+	if id != "" {
+		query = query.Where("id = ?", id)
+	}
+	if cursor != "" {
+		query = query.Where("id >= ?", cursor)
+	}
+	var locks []LFSLock
+	err := query.Order("id").Limit(limit + 1).Find(&locks).Error
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(locks) > limit {
+		nextCursor = locks[limit].ID
+		locks = locks[:limit]
+	}
+	return locks, nextCursor, nil
+}
+
+// DeleteLFSLock unlocks the file, the lock of the others can only be forced to unlock by the repo managers
+func (svc *Service) DeleteLFSLock(repo *gitmodule.Repository, user *User, id string, force bool) (*LFSLock, error) {
+	var lock LFSLock
+	err := svc.db.Where("repo_id = ? and id = ? and soft_deleted_at = 0", repo.ID, id).First(&lock).Error
+	if err != nil {
+		return nil, err
+	}
+	if lock.OwnerID != user.Id {
+		if !force || svc.CheckPermission(repo, user, PermissionRepoSetting, nil) != nil {
+			return nil, ErrLFSLockOwner
+		}
+	}
+	if err = svc.db.Model(&lock).Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error; err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
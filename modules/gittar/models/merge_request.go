@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -64,6 +65,8 @@ type MergeQueryCondition struct {
 type MergeOptions struct {
 	RemoveSourceBranch bool   `json:"removeSourceBranch"`
 	CommitMessage      string `json:"CommitMessage"`
+	// MergeMethod is one of gitmodule.MergeMethods, default is merge commit
+	MergeMethod string `json:"mergeMethod"`
 }
 
 //MergeRequest model
@@ -91,6 +94,7 @@ type MergeRequest struct {
 	Score                int    `gorm:"size:150;index:idx_score"`
 	ScoreNum             int    `gorm:"size:150;index:idx_score_num"`
 	JoinTempBranchStatus string `gorm:"join_temp_branch_status"`
+	MergeMethod          string
 }
 
 type MrCheckRun struct {
@@ -125,6 +129,7 @@ func (mergeRequest *MergeRequest) ToInfo(repo *gitmodule.Repository) *apistructs
 	result.Score = mergeRequest.Score
 	result.ScoreNum = mergeRequest.ScoreNum
 	result.JoinTempBranchStatus = mergeRequest.JoinTempBranchStatus
+	result.MergeMethod = mergeRequest.MergeMethod
 
 	if mergeRequest.SourceBranch != "" && mergeRequest.TargetBranch != "" {
 		result.DefaultCommitMessage = fmt.Sprintf("Merge branch '%s' into '%s'", mergeRequest.SourceBranch, mergeRequest.TargetBranch)
@@ -209,6 +214,14 @@ func (svc *Service) CreateMergeRequest(repo *gitmodule.Repository, user *User, i
 	if err != nil {
 		return nil, err
 	}
+	err = svc.AddMrReviewers(repo, &mergeRequest, info.ReviewerIds, false)
+	if err != nil {
+		return nil, err
+	}
+	// CODEOWNERS 解析失败不影响 MR 创建
+	if err := svc.RequestMrCodeOwners(repo, &mergeRequest); err != nil {
+		logrus.Errorf("failed to request code owners of mr %d: %v", mergeRequest.ID, err)
+	}
 
 	info.RepoMergeId = mergeRequest.RepoMergeId
 	info.AuthorUser = &apistructs.UserInfoDto{
@@ -299,6 +312,16 @@ func (svc *Service) GetMergeRequestDetail(repo *gitmodule.Repository, mergeId in
 		}
 	}
 	result := mergeRequest.ToInfo(repo)
+	result.Reviewers, err = svc.ListReviewers(mergeRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mergeRequest.State == MERGE_REQUEST_OPEN {
+		result.Approval, err = svc.GetMrApproval(repo, &mergeRequest)
+		if err != nil {
+			logrus.Errorf("failed to get approval of mr %d: %v", mergeRequest.ID, err)
+		}
+	}
 	result.IsCheckRunValid, err = svc.IsCheckRunsValid(repo, mergeRequest.ID)
 	return result, err
 }
@@ -470,6 +493,28 @@ func (svc *Service) Merge(repo *gitmodule.Repository, user *User, mergeId int, m
 		return nil, errors.New("has conflict")
 	}
 
+	err = svc.checkMrApproval(repo, &mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if mergeOptions.MergeMethod == "" {
+		mergeOptions.MergeMethod = gitmodule.MergeMethodMerge
+	}
+	methods, err := svc.GetMergeMethods(repo)
+	if err != nil {
+		return nil, err
+	}
+	if !gitmodule.IsMergeMethodAllowed(methods, mergeOptions.MergeMethod) {
+		return nil, fmt.Errorf("merge method %s is not allowed, allowed methods: %s", mergeOptions.MergeMethod, strings.Join(methods, ","))
+	}
+	if !mergeStatus.Methods[mergeOptions.MergeMethod] {
+		return nil, fmt.Errorf("can not merge with method %s", mergeOptions.MergeMethod)
+	}
+	if err = svc.checkMergeSignatures(repo, &mergeRequest, mergeOptions.MergeMethod); err != nil {
+		return nil, err
+	}
+
 	if repo.IsProtectBranch(mergeRequest.TargetBranch) ||
 		(repo.IsProtectBranch(mergeRequest.SourceBranch) && mergeRequest.RemoveSourceBranch) {
 		err = svc.CheckPermission(repo, user, PermissionPushProtectBranch, nil)
@@ -486,7 +531,7 @@ func (svc *Service) Merge(repo *gitmodule.Repository, user *User, mergeId int, m
 		return nil, err
 	}
 
-	commit, err := repo.Merge(mergeRequest.SourceBranch, mergeRequest.TargetBranch, user.ToGitSignature(), mergeOptions.CommitMessage)
+	commit, err := repo.MergeWithMethod(mergeOptions.MergeMethod, mergeRequest.SourceBranch, mergeRequest.TargetBranch, user.ToGitSignature(), mergeOptions.CommitMessage)
 
 	now := time.Now()
 	if err == nil {
@@ -494,6 +539,7 @@ func (svc *Service) Merge(repo *gitmodule.Repository, user *User, mergeId int, m
 		mergeRequest.MergeCommitSha = commit.ID
 		mergeRequest.MergeAt = &now
 		mergeRequest.MergeUserId = user.Id
+		mergeRequest.MergeMethod = mergeOptions.MergeMethod
 		err := svc.db.Save(&mergeRequest).Error
 		if err != nil {
 			return nil, err
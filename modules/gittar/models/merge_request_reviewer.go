@@ -0,0 +1,330 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/gittar/pkg/codeowners"
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/uc"
+	"github.com/erda-project/erda/modules/pkg/diceworkspace"
+)
+
+// MergeRequestReviewer model
+type MergeRequestReviewer struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         int64
+	OrgName       string
+	RepoID        int64
+	MrID          int64
+	ReviewerID    string
+	State         apistructs.MrReviewState
+	IsCodeOwner   bool
+	Commit        string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (MergeRequestReviewer) TableName() string {
+	return "erda_repo_merge_request_reviewer"
+}
+
+func newMergeRequestReviewer(repo *gitmodule.Repository, mergeRequest *MergeRequest, reviewerID string) *MergeRequestReviewer {
+	return &MergeRequestReviewer{
+		ID:         uuid.New().String(),
+		OrgID:      repo.OrgId,
+		OrgName:    repo.OrgName,
+		RepoID:     mergeRequest.RepoID,
+		MrID:       mergeRequest.ID,
+		ReviewerID: reviewerID,
+		State:      apistructs.MrReviewStatePending,
+	}
+}
+
+func (reviewer *MergeRequestReviewer) ToInfo() *apistructs.MrReviewer {
+	result := &apistructs.MrReviewer{
+		ReviewerId:  reviewer.ReviewerID,
+		State:       reviewer.State,
+		IsCodeOwner: reviewer.IsCodeOwner,
+		Commit:      reviewer.Commit,
+		UpdatedAt:   reviewer.UpdatedAt,
+	}
+	dto, err := uc.FindUserByIdWithDesensitize(reviewer.ReviewerID)
+	if err == nil {
+		result.ReviewerUser = dto
+	} else {
+		logrus.Errorf("get user from uc error: %v", err)
+	}
+	return result
+}
+
+// MrReviewRequest is the review submitted by a reviewer
+type MrReviewRequest struct {
+	State apistructs.MrReviewState `json:"state"`
+	Note  string                   `json:"note"`
+}
+
+// MrReviewersRequest adds the reviewers to the merge request
+type MrReviewersRequest struct {
+	ReviewerIds []string `json:"reviewerIds"`
+}
+
+// GetCodeOwners parses the CODEOWNERS file of the branch, returns nil if not exist
+func (svc *Service) GetCodeOwners(repo *gitmodule.Repository, branch string) (*codeowners.CodeOwners, error) {
+	commit, err := repo.GetBranchCommit(branch)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range codeowners.Paths {
+		entry, err := repo.GetTreeEntryByPath(commit.ID, path)
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		reader, err := entry.Blob().Data()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return codeowners.Parse(string(content))
+	}
+	return nil, nil
+}
+
+// getMrCodeOwners returns the CODEOWNERS file of the target branch and the files changed by the merge request
+func (svc *Service) getMrCodeOwners(repo *gitmodule.Repository, mergeRequest *MergeRequest) (*codeowners.CodeOwners, []string, error) {
+	owners, err := svc.GetCodeOwners(repo, mergeRequest.TargetBranch)
+	if err != nil || owners == nil {
+		return nil, nil, err
+	}
+	files, err := repo.ChangedFiles(mergeRequest.TargetBranch, mergeRequest.SourceBranch)
+	if err != nil {
+		return nil, nil, err
+	}
+	return owners, files, nil
+}
+
+// AddMrReviewers requests the reviewers, the author and the existing reviewers are ignored
+func (svc *Service) AddMrReviewers(repo *gitmodule.Repository, mergeRequest *MergeRequest, reviewerIDs []string, isCodeOwner bool) error {
+	var reviewers []MergeRequestReviewer
+	err := svc.db.Where("mr_id = ? and soft_deleted_at = 0", mergeRequest.ID).Find(&reviewers).Error
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{mergeRequest.AuthorId: true}
+	for _, reviewer := range reviewers {
+		exists[reviewer.ReviewerID] = true
+	}
+	for _, reviewerID := range reviewerIDs {
+		if reviewerID == "" || exists[reviewerID] {
+			continue
+		}
+		exists[reviewerID] = true
+		reviewer := newMergeRequestReviewer(repo, mergeRequest, reviewerID)
+		reviewer.IsCodeOwner = isCodeOwner
+		err := svc.db.Create(reviewer).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestMrCodeOwners requests the owners of the changed files according to the CODEOWNERS of the target branch
+func (svc *Service) RequestMrCodeOwners(repo *gitmodule.Repository, mergeRequest *MergeRequest) error {
+	owners, files, err := svc.getMrCodeOwners(repo, mergeRequest)
+	if err != nil || owners == nil {
+		return err
+	}
+	return svc.AddMrReviewers(repo, mergeRequest, owners.OwnersOfFiles(files), true)
+}
+
+// GetMrReviewers lists the reviewers of the merge request
+func (svc *Service) GetMrReviewers(mrID int64) ([]MergeRequestReviewer, error) {
+	var reviewers []MergeRequestReviewer
+	err := svc.db.Where("mr_id = ? and soft_deleted_at = 0", mrID).Order("created_at").Find(&reviewers).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return reviewers, nil
+}
+
+// AddReviewers adds the reviewers to the merge request
+func (svc *Service) AddReviewers(repo *gitmodule.Repository, user *User, mergeId int, reviewerIDs []string) ([]*apistructs.MrReviewer, error) {
+	var mergeRequest MergeRequest
+	err := svc.db.Where("repo_id = ? and repo_merge_id = ?", repo.ID, mergeId).First(&mergeRequest).Error
+	if err != nil {
+		return nil, err
+	}
+	if mergeRequest.State != MERGE_REQUEST_OPEN {
+		return nil, errors.New(mergeRequest.State + " 状态无法添加评审人")
+	}
+	err = svc.CheckPermission(repo, user, PermissionEditMR, getMrUserRole(mergeRequest, user.Id))
+	if err != nil {
+		return nil, err
+	}
+	err = svc.AddMrReviewers(repo, &mergeRequest, reviewerIDs, false)
+	if err != nil {
+		return nil, err
+	}
+	return svc.ListReviewers(mergeRequest.ID)
+}
+
+// ListReviewers lists the reviewers of the merge request with the users
+func (svc *Service) ListReviewers(mrID int64) ([]*apistructs.MrReviewer, error) {
+	reviewers, err := svc.GetMrReviewers(mrID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apistructs.MrReviewer, 0, len(reviewers))
+	for i := range reviewers {
+		result = append(result, reviewers[i].ToInfo())
+	}
+	return result, nil
+}
+
+// Review approves or requests changes on the merge request, the reviewer is added if not requested
+func (svc *Service) Review(repo *gitmodule.Repository, user *User, mergeId int, request *MrReviewRequest) (*apistructs.MrReviewer, error) {
+	switch request.State {
+	case apistructs.MrReviewStateApproved, apistructs.MrReviewStateChangesRequested, apistructs.MrReviewStatePending:
+	default:
+		return nil, fmt.Errorf("invalid review state %s", request.State)
+	}
+	var mergeRequest MergeRequest
+	err := svc.db.Where("repo_id = ? and repo_merge_id = ?", repo.ID, mergeId).First(&mergeRequest).Error
+	if err != nil {
+		return nil, err
+	}
+	if mergeRequest.State != MERGE_REQUEST_OPEN {
+		return nil, errors.New(mergeRequest.State + " 状态无法评审")
+	}
+	if mergeRequest.AuthorId == user.Id {
+		return nil, errors.New("can not review your own merge request")
+	}
+
+	var reviewer MergeRequestReviewer
+	err = svc.db.Where("mr_id = ? and reviewer_id = ? and soft_deleted_at = 0", mergeRequest.ID, user.Id).First(&reviewer).Error
+	if err == gorm.ErrRecordNotFound {
+		// 未被邀请的用户需要有创建 MR 的权限才能评审
+		err = svc.CheckPermission(repo, user, PermissionCreateMR, nil)
+		if err != nil {
+			return nil, err
+		}
+		reviewer = *newMergeRequestReviewer(repo, &mergeRequest, user.Id)
+	} else if err != nil {
+		return nil, err
+	}
+	reviewer.State = request.State
+	reviewer.Commit = mergeRequest.SourceSha
+	if commit, err := repo.GetBranchCommit(mergeRequest.SourceBranch); err == nil {
+		reviewer.Commit = commit.ID
+	}
+	err = svc.db.Save(&reviewer).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Note != "" {
+		_, err = svc.CreateNote(repo, user, mergeRequest.ID, NoteRequest{Note: request.Note, Type: NoteTypeNormal})
+		if err != nil {
+			logrus.Errorf("failed to create review note of mr %d: %v", mergeRequest.ID, err)
+		}
+	}
+	return reviewer.ToInfo(), nil
+}
+
+// GetMrApproval checks the reviews against the rule of the target branch,
+// only the approvals of the current head of the source branch are counted
+func (svc *Service) GetMrApproval(repo *gitmodule.Repository, mergeRequest *MergeRequest) (*apistructs.MrApprovalInfo, error) {
+	rules, err := svc.bundle.GetAppBranchRules(uint64(repo.ApplicationId))
+	if err != nil {
+		return nil, err
+	}
+	rule := diceworkspace.GetValidBranchByGitReference(mergeRequest.TargetBranch, rules)
+	info := &apistructs.MrApprovalInfo{
+		RequiredApprovals:        rule.RequiredApprovals,
+		RequireCodeOwnerApproval: rule.RequireCodeOwnerApproval,
+		StaleApprovals:           []string{},
+		ChangesRequested:         []string{},
+		MissingCodeOwners:        []string{},
+	}
+	head := mergeRequest.SourceSha
+	if commit, err := repo.GetBranchCommit(mergeRequest.SourceBranch); err == nil {
+		head = commit.ID
+	}
+
+	reviewers, err := svc.GetMrReviewers(mergeRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+	approvers := make(map[string]bool)
+	for _, reviewer := range reviewers {
+		switch reviewer.State {
+		case apistructs.MrReviewStateApproved:
+			if reviewer.Commit != head {
+				info.StaleApprovals = append(info.StaleApprovals, reviewer.ReviewerID)
+				continue
+			}
+			approvers[reviewer.ReviewerID] = true
+			info.Approvals++
+		case apistructs.MrReviewStateChangesRequested:
+			info.ChangesRequested = append(info.ChangesRequested, reviewer.ReviewerID)
+		}
+	}
+
+	if rule.RequireCodeOwnerApproval {
+		owners, files, err := svc.getMrCodeOwners(repo, mergeRequest)
+		if err != nil {
+			return nil, err
+		}
+		if owners != nil {
+			info.MissingCodeOwners = owners.MissingOwners(files, approvers)
+		}
+	}
+	info.Approved = info.Approvals >= info.RequiredApprovals &&
+		len(info.ChangesRequested) <= 0 &&
+		len(info.MissingCodeOwners) <= 0
+	return info, nil
+}
+
+// checkMrApproval returns error if the merge request is not approved as the rule of the target branch requires
+func (svc *Service) checkMrApproval(repo *gitmodule.Repository, mergeRequest *MergeRequest) error {
+	info, err := svc.GetMrApproval(repo, mergeRequest)
+	if err != nil {
+		return err
+	}
+	if info.Approved {
+		return nil
+	}
+	if len(info.ChangesRequested) > 0 {
+		return fmt.Errorf("changes requested by reviewers: %v", info.ChangesRequested)
+	}
+	if len(info.MissingCodeOwners) > 0 {
+		return fmt.Errorf("approval from code owners required: %v", info.MissingCodeOwners)
+	}
+	return fmt.Errorf("%d approvals required, got %d", info.RequiredApprovals, info.Approvals)
+}
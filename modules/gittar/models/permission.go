@@ -43,3 +43,21 @@ func (svc *Service) CheckPermission(repo *gitmodule.Repository, user *User, perm
 	}
 	return nil
 }
+
+// CheckOrgPermission returns error if the user can not manage the org of the repo, the system admin is allowed too
+func (svc *Service) CheckOrgPermission(repo *gitmodule.Repository, user *User) error {
+	checkPermission, err := svc.bundle.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   user.Id,
+		Scope:    apistructs.OrgScope,
+		ScopeID:  uint64(repo.OrgId),
+		Resource: apistructs.OrgResource,
+		Action:   apistructs.UpdateAction,
+	})
+	if err != nil {
+		return err
+	}
+	if !checkPermission.Access {
+		return fmt.Errorf("no permission to manage org %s for user: %s", repo.OrgName, user.NickName)
+	}
+	return nil
+}
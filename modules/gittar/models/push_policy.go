@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/pkg/pushpolicy"
+)
+
+// PushPolicy model, the push rules of the repo
+type PushPolicy struct {
+	ID                   string `gorm:"primary_key"`
+	OrgID                int64
+	OrgName              string
+	RepoID               int64
+	Enable               bool `gorm:"column:is_enabled"`
+	CommitMessagePattern string
+	IssueRefRequired     bool `gorm:"column:is_issue_ref_required"`
+	RequireSignedOffBy   bool `gorm:"column:is_signed_off_by_required"`
+	MaxFileSize          int64
+	ForbiddenExtensions  string // 逗号分隔
+	SecretScan           bool   `gorm:"column:is_secret_scan_enabled"`
+	UpdatedBy            string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	SoftDeletedAt        uint64
+}
+
+func (PushPolicy) TableName() string {
+	return "erda_repo_push_policy"
+}
+
+func (p *PushPolicy) ToPolicy() *pushpolicy.Policy {
+	policy := &pushpolicy.Policy{
+		Enable:               p.Enable,
+		CommitMessagePattern: p.CommitMessagePattern,
+		IssueRefRequired:     p.IssueRefRequired,
+		RequireSignedOffBy:   p.RequireSignedOffBy,
+		MaxFileSize:          p.MaxFileSize,
+		ForbiddenExtensions:  []string{},
+		SecretScan:           p.SecretScan,
+	}
+	for _, ext := range strings.Split(p.ForbiddenExtensions, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			policy.ForbiddenExtensions = append(policy.ForbiddenExtensions, ext)
+		}
+	}
+	return policy
+}
+
+// GetPushPolicy returns the push rules of the repo, disabled if not set
+func (svc *Service) GetPushPolicy(repo *gitmodule.Repository) (*pushpolicy.Policy, error) {
+	var policy PushPolicy
+	err := svc.db.Where("repo_id = ? and soft_deleted_at = 0", repo.ID).First(&policy).Error
+	if err == gorm.ErrRecordNotFound {
+		return &pushpolicy.Policy{ForbiddenExtensions: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy.ToPolicy(), nil
+}
+
+// SetPushPolicy updates the push rules of the repo
+func (svc *Service) SetPushPolicy(repo *gitmodule.Repository, user *User, request *pushpolicy.Policy) (*pushpolicy.Policy, error) {
+	if err := svc.CheckPermission(repo, user, PermissionRepoSetting, nil); err != nil {
+		return nil, err
+	}
+	if request.MaxFileSize < 0 {
+		return nil, errors.New("invalid max file size")
+	}
+	if _, err := pushpolicy.NewChecker(request, nil); err != nil {
+		return nil, err
+	}
+
+	var policy PushPolicy
+	err := svc.db.Where("repo_id = ? and soft_deleted_at = 0", repo.ID).First(&policy).Error
+	if err == gorm.ErrRecordNotFound {
+		policy.ID = uuid.New().String()
+		policy.OrgID = repo.OrgId
+		policy.OrgName = repo.OrgName
+	} else if err != nil {
+		return nil, err
+	}
+	policy.RepoID = repo.ID
+	policy.Enable = request.Enable
+	policy.CommitMessagePattern = request.CommitMessagePattern
+	policy.IssueRefRequired = request.IssueRefRequired
+	policy.RequireSignedOffBy = request.RequireSignedOffBy
+	policy.MaxFileSize = request.MaxFileSize
+	policy.ForbiddenExtensions = strings.Join(request.ForbiddenExtensions, ",")
+	policy.SecretScan = request.SecretScan
+	policy.UpdatedBy = user.Id
+	err = svc.db.Save(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return policy.ToPolicy(), nil
+}
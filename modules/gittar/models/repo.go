@@ -20,6 +20,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -46,6 +47,10 @@ type Repo struct {
 	Size        int64
 	IsExternal  bool
 	Config      string
+	// MergeMethods are the comma separated merge methods allowed, all methods are allowed if it's empty
+	MergeMethods string
+	// LFSQuota is the max size of the lfs objects in bytes, the default quota is used if it's 0
+	LFSQuota int64
 }
 
 func (Repo) TableName() string {
@@ -142,6 +147,35 @@ func (svc *Service) SetLocked(repo *gitmodule.Repository, user *User, info *apis
 	return info, nil
 }
 
+// GetMergeMethods returns the merge methods allowed by the repo.
+func (svc *Service) GetMergeMethods(repo *gitmodule.Repository) ([]string, error) {
+	var currentRepo Repo
+	err := svc.db.Table("dice_repos").Where("id = ?", repo.ID).First(&currentRepo).Error
+	if err != nil {
+		return nil, err
+	}
+	return gitmodule.ParseMergeMethods(currentRepo.MergeMethods)
+}
+
+// SetMergeMethods sets the merge methods allowed by the repo.
+func (svc *Service) SetMergeMethods(repo *gitmodule.Repository, user *User, methods []string) ([]string, error) {
+	if err := svc.CheckPermission(repo, user, PermissionRepoSetting, nil); err != nil {
+		return nil, err
+	}
+	if len(methods) <= 0 {
+		return nil, errors.New("at least one merge method is required")
+	}
+	methods, err := gitmodule.ParseMergeMethods(strings.Join(methods, ","))
+	if err != nil {
+		return nil, err
+	}
+	err = svc.db.Table("dice_repos").Where("id = ?", repo.ID).Update("merge_methods", strings.Join(methods, ",")).Error
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
 func (svc *Service) DeleteRepo(repo *Repo) error {
 	repoPath := repo.DiskPath()
 	logrus.Infof("remove gitRepo %v", repoPath)
@@ -168,7 +202,16 @@ func (svc *Service) DeleteRepo(repo *Repo) error {
 		return err
 	}
 	err = svc.RemoveMR(repo)
-	return err
+	if err != nil {
+		return err
+	}
+	err = svc.db.Model(&RepoMirror{}).Where("repo_id = ? and soft_deleted_at = 0", repo.ID).
+		Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+	if err != nil {
+		return err
+	}
+	svc.RemoveSearchIndex(repo.ID)
+	return svc.DeleteLFSObjects(repo.ID)
 }
 
 func (svc *Service) UpdateRepoSizeCache(id int64, size int64) error {
@@ -0,0 +1,413 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/conf"
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/pkg/mirror"
+)
+
+// RepoMirror model, the external repository to pull from or push to
+type RepoMirror struct {
+	ID            string `gorm:"primary_key"`
+	OrgID         int64
+	OrgName       string
+	RepoID        int64
+	Direction     mirror.Direction
+	RemoteURL     string
+	Username      string
+	Password      string // encrypted by GITTAR_MIRROR_SECRET_KEY
+	SyncInterval  int64  // 拉取间隔, 单位分钟
+	Enable        bool   `gorm:"column:is_enabled"`
+	Status        mirror.Status
+	LastError     string
+	LastSyncAt    *time.Time `gorm:"default:'1970-01-01 00:00:00'"`
+	LastSuccessAt *time.Time `gorm:"default:'1970-01-01 00:00:00'"`
+	CreatedBy     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SoftDeletedAt uint64
+}
+
+func (RepoMirror) TableName() string {
+	return "erda_repo_mirror"
+}
+
+// AfterFind hides the default sync time of the mirrors never synced
+func (m *RepoMirror) AfterFind() error {
+	if m.LastSyncAt != nil && m.LastSyncAt.Year() <= 1970 {
+		m.LastSyncAt = nil
+	}
+	if m.LastSuccessAt != nil && m.LastSuccessAt.Year() <= 1970 {
+		m.LastSuccessAt = nil
+	}
+	return nil
+}
+
+// ErrPullMirrorReadOnly is returned when writing to the repo pulled from a mirror
+var ErrPullMirrorReadOnly = errors.New("the repo is pulled from a mirror and read-only, disable the pull mirror to write")
+
+// RepoMirrorInfo is the mirror without the credentials
+type RepoMirrorInfo struct {
+	ID            string           `json:"id"`
+	Direction     mirror.Direction `json:"direction"`
+	RemoteURL     string           `json:"remoteUrl"`
+	Username      string           `json:"username"`
+	SyncInterval  int64            `json:"syncInterval"`
+	Enable        bool             `json:"enable"`
+	Status        mirror.Status    `json:"status"`
+	LastError     string           `json:"lastError"`
+	LastSyncAt    *time.Time       `json:"lastSyncAt"`
+	LastSuccessAt *time.Time       `json:"lastSuccessAt"`
+	CreatedBy     string           `json:"createdBy"`
+	CreatedAt     time.Time        `json:"createdAt"`
+}
+
+// RepoMirrorRequest creates or updates the mirror, the password is kept if it's empty when updating,
+// unless the remote url is changed, so that the credentials are never sent to another remote
+type RepoMirrorRequest struct {
+	Direction    mirror.Direction `json:"direction"`
+	RemoteURL    string           `json:"remoteUrl"`
+	Username     string           `json:"username"`
+	Password     string           `json:"password"`
+	SyncInterval int64            `json:"syncInterval"`
+	Enable       *bool            `json:"enable"`
+}
+
+func (m *RepoMirror) ToInfo() *RepoMirrorInfo {
+	return &RepoMirrorInfo{
+		ID:            m.ID,
+		Direction:     m.Direction,
+		RemoteURL:     (&mirror.Remote{URL: m.RemoteURL}).String(),
+		Username:      m.Username,
+		SyncInterval:  m.SyncInterval,
+		Enable:        m.Enable,
+		Status:        m.Status,
+		LastError:     m.LastError,
+		LastSyncAt:    m.LastSyncAt,
+		LastSuccessAt: m.LastSuccessAt,
+		CreatedBy:     m.CreatedBy,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+// remote returns the remote with the decrypted password
+func (m *RepoMirror) remote() (*mirror.Remote, error) {
+	password, err := mirror.DecryptPassword(m.Password, conf.MirrorSecretKey())
+	if err != nil {
+		return nil, err
+	}
+	return &mirror.Remote{
+		URL:        m.RemoteURL,
+		Username:   m.Username,
+		Password:   password,
+		AllowLocal: conf.MirrorAllowLocal(),
+	}, nil
+}
+
+// isDue returns true if it's time to pull the mirror
+func (m *RepoMirror) isDue(now time.Time) bool {
+	if !m.Enable || m.Direction != mirror.DirectionPull {
+		return false
+	}
+	return m.LastSyncAt == nil || now.Sub(*m.LastSyncAt) >= time.Duration(m.SyncInterval)*time.Minute
+}
+
+// ListRepoMirrors lists the mirrors of the repo
+func (svc *Service) ListRepoMirrors(repo *gitmodule.Repository) ([]*RepoMirrorInfo, error) {
+	var mirrors []RepoMirror
+	err := svc.db.Where("repo_id = ? and soft_deleted_at = 0", repo.ID).Order("created_at").Find(&mirrors).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RepoMirrorInfo, 0, len(mirrors))
+	for i := range mirrors {
+		result = append(result, mirrors[i].ToInfo())
+	}
+	return result, nil
+}
+
+// CreateRepoMirror adds the mirror to the repo, a repo can only pull from one remote
+func (svc *Service) CreateRepoMirror(repo *gitmodule.Repository, user *User, request *RepoMirrorRequest) (*RepoMirrorInfo, error) {
+	if err := svc.CheckPermission(repo, user, PermissionRepoSetting, nil); err != nil {
+		return nil, err
+	}
+	if request.Direction != mirror.DirectionPull && request.Direction != mirror.DirectionPush {
+		return nil, errors.New("invalid mirror direction " + string(request.Direction))
+	}
+	if request.Direction == mirror.DirectionPull {
+		var count int64
+		err := svc.db.Model(&RepoMirror{}).Where("repo_id = ? and direction = ? and soft_deleted_at = 0", repo.ID, mirror.DirectionPull).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.New("pull mirror already exists")
+		}
+	}
+	m := &RepoMirror{
+		ID:        uuid.New().String(),
+		OrgID:     repo.OrgId,
+		OrgName:   repo.OrgName,
+		RepoID:    repo.ID,
+		Direction: request.Direction,
+		Enable:    true,
+		Status:    mirror.StatusPending,
+		CreatedBy: user.Id,
+	}
+	if err := m.update(request); err != nil {
+		return nil, err
+	}
+	if err := svc.db.Create(m).Error; err != nil {
+		return nil, err
+	}
+	// 创建后 gorm 会回填同步时间的默认值
+	m.LastSyncAt, m.LastSuccessAt = nil, nil
+	return m.ToInfo(), nil
+}
+
+func (m *RepoMirror) update(request *RepoMirrorRequest) error {
+	if request.RemoteURL != "" {
+		// 修改远程地址时需要重新填写密码, 不能把原来的密码发给新的地址
+		if request.RemoteURL != m.RemoteURL {
+			m.Password = ""
+		}
+		m.RemoteURL = request.RemoteURL
+		m.Username = request.Username
+	}
+	if request.Password != "" {
+		password, err := mirror.EncryptPassword(request.Password, conf.MirrorSecretKey())
+		if err != nil {
+			return err
+		}
+		m.Password = password
+	}
+	if request.Enable != nil {
+		m.Enable = *request.Enable
+	}
+	m.SyncInterval = request.SyncInterval
+	if m.SyncInterval <= 0 {
+		m.SyncInterval = conf.MirrorInterval()
+	}
+	remote := &mirror.Remote{URL: m.RemoteURL, AllowLocal: conf.MirrorAllowLocal()}
+	return remote.Validate()
+}
+
+func (svc *Service) getRepoMirror(repo *gitmodule.Repository, user *User, id string) (*RepoMirror, error) {
+	if err := svc.CheckPermission(repo, user, PermissionRepoSetting, nil); err != nil {
+		return nil, err
+	}
+	var m RepoMirror
+	err := svc.db.Where("repo_id = ? and id = ? and soft_deleted_at = 0", repo.ID, id).First(&m).Error
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpdateRepoMirror updates the remote, the credentials, the interval or enables the mirror
+func (svc *Service) UpdateRepoMirror(repo *gitmodule.Repository, user *User, id string, request *RepoMirrorRequest) (*RepoMirrorInfo, error) {
+	m, err := svc.getRepoMirror(repo, user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.update(request); err != nil {
+		return nil, err
+	}
+	if err = svc.db.Save(m).Error; err != nil {
+		return nil, err
+	}
+	return m.ToInfo(), nil
+}
+
+// DeleteRepoMirror removes the mirror
+func (svc *Service) DeleteRepoMirror(repo *gitmodule.Repository, user *User, id string) error {
+	m, err := svc.getRepoMirror(repo, user, id)
+	if err != nil {
+		return err
+	}
+	return svc.db.Model(m).Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+}
+
+// CheckPullMirror returns ErrPullMirrorReadOnly if the repo is pulled from an enabled mirror,
+// the fetch overwrites and prunes the branches, so the changes pushed or merged would be lost
+func (svc *Service) CheckPullMirror(repo *gitmodule.Repository) error {
+	var count int64
+	err := svc.db.Model(&RepoMirror{}).
+		Where("repo_id = ? and direction = ? and is_enabled = ? and soft_deleted_at = 0", repo.ID, mirror.DirectionPull, true).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPullMirrorReadOnly
+	}
+	return nil
+}
+
+// SyncRepoMirror starts syncing the mirror in the background, the status is syncing until it's done
+func (svc *Service) SyncRepoMirror(repo *gitmodule.Repository, user *User, id string) (*RepoMirrorInfo, error) {
+	m, err := svc.getRepoMirror(repo, user, id)
+	if err != nil {
+		return nil, err
+	}
+	m.Status = mirror.StatusSyncing
+	go svc.syncMirror(m, repo.DiskPath())
+	return m.ToInfo(), nil
+}
+
+// SyncPushMirrors pushes the repo to all the enabled push mirrors
+func (svc *Service) SyncPushMirrors(repo *gitmodule.Repository) {
+	var mirrors []RepoMirror
+	err := svc.db.Where("repo_id = ? and direction = ? and is_enabled = ? and soft_deleted_at = 0", repo.ID, mirror.DirectionPush, true).Find(&mirrors).Error
+	if err != nil {
+		logrus.Errorf("failed to get push mirrors of repo %d: %v", repo.ID, err)
+		return
+	}
+	for i := range mirrors {
+		svc.syncMirror(&mirrors[i], repo.DiskPath())
+	}
+}
+
+// mirrorSyncs records the running syncs, the value is true if another sync is requested while running
+var mirrorSyncs = struct {
+	sync.Mutex
+	running map[string]bool
+}{running: make(map[string]bool)}
+
+// syncMirror syncs the mirror, the sync requested while running is merged into one more sync after it's done
+func (svc *Service) syncMirror(m *RepoMirror, repoPath string) {
+	mirrorSyncs.Lock()
+	if _, ok := mirrorSyncs.running[m.ID]; ok {
+		mirrorSyncs.running[m.ID] = true
+		mirrorSyncs.Unlock()
+		return
+	}
+	mirrorSyncs.running[m.ID] = false
+	mirrorSyncs.Unlock()
+
+	for {
+		svc.doSyncMirror(m, repoPath)
+
+		mirrorSyncs.Lock()
+		again := mirrorSyncs.running[m.ID]
+		if !again {
+			delete(mirrorSyncs.running, m.ID)
+			mirrorSyncs.Unlock()
+			return
+		}
+		mirrorSyncs.running[m.ID] = false
+		mirrorSyncs.Unlock()
+	}
+}
+
+func (svc *Service) doSyncMirror(m *RepoMirror, repoPath string) {
+	now := time.Now()
+	svc.db.Model(m).UpdateColumns(map[string]interface{}{
+		"status":       mirror.StatusSyncing,
+		"last_sync_at": now,
+	})
+
+	remote, err := m.remote()
+	if err == nil {
+		if m.Direction == mirror.DirectionPull {
+			err = mirror.Fetch(context.Background(), repoPath, remote)
+		} else {
+			err = mirror.Push(context.Background(), repoPath, remote)
+		}
+	}
+
+	columns := map[string]interface{}{
+		"status":     mirror.StatusSuccess,
+		"last_error": "",
+	}
+	if err != nil {
+		logrus.Errorf("failed to sync mirror %s of repo %d: %v", m.ID, m.RepoID, err)
+		columns["status"] = mirror.StatusFailed
+		columns["last_error"] = err.Error()
+	} else {
+		columns["last_success_at"] = time.Now()
+		if m.Direction == mirror.DirectionPull {
+			svc.UpdateSearchIndex(m.RepoID, repoPath)
+		}
+	}
+	if err := svc.db.Model(m).UpdateColumns(columns).Error; err != nil {
+		logrus.Errorf("failed to update status of mirror %s: %v", m.ID, err)
+	}
+}
+
+// encryptMirrorPasswords encrypts the passwords stored in plain text by the earlier versions
+func (svc *Service) encryptMirrorPasswords() {
+	var mirrors []RepoMirror
+	if err := svc.db.Where("password != '' and soft_deleted_at = 0").Find(&mirrors).Error; err != nil {
+		logrus.Errorf("failed to get mirrors: %v", err)
+		return
+	}
+	for i := range mirrors {
+		if mirror.IsEncryptedPassword(mirrors[i].Password) {
+			continue
+		}
+		password, err := mirror.EncryptPassword(mirrors[i].Password, conf.MirrorSecretKey())
+		if err != nil {
+			logrus.Errorf("failed to encrypt password of mirror %s: %v", mirrors[i].ID, err)
+			return
+		}
+		err = svc.db.Model(&mirrors[i]).UpdateColumn("password", password).Error
+		if err != nil {
+			logrus.Errorf("failed to encrypt password of mirror %s: %v", mirrors[i].ID, err)
+		}
+	}
+}
+
+// StartMirrorScheduler pulls the due pull mirrors every minute
+func StartMirrorScheduler(db *DBClient) {
+	svc := NewService(db, nil)
+	svc.encryptMirrorPasswords()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var mirrors []RepoMirror
+		err := svc.db.Where("direction = ? and is_enabled = ? and soft_deleted_at = 0", mirror.DirectionPull, true).Find(&mirrors).Error
+		if err != nil {
+			logrus.Errorf("failed to get pull mirrors: %v", err)
+			continue
+		}
+		for i := range mirrors {
+			if !mirrors[i].isDue(now) {
+				continue
+			}
+			var repo Repo
+			err := svc.db.Where("id = ?", mirrors[i].RepoID).First(&repo).Error
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				logrus.Errorf("failed to get repo %d of mirror %s: %v", mirrors[i].RepoID, mirrors[i].ID, err)
+				continue
+			}
+			go svc.syncMirror(&mirrors[i], repo.DiskPath())
+		}
+	}
+}
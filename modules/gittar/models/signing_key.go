@@ -0,0 +1,323 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/gitmodule"
+	"github.com/erda-project/erda/modules/gittar/pkg/signature"
+)
+
+// verificationCache caches the verification by the repo and object id, flushed when the signing keys changed
+var verificationCache = cache.New(10*time.Minute, 20*time.Minute)
+
+// SigningKey is the gpg or ssh public key used to verify the signatures of the user
+type SigningKey struct {
+	ID          string `json:"id" gorm:"primary_key"`
+	OrgID       int64  `json:"orgId"`
+	OrgName     string `json:"orgName"`
+	UserID      string `json:"userId"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`
+	Fingerprint string `json:"fingerprint"`
+	// KeyIDs are the gpg key ids of the primary key and subkeys, separated by comma
+	KeyIDs string `json:"keyIds" gorm:"column:key_ids"`
+	// Emails are the emails the key can sign for, separated by comma
+	Emails        string    `json:"emails"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	SoftDeletedAt uint64    `json:"-"`
+}
+
+func (SigningKey) TableName() string {
+	return "erda_repo_signing_key"
+}
+
+// SigningKeyRequest .
+type SigningKeyRequest struct {
+	Title string `json:"title"`
+	Key   string `json:"key"`
+
+	// 添加密钥时所在的组织
+	OrgID   int64  `json:"-"`
+	OrgName string `json:"-"`
+}
+
+func (k *SigningKey) toKey() *signature.Key {
+	return &signature.Key{
+		Type:        signature.KeyType(k.Type),
+		Fingerprint: k.Fingerprint,
+		KeyIDs:      strings.Split(k.KeyIDs, ","),
+		Emails:      strings.Split(k.Emails, ","),
+		Content:     k.Content,
+		Owner:       k.UserID,
+	}
+}
+
+// AddSigningKey adds the armored gpg public key or the ssh public key, the key only signs for the email of the user,
+// so the gpg key is rejected if none of its identities matches the email
+func (svc *Service) AddSigningKey(user *User, request *SigningKeyRequest) (*SigningKey, error) {
+	key, err := signature.ParseKey(request.Key)
+	if err != nil {
+		return nil, err
+	}
+	email := user.Email
+	if email == "" && svc.bundle != nil {
+		info, err := svc.bundle.GetCurrentUser(user.Id)
+		if err != nil {
+			return nil, err
+		}
+		email = info.Email
+	}
+	if email == "" {
+		return nil, errors.New("the email of the user is required to add a signing key")
+	}
+	if key.Type != signature.KeyTypeSSH {
+		var matched bool
+		for _, e := range key.Emails {
+			if strings.EqualFold(e, email) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no identity of the gpg key matches your email %s", email)
+		}
+	}
+	key.Emails = []string{email}
+
+	var count int
+	err = svc.db.Model(&SigningKey{}).Where("fingerprint = ? and soft_deleted_at = 0", key.Fingerprint).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("the key is already in use")
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		title = key.Emails[0]
+	}
+	signingKey := &SigningKey{
+		ID:          uuid.New().String(),
+		OrgID:       request.OrgID,
+		OrgName:     request.OrgName,
+		UserID:      user.Id,
+		Type:        string(key.Type),
+		Title:       title,
+		Content:     key.Content,
+		Fingerprint: key.Fingerprint,
+		KeyIDs:      strings.Join(key.KeyIDs, ","),
+		Emails:      strings.Join(key.Emails, ","),
+	}
+	err = svc.db.Create(signingKey).Error
+	if err != nil {
+		return nil, err
+	}
+	verificationCache.Flush()
+	return signingKey, nil
+}
+
+// ListSigningKeys .
+func (svc *Service) ListSigningKeys(user *User) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	err := svc.db.Where("user_id = ? and soft_deleted_at = 0", user.Id).Order("created_at desc").Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeleteSigningKey deletes the signing key of the user, the signatures of the key become unverified
+func (svc *Service) DeleteSigningKey(user *User, id string) error {
+	var key SigningKey
+	err := svc.db.Where("id = ? and user_id = ? and soft_deleted_at = 0", id, user.Id).First(&key).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("key %s not found", id)
+		}
+		return err
+	}
+	err = svc.db.Model(&key).Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+	if err != nil {
+		return err
+	}
+	verificationCache.Flush()
+	return nil
+}
+
+func (svc *Service) lookupSigningKey(keyType signature.KeyType, keyID string) (*signature.Key, error) {
+	var keys []*SigningKey
+	err := svc.db.Where("type = ? and key_ids like ? and soft_deleted_at = 0", keyType, "%"+keyID+"%").Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	for _, k := range keys {
+		for _, id := range strings.Split(k.KeyIDs, ",") {
+			if id == keyID {
+				return k.toKey(), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// VerifyObject verifies the signature of the commit or tag object, typ is commit or tag
+func (svc *Service) VerifyObject(repo *gitmodule.Repository, typ, id string) (*signature.Verification, error) {
+	cacheKey := repo.DiskPath() + "/" + id
+	if v, ok := verificationCache.Get(cacheKey); ok {
+		return v.(*signature.Verification), nil
+	}
+	raw, err := repo.CatFile(typ, id)
+	if err != nil {
+		return nil, err
+	}
+	var o *signature.SignedObject
+	if typ == "tag" {
+		o = signature.ParseTag(raw)
+	} else {
+		o = signature.ParseCommit(raw)
+	}
+	v, err := svc.VerifySignature(o)
+	if err != nil {
+		return nil, err
+	}
+	verificationCache.SetDefault(cacheKey, v)
+	return v, nil
+}
+
+// VerifySignature verifies the signature of the object with the signing keys of the users
+func (svc *Service) VerifySignature(o *signature.SignedObject) (*signature.Verification, error) {
+	return signature.Verify(o, svc.lookupSigningKey)
+}
+
+// VerifyCommits fills the verification of the commits
+func (svc *Service) VerifyCommits(repo *gitmodule.Repository, commits ...*gitmodule.Commit) {
+	for _, commit := range commits {
+		if commit == nil {
+			continue
+		}
+		v, err := svc.VerifyObject(repo, "commit", commit.ID)
+		if err != nil {
+			logrus.Errorf("failed to verify commit %s of %s: %v", commit.ID, repo.DiskPath(), err)
+			continue
+		}
+		commit.Verification = v
+	}
+}
+
+// VerifyTags fills the verification of the tags, the lightweight tag is verified by the commit
+func (svc *Service) VerifyTags(repo *gitmodule.Repository, tags ...*gitmodule.Tag) {
+	for _, tag := range tags {
+		typ := "tag"
+		if tag.ID == tag.Object {
+			typ = "commit"
+		}
+		v, err := svc.VerifyObject(repo, typ, tag.Object)
+		if err != nil {
+			logrus.Errorf("failed to verify tag %s of %s: %v", tag.Name, repo.DiskPath(), err)
+			continue
+		}
+		tag.Verification = v
+	}
+}
+
+// ErrUnsignedCommit is returned when writing the commits created by gittar to the branch requires signed commits,
+// these commits are not signed
+var ErrUnsignedCommit = errors.New("the branch requires signed commits, the commits created on the web are not signed")
+
+// CheckUnsignedCommit returns ErrUnsignedCommit if the branch requires signed commits
+func (svc *Service) CheckUnsignedCommit(repo *gitmodule.Repository, branch string) error {
+	required, err := repo.RequireSignedCommits(branch)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrUnsignedCommit
+	}
+	return nil
+}
+
+// checkMergeSignatures returns error if the target branch requires signed commits but the merge writes the commits not verified,
+// only the fast-forward merge keeps the signed commits of the source branch, the other methods create new commits
+func (svc *Service) checkMergeSignatures(repo *gitmodule.Repository, mergeRequest *MergeRequest, method string) error {
+	required, err := repo.RequireSignedCommits(mergeRequest.TargetBranch)
+	if err != nil {
+		return err
+	}
+	if !required {
+		return nil
+	}
+	if method != gitmodule.MergeMethodFastForward {
+		return fmt.Errorf("the branch %s requires signed commits, only the fast-forward merge is allowed", mergeRequest.TargetBranch)
+	}
+	commits, err := svc.mrCommits(repo, mergeRequest)
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		v, err := svc.VerifyObject(repo, "commit", commit.ID)
+		if err != nil {
+			return err
+		}
+		if !v.Verified {
+			return fmt.Errorf("the branch %s requires signed commits, commit %s is not verified: %s",
+				mergeRequest.TargetBranch, commit.ID, v.Reason)
+		}
+	}
+	return nil
+}
+
+// mrCommits returns the commits of the source branch to merge into the target branch
+func (svc *Service) mrCommits(repo *gitmodule.Repository, mergeRequest *MergeRequest) ([]*gitmodule.Commit, error) {
+	source, target := mergeRequest.SourceSha, mergeRequest.TargetSha
+	if mergeRequest.State == MERGE_REQUEST_OPEN {
+		sourceCommit, err := repo.GetBranchCommit(mergeRequest.SourceBranch)
+		if err != nil {
+			return nil, err
+		}
+		targetCommit, err := repo.GetBranchCommit(mergeRequest.TargetBranch)
+		if err != nil {
+			return nil, err
+		}
+		source, target = sourceCommit.ID, targetCommit.ID
+	}
+	return repo.CommitsAhead(target, source)
+}
+
+// GetMrCommits lists the commits of the merge request with the verification of the signatures
+func (svc *Service) GetMrCommits(repo *gitmodule.Repository, mergeId int) ([]*gitmodule.Commit, error) {
+	var mergeRequest MergeRequest
+	err := svc.db.Where("repo_id = ? and repo_merge_id = ?", repo.ID, mergeId).First(&mergeRequest).Error
+	if err != nil {
+		return nil, err
+	}
+	commits, err := svc.mrCommits(repo, &mergeRequest)
+	if err != nil {
+		return nil, err
+	}
+	svc.VerifyCommits(repo, commits...)
+	return commits, nil
+}
@@ -38,6 +38,7 @@ const (
 	PermissionPushProtectBranch      Permission = "PUSH_PROTECT_BRANCH"
 	PermissionPushProtectBranchForce Permission = "PUSH_PROTECT_BRANCH_FORCE"
 	PermissionRepoLocked             Permission = "REPO_LOCKED"
+	PermissionRepoSetting            Permission = "REPO_SETTING"
 )
 
 var NO_PERMISSION_ERROR = errors.New("no permission")
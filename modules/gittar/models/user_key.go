@@ -0,0 +1,143 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/sshserver"
+)
+
+// UserKey is the ssh public key of the user
+type UserKey struct {
+	ID            string     `json:"id" gorm:"primary_key"`
+	OrgID         int64      `json:"orgId"`
+	OrgName       string     `json:"orgName"`
+	UserID        string     `json:"userId"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	Fingerprint   string     `json:"fingerprint"`
+	LastUsedAt    *time.Time `json:"lastUsedAt" gorm:"default:'1970-01-01 00:00:00'"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	SoftDeletedAt uint64     `json:"-"`
+}
+
+func (UserKey) TableName() string {
+	return "erda_repo_user_key"
+}
+
+// AfterFind hides the default last used time of the keys never used
+func (key *UserKey) AfterFind() error {
+	if key.LastUsedAt != nil && key.LastUsedAt.Year() <= 1970 {
+		key.LastUsedAt = nil
+	}
+	return nil
+}
+
+// UserKeyRequest .
+type UserKeyRequest struct {
+	Title string `json:"title"`
+	Key   string `json:"key"`
+
+	// 添加公钥时所在的组织
+	OrgID   int64  `json:"-"`
+	OrgName string `json:"-"`
+}
+
+// AddUserKey adds the ssh public key in the authorized_keys format, a key can only belong to one user
+func (svc *Service) AddUserKey(user *User, request *UserKeyRequest) (*UserKey, error) {
+	content := strings.TrimSpace(request.Key)
+	fingerprint, key, err := sshserver.Fingerprint(content)
+	if err != nil {
+		return nil, err
+	}
+	var count int
+	err = svc.db.Model(&UserKey{}).Where("fingerprint = ? and soft_deleted_at = 0", fingerprint).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("the key is already in use")
+	}
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		// 默认使用公钥的注释作为标题
+		parts := strings.Fields(content)
+		if len(parts) > 2 {
+			title = strings.Join(parts[2:], " ")
+		} else {
+			title = key.Type()
+		}
+	}
+	userKey := &UserKey{
+		ID:          uuid.New().String(),
+		OrgID:       request.OrgID,
+		OrgName:     request.OrgName,
+		UserID:      user.Id,
+		Title:       title,
+		Content:     content,
+		Fingerprint: fingerprint,
+	}
+	err = svc.db.Create(userKey).Error
+	if err != nil {
+		return nil, err
+	}
+	// 创建后 gorm 会回填 last_used_at 的默认值
+	userKey.LastUsedAt = nil
+	return userKey, nil
+}
+
+// ListUserKeys .
+func (svc *Service) ListUserKeys(user *User) ([]*UserKey, error) {
+	var keys []*UserKey
+	err := svc.db.Where("user_id = ? and soft_deleted_at = 0", user.Id).Order("created_at desc").Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeleteUserKey deletes the key of the user
+func (svc *Service) DeleteUserKey(user *User, id string) error {
+	var key UserKey
+	err := svc.db.Where("id = ? and user_id = ? and soft_deleted_at = 0", id, user.Id).First(&key).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("key %s not found", id)
+		}
+		return err
+	}
+	return svc.db.Model(&key).Update("soft_deleted_at", time.Now().UnixNano()/1e6).Error
+}
+
+// FindUserKeyByFingerprint finds the key used to authenticate the ssh connection and records the last used time
+func (svc *Service) FindUserKeyByFingerprint(fingerprint string) (*UserKey, error) {
+	var key UserKey
+	err := svc.db.Where("fingerprint = ? and soft_deleted_at = 0", fingerprint).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key.LastUsedAt = &now
+	svc.db.Model(&key).UpdateColumn("last_used_at", now)
+	return &key, nil
+}
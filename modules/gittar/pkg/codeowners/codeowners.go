@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codeowners parses the CODEOWNERS file, each line is a path pattern followed by the owners:
+//
+//	# comment
+//	*               1001
+//	/docs/          1002 1003
+//	*.go            @1004
+//	/pkg/**/api.go  1005
+//
+// The owners are the user IDs, the optional @ prefix is removed.
+// The patterns follow the gitignore syntax, and the last matching pattern takes precedence.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Paths are the locations of the CODEOWNERS file, in the order of precedence.
+var Paths = []string{".erda/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule .
+type Rule struct {
+	Pattern string
+	Owners  []string
+	reg     *regexp.Regexp
+}
+
+// CodeOwners .
+type CodeOwners struct {
+	Rules []*Rule
+}
+
+// Parse .
+func Parse(content string) (*CodeOwners, error) {
+	co := &CodeOwners{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) <= 0 || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "[") {
+			continue
+		}
+		fields := strings.Fields(text)
+		reg, err := compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %s", line, fields[0], err)
+		}
+		rule := &Rule{Pattern: fields[0], reg: reg}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			if owner = strings.TrimPrefix(owner, "@"); len(owner) > 0 {
+				rule.Owners = append(rule.Owners, owner)
+			}
+		}
+		co.Rules = append(co.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return co, nil
+}
+
+// Owners returns the owners of the file.
+func (co *CodeOwners) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		if co.Rules[i].reg.MatchString(path) {
+			return co.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// OwnersOfFiles returns the distinct owners of the files, sorted.
+func (co *CodeOwners) OwnersOfFiles(paths []string) []string {
+	set := make(map[string]bool)
+	for _, path := range paths {
+		for _, owner := range co.Owners(path) {
+			set[owner] = true
+		}
+	}
+	owners := make([]string, 0, len(set))
+	for owner := range set {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// MissingOwners returns the owners of the files which are not approved by any of their owners, sorted.
+func (co *CodeOwners) MissingOwners(paths []string, approvers map[string]bool) []string {
+	set := make(map[string]bool)
+	for _, path := range paths {
+		owners := co.Owners(path)
+		approved := len(owners) <= 0
+		for _, owner := range owners {
+			if approvers[owner] {
+				approved = true
+				break
+			}
+		}
+		if !approved {
+			for _, owner := range owners {
+				set[owner] = true
+			}
+		}
+	}
+	missing := make([]string, 0, len(set))
+	for owner := range set {
+		missing = append(missing, owner)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dir {
+		sb.WriteString("/.*$")
+	} else {
+		// a pattern also matches the files under the directory
+		sb.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+const content = `
+# default owners
+*                 1001
+
+[Docs]
+/docs/            1002 @1003
+*.md              1004 # markdown
+/pkg/**/api.go    1005
+build/            1006
+/modules/gittar   1007
+/vendor/
+`
+
+func TestCodeOwners_Owners(t *testing.T) {
+	co, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error: %s", err)
+	}
+	tt := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"1001"}},
+		{"docs/guide/index.html", []string{"1002", "1003"}},
+		{"docs/README.md", []string{"1004"}},
+		{"pkg/api.go", []string{"1005"}},
+		{"pkg/a/b/api.go", []string{"1005"}},
+		{"pkg/a/b/service.go", []string{"1001"}},
+		{"tools/build/Makefile", []string{"1006"}},
+		{"tools/build", []string{"1001"}},
+		{"modules/gittar/models/repo.go", []string{"1007"}},
+		{"/modules/gittar", []string{"1007"}},
+		{"modules/gittar-x/main.go", []string{"1001"}},
+		{"vendor/github.com/a.go", nil},
+	}
+	for _, v := range tt {
+		if got := co.Owners(v.path); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("Owners(%q) = %v, want %v", v.path, got, v.want)
+		}
+	}
+
+	owners := co.OwnersOfFiles([]string{"docs/a.txt", "main.go", "README.md", "docs/b.txt"})
+	if want := []string{"1001", "1002", "1003", "1004"}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("OwnersOfFiles() = %v, want %v", owners, want)
+	}
+}
+
+func TestCodeOwners_MissingOwners(t *testing.T) {
+	co, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error: %s", err)
+	}
+	paths := []string{"docs/a.txt", "main.go", "vendor/a.go"}
+	tt := []struct {
+		approvers map[string]bool
+		want      []string
+	}{
+		{nil, []string{"1001", "1002", "1003"}},
+		{map[string]bool{"1003": true}, []string{"1001"}},
+		{map[string]bool{"1001": true, "1002": true}, []string{}},
+	}
+	for _, v := range tt {
+		if got := co.MissingOwners(paths, v.approvers); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("MissingOwners(%v) = %v, want %v", v.approvers, got, v.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codesearch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newIndex() *Index {
+	ix := New()
+	ix.Add("main.go", []byte("package main\n\nfunc main() {\n\tfmt.Println(\"Hello World\")\n}\n"))
+	ix.Add("pkg/util/strings.go", []byte("package util\n\n// HelloWorld returns hello\nfunc HelloWorld() string { return \"hello\" }\n"))
+	ix.Add("web/index.js", []byte("console.log('hello world');\n"))
+	ix.Add("docs/README.md", []byte("# Erda\nhello\n"))
+	ix.Add("logo.png", []byte("\x89PNG\x00\x00hello world"))
+	return ix
+}
+
+func paths(results []*Result) []string {
+	var result []string
+	for _, r := range results {
+		result = append(result, r.Path)
+	}
+	return result
+}
+
+func TestIndex_Search(t *testing.T) {
+	ix := newIndex()
+	if ix.Len() != 4 {
+		t.Errorf("Len() = %d, binary file should be skipped", ix.Len())
+	}
+
+	tt := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"literal", Query{Pattern: "hello world"}, []string{"main.go", "web/index.js"}},
+		{"case sensitive", Query{Pattern: "Hello World", CaseSensitive: true}, []string{"main.go"}},
+		{"regexp", Query{Pattern: `func \w+\(\)`, Regexp: true}, []string{"main.go", "pkg/util/strings.go"}},
+		{"regexp alternate", Query{Pattern: `erda|println`, Regexp: true}, []string{"docs/README.md", "main.go"}},
+		{"short", Query{Pattern: "he"}, []string{"docs/README.md", "main.go", "pkg/util/strings.go", "web/index.js"}},
+		{"language", Query{Pattern: "hello", Language: "go"}, []string{"main.go", "pkg/util/strings.go"}},
+		{"path", Query{Pattern: "hello", Path: "pkg/"}, []string{"pkg/util/strings.go"}},
+		{"path glob", Query{Pattern: "hello", Path: "*.js"}, []string{"web/index.js"}},
+		{"max results", Query{Pattern: "hello", MaxResults: 1}, []string{"docs/README.md"}},
+		{"not found", Query{Pattern: "not exist"}, nil},
+	}
+	for _, v := range tt {
+		results, _, err := ix.Search(&v.query)
+		if err != nil {
+			t.Errorf("%s: Search() error: %v", v.name, err)
+			continue
+		}
+		if got := paths(results); !reflect.DeepEqual(got, v.want) {
+			t.Errorf("%s: Search() = %v, want %v", v.name, got, v.want)
+		}
+	}
+
+	results, total, _ := ix.Search(&Query{Pattern: "hello", MaxResults: 1})
+	if total != 4 || len(results) != 1 {
+		t.Errorf("Search() total = %d, results = %d", total, len(results))
+	}
+
+	results, _, _ = ix.Search(&Query{Pattern: "hello", Path: "strings.go"})
+	want := []*Match{
+		{Line: 3, Content: "// HelloWorld returns hello", Ranges: [][2]int{{3, 8}, {22, 27}}},
+		{Line: 4, Content: "func HelloWorld() string { return \"hello\" }", Ranges: [][2]int{{5, 10}, {35, 40}}},
+	}
+	if !reflect.DeepEqual(results[0].Matches, want) {
+		t.Errorf("Matches = %+v, want %+v", results[0].Matches[0], want[0])
+	}
+
+	if _, _, err := ix.Search(&Query{Pattern: "(", Regexp: true}); err == nil {
+		t.Error("invalid regexp should fail")
+	}
+}
+
+func TestIndex_Update(t *testing.T) {
+	ix := newIndex()
+	ix.Remove("main.go")
+	ix.Add("web/index.js", []byte("console.log('bye');\n"))
+	results, _, _ := ix.Search(&Query{Pattern: "hello world"})
+	if len(results) != 0 {
+		t.Errorf("removed files should not be found: %v", paths(results))
+	}
+	results, _, _ = ix.Search(&Query{Pattern: "bye"})
+	if got := paths(results); !reflect.DeepEqual(got, []string{"web/index.js"}) {
+		t.Errorf("Search() = %v", got)
+	}
+
+	var buf bytes.Buffer
+	ix.Commit = "abc"
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Commit != "abc" || read.Len() != ix.Len() {
+		t.Errorf("Read() commit = %s, len = %d, want abc, %d", read.Commit, read.Len(), ix.Len())
+	}
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	tt := []struct {
+		expr string
+		want []string
+	}{
+		{`hello`, []string{"hello"}},
+		{`(?i)hello`, []string{"hello"}},
+		{`func \w+\(\) error`, []string{"func ", "() error"}},
+		{`a|b`, nil},
+		{`(foo)+bar`, []string{"foo", "bar"}},
+		{`x*abc`, []string{"abc"}},
+		{`(?i)héllo`, nil},
+	}
+	for _, v := range tt {
+		got, err := requiredLiterals(v.expr)
+		if err != nil {
+			t.Errorf("requiredLiterals(%s) error: %v", v.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, v.want) {
+			t.Errorf("requiredLiterals(%s) = %q, want %q", v.expr, got, v.want)
+		}
+	}
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@erda.cloud",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@erda.cloud")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v error: %v, %s", args, err, output)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	gitRun(t, root, "init", "-q", repo)
+	writeFile(t, filepath.Join(repo, "a.go"), "package a // alpha\n")
+	writeFile(t, filepath.Join(repo, "b/b.go"), "package b // beta\n")
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "init")
+
+	indexer := NewIndexer(filepath.Join(root, "index"), 1)
+	ix, err := indexer.Update(context.Background(), 1, repo)
+	if err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if ix.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", ix.Len())
+	}
+
+	writeFile(t, filepath.Join(repo, "a.go"), "package a // gamma\n")
+	writeFile(t, filepath.Join(repo, "c.go"), "package c // delta\n")
+	gitRun(t, repo, "rm", "-q", "b/b.go")
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "update")
+	if _, err = indexer.Update(context.Background(), 1, repo); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+
+	// 从磁盘重新加载
+	indexer = NewIndexer(filepath.Join(root, "index"), 1)
+	ix = indexer.Get(1, repo)
+	if ix == nil {
+		t.Fatal("Get() should load the index from the disk")
+	}
+	for pattern, want := range map[string][]string{
+		"alpha": nil,
+		"beta":  nil,
+		"gamma": {"a.go"},
+		"delta": {"c.go"},
+	} {
+		results, _, _ := ix.Search(&Query{Pattern: pattern})
+		if got := paths(results); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", pattern, got, want)
+		}
+	}
+
+	if indexer.Get(2, repo) != nil {
+		t.Error("Get() should return nil if the index is not built")
+	}
+	// 等待后台构建完成
+	for i := 0; i < 100; i++ {
+		indexer.mu.Lock()
+		updating := indexer.updating[2]
+		indexer.mu.Unlock()
+		if !updating {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if indexer.Get(2, repo) == nil {
+		t.Error("Get() should return the index built in the background")
+	}
+	if err = indexer.Remove(1); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codesearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type blob struct {
+	path string
+	sha  string
+}
+
+// ResolveCommit returns the commit id of the ref, returns empty if the ref does not exist
+func ResolveCommit(ctx context.Context, repoPath string, ref string) string {
+	output, err := git(ctx, repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Build indexes all the files of the commit
+func Build(ctx context.Context, repoPath string, commit string) (*Index, error) {
+	output, err := git(ctx, repoPath, "ls-tree", "-r", "-z", "--full-tree", commit)
+	if err != nil {
+		return nil, err
+	}
+	var blobs []blob
+	for _, entry := range bytes.Split(output, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <file>
+		tab := bytes.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(string(entry[:tab]))
+		if len(fields) != 3 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		blobs = append(blobs, blob{path: string(entry[tab+1:]), sha: fields[2]})
+	}
+
+	ix := New()
+	if err = readBlobs(ctx, repoPath, blobs, func(b blob, content []byte) {
+		ix.Add(b.path, content)
+	}); err != nil {
+		return nil, err
+	}
+	ix.Commit = commit
+	return ix, nil
+}
+
+// Update updates the index to the commit by the changed files, the index is rebuilt if the diff fails
+func Update(ctx context.Context, repoPath string, ix *Index, commit string) (*Index, error) {
+	if ix == nil || ix.Commit == "" {
+		return Build(ctx, repoPath, commit)
+	}
+	if ix.Commit == commit {
+		return ix, nil
+	}
+	output, err := git(ctx, repoPath, "diff-tree", "-r", "-z", "--no-renames", "--raw", ix.Commit, commit)
+	if err != nil {
+		// 原 commit 可能已被强制推送覆盖
+		return Build(ctx, repoPath, commit)
+	}
+
+	var removed []string
+	var blobs []blob
+	parts := bytes.Split(output, []byte{0})
+	for i := 0; i+1 < len(parts); i += 2 {
+		// :<old mode> SP <new mode> SP <old sha> SP <new sha> SP <status> NUL <path> NUL
+		fields := strings.Fields(strings.TrimPrefix(string(parts[i]), ":"))
+		if len(fields) != 5 {
+			continue
+		}
+		filePath := string(parts[i+1])
+		if fields[4] == "D" || fields[1] == "120000" || fields[1] == "160000" {
+			removed = append(removed, filePath)
+			continue
+		}
+		blobs = append(blobs, blob{path: filePath, sha: fields[3]})
+	}
+
+	contents := make(map[string][]byte, len(blobs))
+	if err = readBlobs(ctx, repoPath, blobs, func(b blob, content []byte) {
+		contents[b.path] = content
+	}); err != nil {
+		return nil, err
+	}
+	for _, filePath := range removed {
+		ix.Remove(filePath)
+	}
+	for _, b := range blobs {
+		if content, ok := contents[b.path]; ok {
+			ix.Add(b.path, content)
+		} else {
+			ix.Remove(b.path)
+		}
+	}
+	ix.mu.Lock()
+	ix.Commit = commit
+	ix.mu.Unlock()
+	return ix, nil
+}
+
+// readBlobs reads the contents of the blobs by git cat-file --batch, the large files are skipped
+func readBlobs(ctx context.Context, repoPath string, blobs []blob, fn func(b blob, content []byte)) error {
+	if len(blobs) <= 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = repoPath
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		w := bufio.NewWriter(stdin)
+		for _, b := range blobs {
+			fmt.Fprintln(w, b.sha)
+		}
+		w.Flush()
+		stdin.Close()
+	}()
+
+	reader := bufio.NewReader(stdout)
+	for _, b := range blobs {
+		content, ok, err := readBlob(reader)
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return fmt.Errorf("failed to read blob %s of %s: %v", b.sha, b.path, err)
+		}
+		if ok {
+			fn(b, content)
+		}
+	}
+	if err = cmd.Wait(); err != nil {
+		return fmt.Errorf("git cat-file failed: %v, %s", err, stderr.String())
+	}
+	return nil
+}
+
+func readBlob(reader *bufio.Reader) ([]byte, bool, error) {
+	// <sha> SP <type> SP <size> LF <contents> LF
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, false, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, false, fmt.Errorf("unexpected header %q", strings.TrimSpace(header))
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, false, err
+	}
+	var content []byte
+	if size > MaxFileSize {
+		_, err = io.CopyN(ioutil.Discard, reader, size)
+	} else {
+		content = make([]byte, size)
+		_, err = io.ReadFull(reader, content)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err = reader.Discard(1); err != nil {
+		return nil, false, err
+	}
+	return content, size <= MaxFileSize, nil
+}
+
+func git(ctx context.Context, repoPath string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v, %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
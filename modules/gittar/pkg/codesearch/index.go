@@ -0,0 +1,327 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codesearch implements the trigram index and the content search of the repositories.
+package codesearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxFileSize is the max size of the file to index, the larger files are skipped
+const MaxFileSize = 1 << 20
+
+// Document is the indexed file
+type Document struct {
+	Path     string
+	Language string
+	Content  []byte
+}
+
+// Index is the trigram index of the files of the commit
+type Index struct {
+	Commit string
+
+	mu       sync.RWMutex
+	docs     []*Document // 删除的文件置为 nil, 删除过多时重建
+	paths    map[string]int32
+	postings map[uint32][]int32
+	deleted  int
+}
+
+// New creates an empty index
+func New() *Index {
+	return &Index{
+		paths:    make(map[string]int32),
+		postings: make(map[uint32][]int32),
+	}
+}
+
+// Len returns the number of the indexed files
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.paths)
+}
+
+// Add indexes the file, the file is replaced if it exists, binary and large files are skipped
+func (ix *Index) Add(filePath string, content []byte) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(filePath)
+	if !Indexable(content) {
+		return false
+	}
+	ix.add(&Document{Path: filePath, Language: DetectLanguage(filePath), Content: content})
+	return true
+}
+
+// Remove removes the file from the index
+func (ix *Index) Remove(filePath string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(filePath)
+	if ix.deleted > 1024 && ix.deleted > len(ix.docs)/2 {
+		ix.rebuild()
+	}
+}
+
+func (ix *Index) add(doc *Document) {
+	id := int32(len(ix.docs))
+	ix.docs = append(ix.docs, doc)
+	ix.paths[doc.Path] = id
+	for _, t := range trigrams(doc.Content) {
+		ix.postings[t] = append(ix.postings[t], id)
+	}
+}
+
+func (ix *Index) remove(filePath string) {
+	id, ok := ix.paths[filePath]
+	if !ok {
+		return
+	}
+	ix.docs[id] = nil
+	delete(ix.paths, filePath)
+	ix.deleted++
+}
+
+// rebuild drops the deleted files from the postings
+func (ix *Index) rebuild() {
+	docs := ix.docs
+	ix.docs = nil
+	ix.paths = make(map[string]int32)
+	ix.postings = make(map[uint32][]int32)
+	ix.deleted = 0
+	for _, doc := range docs {
+		if doc != nil {
+			ix.add(doc)
+		}
+	}
+}
+
+// candidates returns the files containing all the literals, all files if no trigram in the literals
+func (ix *Index) candidates(literals []string) []*Document {
+	var ids []int32
+	filtered := false
+	for _, literal := range literals {
+		for _, t := range trigrams([]byte(literal)) {
+			posting := ix.postings[t]
+			if !filtered {
+				ids = append([]int32(nil), posting...)
+				filtered = true
+			} else {
+				ids = intersect(ids, posting)
+			}
+			if len(ids) <= 0 {
+				return nil
+			}
+		}
+	}
+
+	var result []*Document
+	if !filtered {
+		for _, doc := range ix.docs {
+			if doc != nil {
+				result = append(result, doc)
+			}
+		}
+		return result
+	}
+	for _, id := range ids {
+		if doc := ix.docs[id]; doc != nil {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+func intersect(a, b []int32) []int32 {
+	result := a[:0]
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// trigrams returns the unique trigrams of the content, ascii letters are folded to lower case
+func trigrams(content []byte) []uint32 {
+	if len(content) < 3 {
+		return nil
+	}
+	seen := make(map[uint32]struct{})
+	var result []uint32
+	for i := 0; i+2 < len(content); i++ {
+		t := uint32(fold(content[i]))<<16 | uint32(fold(content[i+1]))<<8 | uint32(fold(content[i+2]))
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		result = append(result, t)
+	}
+	return result
+}
+
+func fold(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// Indexable returns false if the content is binary or too large
+func Indexable(content []byte) bool {
+	if len(content) > MaxFileSize {
+		return false
+	}
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) < 0
+}
+
+var languages = map[string]string{
+	".go":    "Go",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".scala": "Scala",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".vue":   "Vue",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".rs":    "Rust",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".hpp":   "C++",
+	".cs":    "C#",
+	".swift": "Swift",
+	".m":     "Objective-C",
+	".lua":   "Lua",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".html":  "HTML",
+	".css":   "CSS",
+	".scss":  "SCSS",
+	".less":  "Less",
+	".json":  "JSON",
+	".yml":   "YAML",
+	".yaml":  "YAML",
+	".xml":   "XML",
+	".proto": "Protocol Buffers",
+	".md":    "Markdown",
+}
+
+// DetectLanguage detects the language of the file by the extension
+func DetectLanguage(filePath string) string {
+	base := path.Base(filePath)
+	switch base {
+	case "Dockerfile":
+		return "Dockerfile"
+	case "Makefile":
+		return "Makefile"
+	}
+	return languages[strings.ToLower(path.Ext(base))]
+}
+
+// Languages returns the languages detected
+func Languages() []string {
+	set := make(map[string]struct{})
+	for _, language := range languages {
+		set[language] = struct{}{}
+	}
+	result := []string{"Dockerfile", "Makefile"}
+	for language := range set {
+		result = append(result, language)
+	}
+	sort.Strings(result)
+	return result
+}
+
+type indexFile struct {
+	Commit string
+	Docs   []*Document
+}
+
+// WriteTo writes the files of the index, the postings are built when reading
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	ix.mu.RLock()
+	file := indexFile{Commit: ix.Commit}
+	for _, doc := range ix.docs {
+		if doc != nil {
+			file.Docs = append(file.Docs, doc)
+		}
+	}
+	ix.mu.RUnlock()
+
+	counter := &countWriter{w: w}
+	gz := gzip.NewWriter(counter)
+	if err := gob.NewEncoder(gz).Encode(&file); err != nil {
+		return counter.n, err
+	}
+	err := gz.Close()
+	return counter.n, err
+}
+
+// Read reads the index written by WriteTo
+func Read(r io.Reader) (*Index, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var file indexFile
+	if err = gob.NewDecoder(gz).Decode(&file); err != nil {
+		return nil, err
+	}
+	ix := New()
+	ix.Commit = file.Commit
+	for _, doc := range file.Docs {
+		ix.add(doc)
+	}
+	return ix, nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
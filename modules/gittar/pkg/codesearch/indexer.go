@@ -0,0 +1,209 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codesearch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTimeout is the timeout of building or updating the index
+const DefaultTimeout = 10 * time.Minute
+
+// Indexer keeps the indexes of the default branches of the repos on the disk,
+// the recently used indexes are kept in memory
+type Indexer struct {
+	root      string
+	maxLoaded int
+
+	mu       sync.Mutex
+	loaded   map[int64]*entry
+	locks    map[int64]*sync.Mutex
+	updating map[int64]bool
+}
+
+type entry struct {
+	index    *Index
+	lastUsed time.Time
+}
+
+// NewIndexer creates the indexer storing the indexes under the root
+func NewIndexer(root string, maxLoaded int) *Indexer {
+	if maxLoaded <= 0 {
+		maxLoaded = 1
+	}
+	return &Indexer{
+		root:      root,
+		maxLoaded: maxLoaded,
+		loaded:    make(map[int64]*entry),
+		locks:     make(map[int64]*sync.Mutex),
+		updating:  make(map[int64]bool),
+	}
+}
+
+// Path returns the index file of the repo
+func (ir *Indexer) Path(repoID int64) string {
+	return filepath.Join(ir.root, strconv.FormatInt(repoID, 10)+".idx")
+}
+
+// Update indexes the HEAD of the repo, only the changed files are indexed if the index exists
+func (ir *Indexer) Update(ctx context.Context, repoID int64, repoPath string) (*Index, error) {
+	lock := ir.repoLock(repoID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ix, err := ir.load(repoID)
+	if err != nil {
+		logrus.Warnf("failed to load search index of repo %d, rebuild it: %v", repoID, err)
+	}
+	commit := ResolveCommit(ctx, repoPath, "HEAD")
+	if commit == "" {
+		// 空仓库
+		ix = New()
+	} else if ix, err = Update(ctx, repoPath, ix, commit); err != nil {
+		return nil, err
+	}
+	if err = ir.save(repoID, ix); err != nil {
+		return nil, err
+	}
+	ir.put(repoID, ix)
+	return ix, nil
+}
+
+// Get returns the index of the repo, nil if it's not built yet.
+// The index is built or updated in the background if it's missing or behind the HEAD.
+func (ir *Indexer) Get(repoID int64, repoPath string) *Index {
+	ix, err := ir.load(repoID)
+	if err != nil {
+		logrus.Warnf("failed to load search index of repo %d: %v", repoID, err)
+	}
+	if ix == nil || ix.Commit != ResolveCommit(context.Background(), repoPath, "HEAD") {
+		ir.updateAsync(repoID, repoPath)
+	}
+	return ix
+}
+
+// Remove removes the index of the repo
+func (ir *Indexer) Remove(repoID int64) error {
+	ir.mu.Lock()
+	delete(ir.loaded, repoID)
+	ir.mu.Unlock()
+	err := os.Remove(ir.Path(repoID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (ir *Indexer) updateAsync(repoID int64, repoPath string) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if ir.updating[repoID] {
+		return
+	}
+	ir.updating[repoID] = true
+	go func() {
+		defer func() {
+			ir.mu.Lock()
+			delete(ir.updating, repoID)
+			ir.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		defer cancel()
+		if _, err := ir.Update(ctx, repoID, repoPath); err != nil {
+			logrus.Errorf("failed to update search index of repo %d: %v", repoID, err)
+		}
+	}()
+}
+
+func (ir *Indexer) repoLock(repoID int64) *sync.Mutex {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	lock, ok := ir.locks[repoID]
+	if !ok {
+		lock = &sync.Mutex{}
+		ir.locks[repoID] = lock
+	}
+	return lock
+}
+
+// load returns the index in memory or on the disk, nil if not exist
+func (ir *Indexer) load(repoID int64) (*Index, error) {
+	ir.mu.Lock()
+	if e, ok := ir.loaded[repoID]; ok {
+		e.lastUsed = time.Now()
+		ir.mu.Unlock()
+		return e.index, nil
+	}
+	ir.mu.Unlock()
+
+	f, err := os.Open(ir.Path(repoID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+	ir.put(repoID, ix)
+	return ix, nil
+}
+
+func (ir *Indexer) save(repoID int64, ix *Index) error {
+	if err := os.MkdirAll(ir.root, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(ir.root, "tmp-*.idx")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = ix.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), ir.Path(repoID))
+}
+
+// put keeps the index in memory, the least recently used index is dropped if too many
+func (ir *Indexer) put(repoID int64, ix *Index) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	ir.loaded[repoID] = &entry{index: ix, lastUsed: time.Now()}
+	for len(ir.loaded) > ir.maxLoaded {
+		var oldest int64
+		var oldestTime time.Time
+		for id, e := range ir.loaded {
+			if oldestTime.IsZero() || e.lastUsed.Before(oldestTime) {
+				oldest, oldestTime = id, e.lastUsed
+			}
+		}
+		delete(ir.loaded, oldest)
+	}
+}
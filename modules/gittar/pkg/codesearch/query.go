@@ -0,0 +1,254 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codesearch
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxResults is the max number of the files returned
+	DefaultMaxResults = 100
+	// MaxMatchesPerFile is the max number of the lines returned of the single file
+	MaxMatchesPerFile = 20
+	maxLineLength     = 500
+)
+
+// Query is the search of the file contents
+type Query struct {
+	Pattern       string
+	Regexp        bool
+	CaseSensitive bool
+	// Language filters the files by the language detected, case insensitive
+	Language string
+	// Path filters the files by the glob pattern if it contains *?[, otherwise by the substring
+	Path       string
+	MaxResults int
+}
+
+// Match is the matched line
+type Match struct {
+	Line    int      `json:"line"`
+	Content string   `json:"content"`
+	Ranges  [][2]int `json:"ranges"`
+}
+
+// Result is the matched file
+type Result struct {
+	Path     string   `json:"path"`
+	Language string   `json:"language"`
+	Matches  []*Match `json:"matches"`
+}
+
+// Compile compiles the pattern of the query
+func (q *Query) Compile() (*regexp.Regexp, error) {
+	if q.Pattern == "" {
+		return nil, errors.New("pattern is required")
+	}
+	expr := q.Pattern
+	if !q.Regexp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if !q.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+func (q *Query) matchFile(doc *Document) bool {
+	if q.Language != "" && !strings.EqualFold(q.Language, doc.Language) {
+		return false
+	}
+	if q.Path == "" {
+		return true
+	}
+	if !strings.ContainsAny(q.Path, "*?[") {
+		return strings.Contains(doc.Path, q.Path)
+	}
+	if ok, _ := path.Match(q.Path, doc.Path); ok {
+		return true
+	}
+	ok, _ := path.Match(q.Path, path.Base(doc.Path))
+	return ok
+}
+
+// Search searches the files, returns the matched files sorted by the path and the total number of the matched files
+func (ix *Index) Search(q *Query) ([]*Result, int, error) {
+	re, err := q.Compile()
+	if err != nil {
+		return nil, 0, err
+	}
+	literals, err := requiredLiterals(re.String())
+	if err != nil {
+		return nil, 0, err
+	}
+	maxResults := q.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultMaxResults
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	docs := ix.candidates(literals)
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Path < docs[j].Path })
+
+	var results []*Result
+	total := 0
+	for _, doc := range docs {
+		if !q.matchFile(doc) {
+			continue
+		}
+		matches := matchLines(re, doc.Content)
+		if len(matches) <= 0 {
+			continue
+		}
+		total++
+		if len(results) < maxResults {
+			results = append(results, &Result{Path: doc.Path, Language: doc.Language, Matches: matches})
+		}
+	}
+	return results, total, nil
+}
+
+// matchLines returns the lines matched, the ranges are the byte offsets in the line
+func matchLines(re *regexp.Regexp, content []byte) []*Match {
+	locs := re.FindAllIndex(content, -1)
+	var matches []*Match
+	var last *Match
+	lineNo, lineStart := 1, 0
+	for _, loc := range locs {
+		if loc[0] == loc[1] {
+			continue
+		}
+		for {
+			end := bytes.IndexByte(content[lineStart:], '\n')
+			if end < 0 || lineStart+end >= loc[0] {
+				break
+			}
+			lineStart += end + 1
+			lineNo++
+		}
+		if last == nil || last.Line != lineNo {
+			if len(matches) >= MaxMatchesPerFile {
+				break
+			}
+			lineEnd := bytes.IndexByte(content[lineStart:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(content)
+			} else {
+				lineEnd += lineStart
+			}
+			last = &Match{Line: lineNo, Content: truncate(content[lineStart:lineEnd])}
+			matches = append(matches, last)
+		}
+		start, end := loc[0]-lineStart, loc[1]-lineStart
+		if end > len(last.Content) {
+			end = len(last.Content)
+		}
+		if start < end {
+			last.Ranges = append(last.Ranges, [2]int{start, end})
+		}
+	}
+	return matches
+}
+
+func truncate(line []byte) string {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) <= maxLineLength {
+		return string(line)
+	}
+	line = line[:maxLineLength]
+	for len(line) > 0 && !utf8.Valid(line) {
+		line = line[:len(line)-1]
+	}
+	return string(line)
+}
+
+// requiredLiterals returns the literals every match must contain, used to filter the files by the trigrams
+func requiredLiterals(expr string) ([]string, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return literalsOf(re.Simplify()), nil
+}
+
+func literalsOf(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return literalString(re)
+	case syntax.OpCapture, syntax.OpPlus:
+		return literalsOf(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return literalsOf(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var result []string
+		var run []rune
+		foldCase := false
+		flush := func() {
+			if len(run) > 0 {
+				result = append(result, literalString(&syntax.Regexp{Op: syntax.OpLiteral, Rune: run, Flags: flagsOf(foldCase)})...)
+			}
+			run = nil
+		}
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral && (len(run) == 0 || (sub.Flags&syntax.FoldCase != 0) == foldCase) {
+				foldCase = sub.Flags&syntax.FoldCase != 0
+				run = append(run, sub.Rune...)
+				continue
+			}
+			flush()
+			if sub.Op == syntax.OpLiteral {
+				foldCase = sub.Flags&syntax.FoldCase != 0
+				run = append(run, sub.Rune...)
+				continue
+			}
+			result = append(result, literalsOf(sub)...)
+		}
+		flush()
+		return result
+	}
+	return nil
+}
+
+func flagsOf(foldCase bool) syntax.Flags {
+	if foldCase {
+		return syntax.FoldCase
+	}
+	return 0
+}
+
+// literalString returns the literal, the case insensitive non-ascii literals are dropped as the trigrams only fold ascii
+func literalString(re *syntax.Regexp) []string {
+	s := string(re.Rune)
+	if re.Flags&syntax.FoldCase != 0 {
+		for _, r := range re.Rune {
+			if r >= utf8.RuneSelf {
+				return nil
+			}
+		}
+		s = strings.ToLower(s)
+	}
+	return []string{s}
+}
@@ -67,7 +67,8 @@ func repositoryClean() {
 	// initialize a waitGroup according to the number of concurrent
 	var wait = limit_sync_group.NewSemaphore(concurrentNum)
 	for _, projectFileInfo := range projectFileInfos {
-		if !projectFileInfo.IsDir() {
+		// skip the hidden dirs like .lfs and .ssh
+		if !projectFileInfo.IsDir() || strings.HasPrefix(projectFileInfo.Name(), ".") {
 			continue
 		}
 		var projectPath = repositoryRootAddr + "/" + projectFileInfo.Name()
@@ -27,6 +27,8 @@ import (
 
 	git "github.com/libgit2/git2go/v30"
 	"github.com/mcuadros/go-version"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/signature"
 )
 
 const INIT_COMMIT_ID = "0000000000000000000000000000000000000000"
@@ -42,6 +44,8 @@ type Commit struct {
 	Parents        []string   `json:"parents"`
 	submoduleCache *objectCache
 	ParentDirPath  string `json:"parentDirPath"`
+	// Verification is the signature status, only filled by the api need it
+	Verification *signature.Verification `json:"verification,omitempty"`
 }
 
 func (c *Commit) Git2Oid() *git.Oid {
@@ -67,17 +67,35 @@ const (
 	REF_TYPE_TREE   = "tree"
 )
 
+// IsProtectBranch returns true if the branch is protected, the branch is treated as protected if the rules can't be got
 func (repo *Repository) IsProtectBranch(branch string) bool {
+	gitReference, err := repo.getValidBranch(branch)
+	if err != nil {
+		logrus.Errorf("failed to get branch rules of app %d: %v", repo.ApplicationId, err)
+		return true
+	}
+	return gitReference != nil && gitReference.IsProtect
+}
+
+// RequireSignedCommits returns true if the commits written to the protected branch must be signed and verified
+func (repo *Repository) RequireSignedCommits(branch string) (bool, error) {
+	gitReference, err := repo.getValidBranch(branch)
+	if err != nil {
+		return false, err
+	}
+	return gitReference != nil && gitReference.IsProtect && gitReference.RequireSignedCommits, nil
+}
+
+func (repo *Repository) getValidBranch(branch string) (*apistructs.ValidBranch, error) {
 	// repo是http请求级别的实例，一个请求中不重复更新规则
 	if repo.branchRules == nil {
 		rules, err := repo.Bundle.GetAppBranchRules(uint64(repo.ApplicationId))
 		if err != nil {
-			return false
+			return nil, err
 		}
 		repo.branchRules = rules
 	}
-	gitReference := diceworkspace.GetValidBranchByGitReference(branch, repo.branchRules)
-	return gitReference.IsProtect
+	return diceworkspace.GetValidBranchByGitReference(branch, repo.branchRules), nil
 }
 
 func (repo *Repository) IsProtectBranchWithRules(branch string, rules []*apistructs.BranchRule) bool {
@@ -301,6 +319,16 @@ func (repo *Repository) DiskPath() string {
 	return path.Join(repo.Root(), repo.Path)
 }
 
+// CatFile returns the raw content of the object, typ is commit, tag, tree or blob
+func (repo *Repository) CatFile(typ, id string) ([]byte, error) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	if err := NewCommand("cat-file", typ, id).RunInDirPipeline(repo.DiskPath(), stdout, stderr); err != nil {
+		return nil, concatenateError(err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
 func (repo *Repository) FullName() string {
 	return repo.Path
 }
@@ -292,11 +292,26 @@ func (repo *Repository) searchCommits(id string, keyword string) ([]*Commit, err
 }
 
 func (repo *Repository) getFilesChanged(id1 string, id2 string) ([]string, error) {
-	stdout, err := NewCommand("diff", "--Name-only", id1, id2).RunInDirBytes(repo.DiskPath())
+	return repo.diffNames(id1, id2)
+}
+
+// ChangedFiles returns the files changed in head since it forked from base
+func (repo *Repository) ChangedFiles(base string, head string) ([]string, error) {
+	return repo.diffNames(base + "..." + head)
+}
+
+func (repo *Repository) diffNames(revisions ...string) ([]string, error) {
+	stdout, err := NewCommand("diff", "--name-only").AddArguments(revisions...).RunInDirBytes(repo.DiskPath())
 	if err != nil {
 		return nil, err
 	}
-	return strings.Split(string(stdout), "\n"), nil
+	var files []string
+	for _, file := range strings.Split(string(stdout), "\n") {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files, nil
 }
 
 func (repo *Repository) FileCommitsCount(revision, file string) (int64, error) {
@@ -405,6 +420,15 @@ func (repo *Repository) CommitsBetween(last *Commit, before *Commit) ([]*Commit,
 	return repo.parsePrettyFormatLogToList(bytes.TrimSpace(stdout))
 }
 
+// CommitsAhead returns the commits reachable from head but not from base, like git rev-list base..head
+func (repo *Repository) CommitsAhead(base, head string) ([]*Commit, error) {
+	stdout, err := NewCommand("rev-list", base+".."+head).RunInDirBytes(repo.DiskPath())
+	if err != nil {
+		return nil, err
+	}
+	return repo.parsePrettyFormatLogToList(bytes.TrimSpace(stdout))
+}
+
 func (repo *Repository) CommitsBetweenIDs(last, before string) ([]*Commit, error) {
 	lastCommit, err := repo.GetCommit(last)
 	if err != nil {
@@ -18,15 +18,57 @@ package gitmodule
 
 import (
 	"errors"
+	"strings"
 
 	git "github.com/libgit2/git2go/v30"
 )
 
+// merge methods of merge request
+const (
+	MergeMethodMerge       = "merge"
+	MergeMethodSquash      = "squash"
+	MergeMethodRebase      = "rebase"
+	MergeMethodFastForward = "ff"
+)
+
+// MergeMethods are all the supported merge methods.
+var MergeMethods = []string{MergeMethodMerge, MergeMethodSquash, MergeMethodRebase, MergeMethodFastForward}
+
+// ParseMergeMethods parses the comma separated merge methods, all the methods are allowed if it's empty.
+func ParseMergeMethods(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return MergeMethods, nil
+	}
+	var methods []string
+	for _, method := range strings.Split(s, ",") {
+		method = strings.TrimSpace(method)
+		if !IsMergeMethodAllowed(MergeMethods, method) {
+			return nil, errors.New("invalid merge method " + method)
+		}
+		if !IsMergeMethodAllowed(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+// IsMergeMethodAllowed .
+func IsMergeMethodAllowed(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 type MergeStatusInfo struct {
 	HasConflict bool   `json:"hasConflict"`
 	IsMerged    bool   `json:"isMerged"`
 	HasError    bool   `json:"hasError"`
 	ErrorMsg    string `json:"errorMsg"`
+	// Methods reports whether each merge method is possible
+	Methods map[string]bool `json:"methods"`
 }
 
 type MergeInfo struct {
@@ -159,9 +201,207 @@ func (repo *Repository) GetMergeStatus(ourBranch string, theirBranch string) (*M
 	}
 	result.HasConflict = index.HasConflicts()
 
+	result.Methods = map[string]bool{
+		MergeMethodMerge:       !result.HasConflict,
+		MergeMethodSquash:      !result.HasConflict,
+		MergeMethodFastForward: info.BaseCommit.ID == info.TheirCommit.ID,
+	}
+	if result.Methods[MergeMethodFastForward] {
+		result.Methods[MergeMethodRebase] = true
+	} else if !result.HasConflict {
+		result.Methods[MergeMethodRebase] = repo.canRebase(info) == nil
+	}
 	return result, nil
 }
 
+// canRebase replays the commits without creating them, the trees are written into memory and dropped after it.
+func (repo *Repository) canRebase(info *MergeInfo) error {
+	rawRepo, err := repo.GetRawRepo()
+	if err != nil {
+		return err
+	}
+	defer rawRepo.Free()
+	odb, err := rawRepo.Odb()
+	if err != nil {
+		return err
+	}
+	mempack, err := git.NewMempack(odb)
+	if err != nil {
+		return err
+	}
+	defer mempack.Reset()
+	_, err = repo.rebase(rawRepo, info, nil)
+	return err
+}
+
+// MergeWithMethod merges ourBranch into theirBranch with the merge method, the message is ignored by rebase and fast-forward.
+func (repo *Repository) MergeWithMethod(method string, ourBranch string, theirBranch string, signature *Signature, message string) (*Commit, error) {
+	switch method {
+	case "", MergeMethodMerge:
+		return repo.Merge(ourBranch, theirBranch, signature, message)
+	case MergeMethodSquash:
+		return repo.Squash(ourBranch, theirBranch, signature, message)
+	case MergeMethodRebase:
+		return repo.Rebase(ourBranch, theirBranch, signature)
+	case MergeMethodFastForward:
+		return repo.FastForward(ourBranch, theirBranch)
+	}
+	return nil, errors.New("invalid merge method " + method)
+}
+
+// Squash creates a single commit on theirBranch with the changes of ourBranch.
+func (repo *Repository) Squash(ourBranch string, theirBranch string, signature *Signature, message string) (*Commit, error) {
+	info, err := repo.getMergeInfo(ourBranch, theirBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	rawRepo, err := repo.GetRawRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := git.DefaultMergeOptions()
+	if err != nil {
+		return nil, err
+	}
+	index, err := rawRepo.MergeTrees(info.BaseTree, info.OurTree, info.TheirTree, &options)
+	if err != nil {
+		return nil, err
+	}
+	if index.HasConflicts() {
+		return nil, errors.New("has conflict")
+	}
+	newTreeOid, err := index.WriteTreeTo(rawRepo)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := rawRepo.LookupTree(newTreeOid)
+	if err != nil {
+		return nil, err
+	}
+
+	parentCommit, err := rawRepo.LookupCommit(info.TheirCommit.Git2Oid())
+	if err != nil {
+		return nil, err
+	}
+	sig := signature.toGit()
+	newOid, err := rawRepo.CreateCommit(BRANCH_PREFIX+theirBranch, sig, sig, message, newTree, parentCommit)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetCommit(newOid.String())
+}
+
+// Rebase replays the commits of ourBranch on theirBranch one by one, the authors of the commits are kept.
+func (repo *Repository) Rebase(ourBranch string, theirBranch string, signature *Signature) (*Commit, error) {
+	info, err := repo.getMergeInfo(ourBranch, theirBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	rawRepo, err := repo.GetRawRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	newOid, err := repo.rebase(rawRepo, info, signature.toGit())
+	if err != nil {
+		return nil, err
+	}
+	// move the branch once after all the commits are created, only if it's not changed since the merge info is read
+	_, err = NewCommand("update-ref", "-m", "rebase merge "+ourBranch, BRANCH_PREFIX+theirBranch, newOid.String(), info.TheirCommit.ID).
+		RunInDir(repo.DiskPath())
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetCommit(newOid.String())
+}
+
+// rebase replays the commits after the merge base without moving any branch, the commits are not created if sig is nil.
+func (repo *Repository) rebase(rawRepo *git.Repository, info *MergeInfo, sig *git.Signature) (*git.Oid, error) {
+	commits, err := repo.CommitsBetween(info.OurCommit, info.BaseCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := git.DefaultMergeOptions()
+	if err != nil {
+		return nil, err
+	}
+	current, err := rawRepo.LookupCommit(info.TheirCommit.Git2Oid())
+	if err != nil {
+		return nil, err
+	}
+	currentTree := info.TheirTree
+	currentOid := current.Id()
+	// the commits are listed from the newest to the oldest
+	for i := len(commits) - 1; i >= 0; i-- {
+		pick := commits[i]
+		if len(pick.Parents) != 1 {
+			return nil, errors.New("can not rebase merge commit " + pick.ID)
+		}
+		pickCommit, err := rawRepo.LookupCommit(pick.Git2Oid())
+		if err != nil {
+			return nil, err
+		}
+		pickTree, err := pickCommit.Tree()
+		if err != nil {
+			return nil, err
+		}
+		parentTree, err := pickCommit.Parent(0).Tree()
+		if err != nil {
+			return nil, err
+		}
+		index, err := rawRepo.MergeTrees(parentTree, currentTree, pickTree, &options)
+		if err != nil {
+			return nil, err
+		}
+		if index.HasConflicts() {
+			return nil, errors.New("has conflict when rebase commit " + pick.ID)
+		}
+		newTreeOid, err := index.WriteTreeTo(rawRepo)
+		if err != nil {
+			return nil, err
+		}
+		if currentTree, err = rawRepo.LookupTree(newTreeOid); err != nil {
+			return nil, err
+		}
+		if sig == nil {
+			continue
+		}
+		currentOid, err = rawRepo.CreateCommit("", pickCommit.Author(), sig, pick.CommitMessage, currentTree, current)
+		if err != nil {
+			return nil, err
+		}
+		if current, err = rawRepo.LookupCommit(currentOid); err != nil {
+			return nil, err
+		}
+	}
+	return currentOid, nil
+}
+
+// FastForward moves theirBranch to ourBranch, only if theirBranch is an ancestor of ourBranch.
+func (repo *Repository) FastForward(ourBranch string, theirBranch string) (*Commit, error) {
+	info, err := repo.getMergeInfo(ourBranch, theirBranch)
+	if err != nil {
+		return nil, err
+	}
+	if info.BaseCommit.ID != info.TheirCommit.ID {
+		return nil, errors.New("can not fast-forward, " + theirBranch + " has diverged")
+	}
+
+	rawRepo, err := repo.GetRawRepo()
+	if err != nil {
+		return nil, err
+	}
+	_, err = rawRepo.References.Create(BRANCH_PREFIX+theirBranch, info.OurCommit.Git2Oid(), true, "fast-forward merge "+ourBranch)
+	if err != nil {
+		return nil, err
+	}
+	return info.OurCommit, nil
+}
+
 func (repo *Repository) Merge(ourBranch string, theirBranch string, signature *Signature, message string) (*Commit, error) {
 
 	info, err := repo.getMergeInfo(ourBranch, theirBranch)
@@ -228,3 +468,11 @@ func (repo *Repository) Merge(ourBranch string, theirBranch string, signature *S
 	return repo.GetCommit(newOid.String())
 
 }
+
+func (s *Signature) toGit() *git.Signature {
+	return &git.Signature{
+		Name:  s.Name,
+		Email: s.Email,
+		When:  s.When,
+	}
+}
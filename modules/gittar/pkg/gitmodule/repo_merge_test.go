@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitmodule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMergeMethods(t *testing.T) {
+	tt := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{"", MergeMethods, false},
+		{"squash", []string{MergeMethodSquash}, false},
+		{"ff, rebase,ff", []string{MergeMethodFastForward, MergeMethodRebase}, false},
+		{"merge,octopus", nil, true},
+	}
+	for _, v := range tt {
+		got, err := ParseMergeMethods(v.s)
+		if (err != nil) != v.wantErr {
+			t.Fatalf("ParseMergeMethods(%q) error = %v, wantErr %v", v.s, err, v.wantErr)
+		}
+		if !reflect.DeepEqual(got, v.want) {
+			t.Errorf("ParseMergeMethods(%q) = %v, want %v", v.s, got, v.want)
+		}
+	}
+}
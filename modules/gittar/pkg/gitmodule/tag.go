@@ -4,7 +4,11 @@
 
 package gitmodule
 
-import "bytes"
+import (
+	"bytes"
+
+	"github.com/erda-project/erda/modules/gittar/pkg/signature"
+)
 
 // Tag represents a Git tag.
 type Tag struct {
@@ -15,6 +19,8 @@ type Tag struct {
 	Type    string     `json:"-"`
 	Tagger  *Signature `json:"tagger"`
 	Message string     `json:"message"`
+	// Verification is the signature status, lightweight tags are verified by the commit
+	Verification *signature.Verification `json:"verification,omitempty"`
 }
 
 func (tag *Tag) Commit() (*Commit, error) {
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Resolver opens the content of the lfs object, returns nil if the object is not found
+type Resolver func(pointer *Pointer) (io.ReadCloser, error)
+
+// RewriteArchive reads the tar archive generated by git archive, replaces the lfs pointer files
+// with the object contents and writes the archive in the format, one of tar, tar.gz and zip
+func RewriteArchive(r io.Reader, w io.Writer, format string, resolve Resolver) error {
+	var writer archiveWriter
+	switch format {
+	case "tar":
+		writer = &tarWriter{Writer: tar.NewWriter(w)}
+	case "tar.gz", "tgz":
+		gz := gzip.NewWriter(w)
+		writer = &tarWriter{Writer: tar.NewWriter(gz), gz: gz}
+	case "zip":
+		writer = &zipWriter{Writer: zip.NewWriter(w)}
+	default:
+		return fmt.Errorf("unsupported archive format %s", format)
+	}
+
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > MaxPointerSize {
+			if err = writer.WriteEntry(header, reader); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(writer, header, content, resolve); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func writeFile(writer archiveWriter, header *tar.Header, content []byte, resolve Resolver) error {
+	pointer, ok := ParsePointer(content)
+	if !ok {
+		return writer.WriteEntry(header, bytes.NewReader(content))
+	}
+	object, err := resolve(pointer)
+	if err != nil {
+		return err
+	}
+	if object == nil {
+		// 对象不存在时保留指针文件
+		return writer.WriteEntry(header, bytes.NewReader(content))
+	}
+	defer object.Close()
+	header.Size = pointer.Size
+	return writer.WriteEntry(header, io.LimitReader(object, pointer.Size))
+}
+
+type archiveWriter interface {
+	WriteEntry(header *tar.Header, r io.Reader) error
+	Close() error
+}
+
+type tarWriter struct {
+	*tar.Writer
+	gz *gzip.Writer
+}
+
+func (w *tarWriter) WriteEntry(header *tar.Header, r io.Reader) error {
+	if err := w.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(w.Writer, r)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (w *zipWriter) WriteEntry(header *tar.Header, r io.Reader) error {
+	switch header.Typeflag {
+	case tar.TypeXGlobalHeader:
+		// git archive 在 pax 全局头中记录 commit id, zip 中写入注释
+		if comment, ok := header.PAXRecords["comment"]; ok {
+			return w.SetComment(comment)
+		}
+		return nil
+	case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+	default:
+		return nil
+	}
+	zipHeader, err := zip.FileInfoHeader(header.FileInfo())
+	if err != nil {
+		return err
+	}
+	zipHeader.Name = header.Name
+	if header.Typeflag == tar.TypeReg {
+		zipHeader.Method = zip.Deflate
+	}
+	entry, err := w.CreateHeader(zipHeader)
+	if err != nil {
+		return err
+	}
+	if header.Typeflag == tar.TypeSymlink {
+		_, err = io.WriteString(entry, header.Linkname)
+		return err
+	}
+	_, err = io.Copy(entry, r)
+	return err
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lfs implements the Git LFS batch, transfer and locking protocols.
+// See https://github.com/git-lfs/git-lfs/tree/main/docs/api
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MediaType is the content type of the lfs api requests and responses
+	MediaType = "application/vnd.git-lfs+json"
+	// PointerVersion is the version line of the lfs pointer file
+	PointerVersion = "https://git-lfs.github.com/spec/v1"
+	// MaxPointerSize is the max size of the lfs pointer file
+	MaxPointerSize = 1024
+
+	OperationUpload   = "upload"
+	OperationDownload = "download"
+
+	TransferBasic = "basic"
+	HashAlgo      = "sha256"
+)
+
+// Pointer identifies the lfs object
+type Pointer struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// ValidOid returns true if the oid is a sha256 hex string
+func ValidOid(oid string) bool {
+	if len(oid) != 64 {
+		return false
+	}
+	for _, c := range oid {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid returns true if the oid and size of the pointer are valid
+func (p Pointer) Valid() bool {
+	return ValidOid(p.Oid) && p.Size >= 0
+}
+
+// String returns the content of the pointer file
+func (p Pointer) String() string {
+	return fmt.Sprintf("version %s\noid %s:%s\nsize %d\n", PointerVersion, HashAlgo, p.Oid, p.Size)
+}
+
+// ParsePointer parses the content of the pointer file, returns false if it's not a pointer file
+func ParsePointer(content []byte) (*Pointer, bool) {
+	if len(content) > MaxPointerSize {
+		return nil, false
+	}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for i := 0; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		// version 必须在第一行
+		if (i == 0) != (parts[0] == "version") {
+			return nil, false
+		}
+		values[parts[0]] = parts[1]
+	}
+	if values["version"] != PointerVersion {
+		return nil, false
+	}
+	oid := strings.TrimPrefix(values["oid"], HashAlgo+":")
+	size, err := strconv.ParseInt(values["size"], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	pointer := &Pointer{Oid: oid, Size: size}
+	if !pointer.Valid() {
+		return nil, false
+	}
+	return pointer, true
+}
+
+// Ref is the git reference the objects or locks belong to
+type Ref struct {
+	Name string `json:"name"`
+}
+
+// BatchRequest requests the transfer actions of the objects
+type BatchRequest struct {
+	Operation string    `json:"operation"`
+	Transfers []string  `json:"transfers,omitempty"`
+	Ref       *Ref      `json:"ref,omitempty"`
+	Objects   []Pointer `json:"objects"`
+	HashAlgo  string    `json:"hash_algo,omitempty"`
+}
+
+// BatchResponse returns the transfer actions of the objects
+type BatchResponse struct {
+	Transfer string            `json:"transfer,omitempty"`
+	Objects  []*ObjectResponse `json:"objects"`
+	HashAlgo string            `json:"hash_algo,omitempty"`
+}
+
+// ObjectResponse is the actions of the object, no actions means the server already has the object when uploading
+type ObjectResponse struct {
+	Pointer
+	Authenticated bool             `json:"authenticated,omitempty"`
+	Actions       map[string]*Link `json:"actions,omitempty"`
+	Error         *ObjectError     `json:"error,omitempty"`
+}
+
+// Link is the action to upload, download or verify the object
+type Link struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header,omitempty"`
+	ExpiresIn int               `json:"expires_in,omitempty"`
+}
+
+// ObjectError is the error of the single object
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of the failed lfs api response
+type ErrorResponse struct {
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	RequestID        string `json:"request_id,omitempty"`
+}
+
+// Owner is the user who locks the file
+type Owner struct {
+	Name string `json:"name"`
+}
+
+// Lock is the lock of the file
+type Lock struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"`
+	LockedAt time.Time `json:"locked_at"`
+	Owner    *Owner    `json:"owner,omitempty"`
+}
+
+// LockRequest locks the file
+type LockRequest struct {
+	Path string `json:"path"`
+	Ref  *Ref   `json:"ref,omitempty"`
+}
+
+// LockResponse returns the lock created, or the existing lock with the message if conflicts
+type LockResponse struct {
+	Lock    *Lock  `json:"lock"`
+	Message string `json:"message,omitempty"`
+}
+
+// LockList is the page of the locks
+type LockList struct {
+	Locks      []*Lock `json:"locks"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// LockVerifyRequest lists the locks of the current user and the others before pushing
+type LockVerifyRequest struct {
+	Ref    *Ref   `json:"ref,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// LockVerifyResponse returns the locks of the current user and the others
+type LockVerifyResponse struct {
+	Ours       []*Lock `json:"ours"`
+	Theirs     []*Lock `json:"theirs"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// UnlockRequest unlocks the file, force is required to unlock the lock of the others
+type UnlockRequest struct {
+	Force bool `json:"force,omitempty"`
+	Ref   *Ref `json:"ref,omitempty"`
+}
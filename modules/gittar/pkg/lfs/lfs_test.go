@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type memStorage map[string][]byte
+
+func (s memStorage) Read(path string) (io.Reader, error) {
+	content, ok := s[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return bytes.NewReader(content), nil
+}
+
+func (s memStorage) Write(path string, r io.Reader) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s[path] = content
+	return nil
+}
+
+func (s memStorage) Delete(path string) error {
+	delete(s, path)
+	return nil
+}
+
+func newPointer(content string) Pointer {
+	sum := sha256.Sum256([]byte(content))
+	return Pointer{Oid: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+}
+
+func TestParsePointer(t *testing.T) {
+	pointer := newPointer("large file")
+	got, ok := ParsePointer([]byte(pointer.String()))
+	if !ok || *got != pointer {
+		t.Fatalf("ParsePointer(String()) = %v, %v, want %v", got, ok, pointer)
+	}
+
+	invalid := []string{
+		"",
+		"hello world",
+		"oid sha256:" + pointer.Oid + "\nversion " + PointerVersion + "\nsize 10\n",
+		"version https://example.com/spec\noid sha256:" + pointer.Oid + "\nsize 10\n",
+		"version " + PointerVersion + "\noid sha256:1234\nsize 10\n",
+		"version " + PointerVersion + "\noid sha256:" + pointer.Oid + "\nsize x\n",
+	}
+	for _, content := range invalid {
+		if _, ok := ParsePointer([]byte(content)); ok {
+			t.Errorf("ParsePointer(%q) should be invalid", content)
+		}
+	}
+}
+
+func TestValidOid(t *testing.T) {
+	if !ValidOid(newPointer("a").Oid) {
+		t.Error("sha256 hex should be valid")
+	}
+	for _, oid := range []string{"", "../../etc/passwd", "ABCD" + newPointer("a").Oid[4:]} {
+		if ValidOid(oid) {
+			t.Errorf("ValidOid(%q) should be false", oid)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	storage := memStorage{}
+	store := NewStore(storage, "/lfs")
+	pointer := newPointer("large file")
+
+	if got, want := store.Path(1, pointer.Oid), "/lfs/1/"+pointer.Oid[0:2]+"/"+pointer.Oid[2:4]+"/"+pointer.Oid; got != want {
+		t.Errorf("Path() = %s, want %s", got, want)
+	}
+	if err := store.Put(1, pointer, bytes.NewBufferString("other file")); !errors.Is(err, ErrVerify) {
+		t.Errorf("Put() with wrong content error = %v, want ErrVerify", err)
+	}
+	if err := store.Put(1, pointer, bytes.NewBufferString("large file and more")); !errors.Is(err, ErrVerify) {
+		t.Errorf("Put() with longer content error = %v, want ErrVerify", err)
+	}
+	if len(storage) != 0 {
+		t.Errorf("object should be removed if not verified")
+	}
+	if err := store.Put(1, pointer, bytes.NewBufferString("large file")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	rc, err := store.Get(1, pointer.Oid)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	content, _ := ioutil.ReadAll(rc)
+	if string(content) != "large file" {
+		t.Errorf("Get() = %s", content)
+	}
+	if _, err := store.Get(2, pointer.Oid); err == nil {
+		t.Error("objects should be isolated by repo")
+	}
+	if err := store.Put(1, pointer, bytes.NewBufferString("broken")); !errors.Is(err, ErrVerify) {
+		t.Errorf("Put() with wrong content error = %v, want ErrVerify", err)
+	}
+	if string(storage[store.Path(1, pointer.Oid)]) != "large file" {
+		t.Error("existing object should not be replaced by a broken upload")
+	}
+}
+
+func TestRewriteArchive(t *testing.T) {
+	pointer := newPointer("large file content")
+	missing := newPointer("missing")
+	files := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"repo/README.md", "hello", "hello"},
+		{"repo/model.bin", pointer.String(), "large file content"},
+		{"repo/missing.bin", missing.String(), missing.String()},
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": "abc123"}})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "repo/", Mode: 0775})
+	for _, f := range files {
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0664, Size: int64(len(f.content))})
+		tw.Write([]byte(f.content))
+	}
+	tw.Close()
+
+	resolve := func(p *Pointer) (io.ReadCloser, error) {
+		if *p != pointer {
+			return nil, nil
+		}
+		return ioutil.NopCloser(bytes.NewBufferString("large file content")), nil
+	}
+
+	var tarOut bytes.Buffer
+	if err := RewriteArchive(bytes.NewReader(buf.Bytes()), &tarOut, "tar", resolve); err != nil {
+		t.Fatalf("RewriteArchive(tar) error: %v", err)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(&tarOut)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(tr)
+		got[header.Name] = string(content)
+	}
+	for _, f := range files {
+		if got[f.name] != f.want {
+			t.Errorf("tar %s = %q, want %q", f.name, got[f.name], f.want)
+		}
+	}
+
+	var zipOut bytes.Buffer
+	if err := RewriteArchive(bytes.NewReader(buf.Bytes()), &zipOut, "zip", resolve); err != nil {
+		t.Fatalf("RewriteArchive(zip) error: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipOut.Bytes()), int64(zipOut.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.Comment != "abc123" {
+		t.Errorf("zip comment = %q, want abc123", zr.Comment)
+	}
+	got = make(map[string]string)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(content)
+	}
+	for _, f := range files {
+		if got[f.name] != f.want {
+			t.Errorf("zip %s = %q, want %q", f.name, got[f.name], f.want)
+		}
+	}
+	if _, ok := got["repo/"]; !ok {
+		t.Error("zip should contain the directory")
+	}
+
+	if err := RewriteArchive(bytes.NewReader(buf.Bytes()), ioutil.Discard, "rar", resolve); err == nil {
+		t.Error("unsupported format should fail")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+)
+
+// ErrVerify is returned if the uploaded content does not match the oid and size
+var ErrVerify = errors.New("lfs object content does not match the oid or size")
+
+// Storage is where the lfs objects are stored, implemented by pkg/storage
+type Storage interface {
+	Read(path string) (io.Reader, error)
+	Write(path string, r io.Reader) error
+	Delete(path string) error
+}
+
+// Store stores the lfs objects of the repos under the root
+type Store struct {
+	storage Storage
+	root    string
+}
+
+// NewStore creates the store of the lfs objects
+func NewStore(storage Storage, root string) *Store {
+	return &Store{storage: storage, root: root}
+}
+
+// Path returns the path of the object, like {root}/{repoID}/ab/cd/abcd...
+func (s *Store) Path(repoID int64, oid string) string {
+	return path.Join(s.root, strconv.FormatInt(repoID, 10), oid[0:2], oid[2:4], oid)
+}
+
+// Put writes the object, the content is saved to a temp file first and only
+// moved to the storage if it matches the pointer, so an existing object is never replaced by a broken upload
+func (s *Store) Put(repoID int64, pointer Pointer, r io.Reader) error {
+	if !pointer.Valid() {
+		return ErrVerify
+	}
+	tmp, err := ioutil.TempFile("", "lfs-"+pointer.Oid+"-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	reader := &verifyReader{reader: io.LimitReader(r, pointer.Size+1), hash: sha256.New()}
+	if _, err := io.Copy(tmp, reader); err != nil {
+		return err
+	}
+	if reader.size != pointer.Size || hex.EncodeToString(reader.hash.Sum(nil)) != pointer.Oid {
+		return ErrVerify
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.storage.Write(s.Path(repoID, pointer.Oid), tmp)
+}
+
+// Get opens the object
+func (s *Store) Get(repoID int64, oid string) (io.ReadCloser, error) {
+	if !ValidOid(oid) {
+		return nil, ErrVerify
+	}
+	r, err := s.storage.Read(s.Path(repoID, oid))
+	if err != nil {
+		return nil, err
+	}
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+// Delete removes the object
+func (s *Store) Delete(repoID int64, oid string) error {
+	if !ValidOid(oid) {
+		return ErrVerify
+	}
+	return s.storage.Delete(s.Path(repoID, oid))
+}
+
+type verifyReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	size   int64
+}
+
+func (r *verifyReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	r.hash.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mirror syncs the repositories with the external git servers.
+package mirror
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Direction of the mirror
+type Direction string
+
+const (
+	// DirectionPull fetches all the branches and tags from the remote periodically
+	DirectionPull Direction = "pull"
+	// DirectionPush pushes all the branches and tags to the remote after receiving the pushes
+	DirectionPush Direction = "push"
+)
+
+// Status of the last sync
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSyncing Status = "syncing"
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+)
+
+// DefaultTimeout is the timeout of the single sync
+const DefaultTimeout = 10 * time.Minute
+
+// refSpecs are the refs to mirror, the merge request refs and others are kept locally
+var refSpecs = []string{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// Remote is the external repository
+type Remote struct {
+	URL      string
+	Username string
+	Password string
+	// AllowLocal allows file urls, local paths and the hosts of loopback and internal networks, only used for test
+	AllowLocal bool
+}
+
+// lookupIP resolves the host of the remote, replaced in test
+var lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return net.DefaultResolver.LookupIPAddr(ctx, host)
+}
+
+// internalNets are the networks not allowed to mirror, besides loopback, link local, multicast and unspecified addresses
+var internalNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "0.0.0.0/8", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the url of the remote, only http and https urls of the public hosts are allowed,
+// file urls, local paths and internal hosts are only allowed if AllowLocal is true.
+// The credentials must be set by Username and Password rather than the url.
+func (r *Remote) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return fmt.Errorf("invalid remote url: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return errors.New("invalid remote url: host is required")
+		}
+		if u.User != nil {
+			return errors.New("invalid remote url: set the credentials by username and password")
+		}
+		if r.AllowLocal {
+			return nil
+		}
+		return checkHost(u.Hostname())
+	case "file", "":
+		if r.AllowLocal {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported remote url %s, only http and https are allowed", r.String())
+}
+
+// checkHost returns error if the host resolves to a loopback or internal address
+func checkHost(host string) error {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		addrs, err := lookupIP(ctx, host)
+		if err != nil {
+			return fmt.Errorf("failed to resolve remote host %s: %v", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if isInternalIP(ip) {
+			return fmt.Errorf("remote host %s is not allowed, it resolves to the internal address %s", host, ip)
+		}
+	}
+	return nil
+}
+
+// String returns the url without the credentials
+func (r *Remote) String() string {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return r.URL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// credentialHelper answers the credentials from the environments, so that they are not in the command line
+const credentialHelper = `!f() { test "$1" = get && echo "username=${GITTAR_MIRROR_USERNAME}" && echo "password=${GITTAR_MIRROR_PASSWORD}"; }; f`
+
+// gitArgs returns the config of git to use the credentials from the environments returned
+func (r *Remote) gitArgs() ([]string, []string, error) {
+	args := []string{"-c", "http.followRedirects=false", "-c", "credential.helper="}
+	if r.Username == "" && r.Password == "" {
+		return args, nil, nil
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, nil, errors.New("credentials are only supported by http and https remotes")
+	}
+	args = append(args, "-c", "credential.helper="+credentialHelper)
+	envs := []string{"GITTAR_MIRROR_USERNAME=" + r.Username, "GITTAR_MIRROR_PASSWORD=" + r.Password}
+	return args, envs, nil
+}
+
+// redact removes the credentials from the output of git
+func (r *Remote) redact(output string) string {
+	if r.Password != "" {
+		output = strings.Replace(output, r.Password, "******", -1)
+		output = strings.Replace(output, url.QueryEscape(r.Password), "******", -1)
+	}
+	return output
+}
+
+// Fetch mirrors the branches and tags of the remote into the bare repository, the refs deleted by the remote are pruned
+func Fetch(ctx context.Context, repoPath string, remote *Remote) error {
+	return run(ctx, repoPath, remote, "fetch", "--prune", "--no-tags")
+}
+
+// Push mirrors the branches and tags of the bare repository to the remote, the refs deleted locally are pruned
+func Push(ctx context.Context, repoPath string, remote *Remote) error {
+	return run(ctx, repoPath, remote, "push", "--prune")
+}
+
+func run(ctx context.Context, repoPath string, remote *Remote, command string, args ...string) error {
+	// validate again in case the host resolves to another address
+	if err := remote.Validate(); err != nil {
+		return err
+	}
+	gitArgs, envs, err := remote.gitArgs()
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	cmdArgs := append(gitArgs, command)
+	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, remote.URL)
+	cmdArgs = append(cmdArgs, refSpecs...)
+	cmd := exec.CommandContext(ctx, "git", cmdArgs...)
+	cmd.Dir = repoPath
+	// 禁止 git 交互式询问用户名密码
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	if !remote.AllowLocal {
+		cmd.Env = append(cmd.Env, "GIT_ALLOW_PROTOCOL=http:https")
+	}
+	cmd.Env = append(cmd.Env, envs...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("git %s %s failed: %v, %s", command, remote.String(), err,
+			strings.TrimSpace(remote.redact(string(output))))
+	}
+	return nil
+}
+
+// encryptedPrefix marks the password encrypted by EncryptPassword
+const encryptedPrefix = "aes:"
+
+func newGCM(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("the secret key of mirror credentials is not configured")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptPassword encrypts the password with AES-GCM to store it
+func EncryptPassword(password, key string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(password), nil)), nil
+}
+
+// IsEncryptedPassword returns true if the password is encrypted by EncryptPassword
+func IsEncryptedPassword(stored string) bool {
+	return strings.HasPrefix(stored, encryptedPrefix)
+}
+
+// DecryptPassword decrypts the password encrypted by EncryptPassword, the password stored in plain text is returned as is
+func DecryptPassword(stored, key string) (string, error) {
+	if !IsEncryptedPassword(stored) {
+		return stored, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted password")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password: %v", err)
+	}
+	return string(plain), nil
+}
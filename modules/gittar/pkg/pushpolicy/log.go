@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pushpolicy
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LogFormat is the format of git log parsed by ParseLog
+const LogFormat = "--format=%H%x00%an%x00%ae%x00%B%x1e"
+
+// ParseLog parses the output of git log with LogFormat
+func ParseLog(output string) []*Commit {
+	var commits []*Commit
+	for _, record := range strings.Split(output, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		fields := strings.SplitN(record, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, &Commit{
+			ID:          fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Message:     strings.TrimRight(fields[3], "\n"),
+		})
+	}
+	return commits
+}
+
+// ParseObjects parses the output of git rev-list --objects, only the objects with path are returned
+func ParseObjects(output string) map[string]string {
+	paths := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(parts) == 2 && len(parts[1]) > 0 {
+			paths[parts[0]] = parts[1]
+		}
+	}
+	return paths
+}
+
+// ParseBatchCheck parses the output of git cat-file --batch-check, the blob sizes are returned
+func ParseBatchCheck(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 3 || parts[1] != "blob" {
+			continue
+		}
+		size, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[parts[0]] = size
+	}
+	return sizes
+}
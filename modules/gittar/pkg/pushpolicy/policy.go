@@ -0,0 +1,224 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pushpolicy checks the incoming commits and files of a push against the rules of the repository.
+package pushpolicy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxScanSize is the max size of the files scanned for secrets
+const MaxScanSize = 1 << 20
+
+const (
+	RuleCommitMessage = "commit-message"
+	RuleIssueRef      = "issue-ref"
+	RuleSignedOffBy   = "signed-off-by"
+	RuleFileSize      = "file-size"
+	RuleFileExtension = "file-extension"
+	RuleSecret        = "secret"
+	RuleSignedCommit  = "signed-commit"
+)
+
+// IssueRefRegexp matches the issue referenced in the commit message, e.g. #123
+var IssueRefRegexp = regexp.MustCompile(`#(\d+)\b`)
+
+var signedOffByRegexp = regexp.MustCompile(`(?m)^Signed-off-by: .+ <([^>]+)>\s*$`)
+
+// Policy is the push rules of a repository
+type Policy struct {
+	Enable bool `json:"enable"`
+	// CommitMessagePattern is the regexp the commit messages must match
+	CommitMessagePattern string `json:"commitMessagePattern"`
+	// IssueRefRequired requires the commit messages to reference an issue of the project, e.g. #123
+	IssueRefRequired bool `json:"issueRefRequired"`
+	// RequireSignedOffBy requires a Signed-off-by trailer of the commit author
+	RequireSignedOffBy bool `json:"requireSignedOffBy"`
+	// MaxFileSize is the max size of the files in bytes, 0 means unlimited
+	MaxFileSize int64 `json:"maxFileSize"`
+	// ForbiddenExtensions e.g. [".exe", ".jar"]
+	ForbiddenExtensions []string `json:"forbiddenExtensions"`
+	SecretScan          bool     `json:"secretScan"`
+}
+
+// Commit is a new commit of the push
+type Commit struct {
+	ID          string
+	AuthorName  string
+	AuthorEmail string
+	Message     string
+}
+
+// File is a new blob of the push
+type File struct {
+	Commit string
+	Path   string
+	BlobID string
+	Size   int64
+}
+
+// Violation is a rule broken by the push
+type Violation struct {
+	Rule    string
+	Commit  string
+	Path    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	var sb strings.Builder
+	if len(v.Commit) > 0 {
+		sb.WriteString("commit " + shortID(v.Commit) + ": ")
+	}
+	if len(v.Path) > 0 {
+		sb.WriteString(v.Path + ": ")
+	}
+	sb.WriteString(v.Message)
+	return sb.String()
+}
+
+// IssueChecker reports whether the issue exists
+type IssueChecker func(id uint64) (bool, error)
+
+// Checker .
+type Checker struct {
+	policy      *Policy
+	message     *regexp.Regexp
+	extensions  map[string]bool
+	issueExists IssueChecker
+}
+
+// NewChecker .
+func NewChecker(policy *Policy, issueExists IssueChecker) (*Checker, error) {
+	c := &Checker{
+		policy:      policy,
+		extensions:  make(map[string]bool),
+		issueExists: issueExists,
+	}
+	if len(policy.CommitMessagePattern) > 0 {
+		reg, err := regexp.Compile(policy.CommitMessagePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit message pattern: %s", err)
+		}
+		c.message = reg
+	}
+	for _, ext := range policy.ForbiddenExtensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if len(ext) <= 0 {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		c.extensions[ext] = true
+	}
+	return c, nil
+}
+
+// NeedFiles reports whether the files of the push need to be checked
+func (c *Checker) NeedFiles() bool {
+	return c.policy.MaxFileSize > 0 || len(c.extensions) > 0 || c.policy.SecretScan
+}
+
+// CheckCommit checks the commit message and the trailers
+func (c *Checker) CheckCommit(commit *Commit) error {
+	if c.message != nil && !c.message.MatchString(commit.Message) {
+		return &Violation{Rule: RuleCommitMessage, Commit: commit.ID,
+			Message: fmt.Sprintf("commit message does not match %q", c.policy.CommitMessagePattern)}
+	}
+	if c.policy.IssueRefRequired {
+		if err := c.checkIssueRef(commit); err != nil {
+			return err
+		}
+	}
+	if c.policy.RequireSignedOffBy {
+		signed := false
+		for _, match := range signedOffByRegexp.FindAllStringSubmatch(commit.Message, -1) {
+			if strings.EqualFold(match[1], commit.AuthorEmail) {
+				signed = true
+				break
+			}
+		}
+		if !signed {
+			return &Violation{Rule: RuleSignedOffBy, Commit: commit.ID,
+				Message: fmt.Sprintf("missing Signed-off-by: %s <%s>", commit.AuthorName, commit.AuthorEmail)}
+		}
+	}
+	return nil
+}
+
+func (c *Checker) checkIssueRef(commit *Commit) error {
+	matches := IssueRefRegexp.FindAllStringSubmatch(commit.Message, -1)
+	if len(matches) <= 0 {
+		return &Violation{Rule: RuleIssueRef, Commit: commit.ID, Message: "commit message must reference an issue, e.g. #123"}
+	}
+	if c.issueExists == nil {
+		return nil
+	}
+	for _, match := range matches {
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		ok, err := c.issueExists(id)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return &Violation{Rule: RuleIssueRef, Commit: commit.ID,
+		Message: fmt.Sprintf("issue %s not found in the project", matches[0][0])}
+}
+
+// CheckFile checks the size and the extension of the file
+func (c *Checker) CheckFile(file *File) error {
+	if ext := strings.ToLower(path.Ext(file.Path)); len(ext) > 0 && c.extensions[ext] {
+		return &Violation{Rule: RuleFileExtension, Commit: file.Commit, Path: file.Path,
+			Message: fmt.Sprintf("file extension %s is forbidden", ext)}
+	}
+	if c.policy.MaxFileSize > 0 && file.Size > c.policy.MaxFileSize {
+		return &Violation{Rule: RuleFileSize, Commit: file.Commit, Path: file.Path,
+			Message: fmt.Sprintf("file size %d exceeds the limit %d", file.Size, c.policy.MaxFileSize)}
+	}
+	return nil
+}
+
+// NeedContent reports whether the content of the file should be scanned
+func (c *Checker) NeedContent(file *File) bool {
+	return c.policy.SecretScan && file.Size <= MaxScanSize
+}
+
+// CheckContent scans the content for secrets
+func (c *Checker) CheckContent(file *File, content []byte) error {
+	findings := DetectSecrets(content)
+	if len(findings) <= 0 {
+		return nil
+	}
+	return &Violation{Rule: RuleSecret, Commit: file.Commit, Path: file.Path,
+		Message: fmt.Sprintf("possible %s at line %d", findings[0].Rule, findings[0].Line)}
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pushpolicy
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// SecretRule detects a kind of secret
+type SecretRule struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// SecretRules are the built-in rules of the secret detector
+var SecretRules = []*SecretRule{
+	{Name: "private key", Regexp: regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{Name: "aws access key", Regexp: regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "github token", Regexp: regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b`)},
+	{Name: "gitlab token", Regexp: regexp.MustCompile(`\bglpat-[0-9A-Za-z_\-]{20}\b`)},
+	{Name: "slack token", Regexp: regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z\-]{10,48}\b`)},
+	{Name: "google api key", Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{Name: "password assignment", Regexp: regexp.MustCompile(`(?i)\b(password|passwd|secret|access[_-]?key|api[_-]?key|token)\b["']?\s*[:=]\s*["'][^"'\s$]{8,}["']`)},
+}
+
+// Finding is a secret found in the content
+type Finding struct {
+	Rule string
+	Line int
+}
+
+// DetectSecrets scans the text content line by line, the binary content is skipped
+func DetectSecrets(content []byte) []*Finding {
+	if isBinary(content) {
+		return nil
+	}
+	var findings []*Finding
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		for _, rule := range SecretRules {
+			if rule.Regexp.Match(line) {
+				findings = append(findings, &Finding{Rule: rule.Name, Line: i + 1})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
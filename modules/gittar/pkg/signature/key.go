@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const pgpPublicKeyBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// Key is the public key to verify the signatures
+type Key struct {
+	Type KeyType
+	// Fingerprint is the fingerprint of the gpg primary key or the ssh key
+	Fingerprint string
+	// KeyIDs are the ids of the gpg primary key and subkeys, or the fingerprint of the ssh key
+	KeyIDs []string
+	// Emails are the emails of the gpg identities, or the emails of the owner of the ssh key
+	Emails  []string
+	Content string
+	Owner   string
+}
+
+// ParseKey parses the armored gpg public key or the ssh authorized key
+func ParseKey(content string) (*Key, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, pgpPublicKeyBegin) {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		if len(entities) != 1 {
+			return nil, errors.New("only one gpg public key is allowed")
+		}
+		entity := entities[0]
+		key := &Key{
+			Type:        KeyTypeGPG,
+			Fingerprint: strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint[:])),
+			KeyIDs:      []string{formatKeyID(entity.PrimaryKey.KeyId)},
+			Content:     content,
+		}
+		for _, subkey := range entity.Subkeys {
+			key.KeyIDs = append(key.KeyIDs, formatKeyID(subkey.PublicKey.KeyId))
+		}
+		for _, identity := range entity.Identities {
+			if identity.UserId != nil && identity.UserId.Email != "" {
+				key.Emails = append(key.Emails, identity.UserId.Email)
+			}
+		}
+		return key, nil
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(content))
+	if err != nil {
+		return nil, errors.New("invalid key, an armored gpg public key or a ssh public key is required")
+	}
+	fingerprint := ssh.FingerprintSHA256(pub)
+	return &Key{
+		Type:        KeyTypeSSH,
+		Fingerprint: fingerprint,
+		KeyIDs:      []string{fingerprint},
+		Content:     content,
+	}, nil
+}
+
+// HasEmail returns true if the email belongs to the key, case insensitive
+func (k *Key) HasEmail(email string) bool {
+	for _, e := range k.Emails {
+		if strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify verifies the signature of the object with the key
+func (k *Key) Verify(o *SignedObject) error {
+	if k.Type != o.Type() {
+		return errors.New("key type mismatch")
+	}
+	if k.Type == KeyTypeSSH {
+		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.Content))
+		if err != nil {
+			return err
+		}
+		sig, err := parseSSHSignature(o.Signature)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(sig.PublicKey.Marshal(), pub.Marshal()) {
+			return errors.New("key mismatch")
+		}
+		return sig.verify(o.Payload)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k.Content))
+	if err != nil {
+		return err
+	}
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(o.Payload), bytes.NewReader(o.Signature))
+	return err
+}
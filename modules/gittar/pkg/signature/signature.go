@@ -0,0 +1,225 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signature verifies the GPG and SSH signatures of the commits and tags.
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyType is the type of the signing key
+type KeyType string
+
+const (
+	KeyTypeGPG KeyType = "gpg"
+	KeyTypeSSH KeyType = "ssh"
+)
+
+// Reason is the result of the verification
+type Reason string
+
+const (
+	ReasonValid        Reason = "valid"
+	ReasonUnsigned     Reason = "unsigned"
+	ReasonUnknownKey   Reason = "unknown_key"
+	ReasonBadSignature Reason = "bad_signature"
+	ReasonBadEmail     Reason = "bad_email"
+	ReasonMalformed    Reason = "malformed_signature"
+)
+
+const (
+	pgpSignatureBegin = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureBegin = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// Verification is the signature status of the commit or tag
+type Verification struct {
+	Verified bool    `json:"verified"`
+	Reason   Reason  `json:"reason"`
+	KeyType  KeyType `json:"keyType,omitempty"`
+	// KeyID is the gpg key id or the ssh key fingerprint
+	KeyID string `json:"keyId,omitempty"`
+	// SignerID is the user owns the key
+	SignerID string `json:"signerId,omitempty"`
+}
+
+// SignedObject is the commit or tag with the signature
+type SignedObject struct {
+	// Payload is the content signed, the object without the signature
+	Payload   []byte
+	Signature []byte
+	// Email is the email of the committer or the tagger
+	Email string
+}
+
+// Signed returns true if the object has the signature
+func (o *SignedObject) Signed() bool {
+	return len(o.Signature) > 0
+}
+
+// Type returns the type of the signature
+func (o *SignedObject) Type() KeyType {
+	if bytes.HasPrefix(o.Signature, []byte(sshSignatureBegin)) {
+		return KeyTypeSSH
+	}
+	return KeyTypeGPG
+}
+
+// KeyID returns the gpg key id issued the signature or the fingerprint of the ssh key
+func (o *SignedObject) KeyID() (string, error) {
+	if o.Type() == KeyTypeSSH {
+		sig, err := parseSSHSignature(o.Signature)
+		if err != nil {
+			return "", err
+		}
+		return ssh.FingerprintSHA256(sig.PublicKey), nil
+	}
+	block, err := armor.Decode(bytes.NewReader(o.Signature))
+	if err != nil {
+		return "", err
+	}
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return "", err
+	}
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId != nil {
+			return formatKeyID(*sig.IssuerKeyId), nil
+		}
+	case *packet.SignatureV3:
+		return formatKeyID(sig.IssuerKeyId), nil
+	}
+	return "", errors.New("no issuer in the signature")
+}
+
+func formatKeyID(id uint64) string {
+	return fmt.Sprintf("%016X", id)
+}
+
+// ParseCommit parses the raw commit object, the signature is in the gpgsig header
+func ParseCommit(raw []byte) *SignedObject {
+	o := &SignedObject{}
+	var payload bytes.Buffer
+	var signature []string
+	inSignature := false
+	headers := true
+	lines := strings.SplitAfter(string(raw), "\n")
+	for _, line := range lines {
+		if !headers {
+			payload.WriteString(line)
+			continue
+		}
+		if inSignature && strings.HasPrefix(line, " ") {
+			signature = append(signature, strings.TrimPrefix(line, " "))
+			continue
+		}
+		inSignature = false
+		switch {
+		case line == "\n":
+			headers = false
+		case strings.HasPrefix(line, "gpgsig "), strings.HasPrefix(line, "gpgsig-sha256 "):
+			inSignature = true
+			signature = append(signature, line[strings.IndexByte(line, ' ')+1:])
+			continue
+		case strings.HasPrefix(line, "committer "):
+			o.Email = parseEmail(line)
+		}
+		payload.WriteString(line)
+	}
+	o.Payload = payload.Bytes()
+	if len(signature) > 0 {
+		o.Signature = []byte(strings.Join(signature, ""))
+	}
+	return o
+}
+
+// ParseTag parses the raw tag object, the signature is appended to the message
+func ParseTag(raw []byte) *SignedObject {
+	o := &SignedObject{Payload: raw}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "tagger ") {
+			o.Email = parseEmail(line)
+		}
+	}
+	idx := -1
+	for _, begin := range []string{pgpSignatureBegin, sshSignatureBegin} {
+		if i := bytes.LastIndex(raw, []byte("\n"+begin)); i > idx {
+			idx = i
+		}
+	}
+	if idx >= 0 {
+		o.Payload = raw[:idx+1]
+		o.Signature = raw[idx+1:]
+	}
+	return o
+}
+
+// parseEmail parses the email of the line like: committer name <email> 1600000000 +0800
+func parseEmail(line string) string {
+	start := strings.IndexByte(line, '<')
+	end := strings.LastIndexByte(line, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+// Lookup finds the key by the gpg key id or the ssh fingerprint, returns nil if not found
+type Lookup func(keyType KeyType, keyID string) (*Key, error)
+
+// Verify verifies the signature of the object, the email of the committer or tagger must belong to the key
+func Verify(o *SignedObject, lookup Lookup) (*Verification, error) {
+	if !o.Signed() {
+		return &Verification{Reason: ReasonUnsigned}, nil
+	}
+	v := &Verification{KeyType: o.Type()}
+	keyID, err := o.KeyID()
+	if err != nil {
+		v.Reason = ReasonMalformed
+		return v, nil
+	}
+	v.KeyID = keyID
+	key, err := lookup(v.KeyType, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		v.Reason = ReasonUnknownKey
+		return v, nil
+	}
+	v.SignerID = key.Owner
+	if err := key.Verify(o); err != nil {
+		v.Reason = ReasonBadSignature
+		return v, nil
+	}
+	if !key.HasEmail(o.Email) {
+		v.Reason = ReasonBadEmail
+		return v, nil
+	}
+	v.Verified = true
+	v.Reason = ReasonValid
+	return v, nil
+}
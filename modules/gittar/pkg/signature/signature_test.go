@@ -0,0 +1,260 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+const commitPayload = `tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904
+author dice <dice@erda.cloud> 1600000000 +0800
+committer dice <dice@erda.cloud> 1600000000 +0800
+
+init
+`
+
+func signCommit(payload string, sig []byte) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(sig), "\n"), "\n")
+	header := "gpgsig " + strings.Join(lines, "\n ") + "\n"
+	idx := strings.Index(payload, "\n\n")
+	return []byte(payload[:idx+1] + header + payload[idx+1:])
+}
+
+func newGPGKey(t *testing.T, email string) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("dice", "", email, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return entity, buf.String()
+}
+
+func gpgSign(t *testing.T, entity *openpgp.Entity, payload string) []byte {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, entity, strings.NewReader(payload), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sshSign(t *testing.T, priv ed25519.PrivateKey, payload string) []byte {
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sshsigSignData(sshsigNamespace, "sha512", []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signer.Sign(rand.Reader, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := append([]byte(sshsigMagic), ssh.Marshal(&sshsigBlob{
+		Version:       sshsigVersion,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     sshsigNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	})...)
+	return []byte(sshSignatureBegin + "\n" + base64.StdEncoding.EncodeToString(blob) + "\n" + sshSignatureEnd + "\n")
+}
+
+func lookupKeys(keys ...*Key) Lookup {
+	return func(keyType KeyType, keyID string) (*Key, error) {
+		for _, key := range keys {
+			if key.Type != keyType {
+				continue
+			}
+			for _, id := range key.KeyIDs {
+				if id == keyID {
+					return key, nil
+				}
+			}
+		}
+		return nil, nil
+	}
+}
+
+func TestParseCommit(t *testing.T) {
+	sig := []byte(pgpSignatureBegin + "\n\nabc\n-----END PGP SIGNATURE-----\n")
+	o := ParseCommit(signCommit(commitPayload, sig))
+	if string(o.Payload) != commitPayload {
+		t.Fatalf("payload: %q", o.Payload)
+	}
+	if !bytes.Equal(o.Signature, sig) {
+		t.Fatalf("signature: %q", o.Signature)
+	}
+	if o.Email != "dice@erda.cloud" {
+		t.Fatalf("email: %s", o.Email)
+	}
+
+	o = ParseCommit([]byte(commitPayload))
+	if o.Signed() {
+		t.Fatal("unsigned commit")
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	payload := "object 4b825dc642cb6eb9a060e54bf8d69288fbee4904\ntype commit\ntag v1\ntagger dice <dice@erda.cloud> 1600000000 +0800\n\nrelease\n"
+	sig := pgpSignatureBegin + "\n\nabc\n-----END PGP SIGNATURE-----\n"
+	o := ParseTag([]byte(payload + sig))
+	if string(o.Payload) != payload || string(o.Signature) != sig || o.Email != "dice@erda.cloud" {
+		t.Fatalf("unexpected tag: %+v", o)
+	}
+}
+
+func TestVerifyGPG(t *testing.T) {
+	entity, armored := newGPGKey(t, "dice@erda.cloud")
+	key, err := ParseKey(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Owner = "1"
+	if key.Type != KeyTypeGPG || len(key.KeyIDs) != 2 || !key.HasEmail("DICE@erda.cloud") {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+
+	raw := signCommit(commitPayload, gpgSign(t, entity, commitPayload))
+	v, err := Verify(ParseCommit(raw), lookupKeys(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Verified || v.SignerID != "1" || v.KeyType != KeyTypeGPG {
+		t.Fatalf("unexpected verification: %+v", v)
+	}
+
+	tampered := bytes.Replace(raw, []byte("init"), []byte("evil"), 1)
+	if v, _ = Verify(ParseCommit(tampered), lookupKeys(key)); v.Reason != ReasonBadSignature {
+		t.Fatalf("tampered: %+v", v)
+	}
+	if v, _ = Verify(ParseCommit(raw), lookupKeys()); v.Reason != ReasonUnknownKey {
+		t.Fatalf("unknown key: %+v", v)
+	}
+	if v, _ = Verify(ParseCommit([]byte(commitPayload)), lookupKeys(key)); v.Reason != ReasonUnsigned {
+		t.Fatalf("unsigned: %+v", v)
+	}
+
+	other, otherArmored := newGPGKey(t, "other@erda.cloud")
+	otherKey, err := ParseKey(otherArmored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw = signCommit(commitPayload, gpgSign(t, other, commitPayload))
+	if v, _ = Verify(ParseCommit(raw), lookupKeys(key, otherKey)); v.Reason != ReasonBadEmail {
+		t.Fatalf("bad email: %+v", v)
+	}
+}
+
+func TestVerifySSH(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Emails = []string{"dice@erda.cloud"}
+	if key.Type != KeyTypeSSH || key.Fingerprint != ssh.FingerprintSHA256(sshPub) {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+
+	raw := signCommit(commitPayload, sshSign(t, priv, commitPayload))
+	v, err := Verify(ParseCommit(raw), lookupKeys(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Verified || v.KeyID != key.Fingerprint {
+		t.Fatalf("unexpected verification: %+v", v)
+	}
+
+	tampered := bytes.Replace(raw, []byte("init"), []byte("evil"), 1)
+	if v, _ = Verify(ParseCommit(tampered), lookupKeys(key)); v.Reason != ReasonBadSignature {
+		t.Fatalf("tampered: %+v", v)
+	}
+}
+
+// TestVerifyGitSSHSigned verifies the commit signed by git with ssh-keygen
+func TestVerifyGitSSHSigned(t *testing.T) {
+	for _, bin := range []string{"git", "ssh-keygen"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found", bin)
+		}
+	}
+	dir, err := ioutil.TempDir("", "signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "id_ed25519")
+	run := func(name string, args ...string) []byte {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "HOME="+dir, "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Skipf("%s %v: %v, %s", name, args, err, out)
+		}
+		return out
+	}
+	run("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyFile)
+	run("git", "init", "-q", ".")
+	run("git", "-c", "user.name=dice", "-c", "user.email=dice@erda.cloud", "-c", "gpg.format=ssh",
+		"-c", "user.signingkey="+keyFile, "commit", "-q", "-S", "--allow-empty", "-m", "init")
+	raw := run("git", "cat-file", "commit", "HEAD")
+
+	pub, err := ioutil.ReadFile(keyFile + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(string(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Emails = []string{"dice@erda.cloud"}
+	v, err := Verify(ParseCommit(raw), lookupKeys(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Verified {
+		t.Fatalf("unexpected verification: %+v", v)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The ssh signature format, see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+const (
+	sshsigMagic     = "SSHSIG"
+	sshsigVersion   = 1
+	sshsigNamespace = "git"
+	sshSignatureEnd = "-----END SSH SIGNATURE-----"
+)
+
+type sshSignature struct {
+	PublicKey     ssh.PublicKey
+	Namespace     string
+	HashAlgorithm string
+	Signature     *ssh.Signature
+}
+
+type sshsigBlob struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+type sshsigSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func parseSSHSignature(armored []byte) (*sshSignature, error) {
+	content := strings.TrimSpace(string(armored))
+	if !strings.HasPrefix(content, sshSignatureBegin) || !strings.HasSuffix(content, sshSignatureEnd) {
+		return nil, errors.New("invalid ssh signature armor")
+	}
+	content = strings.TrimSuffix(strings.TrimPrefix(content, sshSignatureBegin), sshSignatureEnd)
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(sshsigMagic)) {
+		return nil, errors.New("invalid ssh signature magic")
+	}
+	var blob sshsigBlob
+	if err = ssh.Unmarshal(data[len(sshsigMagic):], &blob); err != nil {
+		return nil, err
+	}
+	if blob.Version != sshsigVersion {
+		return nil, fmt.Errorf("unsupported ssh signature version %d", blob.Version)
+	}
+	pub, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sig := new(ssh.Signature)
+	if err = ssh.Unmarshal(blob.Signature, sig); err != nil {
+		return nil, err
+	}
+	return &sshSignature{
+		PublicKey:     pub,
+		Namespace:     blob.Namespace,
+		HashAlgorithm: blob.HashAlgorithm,
+		Signature:     sig,
+	}, nil
+}
+
+func sshsigHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported ssh signature hash algorithm %s", algorithm)
+}
+
+// sshsigSignData returns the data signed by the key
+func sshsigSignData(namespace, algorithm string, message []byte) ([]byte, error) {
+	h, err := sshsigHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(message)
+	return append([]byte(sshsigMagic), ssh.Marshal(&sshsigSignedData{
+		Namespace:     namespace,
+		HashAlgorithm: algorithm,
+		Hash:          h.Sum(nil),
+	})...), nil
+}
+
+func (s *sshSignature) verify(message []byte) error {
+	if s.Namespace != sshsigNamespace {
+		return fmt.Errorf("invalid ssh signature namespace %s", s.Namespace)
+	}
+	data, err := sshsigSignData(s.Namespace, s.HashAlgorithm, message)
+	if err != nil {
+		return err
+	}
+	return s.PublicKey.Verify(data, s.Signature)
+}
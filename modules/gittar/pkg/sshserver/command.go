@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshserver
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ServiceUploadPack  = "upload-pack"
+	ServiceReceivePack = "receive-pack"
+)
+
+// Command is the git command requested by the ssh client, e.g. git-upload-pack 'org/dop/project/app.git'
+type Command struct {
+	Service string
+	Path    string
+}
+
+// ParseCommand parses the exec command, both "git-upload-pack" and "git upload-pack" are accepted
+func ParseCommand(cmd string) (*Command, error) {
+	cmd = strings.TrimSpace(cmd)
+	var service string
+	for _, prefix := range []string{"git-", "git "} {
+		if strings.HasPrefix(cmd, prefix) {
+			cmd = strings.TrimSpace(cmd[len(prefix):])
+			idx := strings.IndexByte(cmd, ' ')
+			if idx < 0 {
+				return nil, fmt.Errorf("missing repository path")
+			}
+			service, cmd = cmd[:idx], strings.TrimSpace(cmd[idx+1:])
+			break
+		}
+	}
+	if service != ServiceUploadPack && service != ServiceReceivePack {
+		return nil, fmt.Errorf("unsupported command")
+	}
+
+	path := cmd
+	if len(path) >= 2 && (path[0] == '\'' || path[0] == '"') && path[len(path)-1] == path[0] {
+		path = path[1 : len(path)-1]
+	}
+	path = strings.Trim(path, "/")
+	if len(path) <= 0 || strings.ContainsAny(path, "'\"\\ ") {
+		return nil, fmt.Errorf("invalid repository path %q", path)
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." {
+			return nil, fmt.Errorf("invalid repository path %q", path)
+		}
+	}
+	return &Command{Service: service, Path: path}, nil
+}
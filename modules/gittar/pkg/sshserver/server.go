@@ -0,0 +1,308 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sshserver serves the git commands over ssh, the authentication and the git commands are delegated to the callers.
+package sshserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const userIDExtension = "user-id"
+
+// ErrServerClosed is returned by Serve after Close
+var ErrServerClosed = errors.New("ssh: server closed")
+
+// Session is the git command requested in a ssh session
+type Session struct {
+	*Command
+	UserID     string
+	RemoteAddr net.Addr
+	// GitProtocol is the GIT_PROTOCOL env sent by the client, e.g. version=2
+	GitProtocol string
+	Stdin       io.Reader
+	Stdout      io.Writer
+	Stderr      io.Writer
+}
+
+// Authenticator returns the user ID of the public key
+type Authenticator func(conn ssh.ConnMetadata, key ssh.PublicKey) (userID string, err error)
+
+// Handler runs the git command and returns the exit status
+type Handler func(ctx context.Context, s *Session) int
+
+// Config .
+type Config struct {
+	Addr string
+	// HostKeyPath is generated if not exist, an ephemeral key is used if empty
+	HostKeyPath      string
+	HandshakeTimeout time.Duration
+}
+
+// Server .
+type Server struct {
+	cfg       *Config
+	sshConfig *ssh.ServerConfig
+	handler   Handler
+
+	lock     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// New .
+func New(cfg *Config, auth Authenticator, handler Handler) (*Server, error) {
+	signer, err := loadHostKey(cfg.HostKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh host key: %w", err)
+	}
+	if cfg.HandshakeTimeout <= 0 {
+		cfg.HandshakeTimeout = 30 * time.Second
+	}
+	sshConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			userID, err := auth(conn, key)
+			if err != nil {
+				return nil, err
+			}
+			return &ssh.Permissions{Extensions: map[string]string{userIDExtension: userID}}, nil
+		},
+	}
+	sshConfig.AddHostKey(signer)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		cfg:       cfg,
+		sshConfig: sshConfig,
+		handler:   handler,
+		conns:     make(map[net.Conn]struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// ListenAndServe .
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts the connections until the listener is closed
+func (s *Server) Serve(l net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.lock.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		if !s.track(conn, true) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close closes the listener and all the connections
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.cancel()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return err
+}
+
+func (s *Server) track(conn net.Conn, add bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+	return true
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.track(conn, false)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.cfg.HandshakeTimeout))
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.sshConfig)
+	if err != nil {
+		logrus.Debugf("ssh handshake from %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			logrus.Errorf("failed to accept ssh channel: %v", err)
+			continue
+		}
+		go s.handleSession(sconn, channel, requests)
+	}
+}
+
+func (s *Server) handleSession(sconn *ssh.ServerConn, channel ssh.Channel, requests <-chan *ssh.Request) {
+	var (
+		gitProtocol string
+		started     bool
+	)
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			var env struct{ Name, Value string }
+			if err := ssh.Unmarshal(req.Payload, &env); err == nil && env.Name == "GIT_PROTOCOL" {
+				gitProtocol = env.Value
+			}
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if started || ssh.Unmarshal(req.Payload, &payload) != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			cmd, err := ParseCommand(payload.Command)
+			if err != nil {
+				req.Reply(true, nil)
+				fmt.Fprintf(channel.Stderr(), "%s\n", err)
+				exit(channel, 1)
+				continue
+			}
+			started = true
+			req.Reply(true, nil)
+			session := &Session{
+				Command:     cmd,
+				UserID:      sconn.Permissions.Extensions[userIDExtension],
+				RemoteAddr:  sconn.RemoteAddr(),
+				GitProtocol: gitProtocol,
+				Stdin:       channel,
+				Stdout:      channel,
+				Stderr:      channel.Stderr(),
+			}
+			go func() {
+				exit(channel, s.handler(s.ctx, session))
+			}()
+		case "shell":
+			// 不提供交互式 shell
+			req.Reply(true, nil)
+			fmt.Fprintf(channel.Stderr(), "Hi, you've successfully authenticated, but interactive shell access is not provided.\n")
+			exit(channel, 1)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func exit(channel ssh.Channel, status int) {
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	channel.Close()
+}
+
+func loadHostKey(path string) (ssh.Signer, error) {
+	if len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			return ssh.ParsePrivateKey(data)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		logrus.Infof("ssh host key generated at %s", path)
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// Fingerprint returns the SHA256 fingerprint of the authorized key, e.g. "ssh-ed25519 AAAA... comment"
+func Fingerprint(authorizedKey string) (fingerprint string, key ssh.PublicKey, err error) {
+	key, _, _, _, err = ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return ssh.FingerprintSHA256(key), key, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		cmd     string
+		want    *Command
+		wantErr bool
+	}{
+		{cmd: "git-upload-pack 'erda/dop/project/app.git'", want: &Command{Service: ServiceUploadPack, Path: "erda/dop/project/app.git"}},
+		{cmd: "git-receive-pack '/erda/dop/project/app'", want: &Command{Service: ServiceReceivePack, Path: "erda/dop/project/app"}},
+		{cmd: "git upload-pack erda/dop/project/app", want: &Command{Service: ServiceUploadPack, Path: "erda/dop/project/app"}},
+		{cmd: "git-upload-archive 'erda/dop/project/app'", wantErr: true},
+		{cmd: "ls -al", wantErr: true},
+		{cmd: "git-upload-pack", wantErr: true},
+		{cmd: "git-upload-pack '../../etc'", wantErr: true},
+		{cmd: "git-upload-pack 'a/b'; rm -rf /", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCommand(tt.cmd)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCommand(%q) error = %v, wantErr %v", tt.cmd, err, tt.wantErr)
+			continue
+		}
+		if err == nil && *got != *tt.want {
+			t.Errorf("ParseCommand(%q) = %+v, want %+v", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(key)
+	authorized := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	fingerprint, _, err := Fingerprint(authorized)
+	if err != nil {
+		t.Fatalf("Fingerprint() error: %s", err)
+	}
+
+	auth := func(conn ssh.ConnMetadata, key ssh.PublicKey) (string, error) {
+		if ssh.FingerprintSHA256(key) == fingerprint {
+			return "1001", nil
+		}
+		return "", errors.New("unknown key")
+	}
+	handler := func(ctx context.Context, s *Session) int {
+		data, _ := ioutil.ReadAll(s.Stdin)
+		s.Stdout.Write([]byte(s.UserID + " " + s.Service + " " + s.Path + " " + s.GitProtocol + " " + string(data)))
+		return 3
+	}
+	hostKeyPath := filepath.Join(t.TempDir(), "ssh", "host_key")
+	srv, err := New(&Config{HostKeyPath: hostKeyPath}, auth, handler)
+	if err != nil {
+		t.Fatalf("New() error: %s", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	// the generated host key is reused
+	if _, err := New(&Config{HostKeyPath: hostKeyPath}, auth, handler); err != nil {
+		t.Fatalf("New() with generated host key error: %s", err)
+	}
+
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "git",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Dial() error: %s", err)
+	}
+	defer client.Close()
+
+	session, _ := client.NewSession()
+	session.Setenv("GIT_PROTOCOL", "version=2")
+	session.Stdin = strings.NewReader("0000")
+	output, err := session.Output("git-upload-pack 'erda/dop/project/app.git'")
+	if exitErr, ok := err.(*ssh.ExitError); !ok || exitErr.ExitStatus() != 3 {
+		t.Errorf("exit error = %v, want exit status 3", err)
+	}
+	if want := "1001 upload-pack erda/dop/project/app.git version=2 0000"; string(output) != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+
+	session, _ = client.NewSession()
+	if err := session.Run("ls"); err == nil {
+		t.Errorf("Run() unsupported command should fail")
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+	_, err = ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "git",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(otherSigner)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err == nil {
+		t.Errorf("Dial() with unknown key should fail")
+	}
+}
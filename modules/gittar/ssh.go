@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gittar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/erda-project/erda/modules/gittar/api"
+	"github.com/erda-project/erda/modules/gittar/auth"
+	"github.com/erda-project/erda/modules/gittar/conf"
+	"github.com/erda-project/erda/modules/gittar/models"
+	"github.com/erda-project/erda/modules/gittar/pkg/sshserver"
+	"github.com/erda-project/erda/modules/gittar/webcontext"
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+// newSSHServer serves the repositories over ssh, the users are authenticated by their public keys
+func newSSHServer(dbClient *models.DBClient) (*sshserver.Server, error) {
+	svc := models.NewService(dbClient, nil)
+	authenticate := func(conn ssh.ConnMetadata, key ssh.PublicKey) (string, error) {
+		userKey, err := svc.FindUserKeyByFingerprint(ssh.FingerprintSHA256(key))
+		if err != nil {
+			return "", errors.New("unknown public key")
+		}
+		return userKey.UserID, nil
+	}
+	return sshserver.New(&sshserver.Config{
+		Addr:        ":" + conf.SSHPort(),
+		HostKeyPath: conf.SSHHostKeyPath(),
+	}, authenticate, serveSSH)
+}
+
+// serveSSH serves the path like http: <org>/dop/<project>/<app>
+func serveSSH(ctx context.Context, s *sshserver.Session) int {
+	parts := strings.Split(s.Path, "/")
+	if len(parts) != 4 || parts[1] != "dop" {
+		fmt.Fprintf(s.Stderr, "repository %s not found, the path should be <org>/dop/<project>/<app>\n", s.Path)
+		return 1
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+s.Path+"/git-"+s.Service, ioutil.NopCloser(s.Stdin))
+	if err != nil {
+		fmt.Fprintln(s.Stderr, err)
+		return 1
+	}
+	req.Header.Set(httputil.UserHeader, s.UserID)
+	req.Header.Set("Git-Protocol", s.GitProtocol)
+
+	w := &sshResponseWriter{header: http.Header{}, stdout: s.Stdout, stderr: s.Stderr}
+	webcontext.ServeSSH(req, w,
+		[]string{"org", "project", "app", "service"},
+		[]string{parts[0], parts[2], parts[3], s.Service},
+		auth.AuthenticateV3, api.ServiceRepoSSH,
+	)
+	if w.status >= http.StatusBadRequest {
+		return 1
+	}
+	return 0
+}
+
+// sshResponseWriter writes the git output to the ssh channel, and the errors to stderr
+type sshResponseWriter struct {
+	header http.Header
+	status int
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (w *sshResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *sshResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *sshResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest {
+		return w.stderr.Write(data)
+	}
+	return w.stdout.Write(data)
+}
+
+func (w *sshResponseWriter) Flush() {}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webcontext
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+var sshEcho = echo.New()
+
+// ServeSSH runs the middleware and the handler on the request made from the ssh session,
+// so that the git commands over ssh go through the same authentication and permission checks as http.
+// It returns false if the middleware aborts.
+func ServeSSH(req *http.Request, w http.ResponseWriter, paramNames, paramValues []string,
+	middleware ContextHandlerFunc, handler ContextHandlerFunc) bool {
+	c := sshEcho.NewContext(req, w)
+	c.SetParamNames(paramNames...)
+	c.SetParamValues(paramValues...)
+	ctx := NewEchoContext(c, dbClientInstance)
+	middleware(ctx)
+	if !ctx.next {
+		return false
+	}
+	// the repository and the user are set by the middleware
+	handler(NewEchoContext(c, dbClientInstance))
+	return true
+}
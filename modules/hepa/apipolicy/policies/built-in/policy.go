@@ -156,6 +156,9 @@ func (policy Policy) touchPluginIfNeed(zoneId string, builtinPlugins []string, p
 		if err != nil {
 			return false, err
 		}
+		if resp == nil {
+			return false, errors.Errorf("%s plugin is not enabled", pluginName)
+		}
 		configByte, err := json.Marshal(resp.Config)
 		if err != nil {
 			return false, err
@@ -172,6 +175,9 @@ func (policy Policy) touchPluginIfNeed(zoneId string, builtinPlugins []string, p
 	if err != nil {
 		return false, err
 	}
+	if resp == nil {
+		return false, errors.Errorf("%s plugin is not enabled", pluginName)
+	}
 	configByte, err := json.Marshal(resp.Config)
 	if err != nil {
 		return false, err
@@ -165,6 +165,9 @@ send_timeout %ds;
 		if err != nil {
 			return res, err
 		}
+		if resp == nil {
+			return res, errors.Errorf("%s plugin is not enabled", kongReq.Name)
+		}
 		configByte, err := json.Marshal(resp.Config)
 		if err != nil {
 			return res, err
@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficsplit
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/modules/hepa/apipolicy"
+)
+
+const (
+	// RULE_HEADER is set by the ingress with the index of the matched rule, kong routes the request by it
+	RULE_HEADER = "X-Erda-Traffic-Rule"
+	// ROUTE_TAG tags the kong routes created for the rules of the zone
+	ROUTE_TAG = "traffic_split_zone"
+	// UPSTREAM_SUFFIX is the suffix of the kong upstream created by the policy
+	UPSTREAM_SUFFIX = ".traffic-split"
+	MAX_WEIGHT      = 1000
+)
+
+type MatchType string
+
+const (
+	MATCH_HEADER MatchType = "header"
+	MATCH_COOKIE MatchType = "cookie"
+	MATCH_QUERY  MatchType = "query"
+)
+
+// Backend is a runtime service or address the traffic is split to
+type Backend struct {
+	Name string `json:"name"`
+	// 关联的 runtime service, 与 Target 二选一
+	RuntimeServiceId string `json:"runtimeServiceId,omitempty"`
+	// 后端地址 host:port
+	Target string `json:"target,omitempty"`
+	// 按权重分流, 为 0 时只接收匹配规则的流量
+	Weight int64 `json:"weight"`
+}
+
+// MatchRule routes the matched requests to the backend, the first matched rule wins
+type MatchRule struct {
+	Type    MatchType `json:"type"`
+	Key     string    `json:"key"`
+	Value   string    `json:"value"`
+	Backend string    `json:"backend"`
+}
+
+type PolicyDto struct {
+	apipolicy.BaseDto
+	Backends []Backend   `json:"backends"`
+	Rules    []MatchRule `json:"rules,omitempty"`
+}
+
+var (
+	nameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+	keyRegex  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+func (dto PolicyDto) IsValidDto() (bool, string) {
+	if !dto.Switch {
+		return true, ""
+	}
+	if len(dto.Backends) == 0 {
+		return false, "需要至少一个后端服务"
+	}
+	backends := map[string]bool{}
+	var totalWeight int64
+	for _, backend := range dto.Backends {
+		if !nameRegex.MatchString(backend.Name) {
+			return false, fmt.Sprintf("后端名称非法: %s, 只能包含小写字母、数字和-", backend.Name)
+		}
+		if backends[backend.Name] {
+			return false, fmt.Sprintf("后端名称重复: %s", backend.Name)
+		}
+		backends[backend.Name] = true
+		if (backend.RuntimeServiceId == "") == (backend.Target == "") {
+			return false, fmt.Sprintf("后端 %s 需要指定 runtime service 或者地址", backend.Name)
+		}
+		if backend.Target != "" {
+			if _, port, err := net.SplitHostPort(backend.Target); err != nil || port == "" {
+				return false, fmt.Sprintf("后端地址非法: %s, 格式为 host:port", backend.Target)
+			}
+		}
+		if backend.Weight < 0 || backend.Weight > MAX_WEIGHT {
+			return false, fmt.Sprintf("后端 %s 的权重需要在 0 到 %d 之间", backend.Name, MAX_WEIGHT)
+		}
+		totalWeight += backend.Weight
+	}
+	if totalWeight <= 0 {
+		return false, "后端权重之和需要大于0"
+	}
+	for _, rule := range dto.Rules {
+		if rule.Type != MATCH_HEADER && rule.Type != MATCH_COOKIE && rule.Type != MATCH_QUERY {
+			return false, fmt.Sprintf("匹配类型非法: %s", rule.Type)
+		}
+		if !keyRegex.MatchString(rule.Key) || (rule.Type != MATCH_HEADER && strings.Contains(rule.Key, "-")) {
+			return false, fmt.Sprintf("匹配字段非法: %s", rule.Key)
+		}
+		if rule.Value == "" || strings.ContainsAny(rule.Value, "\"'\\;{}$ \t\r\n") {
+			return false, fmt.Sprintf("匹配值非法: %s, 不能为空或包含引号、空白和 \\;{}$", rule.Value)
+		}
+		if !backends[rule.Backend] {
+			return false, fmt.Sprintf("规则关联的后端不存在: %s", rule.Backend)
+		}
+	}
+	return true, ""
+}
+
+// UpstreamName returns the kong upstream of the weighted backends
+func UpstreamName(zoneId string) string {
+	return zoneId + UPSTREAM_SUFFIX
+}
+
+// Weights returns the weights of the targets, the backends without weight only serve the rules
+func Weights(backends []Backend, targets map[string]string) map[string]int64 {
+	weights := map[string]int64{}
+	for _, backend := range backends {
+		if backend.Weight > 0 {
+			weights[targets[backend.Name]] += backend.Weight
+		}
+	}
+	return weights
+}
+
+// nginxVariable returns the nginx variable of the matched field
+func (rule MatchRule) nginxVariable() string {
+	switch rule.Type {
+	case MATCH_COOKIE:
+		return "$cookie_" + rule.Key
+	case MATCH_QUERY:
+		return "$arg_" + rule.Key
+	}
+	return "$http_" + strings.ReplaceAll(strings.ToLower(rule.Key), "-", "_")
+}
+
+// LocationSnippet sets the rule header by the matched rule on the ingress,
+// the header sent by the client is always overwritten.
+func (dto PolicyDto) LocationSnippet() string {
+	if len(dto.Rules) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("set $erda_traffic_rule \"\";\n")
+	// nginx has no else, the later if overwrites so the first rule is written last to win
+	for i := len(dto.Rules) - 1; i >= 0; i-- {
+		rule := dto.Rules[i]
+		sb.WriteString(fmt.Sprintf("if (%s = \"%s\") {\n    set $erda_traffic_rule \"%s\";\n}\n",
+			rule.nginxVariable(), rule.Value, RuleHeaderValue(i)))
+	}
+	sb.WriteString("proxy_set_header " + RULE_HEADER + " $erda_traffic_rule;\n")
+	return sb.String()
+}
+
+// RuleHeaderValue is the value of the rule header set by the ingress for the rule
+func RuleHeaderValue(index int) string {
+	return strconv.Itoa(index)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficsplit_test
+
+import (
+	"strings"
+	"testing"
+
+	trafficsplit "github.com/erda-project/erda/modules/hepa/apipolicy/policies/traffic-split"
+)
+
+func newDto() trafficsplit.PolicyDto {
+	dto := trafficsplit.PolicyDto{
+		Backends: []trafficsplit.Backend{
+			{Name: "stable", Target: "10.0.0.1:8080", Weight: 90},
+			{Name: "canary", RuntimeServiceId: "abc", Weight: 10},
+		},
+		Rules: []trafficsplit.MatchRule{
+			{Type: trafficsplit.MATCH_HEADER, Key: "X-Canary", Value: "true", Backend: "canary"},
+			{Type: trafficsplit.MATCH_COOKIE, Key: "version", Value: "v1", Backend: "stable"},
+		},
+	}
+	dto.Switch = true
+	return dto
+}
+
+func TestPolicyDto_IsValidDto(t *testing.T) {
+	dto := newDto()
+	if ok, msg := dto.IsValidDto(); !ok {
+		t.Fatalf("should be valid: %s", msg)
+	}
+
+	cases := map[string]func(dto *trafficsplit.PolicyDto){
+		"no backend":        func(dto *trafficsplit.PolicyDto) { dto.Backends = nil },
+		"invalid name":      func(dto *trafficsplit.PolicyDto) { dto.Backends[0].Name = "Stable" },
+		"duplicated name":   func(dto *trafficsplit.PolicyDto) { dto.Backends[1].Name = "stable" },
+		"no target":         func(dto *trafficsplit.PolicyDto) { dto.Backends[0].Target = "" },
+		"both target":       func(dto *trafficsplit.PolicyDto) { dto.Backends[1].Target = "10.0.0.2:8080" },
+		"invalid target":    func(dto *trafficsplit.PolicyDto) { dto.Backends[0].Target = "10.0.0.1" },
+		"invalid weight":    func(dto *trafficsplit.PolicyDto) { dto.Backends[0].Weight = -1 },
+		"zero total weight": func(dto *trafficsplit.PolicyDto) { dto.Backends[0].Weight, dto.Backends[1].Weight = 0, 0 },
+		"invalid type":      func(dto *trafficsplit.PolicyDto) { dto.Rules[0].Type = "path" },
+		"invalid key":       func(dto *trafficsplit.PolicyDto) { dto.Rules[1].Key = "my-version" },
+		"invalid value":     func(dto *trafficsplit.PolicyDto) { dto.Rules[0].Value = `"; deny all; "` },
+		"unknown backend":   func(dto *trafficsplit.PolicyDto) { dto.Rules[0].Backend = "beta" },
+	}
+	for name, modify := range cases {
+		dto := newDto()
+		modify(&dto)
+		if ok, _ := dto.IsValidDto(); ok {
+			t.Errorf("%s: should be invalid", name)
+		}
+	}
+}
+
+func TestPolicyDto_LocationSnippet(t *testing.T) {
+	dto := newDto()
+	snippet := dto.LocationSnippet()
+	header := strings.Index(snippet, `if ($http_x_canary = "true")`)
+	cookie := strings.Index(snippet, `if ($cookie_version = "v1")`)
+	if header < 0 || cookie < 0 {
+		t.Fatalf("invalid snippet:\n%s", snippet)
+	}
+	// the first rule is written last to win
+	if header < cookie {
+		t.Fatalf("the first rule should be written last:\n%s", snippet)
+	}
+	if !strings.HasSuffix(snippet, "proxy_set_header "+trafficsplit.RULE_HEADER+" $erda_traffic_rule;\n") {
+		t.Fatalf("the rule header should always be set:\n%s", snippet)
+	}
+
+	dto.Rules = nil
+	if dto.LocationSnippet() != "" {
+		t.Fatal("no snippet without rules")
+	}
+}
+
+func TestWeights(t *testing.T) {
+	backends := []trafficsplit.Backend{
+		{Name: "a", Weight: 50},
+		{Name: "b", Weight: 50},
+		{Name: "c", Weight: 0},
+	}
+	weights := trafficsplit.Weights(backends, map[string]string{"a": "10.0.0.1:80", "b": "10.0.0.1:80", "c": "10.0.0.2:80"})
+	if len(weights) != 1 || weights["10.0.0.1:80"] != 100 {
+		t.Fatalf("unexpected weights: %v", weights)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficsplit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	gw "github.com/erda-project/erda/modules/hepa/gateway/dto"
+	"github.com/erda-project/erda/modules/hepa/kong"
+	kongDto "github.com/erda-project/erda/modules/hepa/kong/dto"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+	db "github.com/erda-project/erda/modules/hepa/repository/service"
+)
+
+type zoneApi struct {
+	api     *orm.GatewayPackageApi
+	service *orm.GatewayService
+	route   *orm.GatewayRoute
+	// current is the kong service of the api, whose timeouts and retries are kept
+	current *kongDto.KongServiceRespDto
+}
+
+// getZoneApi gets the api of the zone and its kong service and route
+func getZoneApi(zone *orm.GatewayZone) (*zoneApi, error) {
+	if zone.Type != db.ZONE_TYPE_PACKAGE_API {
+		return nil, errors.New("流量切分策略只能在 API 上配置")
+	}
+	apiDb, err := db.NewGatewayPackageApiServiceImpl()
+	if err != nil {
+		return nil, err
+	}
+	api, err := apiDb.GetByAny(&orm.GatewayPackageApi{ZoneId: zone.Id})
+	if err != nil {
+		return nil, err
+	}
+	if api == nil {
+		return nil, errors.Errorf("api of zone %s not found", zone.Id)
+	}
+	serviceDb, err := db.NewGatewayServiceServiceImpl()
+	if err != nil {
+		return nil, err
+	}
+	service, err := serviceDb.GetByApiId(api.Id)
+	if err != nil {
+		return nil, err
+	}
+	routeDb, err := db.NewGatewayRouteServiceImpl()
+	if err != nil {
+		return nil, err
+	}
+	route, err := routeDb.GetByApiId(api.Id)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil || route == nil {
+		return nil, errors.New("流量切分策略只支持转发到地址的 API")
+	}
+	return &zoneApi{api: api, service: service, route: route}, nil
+}
+
+// resolveTargets returns the address of the backends
+func resolveTargets(backends []Backend) (map[string]string, error) {
+	runtimeDb, err := db.NewGatewayRuntimeServiceServiceImpl()
+	if err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, backend := range backends {
+		if backend.Target != "" {
+			targets[backend.Name] = backend.Target
+			continue
+		}
+		runtimeService, err := runtimeDb.Get(backend.RuntimeServiceId)
+		if err != nil {
+			return nil, err
+		}
+		if runtimeService == nil || runtimeService.InnerAddress == "" {
+			return nil, errors.Errorf("runtime service %s of backend %s not found", backend.RuntimeServiceId, backend.Name)
+		}
+		targets[backend.Name] = runtimeService.InnerAddress
+	}
+	return targets, nil
+}
+
+func supportRules(adapter kong.KongAdapter) bool {
+	version, err := adapter.GetVersion()
+	return err == nil && strings.HasPrefix(version, "2.")
+}
+
+// getCurrentService gets the kong service of the api
+func getCurrentService(adapter kong.KongAdapter, api *zoneApi) error {
+	current, err := adapter.GetService(api.service.ServiceId)
+	if err != nil {
+		return err
+	}
+	api.current = current
+	return nil
+}
+
+// serviceReq points the service to the host, the timeouts and retries of the current kong service are kept
+func serviceReq(api *zoneApi, host string) *kongDto.KongServiceReqDto {
+	retries := 0
+	url := fmt.Sprintf("%s://%s%s", api.service.Protocol, host, api.service.Path)
+	req := &kongDto.KongServiceReqDto{
+		Url:            url,
+		ConnectTimeout: 5000,
+		ReadTimeout:    60000,
+		WriteTimeout:   60000,
+		Retries:        &retries,
+	}
+	if api.current == nil {
+		return req
+	}
+	if api.current.ConnectTimeout > 0 {
+		req.ConnectTimeout = api.current.ConnectTimeout
+	}
+	if api.current.ReadTimeout > 0 {
+		req.ReadTimeout = api.current.ReadTimeout
+	}
+	if api.current.WriteTimeout > 0 {
+		req.WriteTimeout = api.current.WriteTimeout
+	}
+	if api.current.Retries != nil {
+		req.Retries = api.current.Retries
+	}
+	return req
+}
+
+// touchUpstream creates the upstream if not exist and reconciles its targets
+func touchUpstream(adapter kong.KongAdapter, name string, weights map[string]int64) error {
+	upstream, err := adapter.GetUpstream(name)
+	if err != nil {
+		return err
+	}
+	if upstream == nil {
+		upstream, err = adapter.CreateUpstream(&kongDto.KongUpstreamDto{Name: name})
+		if err != nil {
+			return err
+		}
+	}
+	status, err := adapter.GetUpstreamStatus(upstream.Id)
+	if err != nil {
+		return err
+	}
+	missing := map[string]int64{}
+	for target, weight := range weights {
+		missing[target] = weight
+	}
+	for _, target := range status.Data {
+		if weight, ok := missing[target.Target]; ok && weight == target.Weight {
+			delete(missing, target.Target)
+			continue
+		}
+		err = adapter.DeleteUpstreamTarget(upstream.Id, target.Id)
+		if err != nil {
+			return err
+		}
+	}
+	for target, weight := range missing {
+		_, err = adapter.AddUpstreamTarget(upstream.Id, &kongDto.KongTargetDto{
+			Target: target,
+			Weight: weight,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearRuleRoutes deletes the routes of the rules and their services
+func clearRuleRoutes(adapter kong.KongAdapter, zoneId string) error {
+	routes, err := adapter.GetRoutesWithTag(ROUTE_TAG + "~" + zoneId)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		err = adapter.DeleteRoute(route.Id)
+		if err != nil {
+			return err
+		}
+		if route.Service.Id != "" {
+			err = adapter.DeleteService(route.Service.Id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createRuleRoute creates the route matches the rule header with the same hosts and paths of the api,
+// and binds all the plugins of the api route to it
+func createRuleRoute(adapter kong.KongAdapter, zone *orm.GatewayZone, api *zoneApi, index int, target string) error {
+	service, err := adapter.CreateOrUpdateService(serviceReq(api, target))
+	if err != nil {
+		return err
+	}
+	req := kongDto.NewKongRouteReqDto()
+	_ = json.Unmarshal([]byte(api.route.Protocols), &req.Protocols)
+	_ = json.Unmarshal([]byte(api.route.Methods), &req.Methods)
+	_ = json.Unmarshal([]byte(api.route.Hosts), &req.Hosts)
+	_ = json.Unmarshal([]byte(api.route.Paths), &req.Paths)
+	req.Headers = map[string][]string{RULE_HEADER: {RuleHeaderValue(index)}}
+	req.Service = &kongDto.Service{Id: service.Id}
+	req.AddTag(ROUTE_TAG, zone.Id)
+	req.AddTag("package_api_id", api.api.Id)
+	route, err := adapter.CreateOrUpdateRoute(req)
+	if err != nil {
+		return err
+	}
+	return syncRoutePlugins(adapter, api.route.RouteId, route.Id, zoneEnables(zone))
+}
+
+// zoneEnables returns the plugins enabled by the domain policy of the zone, the api rule plugins
+// are created disabled and only run on the requests matching the domain policy
+func zoneEnables(zone *orm.GatewayZone) map[string]bool {
+	enables := map[string]bool{}
+	if len(zone.KongPolicies) == 0 {
+		return enables
+	}
+	policies := gw.ZoneKongPolicies{}
+	if err := json.Unmarshal(zone.KongPolicies, &policies); err != nil {
+		logrus.Errorf("unmarshal kong policies of zone %s failed, err:%+v", zone.Id, err)
+		return enables
+	}
+	for _, id := range strings.Split(policies.Enables, ",") {
+		if id != "" {
+			enables[id] = true
+		}
+	}
+	return enables
+}
+
+// syncRoutePlugins makes the plugins of the rule route the same as the api route, so the rule route
+// can't bypass the auth, acl and limit plugins of the api, the plugins are updated in place to
+// never leave the rule route unprotected
+func syncRoutePlugins(adapter kong.KongAdapter, from, to string, enables map[string]bool) error {
+	plugins, err := adapter.GetRoutePlugins(from)
+	if err != nil {
+		return err
+	}
+	exists, err := adapter.GetRoutePlugins(to)
+	if err != nil {
+		return err
+	}
+	stale := map[string]string{}
+	for _, plugin := range exists {
+		stale[plugin.Name+"/"+plugin.ConsumerId] = plugin.Id
+	}
+	for _, plugin := range plugins {
+		enabled := plugin.Enabled || enables[plugin.Id]
+		req := &kongDto.KongPluginReqDto{
+			Name:       plugin.Name,
+			RouteId:    to,
+			ConsumerId: plugin.ConsumerId,
+			Config:     plugin.Config,
+			Enabled:    &enabled,
+		}
+		var resp *kongDto.KongPluginRespDto
+		key := plugin.Name + "/" + plugin.ConsumerId
+		if id, ok := stale[key]; ok {
+			delete(stale, key)
+			req.PluginId = id
+			resp, err = adapter.PutPlugin(req)
+		} else {
+			resp, err = adapter.AddPlugin(req)
+		}
+		if err != nil {
+			return err
+		}
+		if resp == nil {
+			return errors.Errorf("plugin %s of route %s can't be bound to the rule route", plugin.Name, from)
+		}
+	}
+	for _, id := range stale {
+		if err = adapter.RemovePlugin(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncRulePlugins copies the plugins of the api to the routes of the rules again, it is called when
+// the plugins of the api or the domain policy of the zone change
+func SyncRulePlugins(adapter kong.KongAdapter, zone *orm.GatewayZone) error {
+	if zone == nil || zone.Type != db.ZONE_TYPE_PACKAGE_API || !supportRules(adapter) {
+		return nil
+	}
+	routes, err := adapter.GetRoutesWithTag(ROUTE_TAG + "~" + zone.Id)
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+	api, err := getZoneApi(zone)
+	if err != nil {
+		return err
+	}
+	enables := zoneEnables(zone)
+	for _, route := range routes {
+		err = syncRoutePlugins(adapter, api.route.RouteId, route.Id, enables)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileKong points the service of the api to the weighted upstream, and creates the routes of the rules
+func reconcileKong(adapter kong.KongAdapter, zone *orm.GatewayZone, dto *PolicyDto) error {
+	api, err := getZoneApi(zone)
+	if err != nil {
+		return err
+	}
+	rules := supportRules(adapter)
+	if len(dto.Rules) > 0 && !rules {
+		return errors.New("当前 kong 版本不支持按请求匹配分流, 需要 2.x 以上版本")
+	}
+	targets, err := resolveTargets(dto.Backends)
+	if err != nil {
+		return err
+	}
+	if err = getCurrentService(adapter, api); err != nil {
+		return err
+	}
+	upstream := UpstreamName(zone.Id)
+	err = touchUpstream(adapter, upstream, Weights(dto.Backends, targets))
+	if err != nil {
+		return err
+	}
+	req := serviceReq(api, upstream)
+	req.ServiceId = api.service.ServiceId
+	_, err = adapter.CreateOrUpdateService(req)
+	if err != nil {
+		return err
+	}
+	if !rules {
+		return nil
+	}
+	err = clearRuleRoutes(adapter, zone.Id)
+	if err != nil {
+		return err
+	}
+	for i, rule := range dto.Rules {
+		err = createRuleRoute(adapter, zone, api, i, targets[rule.Backend])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetKong points the service of the api back to its address and deletes the upstream and rule routes
+func resetKong(adapter kong.KongAdapter, zone *orm.GatewayZone) error {
+	api, err := getZoneApi(zone)
+	if err != nil {
+		// the policy never applied to the zone
+		logrus.Debugf("skip reset traffic split of zone %s: %v", zone.Id, err)
+		return nil
+	}
+	if err = getCurrentService(adapter, api); err != nil {
+		return err
+	}
+	req := serviceReq(api, api.service.Host+":"+api.service.Port)
+	req.ServiceId = api.service.ServiceId
+	_, err = adapter.CreateOrUpdateService(req)
+	if err != nil {
+		return err
+	}
+	if supportRules(adapter) {
+		err = clearRuleRoutes(adapter, zone.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return adapter.DeleteUpstream(UpstreamName(zone.Id))
+}
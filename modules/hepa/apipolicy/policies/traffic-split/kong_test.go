@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficsplit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/erda-project/erda/modules/hepa/kong"
+	kongDto "github.com/erda-project/erda/modules/hepa/kong/dto"
+)
+
+type fakeAdapter struct {
+	kong.KongAdapter
+	plugins     map[string]kongDto.KongPluginRespDto
+	unsupported map[string]bool
+	seq         int
+}
+
+func (f *fakeAdapter) GetRoutePlugins(routeId string) ([]kongDto.KongPluginRespDto, error) {
+	var res []kongDto.KongPluginRespDto
+	for _, plugin := range f.plugins {
+		if plugin.RouteId == routeId {
+			res = append(res, plugin)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeAdapter) put(id string, req *kongDto.KongPluginReqDto) (*kongDto.KongPluginRespDto, error) {
+	if f.unsupported[req.Name] {
+		return nil, nil
+	}
+	plugin := kongDto.KongPluginRespDto{
+		Id:         id,
+		RouteId:    req.RouteId,
+		ConsumerId: req.ConsumerId,
+		Name:       req.Name,
+		Config:     req.Config,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	f.plugins[id] = plugin
+	return &plugin, nil
+}
+
+func (f *fakeAdapter) AddPlugin(req *kongDto.KongPluginReqDto) (*kongDto.KongPluginRespDto, error) {
+	f.seq++
+	return f.put(fmt.Sprintf("copy-%d", f.seq), req)
+}
+
+func (f *fakeAdapter) PutPlugin(req *kongDto.KongPluginReqDto) (*kongDto.KongPluginRespDto, error) {
+	return f.put(req.PluginId, req)
+}
+
+func (f *fakeAdapter) RemovePlugin(id string) error {
+	delete(f.plugins, id)
+	return nil
+}
+
+func newFakeAdapter() *fakeAdapter {
+	return &fakeAdapter{
+		plugins: map[string]kongDto.KongPluginRespDto{
+			// the api rule plugins are disabled and enabled by the domain policy
+			"auth":  {Id: "auth", RouteId: "api", Name: "key-auth", Config: map[string]interface{}{"key_names": []string{"appKey"}}},
+			"acl":   {Id: "acl", RouteId: "api", Name: "acl", Config: map[string]interface{}{"whitelist": "c1"}},
+			"limit": {Id: "limit", RouteId: "api", Name: "rate-limiting", Config: map[string]interface{}{"second": 10}},
+			"off":   {Id: "off", RouteId: "api", Name: "cors"},
+			"path":  {Id: "path", RouteId: "api", Name: "path-variable", Enabled: true},
+			"other": {Id: "other", RouteId: "other", Name: "ip-restriction", Enabled: true},
+		},
+		unsupported: map[string]bool{},
+	}
+}
+
+func routePlugins(f *fakeAdapter, routeId string) map[string]kongDto.KongPluginRespDto {
+	plugins, _ := f.GetRoutePlugins(routeId)
+	res := map[string]kongDto.KongPluginRespDto{}
+	for _, plugin := range plugins {
+		res[plugin.Name] = plugin
+	}
+	return res
+}
+
+func TestSyncRoutePlugins(t *testing.T) {
+	f := newFakeAdapter()
+	enables := map[string]bool{"auth": true, "acl": true, "limit": true}
+	if err := syncRoutePlugins(f, "api", "rule", enables); err != nil {
+		t.Fatal(err)
+	}
+	plugins := routePlugins(f, "rule")
+	if len(plugins) != 5 {
+		t.Fatalf("all the plugins of the api route should be copied, got %v", plugins)
+	}
+	for _, name := range []string{"key-auth", "acl", "rate-limiting", "path-variable"} {
+		if !plugins[name].Enabled {
+			t.Errorf("plugin %s should run on the requests matching the rule", name)
+		}
+	}
+	if plugins["cors"].Enabled {
+		t.Error("plugin cors is not enabled on the api, it should not run on the rule route")
+	}
+	if plugins["acl"].Config["whitelist"] != "c1" {
+		t.Errorf("config of acl should be copied, got %v", plugins["acl"].Config)
+	}
+
+	// the acl changed and the limit removed from the api
+	f.plugins["acl"] = kongDto.KongPluginRespDto{Id: "acl", RouteId: "api", Name: "acl", Config: map[string]interface{}{"whitelist": "c1,c2"}}
+	delete(f.plugins, "limit")
+	aclId := plugins["acl"].Id
+	if err := syncRoutePlugins(f, "api", "rule", enables); err != nil {
+		t.Fatal(err)
+	}
+	plugins = routePlugins(f, "rule")
+	if len(plugins) != 4 {
+		t.Fatalf("the plugin removed from the api route should be removed from the rule route, got %v", plugins)
+	}
+	if plugins["acl"].Id != aclId || plugins["acl"].Config["whitelist"] != "c1,c2" {
+		t.Errorf("acl of the rule route should be updated in place, got %v", plugins["acl"])
+	}
+}
+
+func TestSyncRoutePlugins_Unsupported(t *testing.T) {
+	f := newFakeAdapter()
+	f.unsupported["key-auth"] = true
+	if err := syncRoutePlugins(f, "api", "rule", nil); err == nil {
+		t.Fatal("the rule route should not be created without the auth plugin of the api")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/erda-project/erda/modules/hepa/apipolicy"
+)
+
+const (
+	REQUEST_PLUGIN  = "request-transformer"
+	RESPONSE_PLUGIN = "response-transformer"
+	// BODY_PLUGIN runs responseBodyScript, response-transformer can't rename
+	// json fields, pre-function is used since post-function is taken by the
+	// jwt claim rules of the routes, which override the plugin of the zone
+	BODY_PLUGIN = "pre-function"
+)
+
+//go:embed response_body.lua
+var responseBodyScript string
+
+var (
+	fieldRegex       = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	pathRegex        = regexp.MustCompile(`^/[A-Za-z0-9_.~%/{}-]*$`)
+	placeholderRegex = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*|[0-9]+)\}`)
+)
+
+// Transform is the transformation on headers and json body, shared by requests and responses
+type Transform struct {
+	// 添加或覆盖的头, 头名称 -> 值
+	AddHeaders map[string]string `json:"addHeaders,omitempty"`
+	// 移除的头
+	RemoveHeaders []string `json:"removeHeaders,omitempty"`
+	// 重命名的头, 原名称 -> 新名称
+	RenameHeaders map[string]string `json:"renameHeaders,omitempty"`
+	// JSON body 字段映射, 原字段 -> 新字段
+	BodyMapping map[string]string `json:"bodyMapping,omitempty"`
+	// 移除的 JSON body 字段
+	RemoveBody []string `json:"removeBody,omitempty"`
+}
+
+// RequestTransform transforms the requests before being forwarded to the upstream
+type RequestTransform struct {
+	Transform
+	// 重写后的路径, 可以使用 {name} 引用 API 路径中的变量, {1} 引用第一个匹配组
+	Path string `json:"path,omitempty"`
+	// 注入的 query 参数, 参数名 -> 值
+	AddQuery map[string]string `json:"addQuery,omitempty"`
+}
+
+// ResponseTransform transforms the responses before being returned to the client
+type ResponseTransform struct {
+	Transform
+}
+
+type PolicyDto struct {
+	apipolicy.BaseDto
+	Request  RequestTransform  `json:"request"`
+	Response ResponseTransform `json:"response"`
+}
+
+func checkValue(value string) bool {
+	// kong evaluates $(...) as template
+	return !strings.Contains(value, "$(")
+}
+
+func (t Transform) isValid() (bool, string) {
+	for name, value := range t.AddHeaders {
+		if !apipolicy.IsValidHeaderName(name) {
+			return false, fmt.Sprintf("非法的头名称: %s", name)
+		}
+		if !checkValue(value) {
+			return false, fmt.Sprintf("头 %s 的值不能包含 $(", name)
+		}
+	}
+	for _, name := range t.RemoveHeaders {
+		if !apipolicy.IsValidHeaderName(name) {
+			return false, fmt.Sprintf("非法的头名称: %s", name)
+		}
+	}
+	for from, to := range t.RenameHeaders {
+		if !apipolicy.IsValidHeaderName(from) || !apipolicy.IsValidHeaderName(to) {
+			return false, fmt.Sprintf("非法的头名称: %s -> %s", from, to)
+		}
+	}
+	for from, to := range t.BodyMapping {
+		if !fieldRegex.MatchString(from) || !fieldRegex.MatchString(to) {
+			return false, fmt.Sprintf("非法的字段名称: %s -> %s", from, to)
+		}
+	}
+	for _, name := range t.RemoveBody {
+		if !fieldRegex.MatchString(name) {
+			return false, fmt.Sprintf("非法的字段名称: %s", name)
+		}
+	}
+	return true, ""
+}
+
+func (dto PolicyDto) IsValidDto() (bool, string) {
+	if !dto.Switch {
+		return true, ""
+	}
+	if ok, msg := dto.Request.isValid(); !ok {
+		return false, "请求转换: " + msg
+	}
+	if dto.Request.Path != "" && !pathRegex.MatchString(dto.Request.Path) {
+		return false, fmt.Sprintf("请求转换: 非法的路径: %s", dto.Request.Path)
+	}
+	for key, value := range dto.Request.AddQuery {
+		if !fieldRegex.MatchString(key) {
+			return false, fmt.Sprintf("请求转换: 非法的参数名称: %s", key)
+		}
+		if !checkValue(value) {
+			return false, fmt.Sprintf("请求转换: 参数 %s 的值不能包含 $(", key)
+		}
+	}
+	if ok, msg := dto.Response.isValid(); !ok {
+		return false, "响应转换: " + msg
+	}
+	return true, ""
+}
+
+func pairs(m map[string]string) []string {
+	var res []string
+	for key, value := range m {
+		res = append(res, key+":"+value)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func sorted(list []string) []string {
+	res := append([]string{}, list...)
+	sort.Strings(res)
+	return res
+}
+
+// uriTemplate converts the placeholders to the kong template of route captures
+func uriTemplate(path string) string {
+	return placeholderRegex.ReplaceAllStringFunc(path, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if name[0] >= '0' && name[0] <= '9' {
+			return fmt.Sprintf("$(uri_captures[%s])", name)
+		}
+		return fmt.Sprintf(`$(uri_captures["%s"])`, name)
+	})
+}
+
+// config of the kong plugin, the key of empty section is omitted
+type section map[string]interface{}
+
+func (s section) set(key string, values []string) {
+	if len(values) > 0 {
+		s[key] = values
+	}
+}
+
+// KongConfig returns the config of the request-transformer plugin, nil if nothing to transform
+func (t RequestTransform) KongConfig() map[string]interface{} {
+	remove, rename, replace, add := section{}, section{}, section{}, section{}
+	remove.set("headers", sorted(t.RemoveHeaders))
+	remove.set("body", sorted(t.RemoveBody))
+	rename.set("headers", pairs(t.RenameHeaders))
+	rename.set("body", pairs(t.BodyMapping))
+	// replace the existing headers and query params, add them if not exist
+	replace.set("headers", pairs(t.AddHeaders))
+	add.set("headers", pairs(t.AddHeaders))
+	replace.set("querystring", pairs(t.AddQuery))
+	add.set("querystring", pairs(t.AddQuery))
+	if t.Path != "" {
+		replace["uri"] = uriTemplate(t.Path)
+	}
+	return kongConfig(remove, rename, replace, add)
+}
+
+// KongConfig returns the config of the response-transformer plugin, nil if nothing to transform,
+// the json fields are removed by the body plugin if any is renamed
+func (t ResponseTransform) KongConfig() map[string]interface{} {
+	remove, rename, replace, add := section{}, section{}, section{}, section{}
+	remove.set("headers", sorted(t.RemoveHeaders))
+	if len(t.BodyMapping) == 0 {
+		remove.set("json", sorted(t.RemoveBody))
+	}
+	rename.set("headers", pairs(t.RenameHeaders))
+	replace.set("headers", pairs(t.AddHeaders))
+	add.set("headers", pairs(t.AddHeaders))
+	return kongConfig(remove, rename, replace, add)
+}
+
+// BodyPluginConfig returns the config of the pre-function plugin renaming the json
+// fields of the responses, nil if nothing to rename
+func (t ResponseTransform) BodyPluginConfig() map[string]interface{} {
+	if len(t.BodyMapping) == 0 {
+		return nil
+	}
+	// the field names are checked by fieldRegex, so they can't break out of the lua strings
+	var remove, mapping []string
+	for _, field := range sorted(t.RemoveBody) {
+		remove = append(remove, fmt.Sprintf("%q", field))
+	}
+	var froms []string
+	for from := range t.BodyMapping {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		mapping = append(mapping, fmt.Sprintf("[%q] = %q", from, t.BodyMapping[from]))
+	}
+	script := func(phase string) []string {
+		return []string{fmt.Sprintf("local PHASE = %q\nlocal REMOVE = {%s}\nlocal MAPPING = {%s}\n%s",
+			phase, strings.Join(remove, ", "), strings.Join(mapping, ", "), responseBodyScript)}
+	}
+	return map[string]interface{}{
+		"header_filter": script("header_filter"),
+		"body_filter":   script("body_filter"),
+	}
+}
+
+func kongConfig(remove, rename, replace, add section) map[string]interface{} {
+	res := map[string]interface{}{}
+	for key, s := range map[string]section{"remove": remove, "rename": rename, "replace": replace, "add": add} {
+		if len(s) > 0 {
+			res[key] = map[string]interface{}(s)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
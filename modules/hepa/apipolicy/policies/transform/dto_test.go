@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/erda-project/erda/modules/hepa/apipolicy/policies/transform"
+)
+
+func newDto() transform.PolicyDto {
+	dto := transform.PolicyDto{
+		Request: transform.RequestTransform{
+			Transform: transform.Transform{
+				AddHeaders:    map[string]string{"X-Source": "gateway"},
+				RemoveHeaders: []string{"Cookie"},
+				RenameHeaders: map[string]string{"X-Token": "Authorization"},
+				BodyMapping:   map[string]string{"userName": "name"},
+			},
+			Path:     "/api/v2/users/{id}",
+			AddQuery: map[string]string{"from": "gateway"},
+		},
+		Response: transform.ResponseTransform{
+			Transform: transform.Transform{
+				RemoveHeaders: []string{"Server"},
+				RemoveBody:    []string{"internal"},
+			},
+		},
+	}
+	dto.Switch = true
+	return dto
+}
+
+func TestPolicyDto_IsValidDto(t *testing.T) {
+	dto := newDto()
+	if ok, msg := dto.IsValidDto(); !ok {
+		t.Fatalf("should be valid: %s", msg)
+	}
+
+	cases := map[string]func(dto *transform.PolicyDto){
+		"invalid header":      func(dto *transform.PolicyDto) { dto.Request.AddHeaders["X Source"] = "a" },
+		"template value":      func(dto *transform.PolicyDto) { dto.Request.AddHeaders["X-Source"] = "$(headers.host)" },
+		"invalid rename":      func(dto *transform.PolicyDto) { dto.Request.RenameHeaders["X-Token"] = "Author:ization" },
+		"invalid field":       func(dto *transform.PolicyDto) { dto.Request.BodyMapping["user name"] = "name" },
+		"invalid path":        func(dto *transform.PolicyDto) { dto.Request.Path = "api/v2" },
+		"invalid query":       func(dto *transform.PolicyDto) { dto.Request.AddQuery["a&b"] = "c" },
+		"invalid remove body": func(dto *transform.PolicyDto) { dto.Response.RemoveBody = []string{"a:b"} },
+		"response mapping":    func(dto *transform.PolicyDto) { dto.Response.BodyMapping = map[string]string{"a": "b\""} },
+	}
+	for name, modify := range cases {
+		dto := newDto()
+		modify(&dto)
+		if ok, _ := dto.IsValidDto(); ok {
+			t.Errorf("%s: should be invalid", name)
+		}
+		dto.Switch = false
+		if ok, _ := dto.IsValidDto(); !ok {
+			t.Errorf("%s: should be valid when switch off", name)
+		}
+	}
+}
+
+func TestRequestTransform_KongConfig(t *testing.T) {
+	dto := newDto()
+	expected := map[string]interface{}{
+		"remove": map[string]interface{}{
+			"headers": []string{"Cookie"},
+		},
+		"rename": map[string]interface{}{
+			"headers": []string{"X-Token:Authorization"},
+			"body":    []string{"userName:name"},
+		},
+		"replace": map[string]interface{}{
+			"headers":     []string{"X-Source:gateway"},
+			"querystring": []string{"from:gateway"},
+			"uri":         `/api/v2/users/$(uri_captures["id"])`,
+		},
+		"add": map[string]interface{}{
+			"headers":     []string{"X-Source:gateway"},
+			"querystring": []string{"from:gateway"},
+		},
+	}
+	if config := dto.Request.KongConfig(); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	dto.Request = transform.RequestTransform{Path: "/v1/{1}/{name}"}
+	config := dto.Request.KongConfig()
+	uri := config["replace"].(map[string]interface{})["uri"]
+	if uri != `/v1/$(uri_captures[1])/$(uri_captures["name"])` {
+		t.Errorf("unexpected uri: %v", uri)
+	}
+
+	if config := (transform.RequestTransform{}).KongConfig(); config != nil {
+		t.Errorf("should be nil: %+v", config)
+	}
+}
+
+func TestResponseTransform_KongConfig(t *testing.T) {
+	dto := newDto()
+	expected := map[string]interface{}{
+		"remove": map[string]interface{}{
+			"headers": []string{"Server"},
+			"json":    []string{"internal"},
+		},
+	}
+	if config := dto.Response.KongConfig(); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected config: %+v", config)
+	}
+}
+
+func TestResponseTransform_BodyPluginConfig(t *testing.T) {
+	dto := newDto()
+	if config := dto.Response.BodyPluginConfig(); config != nil {
+		t.Errorf("should be nil without mapping: %+v", config)
+	}
+
+	dto.Response.BodyMapping = map[string]string{"user_name": "name", "id": "userId"}
+	if ok, msg := dto.IsValidDto(); !ok {
+		t.Fatalf("should be valid: %s", msg)
+	}
+	// the json fields are removed by the body plugin before renaming
+	expected := map[string]interface{}{
+		"remove": map[string]interface{}{
+			"headers": []string{"Server"},
+		},
+	}
+	if config := dto.Response.KongConfig(); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected config: %+v", config)
+	}
+	config := dto.Response.BodyPluginConfig()
+	for _, phase := range []string{"header_filter", "body_filter"} {
+		functions, ok := config[phase].([]string)
+		if !ok || len(functions) != 1 {
+			t.Fatalf("unexpected %s: %+v", phase, config[phase])
+		}
+		prefix := `local PHASE = "` + phase + `"
+local REMOVE = {"internal"}
+local MAPPING = {["id"] = "userId", ["user_name"] = "name"}
+`
+		if !strings.HasPrefix(functions[0], prefix) {
+			t.Errorf("unexpected %s: %s", phase, functions[0])
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/modules/hepa/apipolicy"
+	"github.com/erda-project/erda/modules/hepa/kong"
+	kongDto "github.com/erda-project/erda/modules/hepa/kong/dto"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+	db "github.com/erda-project/erda/modules/hepa/repository/service"
+)
+
+const CATEGORY = "transform"
+
+type Policy struct {
+	apipolicy.BasePolicy
+}
+
+func (policy Policy) CreateDefaultConfig(ctx map[string]interface{}) apipolicy.PolicyDto {
+	dto := &PolicyDto{}
+	dto.Switch = false
+	return dto
+}
+
+func (policy Policy) UnmarshalConfig(config []byte) (apipolicy.PolicyDto, error, string) {
+	policyDto := &PolicyDto{}
+	err, msg := apipolicy.UnmarshalDto(config, policyDto)
+	if err != nil {
+		return nil, err, msg
+	}
+	return policyDto, nil, ""
+}
+
+// touchPlugin creates or updates the plugin of the zone, or removes it if config is nil,
+// returns whether the plugins of the zone changed
+func (policy Policy) touchPlugin(adapter kong.KongAdapter, zoneId, pluginName string, config map[string]interface{}) (bool, error) {
+	policyDb, err := db.NewGatewayPolicyServiceImpl()
+	if err != nil {
+		return false, err
+	}
+	exist, err := policyDb.GetByAny(&orm.GatewayPolicy{
+		ZoneId:     zoneId,
+		PluginName: pluginName,
+	})
+	if err != nil {
+		return false, err
+	}
+	if config == nil {
+		if exist == nil {
+			return false, nil
+		}
+		err = adapter.RemovePlugin(exist.PluginId)
+		if err != nil {
+			return false, err
+		}
+		return true, policyDb.DeleteById(exist.Id)
+	}
+	disable := false
+	req := &kongDto.KongPluginReqDto{
+		Name:    pluginName,
+		Config:  config,
+		Enabled: &disable,
+	}
+	if exist != nil {
+		req.Id = exist.PluginId
+		resp, err := adapter.CreateOrUpdatePluginById(req)
+		if err != nil {
+			return false, err
+		}
+		if resp == nil {
+			return false, errors.Errorf("%s plugin is not enabled", pluginName)
+		}
+		exist.Config, err = json.Marshal(resp.Config)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		return false, policyDb.Update(exist)
+	}
+	resp, err := adapter.AddPlugin(req)
+	if err != nil {
+		return false, err
+	}
+	if resp == nil {
+		return false, errors.Errorf("%s plugin is not enabled", pluginName)
+	}
+	configByte, err := json.Marshal(resp.Config)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	err = policyDb.Insert(&orm.GatewayPolicy{
+		ZoneId:     zoneId,
+		PluginName: pluginName,
+		Category:   CATEGORY,
+		PluginId:   resp.Id,
+		Config:     configByte,
+		Enabled:    1,
+	})
+	if err != nil {
+		_ = adapter.RemovePlugin(resp.Id)
+		return false, err
+	}
+	return true, nil
+}
+
+func (policy Policy) ParseConfig(dto apipolicy.PolicyDto, ctx map[string]interface{}) (apipolicy.PolicyConfig, error) {
+	res := apipolicy.PolicyConfig{}
+	policyDto, ok := dto.(*PolicyDto)
+	if !ok {
+		return res, errors.Errorf("invalid config:%+v", dto)
+	}
+	value, ok := ctx[apipolicy.CTX_KONG_ADAPTER]
+	if !ok {
+		return res, errors.Errorf("get identify failed:%+v", ctx)
+	}
+	adapter, ok := value.(kong.KongAdapter)
+	if !ok {
+		return res, errors.Errorf("convert failed:%+v", value)
+	}
+	value, ok = ctx[apipolicy.CTX_ZONE]
+	if !ok {
+		return res, errors.Errorf("get identify failed:%+v", ctx)
+	}
+	zone, ok := value.(*orm.GatewayZone)
+	if !ok {
+		return res, errors.Errorf("convert failed:%+v", value)
+	}
+	var reqConfig, respConfig, bodyConfig map[string]interface{}
+	if policyDto.Switch {
+		reqConfig = policyDto.Request.KongConfig()
+		respConfig = policyDto.Response.KongConfig()
+		bodyConfig = policyDto.Response.BodyPluginConfig()
+	}
+	for pluginName, config := range map[string]map[string]interface{}{
+		REQUEST_PLUGIN:  reqConfig,
+		RESPONSE_PLUGIN: respConfig,
+		BODY_PLUGIN:     bodyConfig,
+	} {
+		changed, err := policy.touchPlugin(adapter, zone.Id, pluginName, config)
+		if err != nil {
+			return res, err
+		}
+		if changed {
+			res.KongPolicyChange = true
+		}
+	}
+	return res, nil
+}
+
+func init() {
+	err := apipolicy.RegisterPolicyEngine(CATEGORY, &Policy{})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,61 @@
+-- Copyright (c) 2021 Terminus, Inc.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- Runs in the pre-function plugin to transform the json body of responses,
+-- the fields in REMOVE are removed and then the fields in MAPPING are renamed,
+-- the same order as request-transformer. PHASE, REMOVE and MAPPING are
+-- prepended by hepa, cjson.safe must be listed in untrusted_lua_sandbox_requires
+-- of kong.
+
+local cjson = require "cjson.safe"
+
+local content_type = kong.response.get_header("Content-Type")
+if not content_type or not content_type:lower():find("application/json", 1, true) then
+  return
+end
+
+if PHASE == "header_filter" then
+  -- the length changes once the body is transformed
+  kong.response.clear_header("Content-Length")
+  return
+end
+
+-- the chunks are buffered until the last one
+local raw = kong.response.get_raw_body()
+if not raw then
+  return
+end
+local body = cjson.decode(raw)
+if type(body) ~= "table" then
+  return
+end
+-- the body is kept as it is if nothing changed, since cjson can't tell an
+-- empty array from an empty object
+local changed = false
+for _, field in ipairs(REMOVE) do
+  if body[field] ~= nil then
+    body[field] = nil
+    changed = true
+  end
+end
+for from, to in pairs(MAPPING) do
+  if body[from] ~= nil then
+    body[to] = body[from]
+    body[from] = nil
+    changed = true
+  end
+end
+if changed then
+  kong.response.set_raw_body(cjson.encode(body))
+end
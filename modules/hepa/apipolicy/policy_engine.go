@@ -15,6 +15,9 @@
 package apipolicy
 
 import (
+	"encoding/json"
+	"regexp"
+
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
@@ -94,6 +97,35 @@ type PolicyDto interface {
 	SetEnable(bool)
 }
 
+// ValidatableDto is the policy dto which checks itself before being pushed to the gateway
+type ValidatableDto interface {
+	IsValidDto() (bool, string)
+}
+
+var headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// IsValidHeaderName checks the header name could be used safely in both nginx and kong
+func IsValidHeaderName(name string) bool {
+	return headerNameRegex.MatchString(name)
+}
+
+// UnmarshalDto parses the config into the dto, and validates it if it is a ValidatableDto,
+// the returned message is shown to the user
+func UnmarshalDto(config []byte, dto PolicyDto) (error, string) {
+	err := json.Unmarshal(config, dto)
+	if err != nil {
+		return errors.Wrapf(err, "json parse config failed, config:%s", config), "Invalid config"
+	}
+	validatable, ok := dto.(ValidatableDto)
+	if !ok {
+		return nil, ""
+	}
+	if ok, msg := validatable.IsValidDto(); !ok {
+		return errors.Errorf("invalid policy dto, msg:%s", msg), msg
+	}
+	return nil, ""
+}
+
 type BaseDto struct {
 	Switch bool `json:"switch"`
 	Global bool `json:"global"`
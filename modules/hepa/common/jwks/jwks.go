@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwks resolves the public keys of the jwt issuers from their JWKS,
+// the key sets are cached since they are rarely rotated.
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+type Key struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// rsa
+	N string `json:"n"`
+	E string `json:"e"`
+	// ec
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+// Signing returns the keys used for signature, the encryption keys are skipped
+func (set KeySet) Signing() []Key {
+	var keys []Key
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Find returns the key with the kid, or the first signing key if kid is empty
+func (set KeySet) Find(kid string) (*Key, error) {
+	for _, key := range set.Signing() {
+		if kid == "" || key.Kid == kid {
+			return &key, nil
+		}
+	}
+	return nil, errors.Errorf("signing key not found in jwks, kid:%s", kid)
+}
+
+// PEM returns the public key in pem format and the signing algorithm
+func (key Key) PEM() (string, string, error) {
+	var pub interface{}
+	alg := key.Alg
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeInt(key.N)
+		if err != nil {
+			return "", "", err
+		}
+		e, err := decodeInt(key.E)
+		if err != nil {
+			return "", "", err
+		}
+		pub = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if alg == "" {
+			alg = "RS256"
+		}
+	case "EC":
+		var curve elliptic.Curve
+		var curveAlg string
+		switch key.Crv {
+		case "P-256":
+			curve, curveAlg = elliptic.P256(), "ES256"
+		case "P-384":
+			curve, curveAlg = elliptic.P384(), "ES384"
+		case "P-521":
+			curve, curveAlg = elliptic.P521(), "ES512"
+		default:
+			return "", "", errors.Errorf("unsupported curve:%s", key.Crv)
+		}
+		x, err := decodeInt(key.X)
+		if err != nil {
+			return "", "", err
+		}
+		y, err := decodeInt(key.Y)
+		if err != nil {
+			return "", "", err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return "", "", errors.Errorf("invalid ec key, kid:%s", key.Kid)
+		}
+		pub = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if alg == "" {
+			alg = curveAlg
+		}
+	default:
+		return "", "", errors.Errorf("unsupported key type:%s", key.Kty)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), alg, nil
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("empty key parameter")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key parameter:%s", value)
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+type entry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+type Cache struct {
+	client  *http.Client
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]entry
+}
+
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		ttl:     ttl,
+		entries: map[string]entry{},
+	}
+}
+
+func (c *Cache) get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expireAt) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *Cache) set(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[key] = entry{value: value, expireAt: time.Now().Add(c.ttl)}
+}
+
+func (c *Cache) fetch(url string, out interface{}) error {
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return errors.Wrapf(err, "request %s failed", url)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "read %s failed", url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request %s failed, code:%d, body:%s", url, resp.StatusCode, body)
+	}
+	if err = json.Unmarshal(body, out); err != nil {
+		return errors.Wrapf(err, "invalid response of %s", url)
+	}
+	return nil
+}
+
+// JwksUri discovers the jwks uri of the issuer by openid configuration
+func (c *Cache) JwksUri(issuer string) (string, error) {
+	cacheKey := "issuer:" + issuer
+	if value, ok := c.get(cacheKey); ok {
+		return value.(string), nil
+	}
+	var config struct {
+		Issuer  string `json:"issuer"`
+		JwksUri string `json:"jwks_uri"`
+	}
+	err := c.fetch(strings.TrimSuffix(issuer, "/")+discoveryPath, &config)
+	if err != nil {
+		return "", err
+	}
+	if config.JwksUri == "" {
+		return "", errors.Errorf("jwks_uri not found in openid configuration of %s", issuer)
+	}
+	c.set(cacheKey, config.JwksUri)
+	return config.JwksUri, nil
+}
+
+func (c *Cache) KeySet(jwksUri string) (*KeySet, error) {
+	cacheKey := "jwks:" + jwksUri
+	if value, ok := c.get(cacheKey); ok {
+		return value.(*KeySet), nil
+	}
+	set := &KeySet{}
+	err := c.fetch(jwksUri, set)
+	if err != nil {
+		return nil, err
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.Errorf("no key found in %s", jwksUri)
+	}
+	c.set(cacheKey, set)
+	return set, nil
+}
+
+// PublicKey is a signing key of the jwks in pem format
+type PublicKey struct {
+	Kid       string
+	Pem       string
+	Algorithm string
+}
+
+// Resolve returns the public key in pem format and the signing algorithm,
+// jwksUri is discovered from the issuer if empty
+func (c *Cache) Resolve(issuer, jwksUri, kid string) (string, string, error) {
+	set, err := c.issuerKeySet(issuer, jwksUri)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := set.Find(kid)
+	if err != nil {
+		return "", "", err
+	}
+	return key.PEM()
+}
+
+// ResolveAll returns all the signing keys currently published by the jwks,
+// so the tokens signed by any of them are accepted while the issuer rotates
+func (c *Cache) ResolveAll(issuer, jwksUri string) ([]PublicKey, error) {
+	set, err := c.issuerKeySet(issuer, jwksUri)
+	if err != nil {
+		return nil, err
+	}
+	var res []PublicKey
+	for _, key := range set.Signing() {
+		pem, alg, err := key.PEM()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, PublicKey{Kid: key.Kid, Pem: pem, Algorithm: alg})
+	}
+	if len(res) == 0 {
+		return nil, errors.Errorf("no signing key found in jwks, issuer:%s, jwks uri:%s", issuer, jwksUri)
+	}
+	return res, nil
+}
+
+func (c *Cache) issuerKeySet(issuer, jwksUri string) (*KeySet, error) {
+	var err error
+	if jwksUri == "" {
+		if issuer == "" {
+			return nil, errors.New("issuer and jwks uri are both empty")
+		}
+		jwksUri, err = c.JwksUri(issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.KeySet(jwksUri)
+}
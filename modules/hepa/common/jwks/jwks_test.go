@@ -0,0 +1,237 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func parsePEM(t *testing.T, raw string) interface{} {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		t.Fatalf("invalid pem:%s", raw)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+func TestKey_PEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     Key
+		want    interface{}
+		wantAlg string
+		wantErr bool
+	}{
+		{
+			"rsa",
+			Key{Kty: "RSA", N: encodeInt(rsaKey.N), E: encodeInt(big.NewInt(int64(rsaKey.E)))},
+			&rsaKey.PublicKey,
+			"RS256",
+			false,
+		},
+		{
+			"rsa with alg",
+			Key{Kty: "RSA", Alg: "RS512", N: encodeInt(rsaKey.N), E: "AQAB"},
+			&rsaKey.PublicKey,
+			"RS512",
+			false,
+		},
+		{
+			"ec",
+			Key{Kty: "EC", Crv: "P-256", X: encodeInt(ecKey.X), Y: encodeInt(ecKey.Y)},
+			&ecKey.PublicKey,
+			"ES256",
+			false,
+		},
+		{
+			"ec not on curve",
+			Key{Kty: "EC", Crv: "P-256", X: encodeInt(ecKey.X), Y: encodeInt(ecKey.X)},
+			nil,
+			"",
+			true,
+		},
+		{
+			"symmetric",
+			Key{Kty: "oct"},
+			nil,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, alg, err := tt.key.PEM()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PEM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if alg != tt.wantAlg {
+				t.Errorf("PEM() alg = %v, want %v", alg, tt.wantAlg)
+			}
+			if pub := parsePEM(t, got); !reflect.DeepEqual(pub, tt.want) {
+				t.Errorf("PEM() = %v, want %v", pub, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySet_Find(t *testing.T) {
+	set := KeySet{Keys: []Key{
+		{Kid: "enc", Use: "enc"},
+		{Kid: "a", Use: "sig"},
+		{Kid: "b"},
+	}}
+	key, err := set.Find("")
+	if err != nil || key.Kid != "a" {
+		t.Errorf("Find() = %v, %v, want a", key, err)
+	}
+	key, err = set.Find("b")
+	if err != nil || key.Kid != "b" {
+		t.Errorf("Find() = %v, %v, want b", key, err)
+	}
+	if _, err = set.Find("enc"); err == nil {
+		t.Error("Find() should skip the encryption key")
+	}
+}
+
+func TestCache_Resolve(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string]int{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case discoveryPath:
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   server.URL,
+				"jwks_uri": server.URL + "/keys",
+			})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(KeySet{Keys: []Key{
+				{Kid: "k1", Kty: "RSA", Use: "sig", N: encodeInt(rsaKey.N), E: "AQAB"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache := NewCache(time.Minute)
+	for i := 0; i < 2; i++ {
+		got, alg, err := cache.Resolve(server.URL+"/", "", "k1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg != "RS256" || !reflect.DeepEqual(parsePEM(t, got), &rsaKey.PublicKey) {
+			t.Errorf("Resolve() = %v, %v", got, alg)
+		}
+	}
+	if requests[discoveryPath] != 1 || requests["/keys"] != 1 {
+		t.Errorf("jwks should be cached, requests:%v", requests)
+	}
+	if _, _, err = cache.Resolve("", server.URL+"/missing", ""); err == nil {
+		t.Error("Resolve() should fail on missing jwks")
+	}
+	if _, _, err = cache.Resolve("", "", ""); err == nil {
+		t.Error("Resolve() should fail without issuer and jwks uri")
+	}
+
+	expired := NewCache(0)
+	_, _, _ = expired.Resolve("", server.URL+"/keys", "")
+	_, _, _ = expired.Resolve("", server.URL+"/keys", "")
+	if requests["/keys"] != 3 {
+		t.Errorf("expired jwks should be fetched again, requests:%v", requests)
+	}
+}
+
+func TestCache_ResolveAll(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(KeySet{Keys: []Key{
+				{Kid: "old", Kty: "RSA", Use: "sig", N: encodeInt(oldKey.N), E: "AQAB"},
+				{Kid: "enc", Kty: "RSA", Use: "enc", N: encodeInt(oldKey.N), E: "AQAB"},
+				{Kid: "new", Kty: "RSA", N: encodeInt(newKey.N), E: "AQAB", Alg: "RS512"},
+			}})
+		case "/enc":
+			_ = json.NewEncoder(w).Encode(KeySet{Keys: []Key{
+				{Kid: "enc", Kty: "RSA", Use: "enc", N: encodeInt(oldKey.N), E: "AQAB"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache := NewCache(time.Minute)
+	keys, err := cache.ResolveAll("", server.URL+"/keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ResolveAll() = %v, want the old and new keys", keys)
+	}
+	if keys[0].Kid != "old" || keys[0].Algorithm != "RS256" || !reflect.DeepEqual(parsePEM(t, keys[0].Pem), &oldKey.PublicKey) {
+		t.Errorf("ResolveAll()[0] = %v", keys[0])
+	}
+	if keys[1].Kid != "new" || keys[1].Algorithm != "RS512" || !reflect.DeepEqual(parsePEM(t, keys[1].Pem), &newKey.PublicKey) {
+		t.Errorf("ResolveAll()[1] = %v", keys[1])
+	}
+	if _, err = cache.ResolveAll("", server.URL+"/enc"); err == nil {
+		t.Error("ResolveAll() should fail without signing key")
+	}
+}
@@ -63,4 +63,9 @@ type ServerConfig struct {
 	TenantGroupKey           string   `default:"58dcbf490ef3"`
 	CenterDomainNameKeepList []string `default:"collector,gittar,hepa,openapi,soldier,uc,dice,uc-adaptor,nexus-sys,sonar-sys"`
 	EdgeDomainNameKeepList   []string `default:"soldier,nexus-sys"`
+	XdsClusters              []string `default:""`
+	XdsProxyPort             int      `default:"8000"`
+	XdsConfigCluster         string   `default:"hepa-xds"`
+	XdsRefreshDelay          int      `default:"5"`
+	XdsSecret                string   `default:""`
 }
@@ -0,0 +1,342 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configbundle defines the declarative, versioned representation of the gateway configuration of an environment,
+// which could be exported, reviewed in the code repository and applied to another environment
+package configbundle
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/exdto"
+)
+
+const (
+	API_VERSION = "hepa.erda.cloud/v1"
+	KIND        = "GatewayBundle"
+)
+
+type Bundle struct {
+	ApiVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   Metadata   `json:"metadata"`
+	Packages   []Package  `json:"packages,omitempty"`
+	Consumers  []Consumer `json:"consumers,omitempty"`
+}
+
+// Metadata records where the bundle is exported from, it's ignored when applying
+type Metadata struct {
+	ProjectId  string `json:"projectId,omitempty"`
+	Env        string `json:"env,omitempty"`
+	ExportTime string `json:"exportTime,omitempty"`
+}
+
+// Policies is the config of api policies, category -> config
+type Policies map[string]map[string]interface{}
+
+type Package struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Scene       string                 `json:"scene"`
+	BindDomain  []string               `json:"bindDomain"`
+	AuthType    string                 `json:"authType,omitempty"`
+	AclType     string                 `json:"aclType,omitempty"`
+	AuthConfig  map[string]interface{} `json:"authConfig,omitempty"`
+	// names of the consumers granted to the package
+	Consumers []string `json:"consumers,omitempty"`
+	Policies  Policies `json:"policies,omitempty"`
+	Apis      []Api    `json:"apis,omitempty"`
+	// id in the environment, empty if not exported from the environment
+	Id string `json:"-"`
+	// the package holds apis from dice.yml, which can't be deleted by the bundle
+	Managed bool `json:"-"`
+}
+
+type Api struct {
+	Method          string `json:"method,omitempty"`
+	ApiPath         string `json:"apiPath"`
+	Description     string `json:"description,omitempty"`
+	RedirectType    string `json:"redirectType"`
+	RedirectAddr    string `json:"redirectAddr,omitempty"`
+	RedirectPath    string `json:"redirectPath,omitempty"`
+	RedirectApp     string `json:"redirectApp,omitempty"`
+	RedirectService string `json:"redirectService,omitempty"`
+	// name of the runtime, resolved to the runtime of the same name in the target environment
+	RedirectRuntime string   `json:"redirectRuntime,omitempty"`
+	AllowPassAuth   bool     `json:"allowPassAuth,omitempty"`
+	Scopes          []string `json:"scopes,omitempty"`
+	Policies        Policies `json:"policies,omitempty"`
+	Id              string   `json:"-"`
+}
+
+type Consumer struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Limits      []Limit `json:"limits,omitempty"`
+	Id          string  `json:"-"`
+}
+
+type Limit struct {
+	Package string `json:"package"`
+	Method  string `json:"method,omitempty"`
+	ApiPath string `json:"apiPath,omitempty"`
+	exdto.LimitType
+	Id string `json:"-"`
+}
+
+func New(projectId, env string) *Bundle {
+	return &Bundle{
+		ApiVersion: API_VERSION,
+		Kind:       KIND,
+		Metadata: Metadata{
+			ProjectId: projectId,
+			Env:       env,
+		},
+	}
+}
+
+// Key identifies the api in the package
+func (api Api) Key() string {
+	if api.Method == "" {
+		return api.ApiPath
+	}
+	return api.Method + " " + api.ApiPath
+}
+
+// Key identifies the limit of the consumer
+func (limit Limit) Key() string {
+	key := limit.Package
+	if limit.ApiPath != "" {
+		key += ":" + Api{Method: limit.Method, ApiPath: limit.ApiPath}.Key()
+	}
+	return key
+}
+
+// Parse parses the yaml (or json) content into bundle and validates it
+func Parse(content []byte) (*Bundle, error) {
+	b := &Bundle{}
+	err := yaml.Unmarshal(content, b)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	if b.ApiVersion != API_VERSION || b.Kind != KIND {
+		return nil, errors.Errorf("unsupported bundle, apiVersion:%s, kind:%s", b.ApiVersion, b.Kind)
+	}
+	err = b.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	err = b.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Marshal returns the yaml content of the bundle
+func (b *Bundle) Marshal() ([]byte, error) {
+	err := b.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(b)
+}
+
+// Validate checks the names are unique and the references are resolvable
+func (b *Bundle) Validate() error {
+	packages := map[string]*Package{}
+	for i := range b.Packages {
+		pack := &b.Packages[i]
+		if pack.Name == "" || len(pack.BindDomain) == 0 || pack.Scene == "" {
+			return errors.Errorf("invalid package: %s, name, bindDomain and scene are required", pack.Name)
+		}
+		if _, ok := packages[pack.Name]; ok {
+			return errors.Errorf("duplicated package: %s", pack.Name)
+		}
+		packages[pack.Name] = pack
+		apis := map[string]bool{}
+		for _, api := range pack.Apis {
+			if api.ApiPath == "" || api.RedirectType == "" {
+				return errors.Errorf("invalid api of package %s: %s, apiPath and redirectType are required", pack.Name, api.Key())
+			}
+			if apis[api.Key()] {
+				return errors.Errorf("duplicated api of package %s: %s", pack.Name, api.Key())
+			}
+			apis[api.Key()] = true
+		}
+	}
+	consumers := map[string]bool{}
+	for _, consumer := range b.Consumers {
+		if consumer.Name == "" {
+			return errors.New("invalid consumer, name is required")
+		}
+		if consumers[consumer.Name] {
+			return errors.Errorf("duplicated consumer: %s", consumer.Name)
+		}
+		consumers[consumer.Name] = true
+		limits := map[string]bool{}
+		for _, limit := range consumer.Limits {
+			pack, ok := packages[limit.Package]
+			if !ok {
+				return errors.Errorf("package of the limit of consumer %s not found: %s", consumer.Name, limit.Package)
+			}
+			if limit.ApiPath != "" && pack.findApi(Api{Method: limit.Method, ApiPath: limit.ApiPath}.Key()) == nil {
+				return errors.Errorf("api of the limit of consumer %s not found: %s", consumer.Name, limit.Key())
+			}
+			if limits[limit.Key()] {
+				return errors.Errorf("duplicated limit of consumer %s: %s", consumer.Name, limit.Key())
+			}
+			limits[limit.Key()] = true
+		}
+	}
+	for _, pack := range b.Packages {
+		for _, name := range pack.Consumers {
+			if !consumers[name] {
+				return errors.Errorf("consumer granted to package %s not found: %s", pack.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (pack *Package) findApi(key string) *Api {
+	for i := range pack.Apis {
+		if pack.Apis[i].Key() == key {
+			return &pack.Apis[i]
+		}
+	}
+	return nil
+}
+
+// Normalize sorts the lists and formats the values, so the same configuration always has the same content
+func (b *Bundle) Normalize() error {
+	for i := range b.Packages {
+		pack := &b.Packages[i]
+		pack.BindDomain = normalizeList(pack.BindDomain, strings.ToLower)
+		pack.Consumers = normalizeList(pack.Consumers, nil)
+		authConfig, err := normalizeConfig(pack.AuthConfig)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid auth config of package %s", pack.Name)
+		}
+		pack.AuthConfig = authConfig
+		err = pack.Policies.normalize()
+		if err != nil {
+			return errors.WithMessagef(err, "invalid policies of package %s", pack.Name)
+		}
+		for j := range pack.Apis {
+			api := &pack.Apis[j]
+			api.Method = strings.ToUpper(api.Method)
+			api.Scopes = normalizeList(api.Scopes, nil)
+			err = api.Policies.normalize()
+			if err != nil {
+				return errors.WithMessagef(err, "invalid policies of api %s of package %s", api.Key(), pack.Name)
+			}
+		}
+		sort.Slice(pack.Apis, func(x, y int) bool {
+			return pack.Apis[x].Key() < pack.Apis[y].Key()
+		})
+	}
+	sort.Slice(b.Packages, func(x, y int) bool {
+		return b.Packages[x].Name < b.Packages[y].Name
+	})
+	for i := range b.Consumers {
+		consumer := &b.Consumers[i]
+		for j := range consumer.Limits {
+			consumer.Limits[j].Method = strings.ToUpper(consumer.Limits[j].Method)
+		}
+		sort.Slice(consumer.Limits, func(x, y int) bool {
+			return consumer.Limits[x].Key() < consumer.Limits[y].Key()
+		})
+	}
+	sort.Slice(b.Consumers, func(x, y int) bool {
+		return b.Consumers[x].Name < b.Consumers[y].Name
+	})
+	return nil
+}
+
+func normalizeList(list []string, format func(string) string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(list))
+	exists := map[string]bool{}
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if format != nil {
+			item = format(item)
+		}
+		if item == "" || exists[item] {
+			continue
+		}
+		exists[item] = true
+		res = append(res, item)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// normalizeConfig converts the values to the types of json, so configs from yaml and db are comparable
+func normalizeConfig(config map[string]interface{}) (map[string]interface{}, error) {
+	if len(config) == 0 {
+		return nil, nil
+	}
+	content, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var res map[string]interface{}
+	err = json.Unmarshal(content, &res)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+func (policies Policies) normalize() error {
+	for category, config := range policies {
+		// whether the config is inherited is decided by the existence of the api policy
+		delete(config, "global")
+		config, err := normalizeConfig(config)
+		if err != nil {
+			return errors.WithMessagef(err, "category:%s", category)
+		}
+		if config == nil {
+			return errors.Errorf("empty config of policy: %s", category)
+		}
+		policies[category] = config
+	}
+	return nil
+}
+
+// ParsePolicyConfig parses the stored config of api policy
+func ParsePolicyConfig(config []byte) (map[string]interface{}, error) {
+	var res map[string]interface{}
+	err := json.Unmarshal(config, &res)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid policy config: %s", config)
+	}
+	delete(res, "global")
+	return res, nil
+}
+
+// PolicyEnabled returns whether the switch of the policy config is on
+func PolicyEnabled(config map[string]interface{}) bool {
+	enable, _ := config["switch"].(bool)
+	return enable
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configbundle_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/configbundle"
+)
+
+const content = `
+apiVersion: hepa.erda.cloud/v1
+kind: GatewayBundle
+metadata:
+  projectId: "1"
+  env: TEST
+packages:
+- name: shop
+  scene: openapi
+  bindDomain: [Shop.example.com]
+  authType: key-auth
+  aclType: "on"
+  consumers: [mobile, web]
+  policies:
+    cors:
+      switch: true
+      global: true
+      maxAge: 3600
+  apis:
+  - method: post
+    apiPath: /orders
+    redirectType: url
+    redirectAddr: http://order.svc:8080
+    redirectPath: /api/orders
+    policies:
+      ip:
+        switch: true
+        ipSource: x-forwarded-for
+  - apiPath: /goods
+    redirectType: service
+    redirectApp: goods
+    redirectService: goods-api
+    redirectRuntime: master
+consumers:
+- name: web
+- name: mobile
+  limits:
+  - package: shop
+    method: POST
+    apiPath: /orders
+    qps: 10
+  - package: shop
+    qpd: 10000
+`
+
+func parse(t *testing.T, content string) *configbundle.Bundle {
+	b, err := configbundle.Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("parse failed: %+v", err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	b := parse(t, content)
+	pack := b.Packages[0]
+	if pack.BindDomain[0] != "shop.example.com" {
+		t.Errorf("domain should be lower case: %v", pack.BindDomain)
+	}
+	if pack.Apis[0].Key() != "/goods" || pack.Apis[1].Key() != "POST /orders" {
+		t.Errorf("apis should be sorted by key: %s, %s", pack.Apis[0].Key(), pack.Apis[1].Key())
+	}
+	if _, ok := pack.Policies["cors"]["global"]; ok {
+		t.Error("global should be removed from policy config")
+	}
+	if b.Consumers[0].Name != "mobile" || b.Consumers[0].Limits[0].Key() != "shop" {
+		t.Errorf("consumers and limits should be sorted: %+v", b.Consumers)
+	}
+
+	out, err := b.Marshal()
+	if err != nil {
+		t.Fatalf("marshal failed: %+v", err)
+	}
+	again := parse(t, string(out))
+	if plan := configbundle.Diff(b, again, true); !plan.Empty() {
+		t.Errorf("marshaled bundle should be the same:\n%s", plan)
+	}
+
+	for name, invalid := range map[string]string{
+		"version":           strings.Replace(content, "hepa.erda.cloud/v1", "hepa.erda.cloud/v2", 1),
+		"duplicated api":    strings.Replace(content, "- apiPath: /goods", "- apiPath: /orders\n    method: POST", 1),
+		"unknown consumer":  strings.Replace(content, "consumers: [mobile, web]", "consumers: [mobile, app]", 1),
+		"unknown package":   strings.Replace(content, "  - package: shop\n    qpd", "  - package: mall\n    qpd", 1),
+		"unknown limit api": strings.Replace(content, "    apiPath: /orders\n    qps", "    apiPath: /users\n    qps", 1),
+	} {
+		if _, err := configbundle.Parse([]byte(invalid)); err == nil {
+			t.Errorf("%s: should be invalid", name)
+		}
+	}
+}
+
+func summary(plan *configbundle.Plan) []string {
+	var res []string
+	for _, change := range plan.Changes {
+		res = append(res, string(change.Action)+" "+string(change.Kind)+" "+change.Name)
+	}
+	return res
+}
+
+func TestDiff(t *testing.T) {
+	desired := parse(t, content)
+	plan := configbundle.Diff(configbundle.New("1", "DEV"), desired, true)
+	expected := []string{
+		"create consumer mobile",
+		"create consumer web",
+		"create package shop",
+		"create api shop//goods",
+		"create api shop/POST /orders",
+		"create policy shop/cors",
+		"create policy shop/POST /orders/ip",
+		"update acl shop",
+		"create limit mobile/shop",
+		"create limit mobile/shop:POST /orders",
+	}
+	if got := summary(plan); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected plan:\n%s", strings.Join(got, "\n"))
+	}
+
+	current := parse(t, content)
+	current.Packages[0].Id = "pack-id"
+	current.Packages[0].Apis[0].Id = "api-id"
+	desired = parse(t, strings.NewReplacer(
+		"redirectPath: /api/orders", "redirectPath: /api/v2/orders",
+		"maxAge: 3600", "maxAge: 600",
+		"qps: 10", "qps: 20",
+		"consumers: [mobile, web]", "consumers: [mobile]",
+		"      ip:\n        switch: true\n        ipSource: x-forwarded-for\n", "",
+		"  - apiPath: /goods\n    redirectType: service\n    redirectApp: goods\n    redirectService: goods-api\n    redirectRuntime: master\n", "",
+		"  - package: shop\n    qpd: 10000\n", "",
+		"- name: web\n", "",
+	).Replace(content))
+	plan = configbundle.Diff(current, desired, false)
+	expected = []string{
+		"update api shop/POST /orders",
+		"update policy shop/cors",
+		"update limit mobile/shop:POST /orders",
+	}
+	if got := summary(plan); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected plan without prune:\n%s", strings.Join(got, "\n"))
+	}
+	if desired.Packages[0].Id != "pack-id" {
+		t.Error("id of the existing package should be filled")
+	}
+
+	plan = configbundle.Diff(current, desired, true)
+	expected = []string{
+		"update api shop/POST /orders",
+		"update policy shop/cors",
+		"delete policy shop/POST /orders/ip",
+		"update acl shop",
+		"update limit mobile/shop:POST /orders",
+		"delete limit mobile/shop",
+		"delete api shop//goods",
+		"delete consumer web",
+	}
+	if got := summary(plan); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected plan with prune:\n%s", strings.Join(got, "\n"))
+	}
+	if plan.Changes[4].Fields[0] != "limit" || plan.Changes[0].Fields[0] != "redirectPath" {
+		t.Errorf("unexpected fields: %+v", plan.Changes)
+	}
+	if !strings.HasSuffix(plan.String(), "Plan: 0 to create, 4 to update, 4 to delete.") {
+		t.Errorf("unexpected plan output:\n%s", plan)
+	}
+
+	current.Packages = append(current.Packages, configbundle.Package{Name: "runtime", Managed: true},
+		configbundle.Package{Name: "legacy"})
+	current.Consumers[1].Limits = []configbundle.Limit{{Package: "shop", Id: "limit-id"}}
+	plan = configbundle.Diff(current, desired, true)
+	got := summary(plan)
+	if got[len(got)-2] != "delete package legacy" {
+		t.Errorf("only unmanaged package should be deleted:\n%s", strings.Join(got, "\n"))
+	}
+	if got[len(got)-4] != "delete limit web/shop" {
+		t.Errorf("limits of the deleted consumer should be deleted:\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configbundle
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/exdto"
+)
+
+type Action string
+
+const (
+	ACTION_CREATE Action = "create"
+	ACTION_UPDATE Action = "update"
+	ACTION_DELETE Action = "delete"
+)
+
+type Kind string
+
+const (
+	KIND_CONSUMER Kind = "consumer"
+	KIND_PACKAGE  Kind = "package"
+	KIND_API      Kind = "api"
+	KIND_POLICY   Kind = "policy"
+	KIND_ACL      Kind = "acl"
+	KIND_LIMIT    Kind = "limit"
+)
+
+// Change is a step of the plan, the changes are ordered by their dependencies
+type Change struct {
+	Action Action `json:"action"`
+	Kind   Kind   `json:"kind"`
+	// readable identity of the object, e.g. package/method path/category
+	Name string `json:"name"`
+	// the changed fields of update
+	Fields []string `json:"fields,omitempty"`
+	// the package of package, api, policy and acl changes, ids are filled if exist
+	Package *Package `json:"-"`
+	// the api of api changes and api policy changes
+	Api *Api `json:"-"`
+	// the consumer of consumer and limit changes
+	Consumer *Consumer `json:"-"`
+	Limit    *Limit    `json:"-"`
+	// the category and config of policy changes, nil config means reset to default
+	Category string                 `json:"-"`
+	Config   map[string]interface{} `json:"-"`
+	// the consumers granted to the package of acl changes
+	Consumers []string `json:"-"`
+}
+
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Diff returns the plan to change the current bundle to the desired one,
+// objects not in the desired bundle are deleted only if prune is true
+func Diff(current, desired *Bundle, prune bool) *Plan {
+	plan := &Plan{}
+	currentConsumers := map[string]*Consumer{}
+	for i := range current.Consumers {
+		currentConsumers[current.Consumers[i].Name] = &current.Consumers[i]
+	}
+	currentPackages := map[string]*Package{}
+	for i := range current.Packages {
+		currentPackages[current.Packages[i].Name] = &current.Packages[i]
+	}
+	desiredConsumers := map[string]bool{}
+	for i := range desired.Consumers {
+		consumer := &desired.Consumers[i]
+		desiredConsumers[consumer.Name] = true
+		exist, ok := currentConsumers[consumer.Name]
+		if !ok {
+			plan.add(Change{Action: ACTION_CREATE, Kind: KIND_CONSUMER, Name: consumer.Name, Consumer: consumer})
+			continue
+		}
+		consumer.Id = exist.Id
+		if consumer.Description != exist.Description {
+			plan.add(Change{Action: ACTION_UPDATE, Kind: KIND_CONSUMER, Name: consumer.Name, Consumer: consumer,
+				Fields: []string{"description"}})
+		}
+	}
+	var deletes []Change
+	desiredPackages := map[string]bool{}
+	for i := range desired.Packages {
+		pack := &desired.Packages[i]
+		desiredPackages[pack.Name] = true
+		exist, ok := currentPackages[pack.Name]
+		if !ok {
+			exist = &Package{}
+			plan.add(Change{Action: ACTION_CREATE, Kind: KIND_PACKAGE, Name: pack.Name, Package: pack})
+		} else {
+			pack.Id = exist.Id
+			if fields := packageFields(exist, pack); len(fields) > 0 {
+				plan.add(Change{Action: ACTION_UPDATE, Kind: KIND_PACKAGE, Name: pack.Name, Package: pack, Fields: fields})
+			}
+		}
+		deletes = append(deletes, plan.diffApis(exist, pack, prune)...)
+		plan.diffPolicies(pack.Name, exist.Policies, pack.Policies, prune, Change{Package: pack})
+		for j := range pack.Apis {
+			api := &pack.Apis[j]
+			var existPolicies Policies
+			if existApi := exist.findApi(api.Key()); existApi != nil {
+				existPolicies = existApi.Policies
+			}
+			plan.diffPolicies(pack.Name+"/"+api.Key(), existPolicies, api.Policies, prune, Change{Package: pack, Api: api})
+		}
+		consumers := pack.Consumers
+		if !prune {
+			consumers = normalizeList(append(append([]string{}, consumers...), exist.Consumers...), nil)
+		}
+		if !reflect.DeepEqual(consumers, exist.Consumers) {
+			plan.add(Change{Action: ACTION_UPDATE, Kind: KIND_ACL, Name: pack.Name, Package: pack, Consumers: consumers})
+		}
+	}
+	for i := range desired.Consumers {
+		deletes = append(deletes, plan.diffLimits(currentConsumers[desired.Consumers[i].Name], &desired.Consumers[i], prune)...)
+	}
+	if prune {
+		deletes = append(deletes, pruneDeletes(current, desiredConsumers, desiredPackages)...)
+	}
+	// limits must be deleted before the apis, and packages before the consumers
+	for _, kind := range []Kind{KIND_LIMIT, KIND_API, KIND_PACKAGE, KIND_CONSUMER} {
+		for _, change := range deletes {
+			if change.Kind == kind {
+				plan.add(change)
+			}
+		}
+	}
+	return plan
+}
+
+func pruneDeletes(current *Bundle, desiredConsumers, desiredPackages map[string]bool) []Change {
+	var deletes []Change
+	for i := range current.Consumers {
+		consumer := &current.Consumers[i]
+		if desiredConsumers[consumer.Name] {
+			continue
+		}
+		for j := range consumer.Limits {
+			limit := &consumer.Limits[j]
+			deletes = append(deletes, Change{Action: ACTION_DELETE, Kind: KIND_LIMIT, Name: consumer.Name + "/" + limit.Key(),
+				Consumer: consumer, Limit: limit})
+		}
+		deletes = append(deletes, Change{Action: ACTION_DELETE, Kind: KIND_CONSUMER, Name: consumer.Name, Consumer: consumer})
+	}
+	for i := range current.Packages {
+		pack := &current.Packages[i]
+		if desiredPackages[pack.Name] || pack.Managed {
+			continue
+		}
+		deletes = append(deletes, Change{Action: ACTION_DELETE, Kind: KIND_PACKAGE, Name: pack.Name, Package: pack})
+	}
+	return deletes
+}
+
+func (plan *Plan) add(change Change) {
+	plan.Changes = append(plan.Changes, change)
+}
+
+// diffApis adds the creates and updates of the apis, and returns the deletes
+func (plan *Plan) diffApis(exist, pack *Package, prune bool) []Change {
+	desiredApis := map[string]bool{}
+	for i := range pack.Apis {
+		api := &pack.Apis[i]
+		desiredApis[api.Key()] = true
+		name := pack.Name + "/" + api.Key()
+		existApi := exist.findApi(api.Key())
+		if existApi == nil {
+			plan.add(Change{Action: ACTION_CREATE, Kind: KIND_API, Name: name, Package: pack, Api: api})
+			continue
+		}
+		api.Id = existApi.Id
+		if fields := apiFields(existApi, api); len(fields) > 0 {
+			plan.add(Change{Action: ACTION_UPDATE, Kind: KIND_API, Name: name, Package: pack, Api: api, Fields: fields})
+		}
+	}
+	if !prune {
+		return nil
+	}
+	var deletes []Change
+	for i := range exist.Apis {
+		api := &exist.Apis[i]
+		if desiredApis[api.Key()] {
+			continue
+		}
+		deletes = append(deletes, Change{Action: ACTION_DELETE, Kind: KIND_API, Name: pack.Name + "/" + api.Key(), Package: pack, Api: api})
+	}
+	return deletes
+}
+
+// diffLimits adds the creates and updates of the limits, and returns the deletes
+func (plan *Plan) diffLimits(exist, consumer *Consumer, prune bool) []Change {
+	if exist == nil {
+		exist = &Consumer{}
+	}
+	existLimits := map[string]*Limit{}
+	for i := range exist.Limits {
+		existLimits[exist.Limits[i].Key()] = &exist.Limits[i]
+	}
+	desiredLimits := map[string]bool{}
+	for i := range consumer.Limits {
+		limit := &consumer.Limits[i]
+		desiredLimits[limit.Key()] = true
+		name := consumer.Name + "/" + limit.Key()
+		existLimit, ok := existLimits[limit.Key()]
+		if !ok {
+			plan.add(Change{Action: ACTION_CREATE, Kind: KIND_LIMIT, Name: name, Consumer: consumer, Limit: limit})
+			continue
+		}
+		limit.Id = existLimit.Id
+		if !limitEqual(existLimit.LimitType, limit.LimitType) {
+			plan.add(Change{Action: ACTION_UPDATE, Kind: KIND_LIMIT, Name: name, Consumer: consumer, Limit: limit,
+				Fields: []string{"limit"}})
+		}
+	}
+	if !prune {
+		return nil
+	}
+	var deletes []Change
+	for i := range exist.Limits {
+		limit := &exist.Limits[i]
+		if desiredLimits[limit.Key()] {
+			continue
+		}
+		deletes = append(deletes, Change{Action: ACTION_DELETE, Kind: KIND_LIMIT, Name: consumer.Name + "/" + limit.Key(),
+			Consumer: consumer, Limit: limit})
+	}
+	return deletes
+}
+
+func (plan *Plan) diffPolicies(prefix string, exist, desired Policies, prune bool, base Change) {
+	for _, category := range sortedCategories(desired) {
+		change := base
+		change.Kind = KIND_POLICY
+		change.Name = prefix + "/" + category
+		change.Category = category
+		change.Config = desired[category]
+		existConfig, ok := exist[category]
+		if !ok {
+			change.Action = ACTION_CREATE
+			plan.add(change)
+			continue
+		}
+		if !reflect.DeepEqual(existConfig, change.Config) {
+			change.Action = ACTION_UPDATE
+			plan.add(change)
+		}
+	}
+	if !prune {
+		return
+	}
+	for _, category := range sortedCategories(exist) {
+		if _, ok := desired[category]; ok {
+			continue
+		}
+		change := base
+		change.Action = ACTION_DELETE
+		change.Kind = KIND_POLICY
+		change.Name = prefix + "/" + category
+		change.Category = category
+		plan.add(change)
+	}
+}
+
+func sortedCategories(policies Policies) []string {
+	var res []string
+	for category := range policies {
+		res = append(res, category)
+	}
+	return normalizeList(res, nil)
+}
+
+func packageFields(exist, desired *Package) []string {
+	var fields []string
+	if exist.Description != desired.Description {
+		fields = append(fields, "description")
+	}
+	if exist.Scene != desired.Scene {
+		fields = append(fields, "scene")
+	}
+	if !reflect.DeepEqual(exist.BindDomain, desired.BindDomain) {
+		fields = append(fields, "bindDomain")
+	}
+	if exist.AuthType != desired.AuthType {
+		fields = append(fields, "authType")
+	}
+	if exist.AclType != desired.AclType {
+		fields = append(fields, "aclType")
+	}
+	if !reflect.DeepEqual(exist.AuthConfig, desired.AuthConfig) {
+		fields = append(fields, "authConfig")
+	}
+	return fields
+}
+
+func apiFields(exist, desired *Api) []string {
+	var fields []string
+	for _, field := range []struct {
+		name          string
+		exist, desire string
+	}{
+		{"description", exist.Description, desired.Description},
+		{"redirectType", exist.RedirectType, desired.RedirectType},
+		{"redirectAddr", exist.RedirectAddr, desired.RedirectAddr},
+		{"redirectPath", exist.RedirectPath, desired.RedirectPath},
+		{"redirectApp", exist.RedirectApp, desired.RedirectApp},
+		{"redirectService", exist.RedirectService, desired.RedirectService},
+		{"redirectRuntime", exist.RedirectRuntime, desired.RedirectRuntime},
+	} {
+		if field.exist != field.desire {
+			fields = append(fields, field.name)
+		}
+	}
+	if exist.AllowPassAuth != desired.AllowPassAuth {
+		fields = append(fields, "allowPassAuth")
+	}
+	if !reflect.DeepEqual(exist.Scopes, desired.Scopes) {
+		fields = append(fields, "scopes")
+	}
+	return fields
+}
+
+func limitEqual(x, y exdto.LimitType) bool {
+	equal := func(a, b *int) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return *a == *b
+	}
+	return equal(x.Day, y.Day) && equal(x.Hour, y.Hour) && equal(x.Minute, y.Minute) && equal(x.Second, y.Second)
+}
+
+// Empty returns true if nothing to change
+func (plan *Plan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+// Summary counts the changes by action
+func (plan *Plan) Summary() map[Action]int {
+	res := map[Action]int{ACTION_CREATE: 0, ACTION_UPDATE: 0, ACTION_DELETE: 0}
+	for _, change := range plan.Changes {
+		res[change.Action]++
+	}
+	return res
+}
+
+// String formats the plan for review, one change per line
+func (plan *Plan) String() string {
+	symbols := map[Action]string{ACTION_CREATE: "+", ACTION_UPDATE: "~", ACTION_DELETE: "-"}
+	var lines []string
+	for _, change := range plan.Changes {
+		line := fmt.Sprintf("%s %s %s", symbols[change.Action], change.Kind, change.Name)
+		if len(change.Fields) > 0 {
+			line += " (" + strings.Join(change.Fields, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	summary := plan.Summary()
+	lines = append(lines, fmt.Sprintf("Plan: %d to create, %d to update, %d to delete.",
+		summary[ACTION_CREATE], summary[ACTION_UPDATE], summary[ACTION_DELETE]))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,98 @@
+-- Copyright (c) 2021 Terminus, Inc.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- Runs in the post-function plugin after the jwt plugin verified the token,
+-- it checks the required scopes and forwards the claims as headers.
+-- RULE is the base64 encoded json rule prepended by hepa, cjson.safe must be
+-- listed in untrusted_lua_sandbox_requires of kong.
+
+local cjson = require "cjson.safe"
+
+local B64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+local function base64_decode(data)
+  data = data:gsub("%-", "+"):gsub("_", "/"):gsub("[^%w%+/]", "")
+  local res, bits, count = {}, 0, 0
+  for i = 1, #data do
+    bits = bits * 64 + B64:find(data:sub(i, i), 1, true) - 1
+    count = count + 6
+    if count >= 8 then
+      count = count - 8
+      local byte = math.floor(bits / 2 ^ count)
+      res[#res + 1] = string.char(byte)
+      bits = bits - byte * 2 ^ count
+    end
+  end
+  return table.concat(res)
+end
+
+local rule = cjson.decode(base64_decode(RULE))
+if type(rule) ~= "table" then
+  return kong.response.exit(500, { message = "Invalid claim rule" })
+end
+
+local token = kong.ctx.shared.authenticated_jwt_token
+if not token and ngx and ngx.ctx then
+  token = ngx.ctx.authenticated_jwt_token
+end
+if not token then
+  return kong.response.exit(401, { message = "Unauthorized" })
+end
+local claims = cjson.decode(base64_decode(token:match("^[^.]*%.([^.]*)") or ""))
+if type(claims) ~= "table" then
+  return kong.response.exit(401, { message = "Invalid token claims" })
+end
+
+if rule.required_scopes then
+  local granted = {}
+  local scopes = claims[rule.scope_claim]
+  if type(scopes) == "string" then
+    for scope in scopes:gmatch("%S+") do
+      granted[scope] = true
+    end
+  elseif type(scopes) == "table" then
+    for _, scope in ipairs(scopes) do
+      granted[tostring(scope)] = true
+    end
+  end
+  for _, scope in ipairs(rule.required_scopes) do
+    if not granted[scope] then
+      return kong.response.exit(403, { message = "Insufficient scope" })
+    end
+  end
+end
+
+local headers = rule.claim_headers
+if type(headers) ~= "table" then
+  headers = {}
+end
+for claim, header in pairs(headers) do
+  local v = claims[claim]
+  if v == cjson.null then
+    v = nil
+  end
+  if type(v) == "table" then
+    local items = {}
+    for _, item in ipairs(v) do
+      items[#items + 1] = tostring(item)
+    end
+    v = table.concat(items, " ")
+  end
+  -- the header sent by the client is dropped so the claims can't be forged
+  if v == nil then
+    kong.service.request.clear_header(header)
+  else
+    kong.service.request.set_header(header, tostring(v))
+  end
+end
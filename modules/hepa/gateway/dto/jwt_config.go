@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dto
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// JWT_CLAIMS is the plugin running jwtClaimsScript, the bundled post-function
+// plugin runs after the jwt plugin has verified the token
+const JWT_CLAIMS = "post-function"
+
+//go:embed jwt_claims.lua
+var jwtClaimsScript string
+
+const (
+	DEFAULT_CONSUMER_CLAIM = "iss"
+	DEFAULT_SCOPE_CLAIM    = "scope"
+)
+
+var headerNameRegex = regexp.MustCompile(`^[0-9a-zA-Z-_]+$`)
+
+type JwtConfig struct {
+	// the claim whose value is matched with the key of consumer's jwt credential,
+	// the credentials resolved from a jwks without a pinned key id are keyed by
+	// kid, take kid as the consumer claim to accept all the keys of the jwks
+	ConsumerClaim string `json:"consumerClaim"`
+	// the claim holding the scopes of token, space separated or an array
+	ScopeClaim string `json:"scopeClaim"`
+	// claim name -> header name, the claims are forwarded to upstream as headers
+	ClaimHeaders map[string]string `json:"claimHeaders,omitempty"`
+}
+
+func (config *JwtConfig) Adjust() {
+	if config.ConsumerClaim == "" {
+		config.ConsumerClaim = DEFAULT_CONSUMER_CLAIM
+	}
+	if config.ScopeClaim == "" {
+		config.ScopeClaim = DEFAULT_SCOPE_CLAIM
+	}
+}
+
+func (config JwtConfig) CheckValid() error {
+	for claim, header := range config.ClaimHeaders {
+		if claim == "" || !headerNameRegex.MatchString(header) {
+			return errors.Errorf("invalid claim header, claim:%s, header:%s", claim, header)
+		}
+	}
+	return nil
+}
+
+// Marshal returns the config stored with the package, the defaults are used
+// if it's nil
+func (config *JwtConfig) Marshal() string {
+	if config == nil {
+		config = &JwtConfig{}
+	}
+	config.Adjust()
+	res, _ := json.Marshal(config)
+	return string(res)
+}
+
+// ParseJwtConfig parses the config stored with the package, the defaults are
+// used if it's empty
+func ParseJwtConfig(raw string) (*JwtConfig, error) {
+	config := &JwtConfig{}
+	if raw != "" {
+		err := json.Unmarshal([]byte(raw), config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid jwt config:%s", raw)
+		}
+	}
+	config.Adjust()
+	return config, nil
+}
+
+// AuthPluginConfig returns the config of the jwt plugin
+func (config JwtConfig) AuthPluginConfig() map[string]interface{} {
+	res := map[string]interface{}{}
+	for key, value := range JWTAUTH_CONFIG {
+		res[key] = value
+	}
+	res["key_claim_name"] = config.ConsumerClaim
+	return res
+}
+
+// ClaimPluginConfig returns the config of the post-function plugin forwarding
+// the claims and checking the scopes, nil means nothing to do, the rule of an
+// api overrides the one of the package, so it forwards the claims as well
+func (config JwtConfig) ClaimPluginConfig(scopes []string) map[string]interface{} {
+	if len(config.ClaimHeaders) == 0 && len(scopes) == 0 {
+		return nil
+	}
+	rule := map[string]interface{}{"claim_headers": config.ClaimHeaders}
+	if len(scopes) > 0 {
+		rule["scope_claim"] = config.ScopeClaim
+		rule["required_scopes"] = scopes
+	}
+	content, _ := json.Marshal(rule)
+	// the rule is passed in base64, so it can't break out of the lua string
+	return map[string]interface{}{
+		"functions": []string{`local RULE = "` + base64.StdEncoding.EncodeToString(content) + "\"\n" + jwtClaimsScript},
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dto_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/dto"
+)
+
+func TestJwtConfig_ClaimPluginConfig(t *testing.T) {
+	config, err := dto.ParseJwtConfig(`{"claimHeaders":{"sub":"X-User-Id"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (dto.JwtConfig{}).ClaimPluginConfig(nil) != nil {
+		t.Error("nothing to forward or check, should be nil")
+	}
+	functions, ok := config.ClaimPluginConfig([]string{"read"})["functions"].([]string)
+	if !ok || len(functions) != 1 {
+		t.Fatalf("unexpected functions: %+v", functions)
+	}
+	matched := regexp.MustCompile(`^local RULE = "([A-Za-z0-9+/=]*)"\n`).FindStringSubmatch(functions[0])
+	if matched == nil || !strings.Contains(functions[0], "kong.service.request.set_header") {
+		t.Fatalf("unexpected function: %s", functions[0])
+	}
+	content, err := base64.StdEncoding.DecodeString(matched[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rule map[string]interface{}
+	if err = json.Unmarshal(content, &rule); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"claim_headers":   map[string]interface{}{"sub": "X-User-Id"},
+		"scope_claim":     "scope",
+		"required_scopes": []interface{}{"read"},
+	}
+	if !reflect.DeepEqual(rule, expected) {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+}
@@ -56,6 +56,7 @@ type OpenapiDto struct {
 	Env                string   `json:"-"`
 	RuntimeServiceId   string   `json:"-"`
 	Hosts              []string `json:"hosts"`
+	Scopes             []string `json:"scopes,omitempty"`
 }
 
 func (dto OpenapiDto) ToEndpointApi() *pb.EndpointApi {
@@ -71,6 +72,7 @@ func (dto OpenapiDto) ToEndpointApi() *pb.EndpointApi {
 		AllowPassAuth:       dto.AllowPassAuth,
 		Description:         dto.Description,
 		Hosts:               dto.Hosts,
+		Scopes:              dto.Scopes,
 	}
 	if dto.Method != "" {
 		ep.Method = structpb.NewStringValue(dto.Method)
@@ -91,6 +93,7 @@ func FromEndpointApi(ep *pb.EndpointApi) *OpenapiDto {
 		AllowPassAuth:       ep.AllowPassAuth,
 		Description:         ep.Description,
 		Hosts:               ep.Hosts,
+		Scopes:              ep.Scopes,
 	}
 	if ep.Method != nil {
 		dto.Method = ep.Method.GetStringValue()
@@ -189,6 +192,11 @@ func (dto *OpenapiDto) CheckValid() (bool, string) {
 	} else {
 		return false, fmt.Sprintf("invalid redirect type: %s", dto.RedirectType)
 	}
+	for _, scope := range dto.Scopes {
+		if scope == "" || strings.ContainsAny(scope, ", ") {
+			return false, fmt.Sprintf("invalid scope: %s", scope)
+		}
+	}
 	return true, ""
 }
 
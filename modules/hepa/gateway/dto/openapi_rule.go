@@ -27,18 +27,21 @@ const (
 	ACL_RULE   RuleCategory = "acl"
 	AUTH_RULE  RuleCategory = "auth"
 	LIMIT_RULE RuleCategory = "limit"
+	CLAIM_RULE RuleCategory = "claim"
 )
 
 var RULE_PRIORITY = map[RuleCategory]int{
 	AUTH_RULE:  1000,
 	ACL_RULE:   999,
 	LIMIT_RULE: 998,
+	CLAIM_RULE: 997,
 }
 
 var KEYAUTH_CONFIG map[string]interface{}
 var OAUTH2_CONFIG map[string]interface{}
 var SIGNAUTH_CONFIG map[string]interface{}
 var HMACAUTH_CONFIG map[string]interface{}
+var JWTAUTH_CONFIG map[string]interface{}
 
 type OpenapiRule struct {
 	Region          RuleRegion
@@ -87,4 +90,9 @@ func init() {
 	HMACAUTH_CONFIG["validate_request_body"] = true
 	HMACAUTH_CONFIG["enforce_headers"] = []string{"date", "request-line"}
 	HMACAUTH_CONFIG["algorithms"] = []string{"hmac-sha256", "hmac-sha384", "hmac-sha512"}
+	JWTAUTH_CONFIG = map[string]interface{}{}
+	JWTAUTH_CONFIG["claims_to_verify"] = []string{"exp"}
+	JWTAUTH_CONFIG["header_names"] = []string{"authorization"}
+	JWTAUTH_CONFIG["uri_param_names"] = []string{"jwt"}
+	JWTAUTH_CONFIG["run_on_preflight"] = false
 }
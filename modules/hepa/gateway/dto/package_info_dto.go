@@ -39,7 +39,7 @@ func (list SortBySceneList) Less(i, j int) bool {
 }
 
 func (dto PackageInfoDto) ToEndpoint() *pb.Endpoint {
-	return &pb.Endpoint{
+	ep := &pb.Endpoint{
 		Id:          dto.Id,
 		CreateAt:    dto.CreateAt,
 		Name:        dto.Name,
@@ -49,10 +49,18 @@ func (dto PackageInfoDto) ToEndpoint() *pb.Endpoint {
 		Scene:       dto.Scene,
 		Description: dto.Description,
 	}
+	if dto.JwtConfig != nil {
+		ep.JwtConfig = &pb.JwtConfig{
+			ConsumerClaim: dto.JwtConfig.ConsumerClaim,
+			ScopeClaim:    dto.JwtConfig.ScopeClaim,
+			ClaimHeaders:  dto.JwtConfig.ClaimHeaders,
+		}
+	}
+	return ep
 }
 
 func FromEndpoint(ep *pb.Endpoint) *PackageDto {
-	return &PackageDto{
+	dto := &PackageDto{
 		Name:        ep.Name,
 		BindDomain:  ep.BindDomain,
 		AuthType:    ep.AuthType,
@@ -60,4 +68,12 @@ func FromEndpoint(ep *pb.Endpoint) *PackageDto {
 		Scene:       ep.Scene,
 		Description: ep.Description,
 	}
+	if ep.JwtConfig != nil {
+		dto.JwtConfig = &JwtConfig{
+			ConsumerClaim: ep.JwtConfig.ConsumerClaim,
+			ScopeClaim:    ep.JwtConfig.ScopeClaim,
+			ClaimHeaders:  ep.JwtConfig.ClaimHeaders,
+		}
+	}
+	return dto
 }
@@ -22,6 +22,7 @@ const (
 	AT_OAUTH2     = "oauth2"
 	AT_SIGN_AUTH  = "sign-auth"
 	AT_HMAC_AUTH  = "hmac-auth"
+	AT_JWT        = "jwt"
 	AT_ALIYUN_APP = "aliyun-app"
 )
 
@@ -41,13 +42,14 @@ const (
 )
 
 type PackageDto struct {
-	Name             string   `json:"name"`
-	BindDomain       []string `json:"bindDomain"`
-	AuthType         string   `json:"authType"`
-	AclType          string   `json:"aclType"`
-	Scene            string   `json:"scene"`
-	Description      string   `json:"description"`
-	NeedBindCloudapi bool     `json:"needBindCloudapi"`
+	Name             string     `json:"name"`
+	BindDomain       []string   `json:"bindDomain"`
+	AuthType         string     `json:"authType"`
+	AclType          string     `json:"aclType"`
+	Scene            string     `json:"scene"`
+	Description      string     `json:"description"`
+	NeedBindCloudapi bool       `json:"needBindCloudapi"`
+	JwtConfig        *JwtConfig `json:"jwtConfig,omitempty"`
 }
 
 func (dto PackageDto) CheckValid() error {
@@ -57,6 +59,11 @@ func (dto PackageDto) CheckValid() error {
 	if !dto.NeedBindCloudapi && dto.AuthType == AT_ALIYUN_APP {
 		goto failed
 	}
+	if dto.AuthType == AT_JWT && dto.JwtConfig != nil {
+		if err := dto.JwtConfig.CheckValid(); err != nil {
+			return err
+		}
+	}
 	return nil
 failed:
 	return errors.Errorf("invalid dto: %+v", dto)
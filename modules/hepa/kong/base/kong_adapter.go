@@ -238,6 +238,32 @@ func (impl *KongAdapterImpl) CreateOrUpdateService(req *KongServiceReqDto) (*Kon
 	return nil, errors.Errorf("CreateOrUpdateService failed: code[%d] msg[%s]", code, body)
 }
 
+// GetService returns nil if the service not found
+func (impl *KongAdapterImpl) GetService(id string) (*KongServiceRespDto, error) {
+	if impl == nil {
+		return nil, errors.New("kong can't be attached")
+	}
+	if len(id) == 0 {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	code, body, err := util.DoCommonRequest(impl.Client, "GET", impl.KongAddr+ServiceRoot+id, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	if code == 404 {
+		return nil, nil
+	}
+	if code == 200 {
+		respDto := &KongServiceRespDto{}
+		err = json.Unmarshal(body, respDto)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unmarshal body failed, body:%s", body)
+		}
+		return respDto, nil
+	}
+	return nil, errors.Errorf("GetService failed: code[%d] msg[%s]", code, body)
+}
+
 func (impl *KongAdapterImpl) DeleteService(id string) error {
 	if impl == nil {
 		return errors.New("kong can't be attached")
@@ -292,6 +318,33 @@ func (impl *KongAdapterImpl) GetPlugin(req *KongPluginReqDto) (*KongPluginRespDt
 	return nil, errors.Errorf("get plugin failed: code[%d] msg[%s]", code, body)
 }
 
+// GetRoutePlugins returns all the plugins bound to the route
+func (impl *KongAdapterImpl) GetRoutePlugins(routeId string) ([]KongPluginRespDto, error) {
+	if impl == nil {
+		return nil, errors.New("kong can't be attached")
+	}
+	if routeId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	code, body, err := util.DoCommonRequest(impl.Client, "GET",
+		impl.KongAddr+RouteRoot+routeId+PluginRoot+"?size=1000", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	if code == 200 {
+		respDto := &KongPluginsDto{}
+		err = json.Unmarshal(body, respDto)
+		if err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		for i := range respDto.Data {
+			respDto.Data[i].Compatiable()
+		}
+		return respDto.Data, nil
+	}
+	return nil, errors.Errorf("get route plugins failed: code[%d] msg[%s]", code, body)
+}
+
 func (impl *KongAdapterImpl) CreateOrUpdatePluginById(req *KongPluginReqDto) (*KongPluginRespDto, error) {
 	if impl == nil {
 		return nil, errors.New("kong can't be attached")
@@ -548,6 +601,32 @@ func (impl *KongAdapterImpl) CreateCredential(req *KongCredentialReqDto) (*KongC
 	return nil, errors.Errorf("CreateCredential failed: code[%d] msg[%s]", code, body)
 }
 
+// UpdateCredential patches the credential whose id is req.Config.Id
+func (impl *KongAdapterImpl) UpdateCredential(req *KongCredentialReqDto) (*KongCredentialDto, error) {
+	if impl == nil {
+		return nil, errors.New("kong can't be attached")
+	}
+	if req == nil || req.IsEmpty() || req.Config == nil || req.Config.Id == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	config := *req.Config
+	config.Id = ""
+	code, body, err := util.DoCommonRequest(impl.Client, "PATCH", impl.KongAddr+ConsumerRoot+req.ConsumerId+"/"+req.PluginName+"/"+req.Config.Id, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	if code == 200 {
+		respDto := &KongCredentialDto{}
+		err = json.Unmarshal(body, respDto)
+		if err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		respDto.Compatiable()
+		return respDto, nil
+	}
+	return nil, errors.Errorf("UpdateCredential failed: code[%d] msg[%s]", code, body)
+}
+
 func (impl *KongAdapterImpl) DeleteCredential(consumerId, pluginName, credentialId string) error {
 	if impl == nil {
 		return errors.New("kong can't be attached")
@@ -627,6 +706,50 @@ func (impl *KongAdapterImpl) CreateUpstream(req *KongUpstreamDto) (*KongUpstream
 	return nil, errors.Errorf("CreateUpstream failed: code[%d] msg[%s]", code, body)
 }
 
+// GetUpstream gets the upstream by id or name, returns nil if not exist
+func (impl *KongAdapterImpl) GetUpstream(upstreamId string) (*KongUpstreamDto, error) {
+	if impl == nil {
+		return nil, errors.New("kong can't be attached")
+	}
+	if upstreamId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	code, body, err := util.DoCommonRequest(impl.Client, "GET", impl.KongAddr+UpstreamRoot+upstreamId, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "request failed")
+	}
+	if code == 404 {
+		return nil, nil
+	}
+	if code == 200 {
+		respDto := &KongUpstreamDto{}
+		err = json.Unmarshal(body, respDto)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unmarshal body failed, body:%s", body)
+		}
+		return respDto, nil
+	}
+	return nil, errors.Errorf("GetUpstream failed: code[%d] msg[%s]", code, body)
+}
+
+// DeleteUpstream deletes the upstream and its targets by id or name
+func (impl *KongAdapterImpl) DeleteUpstream(upstreamId string) error {
+	if impl == nil {
+		return errors.New("kong can't be attached")
+	}
+	if upstreamId == "" {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	code, body, err := util.DoCommonRequest(impl.Client, "DELETE", impl.KongAddr+UpstreamRoot+upstreamId, nil)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	if code < 300 || code == 404 {
+		return nil
+	}
+	return errors.Errorf("DeleteUpstream failed: code[%d] msg[%s]", code, body)
+}
+
 func (impl *KongAdapterImpl) GetUpstreamStatus(upstreamId string) (*KongUpstreamStatusRespDto, error) {
 	if impl == nil {
 		return nil, errors.New("kong can't be attached")
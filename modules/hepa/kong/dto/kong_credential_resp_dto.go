@@ -38,6 +38,11 @@ func (dto KongCredentialDto) ToCredential() *pb.Credential {
 		ClientSecret: dto.ClientSecret,
 		Secret:       dto.Secret,
 		Username:     dto.Username,
+		Algorithm:    dto.Algorithm,
+		RsaPublicKey: dto.RsaPublicKey,
+		Issuer:       dto.Issuer,
+		JwksUri:      dto.JwksUri,
+		KeyId:        dto.KeyId,
 	}
 	v, _ := structpb.NewValue(util.GetPureInterface(dto.RedirectUrl))
 	res.RedirectUrl = v
@@ -56,6 +61,11 @@ func FromCredential(cred *pb.Credential) KongCredentialDto {
 		ClientSecret: cred.ClientSecret,
 		Secret:       cred.Secret,
 		Username:     cred.Username,
+		Algorithm:    cred.Algorithm,
+		RsaPublicKey: cred.RsaPublicKey,
+		Issuer:       cred.Issuer,
+		JwksUri:      cred.JwksUri,
+		KeyId:        cred.KeyId,
 	}
 	res.RedirectUrl = cred.RedirectUrl.AsInterface()
 	return res
@@ -78,6 +88,13 @@ type KongCredentialDto struct {
 	Secret string `json:"secret,omitempty"`
 	// hmac-auth
 	Username string `json:"username,omitempty"`
+	// jwt
+	Algorithm    string `json:"algorithm,omitempty"`
+	RsaPublicKey string `json:"rsa_public_key,omitempty"`
+	// jwt, kept by hepa to resolve the public key from jwks
+	Issuer  string `json:"-"`
+	JwksUri string `json:"-"`
+	KeyId   string `json:"-"`
 }
 
 func (dto *KongCredentialDto) ToHmacReq() {
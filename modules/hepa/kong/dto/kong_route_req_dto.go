@@ -47,6 +47,8 @@ type KongRouteReqDto struct {
 	Service *Service `json:"service,omitempty"`
 	// 正则匹配优先级,当前使用路径中/的个数
 	RegexPriority int `json:"regex_priority,omitempty"`
+	// 按请求头匹配, kong 1.3 以上版本支持
+	Headers map[string][]string `json:"headers,omitempty"`
 	// 真正的路由id，更新时使用
 	RouteId string `json:"-"`
 
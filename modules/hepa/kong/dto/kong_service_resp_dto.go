@@ -23,4 +23,9 @@ type KongServiceRespDto struct {
 	Host      string `json:"host"`
 	Port      int    `json:"port"`
 	Path      string `json:"path"`
+
+	Retries        *int `json:"retries,omitempty"`
+	ConnectTimeout int  `json:"connect_timeout,omitempty"`
+	WriteTimeout   int  `json:"write_timeout,omitempty"`
+	ReadTimeout    int  `json:"read_timeout,omitempty"`
 }
@@ -18,7 +18,12 @@ import (
 	. "github.com/erda-project/erda/modules/hepa/kong/dto"
 )
 
-type KongAdapter interface {
+// KongAdapter is kept for the callers written against kong
+type KongAdapter = GatewayAdapter
+
+// GatewayAdapter is the contract between hepa and a gateway backend, it is
+// implemented by kong and by the xDS control plane in kong/xds
+type GatewayAdapter interface {
 	KongExist() bool
 	GetVersion() (string, error)
 	CheckPluginEnabled(pluginName string) (bool, error)
@@ -28,20 +33,25 @@ type KongAdapter interface {
 	DeleteRoute(string) error
 	UpdateRoute(req *KongRouteReqDto) (*KongRouteRespDto, error)
 	CreateOrUpdateService(req *KongServiceReqDto) (*KongServiceRespDto, error)
+	GetService(string) (*KongServiceRespDto, error)
 	DeleteService(string) error
 	DeletePluginIfExist(req *KongPluginReqDto) error
 	CreateOrUpdatePlugin(req *KongPluginReqDto) (*KongPluginRespDto, error)
 	CreateOrUpdatePluginById(req *KongPluginReqDto) (*KongPluginRespDto, error)
 	GetPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error)
+	GetRoutePlugins(routeId string) ([]KongPluginRespDto, error)
 	AddPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error)
 	UpdatePlugin(req *KongPluginReqDto) (*KongPluginRespDto, error)
 	PutPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error)
 	RemovePlugin(string) error
 	CreateCredential(req *KongCredentialReqDto) (*KongCredentialDto, error)
 	DeleteCredential(string, string, string) error
+	UpdateCredential(req *KongCredentialReqDto) (*KongCredentialDto, error)
 	GetCredentialList(string, string) (*KongCredentialListDto, error)
 	CreateAclGroup(string, string) error
 	CreateUpstream(req *KongUpstreamDto) (*KongUpstreamDto, error)
+	GetUpstream(string) (*KongUpstreamDto, error)
+	DeleteUpstream(string) error
 	GetUpstreamStatus(string) (*KongUpstreamStatusRespDto, error)
 	AddUpstreamTarget(string, *KongTargetDto) (*KongTargetDto, error)
 	DeleteUpstreamTarget(string, string) error
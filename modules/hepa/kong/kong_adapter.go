@@ -24,6 +24,7 @@ import (
 	"github.com/erda-project/erda/modules/hepa/config"
 	"github.com/erda-project/erda/modules/hepa/kong/base"
 	v2 "github.com/erda-project/erda/modules/hepa/kong/v2"
+	"github.com/erda-project/erda/modules/hepa/kong/xds"
 	"github.com/erda-project/erda/modules/hepa/repository/orm"
 	"github.com/erda-project/erda/modules/hepa/repository/service"
 )
@@ -45,7 +46,28 @@ var (
 	ErrInvalidReq = errors.New("kongAdapter: invalid request")
 )
 
+// AdminAddr returns the gateway address recorded for a cluster, clusters
+// configured in XdsClusters are served by the xDS control plane of hepa
+func AdminAddr(az, kongAddr string) string {
+	for _, cluster := range config.ServerConf.XdsClusters {
+		if cluster == az {
+			return orm.XDS_ADDR_PREFIX + az
+		}
+	}
+	return kongAddr
+}
+
 func newKongAdapter(kongAddr string, client *http.Client) KongAdapter {
+	if strings.HasPrefix(kongAddr, orm.XDS_ADDR_PREFIX) {
+		cluster := strings.TrimPrefix(kongAddr, orm.XDS_ADDR_PREFIX)
+		adapter, err := xds.NewAdapter(cluster)
+		if err != nil {
+			log.Errorf("create xds adapter failed, cluster:%s, err:%+v", cluster, err)
+			var empty *xds.AdapterImpl
+			return empty
+		}
+		return adapter
+	}
 	var empty *base.KongAdapterImpl
 	base := &base.KongAdapterImpl{
 		KongAddr: kongAddr,
@@ -0,0 +1,1155 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	. "github.com/erda-project/erda/modules/hepa/common/vars"
+	. "github.com/erda-project/erda/modules/hepa/kong/dto"
+)
+
+// Version is reported as a Kong 2.x compatible version, callers gate route
+// tags, path handling and header matching on the major version
+const Version = "2.0.0-xds"
+
+var (
+	ErrInvalidReq = errors.New("xdsAdapter: invalid request")
+	// ErrPluginNotSupported is returned for the plugins envoy can't enforce, the route
+	// must not be published without them
+	ErrPluginNotSupported = errors.New("xdsAdapter: plugin not supported")
+	errDetached           = errors.New("xds gateway can't be attached")
+)
+
+// DomainPolicy is the global plugin hepa enables the route plugins by, they
+// are created disabled and listed in its enables
+const DomainPolicy = "domain-policy"
+
+// supportedPlugins are the plugins the translator turns into envoy filters,
+// binding other plugins fails with ErrPluginNotSupported
+var supportedPlugins = map[string]bool{
+	"acl":           true,
+	"cors":          true,
+	"key-auth":      true,
+	"rate-limiting": true,
+	DomainPolicy:    true,
+	// only annotates the requests for monitoring, it is stored but not translated
+	"set-route-info": true,
+}
+
+type serviceObj struct {
+	KongServiceRespDto
+}
+
+type routeObj struct {
+	KongRouteRespDto
+	StripPath     *bool               `json:"strip_path,omitempty"`
+	PreserveHost  *bool               `json:"preserve_host,omitempty"`
+	RegexPriority int                 `json:"regex_priority,omitempty"`
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`
+}
+
+type consumerObj struct {
+	KongConsumerRespDto
+	Username string `json:"username"`
+}
+
+// AdapterImpl implements the kong adapter contract on top of a resource
+// store, envoy pulls the translated configuration from the discovery server
+type AdapterImpl struct {
+	Cluster string
+	Store   Store
+}
+
+func NewAdapter(cluster string) (*AdapterImpl, error) {
+	store, err := NewDBStore(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return &AdapterImpl{
+		Cluster: cluster,
+		Store:   store,
+	}, nil
+}
+
+func (impl *AdapterImpl) KongExist() bool {
+	return impl != nil && impl.Store != nil
+}
+
+func (impl *AdapterImpl) GetVersion() (string, error) {
+	if !impl.KongExist() {
+		return "", errDetached
+	}
+	return Version, nil
+}
+
+func (impl *AdapterImpl) CheckPluginEnabled(pluginName string) (bool, error) {
+	if !impl.KongExist() {
+		return false, errDetached
+	}
+	return supportedPlugins[pluginName], nil
+}
+
+func (impl *AdapterImpl) CreateConsumer(req *KongConsumerReqDto) (*KongConsumerRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.IsEmpty() {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	list, err := impl.Store.List(KindConsumer)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range list {
+		if res.Name == req.Username {
+			return nil, errors.Errorf("CreateConsumer failed: username[%s] already exists", req.Username)
+		}
+	}
+	consumer := consumerObj{
+		KongConsumerRespDto: KongConsumerRespDto{
+			Id:        newId(),
+			CustomId:  req.CustomId,
+			CreatedAt: time.Now().Unix(),
+		},
+		Username: req.Username,
+	}
+	err = impl.save(&Resource{Id: consumer.Id, Kind: KindConsumer, Name: req.Username}, consumer, true)
+	if err != nil {
+		return nil, err
+	}
+	return &consumer.KongConsumerRespDto, nil
+}
+
+func (impl *AdapterImpl) DeleteConsumer(id string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if len(id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	for _, kind := range []string{KindCredential, KindAcl} {
+		if err := impl.deleteChildren(kind, id); err != nil {
+			return err
+		}
+	}
+	plugins, err := impl.plugins()
+	if err != nil {
+		return err
+	}
+	for _, plugin := range plugins {
+		if plugin.ConsumerId == id {
+			if err = impl.Store.Delete(KindPlugin, plugin.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return impl.Store.Delete(KindConsumer, id)
+}
+
+func (impl *AdapterImpl) UpdateRoute(req *KongRouteReqDto) (*KongRouteRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.RouteId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	route := &routeObj{}
+	res, err := impl.load(KindRoute, req.RouteId, route)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("UpdateRoute failed: route[%s] not found", req.RouteId)
+	}
+	if req.Protocols != nil {
+		route.Protocols = req.Protocols
+	}
+	if req.Methods != nil {
+		route.Methods = req.Methods
+	}
+	if req.Hosts != nil {
+		route.Hosts = req.Hosts
+	}
+	if req.Paths != nil {
+		route.Paths = req.Paths
+	}
+	if req.StripPath != nil {
+		route.StripPath = req.StripPath
+	}
+	if req.PreserveHost != nil {
+		route.PreserveHost = req.PreserveHost
+	}
+	if req.Service != nil {
+		route.Service = *req.Service
+	}
+	if req.RegexPriority != 0 {
+		route.RegexPriority = req.RegexPriority
+	}
+	if req.Headers != nil {
+		route.Headers = req.Headers
+	}
+	if req.Tags != nil {
+		route.Tags = req.Tags
+	}
+	return impl.saveRoute(route, false)
+}
+
+func (impl *AdapterImpl) CreateOrUpdateRoute(req *KongRouteReqDto) (*KongRouteRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	route := &routeObj{
+		KongRouteRespDto: KongRouteRespDto{
+			Id:        req.RouteId,
+			Protocols: req.Protocols,
+			Methods:   req.Methods,
+			Hosts:     req.Hosts,
+			Paths:     req.Paths,
+		},
+		StripPath:     req.StripPath,
+		PreserveHost:  req.PreserveHost,
+		RegexPriority: req.RegexPriority,
+		Headers:       req.Headers,
+		Tags:          req.Tags,
+	}
+	if req.Service != nil {
+		route.Service = *req.Service
+	}
+	create := true
+	if route.Id != "" {
+		exist := &routeObj{}
+		res, err := impl.load(KindRoute, route.Id, exist)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			create = false
+			route.CreatedAt = exist.CreatedAt
+		}
+	}
+	return impl.saveRoute(route, create)
+}
+
+func (impl *AdapterImpl) saveRoute(route *routeObj, create bool) (*KongRouteRespDto, error) {
+	if err := impl.validateRoute(route); err != nil {
+		log.Errorf("CreateOrUpdateRoute failed: %+v", err)
+		return nil, ErrInvalidReq
+	}
+	if route.Id == "" {
+		route.Id = newId()
+	}
+	if len(route.Protocols) == 0 {
+		route.Protocols = []string{"http", "https"}
+	}
+	now := time.Now().Unix()
+	if route.CreatedAt == 0 {
+		route.CreatedAt = now
+	}
+	route.UpdatedAt = now
+	err := impl.save(&Resource{Id: route.Id, Kind: KindRoute, ParentId: route.Service.Id, Tags: route.Tags}, route, create)
+	if err != nil {
+		return nil, err
+	}
+	return &route.KongRouteRespDto, nil
+}
+
+func (impl *AdapterImpl) validateRoute(route *routeObj) error {
+	if route.Service.Id == "" {
+		return errors.New("service is required")
+	}
+	if len(route.Methods) == 0 && len(route.Hosts) == 0 && len(route.Paths) == 0 && len(route.Headers) == 0 {
+		return errors.New("at least one of methods, hosts, paths or headers is required")
+	}
+	res, err := impl.Store.Get(KindService, route.Service.Id)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return errors.Errorf("service[%s] not found", route.Service.Id)
+	}
+	for _, path := range route.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return errors.Errorf("path[%s] must begin with /", path)
+		}
+		if isRegexPath(path) {
+			if _, err := regexp.Compile(path); err != nil {
+				return errors.Wrapf(err, "invalid regex path[%s]", path)
+			}
+		}
+	}
+	return nil
+}
+
+func (impl *AdapterImpl) TouchRouteOAuthMethod(id string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	route := &routeObj{}
+	res, err := impl.load(KindRoute, id, route)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return errors.Errorf("get route info failed: route[%s] not found", id)
+	}
+	for _, method := range route.Methods {
+		if method == "POST" {
+			return nil
+		}
+	}
+	if len(route.Paths) == 0 {
+		return nil
+	}
+	reqDto := NewKongRouteReqDto()
+	reqDto.Methods = []string{"POST"}
+	reqDto.Hosts = route.Hosts
+	reqDto.Paths = []string{route.Paths[0] + "/oauth2/token", route.Paths[0] + "/oauth2/authorize"}
+	reqDto.Service = &route.Service
+	_, err = impl.CreateOrUpdateRoute(reqDto)
+	return err
+}
+
+func (impl *AdapterImpl) DeleteRoute(id string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if len(id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	if err := impl.deletePlugins(func(plugin *KongPluginRespDto) bool { return plugin.RouteId == id }); err != nil {
+		return err
+	}
+	return impl.Store.Delete(KindRoute, id)
+}
+
+func (impl *AdapterImpl) CreateOrUpdateService(req *KongServiceReqDto) (*KongServiceRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.IsEmpty() {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	svc := &serviceObj{
+		KongServiceRespDto: KongServiceRespDto{
+			Id:       req.ServiceId,
+			Name:     req.Name,
+			Protocol: req.Protocol,
+			Host:     req.Host,
+			Port:     req.Port,
+			Path:     req.Path,
+
+			Retries:        req.Retries,
+			ConnectTimeout: req.ConnectTimeout,
+			WriteTimeout:   req.WriteTimeout,
+			ReadTimeout:    req.ReadTimeout,
+		},
+	}
+	if req.Url != "" {
+		if err := svc.parseUrl(req.Url); err != nil {
+			return nil, err
+		}
+	}
+	if svc.Protocol == "" {
+		svc.Protocol = "http"
+	}
+	if svc.Port == 0 {
+		svc.Port = 80
+		if svc.Protocol == "https" {
+			svc.Port = 443
+		}
+	}
+	now := time.Now().Unix()
+	create := true
+	if svc.Id != "" {
+		exist := &serviceObj{}
+		res, err := impl.load(KindService, svc.Id, exist)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			create = false
+			svc.CreatedAt = exist.CreatedAt
+		}
+	} else {
+		svc.Id = newId()
+	}
+	if svc.CreatedAt == 0 {
+		svc.CreatedAt = now
+	}
+	svc.UpdatedAt = now
+	err := impl.save(&Resource{Id: svc.Id, Kind: KindService, Name: svc.Name}, svc, create)
+	if err != nil {
+		return nil, err
+	}
+	return &svc.KongServiceRespDto, nil
+}
+
+func (svc *serviceObj) parseUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return errors.Errorf("invalid service url:%s", raw)
+	}
+	svc.Protocol = u.Scheme
+	svc.Host = u.Hostname()
+	svc.Path = u.Path
+	if port := u.Port(); port != "" {
+		if svc.Port, err = strconv.Atoi(port); err != nil {
+			return errors.Errorf("invalid service url:%s", raw)
+		}
+	}
+	return nil
+}
+
+func (impl *AdapterImpl) GetService(id string) (*KongServiceRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if len(id) == 0 {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	svc := &serviceObj{}
+	res, err := impl.load(KindService, id, svc)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	return &svc.KongServiceRespDto, nil
+}
+
+func (impl *AdapterImpl) DeleteService(id string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if len(id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	routes, err := impl.Store.List(KindRoute)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if route.ParentId == id {
+			return errors.Errorf("DeleteService failed: service[%s] still referenced by route[%s]", id, route.Id)
+		}
+	}
+	if err = impl.deletePlugins(func(plugin *KongPluginRespDto) bool { return plugin.ServiceId == id }); err != nil {
+		return err
+	}
+	return impl.Store.Delete(KindService, id)
+}
+
+func (impl *AdapterImpl) GetPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.Name == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	plugins, err := impl.plugins()
+	if err != nil {
+		return nil, err
+	}
+	routeId, serviceId, consumerId := pluginScope(req)
+	for i := range plugins {
+		plugin := &plugins[i]
+		if plugin.Name != req.Name {
+			continue
+		}
+		if routeId != "" && plugin.RouteId != routeId ||
+			serviceId != "" && plugin.ServiceId != serviceId ||
+			consumerId != "" && plugin.ConsumerId != consumerId {
+			continue
+		}
+		return plugin, nil
+	}
+	return nil, nil
+}
+
+func (impl *AdapterImpl) GetRoutePlugins(routeId string) ([]KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if routeId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	plugins, err := impl.plugins()
+	if err != nil {
+		return nil, err
+	}
+	var res []KongPluginRespDto
+	for _, plugin := range plugins {
+		if plugin.RouteId == routeId {
+			res = append(res, plugin)
+		}
+	}
+	return res, nil
+}
+
+func (impl *AdapterImpl) CreateOrUpdatePluginById(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	return impl.putPlugin(req.Id, req)
+}
+
+func (impl *AdapterImpl) DeletePluginIfExist(req *KongPluginReqDto) error {
+	enabled, err := impl.CheckPluginEnabled(req.Name)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		log.Warnf("plugin %s not enabled, req:%+v", req.Name, req)
+		return nil
+	}
+	exist, err := impl.GetPlugin(req)
+	if err != nil {
+		return err
+	}
+	if exist == nil {
+		return nil
+	}
+	return impl.RemovePlugin(exist.Id)
+}
+
+func (impl *AdapterImpl) CreateOrUpdatePlugin(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	enabled, err := impl.CheckPluginEnabled(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.Wrapf(ErrPluginNotSupported, "plugin %s", req.Name)
+	}
+	exist, err := impl.GetPlugin(req)
+	if err != nil {
+		return nil, err
+	}
+	if exist == nil {
+		return impl.AddPlugin(req)
+	}
+	req.Id = exist.Id
+	req.PluginId = exist.Id
+	return impl.PutPlugin(req)
+}
+
+func (impl *AdapterImpl) AddPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	return impl.putPlugin("", req)
+}
+
+func (impl *AdapterImpl) PutPlugin(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	return impl.putPlugin(req.PluginId, req)
+}
+
+func (impl *AdapterImpl) UpdatePlugin(req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.PluginId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	plugin := &KongPluginRespDto{}
+	res, err := impl.load(KindPlugin, req.PluginId, plugin)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("UpdatePlugin failed: plugin[%s] not found", req.PluginId)
+	}
+	if req.Enabled != nil {
+		plugin.Enabled = *req.Enabled
+	}
+	for key, value := range req.Config {
+		if plugin.Config == nil {
+			plugin.Config = make(map[string]interface{})
+		}
+		plugin.Config[key] = value
+	}
+	if plugin.Name == DomainPolicy {
+		if err = impl.checkDomainPolicy(plugin.Config); err != nil {
+			return nil, err
+		}
+	}
+	err = impl.save(res, plugin, false)
+	if err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+func (impl *AdapterImpl) putPlugin(id string, req *KongPluginReqDto) (*KongPluginRespDto, error) {
+	enabled, err := impl.CheckPluginEnabled(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.Wrapf(ErrPluginNotSupported, "plugin %s", req.Name)
+	}
+	routeId, serviceId, consumerId := pluginScope(req)
+	if consumerId != "" {
+		return nil, errors.Wrapf(ErrPluginNotSupported, "plugin %s of consumer %s", req.Name, consumerId)
+	}
+	if req.Name == DomainPolicy {
+		if err = impl.checkDomainPolicy(req.Config); err != nil {
+			return nil, err
+		}
+	}
+	plugin := &KongPluginRespDto{
+		Id:         id,
+		RouteId:    routeId,
+		ServiceId:  serviceId,
+		ConsumerId: consumerId,
+		Name:       req.Name,
+		Config:     req.Config,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		CreatedAt:  time.Now().Unix(),
+	}
+	create := true
+	if id != "" {
+		exist := &KongPluginRespDto{}
+		res, err := impl.load(KindPlugin, id, exist)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			create = false
+			plugin.CreatedAt = exist.CreatedAt
+		}
+	} else {
+		plugin.Id = newId()
+	}
+	err = impl.save(&Resource{Id: plugin.Id, Kind: KindPlugin, Name: plugin.Name}, plugin, create)
+	if err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// checkDomainPolicy rejects the domain policy enabling a plugin not bound to a route,
+// kong scopes such plugins by the path regex of the policy which envoy can't
+func (impl *AdapterImpl) checkDomainPolicy(config map[string]interface{}) error {
+	plugins, err := impl.plugins()
+	if err != nil {
+		return err
+	}
+	enables := policyEnables(config)
+	for _, plugin := range plugins {
+		if enables[plugin.Id] && !plugin.Enabled && plugin.RouteId == "" {
+			return errors.Wrapf(ErrPluginNotSupported, "plugin %s[%s] enabled by the domain policy is not bound to a route",
+				plugin.Name, plugin.Id)
+		}
+	}
+	return nil
+}
+
+func (impl *AdapterImpl) RemovePlugin(id string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if len(id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	return impl.Store.Delete(KindPlugin, id)
+}
+
+func (impl *AdapterImpl) CreateCredential(req *KongCredentialReqDto) (*KongCredentialDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.IsEmpty() {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.Store.Get(KindConsumer, req.ConsumerId)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("CreateCredential failed: consumer[%s] not found", req.ConsumerId)
+	}
+	credential := KongCredentialDto{}
+	if req.Config != nil {
+		credential = *req.Config
+	}
+	credential.Id = newId()
+	credential.ConsumerId = req.ConsumerId
+	credential.CreatedAt = time.Now().Unix()
+	switch req.PluginName {
+	case "key-auth", "sign-auth":
+		if credential.Key == "" {
+			credential.Key = randomKey()
+		}
+		if req.PluginName == "sign-auth" && credential.Secret == "" {
+			credential.Secret = randomKey()
+		}
+	case "hmac-auth":
+		if credential.Key != "" && credential.Username == "" {
+			credential.Username = credential.Key
+		}
+		credential.Key = ""
+		if credential.Secret == "" {
+			credential.Secret = randomKey()
+		}
+	case "oauth2":
+		if credential.ClientId == "" {
+			credential.ClientId = randomKey()
+		}
+		if credential.ClientSecret == "" {
+			credential.ClientSecret = randomKey()
+		}
+		credential.ToV2()
+	}
+	err = impl.save(&Resource{Id: credential.Id, Kind: KindCredential, Name: req.PluginName, ParentId: req.ConsumerId}, credential, true)
+	if err != nil {
+		return nil, err
+	}
+	return respCredential(req.PluginName, credential), nil
+}
+
+// UpdateCredential overwrites the non-empty fields of the credential whose id is req.Config.Id
+func (impl *AdapterImpl) UpdateCredential(req *KongCredentialReqDto) (*KongCredentialDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.IsEmpty() || req.Config == nil || req.Config.Id == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	credential := KongCredentialDto{}
+	res, err := impl.load(KindCredential, req.Config.Id, &credential)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || res.ParentId != req.ConsumerId || res.Name != req.PluginName {
+		return nil, errors.Errorf("UpdateCredential failed: credential[%s] not found", req.Config.Id)
+	}
+	patch, err := json.Marshal(req.Config)
+	if err != nil {
+		return nil, errors.Wrap(err, ERR_JSON_FAIL)
+	}
+	if err = json.Unmarshal(patch, &credential); err != nil {
+		return nil, errors.Wrap(err, ERR_JSON_FAIL)
+	}
+	err = impl.save(&Resource{Id: credential.Id, Kind: KindCredential, Name: req.PluginName, ParentId: req.ConsumerId}, credential, false)
+	if err != nil {
+		return nil, err
+	}
+	return respCredential(req.PluginName, credential), nil
+}
+
+func (impl *AdapterImpl) DeleteCredential(consumerId, pluginName, credentialId string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	credential := &KongCredentialDto{}
+	res, err := impl.load(KindCredential, credentialId, credential)
+	if err != nil {
+		return err
+	}
+	if res == nil || res.ParentId != consumerId || res.Name != pluginName {
+		return nil
+	}
+	return impl.Store.Delete(KindCredential, credentialId)
+}
+
+func (impl *AdapterImpl) GetCredentialList(consumerId, pluginName string) (*KongCredentialListDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	list, err := impl.Store.List(KindCredential)
+	if err != nil {
+		return nil, err
+	}
+	respDto := &KongCredentialListDto{Data: []KongCredentialDto{}}
+	for _, res := range list {
+		if res.ParentId != consumerId || res.Name != pluginName {
+			continue
+		}
+		credential := KongCredentialDto{}
+		if err = json.Unmarshal(res.Content, &credential); err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		respDto.Data = append(respDto.Data, *respCredential(pluginName, credential))
+	}
+	respDto.Total = int64(len(respDto.Data))
+	return respDto, nil
+}
+
+func respCredential(pluginName string, credential KongCredentialDto) *KongCredentialDto {
+	if pluginName == "hmac-auth" {
+		credential.ToHmacResp()
+	}
+	credential.Compatiable()
+	return &credential
+}
+
+func (impl *AdapterImpl) CreateAclGroup(consumerId string, customId string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if len(consumerId) == 0 || len(customId) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	list, err := impl.Store.List(KindAcl)
+	if err != nil {
+		return err
+	}
+	for _, res := range list {
+		if res.ParentId == consumerId && res.Name == customId {
+			return nil
+		}
+	}
+	group := map[string]string{"group": customId, "consumer_id": consumerId}
+	return impl.save(&Resource{Id: newId(), Kind: KindAcl, Name: customId, ParentId: consumerId}, group, true)
+}
+
+func (impl *AdapterImpl) CreateUpstream(req *KongUpstreamDto) (*KongUpstreamDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if req == nil || req.Name == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	exist, err := impl.GetUpstream(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exist != nil {
+		return nil, errors.Errorf("CreateUpstream failed: upstream[%s] already exists", req.Name)
+	}
+	upstream := *req
+	upstream.Id = newId()
+	err = impl.save(&Resource{Id: upstream.Id, Kind: KindUpstream, Name: upstream.Name}, upstream, true)
+	if err != nil {
+		return nil, err
+	}
+	return &upstream, nil
+}
+
+// GetUpstream gets the upstream by id or name, returns nil if not exist
+func (impl *AdapterImpl) GetUpstream(upstreamId string) (*KongUpstreamDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if upstreamId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.findUpstream(upstreamId)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	upstream := &KongUpstreamDto{}
+	if err = json.Unmarshal(res.Content, upstream); err != nil {
+		return nil, errors.Wrap(err, ERR_JSON_FAIL)
+	}
+	return upstream, nil
+}
+
+func (impl *AdapterImpl) findUpstream(idOrName string) (*Resource, error) {
+	res, err := impl.Store.Get(KindUpstream, idOrName)
+	if err != nil || res != nil {
+		return res, err
+	}
+	list, err := impl.Store.List(KindUpstream)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Name == idOrName {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteUpstream deletes the upstream and its targets by id or name
+func (impl *AdapterImpl) DeleteUpstream(upstreamId string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if upstreamId == "" {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.findUpstream(upstreamId)
+	if err != nil || res == nil {
+		return err
+	}
+	if err = impl.deleteChildren(KindTarget, res.Id); err != nil {
+		return err
+	}
+	return impl.Store.Delete(KindUpstream, res.Id)
+}
+
+func (impl *AdapterImpl) GetUpstreamStatus(upstreamId string) (*KongUpstreamStatusRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if upstreamId == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.findUpstream(upstreamId)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("GetUpstreamStatus failed: upstream[%s] not found", upstreamId)
+	}
+	targets, err := impl.targets(res.Id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range targets {
+		targets[i].Health = "HEALTHCHECKS_OFF"
+	}
+	return &KongUpstreamStatusRespDto{Data: targets}, nil
+}
+
+func (impl *AdapterImpl) AddUpstreamTarget(upstreamId string, req *KongTargetDto) (*KongTargetDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	if upstreamId == "" || req == nil || req.Target == "" {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.findUpstream(upstreamId)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.Errorf("AddUpstreamTarget failed: upstream[%s] not found", upstreamId)
+	}
+	target := *req
+	target.Id = newId()
+	target.UpstreamId = res.Id
+	target.CreatedAt = time.Now().Unix()
+	if target.Weight == 0 {
+		target.Weight = 100
+	}
+	// like kong, a later target entry overrides the former one of the same address
+	exists, err := impl.targets(res.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, exist := range exists {
+		if exist.Target == target.Target {
+			if err = impl.Store.Delete(KindTarget, exist.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	err = impl.save(&Resource{Id: target.Id, Kind: KindTarget, Name: target.Target, ParentId: res.Id}, target, true)
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+func (impl *AdapterImpl) DeleteUpstreamTarget(upstreamId, targetId string) error {
+	if !impl.KongExist() {
+		return errDetached
+	}
+	if upstreamId == "" || targetId == "" {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	res, err := impl.findUpstream(upstreamId)
+	if err != nil || res == nil {
+		return err
+	}
+	targets, err := impl.targets(res.Id)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if target.Id == targetId || target.Target == targetId {
+			if err = impl.Store.Delete(KindTarget, target.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (impl *AdapterImpl) GetRoutes() ([]KongRouteRespDto, error) {
+	return impl.GetRoutesWithTag("")
+}
+
+func (impl *AdapterImpl) GetRoutesWithTag(tag string) ([]KongRouteRespDto, error) {
+	if !impl.KongExist() {
+		return nil, errDetached
+	}
+	list, err := impl.Store.List(KindRoute)
+	if err != nil {
+		return nil, err
+	}
+	var routes []KongRouteRespDto
+	for _, res := range list {
+		if tag != "" && !hasTag(res.Tags, tag) {
+			continue
+		}
+		route := routeObj{}
+		if err = json.Unmarshal(res.Content, &route); err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		routes = append(routes, route.KongRouteRespDto)
+	}
+	return routes, nil
+}
+
+func (impl *AdapterImpl) load(kind, id string, v interface{}) (*Resource, error) {
+	res, err := impl.Store.Get(kind, id)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(res.Content, v); err != nil {
+		return nil, errors.Wrap(err, ERR_JSON_FAIL)
+	}
+	return res, nil
+}
+
+func (impl *AdapterImpl) save(res *Resource, v interface{}, create bool) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, ERR_JSON_FAIL)
+	}
+	res.Content = content
+	return impl.Store.Save(res, create)
+}
+
+func (impl *AdapterImpl) plugins() ([]KongPluginRespDto, error) {
+	list, err := impl.Store.List(KindPlugin)
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]KongPluginRespDto, 0, len(list))
+	for _, res := range list {
+		plugin := KongPluginRespDto{}
+		if err = json.Unmarshal(res.Content, &plugin); err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+func (impl *AdapterImpl) deletePlugins(match func(*KongPluginRespDto) bool) error {
+	plugins, err := impl.plugins()
+	if err != nil {
+		return err
+	}
+	for i := range plugins {
+		if match(&plugins[i]) {
+			if err = impl.Store.Delete(KindPlugin, plugins[i].Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (impl *AdapterImpl) targets(upstreamId string) ([]KongTargetDto, error) {
+	list, err := impl.Store.List(KindTarget)
+	if err != nil {
+		return nil, err
+	}
+	var targets []KongTargetDto
+	for _, res := range list {
+		if res.ParentId != upstreamId {
+			continue
+		}
+		target := KongTargetDto{}
+		if err = json.Unmarshal(res.Content, &target); err != nil {
+			return nil, errors.Wrap(err, ERR_JSON_FAIL)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func (impl *AdapterImpl) deleteChildren(kind, parentId string) error {
+	list, err := impl.Store.List(kind)
+	if err != nil {
+		return err
+	}
+	for _, res := range list {
+		if res.ParentId == parentId {
+			if err = impl.Store.Delete(kind, res.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pluginScope accepts both the kong 0.x id fields and the 2.x object fields
+func pluginScope(req *KongPluginReqDto) (routeId, serviceId, consumerId string) {
+	routeId, serviceId, consumerId = req.RouteId, req.ServiceId, req.ConsumerId
+	if req.Route != nil {
+		routeId = req.Route.Id
+	}
+	if req.Service != nil {
+		serviceId = req.Service.Id
+	}
+	if req.Consumer != nil {
+		consumerId = req.Consumer.Id
+	}
+	return
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() string {
+	return strings.Replace(uuid.New().String(), "-", "", -1)
+}
+
+func randomKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return newId()
+	}
+	return hex.EncodeToString(b)
+}
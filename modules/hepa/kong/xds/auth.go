@@ -0,0 +1,54 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TokenMetadataKey is the node metadata field carrying the cluster token,
+// envoy can't add headers to REST xDS requests, so it is set in the bootstrap
+const TokenMetadataKey = "hepa_token"
+
+// ClusterToken derives the token of a cluster from the xds secret
+func ClusterToken(secret, cluster string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(cluster))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorized admits a request of the cluster if it presents a verified
+// client certificate issued to the cluster, or the token of the cluster
+func authorized(r *http.Request, secret, cluster, token string) bool {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 &&
+		r.TLS.VerifiedChains[0][0].Subject.CommonName == cluster {
+		return true
+	}
+	if secret == "" || cluster == "" {
+		return false
+	}
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(ClusterToken(secret, cluster))) == 1
+}
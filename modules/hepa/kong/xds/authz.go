@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AuthzServer serves the ext_authz checks of envoy at AuthzPathPrefix, it
+// resolves the key-auth key of a request to its consumer, so the keys are
+// kept in hepa instead of the served configuration
+type AuthzServer struct {
+	Options  Options
+	NewStore func(cluster string) (Store, error)
+
+	lock sync.Mutex
+	keys map[string]*clusterKeys
+}
+
+type clusterKeys struct {
+	expire    time.Time
+	names     []string
+	consumers map[string]string
+}
+
+func NewAuthzServer(opts Options) *AuthzServer {
+	return &AuthzServer{
+		Options:  opts,
+		NewStore: NewDBStore,
+		keys:     make(map[string]*clusterKeys),
+	}
+}
+
+func (s *AuthzServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := strings.TrimPrefix(r.URL.Path, AuthzPathPrefix)
+	if i := strings.Index(cluster, "/"); i >= 0 {
+		cluster = cluster[:i]
+	}
+	if !authorized(r, s.Options.Secret, cluster, "") {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	keys, err := s.load(cluster)
+	if err != nil {
+		log.Errorf("load keys of cluster %s failed, err:%+v", cluster, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	for _, name := range keys.names {
+		key := r.Header.Get(name)
+		if key == "" {
+			for arg, values := range query {
+				if strings.EqualFold(arg, name) && len(values) > 0 {
+					key = values[0]
+					break
+				}
+			}
+		}
+		if key == "" {
+			continue
+		}
+		consumer, ok := keys.consumers[key]
+		if !ok {
+			http.Error(w, "Invalid authentication credentials", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set(ConsumerHeader, consumer+":"+name)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Error(w, "No API key found in request", http.StatusUnauthorized)
+}
+
+// load caches the keys of a cluster as long as envoy waits between config refreshes
+func (s *AuthzServer) load(cluster string) (*clusterKeys, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if keys, ok := s.keys[cluster]; ok && time.Now().Before(keys.expire) {
+		return keys, nil
+	}
+	store, err := s.NewStore(cluster)
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState(store)
+	if err != nil {
+		return nil, err
+	}
+	refreshDelay := s.Options.RefreshDelay
+	if refreshDelay <= 0 {
+		refreshDelay = 5
+	}
+	keys := &clusterKeys{
+		expire:    time.Now().Add(time.Duration(refreshDelay) * time.Second),
+		names:     st.keyNames(),
+		consumers: make(map[string]string),
+	}
+	for consumer, list := range st.keys {
+		for _, key := range list {
+			keys.consumers[key] = consumer
+		}
+	}
+	s.keys[cluster] = keys
+	return keys, nil
+}
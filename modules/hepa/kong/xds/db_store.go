@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+	"github.com/erda-project/erda/modules/hepa/repository/service"
+)
+
+type dbStore struct {
+	cluster string
+	db      service.GatewayXdsResourceService
+}
+
+// NewDBStore returns a store backed by erda_gateway_xds_resource
+func NewDBStore(cluster string) (Store, error) {
+	db, err := service.NewGatewayXdsResourceServiceImpl()
+	if err != nil {
+		return nil, err
+	}
+	return &dbStore{cluster: cluster, db: db}, nil
+}
+
+func (s *dbStore) Get(kind, id string) (*Resource, error) {
+	dao, err := s.db.Get(s.cluster, kind, id)
+	if err != nil || dao == nil {
+		return nil, err
+	}
+	return fromDao(dao), nil
+}
+
+func (s *dbStore) List(kind string) ([]Resource, error) {
+	daos, err := s.db.SelectByKind(s.cluster, kind)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Resource, 0, len(daos))
+	for i := range daos {
+		res = append(res, *fromDao(&daos[i]))
+	}
+	return res, nil
+}
+
+func (s *dbStore) Save(res *Resource, create bool) error {
+	dao := &orm.GatewayXdsResource{
+		Id:       res.Id,
+		Cluster:  s.cluster,
+		Kind:     res.Kind,
+		Name:     res.Name,
+		ParentId: res.ParentId,
+		Tags:     strings.Join(res.Tags, ","),
+		Content:  string(res.Content),
+	}
+	if create {
+		return s.db.Insert(dao)
+	}
+	return s.db.Update(dao)
+}
+
+func (s *dbStore) Delete(kind, id string) error {
+	return s.db.Delete(s.cluster, kind, id)
+}
+
+func fromDao(dao *orm.GatewayXdsResource) *Resource {
+	res := &Resource{
+		Id:       dao.Id,
+		Kind:     dao.Kind,
+		Name:     dao.Name,
+		ParentId: dao.ParentId,
+		Content:  []byte(dao.Content),
+	}
+	if dao.Tags != "" {
+		res.Tags = strings.Split(dao.Tags, ",")
+	}
+	return res
+}
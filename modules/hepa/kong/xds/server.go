@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var discoveryTypes = map[string]string{
+	"discovery:listeners": TypeListener,
+	"discovery:routes":    TypeRoute,
+	"discovery:clusters":  TypeCluster,
+}
+
+type discoveryNode struct {
+	Id       string                 `json:"id"`
+	Cluster  string                 `json:"cluster"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type discoveryRequest struct {
+	VersionInfo   string        `json:"version_info"`
+	Node          discoveryNode `json:"node"`
+	ResourceNames []string      `json:"resource_names"`
+	TypeUrl       string        `json:"type_url"`
+}
+
+type discoveryResponse struct {
+	VersionInfo string        `json:"version_info"`
+	Resources   []interface{} `json:"resources"`
+	TypeUrl     string        `json:"type_url"`
+}
+
+// DiscoveryServer serves the REST-JSON xDS api at /v3/discovery:{listeners,routes,clusters},
+// envoy is expected to set --service-cluster to the name of the cluster it proxies,
+// and to authenticate with a client certificate issued to that cluster or with
+// the cluster token in node metadata
+type DiscoveryServer struct {
+	Options  Options
+	NewStore func(cluster string) (Store, error)
+}
+
+func NewDiscoveryServer(opts Options) *DiscoveryServer {
+	return &DiscoveryServer{
+		Options:  opts,
+		NewStore: NewDBStore,
+	}
+}
+
+func (s *DiscoveryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	typeUrl, ok := discoveryTypes[path.Base(r.URL.Path)]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	req := &discoveryRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "invalid discovery request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Node.Cluster == "" {
+		http.Error(w, "node.cluster is required", http.StatusBadRequest)
+		return
+	}
+	token, _ := req.Node.Metadata[TokenMetadataKey].(string)
+	if !authorized(r, s.Options.Secret, req.Node.Cluster, token) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	resp, err := s.discover(req.Node.Cluster, typeUrl, req.ResourceNames)
+	if err != nil {
+		log.Errorf("xds discovery failed, node:%+v type:%s err:%+v", req.Node, typeUrl, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.VersionInfo == resp.VersionInfo {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("write discovery response failed, err:%+v", err)
+	}
+}
+
+func (s *DiscoveryServer) discover(cluster, typeUrl string, names []string) (*discoveryResponse, error) {
+	store, err := s.NewStore(cluster)
+	if err != nil {
+		return nil, err
+	}
+	opts := s.Options
+	opts.Cluster = cluster
+	snap, err := BuildSnapshot(store, opts)
+	if err != nil {
+		return nil, err
+	}
+	var resources []interface{}
+	switch typeUrl {
+	case TypeListener:
+		resources = snap.Listeners
+	case TypeRoute:
+		resources = snap.Routes
+	case TypeCluster:
+		resources = snap.Clusters
+	}
+	resources = filterByName(resources, names)
+	content, err := json.Marshal(resources)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	return &discoveryResponse{
+		VersionInfo: hex.EncodeToString(sum[:8]),
+		Resources:   nonNil(resources),
+		TypeUrl:     typeUrl,
+	}, nil
+}
+
+func filterByName(resources []interface{}, names []string) []interface{} {
+	if len(names) == 0 {
+		return resources
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var result []interface{}
+	for _, res := range resources {
+		obj, ok := res.(object)
+		if !ok {
+			continue
+		}
+		if name, _ := obj["name"].(string); wanted[name] {
+			result = append(result, res)
+		}
+	}
+	return result
+}
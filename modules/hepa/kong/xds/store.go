@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	KindService    = "service"
+	KindRoute      = "route"
+	KindPlugin     = "plugin"
+	KindConsumer   = "consumer"
+	KindCredential = "credential"
+	KindAcl        = "acl"
+	KindUpstream   = "upstream"
+	KindTarget     = "target"
+)
+
+// Resource is a gateway object owned by the xDS control plane, Content holds
+// the json encoded kong dto so the adapter keeps the same contract as Kong
+type Resource struct {
+	Id       string
+	Kind     string
+	Name     string
+	ParentId string
+	Tags     []string
+	Content  []byte
+}
+
+// Store persists the resources of one cluster
+type Store interface {
+	Get(kind, id string) (*Resource, error)
+	List(kind string) ([]Resource, error)
+	Save(res *Resource, create bool) error
+	Delete(kind, id string) error
+}
+
+type memStore struct {
+	sync.RWMutex
+	seq       int
+	resources map[string]memResource
+}
+
+type memResource struct {
+	Resource
+	seq int
+}
+
+// NewMemoryStore returns a store kept in process memory, used for tests and debugging
+func NewMemoryStore() Store {
+	return &memStore{resources: make(map[string]memResource)}
+}
+
+func (s *memStore) Get(kind, id string) (*Resource, error) {
+	s.RLock()
+	defer s.RUnlock()
+	res, ok := s.resources[kind+"/"+id]
+	if !ok {
+		return nil, nil
+	}
+	copied := res.Resource
+	return &copied, nil
+}
+
+func (s *memStore) List(kind string) ([]Resource, error) {
+	s.RLock()
+	defer s.RUnlock()
+	var items []memResource
+	for _, res := range s.resources {
+		if res.Kind == kind {
+			items = append(items, res)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+	list := make([]Resource, 0, len(items))
+	for _, item := range items {
+		list = append(list, item.Resource)
+	}
+	return list, nil
+}
+
+func (s *memStore) Save(res *Resource, create bool) error {
+	s.Lock()
+	defer s.Unlock()
+	key := res.Kind + "/" + res.Id
+	exist, ok := s.resources[key]
+	if create == ok {
+		return errors.Errorf("save %s failed, exist:%v", key, ok)
+	}
+	seq := exist.seq
+	if create {
+		s.seq++
+		seq = s.seq
+	}
+	s.resources[key] = memResource{Resource: *res, seq: seq}
+	return nil
+}
+
+func (s *memStore) Delete(kind, id string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.resources, kind+"/"+id)
+	return nil
+}
@@ -0,0 +1,865 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	. "github.com/erda-project/erda/modules/hepa/kong/dto"
+)
+
+const (
+	ListenerName    = "hepa_http"
+	RouteConfigName = "hepa_routes"
+	ClusterPrefix   = "hepa_service_"
+
+	TypeListener = "type.googleapis.com/envoy.config.listener.v3.Listener"
+	TypeRoute    = "type.googleapis.com/envoy.config.route.v3.RouteConfiguration"
+	TypeCluster  = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+
+	// AuthzPathPrefix is where envoy checks the key-auth keys, followed by the cluster name
+	AuthzPathPrefix = "/xds/authz/"
+	// ConsumerHeader carries the consumer and the key name resolved by the key check
+	ConsumerHeader = "x-hepa-consumer"
+
+	filterCors      = "envoy.filters.http.cors"
+	filterExtAuthz  = "envoy.filters.http.ext_authz"
+	filterRbac      = "envoy.filters.http.rbac"
+	filterRateLimit = "envoy.filters.http.local_ratelimit"
+	filterRouter    = "envoy.filters.http.router"
+
+	defaultTargetPort     = 8000
+	defaultConnectTimeout = 60000
+	authzTimeout          = 1000
+)
+
+var defaultKeyNames = []string{"apikey"}
+
+var plainPath = regexp.MustCompile(`^[a-zA-Z0-9\.\-_~/%]*$`)
+
+type object = map[string]interface{}
+
+// Options describes how envoy reaches the proxy listener and hepa itself,
+// Secret derives the token each cluster authenticates with
+type Options struct {
+	ProxyPort     int
+	ConfigCluster string
+	RefreshDelay  int
+	Secret        string
+	Cluster       string
+}
+
+// Snapshot is the envoy configuration of one cluster, each resource carries its @type
+type Snapshot struct {
+	Listeners []interface{}
+	Routes    []interface{}
+	Clusters  []interface{}
+}
+
+type state struct {
+	services    []serviceObj
+	routes      []routeObj
+	plugins     []KongPluginRespDto
+	enables     map[string]bool
+	consumers   []consumerObj
+	keys        map[string][]string
+	groups      map[string][]string
+	upstreams   map[string]KongUpstreamDto
+	targets     map[string][]KongTargetDto
+	serviceById map[string]*serviceObj
+}
+
+// BuildSnapshot translates the stored kong objects into envoy listeners,
+// route configurations and clusters
+func BuildSnapshot(store Store, opts Options) (*Snapshot, error) {
+	st, err := loadState(store)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{
+		Listeners: []interface{}{st.listener(opts)},
+		Routes:    []interface{}{st.routeConfiguration()},
+	}
+	for i := range st.services {
+		snap.Clusters = append(snap.Clusters, st.cluster(&st.services[i]))
+	}
+	return snap, nil
+}
+
+func loadState(store Store) (*state, error) {
+	st := &state{
+		keys:        make(map[string][]string),
+		groups:      make(map[string][]string),
+		upstreams:   make(map[string]KongUpstreamDto),
+		targets:     make(map[string][]KongTargetDto),
+		serviceById: make(map[string]*serviceObj),
+	}
+	decode := func(kind string, fn func(res *Resource) (interface{}, error)) error {
+		list, err := store.List(kind)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			v, err := fn(&list[i])
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				continue
+			}
+			if err = json.Unmarshal(list[i].Content, v); err != nil {
+				return errors.Wrapf(err, "decode %s[%s] failed", kind, list[i].Id)
+			}
+		}
+		return nil
+	}
+	err := decode(KindService, func(*Resource) (interface{}, error) {
+		st.services = append(st.services, serviceObj{})
+		return &st.services[len(st.services)-1], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range st.services {
+		st.serviceById[st.services[i].Id] = &st.services[i]
+	}
+	err = decode(KindRoute, func(*Resource) (interface{}, error) {
+		st.routes = append(st.routes, routeObj{})
+		return &st.routes[len(st.routes)-1], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = decode(KindPlugin, func(*Resource) (interface{}, error) {
+		st.plugins = append(st.plugins, KongPluginRespDto{})
+		return &st.plugins[len(st.plugins)-1], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, plugin := range st.plugins {
+		if plugin.Name == DomainPolicy && plugin.RouteId == "" && plugin.ServiceId == "" {
+			st.enables = policyEnables(plugin.Config)
+		}
+	}
+	err = decode(KindConsumer, func(*Resource) (interface{}, error) {
+		st.consumers = append(st.consumers, consumerObj{})
+		return &st.consumers[len(st.consumers)-1], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = decode(KindCredential, func(res *Resource) (interface{}, error) {
+		if res.Name != "key-auth" {
+			return nil, nil
+		}
+		credential := KongCredentialDto{}
+		if err := json.Unmarshal(res.Content, &credential); err != nil {
+			return nil, errors.Wrapf(err, "decode credential[%s] failed", res.Id)
+		}
+		st.keys[res.ParentId] = append(st.keys[res.ParentId], credential.Key)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = decode(KindAcl, func(res *Resource) (interface{}, error) {
+		st.groups[res.ParentId] = append(st.groups[res.ParentId], res.Name)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = decode(KindUpstream, func(res *Resource) (interface{}, error) {
+		upstream := KongUpstreamDto{}
+		if err := json.Unmarshal(res.Content, &upstream); err != nil {
+			return nil, errors.Wrapf(err, "decode upstream[%s] failed", res.Id)
+		}
+		st.upstreams[upstream.Name] = upstream
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = decode(KindTarget, func(res *Resource) (interface{}, error) {
+		target := KongTargetDto{}
+		if err := json.Unmarshal(res.Content, &target); err != nil {
+			return nil, errors.Wrapf(err, "decode target[%s] failed", res.Id)
+		}
+		st.targets[res.ParentId] = append(st.targets[res.ParentId], target)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (st *state) listener(opts Options) object {
+	refreshDelay := opts.RefreshDelay
+	if refreshDelay <= 0 {
+		refreshDelay = 5
+	}
+	return object{
+		"@type": TypeListener,
+		"name":  ListenerName,
+		"address": object{"socket_address": object{
+			"address":    "0.0.0.0",
+			"port_value": opts.ProxyPort,
+		}},
+		"filter_chains": []interface{}{object{
+			"filters": []interface{}{object{
+				"name": "envoy.filters.network.http_connection_manager",
+				"typed_config": object{
+					"@type":       "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+					"stat_prefix": ListenerName,
+					"rds": object{
+						"route_config_name": RouteConfigName,
+						"config_source": object{
+							"resource_api_version": "V3",
+							"api_config_source": object{
+								"api_type":              "REST",
+								"transport_api_version": "V3",
+								"cluster_names":         []string{opts.ConfigCluster},
+								"refresh_delay":         duration(refreshDelay * 1000),
+							},
+						},
+					},
+					"http_filters": []interface{}{
+						httpFilter(filterCors, "type.googleapis.com/envoy.extensions.filters.http.cors.v3.Cors", nil),
+						httpFilter(filterExtAuthz, "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz",
+							st.extAuthz(opts)),
+						httpFilter(filterRbac, "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC", nil),
+						httpFilter(filterRateLimit, "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+							object{"stat_prefix": "hepa_rate_limit"}),
+						httpFilter(filterRouter, "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router", nil),
+					},
+				},
+			}},
+		}},
+	}
+}
+
+// extAuthz asks hepa to resolve the key-auth key of a request, so the keys
+// never leave hepa, the resolved consumer is matched by the rbac filter
+func (st *state) extAuthz(opts Options) object {
+	var patterns []interface{}
+	for _, name := range st.keyNames() {
+		patterns = append(patterns, object{"exact": name, "ignore_case": true})
+	}
+	request := object{"allowed_headers": object{"patterns": nonNil(patterns)}}
+	if opts.Secret != "" {
+		request["headers_to_add"] = []interface{}{
+			object{"key": "authorization", "value": "Bearer " + ClusterToken(opts.Secret, opts.Cluster)},
+		}
+	}
+	return object{
+		"transport_api_version": "V3",
+		"http_service": object{
+			"server_uri": object{
+				"uri":     "http://" + opts.ConfigCluster,
+				"cluster": opts.ConfigCluster,
+				"timeout": duration(authzTimeout),
+			},
+			"path_prefix":           AuthzPathPrefix + url.PathEscape(opts.Cluster),
+			"authorization_request": request,
+			"authorization_response": object{
+				"allowed_upstream_headers": object{"patterns": []interface{}{object{"exact": ConsumerHeader}}},
+			},
+		},
+	}
+}
+
+// keyNames lists the key names of all key-auth plugins, in lower case
+func (st *state) keyNames() []string {
+	set := make(map[string]bool)
+	for _, plugin := range st.plugins {
+		if plugin.Name != "key-auth" || !st.active(&plugin) {
+			continue
+		}
+		names := stringList(plugin.Config["key_names"])
+		if len(names) == 0 {
+			names = defaultKeyNames
+		}
+		for _, name := range names {
+			set[strings.ToLower(name)] = true
+		}
+	}
+	var result []string
+	for name := range set {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func httpFilter(name, typ string, config object) object {
+	typed := object{"@type": typ}
+	for k, v := range config {
+		typed[k] = v
+	}
+	return object{"name": name, "typed_config": typed}
+}
+
+func (st *state) cluster(svc *serviceObj) object {
+	connectTimeout := svc.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	var endpoints []interface{}
+	upstream, isUpstream := st.upstreams[svc.Host]
+	if isUpstream {
+		for _, target := range st.targets[upstream.Id] {
+			if target.Weight <= 0 {
+				continue
+			}
+			host, port := splitTarget(target.Target)
+			endpoints = append(endpoints, object{
+				"endpoint":              object{"address": socketAddress(host, port)},
+				"load_balancing_weight": target.Weight,
+			})
+		}
+	} else {
+		endpoints = append(endpoints, object{
+			"endpoint": object{"address": socketAddress(svc.Host, svc.Port)},
+		})
+	}
+	name := ClusterName(svc.Id)
+	cluster := object{
+		"@type":             TypeCluster,
+		"name":              name,
+		"type":              "STRICT_DNS",
+		"dns_lookup_family": "V4_ONLY",
+		"connect_timeout":   duration(connectTimeout),
+		"lb_policy":         "ROUND_ROBIN",
+		"load_assignment": object{
+			"cluster_name": name,
+			"endpoints":    []interface{}{object{"lb_endpoints": nonNil(endpoints)}},
+		},
+	}
+	if svc.Protocol == "https" {
+		cluster["transport_socket"] = object{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": object{
+				"@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
+				"sni":   svc.Host,
+			},
+		}
+	}
+	if isUpstream {
+		if check := healthCheck(upstream.Healthchecks.Active); check != nil {
+			cluster["health_checks"] = []interface{}{check}
+		}
+	}
+	return cluster
+}
+
+func healthCheck(active ActiveHealthcheckDto) object {
+	if active.HttpPath == "" || active.Healthy.Interval <= 0 {
+		return nil
+	}
+	timeout := active.Timeout
+	if timeout <= 0 {
+		timeout = 1
+	}
+	return object{
+		"timeout":             duration(timeout * 1000),
+		"interval":            duration(active.Healthy.Interval * 1000),
+		"healthy_threshold":   atLeastOne(active.Healthy.Successes),
+		"unhealthy_threshold": atLeastOne(active.Unhealthy.HttpFailures),
+		"http_health_check":   object{"path": active.HttpPath},
+	}
+}
+
+// ClusterName is the envoy cluster of a kong service
+func ClusterName(serviceId string) string {
+	return ClusterPrefix + serviceId
+}
+
+func (st *state) routeConfiguration() object {
+	hostRoutes := make(map[string][]*routeObj)
+	var anyHost []*routeObj
+	for i := range st.routes {
+		route := &st.routes[i]
+		if _, ok := st.serviceById[route.Service.Id]; !ok {
+			continue
+		}
+		if len(route.Hosts) == 0 {
+			anyHost = append(anyHost, route)
+			continue
+		}
+		for _, host := range route.Hosts {
+			host = strings.ToLower(host)
+			hostRoutes[host] = append(hostRoutes[host], route)
+		}
+	}
+	var hosts []string
+	for host := range hostRoutes {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var virtualHosts []interface{}
+	for _, host := range hosts {
+		// routes without hosts match any host in kong, so they are tried after the host specific ones
+		virtualHosts = append(virtualHosts, object{
+			"name":    host,
+			"domains": []string{host},
+			"routes":  st.envoyRoutes(hostRoutes[host], anyHost),
+		})
+	}
+	virtualHosts = append(virtualHosts, object{
+		"name":    "any",
+		"domains": []string{"*"},
+		"routes":  st.envoyRoutes(anyHost),
+	})
+	return object{
+		"@type":         TypeRoute,
+		"name":          RouteConfigName,
+		"virtual_hosts": virtualHosts,
+	}
+}
+
+type routeEntry struct {
+	route *routeObj
+	path  string
+	regex bool
+	order int
+}
+
+// envoyRoutes orders the routes the way the kong router evaluates them:
+// more header conditions first, regex paths by regex_priority, then the
+// longest prefix, envoy picks the first matching route
+func (st *state) envoyRoutes(groups ...[]*routeObj) []interface{} {
+	var result []interface{}
+	order := 0
+	for _, group := range groups {
+		var entries []routeEntry
+		for _, route := range group {
+			paths := route.Paths
+			if len(paths) == 0 {
+				paths = []string{"/"}
+			}
+			for _, path := range paths {
+				entries = append(entries, routeEntry{route: route, path: path, regex: isRegexPath(path), order: order})
+				order++
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if len(a.route.Headers) != len(b.route.Headers) {
+				return len(a.route.Headers) > len(b.route.Headers)
+			}
+			if a.regex != b.regex {
+				return a.regex
+			}
+			if a.regex && a.route.RegexPriority != b.route.RegexPriority {
+				return a.route.RegexPriority > b.route.RegexPriority
+			}
+			if !a.regex && len(a.path) != len(b.path) {
+				return len(a.path) > len(b.path)
+			}
+			return a.order < b.order
+		})
+		for _, entry := range entries {
+			result = append(result, st.envoyRoute(entry))
+		}
+	}
+	return nonNil(result)
+}
+
+func (st *state) envoyRoute(entry routeEntry) object {
+	route := entry.route
+	svc := st.serviceById[route.Service.Id]
+	match := object{}
+	if entry.regex {
+		match["safe_regex"] = object{"regex": "(?:" + strings.TrimPrefix(entry.path, "^") + ").*"}
+	} else {
+		match["prefix"] = entry.path
+	}
+	var headers []interface{}
+	if len(route.Methods) > 0 {
+		headers = append(headers, headerRegex(":method", "^(?:"+strings.Join(quoteAll(route.Methods), "|")+")$"))
+	}
+	var names []string
+	for name := range route.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := route.Headers[name]
+		if len(values) == 1 {
+			headers = append(headers, object{"name": name, "string_match": object{"exact": values[0], "ignore_case": true}})
+			continue
+		}
+		headers = append(headers, headerRegex(name, "(?i)^(?:"+strings.Join(quoteAll(values), "|")+")$"))
+	}
+	if len(headers) > 0 {
+		match["headers"] = headers
+	}
+	action := object{"cluster": ClusterName(svc.Id)}
+	if rewrite := pathRewrite(entry, route, svc.Path); rewrite != nil {
+		action["regex_rewrite"] = rewrite
+	}
+	if route.PreserveHost == nil || !*route.PreserveHost {
+		action["host_rewrite_literal"] = svc.Host
+	}
+	if svc.ReadTimeout > 0 {
+		action["timeout"] = duration(svc.ReadTimeout)
+	}
+	if svc.Retries != nil && *svc.Retries > 0 {
+		action["retry_policy"] = object{
+			"retry_on":    "connect-failure,refused-stream,reset",
+			"num_retries": *svc.Retries,
+		}
+	}
+	result := object{
+		"name":  route.Id,
+		"match": match,
+		"route": action,
+	}
+	if filters := st.routeFilters(route); len(filters) > 0 {
+		result["typed_per_filter_config"] = filters
+	}
+	return result
+}
+
+// pathRewrite follows kong's v1 path handling, the service path is the
+// prefix of the upstream path and strip_path removes the matched route path
+func pathRewrite(entry routeEntry, route *routeObj, servicePath string) object {
+	servicePath = strings.TrimSuffix(servicePath, "/")
+	if route.StripPath == nil || *route.StripPath {
+		var pattern string
+		if entry.regex {
+			pattern = "^(?:" + strings.TrimPrefix(entry.path, "^") + ")/?"
+		} else {
+			pattern = "^" + regexp.QuoteMeta(strings.TrimSuffix(entry.path, "/")) + "/?"
+		}
+		return object{"pattern": object{"regex": pattern}, "substitution": servicePath + "/"}
+	}
+	if servicePath == "" {
+		return nil
+	}
+	return object{"pattern": object{"regex": "^"}, "substitution": servicePath}
+}
+
+// active reports whether the plugin runs, a route plugin created disabled runs
+// when the domain policy enables it
+func (st *state) active(plugin *KongPluginRespDto) bool {
+	return plugin.Enabled || plugin.RouteId != "" && st.enables[plugin.Id]
+}
+
+// effectivePlugins resolves the enabled plugins of a route, a route plugin
+// overrides the service one which overrides the global one
+func (st *state) effectivePlugins(route *routeObj) map[string]*KongPluginRespDto {
+	result := make(map[string]*KongPluginRespDto)
+	rank := make(map[string]int)
+	for i := range st.plugins {
+		plugin := &st.plugins[i]
+		if !st.active(plugin) || plugin.ConsumerId != "" {
+			continue
+		}
+		var level int
+		switch {
+		case plugin.RouteId == route.Id:
+			level = 3
+		case plugin.RouteId == "" && plugin.ServiceId == route.Service.Id:
+			level = 2
+		case plugin.RouteId == "" && plugin.ServiceId == "":
+			level = 1
+		default:
+			continue
+		}
+		if level > rank[plugin.Name] {
+			rank[plugin.Name] = level
+			result[plugin.Name] = plugin
+		}
+	}
+	return result
+}
+
+func (st *state) routeFilters(route *routeObj) object {
+	plugins := st.effectivePlugins(route)
+	filters := object{}
+	if plugin, ok := plugins["cors"]; ok {
+		filters[filterCors] = corsPolicy(plugin.Config)
+	}
+	keyAuth, hasKeyAuth := plugins["key-auth"]
+	acl, hasAcl := plugins["acl"]
+	if hasKeyAuth || hasAcl {
+		var keyConfig map[string]interface{}
+		if hasKeyAuth {
+			keyConfig = keyAuth.Config
+		}
+		var aclConfig map[string]interface{}
+		if hasAcl {
+			aclConfig = acl.Config
+		}
+		filters[filterRbac] = st.rbac(hasKeyAuth, keyConfig, hasAcl, aclConfig)
+	}
+	if !hasKeyAuth {
+		filters[filterExtAuthz] = object{
+			"@type":    "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute",
+			"disabled": true,
+		}
+	}
+	if plugin, ok := plugins["rate-limiting"]; ok {
+		if limit := localRateLimit(plugin.Config); limit != nil {
+			filters[filterRateLimit] = limit
+		}
+	}
+	return filters
+}
+
+func corsPolicy(config map[string]interface{}) object {
+	policy := object{"@type": "type.googleapis.com/envoy.extensions.filters.http.cors.v3.CorsPolicy"}
+	origins := stringList(config["origins"])
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	var matchers []interface{}
+	for _, origin := range origins {
+		switch {
+		case origin == "*":
+			matchers = append(matchers, object{"safe_regex": object{"regex": ".*"}})
+		case isRegexPath(strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://")):
+			matchers = append(matchers, object{"safe_regex": object{"regex": origin}})
+		default:
+			matchers = append(matchers, object{"exact": origin})
+		}
+	}
+	policy["allow_origin_string_match"] = matchers
+	if methods := stringList(config["methods"]); len(methods) > 0 {
+		policy["allow_methods"] = strings.Join(methods, ",")
+	}
+	if headers := stringList(config["headers"]); len(headers) > 0 {
+		policy["allow_headers"] = strings.Join(headers, ",")
+	}
+	if headers := stringList(config["exposed_headers"]); len(headers) > 0 {
+		policy["expose_headers"] = strings.Join(headers, ",")
+	}
+	if maxAge, ok := number(config["max_age"]); ok {
+		policy["max_age"] = strconv.FormatInt(maxAge, 10)
+	}
+	if credentials, ok := config["credentials"].(bool); ok {
+		policy["allow_credentials"] = credentials
+	}
+	return policy
+}
+
+// rbac admits the consumers holding a key-auth credential, narrowed by the
+// acl groups, the consumer is the one the ext_authz check resolved from the
+// key names of the route
+func (st *state) rbac(hasKeyAuth bool, keyConfig map[string]interface{}, hasAcl bool, aclConfig map[string]interface{}) object {
+	keyNames := stringList(keyConfig["key_names"])
+	if len(keyNames) == 0 {
+		keyNames = defaultKeyNames
+	}
+	_, restricted := aclConfig["allow"]
+	allow := stringList(aclConfig["allow"])
+	if whitelist, ok := aclConfig["whitelist"].(string); ok {
+		restricted = true
+		allow = append(allow, splitList(whitelist)...)
+	}
+	deny := stringList(aclConfig["deny"])
+	if blacklist, ok := aclConfig["blacklist"].(string); ok {
+		deny = append(deny, splitList(blacklist)...)
+	}
+	policies := object{}
+	for _, consumer := range st.consumers {
+		// without an auth plugin kong can't identify the consumer, the acl rejects everyone
+		if !hasKeyAuth || len(st.keys[consumer.Id]) == 0 {
+			continue
+		}
+		if hasAcl && !aclAllowed(st.groups[consumer.Id], restricted, allow, deny) {
+			continue
+		}
+		var principals []interface{}
+		for _, name := range keyNames {
+			principals = append(principals, object{"header": object{
+				"name":         ConsumerHeader,
+				"string_match": object{"exact": consumer.Id + ":" + strings.ToLower(name)},
+			}})
+		}
+		policies["consumer-"+consumer.Id] = object{
+			"permissions": []interface{}{object{"any": true}},
+			"principals":  principals,
+		}
+	}
+	return object{
+		"@type": "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBACPerRoute",
+		"rbac": object{"rules": object{
+			"action":   "ALLOW",
+			"policies": policies,
+		}},
+	}
+}
+
+func aclAllowed(groups []string, restricted bool, allow, deny []string) bool {
+	contains := func(list []string) bool {
+		for _, group := range groups {
+			for _, item := range list {
+				if group == item {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if len(deny) > 0 && contains(deny) {
+		return false
+	}
+	return !restricted || contains(allow)
+}
+
+// localRateLimit maps the smallest configured kong window to a token bucket,
+// the limit is enforced per envoy instance
+func localRateLimit(config map[string]interface{}) object {
+	windows := []struct {
+		name    string
+		seconds int
+	}{{"second", 1}, {"minute", 60}, {"hour", 3600}, {"day", 86400}}
+	for _, window := range windows {
+		limit, ok := number(config[window.name])
+		if !ok || limit <= 0 {
+			continue
+		}
+		percent := object{"runtime_key": "hepa_rate_limit_enabled", "default_value": object{"numerator": 100, "denominator": "HUNDRED"}}
+		return object{
+			"@type":       "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+			"stat_prefix": "hepa_rate_limit",
+			"token_bucket": object{
+				"max_tokens":      limit,
+				"tokens_per_fill": limit,
+				"fill_interval":   duration(window.seconds * 1000),
+			},
+			"filter_enabled":  percent,
+			"filter_enforced": percent,
+		}
+	}
+	return nil
+}
+
+func isRegexPath(path string) bool {
+	return !plainPath.MatchString(path)
+}
+
+func headerRegex(name, regex string) object {
+	return object{"name": name, "string_match": object{"safe_regex": object{"regex": regex}}}
+}
+
+func socketAddress(host string, port int) object {
+	return object{"socket_address": object{"address": host, "port_value": port}}
+}
+
+func splitTarget(target string) (string, int) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return target, defaultTargetPort
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return host, defaultTargetPort
+	}
+	return host, p
+}
+
+// duration formats milliseconds as a protobuf json duration
+func duration(ms int) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64) + "s"
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, regexp.QuoteMeta(v))
+	}
+	return quoted
+}
+
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var result []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case string:
+		return splitList(list)
+	}
+	return nil
+}
+
+// policyEnables returns the ids of the plugins enabled by the domain policy
+func policyEnables(config map[string]interface{}) map[string]bool {
+	enables := make(map[string]bool)
+	for _, list := range stringList(config["enables"]) {
+		for _, id := range splitList(list) {
+			enables[id] = true
+		}
+	}
+	return enables
+}
+
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func number(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func nonNil(list []interface{}) []interface{} {
+	if list == nil {
+		return []interface{}{}
+	}
+	return list
+}
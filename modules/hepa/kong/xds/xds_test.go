@@ -0,0 +1,336 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/modules/hepa/kong/dto"
+	"github.com/erda-project/erda/modules/hepa/kong/xds"
+)
+
+func newAdapter(t *testing.T) (*xds.AdapterImpl, string) {
+	adapter := &xds.AdapterImpl{Cluster: "test", Store: xds.NewMemoryStore()}
+	retries := 2
+	svc, err := adapter.CreateOrUpdateService(&dto.KongServiceReqDto{
+		Url:         "http://user-svc.default.svc.cluster.local:8080/v1",
+		Retries:     &retries,
+		ReadTimeout: 30000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Host != "user-svc.default.svc.cluster.local" || svc.Port != 8080 || svc.Path != "/v1" {
+		t.Fatalf("unexpected service: %+v", svc)
+	}
+	return adapter, svc.Id
+}
+
+func snapshotJson(t *testing.T, adapter *xds.AdapterImpl) string {
+	snap, err := xds.BuildSnapshot(adapter.Store, xds.Options{ProxyPort: 8000, ConfigCluster: "hepa-xds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestAdapter_Route(t *testing.T) {
+	adapter, serviceId := newAdapter(t)
+	version, _ := adapter.GetVersion()
+	if !strings.HasPrefix(version, "2.") {
+		t.Fatalf("version should be kong 2.x compatible: %s", version)
+	}
+	req := dto.NewKongRouteReqDto()
+	req.Hosts = []string{"api.example.com"}
+	req.Paths = []string{"/users"}
+	req.Methods = []string{"GET"}
+	req.Service = &dto.Service{Id: serviceId}
+	req.AddTag("package_id", "p1")
+	route, err := adapter.CreateOrUpdateRoute(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := adapter.GetRoutesWithTag("package_id~p1")
+	if err != nil || len(routes) != 1 || routes[0].Id != route.Id {
+		t.Fatalf("get routes with tag failed: %+v, %v", routes, err)
+	}
+	if _, err = adapter.UpdateRoute(&dto.KongRouteReqDto{RouteId: route.Id, Paths: []string{"/members"}}); err != nil {
+		t.Fatal(err)
+	}
+	routes, _ = adapter.GetRoutes()
+	if len(routes) != 1 || routes[0].Paths[0] != "/members" || routes[0].Hosts[0] != "api.example.com" {
+		t.Fatalf("update route failed: %+v", routes)
+	}
+	invalid := dto.NewKongRouteReqDto()
+	invalid.Paths = []string{"/("}
+	invalid.Service = &dto.Service{Id: serviceId}
+	if _, err = adapter.CreateOrUpdateRoute(invalid); err != xds.ErrInvalidReq {
+		t.Fatalf("invalid regex path should be rejected: %v", err)
+	}
+	if err = adapter.DeleteService(serviceId); err == nil {
+		t.Fatal("service referenced by route should not be deleted")
+	}
+
+	content := snapshotJson(t, adapter)
+	for _, expect := range []string{
+		`"domains":["api.example.com"]`,
+		`"prefix":"/members"`,
+		`"regex":"^(?:GET)$"`,
+		`"substitution":"/v1/"`,
+		`"host_rewrite_literal":"user-svc.default.svc.cluster.local"`,
+		`"timeout":"30s"`,
+		`"num_retries":2`,
+		`"cluster":"hepa_service_` + serviceId + `"`,
+		`"port_value":8080`,
+		`"cluster_names":["hepa-xds"]`,
+	} {
+		if !strings.Contains(content, expect) {
+			t.Errorf("snapshot should contain %s: %s", expect, content)
+		}
+	}
+}
+
+func TestAdapter_Upstream(t *testing.T) {
+	adapter := &xds.AdapterImpl{Cluster: "test", Store: xds.NewMemoryStore()}
+	upstream, err := adapter.CreateUpstream(&dto.KongUpstreamDto{Name: "user.upstream", Healthchecks: dto.NewHealthchecks("/health")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adapter.AddUpstreamTarget("user.upstream", &dto.KongTargetDto{Target: "10.0.0.1:8080", Weight: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adapter.AddUpstreamTarget(upstream.Id, &dto.KongTargetDto{Target: "10.0.0.2:8080", Weight: 90}); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := adapter.CreateOrUpdateService(&dto.KongServiceReqDto{Host: "user.upstream"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := snapshotJson(t, adapter)
+	for _, expect := range []string{`"address":"10.0.0.1"`, `"load_balancing_weight":90`, `"path":"/health"`, `"name":"hepa_service_` + svc.Id + `"`} {
+		if !strings.Contains(content, expect) {
+			t.Errorf("snapshot should contain %s: %s", expect, content)
+		}
+	}
+	if err = adapter.DeleteUpstream("user.upstream"); err != nil {
+		t.Fatal(err)
+	}
+	if exist, _ := adapter.GetUpstream(upstream.Id); exist != nil {
+		t.Fatal("upstream should be deleted")
+	}
+	if list, _ := adapter.Store.List(xds.KindTarget); len(list) != 0 {
+		t.Fatalf("targets should be deleted with upstream: %d", len(list))
+	}
+}
+
+func TestAdapter_KeyAuthAcl(t *testing.T) {
+	adapter, serviceId := newAdapter(t)
+	req := dto.NewKongRouteReqDto()
+	req.Paths = []string{"/api/(.*)/detail"}
+	req.Service = &dto.Service{Id: serviceId}
+	route, err := adapter.CreateOrUpdateRoute(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed, err := adapter.CreateConsumer(dto.NewKongConsumerReqDto("allowed", "group-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied, err := adapter.CreateConsumer(dto.NewKongConsumerReqDto("denied", "group-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adapter.CreateConsumer(dto.NewKongConsumerReqDto("allowed", "")); err == nil {
+		t.Fatal("duplicated consumer should be rejected")
+	}
+	for _, consumer := range []*dto.KongConsumerRespDto{allowed, denied} {
+		if err = adapter.CreateAclGroup(consumer.Id, consumer.CustomId); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = adapter.CreateCredential(&dto.KongCredentialReqDto{
+			ConsumerId: consumer.Id,
+			PluginName: "key-auth",
+			Config:     &dto.KongCredentialDto{Key: "key-" + consumer.CustomId},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	credentials, err := adapter.GetCredentialList(allowed.Id, "key-auth")
+	if err != nil || credentials.Total != 1 || credentials.Data[0].Key != "key-group-a" {
+		t.Fatalf("get credentials failed: %+v, %v", credentials, err)
+	}
+	if _, err = adapter.CreateOrUpdatePlugin(&dto.KongPluginReqDto{
+		Name:    "key-auth",
+		RouteId: route.Id,
+		Config:  map[string]interface{}{"key_names": []string{"appKey"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adapter.CreateOrUpdatePlugin(&dto.KongPluginReqDto{
+		Name:    "acl",
+		RouteId: route.Id,
+		Config:  map[string]interface{}{"whitelist": "group-a"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"oauth2", "sign-auth", "hmac-auth", "jwt"} {
+		plugin, err := adapter.CreateOrUpdatePlugin(&dto.KongPluginReqDto{Name: name, RouteId: route.Id})
+		if errors.Cause(err) != xds.ErrPluginNotSupported || plugin != nil {
+			t.Fatalf("unsupported plugin %s should be rejected: %+v, %v", name, plugin, err)
+		}
+	}
+
+	content := snapshotJson(t, adapter)
+	for _, expect := range []string{
+		`"regex":"(?:/api/(.*)/detail).*"`,
+		`"consumer-` + allowed.Id + `"`,
+		`"exact":"` + allowed.Id + `:appkey"`,
+		`"allowed_headers":{"patterns":[{"exact":"appkey","ignore_case":true}]}`,
+	} {
+		if !strings.Contains(strings.ToLower(content), strings.ToLower(expect)) {
+			t.Errorf("snapshot should contain %s: %s", expect, content)
+		}
+	}
+	if strings.Contains(content, denied.Id) {
+		t.Errorf("consumer out of the acl whitelist should not be admitted: %s", content)
+	}
+	if strings.Contains(content, "key-group-") {
+		t.Errorf("keys should not be served to envoy: %s", content)
+	}
+
+	authz := xds.NewAuthzServer(xds.Options{Secret: "secret"})
+	authz.NewStore = func(string) (xds.Store, error) { return adapter.Store, nil }
+	check := func(target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, xds.AuthzPathPrefix+"test"+target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		authz.ServeHTTP(w, r)
+		return w
+	}
+	token := http.Header{"Authorization": {"Bearer " + xds.ClusterToken("secret", "test")}}
+	if w := check("/api/x/detail?appKey=key-group-a", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("key check without the cluster token should be rejected: %d", w.Code)
+	}
+	if w := check("/api/x/detail?appKey=key-group-a", token); w.Code != http.StatusOK || w.Header().Get(xds.ConsumerHeader) != allowed.Id+":appkey" {
+		t.Errorf("key in query should resolve the consumer: %d %v", w.Code, w.Header())
+	}
+	token.Set("appKey", "key-group-b")
+	if w := check("/api/x/detail", token); w.Code != http.StatusOK || w.Header().Get(xds.ConsumerHeader) != denied.Id+":appkey" {
+		t.Errorf("key in header should resolve the consumer: %d %v", w.Code, w.Header())
+	}
+	token.Set("appKey", "unknown")
+	if w := check("/api/x/detail", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key should be rejected: %d", w.Code)
+	}
+
+	if err = adapter.DeleteConsumer(allowed.Id); err != nil {
+		t.Fatal(err)
+	}
+	if content = snapshotJson(t, adapter); strings.Contains(content, allowed.Id) {
+		t.Errorf("consumer should not be admitted after deletion: %s", content)
+	}
+	if err = adapter.DeleteRoute(route.Id); err != nil {
+		t.Fatal(err)
+	}
+	if exist, _ := adapter.GetPlugin(&dto.KongPluginReqDto{Name: "acl", RouteId: route.Id}); exist != nil {
+		t.Fatal("plugins should be deleted with route")
+	}
+}
+
+func TestAdapter_DomainPolicy(t *testing.T) {
+	adapter, serviceId := newAdapter(t)
+	req := dto.NewKongRouteReqDto()
+	req.Paths = []string{"/api"}
+	req.Service = &dto.Service{Id: serviceId}
+	route, err := adapter.CreateOrUpdateRoute(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disable := false
+	limit, err := adapter.AddPlugin(&dto.KongPluginReqDto{
+		Name:    "rate-limiting",
+		RouteId: route.Id,
+		Enabled: &disable,
+		Config:  map[string]interface{}{"second": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(snapshotJson(t, adapter), "token_bucket") {
+		t.Fatal("disabled plugin should not run")
+	}
+	if _, err = adapter.CreateOrUpdatePlugin(&dto.KongPluginReqDto{
+		Name:   xds.DomainPolicy,
+		Config: map[string]interface{}{"enables": []string{"other," + limit.Id}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(snapshotJson(t, adapter), "token_bucket") {
+		t.Fatal("plugin enabled by the domain policy should run")
+	}
+
+	global, err := adapter.AddPlugin(&dto.KongPluginReqDto{Name: "key-auth", Enabled: &disable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = adapter.CreateOrUpdatePlugin(&dto.KongPluginReqDto{
+		Name:   xds.DomainPolicy,
+		Config: map[string]interface{}{"enables": []string{limit.Id + "," + global.Id}},
+	}); errors.Cause(err) != xds.ErrPluginNotSupported {
+		t.Fatalf("domain policy enabling a plugin not bound to a route should be rejected: %v", err)
+	}
+	if _, err = adapter.AddPlugin(&dto.KongPluginReqDto{Name: "rate-limiting", RouteId: route.Id, ConsumerId: "c1"}); errors.Cause(err) != xds.ErrPluginNotSupported {
+		t.Fatalf("consumer plugin should be rejected: %v", err)
+	}
+}
+
+func TestDiscoveryServer_Auth(t *testing.T) {
+	adapter, _ := newAdapter(t)
+	server := xds.NewDiscoveryServer(xds.Options{ProxyPort: 8000, ConfigCluster: "hepa-xds", Secret: "secret"})
+	server.NewStore = func(string) (xds.Store, error) { return adapter.Store, nil }
+	discover := func(token string) int {
+		body, _ := json.Marshal(map[string]interface{}{
+			"node": map[string]interface{}{
+				"cluster":  "test",
+				"metadata": map[string]interface{}{xds.TokenMetadataKey: token},
+			},
+		})
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v3/discovery:clusters", bytes.NewReader(body)))
+		return w.Code
+	}
+	if code := discover(""); code != http.StatusUnauthorized {
+		t.Errorf("discovery without token should be rejected: %d", code)
+	}
+	if code := discover(xds.ClusterToken("secret", "other")); code != http.StatusUnauthorized {
+		t.Errorf("token of another cluster should be rejected: %d", code)
+	}
+	if code := discover(xds.ClusterToken("secret", "test")); code != http.StatusOK {
+		t.Errorf("discovery with the cluster token should succeed: %d", code)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/erda-project/erda/modules/hepa/common/util"
 	"github.com/erda-project/erda/modules/hepa/config"
 	hepaI18n "github.com/erda-project/erda/modules/hepa/i18n"
+	"github.com/erda-project/erda/modules/hepa/kong/xds"
 	"github.com/erda-project/erda/modules/hepa/repository/orm"
 	"github.com/erda-project/erda/modules/monitor/common/permission"
 	"github.com/erda-project/erda/pkg/discover"
@@ -53,7 +54,11 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	common.InitLogger()
 	orm.Init()
 	logrus.Info(version.String())
-	logrus.Infof("server conf: %+v", config.ServerConf)
+	serverConf := *config.ServerConf
+	if serverConf.XdsSecret != "" {
+		serverConf.XdsSecret = "******"
+	}
+	logrus.Infof("server conf: %+v", &serverConf)
 	logrus.Infof("log conf: %+v", config.LogConf)
 	p.HttpServer.GET("/api/gateway/openapi/metrics/*", func(resp http.ResponseWriter, req *http.Request) {
 		path := strings.Replace(req.URL.Path, "/api/gateway/openapi/metrics/charts", "/api/metrics", 1)
@@ -76,6 +81,21 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		permission.ScopeOrg, permission.OrgIDFromHeader(),
 		"org", permission.ActionGet,
 	))
+	if len(config.ServerConf.XdsClusters) > 0 {
+		// envoy of the xds clusters pulls its configuration and checks the keys here,
+		// it authenticates with a client certificate or the token of its cluster
+		opts := xds.Options{
+			ProxyPort:     config.ServerConf.XdsProxyPort,
+			ConfigCluster: config.ServerConf.XdsConfigCluster,
+			RefreshDelay:  config.ServerConf.XdsRefreshDelay,
+			Secret:        config.ServerConf.XdsSecret,
+		}
+		if opts.Secret == "" {
+			logrus.Warn("xds secret is not configured, only envoy with a client certificate is served")
+		}
+		p.HttpServer.POST("/v3/*", xds.NewDiscoveryServer(opts).ServeHTTP)
+		p.HttpServer.Any(xds.AuthzPathPrefix+"*", xds.NewAuthzServer(opts).ServeHTTP)
+	}
 	hepaI18n.SetSingle(ctx.Service("i18n").(i18n.I18n).Translator("log-trans"))
 	return nil
 }
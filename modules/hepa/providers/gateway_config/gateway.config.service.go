@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway_config
+
+import (
+	context "context"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/erda-project/erda-proto-go/core/hepa/gateway_config/pb"
+	"github.com/erda-project/erda/modules/hepa/common/vars"
+	"github.com/erda-project/erda/modules/hepa/gateway/configbundle"
+	"github.com/erda-project/erda/modules/hepa/services/gateway_config"
+	"github.com/erda-project/erda/pkg/common/apis"
+	erdaErr "github.com/erda-project/erda/pkg/common/errors"
+)
+
+type gatewayConfigService struct {
+	p *provider
+}
+
+func (s *gatewayConfigService) ExportConfig(ctx context.Context, req *pb.ExportConfigRequest) (resp *pb.ExportConfigResponse, err error) {
+	service := gateway_config.Service.Clone(ctx)
+	bundle, err := service.Export(apis.GetOrgID(ctx), req.ProjectId, req.Env)
+	if err != nil {
+		err = erdaErr.NewInvalidParameterError(vars.TODO_PARAM, errors.Cause(err).Error())
+		return
+	}
+	content, err := bundle.Marshal()
+	if err != nil {
+		err = erdaErr.NewInternalServerError(err)
+		return
+	}
+	resp = &pb.ExportConfigResponse{
+		Data: string(content),
+	}
+	return
+}
+
+func (s *gatewayConfigService) ApplyConfig(ctx context.Context, req *pb.ApplyConfigRequest) (resp *pb.ApplyConfigResponse, err error) {
+	service := gateway_config.Service.Clone(ctx)
+	bundle, err := configbundle.Parse([]byte(req.Content))
+	if err != nil {
+		err = erdaErr.NewInvalidParameterError("content", errors.Cause(err).Error())
+		return
+	}
+	plan, err := service.Apply(apis.GetOrgID(ctx), req.ProjectId, req.Env, bundle, req.DryRun, req.Prune)
+	if err != nil {
+		// keep the message of the failed change
+		err = erdaErr.NewInvalidParameterError(vars.TODO_PARAM, err.Error())
+		return
+	}
+	summary := plan.Summary()
+	data := &pb.ApplyPlan{
+		Create:  int64(summary[configbundle.ACTION_CREATE]),
+		Update:  int64(summary[configbundle.ACTION_UPDATE]),
+		Delete:  int64(summary[configbundle.ACTION_DELETE]),
+		Output:  plan.String(),
+		Applied: !req.DryRun,
+	}
+	for _, change := range plan.Changes {
+		data.Changes = append(data.Changes, &pb.PlanChange{
+			Action: string(change.Action),
+			Kind:   string(change.Kind),
+			Name:   change.Name,
+			Fields: change.Fields,
+		})
+	}
+	resp = &pb.ApplyConfigResponse{
+		Data: data,
+	}
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway_config
+
+import (
+	logs "github.com/erda-project/erda-infra/base/logs"
+	servicehub "github.com/erda-project/erda-infra/base/servicehub"
+	transport "github.com/erda-project/erda-infra/pkg/transport"
+	pb "github.com/erda-project/erda-proto-go/core/hepa/gateway_config/pb"
+	"github.com/erda-project/erda/modules/hepa/common"
+	"github.com/erda-project/erda/modules/hepa/services/gateway_config/impl"
+	"github.com/erda-project/erda/pkg/common/apis"
+	perm "github.com/erda-project/erda/pkg/common/permission"
+)
+
+type config struct {
+}
+
+// +provider
+type provider struct {
+	Cfg                  *config
+	Log                  logs.Logger
+	Register             transport.Register
+	gatewayConfigService *gatewayConfigService
+	Perm                 perm.Interface `autowired:"permission"`
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.gatewayConfigService = &gatewayConfigService{p}
+	err := impl.NewGatewayConfigServiceImpl()
+	if err != nil {
+		return err
+	}
+	if p.Register != nil {
+		type configService = pb.GatewayConfigServiceServer
+		pb.RegisterGatewayConfigServiceImp(p.Register, p.gatewayConfigService, apis.Options(), p.Perm.Check(
+			perm.Method(configService.ExportConfig, perm.ScopeProject, "project", perm.ActionGet, perm.FieldValue("ProjectId")),
+			perm.Method(configService.ApplyConfig, perm.ScopeProject, "project", perm.ActionGet, perm.FieldValue("ProjectId")),
+		), common.AccessLogWrap(common.AccessLog))
+	}
+	return nil
+}
+
+func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
+	switch {
+	case ctx.Service() == "erda.core.hepa.gateway_config.GatewayConfigService" || ctx.Type() == pb.GatewayConfigServiceServerType() || ctx.Type() == pb.GatewayConfigServiceHandlerType():
+		return p.gatewayConfigService
+	}
+	return p
+}
+
+func init() {
+	servicehub.Register("erda.core.hepa.gateway_config", &servicehub.Spec{
+		Services:             pb.ServiceNames(),
+		Types:                pb.Types(),
+		OptionalDependencies: []string{"service-register"},
+		Dependencies: []string{
+			"hepa",
+			"erda.core.hepa.endpoint_api.EndpointApiService",
+			"erda.core.hepa.openapi_rule.OpenapiRuleService",
+			"erda.core.hepa.openapi_consumer.OpenapiConsumerService",
+			"erda.core.hepa.api_policy.ApiPolicyService",
+			"erda.core.hepa.domain.DomainService",
+		},
+		Description: "",
+		ConfigFunc: func() interface{} {
+			return &config{}
+		},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -24,8 +24,10 @@ const (
 	KEYAUTH      = "key-auth"
 	SIGNAUTH     = "sign-auth"
 	HMACAUTH     = "hmac-auth"
+	JWTAUTH      = "jwt"
 	KeyAuthTips  = "请将appKey带在名为appKey的url参数或者名为X-App-Key的请求头上"
 	SignAuthTips = "请将appKey带在名为appKey的url参数上，将参数签名串带在名为sign的url参数上"
+	JwtAuthTips  = "请将 JWT 以 Bearer 方式带在名为 Authorization 的请求头上，或带在名为 jwt 的url参数上"
 )
 
 type AuthItem struct {
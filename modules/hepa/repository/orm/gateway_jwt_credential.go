@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import "time"
+
+// GatewayJwtCredential is a kong jwt credential whose public key is resolved
+// from the jwks, each signing key of the jwks has its own credential unless
+// the key id is pinned by the user
+type GatewayJwtCredential struct {
+	Id            string    `json:"id" xorm:"not null pk comment('id') VARCHAR(36)"`
+	OrgId         int64     `json:"org_id" xorm:"not null default 0 comment('企业id') BIGINT(20)"`
+	OrgName       string    `json:"org_name" xorm:"not null default '' comment('企业名') VARCHAR(50)"`
+	ConsumerId    string    `json:"consumer_id" xorm:"not null default '' comment('kong的消费者id') VARCHAR(128)"`
+	CredentialId  string    `json:"credential_id" xorm:"not null default '' comment('kong的凭证id') VARCHAR(128)"`
+	Issuer        string    `json:"issuer" xorm:"not null default '' comment('签发者') VARCHAR(1024)"`
+	JwksUri       string    `json:"jwks_uri" xorm:"not null default '' comment('JWKS地址') VARCHAR(1024)"`
+	KeyId         string    `json:"key_id" xorm:"not null default '' comment('JWKS中的密钥id') VARCHAR(256)"`
+	IsPinned      bool      `json:"is_pinned" xorm:"not null default 0 comment('密钥id是否由用户指定') TINYINT(1)"`
+	CreatedAt     time.Time `json:"created_at" xorm:"created"`
+	UpdatedAt     time.Time `json:"updated_at" xorm:"updated"`
+	SoftDeletedAt int64     `json:"soft_deleted_at" xorm:"not null default 0 comment('软删除') BIGINT(20)"`
+}
+
+func (GatewayJwtCredential) TableName() string {
+	return "erda_gateway_jwt_credential"
+}
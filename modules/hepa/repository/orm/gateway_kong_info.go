@@ -14,6 +14,9 @@
 
 package orm
 
+// XDS_ADDR_PREFIX marks a kong_addr served by the xDS control plane of hepa instead of a kong admin api
+const XDS_ADDR_PREFIX = "xds://"
+
 type GatewayKongInfo struct {
 	Az              string `json:"az" xorm:"not null comment('集群名') VARCHAR(32)"`
 	Env             string `json:"env" xorm:"default '' comment('环境名') VARCHAR(32)"`
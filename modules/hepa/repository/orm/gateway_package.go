@@ -38,5 +38,6 @@ type GatewayPackage struct {
 	CloudapiVpcGrant   string `json:"cloudapi_vpc_grant" xorm:"not null default '' comment('阿里云API网关的VPC Grant') VARCHAR(128)"`
 	CloudapiDomain     string `json:"cloudapi_domain" xorm:"not null default '' comment('阿里云API网关的分组二级域名') VARCHAR(1024)"`
 	CloudapiNeedBind   int    `json:"cloudapi_need_bind" xorm:"default 0 comment('是否需要绑定阿里云API网关') TINYINT(1)"`
+	AuthConfig         string `json:"auth_config" xorm:"comment('鉴权配置') TEXT"`
 	BaseRow            `xorm:"extends"`
 }
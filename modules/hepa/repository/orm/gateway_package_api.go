@@ -30,5 +30,6 @@ type GatewayPackageApi struct {
 	RuntimeServiceId string `json:"runtime_service_id" xorm:"not null default '' comment('关联的service的id') VARCHAR(32)"`
 	ZoneId           string `json:"zone_id" xorm:"comment('所属的zone') VARCHAR(32)"`
 	CloudapiApiId    string `json:"cloudapi_api_id" xorm:"not null default '' comment('阿里云API网关的api id') VARCHAR(128)"`
+	Scopes           string `json:"scopes" xorm:"not null default '' comment('要求的jwt scope，逗号分隔') VARCHAR(1024)"`
 	BaseRow          `xorm:"extends"`
 }
@@ -0,0 +1,38 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import "time"
+
+// GatewayXdsResource is a kong object stored for the xDS gateway, it has the
+// standard columns instead of BaseRow
+type GatewayXdsResource struct {
+	Id            string    `json:"id" xorm:"not null pk comment('id') VARCHAR(36)"`
+	OrgId         int64     `json:"org_id" xorm:"not null default 0 comment('企业id') BIGINT(20)"`
+	OrgName       string    `json:"org_name" xorm:"not null default '' comment('企业名') VARCHAR(50)"`
+	Cluster       string    `json:"cluster" xorm:"not null default '' comment('集群名') VARCHAR(32)"`
+	Kind          string    `json:"kind" xorm:"not null default '' comment('资源类型') VARCHAR(32)"`
+	Name          string    `json:"name" xorm:"not null default '' comment('资源名称') VARCHAR(256)"`
+	ParentId      string    `json:"parent_id" xorm:"not null default '' comment('所属资源id') VARCHAR(36)"`
+	Tags          string    `json:"tags" xorm:"not null default '' comment('标签，逗号分隔') VARCHAR(1024)"`
+	Content       string    `json:"content" xorm:"not null comment('资源内容') MEDIUMTEXT"`
+	CreatedAt     time.Time `json:"created_at" xorm:"created"`
+	UpdatedAt     time.Time `json:"updated_at" xorm:"updated"`
+	SoftDeletedAt int64     `json:"soft_deleted_at" xorm:"not null default 0 comment('软删除') BIGINT(20)"`
+}
+
+func (GatewayXdsResource) TableName() string {
+	return "erda_gateway_xds_resource"
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/xormplus/xorm"
+
+	. "github.com/erda-project/erda/modules/hepa/common/vars"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+)
+
+type GatewayJwtCredentialServiceImpl struct {
+	engine   *orm.OrmEngine
+	executor xorm.Interface
+}
+
+func NewGatewayJwtCredentialServiceImpl() (*GatewayJwtCredentialServiceImpl, error) {
+	engine, err := orm.GetSingleton()
+	if err != nil {
+		return nil, errors.Wrap(err, "new GatewayJwtCredentialServiceImpl failed")
+	}
+	return &GatewayJwtCredentialServiceImpl{
+		engine:   engine,
+		executor: engine,
+	}, nil
+}
+
+func (impl *GatewayJwtCredentialServiceImpl) Insert(dao *orm.GatewayJwtCredential) error {
+	if dao == nil || len(dao.ConsumerId) == 0 || len(dao.CredentialId) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	if len(dao.Id) == 0 {
+		dao.Id = uuid.New().String()
+	}
+	_, err := impl.executor.Insert(dao)
+	if err != nil {
+		return errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return nil
+}
+
+func (impl *GatewayJwtCredentialServiceImpl) SelectByConsumer(consumerId string) ([]orm.GatewayJwtCredential, error) {
+	var result []orm.GatewayJwtCredential
+	if len(consumerId) == 0 {
+		return result, errors.New(ERR_INVALID_ARG)
+	}
+	err := impl.executor.Where("consumer_id = ? and soft_deleted_at = 0", consumerId).
+		Asc("created_at").Find(&result)
+	if err != nil {
+		return result, errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return result, nil
+}
+
+func (impl *GatewayJwtCredentialServiceImpl) SelectAll() ([]orm.GatewayJwtCredential, error) {
+	var result []orm.GatewayJwtCredential
+	err := impl.executor.Where("soft_deleted_at = 0").Asc("created_at").Find(&result)
+	if err != nil {
+		return result, errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return result, nil
+}
+
+func (impl *GatewayJwtCredentialServiceImpl) DeleteByCredential(consumerId, credentialId string) error {
+	if len(consumerId) == 0 || len(credentialId) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	_, err := impl.executor.Cols("soft_deleted_at").
+		Where("consumer_id = ? and credential_id = ? and soft_deleted_at = 0", consumerId, credentialId).
+		Update(&orm.GatewayJwtCredential{SoftDeletedAt: time.Now().UnixNano() / 1e6})
+	if err != nil {
+		return errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return nil
+}
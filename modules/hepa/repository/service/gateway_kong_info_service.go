@@ -181,6 +181,9 @@ func (impl *GatewayKongInfoServiceImpl) GetTenantId(projectId, env, az string) (
 }
 
 func (impl *GatewayKongInfoServiceImpl) acquireKongAddr(netportalUrl, selfAz string, info *orm.GatewayKongInfo) (string, error) {
+	if strings.HasPrefix(info.KongAddr, orm.XDS_ADDR_PREFIX) {
+		return info.KongAddr, nil
+	}
 	if strings.HasPrefix(info.KongAddr, "inet://") {
 		pathSlice := strings.SplitN(strings.TrimPrefix(info.KongAddr, "inet://"), "/", 2)
 		if len(pathSlice) != 2 {
@@ -209,7 +212,7 @@ func (impl *GatewayKongInfoServiceImpl) adjustKongInfo(info *orm.GatewayKongInfo
 	}
 	info.KongAddr = kongAddr
 	// TODO: Compatibility code, will be removed later
-	if config.ServerConf.UseAdminEndpoint && selfAz != info.Az {
+	if config.ServerConf.UseAdminEndpoint && selfAz != info.Az && !strings.HasPrefix(kongAddr, orm.XDS_ADDR_PREFIX) {
 		info.KongAddr = "http://" + strings.Replace(info.Endpoint, "gateway", "gateway-admin", 1)
 	}
 	return nil
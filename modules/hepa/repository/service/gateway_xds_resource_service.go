@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/xormplus/xorm"
+
+	. "github.com/erda-project/erda/modules/hepa/common/vars"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+)
+
+type GatewayXdsResourceServiceImpl struct {
+	engine   *orm.OrmEngine
+	executor xorm.Interface
+}
+
+func NewGatewayXdsResourceServiceImpl() (*GatewayXdsResourceServiceImpl, error) {
+	engine, err := orm.GetSingleton()
+	if err != nil {
+		return nil, errors.Wrap(err, "new GatewayXdsResourceServiceImpl failed")
+	}
+	return &GatewayXdsResourceServiceImpl{
+		engine:   engine,
+		executor: engine,
+	}, nil
+}
+
+func (impl *GatewayXdsResourceServiceImpl) Insert(res *orm.GatewayXdsResource) error {
+	if res == nil || len(res.Cluster) == 0 || len(res.Kind) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	if len(res.Id) == 0 {
+		res.Id = uuid.New().String()
+	}
+	_, err := impl.executor.Insert(res)
+	if err != nil {
+		return errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return nil
+}
+
+func (impl *GatewayXdsResourceServiceImpl) Update(res *orm.GatewayXdsResource) error {
+	if res == nil || len(res.Id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	_, err := impl.executor.Cols("name", "parent_id", "tags", "content").
+		Where("id = ? and soft_deleted_at = 0", res.Id).Update(res)
+	if err != nil {
+		return errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return nil
+}
+
+func (impl *GatewayXdsResourceServiceImpl) Get(cluster, kind, id string) (*orm.GatewayXdsResource, error) {
+	if len(cluster) == 0 || len(kind) == 0 || len(id) == 0 {
+		return nil, errors.New(ERR_INVALID_ARG)
+	}
+	dao := &orm.GatewayXdsResource{}
+	succ, err := impl.executor.Where("cluster = ? and kind = ? and id = ? and soft_deleted_at = 0", cluster, kind, id).Get(dao)
+	if err != nil {
+		return nil, errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	if !succ {
+		return nil, nil
+	}
+	return dao, nil
+}
+
+func (impl *GatewayXdsResourceServiceImpl) SelectByKind(cluster, kind string) ([]orm.GatewayXdsResource, error) {
+	var result []orm.GatewayXdsResource
+	if len(cluster) == 0 || len(kind) == 0 {
+		return result, errors.New(ERR_INVALID_ARG)
+	}
+	err := impl.executor.Where("cluster = ? and kind = ? and soft_deleted_at = 0", cluster, kind).
+		Asc("created_at").Find(&result)
+	if err != nil {
+		return result, errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return result, nil
+}
+
+func (impl *GatewayXdsResourceServiceImpl) Delete(cluster, kind, id string) error {
+	if len(cluster) == 0 || len(kind) == 0 || len(id) == 0 {
+		return errors.New(ERR_INVALID_ARG)
+	}
+	_, err := impl.executor.Cols("soft_deleted_at").
+		Where("cluster = ? and kind = ? and id = ? and soft_deleted_at = 0", cluster, kind, id).
+		Update(&orm.GatewayXdsResource{SoftDeletedAt: time.Now().UnixNano() / 1e6})
+	if err != nil {
+		return errors.Wrap(err, ERR_SQL_FAIL)
+	}
+	return nil
+}
@@ -382,3 +382,18 @@ type GatewayDomainService interface {
 	GetPage(options []SelectOption, page *Page) (*PageQuery, error)
 	SelectByOptions(options []SelectOption) ([]GatewayDomain, error)
 }
+
+type GatewayXdsResourceService interface {
+	Insert(*GatewayXdsResource) error
+	Update(*GatewayXdsResource) error
+	Get(cluster, kind, id string) (*GatewayXdsResource, error)
+	SelectByKind(cluster, kind string) ([]GatewayXdsResource, error)
+	Delete(cluster, kind, id string) error
+}
+
+type GatewayJwtCredentialService interface {
+	Insert(*GatewayJwtCredential) error
+	SelectByConsumer(consumerId string) ([]GatewayJwtCredential, error)
+	SelectAll() ([]GatewayJwtCredential, error)
+	DeleteByCredential(consumerId, credentialId string) error
+}
@@ -87,6 +87,21 @@ func (impl *SessionHelper) Rollback() error {
 	return nil
 }
 
+// TryLock acquires the named lock of mysql without waiting, the lock is bound
+// to the connection of the session, so it must be released before Close
+func (impl *SessionHelper) TryLock(name string) (bool, error) {
+	res, err := impl.session.QueryString("select get_lock(?, 0) as locked", name)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return len(res) > 0 && res[0]["locked"] == "1", nil
+}
+
+func (impl *SessionHelper) Unlock(name string) error {
+	_, err := impl.session.Exec("select release_lock(?)", name)
+	return errors.WithStack(err)
+}
+
 func (impl *SessionHelper) Close() {
 	if impl.closed {
 		return
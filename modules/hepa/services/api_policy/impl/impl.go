@@ -36,6 +36,8 @@ import (
 	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/ip"
 	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/proxy"
 	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/server-guard"
+	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/traffic-split"
+	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/transform"
 	_ "github.com/erda-project/erda/modules/hepa/apipolicy/policies/waf"
 	"github.com/erda-project/erda/modules/hepa/bundle"
 	"github.com/erda-project/erda/modules/hepa/common"
@@ -211,23 +211,21 @@ func (impl GatewayOpenapiServiceImpl) createAuthRule(authType string, pack *orm.
 	case gw.AT_SIGN_AUTH:
 		authRule.Config = gw.SIGNAUTH_CONFIG
 	case gw.AT_HMAC_AUTH:
-		kongInfo, err := impl.kongDb.GetKongInfo(&orm.GatewayKongInfo{
-			Az:        pack.DiceClusterName,
-			ProjectId: pack.DiceProjectId,
-			Env:       pack.DiceEnv,
-		})
+		err := impl.checkPluginEnabled(pack, gw.AT_HMAC_AUTH)
 		if err != nil {
 			return nil, err
 		}
-		kongAdapter := kong.NewKongAdapter(kongInfo.KongAddr)
-		enabled, err := kongAdapter.CheckPluginEnabled(gw.AT_HMAC_AUTH)
+		authRule.Config = gw.HMACAUTH_CONFIG
+	case gw.AT_JWT:
+		err := impl.checkPluginEnabled(pack, gw.AT_JWT)
 		if err != nil {
 			return nil, err
 		}
-		if !enabled {
-			return nil, errors.New("hmac-auth plugin is not enabled")
+		jwtConfig, err := gw.ParseJwtConfig(pack.AuthConfig)
+		if err != nil {
+			return nil, err
 		}
-		authRule.Config = gw.HMACAUTH_CONFIG
+		authRule.Config = jwtConfig.AuthPluginConfig()
 	case gw.AT_ALIYUN_APP:
 		authRule.Config = nil
 		authRule.NotKongPlugin = true
@@ -237,11 +235,133 @@ func (impl GatewayOpenapiServiceImpl) createAuthRule(authType string, pack *orm.
 	return authRule, nil
 }
 
+func (impl GatewayOpenapiServiceImpl) checkPluginEnabled(pack *orm.GatewayPackage, pluginName string) error {
+	kongInfo, err := impl.kongDb.GetKongInfo(&orm.GatewayKongInfo{
+		Az:        pack.DiceClusterName,
+		ProjectId: pack.DiceProjectId,
+		Env:       pack.DiceEnv,
+	})
+	if err != nil {
+		return err
+	}
+	kongAdapter := kong.NewKongAdapter(kongInfo.KongAddr)
+	enabled, err := kongAdapter.CheckPluginEnabled(pluginName)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.Errorf("%s plugin is not enabled", pluginName)
+	}
+	return nil
+}
+
+// createClaimRule creates the claim rule forwarding the claims for the package,
+// or checking the required scopes for the api, nil means no rule is needed
+func (impl GatewayOpenapiServiceImpl) createClaimRule(pack *orm.GatewayPackage, api *orm.GatewayPackageApi) (*gw.OpenapiRule, error) {
+	if pack.AuthType != gw.AT_JWT {
+		return nil, nil
+	}
+	jwtConfig, err := gw.ParseJwtConfig(pack.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
+	rule := &gw.OpenapiRule{
+		PackageId:  pack.Id,
+		PluginName: gw.JWT_CLAIMS,
+		Category:   gw.CLAIM_RULE,
+		Enabled:    true,
+		Region:     gw.PACKAGE_RULE,
+	}
+	if api == nil {
+		rule.Config = jwtConfig.ClaimPluginConfig(nil)
+	} else if api.Scopes != "" {
+		// the scopes are checked even if the acl of the api is off, the rule
+		// rejects the requests without a verified token
+		rule.Config = jwtConfig.ClaimPluginConfig(strings.Split(api.Scopes, ","))
+		rule.PackageApiId = api.Id
+		rule.Region = gw.API_RULE
+	}
+	if rule.Config == nil {
+		return nil, nil
+	}
+	err = impl.checkPluginEnabled(pack, gw.JWT_CLAIMS)
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// touchApiClaimRule recreates the claim rule of the api
+func (impl GatewayOpenapiServiceImpl) touchApiClaimRule(pack *orm.GatewayPackage, api *orm.GatewayPackageApi, session *db.SessionHelper) error {
+	rules, err := (*impl.ruleBiz).GetApiRules(api.Id, gw.CLAIM_RULE)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		err = (*impl.ruleBiz).DeleteRule(rule.Id, session)
+		if err != nil {
+			return err
+		}
+	}
+	rule, err := impl.createClaimRule(pack, api)
+	if err != nil || rule == nil {
+		return err
+	}
+	return (*impl.ruleBiz).CreateRule(gw.DiceInfo{
+		ProjectId: pack.DiceProjectId,
+		Env:       pack.DiceEnv,
+		Az:        pack.DiceClusterName,
+	}, rule, session)
+}
+
+// touchClaimRules recreates the claim rules of the package and its apis
+func (impl GatewayOpenapiServiceImpl) touchClaimRules(pack *orm.GatewayPackage, session *db.SessionHelper) error {
+	rules, err := (*impl.ruleBiz).GetPackageRules(pack.Id, session, gw.CLAIM_RULE)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		err = (*impl.ruleBiz).DeleteRule(rule.Id, session)
+		if err != nil {
+			return err
+		}
+	}
+	rule, err := impl.createClaimRule(pack, nil)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		err = (*impl.ruleBiz).CreateRule(gw.DiceInfo{
+			ProjectId: pack.DiceProjectId,
+			Env:       pack.DiceEnv,
+			Az:        pack.DiceClusterName,
+		}, rule, session)
+		if err != nil {
+			return err
+		}
+	}
+	apiSession, err := impl.packageApiDb.NewSession(session)
+	if err != nil {
+		return err
+	}
+	apis, err := apiSession.SelectByAny(&orm.GatewayPackageApi{PackageId: pack.Id})
+	if err != nil {
+		return err
+	}
+	for i := range apis {
+		err = impl.touchApiClaimRule(pack, &apis[i], session)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (impl GatewayOpenapiServiceImpl) CreatePackage(args *gw.DiceArgsDto, dto *gw.PackageDto) (result *gw.PackageInfoDto, existName string, err error) {
 	var diceInfo gw.DiceInfo
 	var helper *db.SessionHelper
 	var pack *orm.GatewayPackage
-	var aclRule, authRule *gw.OpenapiRule
+	var aclRule, authRule, claimRule *gw.OpenapiRule
 	var packSession db.GatewayPackageService
 	var z *orm.GatewayZone
 	var unique bool
@@ -326,6 +446,9 @@ func (impl GatewayOpenapiServiceImpl) CreatePackage(args *gw.DiceArgsDto, dto *g
 		Scene:           dto.Scene,
 		Description:     dto.Description,
 	}
+	if dto.AuthType == gw.AT_JWT {
+		pack.AuthConfig = dto.JwtConfig.Marshal()
+	}
 	unique, err = packSession.CheckUnique(pack)
 	if err != nil {
 		return
@@ -363,6 +486,16 @@ func (impl GatewayOpenapiServiceImpl) CreatePackage(args *gw.DiceArgsDto, dto *g
 		if err != nil {
 			return
 		}
+		claimRule, err = impl.createClaimRule(pack, nil)
+		if err != nil {
+			return
+		}
+		if claimRule != nil {
+			err = (*impl.ruleBiz).CreateRule(diceInfo, claimRule, helper)
+			if err != nil {
+				return
+			}
+		}
 		// update zone kong polices
 		err = (*impl.ruleBiz).SetPackageKongPolicies(pack, helper)
 		if err != nil {
@@ -481,6 +614,9 @@ func (impl GatewayOpenapiServiceImpl) packageDto(dao *orm.GatewayPackage, domain
 			Scene:       dao.Scene,
 		},
 	}
+	if dao.AuthType == gw.AT_JWT {
+		res.JwtConfig, _ = gw.ParseJwtConfig(dao.AuthConfig)
+	}
 	return res
 }
 
@@ -577,6 +713,7 @@ func (impl GatewayOpenapiServiceImpl) UpdatePackage(orgId, id string, dto *gw.Pa
 	var domains []string
 	var apis []orm.GatewayPackageApi
 	var session *db.SessionHelper
+	var authConfig string
 	defer func() {
 		if err != nil {
 			log.Errorf("error happened, err:%+v", err)
@@ -693,8 +830,13 @@ func (impl GatewayOpenapiServiceImpl) UpdatePackage(orgId, id string, dto *gw.Pa
 			return
 		}
 	}
-	if dao.AuthType != dto.AuthType {
+	if dto.AuthType == gw.AT_JWT {
+		authConfig = dto.JwtConfig.Marshal()
+	}
+	if dao.AuthType != dto.AuthType || dao.AuthConfig != authConfig {
 		needUpdate = true
+		dao.AuthType = dto.AuthType
+		dao.AuthConfig = authConfig
 		var oldAuthRule []gw.OpenapiRuleInfo
 		oldAuthRule, err = (*impl.ruleBiz).GetPackageRules(id, session, gw.AUTH_RULE)
 		if err != nil {
@@ -718,6 +860,10 @@ func (impl GatewayOpenapiServiceImpl) UpdatePackage(orgId, id string, dto *gw.Pa
 				return
 			}
 		}
+		err = impl.touchClaimRules(dao, session)
+		if err != nil {
+			return
+		}
 	}
 	if dao.AclType != dto.AclType {
 		needUpdate = true
@@ -948,6 +1094,7 @@ func (impl GatewayOpenapiServiceImpl) packageApiDao(dto *gw.OpenapiDto) *orm.Gat
 		RedirectType: dto.RedirectType,
 		Description:  dto.Description,
 		Origin:       string(gw.FROM_CUSTOM),
+		Scopes:       strings.Join(dto.Scopes, ","),
 	}
 	if dto.AllowPassAuth {
 		dao.AclType = gw.ACL_OFF
@@ -1369,7 +1516,8 @@ func (impl GatewayOpenapiServiceImpl) SessionCreatePackageApi(id string, dto *gw
 		if err != nil {
 			goto failed
 		}
-		authRule, err = impl.createApiAuthRule(dao.PackageId, dao.Id, false)
+		// the token is still verified if the api requires scopes
+		authRule, err = impl.createApiAuthRule(dao.PackageId, dao.Id, dao.Scopes != "")
 		if err != nil {
 			goto failed
 		}
@@ -1383,6 +1531,13 @@ func (impl GatewayOpenapiServiceImpl) SessionCreatePackageApi(id string, dto *gw
 		}
 		needUpdateDomainPolicy = true
 	}
+	if dao.Scopes != "" {
+		err = impl.touchApiClaimRule(pack, dao, session)
+		if err != nil {
+			goto failed
+		}
+		needUpdateDomainPolicy = true
+	}
 	if needUpdateDomainPolicy {
 		err = (*impl.ruleBiz).SetPackageKongPolicies(pack, session)
 		if err != nil {
@@ -1580,6 +1735,9 @@ func (impl GatewayOpenapiServiceImpl) openapiDto(dao *orm.GatewayPackageApi) *gw
 			Description:  dao.Description,
 		},
 	}
+	if dao.Scopes != "" {
+		dto.Scopes = strings.Split(dao.Scopes, ",")
+	}
 	if dao.AclType == gw.ACL_OFF {
 		dto.AllowPassAuth = true
 	}
@@ -1644,7 +1802,9 @@ func (impl GatewayOpenapiServiceImpl) deleteApiPassAuthRules(apiId string) error
 	return nil
 }
 
-func (impl GatewayOpenapiServiceImpl) createApiPassAuthRules(pack *orm.GatewayPackage, apiId string) error {
+// createApiPassAuthRules turns off the acl of the api, the auth is kept if the
+// api requires scopes, as they are checked against the verified token
+func (impl GatewayOpenapiServiceImpl) createApiPassAuthRules(pack *orm.GatewayPackage, apiId, scopes string) error {
 	var authRule, aclRule *gw.OpenapiRule
 	diceInfo := gw.DiceInfo{
 		ProjectId: pack.DiceProjectId,
@@ -1655,7 +1815,7 @@ func (impl GatewayOpenapiServiceImpl) createApiPassAuthRules(pack *orm.GatewayPa
 	if err != nil {
 		return err
 	}
-	authRule, err = impl.createApiAuthRule(pack.Id, apiId, false)
+	authRule, err = impl.createApiAuthRule(pack.Id, apiId, scopes != "")
 	if err != nil {
 		return err
 	}
@@ -1898,7 +2058,7 @@ func (impl GatewayOpenapiServiceImpl) UpdatePackageApi(packageId, apiId string,
 				return
 			}
 		} else if updateDao.AclType == gw.ACL_OFF {
-			err = impl.createApiPassAuthRules(pack, apiId)
+			err = impl.createApiPassAuthRules(pack, apiId, updateDao.Scopes)
 			if err != nil {
 				return
 			}
@@ -1910,12 +2070,31 @@ func (impl GatewayOpenapiServiceImpl) UpdatePackageApi(packageId, apiId string,
 			if err != nil {
 				return
 			}
-			err = impl.createApiPassAuthRules(pack, apiId)
+			err = impl.createApiPassAuthRules(pack, apiId, updateDao.Scopes)
 			if err != nil {
 				return
 			}
 		}
 	}
+	if dao.Scopes != updateDao.Scopes && dao.AclType == updateDao.AclType &&
+		dao.RedirectType == updateDao.RedirectType && updateDao.AclType == gw.ACL_OFF {
+		err = impl.deleteApiPassAuthRules(apiId)
+		if err != nil {
+			return
+		}
+		err = impl.createApiPassAuthRules(pack, apiId, updateDao.Scopes)
+		if err != nil {
+			return
+		}
+	}
+	if dao.Scopes != updateDao.Scopes || dao.AclType != updateDao.AclType || dao.RedirectType != updateDao.RedirectType {
+		needUpdateDomainPolicy = true
+		updateDao.Id = apiId
+		err = impl.touchApiClaimRule(pack, updateDao, nil)
+		if err != nil {
+			return
+		}
+	}
 	if needUpdateDomainPolicy {
 		err = (*impl.ruleBiz).SetPackageKongPolicies(pack, nil)
 		if err != nil {
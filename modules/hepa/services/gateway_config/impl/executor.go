@@ -0,0 +1,264 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/configbundle"
+	gw "github.com/erda-project/erda/modules/hepa/gateway/dto"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+)
+
+// executor applies the changes of plan by the services, so the changes take effect as made by the users
+type executor struct {
+	impl GatewayConfigServiceImpl
+	args *gw.DiceArgsDto
+	// the desired packages, the ids are filled when created
+	packages map[string]*configbundle.Package
+	// consumer name -> id
+	consumerIds map[string]string
+}
+
+func (e *executor) execute(change configbundle.Change) error {
+	switch change.Kind {
+	case configbundle.KIND_CONSUMER:
+		return e.executeConsumer(change)
+	case configbundle.KIND_PACKAGE:
+		return e.executePackage(change)
+	case configbundle.KIND_API:
+		return e.executeApi(change)
+	case configbundle.KIND_POLICY:
+		return e.executePolicy(change)
+	case configbundle.KIND_ACL:
+		return e.executeAcl(change)
+	case configbundle.KIND_LIMIT:
+		return e.executeLimit(change)
+	}
+	return errors.Errorf("unknown kind: %s", change.Kind)
+}
+
+func (e *executor) executeConsumer(change configbundle.Change) error {
+	service := (*e.impl.consumerBiz).Clone(e.impl.reqCtx)
+	consumer := change.Consumer
+	dto := &gw.OpenConsumerDto{
+		Name:        consumer.Name,
+		Description: consumer.Description,
+	}
+	switch change.Action {
+	case configbundle.ACTION_CREATE:
+		id, exists, err := service.CreateConsumer(e.args, dto)
+		if exists {
+			return errors.Errorf("consumer %s already exists", consumer.Name)
+		}
+		if err != nil {
+			return err
+		}
+		consumer.Id = id
+		e.consumerIds[consumer.Name] = id
+		return nil
+	case configbundle.ACTION_UPDATE:
+		_, err := service.UpdateConsumer(consumer.Id, dto)
+		return err
+	default:
+		_, err := service.DeleteConsumer(consumer.Id)
+		return err
+	}
+}
+
+func (e *executor) executePackage(change configbundle.Change) error {
+	service := (*e.impl.packageBiz).Clone(e.impl.reqCtx)
+	pack := change.Package
+	if change.Action == configbundle.ACTION_DELETE {
+		_, err := service.DeletePackage(pack.Id)
+		return err
+	}
+	dto := &gw.PackageDto{
+		Name:        pack.Name,
+		BindDomain:  pack.BindDomain,
+		AuthType:    pack.AuthType,
+		AclType:     pack.AclType,
+		Scene:       pack.Scene,
+		Description: pack.Description,
+	}
+	if len(pack.AuthConfig) > 0 {
+		content, err := json.Marshal(pack.AuthConfig)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dto.JwtConfig = &gw.JwtConfig{}
+		err = json.Unmarshal(content, dto.JwtConfig)
+		if err != nil {
+			return errors.Wrapf(err, "invalid auth config: %s", content)
+		}
+	}
+	if change.Action == configbundle.ACTION_UPDATE {
+		_, err := service.UpdatePackage(e.args.OrgId, pack.Id, dto)
+		return err
+	}
+	info, existName, err := service.CreatePackage(e.args, dto)
+	if existName != "" {
+		return errors.Errorf("%s already exists", existName)
+	}
+	if err != nil {
+		return err
+	}
+	pack.Id = info.Id
+	return nil
+}
+
+func (e *executor) openapiDto(api *configbundle.Api) (*gw.OpenapiDto, error) {
+	dto := &gw.OpenapiDto{
+		ApiPath:         api.ApiPath,
+		Method:          api.Method,
+		Description:     api.Description,
+		RedirectType:    api.RedirectType,
+		RedirectAddr:    api.RedirectAddr,
+		RedirectPath:    api.RedirectPath,
+		RedirectApp:     api.RedirectApp,
+		RedirectService: api.RedirectService,
+		AllowPassAuth:   api.AllowPassAuth,
+		Scopes:          api.Scopes,
+	}
+	if api.RedirectType != gw.RT_SERVICE {
+		return dto, nil
+	}
+	az, err := e.impl.azDb.GetAz(&orm.GatewayAzInfo{
+		Env:       e.args.Env,
+		OrgId:     e.args.OrgId,
+		ProjectId: e.args.ProjectId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the runtime of the same name in this environment
+	runtimeService, err := e.impl.runtimeDb.GetByAny(&orm.GatewayRuntimeService{
+		ProjectId:   e.args.ProjectId,
+		Workspace:   e.args.Env,
+		ClusterName: az,
+		AppName:     api.RedirectApp,
+		ServiceName: api.RedirectService,
+		RuntimeName: api.RedirectRuntime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if runtimeService == nil {
+		return nil, errors.Errorf("service %s/%s of runtime %s not found", api.RedirectApp, api.RedirectService, api.RedirectRuntime)
+	}
+	dto.RedirectRuntimeId = runtimeService.RuntimeId
+	dto.RedirectRuntimeName = runtimeService.RuntimeName
+	return dto, nil
+}
+
+func (e *executor) executeApi(change configbundle.Change) error {
+	service := (*e.impl.packageBiz).Clone(e.impl.reqCtx)
+	pack, api := change.Package, change.Api
+	if change.Action == configbundle.ACTION_DELETE {
+		_, err := service.DeletePackageApi(pack.Id, api.Id)
+		return err
+	}
+	dto, err := e.openapiDto(api)
+	if err != nil {
+		return err
+	}
+	if change.Action == configbundle.ACTION_UPDATE {
+		_, _, err = service.UpdatePackageApi(pack.Id, api.Id, dto)
+		return err
+	}
+	id, exists, err := service.CreatePackageApi(pack.Id, dto)
+	if exists {
+		return errors.Errorf("api %s already exists", api.Key())
+	}
+	if err != nil {
+		return err
+	}
+	api.Id = id
+	return nil
+}
+
+func (e *executor) executePolicy(change configbundle.Change) error {
+	service := (*e.impl.policyBiz).Clone(e.impl.reqCtx)
+	pack := change.Package
+	config := change.Config
+	apiId := ""
+	if change.Api != nil {
+		apiId = change.Api.Id
+	}
+	if change.Action == configbundle.ACTION_DELETE {
+		// the package policy is disabled, and the api policy inherits from the package
+		config = map[string]interface{}{"switch": false}
+		if change.Api != nil {
+			config = map[string]interface{}{}
+			for key, value := range pack.Policies[change.Category] {
+				config[key] = value
+			}
+			config["global"] = true
+		}
+	}
+	content, err := json.Marshal(config)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = service.SetPolicyConfig(change.Category, pack.Id, apiId, content)
+	return err
+}
+
+func (e *executor) executeAcl(change configbundle.Change) error {
+	service := (*e.impl.consumerBiz).Clone(e.impl.reqCtx)
+	var ids []string
+	for _, name := range change.Consumers {
+		id, ok := e.consumerIds[name]
+		if !ok {
+			return errors.Errorf("consumer %s not found", name)
+		}
+		ids = append(ids, id)
+	}
+	_, err := service.UpdatePackageAcls(change.Package.Id, &gw.PackageAclsDto{
+		Consumers: ids,
+	})
+	return err
+}
+
+func (e *executor) executeLimit(change configbundle.Change) error {
+	service := (*e.impl.ruleBiz).Clone(e.impl.reqCtx)
+	limit := change.Limit
+	if change.Action == configbundle.ACTION_DELETE {
+		_, err := service.DeleteLimitRule(limit.Id)
+		return err
+	}
+	pack, ok := e.packages[limit.Package]
+	if !ok {
+		return errors.Errorf("package %s not found", limit.Package)
+	}
+	dto := &gw.OpenLimitRuleDto{
+		ConsumerId: change.Consumer.Id,
+		PackageId:  pack.Id,
+		Method:     limit.Method,
+		ApiPath:    limit.ApiPath,
+		Limit:      limit.LimitType,
+	}
+	if change.Action == configbundle.ACTION_UPDATE {
+		_, err := service.UpdateLimitRule(limit.Id, dto)
+		return err
+	}
+	_, existed, err := service.CreateLimitRule(e.args, dto)
+	if existed {
+		return errors.Errorf("limit %s already exists", limit.Key())
+	}
+	return err
+}
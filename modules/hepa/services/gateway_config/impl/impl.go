@@ -0,0 +1,426 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/configbundle"
+	gw "github.com/erda-project/erda/modules/hepa/gateway/dto"
+	"github.com/erda-project/erda/modules/hepa/gateway/exdto"
+	"github.com/erda-project/erda/modules/hepa/repository/orm"
+	db "github.com/erda-project/erda/modules/hepa/repository/service"
+	"github.com/erda-project/erda/modules/hepa/services/api_policy"
+	"github.com/erda-project/erda/modules/hepa/services/domain"
+	"github.com/erda-project/erda/modules/hepa/services/endpoint_api"
+	"github.com/erda-project/erda/modules/hepa/services/gateway_config"
+	"github.com/erda-project/erda/modules/hepa/services/openapi_consumer"
+	"github.com/erda-project/erda/modules/hepa/services/openapi_rule"
+)
+
+// the built-in policy is maintained by hepa itself
+const builtInPolicy = "built-in"
+
+type GatewayConfigServiceImpl struct {
+	packageDb       db.GatewayPackageService
+	packageApiDb    db.GatewayPackageApiService
+	consumerDb      db.GatewayConsumerService
+	packageInDb     db.GatewayPackageInConsumerService
+	ruleDb          db.GatewayPackageRuleService
+	defaultPolicyDb db.GatewayDefaultPolicyService
+	ingressPolicyDb db.GatewayIngressPolicyService
+	runtimeDb       db.GatewayRuntimeServiceService
+	azDb            db.GatewayAzInfoService
+	packageBiz      *endpoint_api.GatewayOpenapiService
+	consumerBiz     *openapi_consumer.GatewayOpenapiConsumerService
+	ruleBiz         *openapi_rule.GatewayOpenapiRuleService
+	policyBiz       *api_policy.GatewayApiPolicyService
+	domainBiz       *domain.GatewayDomainService
+	reqCtx          context.Context
+}
+
+var once sync.Once
+
+// serialize the applies, the plan is computed from the current state
+var applyLock sync.Mutex
+
+func NewGatewayConfigServiceImpl() error {
+	once.Do(
+		func() {
+			packageDb, _ := db.NewGatewayPackageServiceImpl()
+			packageApiDb, _ := db.NewGatewayPackageApiServiceImpl()
+			consumerDb, _ := db.NewGatewayConsumerServiceImpl()
+			packageInDb, _ := db.NewGatewayPackageInConsumerServiceImpl()
+			ruleDb, _ := db.NewGatewayPackageRuleServiceImpl()
+			defaultPolicyDb, _ := db.NewGatewayDefaultPolicyServiceImpl()
+			ingressPolicyDb, _ := db.NewGatewayIngressPolicyServiceImpl()
+			runtimeDb, _ := db.NewGatewayRuntimeServiceServiceImpl()
+			azDb, _ := db.NewGatewayAzInfoServiceImpl()
+			gateway_config.Service = &GatewayConfigServiceImpl{
+				packageDb:       packageDb,
+				packageApiDb:    packageApiDb,
+				consumerDb:      consumerDb,
+				packageInDb:     packageInDb,
+				ruleDb:          ruleDb,
+				defaultPolicyDb: defaultPolicyDb,
+				ingressPolicyDb: ingressPolicyDb,
+				runtimeDb:       runtimeDb,
+				azDb:            azDb,
+				packageBiz:      &endpoint_api.Service,
+				consumerBiz:     &openapi_consumer.Service,
+				ruleBiz:         &openapi_rule.Service,
+				policyBiz:       &api_policy.Service,
+				domainBiz:       &domain.Service,
+			}
+		})
+	return nil
+}
+
+func (impl GatewayConfigServiceImpl) Clone(ctx context.Context) gateway_config.GatewayConfigService {
+	newService := impl
+	newService.reqCtx = ctx
+	return &newService
+}
+
+func (impl GatewayConfigServiceImpl) Export(orgId, projectId, env string) (result *configbundle.Bundle, err error) {
+	defer func() {
+		if err != nil {
+			log.Errorf("error happened, err:%+v", err)
+		}
+	}()
+	if projectId == "" || env == "" {
+		err = errors.New("projectId or env is empty")
+		return
+	}
+	az, err := impl.azDb.GetAz(&orm.GatewayAzInfo{
+		Env:       env,
+		OrgId:     orgId,
+		ProjectId: projectId,
+	})
+	if err != nil {
+		return
+	}
+	b := configbundle.New(projectId, env)
+	b.Metadata.ExportTime = time.Now().Format(time.RFC3339)
+	consumers, err := impl.consumerDb.SelectByAny(&orm.GatewayConsumer{
+		ProjectId: projectId,
+		Env:       env,
+		Az:        az,
+	})
+	if err != nil {
+		return
+	}
+	consumerNames := map[string]string{}
+	for _, consumer := range consumers {
+		// the consumers of org clients are managed by the clients
+		if consumer.Type == orm.APIM_CLIENT_CONSUMER {
+			continue
+		}
+		consumerNames[consumer.Id] = consumer.ConsumerName
+		b.Consumers = append(b.Consumers, configbundle.Consumer{
+			Id:          consumer.Id,
+			Name:        consumer.ConsumerName,
+			Description: consumer.Description,
+		})
+	}
+	packs, err := impl.packageDb.SelectByAny(&orm.GatewayPackage{
+		DiceProjectId:   projectId,
+		DiceEnv:         env,
+		DiceClusterName: az,
+	})
+	if err != nil {
+		return
+	}
+	packageNames := map[string]string{}
+	apis := map[string]*orm.GatewayPackageApi{}
+	for i := range packs {
+		pack := &packs[i]
+		// the packages of runtime services and tenants are created by hepa itself
+		if pack.RuntimeServiceId != "" || pack.Scene == orm.UNITY_SCENE {
+			continue
+		}
+		var exported *configbundle.Package
+		exported, err = impl.exportPackage(pack, consumerNames, apis)
+		if err != nil {
+			return
+		}
+		packageNames[pack.Id] = pack.PackageName
+		b.Packages = append(b.Packages, *exported)
+	}
+	for i := range b.Consumers {
+		consumer := &b.Consumers[i]
+		consumer.Limits, err = impl.exportLimits(consumer.Id, packageNames, apis)
+		if err != nil {
+			return
+		}
+	}
+	err = b.Normalize()
+	if err != nil {
+		return
+	}
+	result = b
+	return
+}
+
+func (impl GatewayConfigServiceImpl) exportPackage(pack *orm.GatewayPackage, consumerNames map[string]string,
+	apis map[string]*orm.GatewayPackageApi) (*configbundle.Package, error) {
+	domains, err := (*impl.domainBiz).GetPackageDomains(pack.Id)
+	if err != nil {
+		return nil, err
+	}
+	res := &configbundle.Package{
+		Id:          pack.Id,
+		Name:        pack.PackageName,
+		Description: pack.Description,
+		Scene:       pack.Scene,
+		BindDomain:  domains,
+		AuthType:    pack.AuthType,
+		AclType:     pack.AclType,
+	}
+	if pack.AuthConfig != "" {
+		err = json.Unmarshal([]byte(pack.AuthConfig), &res.AuthConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid auth config of package %s", pack.PackageName)
+		}
+	}
+	acls, err := impl.packageInDb.SelectByAny(&orm.GatewayPackageInConsumer{
+		PackageId: pack.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, acl := range acls {
+		if name, ok := consumerNames[acl.ConsumerId]; ok {
+			res.Consumers = append(res.Consumers, name)
+		}
+	}
+	defaultPolicies, err := impl.defaultPolicyDb.SelectByAny(&orm.GatewayDefaultPolicy{
+		Level:     orm.POLICY_PACKAGE_LEVEL,
+		PackageId: pack.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range defaultPolicies {
+		if policy.Name == builtInPolicy || len(policy.Config) == 0 {
+			continue
+		}
+		config, err := configbundle.ParsePolicyConfig(policy.Config)
+		if err != nil {
+			return nil, err
+		}
+		// the disabled policy is the same as the default
+		if !configbundle.PolicyEnabled(config) {
+			continue
+		}
+		if res.Policies == nil {
+			res.Policies = configbundle.Policies{}
+		}
+		res.Policies[policy.Name] = config
+	}
+	daos, err := impl.packageApiDb.SelectByAny(&orm.GatewayPackageApi{
+		PackageId: pack.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daos {
+		dao := &daos[i]
+		if dao.Origin != string(gw.FROM_CUSTOM) {
+			res.Managed = true
+			continue
+		}
+		api, err := impl.exportApi(dao)
+		if err != nil {
+			return nil, err
+		}
+		apis[dao.Id] = dao
+		res.Apis = append(res.Apis, *api)
+	}
+	return res, nil
+}
+
+func (impl GatewayConfigServiceImpl) exportApi(dao *orm.GatewayPackageApi) (*configbundle.Api, error) {
+	res := &configbundle.Api{
+		Id:            dao.Id,
+		Method:        dao.Method,
+		ApiPath:       dao.ApiPath,
+		Description:   dao.Description,
+		RedirectType:  dao.RedirectType,
+		AllowPassAuth: dao.AclType == gw.ACL_OFF,
+	}
+	if dao.Scopes != "" {
+		res.Scopes = strings.Split(dao.Scopes, ",")
+	}
+	if dao.RuntimeServiceId != "" {
+		runtimeService, err := impl.runtimeDb.Get(dao.RuntimeServiceId)
+		if err != nil {
+			return nil, err
+		}
+		if runtimeService == nil {
+			return nil, errors.Errorf("runtime service of api %s not found", dao.ApiPath)
+		}
+		res.RedirectPath = dao.RedirectPath
+		res.RedirectApp = runtimeService.AppName
+		res.RedirectService = runtimeService.ServiceName
+		res.RedirectRuntime = runtimeService.RuntimeName
+	} else if dao.RedirectAddr != "" {
+		// the redirect path is joined into the addr when created
+		res.RedirectAddr = dao.RedirectAddr
+		res.RedirectPath = "/"
+		if schemeIndex := strings.Index(res.RedirectAddr, "://"); schemeIndex != -1 {
+			if slashIndex := strings.Index(res.RedirectAddr[schemeIndex+3:], "/"); slashIndex != -1 {
+				res.RedirectPath = res.RedirectAddr[schemeIndex+3+slashIndex:]
+				res.RedirectAddr = res.RedirectAddr[:schemeIndex+3+slashIndex]
+			}
+		}
+	}
+	if dao.ZoneId == "" {
+		return res, nil
+	}
+	policies, err := impl.ingressPolicyDb.SelectByAny(&orm.GatewayIngressPolicy{
+		ZoneId: dao.ZoneId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		// empty config means inheriting from the package
+		if policy.Name == builtInPolicy || len(policy.Config) == 0 {
+			continue
+		}
+		config, err := configbundle.ParsePolicyConfig(policy.Config)
+		if err != nil {
+			return nil, err
+		}
+		if res.Policies == nil {
+			res.Policies = configbundle.Policies{}
+		}
+		res.Policies[policy.Name] = config
+	}
+	return res, nil
+}
+
+func (impl GatewayConfigServiceImpl) exportLimits(consumerId string, packageNames map[string]string,
+	apis map[string]*orm.GatewayPackageApi) ([]configbundle.Limit, error) {
+	rules, err := impl.ruleDb.SelectByAny(&orm.GatewayPackageRule{
+		ConsumerId: consumerId,
+		Category:   string(gw.LIMIT_RULE),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var res []configbundle.Limit
+	for _, rule := range rules {
+		packageName, ok := packageNames[rule.PackageId]
+		if !ok {
+			continue
+		}
+		limit := configbundle.Limit{
+			Id:        rule.Id,
+			Package:   packageName,
+			LimitType: config2Limit(rule.Config),
+		}
+		if rule.ApiId != "" {
+			api, ok := apis[rule.ApiId]
+			if !ok {
+				continue
+			}
+			limit.Method = api.Method
+			limit.ApiPath = api.ApiPath
+		}
+		res = append(res, limit)
+	}
+	return res, nil
+}
+
+func config2Limit(config []byte) exdto.LimitType {
+	res := exdto.LimitType{}
+	var configMap map[string]interface{}
+	if len(config) != 0 {
+		err := json.Unmarshal(config, &configMap)
+		if err != nil {
+			log.Errorf("json unmarshal failed, config:%s, err:%s", config, err)
+		}
+	}
+	for key, value := range configMap {
+		number, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		limit := int(number)
+		switch key {
+		case "day":
+			res.Day = &limit
+		case "hour":
+			res.Hour = &limit
+		case "minute":
+			res.Minute = &limit
+		case "second":
+			res.Second = &limit
+		}
+	}
+	return res
+}
+
+func (impl GatewayConfigServiceImpl) Apply(orgId, projectId, env string, bundle *configbundle.Bundle, dryRun, prune bool) (plan *configbundle.Plan, err error) {
+	defer func() {
+		if err != nil {
+			log.Errorf("error happened, err:%+v", err)
+		}
+	}()
+	applyLock.Lock()
+	defer applyLock.Unlock()
+	current, err := impl.Export(orgId, projectId, env)
+	if err != nil {
+		return
+	}
+	plan = configbundle.Diff(current, bundle, prune)
+	if dryRun || plan.Empty() {
+		return
+	}
+	e := &executor{
+		impl: impl,
+		args: &gw.DiceArgsDto{
+			OrgId:     orgId,
+			ProjectId: projectId,
+			Env:       env,
+		},
+		packages:    map[string]*configbundle.Package{},
+		consumerIds: map[string]string{},
+	}
+	for i := range current.Consumers {
+		e.consumerIds[current.Consumers[i].Name] = current.Consumers[i].Id
+	}
+	for i := range bundle.Packages {
+		e.packages[bundle.Packages[i].Name] = &bundle.Packages[i]
+	}
+	for _, change := range plan.Changes {
+		err = e.execute(change)
+		if err != nil {
+			err = errors.WithMessagef(err, "%s %s %s failed", change.Action, change.Kind, change.Name)
+			return
+		}
+		log.Infof("gateway config of %s/%s changed: %s %s %s", projectId, env, change.Action, change.Kind, change.Name)
+	}
+	return
+}
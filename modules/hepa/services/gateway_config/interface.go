@@ -0,0 +1,30 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway_config
+
+import (
+	"context"
+
+	"github.com/erda-project/erda/modules/hepa/gateway/configbundle"
+)
+
+var Service GatewayConfigService
+
+type GatewayConfigService interface {
+	Clone(context.Context) GatewayConfigService
+	Export(orgId, projectId, env string) (*configbundle.Bundle, error)
+	// Apply changes the environment to the bundle, only returns the plan if dryRun
+	Apply(orgId, projectId, env string, bundle *configbundle.Bundle, dryRun, prune bool) (*configbundle.Plan, error)
+}
@@ -410,7 +410,7 @@ func (impl *GatewayGlobalServiceImpl) CreateTenant(tenant *gw.TenantDto) (result
 			Env:             tenant.Env,
 			ProjectId:       tenant.ProjectId,
 			ProjectName:     tenant.ProjectName,
-			KongAddr:        tenant.AdminAddr,
+			KongAddr:        kong.AdminAddr(tenant.Az, tenant.AdminAddr),
 			Endpoint:        tenant.GatewayEndpoint,
 			InnerAddr:       tenant.InnerAddr,
 			ServiceName:     tenant.ServiceName,
@@ -427,7 +427,7 @@ func (impl *GatewayGlobalServiceImpl) CreateTenant(tenant *gw.TenantDto) (result
 			Env:             tenant.Env,
 			ProjectId:       tenant.ProjectId,
 			ProjectName:     tenant.ProjectName,
-			KongAddr:        tenant.AdminAddr,
+			KongAddr:        kong.AdminAddr(tenant.Az, tenant.AdminAddr),
 			Endpoint:        tenant.GatewayEndpoint,
 			InnerAddr:       tenant.InnerAddr,
 			ServiceName:     tenant.ServiceName,
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +29,7 @@ import (
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/modules/hepa/bundle"
 	"github.com/erda-project/erda/modules/hepa/common"
+	"github.com/erda-project/erda/modules/hepa/common/jwks"
 	"github.com/erda-project/erda/modules/hepa/common/util"
 	gw "github.com/erda-project/erda/modules/hepa/gateway/dto"
 	"github.com/erda-project/erda/modules/hepa/kong"
@@ -39,19 +41,26 @@ import (
 )
 
 type GatewayOpenapiConsumerServiceImpl struct {
-	packageDb      db.GatewayPackageService
-	packageApiDb   db.GatewayPackageApiService
-	consumerDb     db.GatewayConsumerService
-	azDb           db.GatewayAzInfoService
-	kongDb         db.GatewayKongInfoService
-	packageInDb    db.GatewayPackageInConsumerService
-	packageApiInDb db.GatewayPackageApiInConsumerService
-	ruleBiz        *openapi_rule.GatewayOpenapiRuleService
-	reqCtx         context.Context
+	packageDb       db.GatewayPackageService
+	packageApiDb    db.GatewayPackageApiService
+	consumerDb      db.GatewayConsumerService
+	azDb            db.GatewayAzInfoService
+	kongDb          db.GatewayKongInfoService
+	packageInDb     db.GatewayPackageInConsumerService
+	packageApiInDb  db.GatewayPackageApiInConsumerService
+	jwtCredentialDb db.GatewayJwtCredentialService
+	jwks            *jwks.Cache
+	ruleBiz         *openapi_rule.GatewayOpenapiRuleService
+	reqCtx          context.Context
 }
 
 var once sync.Once
 
+const (
+	jwksCacheTTL    = 10 * time.Minute
+	jwksRefreshLock = "hepa-jwks-refresh"
+)
+
 func NewGatewayOpenapiConsumerServiceImpl() error {
 	once.Do(
 		func() {
@@ -62,16 +71,26 @@ func NewGatewayOpenapiConsumerServiceImpl() error {
 			kongDb, _ := db.NewGatewayKongInfoServiceImpl()
 			packageInDb, _ := db.NewGatewayPackageInConsumerServiceImpl()
 			packageApiInDb, _ := db.NewGatewayPackageApiInConsumerServiceImpl()
-			openapi_consumer.Service = &GatewayOpenapiConsumerServiceImpl{
-				consumerDb:     consumerDb,
-				azDb:           azDb,
-				kongDb:         kongDb,
-				packageInDb:    packageInDb,
-				packageApiInDb: packageApiInDb,
-				packageDb:      packageDb,
-				packageApiDb:   packageApiDb,
-				ruleBiz:        &openapi_rule.Service,
+			jwtCredentialDb, _ := db.NewGatewayJwtCredentialServiceImpl()
+			impl := &GatewayOpenapiConsumerServiceImpl{
+				consumerDb:      consumerDb,
+				azDb:            azDb,
+				kongDb:          kongDb,
+				packageInDb:     packageInDb,
+				packageApiInDb:  packageApiInDb,
+				packageDb:       packageDb,
+				packageApiDb:    packageApiDb,
+				jwtCredentialDb: jwtCredentialDb,
+				jwks:            jwks.NewCache(jwksCacheTTL),
+				ruleBiz:         &openapi_rule.Service,
 			}
+			openapi_consumer.Service = impl
+			go func() {
+				defer util.DoRecover()
+				for range time.Tick(jwksCacheTTL) {
+					impl.refreshJwtCredentials()
+				}
+			}()
 		})
 	return nil
 }
@@ -114,14 +133,67 @@ func (impl GatewayOpenapiConsumerServiceImpl) getCredentialList(kongAdapter kong
 			Data: []kongDto.KongCredentialDto{},
 		}
 	}
+	jCredentials, err := kongAdapter.GetCredentialList(consumerId, orm.JWTAUTH)
+	if err != nil {
+		jCredentials = &kongDto.KongCredentialListDto{
+			Data: []kongDto.KongCredentialDto{},
+		}
+	}
+	impl.fillJwtCredentials(consumerId, jCredentials)
 	return map[string]kongDto.KongCredentialListDto{
 		orm.KEYAUTH:  *kCredentials,
 		orm.OAUTH2:   *oCredentials,
 		orm.SIGNAUTH: *sCredentials,
 		orm.HMACAUTH: *hCredentials,
+		orm.JWTAUTH:  *jCredentials,
 	}, nil
 }
 
+// fillJwtCredentials fills the issuer and jwks of the jwt credentials, which are kept by hepa
+func (impl GatewayOpenapiConsumerServiceImpl) fillJwtCredentials(consumerId string, credentials *kongDto.KongCredentialListDto) {
+	if len(credentials.Data) == 0 {
+		return
+	}
+	daos, err := impl.jwtCredentialDb.SelectByConsumer(consumerId)
+	if err != nil {
+		log.Errorf("get jwt credentials failed, consumer:%s, err:%+v", consumerId, err)
+		return
+	}
+	for i := range credentials.Data {
+		for _, dao := range daos {
+			if dao.CredentialId == credentials.Data[i].Id {
+				credentials.Data[i].Issuer = dao.Issuer
+				credentials.Data[i].JwksUri = dao.JwksUri
+				if dao.IsPinned {
+					credentials.Data[i].KeyId = dao.KeyId
+				}
+				break
+			}
+		}
+	}
+}
+
+// resolveJwtKey resolves the public key of the jwt credential from the jwks of issuer,
+// the credential is left as it is if neither issuer nor jwks uri is given
+func (impl GatewayOpenapiConsumerServiceImpl) resolveJwtKey(config *kongDto.KongCredentialDto) error {
+	if config.Issuer == "" && config.JwksUri == "" {
+		if config.RsaPublicKey != "" && config.Algorithm == "" {
+			config.Algorithm = "RS256"
+		}
+		return nil
+	}
+	publicKey, algorithm, err := impl.jwks.Resolve(config.Issuer, config.JwksUri, config.KeyId)
+	if err != nil {
+		return err
+	}
+	config.RsaPublicKey = publicKey
+	config.Algorithm = algorithm
+	if config.Key == "" {
+		config.Key = config.Issuer
+	}
+	return nil
+}
+
 func (impl GatewayOpenapiConsumerServiceImpl) createCredential(kongAdapter kong.KongAdapter, pluginName string, consumerId string, config *kongDto.KongCredentialDto) (*kongDto.KongCredentialDto, error) {
 	req := &kongDto.KongCredentialReqDto{}
 	req.ConsumerId = consumerId
@@ -136,7 +208,260 @@ func (impl GatewayOpenapiConsumerServiceImpl) createCredential(kongAdapter kong.
 			return &kongDto.KongCredentialDto{}, nil
 		}
 	}
-	return kongAdapter.CreateCredential(req)
+	if pluginName != orm.JWTAUTH {
+		return kongAdapter.CreateCredential(req)
+	}
+	if (config.Issuer != "" || config.JwksUri != "") && config.KeyId == "" {
+		return impl.createJwksCredentials(kongAdapter, consumerId, config)
+	}
+	err := impl.resolveJwtKey(config)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := kongAdapter.CreateCredential(req)
+	if err != nil {
+		return nil, err
+	}
+	if config.Issuer != "" || config.JwksUri != "" {
+		err = impl.jwtCredentialDb.Insert(&orm.GatewayJwtCredential{
+			ConsumerId:   consumerId,
+			CredentialId: resp.Id,
+			Issuer:       config.Issuer,
+			JwksUri:      config.JwksUri,
+			KeyId:        config.KeyId,
+			IsPinned:     true,
+		})
+		if err != nil {
+			_ = kongAdapter.DeleteCredential(consumerId, pluginName, resp.Id)
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// createJwksCredentials creates a credential for each signing key of the jwks,
+// since kong verifies a credential with only one key, the credentials are keyed
+// by kid and the package should take kid as the consumer claim
+func (impl GatewayOpenapiConsumerServiceImpl) createJwksCredentials(kongAdapter kong.KongAdapter, consumerId string, config *kongDto.KongCredentialDto) (*kongDto.KongCredentialDto, error) {
+	keys, err := impl.jwks.ResolveAll(config.Issuer, config.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+	defaultKey := config.Key
+	if defaultKey == "" {
+		defaultKey = config.Issuer
+	}
+	var created []*kongDto.KongCredentialDto
+	for _, key := range keys {
+		resp, err := impl.createJwksCredential(kongAdapter, consumerId, config.Issuer, config.JwksUri, defaultKey, key)
+		if err != nil {
+			for _, credential := range created {
+				_ = kongAdapter.DeleteCredential(consumerId, orm.JWTAUTH, credential.Id)
+				_ = impl.jwtCredentialDb.DeleteByCredential(consumerId, credential.Id)
+			}
+			return nil, err
+		}
+		created = append(created, resp)
+	}
+	return created[0], nil
+}
+
+// createJwksCredential creates the credential of a signing key, defaultKey is
+// used if the key has no kid
+func (impl GatewayOpenapiConsumerServiceImpl) createJwksCredential(kongAdapter kong.KongAdapter, consumerId, issuer, jwksUri, defaultKey string, key jwks.PublicKey) (*kongDto.KongCredentialDto, error) {
+	credentialKey := key.Kid
+	if credentialKey == "" {
+		credentialKey = defaultKey
+	}
+	resp, err := kongAdapter.CreateCredential(&kongDto.KongCredentialReqDto{
+		ConsumerId: consumerId,
+		PluginName: orm.JWTAUTH,
+		Config: &kongDto.KongCredentialDto{
+			Key:          credentialKey,
+			Algorithm:    key.Algorithm,
+			RsaPublicKey: key.Pem,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = impl.jwtCredentialDb.Insert(&orm.GatewayJwtCredential{
+		ConsumerId:   consumerId,
+		CredentialId: resp.Id,
+		Issuer:       issuer,
+		JwksUri:      jwksUri,
+		KeyId:        key.Kid,
+	})
+	if err != nil {
+		_ = kongAdapter.DeleteCredential(consumerId, orm.JWTAUTH, resp.Id)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// refreshJwtCredentials re-resolves the public keys of the jwt credentials
+// from the jwks, so the keys rotated by the issuers take effect in kong, it
+// runs under a mysql lock so that only one replica refreshes at a time
+func (impl GatewayOpenapiConsumerServiceImpl) refreshJwtCredentials() {
+	session, err := db.NewSessionHelper()
+	if err != nil {
+		log.Errorf("refresh jwt credentials failed, err:%+v", err)
+		return
+	}
+	defer session.Close()
+	locked, err := session.TryLock(jwksRefreshLock)
+	if err != nil {
+		log.Errorf("lock jwt credentials refresh failed, err:%+v", err)
+		return
+	}
+	if !locked {
+		log.Debug("jwt credentials are being refreshed by another replica")
+		return
+	}
+	defer func() {
+		if err := session.Unlock(jwksRefreshLock); err != nil {
+			log.Errorf("unlock jwt credentials refresh failed, err:%+v", err)
+		}
+	}()
+	daos, err := impl.jwtCredentialDb.SelectAll()
+	if err != nil {
+		log.Errorf("get jwt credentials failed, err:%+v", err)
+		return
+	}
+	var sources []string
+	groups := map[string][]orm.GatewayJwtCredential{}
+	for _, dao := range daos {
+		if dao.IsPinned {
+			if err = impl.refreshJwtCredential(dao); err != nil {
+				log.Errorf("refresh jwt credential failed, consumer:%s, credential:%s, err:%+v",
+					dao.ConsumerId, dao.CredentialId, err)
+			}
+			continue
+		}
+		source := strings.Join([]string{dao.ConsumerId, dao.Issuer, dao.JwksUri}, " ")
+		if _, ok := groups[source]; !ok {
+			sources = append(sources, source)
+		}
+		groups[source] = append(groups[source], dao)
+	}
+	for _, source := range sources {
+		if err = impl.refreshJwksCredentials(groups[source]); err != nil {
+			log.Errorf("refresh jwks credentials failed, consumer:%s, issuer:%s, jwks uri:%s, err:%+v",
+				groups[source][0].ConsumerId, groups[source][0].Issuer, groups[source][0].JwksUri, err)
+		}
+	}
+}
+
+func (impl GatewayOpenapiConsumerServiceImpl) jwtCredentialAdapter(consumerId string) (kong.KongAdapter, map[string]kongDto.KongCredentialDto, error) {
+	consumer, err := impl.consumerDb.GetByAny(&orm.GatewayConsumer{ConsumerId: consumerId})
+	if err != nil {
+		return nil, nil, err
+	}
+	if consumer == nil {
+		return nil, nil, nil
+	}
+	kongAdapter := kong.NewKongAdapterForConsumer(consumer)
+	if kongAdapter == nil {
+		return nil, nil, errors.New("kong can't be attached")
+	}
+	credentials, err := kongAdapter.GetCredentialList(consumerId, orm.JWTAUTH)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := map[string]kongDto.KongCredentialDto{}
+	for _, credential := range credentials.Data {
+		res[credential.Id] = credential
+	}
+	return kongAdapter, res, nil
+}
+
+func (impl GatewayOpenapiConsumerServiceImpl) updateJwtKey(kongAdapter kong.KongAdapter, consumerId string, credential kongDto.KongCredentialDto, publicKey, algorithm string) error {
+	if publicKey == credential.RsaPublicKey && algorithm == credential.Algorithm {
+		return nil
+	}
+	_, err := kongAdapter.UpdateCredential(&kongDto.KongCredentialReqDto{
+		ConsumerId: consumerId,
+		PluginName: orm.JWTAUTH,
+		Config: &kongDto.KongCredentialDto{
+			Id:           credential.Id,
+			Algorithm:    algorithm,
+			RsaPublicKey: publicKey,
+		},
+	})
+	return err
+}
+
+// refreshJwtCredential refreshes the credential whose key id is pinned
+func (impl GatewayOpenapiConsumerServiceImpl) refreshJwtCredential(dao orm.GatewayJwtCredential) error {
+	kongAdapter, credentials, err := impl.jwtCredentialAdapter(dao.ConsumerId)
+	if err != nil || kongAdapter == nil {
+		return err
+	}
+	credential, ok := credentials[dao.CredentialId]
+	if !ok {
+		return nil
+	}
+	config := &kongDto.KongCredentialDto{
+		Key:     credential.Key,
+		Issuer:  dao.Issuer,
+		JwksUri: dao.JwksUri,
+		KeyId:   dao.KeyId,
+	}
+	if err = impl.resolveJwtKey(config); err != nil {
+		return err
+	}
+	return impl.updateJwtKey(kongAdapter, dao.ConsumerId, credential, config.RsaPublicKey, config.Algorithm)
+}
+
+// refreshJwksCredentials keeps a credential for each signing key currently in
+// the jwks, the credentials of the new keys are created and the ones of the
+// keys removed from the jwks are deleted, daos are the credentials of the same
+// consumer, issuer and jwks uri
+func (impl GatewayOpenapiConsumerServiceImpl) refreshJwksCredentials(daos []orm.GatewayJwtCredential) error {
+	consumerId, issuer, jwksUri := daos[0].ConsumerId, daos[0].Issuer, daos[0].JwksUri
+	kongAdapter, credentials, err := impl.jwtCredentialAdapter(consumerId)
+	if err != nil || kongAdapter == nil {
+		return err
+	}
+	// the credentials are kept as they are if the jwks is unavailable
+	keys, err := impl.jwks.ResolveAll(issuer, jwksUri)
+	if err != nil {
+		return err
+	}
+	stale := map[string]orm.GatewayJwtCredential{}
+	for _, dao := range daos {
+		stale[dao.KeyId] = dao
+	}
+	for _, key := range keys {
+		dao, ok := stale[key.Kid]
+		if ok {
+			delete(stale, key.Kid)
+			if credential, exist := credentials[dao.CredentialId]; exist {
+				if err = impl.updateJwtKey(kongAdapter, consumerId, credential, key.Pem, key.Algorithm); err != nil {
+					return err
+				}
+				continue
+			}
+			// the credential has been removed from kong, create it again
+			if err = impl.jwtCredentialDb.DeleteByCredential(consumerId, dao.CredentialId); err != nil {
+				return err
+			}
+		}
+		if _, err = impl.createJwksCredential(kongAdapter, consumerId, issuer, jwksUri, issuer, key); err != nil {
+			return err
+		}
+	}
+	for _, dao := range stale {
+		if _, exist := credentials[dao.CredentialId]; exist {
+			if err = kongAdapter.DeleteCredential(consumerId, orm.JWTAUTH, dao.CredentialId); err != nil {
+				return err
+			}
+		}
+		if err = impl.jwtCredentialDb.DeleteByCredential(consumerId, dao.CredentialId); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (impl GatewayOpenapiConsumerServiceImpl) CreateClientConsumer(clientName, clientId, clientSecret, clusterName string) (consumer *orm.GatewayConsumer, err error) {
@@ -597,6 +922,11 @@ func (impl GatewayOpenapiConsumerServiceImpl) GetConsumerCredentials(id string)
 					AuthType: orm.HMACAUTH,
 					AuthData: credentialListMap[orm.HMACAUTH],
 				},
+				{
+					AuthTips: orm.JwtAuthTips,
+					AuthType: orm.JWTAUTH,
+					AuthData: credentialListMap[orm.JWTAUTH],
+				},
 			},
 		},
 	}
@@ -688,6 +1018,12 @@ func (impl GatewayOpenapiConsumerServiceImpl) UpdateConsumerCredentials(id strin
 			if err != nil {
 				return
 			}
+			if authType == orm.JWTAUTH {
+				err = impl.jwtCredentialDb.DeleteByCredential(consumer.ConsumerId, credential.Id)
+				if err != nil {
+					return
+				}
+			}
 		}
 	}
 	for authType, credentials := range adds {
@@ -759,6 +1095,11 @@ func (impl GatewayOpenapiConsumerServiceImpl) UpdateConsumerCredentials(id strin
 					AuthType: orm.HMACAUTH,
 					AuthData: credentialListMap[orm.HMACAUTH],
 				},
+				{
+					AuthTips: orm.JwtAuthTips,
+					AuthType: orm.JWTAUTH,
+					AuthData: credentialListMap[orm.JWTAUTH],
+				},
 			},
 		},
 	}
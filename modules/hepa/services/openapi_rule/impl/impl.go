@@ -24,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	trafficsplit "github.com/erda-project/erda/modules/hepa/apipolicy/policies/traffic-split"
 	"github.com/erda-project/erda/modules/hepa/common"
 	gw "github.com/erda-project/erda/modules/hepa/gateway/dto"
 	"github.com/erda-project/erda/modules/hepa/gateway/exdto"
@@ -260,6 +261,10 @@ func (impl GatewayOpenapiRuleServiceImpl) setPackageApiKongPolicies(basePriority
 			if err != nil {
 				return false, err
 			}
+			err = impl.syncTrafficSplitRoutes(packageApi.ZoneId, helper)
+			if err != nil {
+				return false, err
+			}
 			return true, nil
 		}
 		return false, nil
@@ -299,9 +304,33 @@ func (impl GatewayOpenapiRuleServiceImpl) setPackageApiKongPolicies(basePriority
 	if err != nil {
 		return false, err
 	}
+	err = impl.syncTrafficSplitRoutes(packageApi.ZoneId, helper)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
+// syncTrafficSplitRoutes binds the current plugins of the api to the routes of its traffic split rules
+func (impl GatewayOpenapiRuleServiceImpl) syncTrafficSplitRoutes(zoneId string, helper *db.SessionHelper) error {
+	zone, err := (*impl.zoneBiz).GetZone(zoneId, helper)
+	if err != nil {
+		return err
+	}
+	if zone == nil {
+		return nil
+	}
+	kongInfo, err := impl.kongDb.GetKongInfo(&orm.GatewayKongInfo{
+		Az:        zone.DiceClusterName,
+		ProjectId: zone.DiceProjectId,
+		Env:       zone.DiceEnv,
+	})
+	if err != nil {
+		return err
+	}
+	return trafficsplit.SyncRulePlugins(kong.NewKongAdapter(kongInfo.KongAddr), zone)
+}
+
 func (impl GatewayOpenapiRuleServiceImpl) disableAclRulesOnAliyunApp(rules []gw.OpenapiRuleInfo) []gw.OpenapiRuleInfo {
 	authIndex := -1
 	aclIndex := -1
@@ -802,6 +831,9 @@ func (impl GatewayOpenapiRuleServiceImpl) createOrUpdateKongPlugin(adapter kong.
 	if err != nil {
 		return "", err
 	}
+	if resp == nil {
+		return "", errors.Errorf("%s plugin is not enabled", req.Name)
+	}
 	return resp.Id, nil
 }
 
@@ -22,6 +22,8 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/jinzhu/gorm"
+
 	"github.com/erda-project/erda-proto-go/core/messenger/notifygroup/pb"
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/bundle"
@@ -36,6 +38,7 @@ type notifyGroupService struct {
 	Permission  *permission.Permission
 	NotifyGroup *notify.NotifyGroup
 	bdl         *bundle.Bundle
+	db          *gorm.DB
 }
 
 func (n *notifyGroupService) BatchGetNotifyGroup(ctx context.Context, request *pb.BatchGetNotifyGroupRequest) (*pb.BatchGetNotifyGroupResponse, error) {
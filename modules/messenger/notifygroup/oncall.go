@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifygroup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notifygroup/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/common/errors"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// NotifyGroupOnCall is the on-call rotation of a notify group.
+type NotifyGroupOnCall struct {
+	ID            string    `gorm:"column:id;primary_key"`
+	OrgID         int64     `gorm:"column:org_id"`
+	OrgName       string    `gorm:"column:org_name"`
+	NotifyGroupID int64     `gorm:"column:notify_group_id"`
+	UserIDs       string    `gorm:"column:user_ids"`
+	ShiftMinutes  int64     `gorm:"column:shift_minutes"`
+	StartAt       time.Time `gorm:"column:start_at"`
+	Creator       string    `gorm:"column:creator"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+	SoftDeletedAt int64     `gorm:"column:soft_deleted_at"`
+}
+
+// TableName .
+func (NotifyGroupOnCall) TableName() string { return "erda_notify_group_oncall" }
+
+// OnCallAt returns the user on call at t and the end time of the shift.
+// The users take turns in order, every shift lasts shiftMinutes from startAt.
+func OnCallAt(userIDs []string, shiftMinutes int64, startAt, t time.Time) (string, time.Time) {
+	if len(userIDs) == 0 || shiftMinutes <= 0 {
+		return "", time.Time{}
+	}
+	shift := time.Duration(shiftMinutes) * time.Minute
+	if t.Before(startAt) {
+		return userIDs[0], startAt.Add(shift)
+	}
+	n := int64(t.Sub(startAt) / shift)
+	return userIDs[n%int64(len(userIDs))], startAt.Add(time.Duration(n+1) * shift)
+}
+
+func (n *notifyGroupService) SetNotifyGroupOnCall(ctx context.Context, request *pb.SetNotifyGroupOnCallRequest) (*pb.SetNotifyGroupOnCallResponse, error) {
+	orgId, err := strconv.ParseInt(apis.GetOrgID(ctx), 10, 64)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	notifyGroup, err := n.NotifyGroup.Get(request.GroupID, orgId)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	userIdStr := apis.GetUserID(ctx)
+	err = n.checkNotifyPermission(userIdStr, notifyGroup.ScopeType, notifyGroup.ScopeID, apistructs.UpdateAction)
+	if err != nil {
+		return nil, errors.NewPermissionError(apistructs.NotifyResource, apistructs.UpdateAction, err.Error())
+	}
+	if len(request.UserIDs) == 0 {
+		return nil, errors.NewMissingParameterError("userIDs")
+	}
+	if request.ShiftMinutes <= 0 {
+		return nil, errors.NewInvalidParameterError("shiftMinutes", "must be greater than 0")
+	}
+	for _, id := range request.UserIDs {
+		if len(id) == 0 || strings.Contains(id, ",") {
+			return nil, errors.NewInvalidParameterError("userIDs", fmt.Sprintf("invalid user id %q", id))
+		}
+	}
+	startAt := time.Now()
+	if request.StartAt > 0 {
+		startAt = time.Unix(request.StartAt/1e3, request.StartAt%1e3*1e6)
+	}
+
+	oncall, err := n.getOnCall(request.GroupID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	if oncall == nil {
+		oncall = &NotifyGroupOnCall{
+			ID:            uuid.UUID(),
+			OrgID:         orgId,
+			NotifyGroupID: request.GroupID,
+			Creator:       userIdStr,
+		}
+	}
+	oncall.UserIDs = strings.Join(request.UserIDs, ",")
+	oncall.ShiftMinutes = request.ShiftMinutes
+	oncall.StartAt = startAt
+	if err := n.db.Save(oncall).Error; err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &pb.SetNotifyGroupOnCallResponse{Data: toPBOnCall(oncall, time.Now())}, nil
+}
+
+func (n *notifyGroupService) GetNotifyGroupOnCall(ctx context.Context, request *pb.GetNotifyGroupOnCallRequest) (*pb.GetNotifyGroupOnCallResponse, error) {
+	if !apis.IsInternalClient(ctx) {
+		orgId, err := strconv.ParseInt(apis.GetOrgID(ctx), 10, 64)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err)
+		}
+		notifyGroup, err := n.NotifyGroup.Get(request.GroupID, orgId)
+		if err != nil {
+			return nil, errors.NewInternalServerError(err)
+		}
+		err = n.checkNotifyPermission(apis.GetUserID(ctx), notifyGroup.ScopeType, notifyGroup.ScopeID, apistructs.GetAction)
+		if err != nil {
+			return nil, errors.NewPermissionError(apistructs.NotifyResource, apistructs.GetAction, err.Error())
+		}
+	}
+	oncall, err := n.getOnCall(request.GroupID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	if oncall == nil {
+		return &pb.GetNotifyGroupOnCallResponse{}, nil
+	}
+	at := time.Now()
+	if request.At > 0 {
+		at = time.Unix(request.At/1e3, request.At%1e3*1e6)
+	}
+	return &pb.GetNotifyGroupOnCallResponse{Data: toPBOnCall(oncall, at)}, nil
+}
+
+func (n *notifyGroupService) getOnCall(groupID int64) (*NotifyGroupOnCall, error) {
+	var oncall NotifyGroupOnCall
+	err := n.db.Where("notify_group_id=? AND soft_deleted_at=0", groupID).First(&oncall).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &oncall, nil
+}
+
+func toPBOnCall(oncall *NotifyGroupOnCall, at time.Time) *pb.NotifyGroupOnCall {
+	userIDs := strings.Split(oncall.UserIDs, ",")
+	user, shiftEnd := OnCallAt(userIDs, oncall.ShiftMinutes, oncall.StartAt, at)
+	return &pb.NotifyGroupOnCall{
+		GroupID:      oncall.NotifyGroupID,
+		UserIDs:      userIDs,
+		ShiftMinutes: oncall.ShiftMinutes,
+		StartAt:      oncall.StartAt.UnixNano() / 1e6,
+		CurrentUser:  user,
+		ShiftEndAt:   shiftEnd.UnixNano() / 1e6,
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifygroup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnCallAt(t *testing.T) {
+	users := []string{"1", "2", "3"}
+	start := time.Date(2022, 6, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at       time.Time
+		want     string
+		wantEnds time.Time
+	}{
+		{at: start.Add(-time.Hour), want: "1", wantEnds: start.Add(24 * time.Hour)},
+		{at: start, want: "1", wantEnds: start.Add(24 * time.Hour)},
+		{at: start.Add(25 * time.Hour), want: "2", wantEnds: start.Add(48 * time.Hour)},
+		{at: start.Add(72 * time.Hour), want: "1", wantEnds: start.Add(96 * time.Hour)},
+	}
+	for _, tt := range tests {
+		got, ends := OnCallAt(users, 24*60, start, tt.at)
+		if got != tt.want || !ends.Equal(tt.wantEnds) {
+			t.Errorf("OnCallAt(%s) = %s, %s, want %s, %s", tt.at, got, ends, tt.want, tt.wantEnds)
+		}
+	}
+	if got, _ := OnCallAt(nil, 60, start, start); got != "" {
+		t.Errorf("OnCallAt() without users = %q, want empty", got)
+	}
+}
@@ -46,7 +46,7 @@ type provider struct {
 
 func (p *provider) Init(ctx servicehub.Context) error {
 	p.audit = audit.GetAuditor(ctx)
-	p.notifyGroupService = &notifyGroupService{}
+	p.notifyGroupService = &notifyGroupService{db: p.DB}
 	pm := permission.New(permission.WithDBClient(&dao.DBClient{
 		DB: p.DB,
 	}))
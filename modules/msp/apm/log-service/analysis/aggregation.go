@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/recallsong/go-utils/errorx"
+
+	logs "github.com/erda-project/erda/modules/core/monitor/log"
+	metrics "github.com/erda-project/erda/modules/core/monitor/metric"
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/aggregation"
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors"
+)
+
+// aggregationKeepTags are the tags kept by the aggregated log metrics besides the group tags,
+// the others such as pod and container are dropped to limit the series.
+var aggregationKeepTags = map[string]bool{
+	"_meta":                 true,
+	"_metric_scope":         true,
+	"_metric_scope_id":      true,
+	"monitor_log_key":       true,
+	"msp_env_id":            true,
+	"terminus_key":          true,
+	"org_name":              true,
+	"cluster_name":          true,
+	"dice_org_id":           true,
+	"dice_org_name":         true,
+	"dice_project_id":       true,
+	"dice_project_name":     true,
+	"dice_application_id":   true,
+	"dice_application_name": true,
+	"dice_runtime_id":       true,
+	"dice_runtime_name":     true,
+	"dice_service_name":     true,
+	"dice_workspace":        true,
+}
+
+// aggregationInstanceTag is the tag of the instance which aggregates the log metrics.
+const aggregationInstanceTag = "_aggregation_instance"
+
+func (p *provider) aggregate(ap processors.AggregatedProcessor, processor processors.Processor, log *logs.Log) {
+	var (
+		name   string
+		fields map[string]interface{}
+		err    error
+	)
+	if tp, ok := processor.(processors.TagsProcessor); ok {
+		name, fields, _, _, err = tp.ProcessWithTags(log.Content, log.Tags)
+	} else {
+		name, fields, _, _, err = processor.Process(log.Content)
+	}
+	if err != nil {
+		return
+	}
+	agg := ap.Aggregation()
+	ok := p.window.Add(&aggregation.Point{
+		Name:      name,
+		Timestamp: log.Timestamp,
+		Tags:      log.Tags,
+		Fields:    fields,
+	}, agg.Aggregator, agg.GroupBy, aggregationKeepTags)
+	if !ok {
+		atomic.AddInt64(&p.latePoints, 1)
+	}
+}
+
+// flushAggregation writes the aggregated points with the instance tag, so that the partial aggregations
+// of the same window from different instances are not overwritten, and are merged by <field>_sum and <field>_count.
+func (p *provider) flushAggregation(now time.Time) error {
+	if n := atomic.SwapInt64(&p.latePoints, 0); n > 0 {
+		p.L.Warnf("drop %d late logs of the flushed aggregation windows", n)
+	}
+	var errs errorx.Errors
+	for _, point := range p.window.Flush(now) {
+		point.Tags[aggregationInstanceTag] = p.instance
+		err := p.output.Write(&metrics.Metric{
+			Name:      point.Name,
+			Timestamp: point.Timestamp,
+			Tags:      point.Tags,
+			Fields:    point.Fields,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.MaybeUnwrap()
+}
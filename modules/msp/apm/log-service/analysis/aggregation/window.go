@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregation
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CountField is the number of the logs aggregated into a point.
+const CountField = "count"
+
+// Point is the value of a single log, or the aggregated value of a window.
+type Point struct {
+	Name      string
+	Timestamp int64
+	Tags      map[string]string
+	Fields    map[string]interface{}
+}
+
+// Window aggregates the points in tumbling windows.
+// The windows are closed by the event time of the points, so the lag of consumer does not drop points.
+type Window struct {
+	interval  time.Duration
+	delay     time.Duration
+	lock      sync.Mutex
+	series    map[string]*series
+	watermark int64
+	maxEvent  int64     // max event time of the points added
+	lastAdd   time.Time // wall time of the last point added, to close the windows when there is no more point
+	now       func() time.Time
+}
+
+type series struct {
+	name       string
+	start      int64
+	tags       map[string]string
+	aggregator string
+	count      int64
+	stats      map[string]*stat
+}
+
+type stat struct {
+	sum, min, max float64
+	count         int64
+}
+
+// NewWindow returns a Window, the points of a window are flushed after the event time passes
+// the end of window for delay, to wait for the late logs.
+func NewWindow(interval, delay time.Duration) *Window {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Window{
+		interval: interval,
+		delay:    delay,
+		series:   make(map[string]*series),
+		now:      time.Now,
+	}
+}
+
+// Add aggregates the point with the aggregator. Only the tags of keepTags and groupBy are kept,
+// the points with different values of them are aggregated separately.
+// It returns false if the window of the point has been flushed.
+func (w *Window) Add(p *Point, aggregator string, groupBy []string, keepTags map[string]bool) bool {
+	start := p.Timestamp - p.Timestamp%int64(w.interval)
+	tags := make(map[string]string)
+	for k, v := range p.Tags {
+		if keepTags[k] {
+			tags[k] = v
+		}
+	}
+	for _, k := range groupBy {
+		tags[k] = p.Tags[k]
+	}
+	key := seriesKey(p.Name, aggregator, start, tags)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if start < w.watermark {
+		return false
+	}
+	if p.Timestamp > w.maxEvent {
+		w.maxEvent = p.Timestamp
+	}
+	w.lastAdd = w.now()
+	s, ok := w.series[key]
+	if !ok {
+		s = &series{
+			name:       p.Name,
+			start:      start,
+			tags:       tags,
+			aggregator: aggregator,
+			stats:      make(map[string]*stat),
+		}
+		w.series[key] = s
+	}
+	s.count++
+	for k, v := range p.Fields {
+		if k == CountField {
+			continue
+		}
+		val, ok := toFloat(v)
+		if !ok {
+			continue
+		}
+		st, ok := s.stats[k]
+		if !ok {
+			st = &stat{min: val, max: val}
+			s.stats[k] = st
+		}
+		st.sum += val
+		st.count++
+		if val < st.min {
+			st.min = val
+		}
+		if val > st.max {
+			st.max = val
+		}
+	}
+	return true
+}
+
+// Flush returns the aggregated points of the windows which have ended.
+// The end is the max event time minus delay, or now minus delay if no point is added for a window,
+// and the event time in the future of now is ignored.
+// Besides the aggregated value, <field>_sum and <field>_count are returned so that
+// the points of the same window from different instances can be merged.
+func (w *Window) Flush(now time.Time) []*Point {
+	w.lock.Lock()
+	end := w.maxEvent
+	if end > now.UnixNano() || (!w.lastAdd.IsZero() && now.Sub(w.lastAdd) > w.interval+w.delay) {
+		end = now.UnixNano()
+	}
+	end -= int64(w.delay)
+	watermark := end - end%int64(w.interval)
+	var list []*series
+	for key, s := range w.series {
+		if s.start < watermark {
+			list = append(list, s)
+			delete(w.series, key)
+		}
+	}
+	if watermark > w.watermark {
+		w.watermark = watermark
+	}
+	w.lock.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].start < list[j].start })
+	points := make([]*Point, 0, len(list))
+	for _, s := range list {
+		fields := map[string]interface{}{
+			CountField: s.count,
+		}
+		for k, st := range s.stats {
+			fields[k] = st.value(s.aggregator)
+			fields[k+"_sum"] = st.sum
+			fields[k+"_count"] = st.count
+		}
+		points = append(points, &Point{
+			Name:      s.name,
+			Timestamp: s.start,
+			Tags:      s.tags,
+			Fields:    fields,
+		})
+	}
+	return points
+}
+
+func (st *stat) value(aggregator string) float64 {
+	switch aggregator {
+	case "sum":
+		return st.sum
+	case "max":
+		return st.max
+	case "min":
+		return st.min
+	}
+	return st.sum / float64(st.count)
+}
+
+func seriesKey(name, aggregator string, start int64, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString("/")
+	sb.WriteString(aggregator)
+	sb.WriteString("/")
+	sb.WriteString(time.Duration(start).String())
+	for _, k := range keys {
+		sb.WriteString("\n")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	}
+	return 0, false
+}
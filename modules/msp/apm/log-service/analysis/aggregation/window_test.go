@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	base := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	w := NewWindow(time.Minute, 10*time.Second)
+	keep := map[string]bool{"msp_env_id": true}
+	var clock time.Time
+	w.now = func() time.Time { return clock }
+	add := func(offset time.Duration, service string, elapsed float64) bool {
+		clock = base.Add(offset)
+		return w.Add(&Point{
+			Name:      "log_metric",
+			Timestamp: base.Add(offset).UnixNano(),
+			Tags:      map[string]string{"msp_env_id": "env", "service_name": service, "pod": "p-" + service},
+			Fields:    map[string]interface{}{CountField: int64(1), "elapsed": elapsed},
+		}, "avg", []string{"service_name"}, keep)
+	}
+	add(time.Second, "api", 100)
+	add(30*time.Second, "api", 300)
+	add(40*time.Second, "web", 50)
+	add(70*time.Second, "api", 10)
+
+	if points := w.Flush(base.Add(65 * time.Second)); len(points) != 0 {
+		t.Fatalf("Flush() before the delay = %v, want nothing", points)
+	}
+	points := w.Flush(base.Add(75 * time.Second))
+	if len(points) != 2 {
+		t.Fatalf("Flush() got %d points, want 2", len(points))
+	}
+	for _, p := range points {
+		if p.Timestamp != base.UnixNano() {
+			t.Errorf("point timestamp = %d, want the start of the window", p.Timestamp)
+		}
+		switch p.Tags["service_name"] {
+		case "api":
+			want := map[string]interface{}{
+				CountField:      int64(2),
+				"elapsed":       float64(200),
+				"elapsed_sum":   float64(400),
+				"elapsed_count": int64(2),
+			}
+			if !reflect.DeepEqual(p.Fields, want) {
+				t.Errorf("api fields = %v, want %v", p.Fields, want)
+			}
+		case "web":
+			if p.Fields[CountField] != int64(1) {
+				t.Errorf("web fields = %v", p.Fields)
+			}
+		}
+		if _, ok := p.Tags["pod"]; ok || p.Tags["msp_env_id"] != "env" {
+			t.Errorf("point tags = %v", p.Tags)
+		}
+	}
+	if add(5*time.Second, "api", 1) {
+		t.Errorf("Add() to a flushed window should be dropped")
+	}
+	if points := w.Flush(base.Add(2*time.Minute + 10*time.Second)); len(points) != 0 {
+		t.Fatalf("Flush() before the window is idle = %v, want nothing", points)
+	}
+	points = w.Flush(base.Add(2*time.Minute + 30*time.Second))
+	if len(points) != 1 || points[0].Fields["elapsed"] != float64(10) {
+		t.Errorf("Flush() = %v", points)
+	}
+}
+
+func TestWindowLag(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	w := NewWindow(time.Minute, 10*time.Second)
+	w.now = func() time.Time { return now }
+	base := now.Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		ok := w.Add(&Point{
+			Name:      "log_metric",
+			Timestamp: base.Add(time.Duration(i) * 30 * time.Second).UnixNano(),
+			Fields:    map[string]interface{}{"elapsed": float64(i)},
+		}, "max", nil, nil)
+		if !ok {
+			t.Fatalf("Add() of the lagged point %d is dropped", i)
+		}
+		if points := w.Flush(now); i < 2 && len(points) != 0 {
+			t.Fatalf("Flush() of the lagged window = %v, want nothing", points)
+		}
+	}
+	w.Add(&Point{Name: "log_metric", Timestamp: now.Add(time.Hour).UnixNano()}, "max", nil, nil)
+	points := w.Flush(now)
+	if len(points) != 2 || points[0].Fields[CountField] != int64(2) || points[0].Fields["elapsed"] != float64(1) {
+		t.Errorf("Flush() = %v", points)
+	}
+}
+
+func TestStatValue(t *testing.T) {
+	st := &stat{sum: 10, min: 1, max: 6, count: 4}
+	for aggregator, want := range map[string]float64{"sum": 10, "max": 6, "min": 1, "avg": 2.5} {
+		if got := st.value(aggregator); got != want {
+			t.Errorf("value(%s) = %v, want %v", aggregator, got, want)
+		}
+	}
+}
@@ -108,6 +108,10 @@ func (p *provider) invoke(key []byte, value []byte, topic *string, timestamp tim
 	ps := (pv.(*processors.Processors)).Find("", scopeID, log.Tags)
 	var errs errorx.Errors
 	for _, processor := range ps {
+		if ap, ok := processor.(processors.AggregatedProcessor); ok {
+			p.aggregate(ap, processor, log)
+			continue
+		}
 		name, fields, appendTags, replaceKey, err := processor.Process(log.Content)
 		if err != nil {
 			// invalid processor or not match content
@@ -19,6 +19,7 @@ import (
 	"github.com/recallsong/go-utils/reflectx"
 
 	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors"
+	_ "github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors/query" //
 	_ "github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors/regex" //
 )
 
@@ -26,6 +26,22 @@ type Processor interface {
 	Keys() []*pb.FieldDefine
 }
 
+// TagsProcessor is implemented by the processors which need the tags of the log.
+type TagsProcessor interface {
+	ProcessWithTags(content string, tags map[string]string) (string, map[string]interface{}, map[string]string, map[string]string, error)
+}
+
+// Aggregation describes how the outputs of a processor are aggregated in time windows before they are written.
+type Aggregation struct {
+	Aggregator string
+	GroupBy    []string
+}
+
+// AggregatedProcessor is implemented by the processors whose outputs need to be aggregated.
+type AggregatedProcessor interface {
+	Aggregation() *Aggregation
+}
+
 type processor struct {
 	Processor
 	tags map[string]string
@@ -89,7 +105,7 @@ loop:
 				}
 			}
 		}
-		list = append(list, p)
+		list = append(list, p.Processor)
 	}
 	return list
 }
@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+
+	"github.com/erda-project/erda/modules/core/monitor/log/storage/clickhouse/query_parser/parser"
+)
+
+// matcher reports whether a log matches the query.
+type matcher func(content string, tags map[string]string) bool
+
+func matchAll(content string, tags map[string]string) bool { return true }
+
+// compileMatcher compiles the query expression, which has the same syntax as GetLogByExpression,
+// into a matcher evaluated on the logs in memory. Like the log storage, the terms without field
+// match the content by substring, the others match the field exactly.
+func compileMatcher(expr string) (matcher, error) {
+	if len(strings.TrimSpace(expr)) == 0 {
+		return matchAll, nil
+	}
+	lexer := parser.NewEsQueryStringLexer(antlr.NewInputStream(expr))
+	p := parser.NewEsQueryStringParser(antlr.NewCommonTokenStream(lexer, antlr.TokenDefaultChannel))
+	listener := &matcherListener{}
+	p.RemoveErrorListeners()
+	p.AddErrorListener(listener)
+	antlr.ParseTreeWalkerDefault.Walk(listener, p.Query())
+	if len(listener.errs) > 0 {
+		return nil, listener.errs[0]
+	}
+	if len(listener.stack) != 1 {
+		return nil, fmt.Errorf("invalid query expression: %s", expr)
+	}
+	return listener.stack[0], nil
+}
+
+type matcherListener struct {
+	*parser.BaseEsQueryStringListener
+	*antlr.DefaultErrorListener
+
+	stack []matcher
+	errs  []error
+}
+
+func (l *matcherListener) SyntaxError(recognizer antlr.Recognizer, offendingSymbol interface{}, line, column int, msg string, ex antlr.RecognitionException) {
+	l.errs = append(l.errs, fmt.Errorf("line "+strconv.Itoa(line)+":"+strconv.Itoa(column)+" "+msg))
+}
+
+func (l *matcherListener) ExitNotExpression(c *parser.NotExpressionContext) {
+	if len(l.stack) < 1 {
+		return
+	}
+	m := l.pop()
+	l.push(func(content string, tags map[string]string) bool {
+		return !m(content, tags)
+	})
+}
+
+func (l *matcherListener) ExitAndExpression(c *parser.AndExpressionContext) {
+	l.and()
+}
+
+// ExitDefaultOpExpression uses AND as the default operator, the same as the log query.
+func (l *matcherListener) ExitDefaultOpExpression(c *parser.DefaultOpExpressionContext) {
+	l.and()
+}
+
+func (l *matcherListener) ExitOrExpression(c *parser.OrExpressionContext) {
+	if len(l.stack) < 2 {
+		return
+	}
+	right, left := l.pop(), l.pop()
+	l.push(func(content string, tags map[string]string) bool {
+		return left(content, tags) || right(content, tags)
+	})
+}
+
+func (l *matcherListener) ExitNamedPhraseFieldQuery(c *parser.NamedPhraseFieldQueryContext) {
+	l.push(fieldMatcher(strings.TrimRight(c.FIELD().GetText(), ":"), strings.Trim(c.PHRASE().GetText(), `"`)))
+}
+
+func (l *matcherListener) ExitNamedTermFieldQuery(c *parser.NamedTermFieldQueryContext) {
+	l.push(fieldMatcher(strings.TrimRight(c.FIELD().GetText(), ":"), c.TERM().GetText()))
+}
+
+func (l *matcherListener) ExitPhraseFieldQuery(c *parser.PhraseFieldQueryContext) {
+	l.push(fieldMatcher("content", strings.Trim(c.PHRASE().GetText(), `"`)))
+}
+
+func (l *matcherListener) ExitTermFieldQuery(c *parser.TermFieldQueryContext) {
+	l.push(fieldMatcher("content", c.TERM().GetText()))
+}
+
+func (l *matcherListener) and() {
+	if len(l.stack) < 2 {
+		return
+	}
+	right, left := l.pop(), l.pop()
+	l.push(func(content string, tags map[string]string) bool {
+		return left(content, tags) && right(content, tags)
+	})
+}
+
+func (l *matcherListener) pop() matcher {
+	m := l.stack[len(l.stack)-1]
+	l.stack = l.stack[:len(l.stack)-1]
+	return m
+}
+
+func (l *matcherListener) push(m matcher) {
+	l.stack = append(l.stack, m)
+}
+
+func fieldMatcher(field, value string) matcher {
+	if field == "content" {
+		return func(content string, tags map[string]string) bool {
+			return strings.Contains(content, value)
+		}
+	}
+	field = strings.TrimPrefix(field, "tags.")
+	return func(content string, tags map[string]string) bool {
+		v, ok := tags[field]
+		return ok && v == value
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/metric/pb"
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors"
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors/convert"
+)
+
+// CountField is the number of the matched logs in the window.
+const CountField = "count"
+
+var aggregators = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"max":   true,
+	"min":   true,
+}
+
+type config struct {
+	Query      string   `json:"query"`
+	Aggregator string   `json:"aggregator"`
+	Field      string   `json:"field"`
+	Pattern    string   `json:"pattern"`
+	GroupBy    []string `json:"groupBy"`
+}
+
+type processor struct {
+	metric      string
+	match       matcher
+	aggregation *processors.Aggregation
+	field       string
+	reg         *regexp.Regexp
+	convert     func(text string) (interface{}, error)
+}
+
+// New .
+func New(metric string, cfg []byte) (processors.Processor, error) {
+	var c config
+	err := json.Unmarshal(cfg, &c)
+	if err != nil {
+		return nil, fmt.Errorf("fail to unmarshal query config: %s", err)
+	}
+	match, err := compileMatcher(c.Query)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse query: %s", err)
+	}
+	if len(c.Aggregator) <= 0 {
+		c.Aggregator = "count"
+	}
+	if !aggregators[c.Aggregator] {
+		return nil, fmt.Errorf("not support aggregator %q", c.Aggregator)
+	}
+	p := &processor{
+		metric:  metric,
+		match:   match,
+		field:   c.Field,
+		convert: convert.Converter("float"),
+		aggregation: &processors.Aggregation{
+			Aggregator: c.Aggregator,
+			GroupBy:    c.GroupBy,
+		},
+	}
+	if c.Aggregator != "count" {
+		if len(c.Field) <= 0 || c.Field == CountField {
+			return nil, fmt.Errorf("field is required by aggregator %s and must not be %q", c.Aggregator, CountField)
+		}
+		if len(c.Pattern) > 0 {
+			reg, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("fail to compile regexp pattern: %s", err)
+			}
+			if reg.NumSubexp() != 1 {
+				return nil, fmt.Errorf("regexp pattern must have exactly one capturing group")
+			}
+			p.reg = reg
+		}
+	}
+	return p, nil
+}
+
+// ErrNotMatch .
+var ErrNotMatch = fmt.Errorf("not match query")
+
+// Process .
+func (p *processor) Process(content string) (string, map[string]interface{}, map[string]string, map[string]string, error) {
+	return p.ProcessWithTags(content, nil)
+}
+
+// ProcessWithTags returns the value of a single log, which is aggregated later.
+func (p *processor) ProcessWithTags(content string, tags map[string]string) (string, map[string]interface{}, map[string]string, map[string]string, error) {
+	if !p.match(content, tags) {
+		return "", nil, nil, nil, ErrNotMatch
+	}
+	fields := map[string]interface{}{
+		CountField: int64(1),
+	}
+	if p.aggregation.Aggregator != "count" {
+		text, ok := tags[p.field]
+		if p.reg != nil {
+			match := p.reg.FindStringSubmatch(content)
+			ok = len(match) == 2
+			if ok {
+				text = match[1]
+			}
+		}
+		if !ok {
+			return "", nil, nil, nil, ErrNotMatch
+		}
+		val, err := p.convert(text)
+		if err != nil {
+			return "", nil, nil, nil, ErrNotMatch
+		}
+		fields[p.field] = val
+	}
+	return p.metric, fields, nil, nil, nil
+}
+
+// Keys .
+func (p *processor) Keys() []*pb.FieldDefine {
+	keys := []*pb.FieldDefine{{Key: CountField, Type: "number", Name: CountField}}
+	if p.aggregation.Aggregator != "count" {
+		keys = append(keys, &pb.FieldDefine{Key: p.field, Type: "number", Name: p.field})
+	}
+	return keys
+}
+
+// Aggregation .
+func (p *processor) Aggregation() *processors.Aggregation {
+	return p.aggregation
+}
+
+func init() {
+	processors.RegisterProcessor("query", New)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors"
+)
+
+func Test_compileMatcher(t *testing.T) {
+	tags := map[string]string{"level": "ERROR", "service_name": "api"}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "", want: true},
+		{expr: "timeout", want: true},
+		{expr: `"read timeout"`, want: true},
+		{expr: "level:ERROR timeout", want: true},
+		{expr: "tags.level:ERROR AND service_name:api", want: true},
+		{expr: "level:WARN OR refused", want: false},
+		{expr: "level:WARN OR (timeout AND NOT refused)", want: true},
+		{expr: `level:ERROR AND NOT "read timeout"`, want: false},
+	}
+	for _, tt := range tests {
+		m, err := compileMatcher(tt.expr)
+		if err != nil {
+			t.Fatalf("compileMatcher(%q) error: %s", tt.expr, err)
+		}
+		if got := m("upstream read timeout after 3s", tags); got != tt.want {
+			t.Errorf("compileMatcher(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+	if _, err := compileMatcher("(level:ERROR"); err == nil {
+		t.Errorf("compileMatcher() with unbalanced group should fail")
+	}
+}
+
+func Test_Process_With_Aggregation(t *testing.T) {
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"query":      "level:ERROR",
+		"aggregator": "avg",
+		"field":      "elapsed",
+		"pattern":    `elapsed=(\d+)ms`,
+		"groupBy":    []string{"service_name"},
+	})
+	p, err := New("log_metric", cfg)
+	if err != nil {
+		t.Fatalf("New() error: %s", err)
+	}
+	tp := p.(processors.TagsProcessor)
+	name, fields, _, _, err := tp.ProcessWithTags("done elapsed=120ms", map[string]string{"level": "ERROR"})
+	if err != nil || name != "log_metric" || fields["elapsed"] != float64(120) || fields[CountField] != int64(1) {
+		t.Errorf("ProcessWithTags() = %s, %v, %v", name, fields, err)
+	}
+	if _, _, _, _, err := tp.ProcessWithTags("done elapsed=120ms", map[string]string{"level": "INFO"}); err != ErrNotMatch {
+		t.Errorf("ProcessWithTags() should miss match by tags")
+	}
+	if _, _, _, _, err := tp.ProcessWithTags("done", map[string]string{"level": "ERROR"}); err != ErrNotMatch {
+		t.Errorf("ProcessWithTags() should miss match without field")
+	}
+	agg := p.(processors.AggregatedProcessor).Aggregation()
+	if agg.Aggregator != "avg" || len(agg.GroupBy) != 1 || len(p.Keys()) != 2 {
+		t.Errorf("Aggregation() = %+v, Keys() = %v", agg, p.Keys())
+	}
+}
+
+func Test_New_With_InvalidConfig_Should_Fail(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{"query": "(level:ERROR"},
+		{"aggregator": "p99", "field": "elapsed"},
+		{"aggregator": "sum"},
+		{"aggregator": "sum", "field": "elapsed", "pattern": `(\d+)-(\d+)`},
+	} {
+		byts, _ := json.Marshal(cfg)
+		if _, err := New("log_metric", byts); err == nil {
+			t.Errorf("New(%v) should fail", cfg)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package analysis
 
 import (
 	"fmt"
+	"os"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	writer "github.com/erda-project/erda-infra/pkg/parallel-writer"
 	"github.com/erda-project/erda-infra/providers/kafka"
 	"github.com/erda-project/erda-infra/providers/mysql"
+	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/aggregation"
 	"github.com/erda-project/erda/modules/msp/apm/log-service/rules/db"
 )
 
@@ -37,6 +39,12 @@ type config struct {
 		ScopeIDKey     string        `file:"scope_id_key"`
 		ReloadInterval time.Duration `file:"reload_interval" default:"3m"`
 	} `file:"processors"`
+	Aggregation struct {
+		Interval      time.Duration `file:"interval" default:"1m"`
+		Delay         time.Duration `file:"delay" default:"30s"`
+		FlushInterval time.Duration `file:"flush_interval" default:"10s"`
+		Instance      string        `file:"instance" env:"POD_NAME"`
+	} `file:"aggregation"`
 	Input  kafka.ConsumerConfig `file:"input"`
 	Output struct {
 		Type      string               `file:"type"`
@@ -57,6 +65,9 @@ type provider struct {
 	output     writer.Writer
 	processors atomic.Value
 	db         *db.DB
+	window     *aggregation.Window
+	instance   string
+	latePoints int64
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -68,6 +79,11 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		return fmt.Errorf("fail to create kafka producer: %s", err)
 	}
 	p.output = w
+	p.window = aggregation.NewWindow(p.C.Aggregation.Interval, p.C.Aggregation.Delay)
+	p.instance = p.C.Aggregation.Instance
+	if len(p.instance) <= 0 {
+		p.instance, _ = os.Hostname()
+	}
 	return nil
 }
 
@@ -86,6 +102,14 @@ func (p *provider) Start() error {
 			time.Sleep(p.C.Processors.ReloadInterval)
 		}
 	}()
+	go func() {
+		for {
+			time.Sleep(p.C.Aggregation.FlushInterval)
+			if err := p.flushAggregation(time.Now()); err != nil {
+				p.L.Errorf("fail to flush aggregated log metrics: %s", err)
+			}
+		}
+	}()
 	return nil
 }
 
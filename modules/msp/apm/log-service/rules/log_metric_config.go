@@ -174,6 +174,13 @@ func (p *provider) convertToMetricMeta(cfg *db.LogMetricConfig) (*pb.MetricMeta,
 					}
 					m.Fields[k.Key] = k
 				}
+				if ap, ok := proc.(processors.AggregatedProcessor); ok {
+					for _, key := range ap.Aggregation().GroupBy {
+						if _, ok := m.Tags[key]; !ok {
+							m.Tags[key] = &pb.TagDefine{Key: key, Name: key}
+						}
+					}
+				}
 			}
 			break
 		}
@@ -26,6 +26,7 @@ import (
 	"github.com/erda-project/erda/apistructs"
 	metrics "github.com/erda-project/erda/modules/core/monitor/metric"
 	"github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors"
+	_ "github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors/query" //
 	_ "github.com/erda-project/erda/modules/msp/apm/log-service/analysis/processors/regex" //
 	api "github.com/erda-project/erda/pkg/common/httpapi"
 )
@@ -288,6 +289,7 @@ func (p *provider) deleteRule(r *http.Request, params struct {
 
 func (p *provider) testRule(params struct {
 	Content    string             `json:"content"`
+	Tags       map[string]string  `json:"tags"`
 	MetricName string             `json:"metric_name"`
 	Processors []*ProcessorConfig `json:"processors"`
 }) interface{} {
@@ -300,13 +302,25 @@ func (p *provider) testRule(params struct {
 		if err != nil {
 			return api.Errors.InvalidParameter("fail to create processor", err.Error())
 		}
-		name, fields, _, _, err := proc.Process(params.Content)
+		var (
+			name   string
+			fields map[string]interface{}
+		)
+		if tp, ok := proc.(processors.TagsProcessor); ok {
+			name, fields, _, _, err = tp.ProcessWithTags(params.Content, params.Tags)
+		} else {
+			name, fields, _, _, err = proc.Process(params.Content)
+		}
 		if err != nil {
 			return api.Success(nil)
 		}
+		tags := params.Tags
+		if tags == nil {
+			tags = map[string]string{}
+		}
 		return api.Success(&metrics.Metric{
 			Name:      name,
-			Tags:      map[string]string{},
+			Tags:      tags,
 			Fields:    fields,
 			Timestamp: time.Now().UnixNano(),
 		})
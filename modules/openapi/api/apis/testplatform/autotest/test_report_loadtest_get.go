@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotest
+
+import (
+	"net/http"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/openapi/api/apis"
+)
+
+var REPORT_LOAD_TEST_GET = apis.ApiSpec{
+	Path:         "/api/projects/<projectID>/test-reports/load-tests/<pipelineID>",
+	BackendPath:  "/api/projects/<projectID>/test-reports/load-tests/<pipelineID>",
+	Host:         "dop.marathon.l4lb.thisdcos.directory:9527",
+	Scheme:       "http",
+	Method:       http.MethodGet,
+	ResponseType: apistructs.AutoTestLoadTestReportResponse{},
+	CheckLogin:   true,
+	IsOpenAPI:    true,
+	CheckToken:   true,
+	Doc:          "summary: 获取压测报告",
+}
@@ -71,7 +71,7 @@ func buildStepOutPut(steps []apistructs.AutoTestSceneStep) (map[string]map[strin
 	outputs := make(map[string]map[string]string, 0)
 	for _, step := range steps {
 		var value APISpec
-		if step.Type == apistructs.StepTypeAPI {
+		if step.Type.IsAPIStepType() {
 			if step.Value == "" {
 				step.Value = "{}"
 			}
@@ -101,7 +101,7 @@ func GetStepOutPut(steps []apistructs.AutoTestSceneStep) (map[string]map[string]
 	outputs := make(map[string]map[string]string, 0)
 	for _, step := range steps {
 		var value APISpec
-		if step.Type == apistructs.StepTypeAPI {
+		if step.Type.IsAPIStepType() {
 			if step.Value == "" {
 				step.Value = "{}"
 			}
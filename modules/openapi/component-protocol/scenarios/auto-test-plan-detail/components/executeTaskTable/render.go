@@ -250,7 +250,7 @@ func transformStepType(str apistructs.StepAPIType, i18nLocale *i18n.LocaleResour
 	switch str {
 	case apistructs.StepTypeWait:
 		return i18nLocale.Get(i18nkey.I18nKeyWait)
-	case apistructs.StepTypeAPI:
+	case apistructs.StepTypeAPI, apistructs.StepTypeGRPC, apistructs.StepTypeWebSocket:
 		return i18nLocale.Get(i18nkey.I18nKeyInterface)
 	case apistructs.StepTypeScene:
 		return i18nLocale.Get(i18nkey.I18nKeyScene)
@@ -353,7 +353,7 @@ func (a *ExecuteTaskTable) setData(pipeline *apistructs.PipelineDetailDTO, i18nL
 						return err
 					}
 
-					if res.Type == apistructs.StepTypeAPI || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
+					if res.Type.IsAPIStepType() || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
 						err := json.Unmarshal([]byte(res.Value), &value)
 						if err != nil {
 							return err
@@ -371,7 +371,7 @@ func (a *ExecuteTaskTable) setData(pipeline *apistructs.PipelineDetailDTO, i18nL
 					res.Type = apistructs.StepAPIType(task.Type)
 				}
 				operations := map[string]interface{}{}
-				if res.Type == apistructs.StepTypeAPI || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
+				if res.Type.IsAPIStepType() || res.Type == apistructs.StepTypeWait || res.Type == apistructs.StepTypeCustomScript {
 					operations = map[string]interface{}{
 						"checkDetail": dataOperation{
 							Key:         "checkDetail",
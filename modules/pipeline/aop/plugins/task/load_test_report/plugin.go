@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package load_test_report
+
+import (
+	"encoding/json"
+
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/modules/pipeline/aop"
+	"github.com/erda-project/erda/modules/pipeline/aop/aoptypes"
+)
+
+const metaKeyLoadTestReport = "load_test_report"
+
+// +provider
+type provider struct {
+	aoptypes.TaskBaseTunePoint
+}
+
+func (p *provider) Name() string { return "load-test-report" }
+
+// Handle save report in metafile of load-test action as pipeline report, so it can be referenced by test report.
+func (p *provider) Handle(ctx *aoptypes.TuneContext) error {
+	if ctx.SDK.Task.Type != apistructs.ActionTypeLoadTest {
+		return nil
+	}
+
+	var reportJSON string
+	for _, field := range ctx.SDK.Task.GetMetadata() {
+		if field.Name == metaKeyLoadTestReport {
+			reportJSON = field.Value
+			break
+		}
+	}
+	if reportJSON == "" {
+		return nil
+	}
+	var report apistructs.AutoTestLoadTestReport
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		return err
+	}
+	report.PipelineID = ctx.SDK.Pipeline.ID
+
+	_, err := ctx.SDK.Report.Create(apistructs.PipelineReportCreateRequest{
+		PipelineID: ctx.SDK.Pipeline.ID,
+		Type:       apistructs.PipelineReportTypeLoadTest,
+		Meta:       apistructs.PipelineReportMeta{"data": report},
+	})
+	return err
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	err := aop.RegisterTunePoint(p)
+	if err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+func init() {
+	servicehub.Register(aop.NewProviderNameByPluginName(&provider{}), &servicehub.Spec{
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
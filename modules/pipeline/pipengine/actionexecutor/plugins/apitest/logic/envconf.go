@@ -30,6 +30,10 @@ type EnvConfig struct {
 	Asserts      []APIAssert                   `env:"ACTION_ASSERTS"`
 	GlobalConfig *apistructs.AutoTestAPIConfig `env:"AUTOTEST_API_GLOBAL_CONFIG"`
 
+	Protocol  apistructs.APIProtocol         `env:"ACTION_PROTOCOL"`
+	GRPC      *apistructs.APIGRPCConfig      `env:"ACTION_GRPC"`
+	WebSocket *apistructs.APIWebSocketConfig `env:"ACTION_WEBSOCKET"`
+
 	MetaFile string `env:"METAFILE"`
 }
 
@@ -55,6 +59,9 @@ func generateAPIInfoFromEnv(cfg EnvConfig) *apistructs.APIInfo {
 		Body:      cfg.Body,
 		OutParams: cfg.OutParams,
 		Asserts:   [][]apistructs.APIAssert{asserts}, // 目前有且只有一组断言
+		Protocol:  cfg.Protocol,
+		GRPC:      cfg.GRPC,
+		WebSocket: cfg.WebSocket,
 	}
 }
 
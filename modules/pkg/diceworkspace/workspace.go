@@ -40,6 +40,10 @@ func GetValidBranchByGitReference(ref string, branchRules []*apistructs.BranchRu
 					IsTriggerPipeline: branchRule.IsTriggerPipeline,
 					Workspace:         branchRule.Workspace,
 					ArtifactWorkspace: branchRule.ArtifactWorkspace,
+
+					RequiredApprovals:        branchRule.RequiredApprovals,
+					RequireCodeOwnerApproval: branchRule.RequireCodeOwnerApproval,
+					RequireSignedCommits:     branchRule.RequireSignedCommits,
 				}
 			}
 		}
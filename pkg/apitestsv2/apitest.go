@@ -116,6 +116,13 @@ func (at *APITest) Invoke(httpClient *http.Client, testEnv *apistructs.APITestEn
 		return nil, nil, err
 	}
 
+	switch at.API.Protocol {
+	case apistructs.APIProtocolGRPC:
+		return at.invokeGRPC()
+	case apistructs.APIProtocolWebSocket:
+		return at.invokeWebSocket(testEnv)
+	}
+
 	// generate api request for invoking
 	var apiReq apistructs.APIRequestInfo
 
@@ -303,6 +310,12 @@ func (at *APITest) renderAtOnce(apiReq *apistructs.APIInfo, caseParams map[strin
 			apiReq.Body.Content = renderedContent
 		}
 	}
+	// websocket messages
+	if apiReq.WebSocket != nil {
+		for i := range apiReq.WebSocket.Messages {
+			apiReq.WebSocket.Messages[i] = renderFunc(apiReq.WebSocket.Messages[i], caseParams)
+		}
+	}
 	// headers
 	for i := range apiReq.Headers {
 		header := apiReq.Headers[i]
@@ -0,0 +1,426 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const defaultGRPCTimeout = 30 * time.Second
+
+// invokeGRPC 执行 gRPC API 测试，支持 unary 和 server streaming 调用
+//
+// resp.Status is the grpc status code, resp.Headers contains the header and trailer metadata,
+// resp.Body is the json of response message, or json array of messages for server streaming.
+func (at *APITest) invokeGRPC() (*apistructs.APIRequestInfo, *apistructs.APIResp, error) {
+	conf := at.API.GRPC
+	if conf == nil {
+		conf = &apistructs.APIGRPCConfig{}
+	}
+	target, useTLS, err := parseGRPCTarget(at.API.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullMethod, err := parseGRPCMethod(at.API.Method)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// request info
+	var apiReq apistructs.APIRequestInfo
+	apiReq.URL = target
+	apiReq.Method = fullMethod
+	apiReq.Headers = make(http.Header)
+	md := metadata.MD{}
+	for _, h := range at.API.Headers {
+		if h.Key == "" {
+			continue
+		}
+		apiReq.Headers.Add(h.Key, h.Value)
+		md.Append(h.Key, h.Value)
+	}
+	reqBody := "{}"
+	if at.API.Body.Content != nil && strings.TrimSpace(fmt.Sprint(at.API.Body.Content)) != "" {
+		reqBody = fmt.Sprint(at.API.Body.Content)
+	}
+	apiReq.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: reqBody}
+
+	timeout := defaultGRPCTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	creds := grpc.WithInsecure()
+	if useTLS {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	conn, err := grpc.DialContext(ctx, target, creds)
+	if err != nil {
+		return &apiReq, nil, fmt.Errorf("failed to dial grpc target %s, err: %v", target, err)
+	}
+	defer conn.Close()
+
+	// resolve method
+	var files *protoregistry.Files
+	switch {
+	case conf.Descriptor != "":
+		files, err = filesFromDescriptorSet(conf.Descriptor)
+	case conf.Reflection:
+		files, err = filesFromReflection(ctx, conn, fullMethod[:strings.LastIndex(fullMethod, "/")])
+	default:
+		err = fmt.Errorf("neither proto descriptor nor server reflection is specified")
+	}
+	if err != nil {
+		return &apiReq, nil, err
+	}
+	method, err := findGRPCMethod(files, fullMethod)
+	if err != nil {
+		return &apiReq, nil, err
+	}
+	if method.IsStreamingClient() {
+		return &apiReq, nil, fmt.Errorf("client streaming method %s is not supported", fullMethod)
+	}
+
+	// request message
+	reqMsg := dynamicpb.NewMessage(method.Input())
+	if err := protojson.Unmarshal([]byte(reqBody), reqMsg); err != nil {
+		return &apiReq, nil, fmt.Errorf("failed to convert request body to %s, err: %v", method.Input().FullName(), err)
+	}
+	reqBytes, err := proto.Marshal(reqMsg)
+	if err != nil {
+		return &apiReq, nil, err
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	var (
+		header, trailer metadata.MD
+		respMsgs        [][]byte
+		invokeErr       error
+	)
+	if method.IsStreamingServer() {
+		respMsgs, header, trailer, invokeErr = invokeGRPCServerStream(ctx, conn, fullMethod, reqBytes)
+	} else {
+		var respBytes []byte
+		invokeErr = conn.Invoke(ctx, fullMethod, &reqBytes, &respBytes,
+			grpc.ForceCodec(rawCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+		if invokeErr == nil {
+			respMsgs = append(respMsgs, respBytes)
+		}
+	}
+
+	// resp
+	st := status.Convert(invokeErr)
+	apiResp := apistructs.APIResp{
+		Status:  int(st.Code()),
+		Headers: make(map[string][]string),
+	}
+	for _, m := range []metadata.MD{header, trailer} {
+		for k, v := range m {
+			apiResp.Headers[k] = append(apiResp.Headers[k], v...)
+		}
+	}
+	apiResp.Headers["grpc-status"] = []string{fmt.Sprint(int(st.Code()))}
+	if st.Message() != "" {
+		apiResp.Headers["grpc-message"] = []string{st.Message()}
+	}
+	var bodies []json.RawMessage
+	for _, b := range respMsgs {
+		respMsg := dynamicpb.NewMessage(method.Output())
+		if err := proto.Unmarshal(b, respMsg); err != nil {
+			return &apiReq, &apiResp, fmt.Errorf("failed to unmarshal response to %s, err: %v", method.Output().FullName(), err)
+		}
+		j, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(respMsg)
+		if err != nil {
+			return &apiReq, &apiResp, err
+		}
+		bodies = append(bodies, j)
+	}
+	var body []byte
+	switch {
+	case method.IsStreamingServer():
+		if bodies == nil {
+			bodies = []json.RawMessage{}
+		}
+		body, err = json.Marshal(bodies)
+	case len(bodies) > 0:
+		body, err = json.Marshal(bodies[0])
+	}
+	if err != nil {
+		return &apiReq, &apiResp, err
+	}
+	apiResp.Body = body
+	apiResp.BodyStr = string(body)
+
+	return &apiReq, &apiResp, nil
+}
+
+func invokeGRPCServerStream(ctx context.Context, conn *grpc.ClientConn, fullMethod string, req []byte) (
+	msgs [][]byte, header, trailer metadata.MD, err error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = stream.SendMsg(&req); err != nil {
+		return nil, nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, nil, err
+	}
+	for {
+		var b []byte
+		if err = stream.RecvMsg(&b); err != nil {
+			break
+		}
+		msgs = append(msgs, b)
+	}
+	header, _ = stream.Header()
+	trailer = stream.Trailer()
+	if err == io.EOF {
+		err = nil
+	}
+	return msgs, header, trailer, err
+}
+
+// parseGRPCTarget return the dial target and whether to use tls.
+// Supported formats: host:port, grpc://host:port, grpcs://host:port, http://host:port, https://host:port.
+func parseGRPCTarget(rawurl string) (string, bool, error) {
+	target := strings.TrimSpace(rawurl)
+	var useTLS bool
+	for _, scheme := range []string{"grpcs://", "https://", "grpc://", "http://"} {
+		if strings.HasPrefix(target, scheme) {
+			target = strings.TrimPrefix(target, scheme)
+			useTLS = scheme == "grpcs://" || scheme == "https://"
+			break
+		}
+	}
+	target = strings.TrimSuffix(target, "/")
+	if target == "" || strings.Contains(target, "/") {
+		return "", false, fmt.Errorf("invalid grpc target: %s", rawurl)
+	}
+	return target, useTLS, nil
+}
+
+// parseGRPCMethod return the full method name like /package.Service/Method.
+func parseGRPCMethod(method string) (string, error) {
+	method = strings.Trim(strings.TrimSpace(method), "/")
+	// package.Service.Method is also accepted
+	if !strings.Contains(method, "/") {
+		if i := strings.LastIndex(method, "."); i > 0 {
+			method = method[:i] + "/" + method[i+1:]
+		}
+	}
+	parts := strings.Split(method, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid grpc method: %s, should be package.Service/Method", method)
+	}
+	return "/" + method, nil
+}
+
+func findGRPCMethod(files *protoregistry.Files, fullMethod string) (protoreflect.MethodDescriptor, error) {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	d, err := files.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found, err: %v", parts[0], err)
+	}
+	svc, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", parts[0])
+	}
+	method := svc.Methods().ByName(protoreflect.Name(parts[1]))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", parts[1], parts[0])
+	}
+	return method, nil
+}
+
+// filesFromDescriptorSet build files from base64 encoded FileDescriptorSet.
+func filesFromDescriptorSet(descriptor string) (*protoregistry.Files, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(descriptor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode proto descriptor, err: %v", err)
+	}
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &fds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proto descriptor, err: %v", err)
+	}
+	return buildFiles(fds.File)
+}
+
+// filesFromReflection fetch the file containing service and its dependencies by server reflection.
+func filesFromReflection(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	service = strings.TrimPrefix(service, "/")
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call server reflection, err: %v", err)
+	}
+	defer stream.CloseSend()
+
+	var fdps []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	pending := []*rpb.ServerReflectionRequest{{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	}}
+	for len(pending) > 0 {
+		req := pending[0]
+		pending = pending[1:]
+		if err := stream.Send(req); err != nil {
+			return nil, fmt.Errorf("failed to send server reflection request, err: %v", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive server reflection response, err: %v", err)
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return nil, fmt.Errorf("server reflection error: %s", errResp.ErrorMessage)
+		}
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			var fdp descriptorpb.FileDescriptorProto
+			if err := proto.Unmarshal(b, &fdp); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal file descriptor, err: %v", err)
+			}
+			if seen[fdp.GetName()] {
+				continue
+			}
+			seen[fdp.GetName()] = true
+			fdps = append(fdps, &fdp)
+		}
+		// the server may not send dependencies already known by the stream, fetch missing ones
+		for _, fdp := range fdps {
+			for _, dep := range fdp.GetDependency() {
+				if seen[dep] || isGlobalFile(dep) {
+					continue
+				}
+				seen[dep] = true
+				pending = append(pending, &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+				})
+			}
+		}
+	}
+	return buildFiles(fdps)
+}
+
+// buildFiles register files in dependency order, well-known types are resolved from the global registry.
+func buildFiles(fdps []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	files := new(protoregistry.Files)
+	r := fileResolver{files}
+	pending := fdps
+	for len(pending) > 0 {
+		var rest []*descriptorpb.FileDescriptorProto
+		for _, fdp := range pending {
+			if _, err := files.FindFileByPath(fdp.GetName()); err == nil {
+				continue
+			}
+			if !r.hasDependencies(fdp) {
+				rest = append(rest, fdp)
+				continue
+			}
+			fd, err := protodesc.NewFile(fdp, r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proto file %s, err: %v", fdp.GetName(), err)
+			}
+			if err := files.RegisterFile(fd); err != nil {
+				return nil, err
+			}
+		}
+		if len(rest) == len(pending) {
+			return nil, fmt.Errorf("missing dependencies of proto file %s", rest[0].GetName())
+		}
+		pending = rest
+	}
+	return files, nil
+}
+
+func isGlobalFile(path string) bool {
+	_, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	return err == nil
+}
+
+type fileResolver struct {
+	files *protoregistry.Files
+}
+
+func (r fileResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r fileResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+func (r fileResolver) hasDependencies(fdp *descriptorpb.FileDescriptorProto) bool {
+	for _, dep := range fdp.GetDependency() {
+		if _, err := r.FindFileByPath(dep); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// rawCodec pass through the marshaled message bytes, so that messages are handled by dynamicpb.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name use proto as content-subtype, so that the server decodes messages with its proto codec.
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func startHealthServer(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("erda", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	reflection.Register(s)
+	go s.Serve(lis)
+	return lis.Addr().String(), s.Stop
+}
+
+func healthDescriptor(t *testing.T) string {
+	fd, err := protoregistry.GlobalFiles.FindFileByPath("grpc/health/v1/health.proto")
+	assert.NoError(t, err)
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(fd)},
+	})
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestAPITest_invokeGRPC(t *testing.T) {
+	addr, stop := startHealthServer(t)
+	defer stop()
+
+	tt := []struct {
+		name       string
+		api        *apistructs.APIInfo
+		wantStatus codes.Code
+		wantBody   string
+	}{
+		{
+			name: "unary by reflection",
+			api: &apistructs.APIInfo{
+				URL:     "grpc://" + addr,
+				Method:  "grpc.health.v1.Health/Check",
+				Headers: []apistructs.APIHeader{{Key: "x-erda-token", Value: "t"}},
+				Body:    apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: `{"service":"erda"}`},
+				GRPC:    &apistructs.APIGRPCConfig{Reflection: true},
+			},
+			wantStatus: codes.OK,
+			wantBody:   `{"status":"SERVING"}`,
+		},
+		{
+			name: "unary by descriptor",
+			api: &apistructs.APIInfo{
+				URL:    addr,
+				Method: "/grpc.health.v1.Health/Check",
+				Body:   apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: `{"service":"unknown"}`},
+				GRPC:   &apistructs.APIGRPCConfig{Descriptor: healthDescriptor(t)},
+			},
+			wantStatus: codes.NotFound,
+		},
+		{
+			name: "server streaming until timeout",
+			api: &apistructs.APIInfo{
+				URL:    addr,
+				Method: "grpc.health.v1.Health.Watch",
+				Body:   apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: `{"service":"erda"}`},
+				GRPC:   &apistructs.APIGRPCConfig{Reflection: true, Timeout: 1},
+			},
+			wantStatus: codes.DeadlineExceeded,
+			wantBody:   `[{"status":"SERVING"}]`,
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			at := New(v.api)
+			req, resp, err := at.invokeGRPC()
+			assert.NoError(t, err)
+			assert.Equal(t, addr, req.URL)
+			assert.Equal(t, int(v.wantStatus), resp.Status)
+			if v.wantBody != "" {
+				assert.JSONEq(t, v.wantBody, resp.BodyStr)
+			}
+			if v.wantStatus != codes.OK {
+				assert.NotEmpty(t, resp.Headers["grpc-message"])
+			}
+
+			outParams := at.ParseOutParams([]apistructs.APIOutParam{
+				{Key: "status", Source: apistructs.APIOutParamSourceStatus},
+			}, resp, map[string]*apistructs.CaseParams{})
+			assert.Equal(t, int(v.wantStatus), outParams["status"])
+		})
+	}
+}
+
+func TestAPITest_invokeGRPCError(t *testing.T) {
+	addr, stop := startHealthServer(t)
+	defer stop()
+
+	_, _, err := New(&apistructs.APIInfo{URL: addr, Method: "grpc.health.v1.Health/Check"}).invokeGRPC()
+	assert.Error(t, err, "no descriptor")
+
+	_, _, err = New(&apistructs.APIInfo{URL: addr, Method: "grpc.health.v1.Health/Unknown",
+		GRPC: &apistructs.APIGRPCConfig{Reflection: true}}).invokeGRPC()
+	assert.Error(t, err, "unknown method")
+
+	_, _, err = New(&apistructs.APIInfo{URL: addr, Method: "grpc.health.v1.Health/Check",
+		Body: apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: `{"unknown":1}`},
+		GRPC: &apistructs.APIGRPCConfig{Reflection: true}}).invokeGRPC()
+	assert.Error(t, err, "invalid body")
+}
+
+func Test_parseGRPCTarget(t *testing.T) {
+	tt := []struct {
+		url     string
+		target  string
+		tls     bool
+		wantErr bool
+	}{
+		{"localhost:8080", "localhost:8080", false, false},
+		{"grpc://localhost:8080/", "localhost:8080", false, false},
+		{"grpcs://erda.cloud:443", "erda.cloud:443", true, false},
+		{"https://erda.cloud", "erda.cloud", true, false},
+		{"http://erda.cloud/api", "", false, true},
+		{"", "", false, true},
+	}
+	for _, v := range tt {
+		target, useTLS, err := parseGRPCTarget(v.url)
+		assert.Equal(t, v.wantErr, err != nil, v.url)
+		assert.Equal(t, v.target, target, v.url)
+		assert.Equal(t, v.tls, useTLS, v.url)
+	}
+}
+
+func Test_parseGRPCMethod(t *testing.T) {
+	tt := []struct {
+		method  string
+		want    string
+		wantErr bool
+	}{
+		{"erda.Service/Method", "/erda.Service/Method", false},
+		{"/erda.Service/Method", "/erda.Service/Method", false},
+		{"erda.Service.Method", "/erda.Service/Method", false},
+		{"Method", "", true},
+		{"a/b/c", "", true},
+	}
+	for _, v := range tt {
+		got, err := parseGRPCMethod(v.method)
+		assert.Equal(t, v.wantErr, err != nil, v.method)
+		assert.Equal(t, v.want, got, v.method)
+	}
+}
+
+func Test_rawCodec(t *testing.T) {
+	req := []byte(`data`)
+	b, err := rawCodec{}.Marshal(&req)
+	assert.NoError(t, err)
+	var resp []byte
+	assert.NoError(t, rawCodec{}.Unmarshal(b, &resp))
+	assert.Equal(t, req, resp)
+	_, err = rawCodec{}.Marshal(json.RawMessage(req))
+	assert.Error(t, err)
+}
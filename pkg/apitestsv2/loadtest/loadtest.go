@@ -0,0 +1,248 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loadtest runs api steps of autotest scene concurrently as a performance test.
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/apitestsv2"
+	"github.com/erda-project/erda/pkg/apitestsv2/cookiejar"
+)
+
+// Runner drives the steps by virtual users, every virtual user invokes the steps in order repeatedly
+// until the duration is reached, outputs of steps are passed to the latter steps in the same iteration.
+type Runner struct {
+	steps      []apistructs.AutoTestLoadTestStep
+	config     apistructs.AutoTestLoadTestConfig
+	testEnv    *apistructs.APITestEnvData
+	caseParams map[string]*apistructs.CaseParams
+	cookies    cookiejar.Cookies
+	apiOpts    []apitestsv2.OpOption
+
+	// invoke one step and returns whether it is succeeded, outputs are stored into caseParams
+	invoke func(hc *http.Client, step apistructs.AutoTestLoadTestStep, caseParams map[string]*apistructs.CaseParams) bool
+}
+
+type Option func(*Runner)
+
+// WithTestEnv set global config, which is shared by all virtual users.
+func WithTestEnv(testEnv *apistructs.APITestEnvData, caseParams map[string]*apistructs.CaseParams) Option {
+	return func(r *Runner) {
+		r.testEnv = testEnv
+		r.caseParams = caseParams
+	}
+}
+
+// WithCookies set initial cookies of every virtual user.
+func WithCookies(cookies cookiejar.Cookies) Option {
+	return func(r *Runner) {
+		r.cookies = cookies
+	}
+}
+
+// WithAPITestOptions set options of apitestsv2, e.g. netportal.
+func WithAPITestOptions(opts ...apitestsv2.OpOption) Option {
+	return func(r *Runner) {
+		r.apiOpts = opts
+	}
+}
+
+func New(steps []apistructs.AutoTestLoadTestStep, config apistructs.AutoTestLoadTestConfig, options ...Option) *Runner {
+	r := &Runner{
+		steps:      steps,
+		config:     config,
+		caseParams: map[string]*apistructs.CaseParams{},
+	}
+	r.invoke = r.invokeAPI
+	for _, op := range options {
+		op(r)
+	}
+	return r
+}
+
+// Run blocks until the duration is reached or ctx is done, then returns the report judged by thresholds.
+func (r *Runner) Run(ctx context.Context) *apistructs.AutoTestLoadTestReport {
+	stats := make([]*stat, len(r.steps))
+	for i := range stats {
+		stats[i] = newStat()
+	}
+	total := newStat()
+
+	startedAt := time.Now()
+	ctx, cancel := context.WithDeadline(ctx, startedAt.Add(time.Duration(r.config.DurationSec)*time.Second))
+	defer cancel()
+
+	rampUp := time.Duration(r.config.RampUpSec) * time.Second
+	var wg sync.WaitGroup
+	for i := 0; i < r.config.VirtualUsers; i++ {
+		delay := rampUp * time.Duration(i) / time.Duration(r.config.VirtualUsers)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !sleep(ctx, delay) {
+				return
+			}
+			r.virtualUser(ctx, stats, total)
+		}()
+	}
+	wg.Wait()
+	endedAt := time.Now()
+
+	elapsed := endedAt.Sub(startedAt)
+	report := &apistructs.AutoTestLoadTestReport{
+		Config:    r.config,
+		StartedAt: startedAt,
+		EndedAt:   endedAt,
+		Total:     total.result(elapsed),
+	}
+	report.Total.Name = "total"
+	for i, step := range r.steps {
+		s := stats[i].result(elapsed)
+		s.StepID = step.ID
+		s.Name = step.Name
+		report.Steps = append(report.Steps, s)
+	}
+	Judge(report)
+	return report
+}
+
+func (r *Runner) virtualUser(ctx context.Context, stats []*stat, total *stat) {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if len(r.cookies) > 0 {
+		jar.SetEntries(r.cookies)
+	}
+	hc := &http.Client{Jar: jar}
+	thinkTime := time.Duration(r.config.ThinkTimeMs) * time.Millisecond
+
+	for ctx.Err() == nil {
+		// every iteration starts from the global params
+		caseParams := make(map[string]*apistructs.CaseParams, len(r.caseParams))
+		for k, v := range r.caseParams {
+			caseParams[k] = v
+		}
+		for i, step := range r.steps {
+			if ctx.Err() != nil {
+				return
+			}
+			begin := time.Now()
+			succ := r.invoke(hc, step, caseParams)
+			latency := time.Since(begin)
+			stats[i].add(latency, succ)
+			total.add(latency, succ)
+			if !sleep(ctx, thinkTime) {
+				return
+			}
+			// the latter steps may depend on outputs of the failed one
+			if !succ {
+				break
+			}
+		}
+	}
+}
+
+func (r *Runner) invokeAPI(hc *http.Client, step apistructs.AutoTestLoadTestStep, caseParams map[string]*apistructs.CaseParams) bool {
+	at := apitestsv2.New(newAPIInfo(step.APISpec), r.apiOpts...)
+	_, resp, err := at.Invoke(hc, r.testEnv, caseParams)
+	if err != nil {
+		return false
+	}
+	outParams := at.ParseOutParams(at.API.OutParams, resp, caseParams)
+	for _, group := range at.API.Asserts {
+		if len(group) == 0 {
+			continue
+		}
+		if succ, _ := at.JudgeAsserts(outParams, group); !succ {
+			return false
+		}
+	}
+	return true
+}
+
+// newAPIInfo copy the spec for every invocation, because it is rendered in place.
+func newAPIInfo(spec apistructs.APIInfoV2) *apistructs.APIInfo {
+	api := &apistructs.APIInfo{
+		ID:        spec.ID,
+		Name:      spec.Name,
+		URL:       spec.URL,
+		Method:    spec.Method,
+		Headers:   append([]apistructs.APIHeader(nil), spec.Headers...),
+		Params:    append([]apistructs.APIParam(nil), spec.Params...),
+		Body:      spec.Body,
+		OutParams: append([]apistructs.APIOutParam(nil), spec.OutParams...),
+		Asserts:   [][]apistructs.APIAssert{append([]apistructs.APIAssert(nil), spec.Asserts...)},
+		Protocol:  spec.Protocol,
+		GRPC:      spec.GRPC,
+	}
+	if spec.WebSocket != nil {
+		ws := *spec.WebSocket
+		ws.Messages = append([]string(nil), ws.Messages...)
+		api.WebSocket = &ws
+	}
+	return api
+}
+
+// Judge check the report by thresholds of config.
+func Judge(report *apistructs.AutoTestLoadTestReport) {
+	t := report.Config.Thresholds
+	var violations []string
+	check := func(s apistructs.AutoTestLoadTestStat) {
+		if t.MaxErrorRate > 0 && s.ErrorRate > t.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("%s: error rate %.2f%% exceeds %.2f%%", s.Name, s.ErrorRate, t.MaxErrorRate))
+		}
+		if t.MaxAvgMs > 0 && s.AvgMs > t.MaxAvgMs {
+			violations = append(violations, fmt.Sprintf("%s: avg latency %.2fms exceeds %.2fms", s.Name, s.AvgMs, t.MaxAvgMs))
+		}
+		if t.MaxP95Ms > 0 && s.P95Ms > t.MaxP95Ms {
+			violations = append(violations, fmt.Sprintf("%s: p95 latency %.2fms exceeds %.2fms", s.Name, s.P95Ms, t.MaxP95Ms))
+		}
+		if t.MaxP99Ms > 0 && s.P99Ms > t.MaxP99Ms {
+			violations = append(violations, fmt.Sprintf("%s: p99 latency %.2fms exceeds %.2fms", s.Name, s.P99Ms, t.MaxP99Ms))
+		}
+	}
+	for _, s := range report.Steps {
+		check(s)
+	}
+	if report.Total.Requests == 0 {
+		violations = append(violations, "no request is executed")
+	}
+	if t.MinThroughput > 0 && report.Total.Throughput < t.MinThroughput {
+		violations = append(violations, fmt.Sprintf("throughput %.2f/s is lower than %.2f/s", report.Total.Throughput, t.MinThroughput))
+	}
+	report.Violations = violations
+	report.Passed = len(violations) == 0
+}
+
+// sleep returns false if ctx is done before d elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadtest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestRunner_Run(t *testing.T) {
+	steps := []apistructs.AutoTestLoadTestStep{{ID: 1, Name: "login"}, {ID: 2, Name: "list"}}
+	config := apistructs.AutoTestLoadTestConfig{
+		VirtualUsers: 4,
+		RampUpSec:    0,
+		DurationSec:  1,
+		ThinkTimeMs:  10,
+		Thresholds:   apistructs.AutoTestLoadTestThresholds{MaxErrorRate: 1, MaxP99Ms: 1000},
+	}
+	var missingOutput int32
+	r := New(steps, config, WithTestEnv(nil, map[string]*apistructs.CaseParams{"host": {Key: "host", Value: "erda.cloud"}}))
+	r.invoke = func(hc *http.Client, step apistructs.AutoTestLoadTestStep, caseParams map[string]*apistructs.CaseParams) bool {
+		time.Sleep(5 * time.Millisecond)
+		switch step.ID {
+		case 1:
+			caseParams["token"] = &apistructs.CaseParams{Key: "token", Value: "t"}
+		case 2:
+			if caseParams["token"] == nil || caseParams["host"] == nil {
+				atomic.AddInt32(&missingOutput, 1)
+			}
+		}
+		return true
+	}
+
+	report := r.Run(context.Background())
+	assert.True(t, report.Passed, report.Violations)
+	assert.Equal(t, int32(0), missingOutput)
+	assert.Equal(t, 2, len(report.Steps))
+	assert.Equal(t, "login", report.Steps[0].Name)
+	assert.Equal(t, uint64(2), report.Steps[1].StepID)
+	assert.True(t, report.Steps[1].Requests > 0)
+	assert.True(t, report.Steps[0].Requests >= report.Steps[1].Requests)
+	assert.Equal(t, report.Steps[0].Requests+report.Steps[1].Requests, report.Total.Requests)
+	assert.Equal(t, int64(0), report.Total.Errors)
+	assert.True(t, report.Total.Throughput > 0)
+	assert.True(t, report.Total.P50Ms >= 5)
+	assert.True(t, report.EndedAt.Sub(report.StartedAt) >= time.Second)
+}
+
+func TestRunner_RunFailedStep(t *testing.T) {
+	steps := []apistructs.AutoTestLoadTestStep{{ID: 1, Name: "login"}, {ID: 2, Name: "list"}}
+	config := apistructs.AutoTestLoadTestConfig{
+		VirtualUsers: 2,
+		RampUpSec:    0,
+		DurationSec:  1,
+		ThinkTimeMs:  50,
+		Thresholds:   apistructs.AutoTestLoadTestThresholds{MaxErrorRate: 10},
+	}
+	r := New(steps, config)
+	r.invoke = func(hc *http.Client, step apistructs.AutoTestLoadTestStep, caseParams map[string]*apistructs.CaseParams) bool {
+		return step.ID != 1
+	}
+
+	report := r.Run(context.Background())
+	assert.False(t, report.Passed)
+	assert.Equal(t, float64(100), report.Steps[0].ErrorRate)
+	assert.Equal(t, int64(0), report.Steps[1].Requests)
+	assert.Equal(t, []string{"login: error rate 100.00% exceeds 10.00%"}, report.Violations)
+}
+
+func TestJudge(t *testing.T) {
+	report := &apistructs.AutoTestLoadTestReport{
+		Config: apistructs.AutoTestLoadTestConfig{Thresholds: apistructs.AutoTestLoadTestThresholds{
+			MaxAvgMs:      100,
+			MaxP95Ms:      200,
+			MinThroughput: 50,
+		}},
+		Total: apistructs.AutoTestLoadTestStat{Name: "total", Requests: 100, Throughput: 10},
+		Steps: []apistructs.AutoTestLoadTestStat{
+			{Name: "a", Requests: 50, AvgMs: 50, P95Ms: 300},
+			{Name: "b", Requests: 50, AvgMs: 150, P95Ms: 100, ErrorRate: 50},
+		},
+	}
+	Judge(report)
+	assert.False(t, report.Passed)
+	assert.Equal(t, []string{
+		"a: p95 latency 300.00ms exceeds 200.00ms",
+		"b: avg latency 150.00ms exceeds 100.00ms",
+		"throughput 10.00/s is lower than 50.00/s",
+	}, report.Violations)
+
+	report.Config.Thresholds = apistructs.AutoTestLoadTestThresholds{}
+	Judge(report)
+	assert.True(t, report.Passed)
+
+	report.Total.Requests = 0
+	Judge(report)
+	assert.False(t, report.Passed)
+}
+
+func TestStat(t *testing.T) {
+	s := newStat()
+	for i := 100; i > 0; i-- {
+		s.add(time.Duration(i)*time.Millisecond, i%10 != 0)
+	}
+	r := s.result(2 * time.Second)
+	assert.Equal(t, int64(100), r.Requests)
+	assert.Equal(t, int64(10), r.Errors)
+	assert.Equal(t, float64(10), r.ErrorRate)
+	assert.Equal(t, float64(50), r.Throughput)
+	assert.Equal(t, 50.5, r.AvgMs)
+	assert.Equal(t, float64(1), r.MinMs)
+	assert.Equal(t, float64(100), r.MaxMs)
+	assert.Equal(t, float64(50), r.P50Ms)
+	assert.Equal(t, float64(90), r.P90Ms)
+	assert.Equal(t, float64(95), r.P95Ms)
+	assert.Equal(t, float64(99), r.P99Ms)
+
+	assert.Equal(t, apistructs.AutoTestLoadTestStat{}, newStat().result(time.Second))
+}
+
+func TestStatReservoir(t *testing.T) {
+	s := newStat()
+	for i := 0; i < reservoirSize*3; i++ {
+		s.add(time.Millisecond, true)
+	}
+	assert.Equal(t, reservoirSize, len(s.samples))
+	assert.Equal(t, int64(reservoirSize*3), s.result(time.Second).Requests)
+}
+
+func Test_newAPIInfo(t *testing.T) {
+	spec := apistructs.APIInfoV2{
+		Headers:   []apistructs.APIHeader{{Key: "a", Value: "{{b}}"}},
+		WebSocket: &apistructs.APIWebSocketConfig{Messages: []string{"{{c}}"}},
+	}
+	api := newAPIInfo(spec)
+	api.Headers[0].Value = "b"
+	api.WebSocket.Messages[0] = "c"
+	assert.Equal(t, "{{b}}", spec.Headers[0].Value)
+	assert.Equal(t, "{{c}}", spec.WebSocket.Messages[0])
+	assert.Equal(t, 1, len(api.Asserts))
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadtest
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// reservoirSize latency samples kept for percentiles of each step
+const reservoirSize = 10000
+
+// stat collects latencies, count and errors are exact and percentiles are calculated by reservoir sampling.
+type stat struct {
+	mu       sync.Mutex
+	requests int64
+	errors   int64
+	sum      time.Duration
+	min      time.Duration
+	max      time.Duration
+	samples  []time.Duration
+	rnd      *rand.Rand
+}
+
+func newStat() *stat {
+	return &stat{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (s *stat) add(latency time.Duration, succ bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if !succ {
+		s.errors++
+	}
+	s.sum += latency
+	if s.requests == 1 || latency < s.min {
+		s.min = latency
+	}
+	if latency > s.max {
+		s.max = latency
+	}
+	if len(s.samples) < reservoirSize {
+		s.samples = append(s.samples, latency)
+		return
+	}
+	if i := s.rnd.Int63n(s.requests); i < reservoirSize {
+		s.samples[i] = latency
+	}
+}
+
+func (s *stat) result(elapsed time.Duration) apistructs.AutoTestLoadTestStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var r apistructs.AutoTestLoadTestStat
+	if s.requests == 0 {
+		return r
+	}
+	r.Requests = s.requests
+	r.Errors = s.errors
+	r.ErrorRate = round(float64(s.errors) * 100 / float64(s.requests))
+	if elapsed > 0 {
+		r.Throughput = round(float64(s.requests) / elapsed.Seconds())
+	}
+	r.AvgMs = ms(s.sum / time.Duration(s.requests))
+	r.MinMs = ms(s.min)
+	r.MaxMs = ms(s.max)
+
+	samples := append([]time.Duration(nil), s.samples...)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	r.P50Ms = ms(percentile(samples, 50))
+	r.P90Ms = ms(percentile(samples, 90))
+	r.P95Ms = ms(percentile(samples, 95))
+	r.P99Ms = ms(percentile(samples, 99))
+	return r
+}
+
+// percentile by nearest-rank of sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func ms(d time.Duration) float64 {
+	return round(float64(d) / float64(time.Millisecond))
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
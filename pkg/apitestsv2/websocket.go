@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const defaultWebSocketTimeout = 10 * time.Second
+
+// invokeWebSocket 执行 WebSocket API 测试：连接，按序发送消息，并在超时时间内等待期望数量的消息
+//
+// resp.Status and resp.Headers are from the handshake response,
+// resp.Body is the json array of received messages, json message is kept as object, others as string.
+func (at *APITest) invokeWebSocket(testEnv *apistructs.APITestEnvData) (*apistructs.APIRequestInfo, *apistructs.APIResp, error) {
+	conf := at.API.WebSocket
+	if conf == nil {
+		conf = &apistructs.APIWebSocketConfig{}
+	}
+
+	var domain string
+	if testEnv != nil {
+		domain = testEnv.Domain
+	}
+	wsURL, err := polishWebSocketURL(at.API.URL, domain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(at.API.Params) > 0 {
+		u, err := url.Parse(wsURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid url: %s, err: %v", wsURL, err)
+		}
+		query := u.Query()
+		for _, p := range at.API.Params {
+			if p.Key != "" {
+				query.Add(p.Key, p.Value)
+			}
+		}
+		u.RawQuery = query.Encode()
+		wsURL = u.String()
+	}
+
+	var apiReq apistructs.APIRequestInfo
+	apiReq.URL = wsURL
+	apiReq.Method = http.MethodGet
+	apiReq.Headers = make(http.Header)
+	if testEnv != nil && testEnv.Header != nil {
+		for k, v := range testEnv.Header {
+			apiReq.Headers.Set(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	for _, h := range at.API.Headers {
+		if h.Key != "" {
+			apiReq.Headers.Set(h.Key, h.Value)
+		}
+	}
+	apiReq.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeText, Content: strings.Join(conf.Messages, "\n")}
+
+	timeout := defaultWebSocketTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: timeout}
+	conn, httpResp, err := dialer.Dial(wsURL, websocketHandshakeHeaders(apiReq.Headers))
+	var apiResp apistructs.APIResp
+	if httpResp != nil {
+		apiResp.Status = httpResp.StatusCode
+		apiResp.Headers = httpResp.Header
+	}
+	if err != nil {
+		return &apiReq, &apiResp, fmt.Errorf("failed to connect websocket %s, err: %v", wsURL, err)
+	}
+	defer conn.Close()
+
+	for _, msg := range conf.Messages {
+		_ = conn.SetWriteDeadline(deadline)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			return &apiReq, &apiResp, fmt.Errorf("failed to send websocket message, err: %v", err)
+		}
+	}
+
+	received := make([]interface{}, 0, conf.Expect)
+	var readErr error
+	for len(received) < conf.Expect {
+		_ = conn.SetReadDeadline(deadline)
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			readErr = err
+			break
+		}
+		received = append(received, websocketMessageValue(msg))
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+
+	body, err := json.Marshal(received)
+	if err != nil {
+		return &apiReq, &apiResp, err
+	}
+	apiResp.Body = body
+	apiResp.BodyStr = string(body)
+	if readErr != nil {
+		return &apiReq, &apiResp, fmt.Errorf("expect %d websocket messages within %s, but received %d, err: %v",
+			conf.Expect, timeout, len(received), readErr)
+	}
+
+	return &apiReq, &apiResp, nil
+}
+
+// polishWebSocketURL keep ws:// and wss://, and convert http:// and https://, domain is used if url has no schema.
+func polishWebSocketURL(rawurl string, domain string) (string, error) {
+	rawurl = strings.TrimSpace(rawurl)
+	if !strings.HasPrefix(rawurl, "ws://") && !strings.HasPrefix(rawurl, "wss://") {
+		u, err := polishURL(rawurl, domain)
+		if err != nil {
+			return "", err
+		}
+		rawurl = u
+		if strings.HasPrefix(u, "https://") {
+			rawurl = "wss://" + strings.TrimPrefix(u, "https://")
+		} else if strings.HasPrefix(u, "http://") {
+			rawurl = "ws://" + strings.TrimPrefix(u, "http://")
+		}
+	}
+	return rawurl, nil
+}
+
+// websocketHandshakeHeaders drop headers generated by the websocket dialer.
+func websocketHandshakeHeaders(headers http.Header) http.Header {
+	h := make(http.Header)
+	for k, v := range headers {
+		switch http.CanonicalHeaderKey(k) {
+		case "Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions":
+			continue
+		}
+		h[k] = v
+	}
+	return h
+}
+
+func websocketMessageValue(msg []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(msg, &v); err == nil {
+		return v
+	}
+	return string(msg)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func startEchoServer() *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, http.Header{"X-Erda-Token": r.Header["X-Erda-Token"]})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestAPITest_invokeWebSocket(t *testing.T) {
+	s := startEchoServer()
+	defer s.Close()
+
+	at := New(&apistructs.APIInfo{
+		URL:     s.URL + "/ws",
+		Params:  []apistructs.APIParam{{Key: "id", Value: "1"}},
+		Headers: []apistructs.APIHeader{{Key: "X-Erda-Token", Value: "t"}},
+		WebSocket: &apistructs.APIWebSocketConfig{
+			Messages: []string{`{"name":"erda"}`, "hello"},
+			Expect:   2,
+			Timeout:  1,
+		},
+	})
+	req, resp, err := at.invokeWebSocket(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ws://"+s.Listener.Addr().String()+"/ws?id=1", req.URL)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.Status)
+	assert.JSONEq(t, `[{"name":"erda"},"hello"]`, resp.BodyStr)
+
+	outParams := at.ParseOutParams([]apistructs.APIOutParam{
+		{Key: "name", Source: apistructs.APIOutParamSourceBodyJson, Expression: ".[0].name"},
+		{Key: "token", Source: apistructs.APIOutParamSourceHeader, Expression: "X-Erda-Token"},
+	}, resp, map[string]*apistructs.CaseParams{})
+	assert.Equal(t, "erda", outParams["name"])
+	assert.Equal(t, "t", outParams["token"])
+
+	// expect more messages than received
+	at.API.WebSocket.Expect = 3
+	_, resp, err = at.invokeWebSocket(nil)
+	assert.Error(t, err)
+	assert.JSONEq(t, `[{"name":"erda"},"hello"]`, resp.BodyStr)
+}
+
+func Test_polishWebSocketURL(t *testing.T) {
+	tt := []struct {
+		url    string
+		domain string
+		want   string
+	}{
+		{"ws://erda.cloud/ws?a=1", "", "ws://erda.cloud/ws?a=1"},
+		{"wss://erda.cloud/ws", "", "wss://erda.cloud/ws"},
+		{"https://erda.cloud/ws", "", "wss://erda.cloud/ws"},
+		{"/ws", "http://erda.cloud", "ws://erda.cloud/ws"},
+	}
+	for _, v := range tt {
+		got, err := polishWebSocketURL(v.url, v.domain)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, got)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const maxGenerateDepth = 8
+
+var formatExamples = map[string]string{
+	"date-time": "2021-01-01T00:00:00Z",
+	"date":      "2021-01-01",
+	"time":      "00:00:00",
+	"email":     "user@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"byte":      "c3RyaW5n",
+	"password":  "******",
+}
+
+// Generate 根据 schema 生成示例值. 依次使用 example, default, enum 的第一个值, 否则按类型和 format 生成.
+// 自引用的 schema 展开到引用自身时生成 nil.
+func Generate(schema *openapi3.Schema) interface{} {
+	return generate(schema, make(map[*openapi3.Schema]bool), 0)
+}
+
+func generate(schema *openapi3.Schema, visiting map[*openapi3.Schema]bool, depth int) interface{} {
+	if schema == nil || visiting[schema] || depth > maxGenerateDepth {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+
+	visiting[schema] = true
+	defer delete(visiting, schema)
+
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, ref := range schema.AllOf {
+			if ref == nil {
+				continue
+			}
+			if m, ok := generate(ref.Value, visiting, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		if m, ok := generateObject(schema, visiting, depth).(map[string]interface{}); ok {
+			for k, v := range m {
+				merged[k] = v
+			}
+		}
+		return merged
+	}
+	for _, refs := range []openapi3.SchemaRefs{schema.OneOf, schema.AnyOf} {
+		if len(refs) > 0 && refs[0] != nil {
+			return generate(refs[0].Value, visiting, depth+1)
+		}
+	}
+
+	switch strings.ToLower(schema.Type) {
+	case "string":
+		if v, ok := formatExamples[schema.Format]; ok {
+			return v
+		}
+		return "string"
+	case "integer":
+		if schema.Min != nil {
+			return int64(*schema.Min)
+		}
+		return 0
+	case "number":
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0.0
+	case "boolean":
+		return true
+	case "array":
+		n := int(schema.MinItems)
+		if n < 1 {
+			n = 1
+		}
+		if n > 5 {
+			n = 5
+		}
+		var item *openapi3.Schema
+		if schema.Items != nil {
+			item = schema.Items.Value
+		}
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			if v := generate(item, visiting, depth+1); v != nil {
+				list = append(list, v)
+			}
+		}
+		return list
+	case "object", "":
+		return generateObject(schema, visiting, depth)
+	}
+	return nil
+}
+
+func generateObject(schema *openapi3.Schema, visiting map[*openapi3.Schema]bool, depth int) interface{} {
+	if schema.Type == "" && len(schema.Properties) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(schema.Properties))
+	for name, ref := range schema.Properties {
+		if ref == nil {
+			continue
+		}
+		m[name] = generate(ref.Value, visiting, depth+1)
+	}
+	return m
+}
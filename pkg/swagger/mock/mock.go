@@ -0,0 +1,361 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mock 根据 OpenAPI 3 文档模拟接口: 校验请求, 返回文档中的示例或根据 schema 生成的响应.
+// OAS2 文档应先转换为 OAS3.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// HeaderScenario 请求头中指定要使用的场景名称, 响应头中返回命中的场景名称
+	HeaderScenario = "X-Mock-Scenario"
+
+	// maxDelay 场景响应延迟的上限
+	maxDelay = 30 * time.Second
+)
+
+var pathParamRegexp = regexp.MustCompile(`\{([^}]*)}`)
+
+// Mock 由一份 OpenAPI 3 文档构建的模拟服务, 构建后只读, 可以并发使用
+type Mock struct {
+	v3        *openapi3.Swagger
+	routes    []*route
+	basePaths []string
+}
+
+type route struct {
+	path    string
+	pattern *regexp.Regexp
+	names   []string
+	literal int
+	item    *openapi3.PathItem
+}
+
+// Request 模拟请求, Path 为去掉 mock 地址前缀后的接口路径
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Response 模拟响应
+type Response struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	Scenario string
+	Delay    time.Duration
+}
+
+// ErrorBody 模拟服务自身产生的错误响应体, 如路径不存在, 请求校验不通过
+type ErrorBody struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// New 构建模拟服务
+func New(v3 *openapi3.Swagger) *Mock {
+	m := &Mock{v3: v3}
+	for path, item := range v3.Paths {
+		if item == nil {
+			continue
+		}
+		m.routes = append(m.routes, newRoute(path, item))
+	}
+	// 字面量越多的路径越具体, 优先匹配, 如 /users/me 优先于 /users/{id}
+	sort.Slice(m.routes, func(i, j int) bool {
+		if m.routes[i].literal != m.routes[j].literal {
+			return m.routes[i].literal > m.routes[j].literal
+		}
+		return m.routes[i].path < m.routes[j].path
+	})
+	for _, server := range v3.Servers {
+		if server == nil {
+			continue
+		}
+		if u, err := url.Parse(server.URL); err == nil {
+			if base := strings.TrimRight(u.Path, "/"); base != "" {
+				m.basePaths = append(m.basePaths, base)
+			}
+		}
+	}
+	return m
+}
+
+// NewRequest 从 http 请求构造模拟请求
+func NewRequest(r *http.Request, path string) (*Request, error) {
+	var body []byte
+	if r.Body != nil {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return &Request{
+		Method: strings.ToUpper(r.Method),
+		Path:   path,
+		Query:  r.URL.Query(),
+		Header: r.Header,
+		Body:   body,
+	}, nil
+}
+
+// Serve 处理一次模拟请求. scenarios 按顺序匹配, 第一个命中的场景覆盖默认响应.
+func (m *Mock) Serve(req *Request, scenarios []*Scenario) *Response {
+	rt, pathParams := m.match(req.Path)
+	if rt == nil {
+		return errorResponse(http.StatusNotFound, "PathNotFound", fmt.Sprintf("no path in the document matches %s", req.Path))
+	}
+	operation := rt.item.GetOperation(req.Method)
+	if operation == nil {
+		return errorResponse(http.StatusMethodNotAllowed, "MethodNotAllowed",
+			fmt.Sprintf("method %s is not defined for path %s", req.Method, rt.path))
+	}
+
+	scenario := selectScenario(scenarios, req, rt.path)
+	if scenario == nil || !scenario.SkipValidation {
+		if errs := validateRequest(req, pathParams, rt.item, operation); len(errs) > 0 {
+			resp := errorResponse(http.StatusBadRequest, "InvalidRequest", "request does not match the document", errs...)
+			if scenario != nil {
+				resp.Header.Set(HeaderScenario, scenario.Name)
+				resp.Scenario = scenario.Name
+			}
+			return resp
+		}
+	}
+
+	resp := &Response{Header: make(http.Header)}
+	var status int
+	if scenario != nil {
+		status = scenario.Status
+	}
+	code, responseRef := selectResponse(operation.Responses, status)
+	resp.Status = code
+	if status != 0 {
+		resp.Status = status
+	}
+	if responseRef != nil && responseRef.Value != nil {
+		mediaType, content := selectContent(responseRef.Value.Content, req.Header.Get("Accept"))
+		if content != nil {
+			resp.Body = renderContent(mediaType, content)
+			resp.Header.Set("Content-Type", mediaType)
+		}
+	}
+
+	if scenario != nil {
+		scenario.apply(resp)
+		resp.Header.Set(HeaderScenario, scenario.Name)
+		resp.Scenario = scenario.Name
+	}
+	return resp
+}
+
+// Write 将响应写入 w, 场景设置了延迟时先等待
+func (r *Response) Write(w http.ResponseWriter) error {
+	if r.Delay > 0 {
+		delay := r.Delay
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		time.Sleep(delay)
+	}
+	for k, values := range r.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(r.Status)
+	_, err := w.Write(r.Body)
+	return err
+}
+
+func newRoute(path string, item *openapi3.PathItem) *route {
+	rt := &route{path: path, item: item}
+	var (
+		expr strings.Builder
+		last int
+	)
+	expr.WriteString("^")
+	for _, loc := range pathParamRegexp.FindAllStringSubmatchIndex(path, -1) {
+		expr.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		expr.WriteString("([^/]+)")
+		rt.names = append(rt.names, path[loc[2]:loc[3]])
+		rt.literal += loc[0] - last
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(path[last:]))
+	expr.WriteString("/?$")
+	rt.literal += len(path) - last
+	rt.pattern = regexp.MustCompile(expr.String())
+	return rt
+}
+
+// match 查找匹配请求路径的接口, 文档 servers 中声明的路径前缀可带可不带
+func (m *Mock) match(path string) (*route, map[string]string) {
+	candidates := []string{path}
+	for _, base := range m.basePaths {
+		if strings.HasPrefix(path, base+"/") {
+			candidates = append(candidates, strings.TrimPrefix(path, base))
+		}
+	}
+	for _, p := range candidates {
+		for _, rt := range m.routes {
+			values := rt.pattern.FindStringSubmatch(p)
+			if values == nil {
+				continue
+			}
+			params := make(map[string]string, len(rt.names))
+			for i, name := range rt.names {
+				v, err := url.PathUnescape(values[i+1])
+				if err != nil {
+					v = values[i+1]
+				}
+				params[name] = v
+			}
+			return rt, params
+		}
+	}
+	return nil, nil
+}
+
+// selectResponse 选择响应: 指定了状态码时优先使用对应的定义, 否则使用最小的 2xx, 再次使用 default
+func selectResponse(responses openapi3.Responses, status int) (int, *openapi3.ResponseRef) {
+	if status != 0 {
+		if ref, ok := responses[fmt.Sprint(status)]; ok {
+			return status, ref
+		}
+		if ref, ok := responses[fmt.Sprintf("%dXX", status/100)]; ok {
+			return status, ref
+		}
+		return status, responses["default"]
+	}
+
+	var codes []string
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			var n int
+			if _, err := fmt.Sscanf(code, "%d", &n); err != nil || n < 200 {
+				n = http.StatusOK
+			}
+			return n, responses[code]
+		}
+	}
+	if ref, ok := responses["default"]; ok {
+		return http.StatusOK, ref
+	}
+	for _, code := range codes {
+		var n int
+		if _, err := fmt.Sscanf(code, "%d", &n); err == nil {
+			return n, responses[code]
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// selectContent 按 Accept 选择响应的媒体类型, 无法满足时优先 JSON
+func selectContent(content openapi3.Content, accept string) (string, *openapi3.MediaType) {
+	if len(content) == 0 {
+		return "", nil
+	}
+	var types []string
+	for mt := range content {
+		types = append(types, mt)
+	}
+	sort.Strings(types)
+	for _, part := range strings.Split(accept, ",") {
+		want := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if want == "" || want == "*/*" {
+			continue
+		}
+		for _, mt := range types {
+			if mt == want {
+				return mt, content[mt]
+			}
+		}
+	}
+	for _, mt := range types {
+		if isJSON(mt) {
+			return mt, content[mt]
+		}
+	}
+	return types[0], content[types[0]]
+}
+
+// renderContent 响应体优先使用文档中的 example, 其次是 examples 中的第一个, 最后根据 schema 生成
+func renderContent(mediaType string, content *openapi3.MediaType) []byte {
+	value := content.Example
+	if value == nil && len(content.Examples) > 0 {
+		var names []string
+		for name := range content.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ref := content.Examples[name]; ref != nil && ref.Value != nil && ref.Value.Value != nil {
+				value = ref.Value.Value
+				break
+			}
+		}
+	}
+	if value == nil && content.Schema != nil {
+		value = Generate(content.Schema.Value)
+	}
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.(string); ok && !isJSON(mediaType) {
+		return []byte(s)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return []byte(fmt.Sprint(value))
+	}
+	return data
+}
+
+func errorResponse(status int, code, message string, errs ...string) *Response {
+	data, _ := json.Marshal(ErrorBody{Code: code, Message: message, Errors: errs})
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	return &Response{Status: status, Header: header, Body: data}
+}
+
+func isJSON(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	return strings.Contains(mediaType, "json")
+}
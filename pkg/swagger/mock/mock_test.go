@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/erda-project/erda/pkg/swagger/mock"
+	"github.com/erda-project/erda/pkg/swagger/oas3"
+)
+
+const petstore = `
+openapi: 3.0.0
+info:
+  title: petstore
+  version: 1.0.0
+servers:
+  - url: https://petstore.example.com/v1
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              example:
+                id: 1
+                name: kitty
+        "400":
+          description: bad request
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        "404":
+          description: not found
+          content:
+            application/json:
+              example:
+                message: pet not found
+  /pets/mine:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            text/plain:
+              example: mine
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+        status:
+          type: string
+          enum: [available, sold]
+        createdAt:
+          type: string
+          format: date-time
+`
+
+func newMock(t *testing.T) *mock.Mock {
+	v3, err := oas3.LoadFromData([]byte(petstore))
+	if err != nil {
+		t.Fatalf("failed to load document: %v", err)
+	}
+	return mock.New(v3)
+}
+
+func newRequest(method, path, query, body string) *mock.Request {
+	q, _ := url.ParseQuery(query)
+	header := make(http.Header)
+	if body != "" {
+		header.Set("Content-Type", "application/json")
+	}
+	return &mock.Request{Method: method, Path: path, Query: q, Header: header, Body: []byte(body)}
+}
+
+func TestServe(t *testing.T) {
+	m := newMock(t)
+
+	resp := m.Serve(newRequest(http.MethodGet, "/pets/1", "", ""), nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.Status, resp.Body)
+	}
+	var pet map[string]interface{}
+	if err := json.Unmarshal(resp.Body, &pet); err != nil {
+		t.Fatalf("invalid body %s: %v", resp.Body, err)
+	}
+	if pet["status"] != "available" || pet["name"] != "string" || pet["createdAt"] != "2021-01-01T00:00:00Z" {
+		t.Errorf("unexpected generated body: %s", resp.Body)
+	}
+
+	// 路径前缀来自 servers, 字面量路径优先于参数路径
+	resp = m.Serve(newRequest(http.MethodGet, "/v1/pets/mine", "", ""), nil)
+	if resp.Status != http.StatusOK || string(resp.Body) != "mine" || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected response %d %s", resp.Status, resp.Body)
+	}
+
+	resp = m.Serve(newRequest(http.MethodPost, "/pets", "", `{"name":"kitty"}`), nil)
+	if resp.Status != http.StatusCreated || string(resp.Body) != `{"id":1,"name":"kitty"}` {
+		t.Errorf("unexpected response %d %s", resp.Status, resp.Body)
+	}
+
+	resp = m.Serve(newRequest(http.MethodGet, "/pets", "", ""), nil)
+	var pets []interface{}
+	if err := json.Unmarshal(resp.Body, &pets); err != nil || len(pets) != 1 {
+		t.Errorf("unexpected list body %s", resp.Body)
+	}
+
+	if resp := m.Serve(newRequest(http.MethodGet, "/stores", "", ""), nil); resp.Status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.Status)
+	}
+	if resp := m.Serve(newRequest(http.MethodDelete, "/pets/1", "", ""), nil); resp.Status != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", resp.Status)
+	}
+}
+
+func TestServeValidation(t *testing.T) {
+	m := newMock(t)
+	cases := []struct {
+		name string
+		req  *mock.Request
+	}{
+		{"path param type", newRequest(http.MethodGet, "/pets/abc", "", "")},
+		{"query param maximum", newRequest(http.MethodGet, "/pets", "limit=1000", "")},
+		{"missing body", newRequest(http.MethodPost, "/pets", "", "")},
+		{"missing required property", newRequest(http.MethodPost, "/pets", "", `{"id":1}`)},
+		{"enum", newRequest(http.MethodPost, "/pets", "", `{"name":"kitty","status":"lost"}`)},
+		{"invalid json", newRequest(http.MethodPost, "/pets", "", `{"name":`)},
+	}
+	for _, c := range cases {
+		resp := m.Serve(c.req, nil)
+		if resp.Status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %s", c.name, resp.Status, resp.Body)
+			continue
+		}
+		var body mock.ErrorBody
+		if err := json.Unmarshal(resp.Body, &body); err != nil || len(body.Errors) == 0 {
+			t.Errorf("%s: unexpected error body %s", c.name, resp.Body)
+		}
+	}
+}
+
+func TestServeScenario(t *testing.T) {
+	m := newMock(t)
+	scenarios := []*mock.Scenario{
+		{Name: "not-found", Method: http.MethodGet, Path: "/pets/{petId}", Status: http.StatusNotFound},
+		{Name: "sold", Enabled: true, Path: "/pets/{petId}", Query: map[string]string{"view": "sold"},
+			Body: `{"id":2,"name":"dog","status":"sold"}`, ResponseHeader: map[string]string{"X-Total": "1"}},
+		{Name: "invalid", Method: http.MethodPost, Status: http.StatusBadRequest, SkipValidation: true},
+	}
+	for _, s := range scenarios {
+		if err := s.Validate(); err != nil {
+			t.Fatalf("scenario %s: %v", s.Name, err)
+		}
+	}
+
+	// 通过请求头指定场景, 响应体取文档中该状态码的示例
+	req := newRequest(http.MethodGet, "/pets/1", "", "")
+	req.Header.Set(mock.HeaderScenario, "not-found")
+	resp := m.Serve(req, scenarios)
+	if resp.Status != http.StatusNotFound || string(resp.Body) != `{"message":"pet not found"}` || resp.Header.Get(mock.HeaderScenario) != "not-found" {
+		t.Errorf("unexpected response %d %s", resp.Status, resp.Body)
+	}
+
+	// 按条件命中启用的场景
+	resp = m.Serve(newRequest(http.MethodGet, "/pets/2", "view=sold", ""), scenarios)
+	if resp.Scenario != "sold" || resp.Header.Get("X-Total") != "1" || string(resp.Body) != `{"id":2,"name":"dog","status":"sold"}` {
+		t.Errorf("unexpected response %d %s", resp.Status, resp.Body)
+	}
+
+	// 未启用的场景不会自动命中
+	if resp := m.Serve(newRequest(http.MethodGet, "/pets/2", "", ""), scenarios); resp.Scenario != "" || resp.Status != http.StatusOK {
+		t.Errorf("unexpected scenario %q", resp.Scenario)
+	}
+
+	// 跳过校验
+	req = newRequest(http.MethodPost, "/pets", "", `{}`)
+	req.Header.Set(mock.HeaderScenario, "invalid")
+	if resp := m.Serve(req, scenarios); resp.Status != http.StatusBadRequest || resp.Scenario != "invalid" || len(resp.Body) != 0 {
+		t.Errorf("unexpected response %d %s", resp.Status, resp.Body)
+	}
+
+	if err := (&mock.Scenario{Name: "x", Status: 1000}).Validate(); err == nil {
+		t.Error("expected invalid status error")
+	}
+}
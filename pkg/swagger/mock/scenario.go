@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scenario 场景, 命中的请求返回指定的响应, 用于模拟异常、边界数据等文档之外的情况.
+// 请求头 X-Mock-Scenario 可以指定场景名称; 未指定时, 按顺序匹配启用的场景.
+type Scenario struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+
+	// Method 和 Path 限定场景作用的接口, Path 为文档中的路径模板, 如 /users/{id}. 为空表示不限
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+
+	// Query 和 Header 为命中条件, 全部相等时命中, 为空表示不限
+	Query  map[string]string `json:"query,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+
+	// Status 为 0 时使用文档中的默认响应状态码; Body 为空时根据文档中 Status 对应的响应生成
+	Status         int               `json:"status,omitempty"`
+	ResponseHeader map[string]string `json:"responseHeader,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	DelayMs        int64             `json:"delayMs,omitempty"`
+
+	// SkipValidation 为 true 时命中该场景的请求不做校验
+	SkipValidation bool `json:"skipValidation,omitempty"`
+}
+
+// Validate 校验场景配置
+func (s *Scenario) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("scenario name is required")
+	}
+	if s.Status != 0 && (s.Status < 100 || s.Status > 599) {
+		return errors.Errorf("invalid status %d", s.Status)
+	}
+	if s.DelayMs < 0 || time.Duration(s.DelayMs)*time.Millisecond > maxDelay {
+		return errors.Errorf("delayMs must be between 0 and %d", maxDelay.Milliseconds())
+	}
+	if s.Method != "" {
+		switch strings.ToUpper(s.Method) {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+			http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodConnect:
+		default:
+			return errors.Errorf("invalid method %s", s.Method)
+		}
+	}
+	if s.Path != "" && !strings.HasPrefix(s.Path, "/") {
+		return errors.Errorf("path %s must start with /", s.Path)
+	}
+	return nil
+}
+
+func (s *Scenario) appliesTo(method, path string) bool {
+	if s.Method != "" && !strings.EqualFold(s.Method, method) {
+		return false
+	}
+	return s.Path == "" || s.Path == path
+}
+
+func (s *Scenario) matches(req *Request) bool {
+	for k, v := range s.Query {
+		if req.Query.Get(k) != v {
+			return false
+		}
+	}
+	for k, v := range s.Header {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scenario) apply(resp *Response) {
+	if s.Body != "" {
+		resp.Body = []byte(s.Body)
+		if json.Valid(resp.Body) {
+			resp.Header.Set("Content-Type", "application/json")
+		} else {
+			resp.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+	for k, v := range s.ResponseHeader {
+		resp.Header.Set(k, v)
+	}
+	resp.Delay = time.Duration(s.DelayMs) * time.Millisecond
+}
+
+// selectScenario 选择请求命中的场景, path 为匹配到的文档路径模板
+func selectScenario(scenarios []*Scenario, req *Request, path string) *Scenario {
+	if name := req.Header.Get(HeaderScenario); name != "" {
+		for _, s := range scenarios {
+			if s != nil && s.Name == name && s.appliesTo(req.Method, path) {
+				return s
+			}
+		}
+		return nil
+	}
+	for _, s := range scenarios {
+		if s != nil && s.Enabled && s.appliesTo(req.Method, path) && s.matches(req) {
+			return s
+		}
+	}
+	return nil
+}
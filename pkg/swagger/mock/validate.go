@@ -0,0 +1,266 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// validateRequest 按文档校验请求参数和请求体, 返回全部校验错误
+func validateRequest(req *Request, pathParams map[string]string, item *openapi3.PathItem, operation *openapi3.Operation) []string {
+	var errs []string
+	for _, p := range mergeParameters(item.Parameters, operation.Parameters) {
+		values, present := parameterValues(req, pathParams, p)
+		location := fmt.Sprintf("%s parameter %q", p.In, p.Name)
+		if !present {
+			if p.Required || p.In == openapi3.ParameterInPath {
+				errs = append(errs, location+" is required")
+			}
+			continue
+		}
+		if p.Schema == nil || p.Schema.Value == nil {
+			continue
+		}
+		value, err := coerce(values, p.Schema.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", location, err))
+			continue
+		}
+		if err := p.Schema.Value.VisitJSON(value); err != nil {
+			errs = append(errs, schemaErrorMessage(location, err))
+		}
+	}
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		errs = append(errs, validateBody(req, operation.RequestBody.Value)...)
+	}
+	return errs
+}
+
+func validateBody(req *Request, body *openapi3.RequestBody) []string {
+	if len(req.Body) == 0 {
+		if body.Required {
+			return []string{"request body is required"}
+		}
+		return nil
+	}
+	if len(body.Content) == 0 {
+		return nil
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	mediaType, content := lookupContent(body.Content, strings.ToLower(contentType))
+	if content == nil {
+		return []string{fmt.Sprintf("content type %q is not supported", contentType)}
+	}
+	if content.Schema == nil || content.Schema.Value == nil {
+		return nil
+	}
+	schema := content.Schema.Value
+
+	var value interface{}
+	switch {
+	case isJSON(mediaType):
+		if err := json.Unmarshal(req.Body, &value); err != nil {
+			return []string{fmt.Sprintf("request body is not valid JSON: %v", err)}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(req.Body))
+		if err != nil {
+			return []string{fmt.Sprintf("request body is not a valid form: %v", err)}
+		}
+		m := make(map[string]interface{}, len(form))
+		for k, values := range form {
+			var propSchema *openapi3.Schema
+			if ref := schema.Properties[k]; ref != nil {
+				propSchema = ref.Value
+			}
+			v, err := coerce(values, propSchema)
+			if err != nil {
+				return []string{fmt.Sprintf("form field %q: %v", k, err)}
+			}
+			m[k] = v
+		}
+		value = m
+	default:
+		// 其他媒体类型无法按 schema 校验
+		return nil
+	}
+	if err := schema.VisitJSON(value); err != nil {
+		return []string{schemaErrorMessage("request body", err)}
+	}
+	return nil
+}
+
+// lookupContent 按请求的媒体类型查找文档中的定义, 支持 application/* 和 */* 通配.
+// 请求未声明 Content-Type 时, 文档只定义了一种媒体类型则使用它.
+func lookupContent(content openapi3.Content, contentType string) (string, *openapi3.MediaType) {
+	if contentType == "" {
+		if len(content) == 1 {
+			for mt, c := range content {
+				return strings.ToLower(mt), c
+			}
+		}
+		contentType = "application/json"
+	}
+	var types []string
+	for mt := range content {
+		types = append(types, mt)
+	}
+	sort.Strings(types)
+	for _, mt := range types {
+		if strings.EqualFold(mt, contentType) {
+			return contentType, content[mt]
+		}
+	}
+	for _, mt := range types {
+		if strings.HasSuffix(mt, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(mt, "*")) || mt == "*/*" {
+			return contentType, content[mt]
+		}
+	}
+	return contentType, nil
+}
+
+// mergeParameters 合并路径级与操作级参数, 操作级参数覆盖同名的路径级参数
+func mergeParameters(common, own openapi3.Parameters) []*openapi3.Parameter {
+	var (
+		keys   []string
+		params = make(map[string]*openapi3.Parameter)
+	)
+	for _, list := range []openapi3.Parameters{common, own} {
+		for _, ref := range list {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			key := ref.Value.In + "." + ref.Value.Name
+			if _, ok := params[key]; !ok {
+				keys = append(keys, key)
+			}
+			params[key] = ref.Value
+		}
+	}
+	result := make([]*openapi3.Parameter, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, params[key])
+	}
+	return result
+}
+
+func parameterValues(req *Request, pathParams map[string]string, p *openapi3.Parameter) ([]string, bool) {
+	switch p.In {
+	case openapi3.ParameterInPath:
+		v, ok := pathParams[p.Name]
+		return []string{v}, ok
+	case openapi3.ParameterInQuery:
+		values, ok := req.Query[p.Name]
+		return values, ok
+	case openapi3.ParameterInHeader:
+		values := req.Header.Values(p.Name)
+		return values, len(values) > 0
+	case openapi3.ParameterInCookie:
+		cookie, err := (&http.Request{Header: req.Header}).Cookie(p.Name)
+		if err != nil {
+			return nil, false
+		}
+		return []string{cookie.Value}, true
+	}
+	return nil, false
+}
+
+// coerce 将字符串形式的参数转换为 schema 声明的类型, 以便按 JSON schema 校验
+func coerce(values []string, schema *openapi3.Schema) (interface{}, error) {
+	if schema == nil {
+		if len(values) == 1 {
+			return values[0], nil
+		}
+		return toInterfaces(values), nil
+	}
+	if schema.Type == "array" {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		var item *openapi3.Schema
+		if schema.Items != nil {
+			item = schema.Items.Value
+		}
+		list := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			converted, err := coerce([]string{v}, item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return list, nil
+	}
+
+	var v string
+	if len(values) > 0 {
+		v = values[0]
+	}
+	switch schema.Type {
+	case "integer", "number":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a %s", v, schema.Type)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a boolean", v)
+		}
+		return b, nil
+	case "object":
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			return nil, fmt.Errorf("value %q is not a JSON object", v)
+		}
+		return m, nil
+	}
+	return v, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
+
+// schemaErrorMessage 只保留出错位置和原因, 不输出 kin-openapi 附带的完整 schema
+func schemaErrorMessage(location string, err error) string {
+	e, ok := err.(*openapi3.SchemaError)
+	if !ok {
+		return fmt.Sprintf("%s: %v", location, err)
+	}
+	reason := e.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("doesn't match schema %q", e.SchemaField)
+	}
+	if pointer := e.JSONPointer(); len(pointer) > 0 {
+		return fmt.Sprintf("%s /%s: %s", location, strings.Join(pointer, "/"), reason)
+	}
+	return fmt.Sprintf("%s: %s", location, reason)
+}
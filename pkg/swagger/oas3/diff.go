@@ -0,0 +1,526 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oas3
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ChangeLevel 变更级别
+type ChangeLevel string
+
+const (
+	ChangeLevelBreaking    ChangeLevel = "breaking"
+	ChangeLevelNonBreaking ChangeLevel = "non-breaking"
+)
+
+// ChangeType 变更类型
+type ChangeType string
+
+const (
+	ChangePathAdded             ChangeType = "path-added"
+	ChangePathRemoved           ChangeType = "path-removed"
+	ChangeOperationAdded        ChangeType = "operation-added"
+	ChangeOperationRemoved      ChangeType = "operation-removed"
+	ChangeParameterAdded        ChangeType = "parameter-added"
+	ChangeParameterRemoved      ChangeType = "parameter-removed"
+	ChangeParameterRequired     ChangeType = "parameter-became-required"
+	ChangeParameterOptional     ChangeType = "parameter-became-optional"
+	ChangeRequestBodyAdded      ChangeType = "request-body-added"
+	ChangeRequestBodyRemoved    ChangeType = "request-body-removed"
+	ChangeRequestBodyRequired   ChangeType = "request-body-became-required"
+	ChangeMediaTypeAdded        ChangeType = "media-type-added"
+	ChangeMediaTypeRemoved      ChangeType = "media-type-removed"
+	ChangePropertyAdded         ChangeType = "property-added"
+	ChangePropertyRemoved       ChangeType = "property-removed"
+	ChangePropertyRequired      ChangeType = "property-became-required"
+	ChangeTypeChanged           ChangeType = "type-changed"
+	ChangeEnumNarrowed          ChangeType = "enum-narrowed"
+	ChangeEnumWidened           ChangeType = "enum-widened"
+	ChangeResponseAdded         ChangeType = "response-added"
+	ChangeResponseRemoved       ChangeType = "response-removed"
+	ChangeResponseSchemaRemoved ChangeType = "response-schema-removed"
+	ChangeOperationDeprecated   ChangeType = "operation-deprecated"
+)
+
+// maxDiffDepth 限制 schema 递归比较的深度, 防止自引用的 schema 无限展开
+const maxDiffDepth = 16
+
+var pathParamRegexp = regexp.MustCompile(`\{[^}]*}`)
+
+// Change 两个文档之间的一处语义变更
+type Change struct {
+	Level    ChangeLevel `json:"level"`
+	Type     ChangeType  `json:"type"`
+	Path     string      `json:"path"`
+	Method   string      `json:"method,omitempty"`
+	Location string      `json:"location,omitempty"`
+	Message  string      `json:"message"`
+}
+
+func (c *Change) String() string {
+	s := fmt.Sprintf("[%s] %s", c.Level, c.Path)
+	if c.Method != "" {
+		s = fmt.Sprintf("[%s] %s %s", c.Level, c.Method, c.Path)
+	}
+	if c.Location != "" {
+		s += " " + c.Location
+	}
+	return s + ": " + c.Message
+}
+
+// DiffResult 文档比较结果
+type DiffResult struct {
+	Changes []*Change `json:"changes"`
+}
+
+// HasBreaking 是否存在不兼容变更
+func (r *DiffResult) HasBreaking() bool {
+	return len(r.BreakingChanges()) > 0
+}
+
+// BreakingChanges 返回全部不兼容变更
+func (r *DiffResult) BreakingChanges() []*Change {
+	var changes []*Change
+	for _, c := range r.Changes {
+		if c.Level == ChangeLevelBreaking {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Diff 比较 base 与 target 两个文档, 找出 target 相对于 base 的语义变更并区分是否兼容.
+// 路径参数名称不参与比较, 即 /users/{id} 与 /users/{userId} 视为同一路径.
+func Diff(base, target *openapi3.Swagger) *DiffResult {
+	d := &differ{result: new(DiffResult)}
+	if base == nil || target == nil {
+		return d.result
+	}
+
+	basePaths := normalizePaths(base.Paths)
+	targetPaths := normalizePaths(target.Paths)
+	for _, key := range sortedKeys(basePaths) {
+		bp := basePaths[key]
+		tp, ok := targetPaths[key]
+		if !ok {
+			d.add(ChangeLevelBreaking, ChangePathRemoved, bp.path, "", "", "path removed")
+			continue
+		}
+		d.diffPathItem(bp.path, tp.path, bp.item, tp.item)
+	}
+	for _, key := range sortedKeys(targetPaths) {
+		if _, ok := basePaths[key]; !ok {
+			d.add(ChangeLevelNonBreaking, ChangePathAdded, targetPaths[key].path, "", "", "path added")
+		}
+	}
+	return d.result
+}
+
+type differ struct {
+	result *DiffResult
+}
+
+type namedPath struct {
+	path string
+	item *openapi3.PathItem
+}
+
+// schemaDirection 区分 schema 用于请求还是响应, 两者兼容性判定相反:
+// 请求侧收紧约束是不兼容的, 响应侧放宽约束是不兼容的.
+type schemaDirection int
+
+const (
+	directionRequest schemaDirection = iota
+	directionResponse
+)
+
+func (d *differ) add(level ChangeLevel, typ ChangeType, path, method, location, message string) {
+	d.result.Changes = append(d.result.Changes, &Change{
+		Level:    level,
+		Type:     typ,
+		Path:     path,
+		Method:   method,
+		Location: location,
+		Message:  message,
+	})
+}
+
+func (d *differ) diffPathItem(basePath, path string, base, target *openapi3.PathItem) {
+	if base == nil || target == nil {
+		return
+	}
+	baseOps := base.Operations()
+	targetOps := target.Operations()
+	for _, method := range sortedKeys(baseOps) {
+		tOp, ok := targetOps[method]
+		if !ok {
+			d.add(ChangeLevelBreaking, ChangeOperationRemoved, path, method, "", "operation removed")
+			continue
+		}
+		d.diffOperation(basePath, path, method, base.Parameters, target.Parameters, baseOps[method], tOp)
+	}
+	for _, method := range sortedKeys(targetOps) {
+		if _, ok := baseOps[method]; !ok {
+			d.add(ChangeLevelNonBreaking, ChangeOperationAdded, path, method, "", "operation added")
+		}
+	}
+}
+
+func (d *differ) diffOperation(basePath, path, method string, baseCommon, targetCommon openapi3.Parameters, base, target *openapi3.Operation) {
+	if !base.Deprecated && target.Deprecated {
+		d.add(ChangeLevelNonBreaking, ChangeOperationDeprecated, path, method, "", "operation deprecated")
+	}
+	d.diffParameters(path, method, mergeParameters(basePath, baseCommon, base.Parameters), mergeParameters(path, targetCommon, target.Parameters))
+	d.diffRequestBody(path, method, base.RequestBody, target.RequestBody)
+	d.diffResponses(path, method, base.Responses, target.Responses)
+}
+
+func (d *differ) diffParameters(path, method string, base, target map[string]*openapi3.Parameter) {
+	for _, key := range sortedKeys(base) {
+		bp := base[key]
+		location := parameterLocation(bp)
+		tp, ok := target[key]
+		if !ok {
+			d.add(ChangeLevelNonBreaking, ChangeParameterRemoved, path, method, location, "parameter removed")
+			continue
+		}
+		if !bp.Required && tp.Required {
+			d.add(ChangeLevelBreaking, ChangeParameterRequired, path, method, location, "parameter became required")
+		}
+		if bp.Required && !tp.Required {
+			d.add(ChangeLevelNonBreaking, ChangeParameterOptional, path, method, location, "parameter became optional")
+		}
+		d.diffSchema(path, method, location, directionRequest, bp.Schema, tp.Schema, 0)
+	}
+	for _, key := range sortedKeys(target) {
+		if _, ok := base[key]; ok {
+			continue
+		}
+		location := parameterLocation(target[key])
+		if target[key].Required {
+			d.add(ChangeLevelBreaking, ChangeParameterAdded, path, method, location, "required parameter added")
+		} else {
+			d.add(ChangeLevelNonBreaking, ChangeParameterAdded, path, method, location, "optional parameter added")
+		}
+	}
+}
+
+func (d *differ) diffRequestBody(path, method string, base, target *openapi3.RequestBodyRef) {
+	var bb, tb *openapi3.RequestBody
+	if base != nil {
+		bb = base.Value
+	}
+	if target != nil {
+		tb = target.Value
+	}
+	switch {
+	case bb == nil && tb == nil:
+		return
+	case bb == nil:
+		if tb.Required {
+			d.add(ChangeLevelBreaking, ChangeRequestBodyAdded, path, method, "requestBody", "required request body added")
+		} else {
+			d.add(ChangeLevelNonBreaking, ChangeRequestBodyAdded, path, method, "requestBody", "optional request body added")
+		}
+		return
+	case tb == nil:
+		d.add(ChangeLevelNonBreaking, ChangeRequestBodyRemoved, path, method, "requestBody", "request body removed")
+		return
+	}
+	if !bb.Required && tb.Required {
+		d.add(ChangeLevelBreaking, ChangeRequestBodyRequired, path, method, "requestBody", "request body became required")
+	}
+	for _, mt := range sortedKeys(bb.Content) {
+		location := "requestBody." + mt
+		tmt, ok := tb.Content[mt]
+		if !ok {
+			d.add(ChangeLevelBreaking, ChangeMediaTypeRemoved, path, method, location, "request media type removed")
+			continue
+		}
+		if bmt := bb.Content[mt]; bmt != nil && tmt != nil {
+			d.diffSchema(path, method, location, directionRequest, bmt.Schema, tmt.Schema, 0)
+		}
+	}
+	for _, mt := range sortedKeys(tb.Content) {
+		if _, ok := bb.Content[mt]; !ok {
+			d.add(ChangeLevelNonBreaking, ChangeMediaTypeAdded, path, method, "requestBody."+mt, "request media type added")
+		}
+	}
+}
+
+func (d *differ) diffResponses(path, method string, base, target openapi3.Responses) {
+	for _, status := range sortedKeys(base) {
+		location := "responses." + status
+		br := base[status]
+		tr, ok := target[status]
+		if !ok {
+			d.add(ChangeLevelBreaking, ChangeResponseRemoved, path, method, location, "response removed")
+			continue
+		}
+		if br == nil || br.Value == nil || tr == nil || tr.Value == nil {
+			continue
+		}
+		for _, mt := range sortedKeys(br.Value.Content) {
+			mtLocation := location + "." + mt
+			tmt, ok := tr.Value.Content[mt]
+			if !ok {
+				d.add(ChangeLevelBreaking, ChangeMediaTypeRemoved, path, method, mtLocation, "response media type removed")
+				continue
+			}
+			bmt := br.Value.Content[mt]
+			if bmt == nil || tmt == nil {
+				continue
+			}
+			if schemaOf(bmt.Schema) != nil && schemaOf(tmt.Schema) == nil {
+				d.add(ChangeLevelBreaking, ChangeResponseSchemaRemoved, path, method, mtLocation, "response schema removed")
+				continue
+			}
+			d.diffSchema(path, method, mtLocation, directionResponse, bmt.Schema, tmt.Schema, 0)
+		}
+		for _, mt := range sortedKeys(tr.Value.Content) {
+			if _, ok := br.Value.Content[mt]; !ok {
+				d.add(ChangeLevelNonBreaking, ChangeMediaTypeAdded, path, method, location+"."+mt, "response media type added")
+			}
+		}
+	}
+	for _, status := range sortedKeys(target) {
+		if _, ok := base[status]; !ok {
+			d.add(ChangeLevelNonBreaking, ChangeResponseAdded, path, method, "responses."+status, "response added")
+		}
+	}
+}
+
+func (d *differ) diffSchema(path, method, location string, direction schemaDirection, baseRef, targetRef *openapi3.SchemaRef, depth int) {
+	if depth > maxDiffDepth {
+		return
+	}
+	base, target := flattenSchema(schemaOf(baseRef)), flattenSchema(schemaOf(targetRef))
+	if base == nil || target == nil {
+		return
+	}
+
+	if base.Type != "" && target.Type != "" && (base.Type != target.Type || !compatibleFormat(base.Format, target.Format)) {
+		d.add(ChangeLevelBreaking, ChangeTypeChanged, path, method, location,
+			fmt.Sprintf("type changed from %s to %s", typeString(base), typeString(target)))
+		return
+	}
+
+	d.diffEnum(path, method, location, direction, base.Enum, target.Enum)
+
+	if base.Items != nil || target.Items != nil {
+		d.diffSchema(path, method, location+"[]", direction, base.Items, target.Items, depth+1)
+	}
+
+	baseRequired := stringSet(base.Required)
+	targetRequired := stringSet(target.Required)
+	for _, name := range sortedKeys(base.Properties) {
+		propLocation := joinLocation(location, name)
+		tp, ok := target.Properties[name]
+		if !ok {
+			if direction == directionResponse {
+				d.add(ChangeLevelBreaking, ChangePropertyRemoved, path, method, propLocation, "response property removed")
+			} else {
+				d.add(ChangeLevelNonBreaking, ChangePropertyRemoved, path, method, propLocation, "request property removed")
+			}
+			continue
+		}
+		if direction == directionRequest && !baseRequired[name] && targetRequired[name] {
+			d.add(ChangeLevelBreaking, ChangePropertyRequired, path, method, propLocation, "request property became required")
+		}
+		d.diffSchema(path, method, propLocation, direction, base.Properties[name], tp, depth+1)
+	}
+	for _, name := range sortedKeys(target.Properties) {
+		if _, ok := base.Properties[name]; ok {
+			continue
+		}
+		propLocation := joinLocation(location, name)
+		if direction == directionRequest && targetRequired[name] {
+			d.add(ChangeLevelBreaking, ChangePropertyAdded, path, method, propLocation, "required request property added")
+		} else {
+			d.add(ChangeLevelNonBreaking, ChangePropertyAdded, path, method, propLocation, "property added")
+		}
+	}
+}
+
+// diffEnum 请求侧删除枚举值 (收窄) 是不兼容的; 响应侧新增枚举值 (放宽) 是不兼容的, 客户端可能无法处理新值.
+func (d *differ) diffEnum(path, method, location string, direction schemaDirection, base, target []interface{}) {
+	if len(base) == 0 && len(target) == 0 {
+		return
+	}
+	baseSet, targetSet := enumSet(base), enumSet(target)
+	var removed, added []string
+	for _, v := range sortedKeys(baseSet) {
+		if !targetSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	for _, v := range sortedKeys(targetSet) {
+		if !baseSet[v] {
+			added = append(added, v)
+		}
+	}
+	// 没有枚举约束相当于允许任意值
+	if len(base) == 0 {
+		removed, added = []string{"*"}, nil
+	}
+	if len(target) == 0 {
+		removed, added = nil, []string{"*"}
+	}
+
+	if len(removed) > 0 {
+		level := ChangeLevelNonBreaking
+		if direction == directionRequest {
+			level = ChangeLevelBreaking
+		}
+		d.add(level, ChangeEnumNarrowed, path, method, location, "enum values removed: "+strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		level := ChangeLevelNonBreaking
+		if direction == directionResponse {
+			level = ChangeLevelBreaking
+		}
+		d.add(level, ChangeEnumWidened, path, method, location, "enum values added: "+strings.Join(added, ", "))
+	}
+}
+
+// normalizePaths 以去掉路径参数名后的路径作为 key
+func normalizePaths(paths openapi3.Paths) map[string]namedPath {
+	m := make(map[string]namedPath, len(paths))
+	for path, item := range paths {
+		m[pathParamRegexp.ReplaceAllString(path, "{}")] = namedPath{path: path, item: item}
+	}
+	return m
+}
+
+// mergeParameters 合并路径级与操作级参数, 操作级参数覆盖同名的路径级参数.
+// 路径参数以其在路径模板中的位置作为 key, 使参数改名不被误判为删除与新增.
+func mergeParameters(path string, common, own openapi3.Parameters) map[string]*openapi3.Parameter {
+	positions := make(map[string]int)
+	for i, placeholder := range pathParamRegexp.FindAllString(path, -1) {
+		positions[strings.Trim(placeholder, "{}")] = i
+	}
+	m := make(map[string]*openapi3.Parameter)
+	for _, params := range []openapi3.Parameters{common, own} {
+		for _, ref := range params {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			p := ref.Value
+			name := p.Name
+			switch p.In {
+			case openapi3.ParameterInHeader:
+				name = strings.ToLower(name)
+			case openapi3.ParameterInPath:
+				if i, ok := positions[name]; ok {
+					name = fmt.Sprintf("#%d", i)
+				}
+			}
+			m[p.In+"."+name] = p
+		}
+	}
+	return m
+}
+
+func parameterLocation(p *openapi3.Parameter) string {
+	return "parameters." + p.In + "." + p.Name
+}
+
+func schemaOf(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+	return ref.Value
+}
+
+// flattenSchema 将 allOf 合并为一个 schema 以便逐属性比较
+func flattenSchema(schema *openapi3.Schema) *openapi3.Schema {
+	if schema == nil || len(schema.AllOf) == 0 {
+		return schema
+	}
+	merged := *schema
+	merged.Properties = make(openapi3.Schemas)
+	for name, prop := range schema.Properties {
+		merged.Properties[name] = prop
+	}
+	merged.Required = append([]string(nil), schema.Required...)
+	for _, ref := range schema.AllOf {
+		sub := flattenSchema(schemaOf(ref))
+		if sub == nil {
+			continue
+		}
+		if merged.Type == "" {
+			merged.Type = sub.Type
+		}
+		for name, prop := range sub.Properties {
+			merged.Properties[name] = prop
+		}
+		merged.Required = append(merged.Required, sub.Required...)
+	}
+	merged.AllOf = nil
+	return &merged
+}
+
+// compatibleFormat 只有两边都声明了 format 且不相同时才视为类型变更
+func compatibleFormat(base, target string) bool {
+	return base == "" || target == "" || base == target
+}
+
+func typeString(schema *openapi3.Schema) string {
+	if schema.Format != "" {
+		return schema.Type + "(" + schema.Format + ")"
+	}
+	return schema.Type
+}
+
+func joinLocation(location, name string) string {
+	if location == "" {
+		return name
+	}
+	return location + "." + name
+}
+
+func stringSet(ss []string) map[string]bool {
+	m := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		m[s] = true
+	}
+	return m
+}
+
+func enumSet(values []interface{}) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[fmt.Sprintf("%v", v)] = true
+	}
+	return m
+}
+
+// sortedKeys 返回 string 为 key 的 map 的有序 key 列表, 保证比较结果的顺序稳定
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	ss := make([]string, 0, len(keys))
+	for _, k := range keys {
+		ss = append(ss, k.String())
+	}
+	sort.Strings(ss)
+	return ss
+}
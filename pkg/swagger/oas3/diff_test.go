@@ -0,0 +1,227 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oas3_test
+
+import (
+	"testing"
+
+	"github.com/erda-project/erda/pkg/swagger/oas3"
+)
+
+const diffBaseText = `
+openapi: 3.0.0
+info:
+  title: users
+  version: 1.0.0
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+        - name: view
+          in: query
+          schema:
+            type: string
+            enum: [brief, full, raw]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+                  age:
+                    type: integer
+                  status:
+                    type: string
+                    enum: [active, disabled]
+        "404":
+          description: not found
+    delete:
+      responses:
+        "204":
+          description: deleted
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                tags:
+                  type: array
+                  items:
+                    type: string
+      responses:
+        "200":
+          description: ok
+  /legacy:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+const diffTargetText = `
+openapi: 3.0.0
+info:
+  title: users
+  version: 1.1.0
+paths:
+  /users/{userId}:
+    parameters:
+      - name: userId
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      parameters:
+        - name: fields
+          in: query
+          required: true
+          schema:
+            type: string
+        - name: view
+          in: query
+          schema:
+            type: string
+            enum: [brief, full]
+        - name: lang
+          in: query
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+                  status:
+                    type: string
+                    enum: [active, disabled, locked]
+                  email:
+                    type: string
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name, email]
+              properties:
+                name:
+                  type: string
+                email:
+                  type: string
+                tags:
+                  type: array
+                  items:
+                    type: integer
+      responses:
+        "200":
+          description: ok
+  /health:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+
+func TestDiff(t *testing.T) {
+	base, err := oas3.LoadFromData([]byte(diffBaseText))
+	if err != nil {
+		t.Fatalf("failed to load base: %v", err)
+	}
+	target, err := oas3.LoadFromData([]byte(diffTargetText))
+	if err != nil {
+		t.Fatalf("failed to load target: %v", err)
+	}
+
+	result := oas3.Diff(base, target)
+	got := make(map[string]*oas3.Change)
+	for _, c := range result.Changes {
+		got[string(c.Type)+" "+c.Method+" "+c.Path+" "+c.Location] = c
+	}
+
+	expects := []struct {
+		key   string
+		level oas3.ChangeLevel
+	}{
+		{"path-removed  /legacy ", oas3.ChangeLevelBreaking},
+		{"path-added  /health ", oas3.ChangeLevelNonBreaking},
+		{"operation-removed DELETE /users/{userId} ", oas3.ChangeLevelBreaking},
+		{"parameter-became-required GET /users/{userId} parameters.query.fields", oas3.ChangeLevelBreaking},
+		{"enum-narrowed GET /users/{userId} parameters.query.view", oas3.ChangeLevelBreaking},
+		{"parameter-added GET /users/{userId} parameters.query.lang", oas3.ChangeLevelNonBreaking},
+		{"response-removed GET /users/{userId} responses.404", oas3.ChangeLevelBreaking},
+		{"property-removed GET /users/{userId} responses.200.application/json.age", oas3.ChangeLevelBreaking},
+		{"enum-widened GET /users/{userId} responses.200.application/json.status", oas3.ChangeLevelBreaking},
+		{"property-added GET /users/{userId} responses.200.application/json.email", oas3.ChangeLevelNonBreaking},
+		{"property-added POST /users requestBody.application/json.email", oas3.ChangeLevelBreaking},
+		{"type-changed POST /users requestBody.application/json.tags[]", oas3.ChangeLevelBreaking},
+	}
+	for _, e := range expects {
+		c, ok := got[e.key]
+		if !ok {
+			t.Errorf("missing change %q", e.key)
+			continue
+		}
+		if c.Level != e.level {
+			t.Errorf("change %q: level %s, want %s", e.key, c.Level, e.level)
+		}
+	}
+	if len(result.Changes) != len(expects) {
+		for _, c := range result.Changes {
+			t.Log(c)
+		}
+		t.Errorf("got %d changes, want %d", len(result.Changes), len(expects))
+	}
+	if !result.HasBreaking() {
+		t.Error("expected breaking changes")
+	}
+}
+
+func TestDiffCompatible(t *testing.T) {
+	base, err := oas3.LoadFromData([]byte(diffBaseText))
+	if err != nil {
+		t.Fatalf("failed to load base: %v", err)
+	}
+	result := oas3.Diff(base, base)
+	if len(result.Changes) != 0 {
+		t.Errorf("expected no changes, got %v", result.Changes)
+	}
+}